@@ -0,0 +1,297 @@
+// Command bench-pub measures the publishing performance of the API against a broker, a lightweight sdkperf: it
+// publishes messages of a fixed size, or generated payloads, at a fixed rate (or as fast as possible) and reports the sustained throughput,
+// the publish latency (the time spent in the Publish call, i.e. the back pressure) and, in persistent mode, the ack
+// latency (from publishing a message to its acknowledgement by the broker).
+//
+//	go run ./cmd/bench-pub -size 1024 -duration 30s
+//	go run ./cmd/bench-pub -mode persistent -rate 10000 -count 500000 -backpressure wait -buffer 1000
+//	go run ./cmd/bench-pub -mode direct -backpressure reject -buffer 100 -rate 0
+//	go run ./cmd/bench-pub -template @order.json.tmpl -size exp:2048 -pool 10000
+//
+// With -template, or a -size distribution such as uniform:100-1000, a pool of -pool payloads is generated before the
+// run (see pkg/payloadgen) and published round robin, so the benchmark measures the API, not the payload generation.
+// With -backpressure reject, the messages rejected while the buffer is full are counted and dropped, with wait the
+// Publish call blocks until the buffer has room. Run cmd/bench-sub on the same topic to measure the consuming side.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/metricsnap"
+	"SolaceSamples.com/PubSub+Go/pkg/payloadgen"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/metrics"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// publishFunc publishes a message, hiding the difference between the direct and persistent publishers
+type publishFunc func(msg message.OutboundMessage) error
+
+// results of a run
+type results struct {
+	rejected, failed           int64
+	acknowledged, nacked       int64
+	publishLatency, ackLatency *bench.Latency
+	counter                    *bench.Counter
+}
+
+func (r *results) print(elapsed time.Duration, persistent bool) {
+	published, bytes := r.counter.Total()
+	average := int64(0)
+	if published > 0 {
+		average = bytes / published
+	}
+	fmt.Printf("\nPublished %d message(s) of %d bytes on average in %s: %.0f msg/s, %.2f MB/s\n",
+		published, average, elapsed.Round(time.Millisecond), bench.Rate(published, elapsed), bench.Rate(bytes, elapsed)/1e6)
+	fmt.Printf("Rejected (back pressure): %d, failed: %d\n", atomic.LoadInt64(&r.rejected), atomic.LoadInt64(&r.failed))
+	fmt.Printf("Publish latency: %s\n", r.publishLatency.Summary())
+	if persistent {
+		fmt.Printf("Acknowledged: %d, rejected by the broker: %d\n", atomic.LoadInt64(&r.acknowledged), atomic.LoadInt64(&r.nacked))
+		fmt.Printf("Ack latency:     %s\n", r.ackLatency.Summary())
+	}
+}
+
+func main() {
+	topicName := flag.String("topic", "solace/samples/bench", "topic to publish on")
+	size := flag.String("size", "100", "payload size in bytes, or size distribution of the generated payloads, e.g. uniform:100-1000, normal:512,64 or exp:256")
+	templateArg := flag.String("template", "", "template of the generated payloads, or @file to read it from a file, see pkg/payloadgen")
+	poolSize := flag.Int("pool", 1000, "number of distinct payloads generated before the run with -template or a size distribution")
+	seed := flag.Int64("seed", 0, "seed of the generated values and sizes, 0 for a different run every time")
+	rate := flag.Int("rate", 0, "messages published per second, 0 publishes as fast as possible")
+	count := flag.Int64("count", 0, "messages to publish, 0 publishes until the duration is over")
+	duration := flag.Duration("duration", 10*time.Second, "duration of the run when -count is 0")
+	mode := flag.String("mode", "direct", "delivery mode: direct or persistent")
+	backPressure := flag.String("backpressure", "wait", "back pressure strategy of the publisher: wait or reject")
+	buffer := flag.Uint("buffer", 1000, "publisher buffer size, in messages")
+	interval := flag.Duration("interval", time.Second, "period the throughput is reported")
+	ackTimeout := flag.Duration("ack-timeout", 30*time.Second, "time to wait for outstanding acknowledgements at the end of a persistent run")
+	flag.Parse()
+
+	persistent := *mode == "persistent"
+	if !persistent && *mode != "direct" {
+		fmt.Fprintf(os.Stderr, "unknown mode '%s', expected direct or persistent\n", *mode)
+		os.Exit(2)
+	}
+	if *backPressure != "wait" && *backPressure != "reject" {
+		fmt.Fprintf(os.Stderr, "unknown back pressure strategy '%s', expected wait or reject\n", *backPressure)
+		os.Exit(2)
+	}
+	if *count < 0 || *poolSize < 1 || (*count == 0 && *duration <= 0) {
+		fmt.Fprintln(os.Stderr, "-count can not be negative, -pool must be positive, -duration must be positive when -count is 0")
+		os.Exit(2)
+	}
+	sizes, err := payloadgen.ParseDistribution(*size)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	text, err := payloadgen.Load(*templateArg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read the template: ", err)
+		os.Exit(2)
+	}
+
+	// Configuration parameters, loaded from the secrets source selected with -secrets-source (environment by default)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+	defer messagingService.Disconnect()
+
+	r := &results{
+		publishLatency: bench.NewLatency(time.Minute),
+		ackLatency:     bench.NewLatency(time.Minute),
+		counter:        bench.NewCounter(),
+	}
+	topic := resource.TopicOf(*topicName)
+	var outstanding sync.WaitGroup
+	var publish publishFunc
+	var publisher solace.LifecycleControl
+
+	if persistent {
+		builder := messagingService.CreatePersistentMessagePublisherBuilder()
+		if *backPressure == "reject" {
+			builder = builder.OnBackPressureReject(*buffer)
+		} else {
+			builder = builder.OnBackPressureWait(*buffer)
+		}
+		persistentPublisher, err := builder.Build()
+		if err == nil {
+			err = persistentPublisher.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the persistent publisher: ", err)
+			os.Exit(1)
+		}
+		// The publish time travels with the message as the user context of its receipt
+		persistentPublisher.SetMessagePublishReceiptListener(func(receipt solace.PublishReceipt) {
+			if sent, ok := receipt.GetUserContext().(time.Time); ok {
+				r.ackLatency.Record(time.Since(sent))
+			}
+			if receipt.GetError() != nil {
+				atomic.AddInt64(&r.nacked, 1)
+			} else {
+				atomic.AddInt64(&r.acknowledged, 1)
+			}
+			outstanding.Done()
+		})
+		publish = func(msg message.OutboundMessage) error {
+			outstanding.Add(1)
+			if err := persistentPublisher.Publish(msg, topic, nil, time.Now()); err != nil {
+				outstanding.Done()
+				return err
+			}
+			return nil
+		}
+		publisher = persistentPublisher
+	} else {
+		builder := messagingService.CreateDirectMessagePublisherBuilder()
+		if *backPressure == "reject" {
+			builder = builder.OnBackPressureReject(*buffer)
+		} else {
+			builder = builder.OnBackPressureWait(*buffer)
+		}
+		directPublisher, err := builder.Build()
+		if err == nil {
+			err = directPublisher.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the direct publisher: ", err)
+			os.Exit(1)
+		}
+		publish = func(msg message.OutboundMessage) error {
+			return directPublisher.Publish(msg, topic)
+		}
+		publisher = directPublisher
+	}
+
+	// The messages are built before the run, the benchmark measures the API, not the payload generation: the same
+	// zeroed payload for every message for a fixed size without template, a pool of generated payloads otherwise
+	var payloads [][]byte
+	if fixedSize, err := strconv.Atoi(*size); err == nil && text == "" {
+		payloads = append(payloads, make([]byte, fixedSize))
+	} else {
+		generator, err := payloadgen.New(text, payloadgen.Options{Size: sizes, Seed: *seed})
+		if err == nil {
+			payloads, err = generator.Pool(*poolSize)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not generate the payloads: ", err)
+			os.Exit(1)
+		}
+	}
+	messages := make([]message.OutboundMessage, len(payloads))
+	for i, payload := range payloads {
+		if messages[i], err = messagingService.MessageBuilder().BuildWithByteArrayPayload(payload); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not build the message: ", err)
+			os.Exit(1)
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	stop := make(chan struct{})
+	go func() {
+		// the count ends the run when it is set, the duration otherwise
+		var timeout <-chan time.Time
+		if *count == 0 {
+			timeout = time.After(*duration)
+		}
+		select {
+		case <-interrupt:
+		case <-timeout:
+		}
+		close(stop)
+	}()
+
+	fmt.Printf("Publishing %s messages of %s bytes on %s (rate %d msg/s, 0 is unlimited)\n", *mode, *size, *topicName, *rate)
+	start := time.Now()
+	apiMetrics := metricsnap.NewTracker(messagingService.Metrics())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pacer := bench.NewPacer(*rate)
+		for n := int64(0); *count == 0 || n < *count; n++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			pacer.Wait()
+			before := time.Now()
+			err := publish(messages[n%int64(len(messages))])
+			r.publishLatency.Record(time.Since(before))
+			var overflow *solace.PublisherOverflowError
+			switch {
+			case err == nil:
+				r.counter.Add(len(payloads[n%int64(len(payloads))]))
+			case errors.As(err, &overflow):
+				atomic.AddInt64(&r.rejected, 1)
+			default:
+				if atomic.AddInt64(&r.failed, 1) == 1 {
+					fmt.Fprintln(os.Stderr, "Publish failed: ", err)
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+report:
+	for {
+		select {
+		case <-ticker.C:
+			messagesPerSecond, bytesPerSecond := r.counter.Interval()
+			published, _ := r.counter.Total()
+			wouldBlock := apiMetrics.Interval().Get(metrics.PublisherWouldBlock).Delta
+			fmt.Printf("published=%d rate=%.0f msg/s %.2f MB/s rejected=%d acknowledged=%d would-block=%d\n", published,
+				messagesPerSecond, bytesPerSecond/1e6, atomic.LoadInt64(&r.rejected), atomic.LoadInt64(&r.acknowledged), wouldBlock)
+		case <-done:
+			break report
+		}
+	}
+	elapsed := time.Since(start)
+
+	if persistent {
+		acked := make(chan struct{})
+		go func() {
+			outstanding.Wait()
+			close(acked)
+		}()
+		select {
+		case <-acked:
+		case <-time.After(*ackTimeout):
+			fmt.Fprintln(os.Stderr, "Timed out waiting for the acknowledgement of all persistent messages")
+		}
+	}
+
+	publisher.Terminate(5 * time.Second)
+	r.print(elapsed, persistent)
+	fmt.Println("\nAPI metrics of the run:")
+	apiMetrics.Run().Render(os.Stdout, true)
+}
@@ -0,0 +1,352 @@
+// Command bench-sub measures the consuming performance of the API, the counterpart of cmd/bench-pub: it receives
+// messages from a topic (direct) or a queue (persistent), processes them in a pool of workers with a simulated
+// handler delay, and reports the receive rate, the settlement (acknowledgement) rate and the processing latency.
+// The results of the run are written as JSON for comparisons across runs.
+//
+//	go run ./cmd/bench-sub -topic 'solace/samples/bench' -duration 30s
+//	go run ./cmd/bench-sub -queue bench-queue -handler-delay 2ms -concurrency 16 -results results.json
+//
+// The processing latency runs from the receipt of a message by the handler of the API to the end of its processing,
+// it includes the time waiting for a free worker; the handler time only counts the processing itself.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/metricsnap"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/metrics"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Config is the configuration of a run, repeated in its results
+type Config struct {
+	Topic         string        `json:"topic,omitempty"`
+	Queue         string        `json:"queue,omitempty"`
+	HandlerDelay  time.Duration `json:"handler_delay_ns"`
+	HandlerJitter time.Duration `json:"handler_jitter_ns"`
+	Concurrency   int           `json:"concurrency"`
+	Backlog       int           `json:"backlog"`
+	Duration      time.Duration `json:"duration_ns"`
+	Count         int64         `json:"count"`
+}
+
+// Results are written to the results file at the end of a run
+type Results struct {
+	Config   Config        `json:"config"`
+	Start    time.Time     `json:"start"`
+	Elapsed  time.Duration `json:"elapsed_ns"`
+	Received int64         `json:"received"`
+	Bytes    int64         `json:"bytes"`
+	// Processed counts the messages processed by the workers, the received ones still waiting are not
+	Processed        int64         `json:"processed"`
+	Settled          int64         `json:"settled"`
+	SettleFailures   int64         `json:"settle_failures"`
+	ReceiveRate      float64       `json:"receive_rate"`
+	SettleRate       float64       `json:"settle_rate"`
+	MegabytesPerSec  float64       `json:"megabytes_per_second"`
+	Processing       bench.Summary `json:"processing_latency"`
+	Handler          bench.Summary `json:"handler_time"`
+	APIDiscarded     uint64        `json:"api_discarded"`
+	APIRedelivered   uint64        `json:"api_redelivered"`
+	MaxWorkerBacklog int           `json:"max_worker_backlog"`
+}
+
+// received is a message waiting for a worker
+type received struct {
+	message message.InboundMessage
+	at      time.Time
+}
+
+// consumer processes the received messages in a pool of workers
+type consumer struct {
+	config     Config
+	settle     func(message.InboundMessage) error
+	counter    *bench.Counter
+	processing *bench.Latency
+	handler    *bench.Latency
+	backlog    chan received
+	workers    sync.WaitGroup
+
+	processed, settled, settleFailures int64
+	maxBacklog                         int64
+}
+
+func newConsumer(config Config, settle func(message.InboundMessage) error) *consumer {
+	c := &consumer{
+		config:     config,
+		settle:     settle,
+		counter:    bench.NewCounter(),
+		processing: bench.NewLatency(time.Minute),
+		handler:    bench.NewLatency(time.Minute),
+		backlog:    make(chan received, config.Backlog),
+	}
+	for i := 0; i < config.Concurrency; i++ {
+		c.workers.Add(1)
+		go c.work()
+	}
+	return c
+}
+
+// receive is the message handler of the API, it hands the message over to the workers, blocking when all are busy
+// and the backlog is full, which in turn applies back pressure to the API
+func (c *consumer) receive(inbound message.InboundMessage) {
+	size := 0
+	if payload, ok := inbound.GetPayloadAsBytes(); ok {
+		size = len(payload)
+	}
+	c.counter.Add(size)
+	c.backlog <- received{message: inbound, at: time.Now()}
+	if backlog := int64(len(c.backlog)); backlog > atomic.LoadInt64(&c.maxBacklog) {
+		atomic.StoreInt64(&c.maxBacklog, backlog)
+	}
+}
+
+func (c *consumer) work() {
+	defer c.workers.Done()
+	for r := range c.backlog {
+		start := time.Now()
+		delay := c.config.HandlerDelay
+		if c.config.HandlerJitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(c.config.HandlerJitter)))
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		end := time.Now()
+		c.handler.Record(end.Sub(start))
+		c.processing.Record(end.Sub(r.at))
+		atomic.AddInt64(&c.processed, 1)
+
+		if c.settle != nil {
+			if err := c.settle(r.message); err != nil {
+				if atomic.AddInt64(&c.settleFailures, 1) == 1 {
+					fmt.Fprintln(os.Stderr, "Could not acknowledge the message: ", err)
+				}
+			} else {
+				atomic.AddInt64(&c.settled, 1)
+			}
+		}
+	}
+}
+
+// drain waits up to the timeout until the workers processed every message received so far
+func (c *consumer) drain(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if received, _ := c.counter.Total(); atomic.LoadInt64(&c.processed) >= received {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// stop waits for the workers to process the messages handed over
+func (c *consumer) stop() {
+	close(c.backlog)
+	c.workers.Wait()
+}
+
+func main() {
+	topicName := flag.String("topic", "", "topic subscription to receive from with a direct receiver")
+	queueName := flag.String("queue", "", "durable queue to receive from with a persistent receiver, acknowledging every message once processed")
+	handlerDelay := flag.Duration("handler-delay", 0, "time spent processing each message")
+	handlerJitter := flag.Duration("handler-jitter", 0, "random time added to the handler delay, up to this value")
+	concurrency := flag.Int("concurrency", 1, "workers processing the messages")
+	backlog := flag.Int("backlog", 1000, "received messages waiting for a free worker before the API handler blocks")
+	duration := flag.Duration("duration", 10*time.Second, "duration of the run when -count is 0")
+	count := flag.Int64("count", 0, "messages to receive, 0 receives until the duration is over")
+	interval := flag.Duration("interval", time.Second, "period the rates are reported")
+	resultsFile := flag.String("results", "bench-sub-results.json", "file the JSON results are written to, - for stdout")
+	flag.Parse()
+
+	if (*topicName == "") == (*queueName == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of the -topic and -queue flags is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *concurrency < 1 || *backlog < 0 || *count < 0 || (*count == 0 && *duration <= 0) {
+		fmt.Fprintln(os.Stderr, "-concurrency must be positive, -backlog and -count not negative, -duration positive when -count is 0")
+		os.Exit(2)
+	}
+	config := Config{
+		Topic:         *topicName,
+		Queue:         *queueName,
+		HandlerDelay:  *handlerDelay,
+		HandlerJitter: *handlerJitter,
+		Concurrency:   *concurrency,
+		Backlog:       *backlog,
+		Duration:      *duration,
+		Count:         *count,
+	}
+
+	// Configuration parameters, loaded from the secrets source selected with -secrets-source (environment by default)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+	defer messagingService.Disconnect()
+	// The API counters of the run, from before the receiver starts
+	apiMetrics := metricsnap.NewTracker(messagingService.Metrics())
+
+	var receiver solace.LifecycleControl
+	var pause func() error
+	var c *consumer
+	if *queueName != "" {
+		persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+			WithMessageClientAcknowledgement().
+			Build(resource.QueueDurableExclusive(*queueName))
+		if err == nil {
+			err = persistentReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not bind to queue '%s': %s\n", *queueName, err)
+			os.Exit(1)
+		}
+		c = newConsumer(config, persistentReceiver.Ack)
+		err = persistentReceiver.ReceiveAsync(c.receive)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not register the message handler: ", err)
+			os.Exit(1)
+		}
+		receiver, pause = persistentReceiver, persistentReceiver.Pause
+	} else {
+		directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+			WithSubscriptions(resource.TopicSubscriptionOf(*topicName)).
+			Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the direct receiver: ", err)
+			os.Exit(1)
+		}
+		c = newConsumer(config, nil)
+		err = directReceiver.ReceiveAsync(c.receive)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not register the message handler: ", err)
+			os.Exit(1)
+		}
+		receiver = directReceiver
+	}
+
+	fmt.Fprintf(os.Stderr, "Receiving with %d worker(s), handler delay %s (+ up to %s)\n", *concurrency, *handlerDelay, *handlerJitter)
+	start := time.Now()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	var timeout <-chan time.Time
+	if *count == 0 {
+		timeout = time.After(*duration)
+	}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	var lastSettled int64
+	lastTick := start
+run:
+	for {
+		select {
+		case <-interrupt:
+			break run
+		case <-timeout:
+			break run
+		case now := <-ticker.C:
+			messagesPerSecond, bytesPerSecond := c.counter.Interval()
+			received, _ := c.counter.Total()
+			settled := atomic.LoadInt64(&c.settled)
+			settleRate := float64(settled-lastSettled) / now.Sub(lastTick).Seconds()
+			lastSettled, lastTick = settled, now
+			discarded := apiMetrics.Interval().Get(metrics.ReceivedMessagesBackpressureDiscarded).Delta
+			fmt.Fprintf(os.Stderr, "received=%d rate=%.0f msg/s %.2f MB/s settled=%.0f msg/s backlog=%d processing p99=%s discarded=%d\n",
+				received, messagesPerSecond, bytesPerSecond/1e6, settleRate, len(c.backlog), c.processing.Summary().P99, discarded)
+			if *count > 0 && received >= *count {
+				break run
+			}
+		}
+	}
+
+	// Stop the delivery of persistent messages first, the messages handed over to the workers are acknowledged before
+	// the receiver is terminated, an acknowledgement is not possible anymore afterwards
+	if pause != nil {
+		if err := pause(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not pause the receiver: ", err)
+		}
+		c.drain(10 * time.Second)
+	}
+	elapsed := time.Since(start)
+	if err := receiver.Terminate(5 * time.Second); err != nil {
+		fmt.Fprintln(os.Stderr, "Error terminating the receiver: ", err)
+	}
+	c.stop()
+
+	receivedCount, bytes := c.counter.Total()
+	settled := atomic.LoadInt64(&c.settled)
+	run := apiMetrics.Run()
+	results := Results{
+		Config:           config,
+		Start:            start,
+		Elapsed:          elapsed,
+		Received:         receivedCount,
+		Bytes:            bytes,
+		Processed:        atomic.LoadInt64(&c.processed),
+		Settled:          settled,
+		SettleFailures:   atomic.LoadInt64(&c.settleFailures),
+		ReceiveRate:      bench.Rate(receivedCount, elapsed),
+		SettleRate:       bench.Rate(settled, elapsed),
+		MegabytesPerSec:  bench.Rate(bytes, elapsed) / 1e6,
+		Processing:       c.processing.Summary(),
+		Handler:          c.handler.Summary(),
+		APIDiscarded:     run.Get(metrics.ReceivedMessagesBackpressureDiscarded).Delta,
+		APIRedelivered:   run.Get(metrics.PersistentMessagesRedelivered).Delta,
+		MaxWorkerBacklog: int(atomic.LoadInt64(&c.maxBacklog)),
+	}
+
+	fmt.Fprintf(os.Stderr, "\nReceived %d message(s) in %s: %.0f msg/s, %.2f MB/s, settled %.0f msg/s\n",
+		results.Received, elapsed.Round(time.Millisecond), results.ReceiveRate, results.MegabytesPerSec, results.SettleRate)
+	fmt.Fprintf(os.Stderr, "Processing latency: %s\n", results.Processing)
+	fmt.Fprintf(os.Stderr, "Handler time:       %s\n", results.Handler)
+	fmt.Fprintln(os.Stderr, "\nAPI metrics of the run:")
+	run.Render(os.Stderr, true)
+
+	encoded, err := json.MarshalIndent(results, "", "  ")
+	if err == nil {
+		if *resultsFile == "-" {
+			_, err = os.Stdout.Write(append(encoded, '\n'))
+		} else {
+			err = os.WriteFile(*resultsFile, append(encoded, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write the results: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+	if *resultsFile != "-" {
+		fmt.Fprintf(os.Stderr, "Results written to %s\n", *resultsFile)
+	}
+}
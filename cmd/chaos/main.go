@@ -0,0 +1,471 @@
+// Command chaos checks that the API recovers from connection failures: a persistent publisher and a persistent
+// receiver connect to the broker through a local TCP proxy (see internal/chaos) that randomly severs their
+// connections, delays the traffic, or refuses new connections for a while as a broker down would. At the end of the
+// run the command asserts that
+//
+//   - the publisher and the receiver recovered: both services are connected and the receiver is running,
+//   - no acks were lost: every message accepted by the publisher got its publish receipt,
+//   - no messages were lost: every message acknowledged by the broker was received (duplicates are allowed),
+//   - no goroutines leaked: once the services are disconnected, the goroutine count is back to its baseline,
+//
+// and fails with a report otherwise.
+//
+//	go run ./cmd/chaos -duration 2m
+//	go run ./cmd/chaos -duration 10m -fault-interval 5s -faults sever,outage -outage 10s -rate 500
+//
+// The broker address is taken from SOLACE_HOST unless -target is set. The queue is created on start when missing,
+// the messages of earlier runs still spooled on it are ignored.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/chaos"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// tracker follows every message of the run, from its publication to its receipt and its delivery
+type tracker struct {
+	run string
+
+	mu       sync.Mutex
+	pending  map[int64]time.Time
+	acked    map[int64]bool
+	nacked   map[int64]bool
+	received map[int64]int
+	failed   int64
+}
+
+func newTracker(run string) *tracker {
+	return &tracker{
+		run:      run,
+		pending:  map[int64]time.Time{},
+		acked:    map[int64]bool{},
+		nacked:   map[int64]bool{},
+		received: map[int64]int{},
+	}
+}
+
+// id returns the application message ID of the message of the sequence number
+func (t *tracker) id(seq int64) string {
+	return t.run + "-" + strconv.FormatInt(seq, 10)
+}
+
+// seq returns the sequence number of a message of this run, false for the messages of other runs
+func (t *tracker) seq(msg message.InboundMessage) (int64, bool) {
+	id, ok := msg.GetApplicationMessageID()
+	if !ok || !strings.HasPrefix(id, t.run+"-") {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(strings.TrimPrefix(id, t.run+"-"), 10, 64)
+	return seq, err == nil
+}
+
+func (t *tracker) published(seq int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[seq] = time.Now()
+}
+
+func (t *tracker) publishFailed(seq int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, seq)
+	t.failed++
+}
+
+func (t *tracker) receipt(seq int64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, seq)
+	if err != nil {
+		t.nacked[seq] = true
+	} else {
+		t.acked[seq] = true
+	}
+}
+
+func (t *tracker) delivered(seq int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.received[seq]++
+}
+
+// outstanding returns the messages still waiting for their receipt
+func (t *tracker) outstanding() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+// missing returns the sequence numbers acknowledged by the broker but not received, in order
+func (t *tracker) missing() []int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var missing []int64
+	for seq := range t.acked {
+		if t.received[seq] == 0 {
+			missing = append(missing, seq)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
+}
+
+// duplicates returns the number of redelivered messages
+func (t *tracker) duplicates() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	duplicates := 0
+	for _, n := range t.received {
+		duplicates += n - 1
+	}
+	return duplicates
+}
+
+// injector runs the faults through the proxy
+type injector struct {
+	proxy    *chaos.Proxy
+	faults   []string
+	interval time.Duration
+	maxDelay time.Duration
+	outage   time.Duration
+	random   *rand.Rand
+	injected map[string]int
+}
+
+// run injects a fault every interval on average, exponentially distributed, until the context is done
+func (i *injector) run(ctx context.Context) {
+	for {
+		wait := time.Duration(i.random.ExpFloat64() * float64(i.interval))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		fault := i.faults[i.random.Intn(len(i.faults))]
+		i.injected[fault]++
+		switch fault {
+		case "sever":
+			fmt.Printf("%s fault: severed %d connection(s)\n", time.Now().Format(time.TimeOnly), i.proxy.Sever())
+		case "delay":
+			delay := time.Duration(i.random.Int63n(int64(i.maxDelay)) + 1)
+			fmt.Printf("%s fault: delaying the traffic by %s for %s\n", time.Now().Format(time.TimeOnly), delay, i.outage)
+			i.proxy.SetDelay(delay)
+			i.pause(ctx)
+			i.proxy.SetDelay(0)
+		case "outage":
+			fmt.Printf("%s fault: broker down for %s, severed %d connection(s)\n", time.Now().Format(time.TimeOnly), i.outage, i.proxy.Sever())
+			i.proxy.SetRefuse(true)
+			i.pause(ctx)
+			i.proxy.SetRefuse(false)
+		}
+	}
+}
+
+func (i *injector) pause(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(i.outage):
+	}
+}
+
+// proxiedHosts returns the host list pointing at the proxy, the scheme of the broker host is kept
+func proxiedHosts(host, proxy string) string {
+	scheme := "tcp://"
+	if index := strings.Index(host, "://"); index >= 0 {
+		scheme = host[:index+3]
+	}
+	return scheme + proxy
+}
+
+// targetOf returns the address of the first broker of the host list, with the default port of its scheme
+func targetOf(host string) string {
+	first := strings.TrimSpace(strings.Split(host, ",")[0])
+	port := "55555"
+	if strings.HasPrefix(first, "tcps://") {
+		port = "55443"
+	}
+	if index := strings.Index(first, "://"); index >= 0 {
+		first = first[index+3:]
+	}
+	if _, _, err := net.SplitHostPort(first); err != nil {
+		return net.JoinHostPort(first, port)
+	}
+	return first
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	duration := flag.Duration("duration", time.Minute, "duration of the run, faults included")
+	rate := flag.Int("rate", 100, "messages published per second")
+	topicName := flag.String("topic", "solace/samples/chaos", "topic the messages are published on")
+	queueName := flag.String("queue", "chaos-queue", "durable queue receiving the messages, created on start when missing")
+	target := flag.String("target", "", "broker address the proxy forwards to, host:port (default the first host of SOLACE_HOST)")
+	faults := flag.String("faults", "sever,delay,outage", "comma separated faults to inject: sever, delay and outage")
+	faultInterval := flag.Duration("fault-interval", 10*time.Second, "mean interval between faults")
+	maxDelay := flag.Duration("max-delay", 500*time.Millisecond, "highest delay added to the traffic by a delay fault")
+	outage := flag.Duration("outage", 3*time.Second, "duration of the delay and outage faults")
+	retryInterval := flag.Duration("retry-interval", time.Second, "reconnection retry interval of the services")
+	settle := flag.Duration("settle", 30*time.Second, "time to wait for the outstanding receipts and deliveries at the end of the run")
+	goroutineSlack := flag.Int("goroutine-slack", 0, "goroutines allowed above the baseline once disconnected")
+	seed := flag.Int64("seed", 0, "seed of the fault schedule, 0 picks one from the time")
+	flag.Parse()
+
+	injector := &injector{
+		interval: *faultInterval,
+		maxDelay: *maxDelay,
+		outage:   *outage,
+		injected: map[string]int{},
+	}
+	for _, fault := range strings.Split(*faults, ",") {
+		switch fault = strings.TrimSpace(fault); fault {
+		case "sever", "delay", "outage":
+			injector.faults = append(injector.faults, fault)
+		case "":
+		default:
+			fmt.Fprintf(os.Stderr, "unknown fault '%s', expected sever, delay or outage\n", fault)
+			return 2
+		}
+	}
+	if len(injector.faults) == 0 || *faultInterval <= 0 || *maxDelay <= 0 || *rate <= 0 {
+		fmt.Fprintln(os.Stderr, "-faults can not be empty, -fault-interval, -max-delay and -rate must be positive")
+		return 2
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	injector.random = rand.New(rand.NewSource(*seed))
+
+	// Configuration parameters, loaded from the secrets source selected with -secrets-source (environment by default)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		return 1
+	}
+	host, _ := brokerConfig.Properties[config.TransportLayerPropertyHost].(string)
+	if *target == "" {
+		*target = targetOf(host)
+	}
+
+	// The baseline is taken before anything of the run starts, the proxy included
+	baseline := runtime.NumGoroutine()
+
+	proxy, err := chaos.Listen("localhost:0", *target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the proxy: ", err)
+		return 1
+	}
+	injector.proxy = proxy
+	properties := config.ServicePropertyMap{}
+	for property, value := range brokerConfig.Properties {
+		properties[property] = value
+	}
+	properties[config.TransportLayerPropertyHost] = proxiedHosts(host, proxy.Addr())
+	fmt.Printf("Proxying %s to %s, seed %d\n", proxy.Addr(), *target, *seed)
+
+	// One service for the publisher and one for the receiver, both reconnecting forever
+	var interruptions, reconnections int64
+	newService := func(name string) (solace.MessagingService, error) {
+		service, err := messaging.NewMessagingServiceBuilder().
+			FromConfigurationProvider(properties).
+			WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(*retryInterval)).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		service.AddReconnectionListener(func(event solace.ServiceEvent) {
+			atomic.AddInt64(&reconnections, 1)
+			fmt.Printf("%s %s reconnected\n", event.GetTimestamp().Format(time.TimeOnly), name)
+		})
+		service.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+			atomic.AddInt64(&interruptions, 1)
+			fmt.Printf("%s %s interrupted: %v\n", event.GetTimestamp().Format(time.TimeOnly), name, event.GetCause())
+		})
+		return service, service.Connect()
+	}
+	publisherService, err := newService("publisher")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect the publisher service: ", err)
+		return 1
+	}
+	receiverService, err := newService("receiver")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect the receiver service: ", err)
+		publisherService.Disconnect()
+		return 1
+	}
+
+	t := newTracker(strconv.FormatInt(time.Now().UnixNano(), 36))
+
+	receiver, err := receiverService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		WithMissingResourcesCreationStrategy(config.PersistentReceiverCreateOnStartMissingResources).
+		WithSubscriptions(resource.TopicSubscriptionOf(*topicName)).
+		Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = receiver.Start()
+	}
+	if err == nil {
+		err = receiver.ReceiveAsync(func(msg message.InboundMessage) {
+			if seq, ok := t.seq(msg); ok {
+				t.delivered(seq)
+			}
+			receiver.Ack(msg)
+		})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the persistent receiver: ", err)
+		return 1
+	}
+
+	publisher, err := publisherService.CreatePersistentMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err == nil {
+		err = publisher.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the persistent publisher: ", err)
+		return 1
+	}
+	// The sequence number of the message travels as the user context of its receipt
+	publisher.SetMessagePublishReceiptListener(func(receipt solace.PublishReceipt) {
+		if seq, ok := receipt.GetUserContext().(int64); ok {
+			t.receipt(seq, receipt.GetError())
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	injected := make(chan struct{})
+	go func() {
+		defer close(injected)
+		injector.run(ctx)
+	}()
+
+	topic := resource.TopicOf(*topicName)
+	var seq int64
+	ticker := time.NewTicker(time.Second / time.Duration(*rate))
+publish:
+	for ; ; seq++ {
+		select {
+		case <-ctx.Done():
+			break publish
+		case <-ticker.C:
+		}
+		msg, err := publisherService.MessageBuilder().
+			WithApplicationMessageID(t.id(seq)).
+			BuildWithStringPayload(t.id(seq))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not build the message: ", err)
+			return 1
+		}
+		t.published(seq)
+		if err := publisher.Publish(msg, topic, nil, seq); err != nil {
+			t.publishFailed(seq)
+			var illegalState *solace.IllegalStateError
+			if errors.As(err, &illegalState) {
+				fmt.Fprintln(os.Stderr, "Publisher unusable: ", err)
+				break publish
+			}
+		}
+	}
+	ticker.Stop()
+	<-injected
+	// The faults are over, the proxy forwards as is from now on
+	proxy.SetRefuse(false)
+	proxy.SetDelay(0)
+
+	fmt.Printf("Published %d message(s), waiting up to %s for the receipts and deliveries\n", seq, *settle)
+	deadline := time.Now().Add(*settle)
+	for time.Now().Before(deadline) && (t.outstanding() > 0 || len(t.missing()) > 0) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var failures []string
+	if !publisherService.IsConnected() || !receiverService.IsConnected() || !receiver.IsRunning() || !publisher.IsRunning() {
+		failures = append(failures, fmt.Sprintf("not recovered: publisher connected %t running %t, receiver connected %t running %t",
+			publisherService.IsConnected(), publisher.IsRunning(), receiverService.IsConnected(), receiver.IsRunning()))
+	}
+	if n := atomic.LoadInt64(&interruptions); n > 0 {
+		failures = append(failures, fmt.Sprintf("%d service interruption(s), the reconnection gave up", n))
+	}
+	if n := t.outstanding(); n > 0 {
+		failures = append(failures, fmt.Sprintf("%d publish receipt(s) lost", n))
+	}
+	if missing := t.missing(); len(missing) > 0 {
+		shown := missing
+		if len(shown) > 10 {
+			shown = shown[:10]
+		}
+		failures = append(failures, fmt.Sprintf("%d acknowledged message(s) not received, first %v", len(missing), shown))
+	}
+
+	publisher.Terminate(5 * time.Second)
+	receiver.Terminate(5 * time.Second)
+	publisherService.Disconnect()
+	receiverService.Disconnect()
+	proxy.Close()
+	signal.Stop(interrupt)
+	cancel()
+
+	// The API winds down its goroutines asynchronously, give them some time before counting
+	goroutines := runtime.NumGoroutine()
+	for leakDeadline := time.Now().Add(10 * time.Second); goroutines > baseline+*goroutineSlack && time.Now().Before(leakDeadline); {
+		time.Sleep(100 * time.Millisecond)
+		goroutines = runtime.NumGoroutine()
+	}
+	if goroutines > baseline+*goroutineSlack {
+		failures = append(failures, fmt.Sprintf("%d goroutine(s) leaked, %d running against a baseline of %d",
+			goroutines-baseline, goroutines, baseline))
+		pprof.Lookup("goroutine").WriteTo(os.Stderr, 1)
+	}
+
+	t.mu.Lock()
+	fmt.Printf("\nFaults injected: %v, reconnections: %d\n", injector.injected, atomic.LoadInt64(&reconnections))
+	fmt.Printf("Published: %d, failed to publish: %d, acknowledged: %d, rejected by the broker: %d\n",
+		seq, t.failed, len(t.acked), len(t.nacked))
+	fmt.Printf("Received: %d distinct, goroutines: %d (baseline %d)\n", len(t.received), goroutines, baseline)
+	t.mu.Unlock()
+	fmt.Printf("Redelivered: %d\n", t.duplicates())
+
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			fmt.Println("FAIL:", failure)
+		}
+		return 1
+	}
+	fmt.Println("PASS: recovered from every fault, no acks or messages lost, no goroutines leaked")
+	return 0
+}
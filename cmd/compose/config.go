@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role kinds
+const (
+	// Publisher publishes messages to a topic
+	Publisher = "publisher"
+	// Receiver receives the messages of topic subscriptions or of a queue
+	Receiver = "receiver"
+	// Processor receives the messages of topic subscriptions or of a queue and publishes a copy to the output topic
+	Processor = "processor"
+)
+
+// Composition is a composite demo: the roles are started together in one process, sharing the messaging services
+type Composition struct {
+	// Services are the messaging services, by name, the roles connect through; a role without service uses a default
+	// one built from the configuration of the broker
+	Services map[string]Service `yaml:"services"`
+	// Queues are the durable queues of the roles with their topic subscriptions, created before the roles start
+	// when run with -provision (see internal/semp)
+	Queues map[string][]string `yaml:"queues"`
+	Roles  []Role              `yaml:"roles"`
+}
+
+// Service is a messaging service shared by roles
+type Service struct {
+	// Properties override the service properties of the configuration of the broker, e.g.
+	// solace.messaging.client.name or solace.messaging.service.vpn-name
+	Properties map[string]string `yaml:"properties"`
+}
+
+// Role is a pattern run by the composition
+type Role struct {
+	Name string `yaml:"name"`
+	// Kind is publisher, receiver or processor
+	Kind string `yaml:"kind"`
+	// Service is the name of the messaging service of the role, the default one when empty
+	Service string `yaml:"service"`
+	// Replicas is the number of instances of the role, on the same service
+	Replicas int `yaml:"replicas"`
+
+	// Topic a publisher publishes to
+	Topic string `yaml:"topic"`
+	// Mode is how publishers and processors publish: direct (default) or persistent
+	Mode string `yaml:"mode"`
+	// Rate of a publisher, in messages per second per replica, 0 for as fast as possible
+	Rate int `yaml:"rate"`
+	// Count is the number of messages a publisher publishes, 0 until the composition stops
+	Count int64 `yaml:"count"`
+	// Template of the payloads of a publisher, inline or @file, see pkg/payloadgen
+	Template string `yaml:"template"`
+	// Size is the size distribution of the payloads of a publisher, e.g. 1024 or uniform:100-1000
+	Size string `yaml:"size"`
+
+	// Subscriptions are the topic subscriptions of a receiver or a processor
+	Subscriptions []string `yaml:"subscriptions"`
+	// Queue is the durable queue of a receiver or a processor, instead of subscriptions
+	Queue string `yaml:"queue"`
+	// Output is the topic a processor publishes to
+	Output string `yaml:"output"`
+	// Print prints every message received or published
+	Print bool `yaml:"print"`
+	// Delay delays the start of the role, e.g. to start publishing once the receivers are subscribed
+	Delay time.Duration `yaml:"delay"`
+}
+
+// stage is the shutdown stage of the role: the publishers stop first, then the processors, then the receivers, so
+// that the messages in flight are received
+func (r *Role) stage() int {
+	switch r.Kind {
+	case Publisher:
+		return 0
+	case Processor:
+		return 1
+	}
+	return 2
+}
+
+// LoadComposition reads the YAML composition, applies the defaults and checks it
+func LoadComposition(path string) (*Composition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	composition := &Composition{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(composition); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	if len(composition.Roles) == 0 {
+		return nil, fmt.Errorf("%s has no roles", path)
+	}
+	names := map[string]bool{}
+	for i := range composition.Roles {
+		role := &composition.Roles[i]
+		if role.Name == "" {
+			role.Name = fmt.Sprintf("%s-%d", role.Kind, i+1)
+		}
+		if role.Mode == "" {
+			role.Mode = "direct"
+		}
+		if role.Replicas == 0 {
+			role.Replicas = 1
+		}
+		if _, ok := composition.Services[role.Service]; role.Service != "" && !ok {
+			return nil, fmt.Errorf("role %s: unknown service '%s'", role.Name, role.Service)
+		}
+		consumes := len(role.Subscriptions) > 0 || role.Queue != ""
+		switch {
+		case names[role.Name]:
+			return nil, fmt.Errorf("role %s: duplicate name", role.Name)
+		case role.Kind != Publisher && role.Kind != Receiver && role.Kind != Processor:
+			return nil, fmt.Errorf("role %s: unknown kind '%s', expected publisher, receiver or processor", role.Name, role.Kind)
+		case role.Mode != "direct" && role.Mode != "persistent":
+			return nil, fmt.Errorf("role %s: unknown mode '%s', expected direct or persistent", role.Name, role.Mode)
+		case role.Replicas < 0 || role.Rate < 0 || role.Count < 0:
+			return nil, fmt.Errorf("role %s: replicas, rate and count can not be negative", role.Name)
+		case role.Kind == Publisher && role.Topic == "":
+			return nil, fmt.Errorf("role %s: a publisher needs a topic", role.Name)
+		case (role.Kind == Receiver || role.Kind == Processor) && !consumes:
+			return nil, fmt.Errorf("role %s: a %s needs subscriptions or a queue", role.Name, role.Kind)
+		case (role.Kind == Receiver || role.Kind == Processor) && len(role.Subscriptions) > 0 && role.Queue != "":
+			return nil, fmt.Errorf("role %s: subscriptions and queue are exclusive", role.Name)
+		case role.Kind == Processor && role.Output == "":
+			return nil, fmt.Errorf("role %s: a processor needs an output topic", role.Name)
+		}
+		names[role.Name] = true
+	}
+	return composition, nil
+}
@@ -0,0 +1,192 @@
+// Command compose runs several patterns in one process from a YAML composition, e.g. one publisher, three receivers and
+// one processor, to build composite demos: the roles share the messaging services they are given and are shut down in
+// order.
+//
+//	go run ./cmd/compose -f cmd/compose/example.yaml
+//	go run ./cmd/compose -f demo.yaml -duration 2m -report 10s
+//
+// The composition names the messaging services, each one a connection with its own overrides of the configuration of
+// the broker, and the roles: publishers, receivers of topic subscriptions or of a queue, and processors
+// republishing what they receive, each one with a number of replicas on its service (see example.yaml). With
+// -provision the queues of the composition are created first.
+//
+// On interrupt or SIGTERM, after -duration or when a role fails, the publishers are stopped first, then the
+// processors, then the receivers, -drain apart so the messages in flight are received, before the services are
+// disconnected. A processor or a receiver stops by draining: paused, the messages it is handling processed and
+// acknowledged, then terminated (see pkg/lifecycle).
+// The number of messages of every role is reported every -report and once stopped.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// stages is the number of shutdown stages, see Role.stage
+const stages = 3
+
+func main() {
+	file := flag.String("f", "compose.yaml", "YAML composition to run")
+	duration := flag.Duration("duration", 0, "stop after this long (0 until interrupted)")
+	drain := flag.Duration("drain", 1*time.Second, "delay between the shutdown stages")
+	report := flag.Duration("report", 5*time.Second, "interval of the reports (0 for none)")
+	flag.Parse()
+
+	composition, err := LoadComposition(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the composition: ", err)
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	queues := make([]semp.Queue, 0, len(composition.Queues))
+	for name, subscriptions := range composition.Queues {
+		queues = append(queues, semp.Queue{Name: name, Subscriptions: subscriptions})
+	}
+	if err := semp.Provision(context.Background(), queues...); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the queues: ", err)
+		os.Exit(1)
+	}
+
+	// the services used by the roles, connected once and shared by their roles
+	services := map[string]solace.MessagingService{}
+	disconnect := func() {
+		for _, service := range services {
+			service.Disconnect()
+		}
+	}
+	for _, role := range composition.Roles {
+		if _, ok := services[role.Service]; ok {
+			continue
+		}
+		properties := config.ServicePropertyMap{}
+		for name, value := range brokerConfig.Properties {
+			properties[name] = value
+		}
+		for name, value := range composition.Services[role.Service].Properties {
+			properties[config.ServiceProperty(name)] = value
+		}
+		service, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(properties).Build()
+		if err == nil {
+			err = service.Connect()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not connect the service '%s': %s\n", role.Service, err)
+			disconnect()
+			os.Exit(1)
+		}
+		services[role.Service] = service
+	}
+
+	// the instances are started by stage, each stage with its own context so they can be stopped in order
+	var contexts [stages]context.Context
+	var cancels [stages]context.CancelFunc
+	var groups [stages]sync.WaitGroup
+	for stage := range contexts {
+		contexts[stage], cancels[stage] = context.WithCancel(context.Background())
+	}
+	failed := make(chan struct{})
+	var failOnce sync.Once
+	var instances []*instance
+	for n := range composition.Roles {
+		role := &composition.Roles[n]
+		for replica := 1; replica <= role.Replicas; replica++ {
+			i := &instance{role: role, name: role.Name, service: services[role.Service]}
+			if role.Replicas > 1 {
+				i.name = fmt.Sprintf("%s-%d", role.Name, replica)
+			}
+			instances = append(instances, i)
+			stage := role.stage()
+			groups[stage].Add(1)
+			go func() {
+				defer groups[stage].Done()
+				if err := i.run(contexts[stage]); err != nil {
+					fmt.Fprintf(os.Stderr, "[%s] failed: %s\n", i.name, err)
+					failOnce.Do(func() { close(failed) })
+				}
+			}()
+		}
+	}
+	fmt.Printf("Running %d role(s), %d instance(s) on %d service(s)\n", len(composition.Roles), len(instances), len(services))
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, lifecycle.ShutdownSignals...)
+	var deadline <-chan time.Time
+	if *duration > 0 {
+		deadline = time.After(*duration)
+	}
+	var ticks <-chan time.Time
+	if *report > 0 {
+		ticker := time.NewTicker(*report)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	started := time.Now()
+	exitCode := 0
+waitLoop:
+	for {
+		select {
+		case <-ticks:
+			printReport(instances, time.Since(started))
+		case <-interrupted:
+			break waitLoop
+		case <-deadline:
+			break waitLoop
+		case <-failed:
+			exitCode = 1
+			break waitLoop
+		}
+	}
+
+	for stage := range contexts {
+		if stage > 0 {
+			time.Sleep(*drain)
+		}
+		cancels[stage]()
+		groups[stage].Wait()
+	}
+	disconnect()
+	printReport(instances, time.Since(started))
+	os.Exit(exitCode)
+}
+
+// printReport prints the number of messages and the average rate of every role, all its replicas together
+func printReport(instances []*instance, elapsed time.Duration) {
+	totals := map[*Role]int64{}
+	for _, i := range instances {
+		totals[i.role] += i.messages.Load()
+	}
+	roles := make([]*Role, 0, len(totals))
+	for role := range totals {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(a, b int) bool { return roles[a].Name < roles[b].Name })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ROLE\tKIND\tREPLICAS\tMESSAGES\tRATE\t(%s)\n", elapsed.Round(time.Second))
+	for _, role := range roles {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.1f/s\t\n", role.Name, role.Kind, role.Replicas, totals[role],
+			float64(totals[role])/elapsed.Seconds())
+	}
+	w.Flush()
+}
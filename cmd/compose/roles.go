@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/payloadgen"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// instance is a replica of a role, run until its context is done
+type instance struct {
+	role    *Role
+	name    string
+	service solace.MessagingService
+	// messages counts the messages published or received
+	messages atomic.Int64
+}
+
+// run runs the instance until the context is done, or a publisher published its count
+func (i *instance) run(ctx context.Context) error {
+	if i.role.Delay > 0 {
+		select {
+		case <-time.After(i.role.Delay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if i.role.Kind == Publisher {
+		return i.publish(ctx)
+	}
+	return i.consume(ctx)
+}
+
+func (i *instance) print(format string, args ...interface{}) {
+	if i.role.Print {
+		fmt.Printf("[%s] %s\n", i.name, fmt.Sprintf(format, args...))
+	}
+}
+
+// publisher publishes to a topic, it hides the difference between the direct and persistent publishers
+type publisher struct {
+	publish func(msg message.OutboundMessage, topic *resource.Topic) error
+	solace.LifecycleControl
+}
+
+func (i *instance) startPublisher() (*publisher, error) {
+	if i.role.Mode == "persistent" {
+		persistentPublisher, err := i.service.CreatePersistentMessagePublisherBuilder().Build()
+		if err == nil {
+			err = persistentPublisher.Start()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &publisher{func(msg message.OutboundMessage, topic *resource.Topic) error {
+			return persistentPublisher.PublishAwaitAcknowledgement(msg, topic, 10*time.Second, nil)
+		}, persistentPublisher}, nil
+	}
+	directPublisher, err := i.service.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err == nil {
+		err = directPublisher.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &publisher{directPublisher.Publish, directPublisher}, nil
+}
+
+func (i *instance) publish(ctx context.Context) error {
+	var sizes payloadgen.Distribution
+	if i.role.Size != "" {
+		var err error
+		if sizes, err = payloadgen.ParseDistribution(i.role.Size); err != nil {
+			return err
+		}
+	}
+	template, err := payloadgen.Load(i.role.Template)
+	if err != nil {
+		return err
+	}
+	if template == "" && sizes == nil {
+		template = `{"seq":{{.Seq}},"at":"{{now}}"}`
+	}
+	generator, err := payloadgen.New(template, payloadgen.Options{Size: sizes})
+	if err != nil {
+		return err
+	}
+	p, err := i.startPublisher()
+	if err != nil {
+		return err
+	}
+	defer p.Terminate(5 * time.Second)
+
+	topic := resource.TopicOf(i.role.Topic)
+	pacer := bench.NewPacer(i.role.Rate)
+	for i.role.Count == 0 || i.messages.Load() < i.role.Count {
+		if ctx.Err() != nil {
+			return nil
+		}
+		pacer.Wait()
+		payload, err := generator.Next()
+		if err != nil {
+			return err
+		}
+		msg, err := i.service.MessageBuilder().BuildWithByteArrayPayload(payload)
+		if err != nil {
+			return err
+		}
+		if err := p.publish(msg, topic); err != nil {
+			return err
+		}
+		i.messages.Add(1)
+		i.print("published %d bytes to %s", len(payload), i.role.Topic)
+	}
+	return nil
+}
+
+// consume runs a receiver, or a processor publishing a copy of the messages received to its output
+func (i *instance) consume(ctx context.Context) error {
+	var forward *publisher
+	if i.role.Kind == Processor {
+		var err error
+		if forward, err = i.startPublisher(); err != nil {
+			return err
+		}
+		defer forward.Terminate(5 * time.Second)
+	}
+	output := resource.TopicOf(i.role.Output)
+	// handle processes a message, a queue message is acknowledged once it was processed
+	handle := func(msg message.InboundMessage) error {
+		i.messages.Add(1)
+		payload, _ := msg.GetPayloadAsBytes()
+		i.print("received %d bytes on %s", len(payload), msg.GetDestinationName())
+		if forward == nil {
+			return nil
+		}
+		properties := config.MessagePropertyMap{}
+		for name, value := range msg.GetProperties() {
+			properties[config.MessageProperty(name)] = value
+		}
+		copied, err := i.service.MessageBuilder().FromConfigurationProvider(properties).BuildWithByteArrayPayload(payload)
+		if err == nil {
+			err = forward.publish(copied, output)
+		}
+		return err
+	}
+
+	// the receiver is drained once the context is done: paused, its handlers in flight waited for to settle their
+	// messages, then terminated (see pkg/lifecycle)
+	drainer := lifecycle.New(lifecycle.WithGracePeriod(5 * time.Second))
+	if i.role.Queue != "" {
+		queueReceiver, err := i.service.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement().
+			Build(resource.QueueDurableExclusive(i.role.Queue))
+		if err == nil {
+			err = queueReceiver.Start()
+		}
+		if err == nil {
+			err = queueReceiver.ReceiveAsync(drainer.Handler(func(msg message.InboundMessage) {
+				if err := handle(msg); err != nil {
+					// left unacknowledged, the broker redelivers it to the next consumer of the queue
+					fmt.Printf("[%s] could not process a message: %s\n", i.name, err)
+					return
+				}
+				queueReceiver.Ack(msg)
+			}))
+		}
+		if err != nil {
+			return err
+		}
+		drainer.AddReceiver(i.name, queueReceiver)
+	} else {
+		subscriptions := make([]resource.Subscription, len(i.role.Subscriptions))
+		for n, subscription := range i.role.Subscriptions {
+			subscriptions[n] = resource.TopicSubscriptionOf(subscription)
+		}
+		directReceiver, err := i.service.CreateDirectMessageReceiverBuilder().WithSubscriptions(subscriptions...).Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err == nil {
+			err = directReceiver.ReceiveAsync(drainer.Handler(func(msg message.InboundMessage) {
+				if err := handle(msg); err != nil {
+					fmt.Printf("[%s] could not process a message: %s\n", i.name, err)
+				}
+			}))
+		}
+		if err != nil {
+			return err
+		}
+		drainer.AddReceiver(i.name, directReceiver)
+	}
+	<-ctx.Done()
+	report := drainer.Shutdown()
+	if report.Abandoned > 0 {
+		fmt.Printf("[%s] %s\n", i.name, report)
+	}
+	return report.Err
+}
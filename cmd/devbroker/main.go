@@ -0,0 +1,217 @@
+// Command devbroker starts a PubSub+ software broker in a local Docker container, waits until it is ready, provisions
+// the queues and the replay log the samples bind to and prints the settings to run them with. It removes the container
+// on interrupt, unless -detach leaves it running for a later -down.
+//
+//	go run ./cmd/devbroker
+//	go run ./cmd/devbroker -detach -port-offset 10000
+//	go run ./cmd/devbroker -down
+//
+// The Docker daemon is the one of the environment (DOCKER_HOST, DOCKER_CERT_PATH, ...), as for the docker CLI. The
+// broker publishes SMF on 55555, SMF over TLS on 55443, WebSocket on 8008, MQTT on 1883 and SEMP on 8080, each shifted
+// by -port-offset to run next to another broker. It takes a minute or so to start, longer on the first run which pulls
+// the image.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+)
+
+// Ports are the ports of the broker published on the host, by use
+var Ports = map[string]int{
+	"SMF":       55555,
+	"SMF TLS":   55443,
+	"WebSocket": 8008,
+	"MQTT":      1883,
+	"SEMP":      8080,
+}
+
+// AdminUsername and AdminPassword are the credentials of the admin user of the broker, for SEMP
+const (
+	AdminUsername = "admin"
+	AdminPassword = "admin"
+)
+
+func main() {
+	image := flag.String("image", "solace/solace-pubsub-standard:latest", "image of the broker")
+	name := flag.String("name", "solace-samples-broker", "name of the container")
+	portOffset := flag.Int("port-offset", 0, "offset added to the ports published on the host")
+	pull := flag.Bool("pull", false, "pull the image even when it is present")
+	readyTimeout := flag.Duration("ready-timeout", 3*time.Minute, "how long to wait for the broker to be ready")
+	detach := flag.Bool("detach", false, "leave the broker running on exit, remove it later with -down")
+	down := flag.Bool("down", false, "remove the container and exit")
+	flag.Parse()
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not create the Docker client: ", err)
+		os.Exit(1)
+	}
+	defer cli.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *down {
+		err := remove(cli, *name)
+		if errdefs.IsNotFound(err) {
+			fmt.Printf("No container %s to remove\n", *name)
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not remove the broker: ", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s\n", *name)
+		return
+	}
+
+	id, err := start(ctx, cli, *name, *image, *portOffset, *pull)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the broker: ", err)
+		os.Exit(1)
+	}
+	// teardown removes the container, unless detached, on the way out including after a failure
+	teardown := func() {
+		if *detach {
+			return
+		}
+		if err := remove(cli, id); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not remove the broker: ", err)
+			return
+		}
+		fmt.Printf("Removed %s\n", *name)
+	}
+
+	semp := &SEMP{
+		URL:      fmt.Sprintf("http://localhost:%d", Ports["SEMP"]+*portOffset),
+		VPN:      "default",
+		Username: AdminUsername,
+		Password: AdminPassword,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	fmt.Printf("Waiting for %s to be ready ...\n", *name)
+	readyCtx, cancel := context.WithTimeout(ctx, *readyTimeout)
+	err = semp.Ready(readyCtx, 2*time.Second)
+	cancel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Broker not ready: ", err)
+		teardown()
+		os.Exit(1)
+	}
+	if err := semp.Provision(ctx, Queues); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the broker: ", err)
+		teardown()
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%s is ready, provisioned with:\n", *name)
+	for _, queue := range Queues {
+		fmt.Printf("  queue %s for %s\n", queue.Name, queue.Samples)
+	}
+	fmt.Printf("  replay log %s for guaranteed_receiver_replay_checkpoint.go\n", ReplayLog)
+	fmt.Printf("\nRun the samples with:\n\n")
+	fmt.Printf("export SOLACE_HOST=tcp://localhost:%d\n", Ports["SMF"]+*portOffset)
+	fmt.Printf("export SOLACE_VPN=default\n")
+	fmt.Printf("export SOLACE_USERNAME=default\n")
+	fmt.Printf("export SOLACE_PASSWORD=default\n")
+	fmt.Printf("export SOLACE_MQTT_HOST=tcp://localhost:%d\n", Ports["MQTT"]+*portOffset)
+	fmt.Printf("export SOLACE_SEMP_URL=%s\n", semp.URL)
+	fmt.Printf("export SOLACE_SEMP_USERNAME=%s\n", AdminUsername)
+	fmt.Printf("export SOLACE_SEMP_PASSWORD=%s\n", AdminPassword)
+	fmt.Printf("\nThe TLS samples connect to tcps://localhost:%d, the WebSocket ones to ws://localhost:%d, and PubSub+ Manager is at %s\n",
+		Ports["SMF TLS"]+*portOffset, Ports["WebSocket"]+*portOffset, semp.URL)
+
+	if *detach {
+		fmt.Printf("\nRemove the broker with: go run ./cmd/devbroker -down -name %s\n", *name)
+		return
+	}
+	fmt.Println("\nPress Ctrl-C to stop and remove the broker")
+	<-ctx.Done()
+	teardown()
+}
+
+// start creates and starts the container of the broker, pulling the image when missing. A container of the same
+// name left by an earlier -detach run is removed first, so that the broker always starts provisioned from scratch.
+func start(ctx context.Context, cli *client.Client, name, image string, portOffset int, pull bool) (string, error) {
+	if _, _, err := cli.ImageInspectWithRaw(ctx, image); err != nil || pull {
+		if err != nil && !errdefs.IsNotFound(err) {
+			return "", err
+		}
+		fmt.Printf("Pulling %s ...\n", image)
+		progress, err := cli.ImagePull(ctx, image, types.ImagePullOptions{})
+		if err != nil {
+			return "", err
+		}
+		// the pull completes once the progress is read to the end
+		_, err = io.Copy(io.Discard, progress)
+		progress.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := remove(cli, name); err != nil && !errdefs.IsNotFound(err) {
+		return "", err
+	}
+
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
+	for _, port := range Ports {
+		containerPort := nat.Port(strconv.Itoa(port) + "/tcp")
+		exposed[containerPort] = struct{}{}
+		bindings[containerPort] = []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: strconv.Itoa(port + portOffset)}}
+	}
+	created, err := cli.ContainerCreate(ctx,
+		&container.Config{
+			Image: image,
+			Env: []string{
+				"username_admin_globalaccesslevel=admin",
+				"username_admin_password=" + AdminPassword,
+				// the smallest scaling tier, enough for the samples
+				"system_scaling_maxconnectioncount=100",
+			},
+			ExposedPorts: exposed,
+			Labels:       map[string]string{"org.solace.samples": "devbroker"},
+		},
+		&container.HostConfig{
+			PortBindings: bindings,
+			// the broker needs a larger shared memory than the default of Docker, and more open files
+			ShmSize: 1 << 30,
+			Resources: container.Resources{
+				Ulimits: []*units.Ulimit{{Name: "nofile", Soft: 2448, Hard: 1048576}},
+			},
+		},
+		nil, nil, name)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Starting %s (%s) from %s\n", name, created.ID[:12], image)
+	if err := cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		remove(cli, created.ID)
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// remove stops and removes the container, with its volumes. It runs without the context of main, which is done once
+// interrupted.
+func remove(cli *client.Client, container string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return cli.ContainerRemove(ctx, container, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+}
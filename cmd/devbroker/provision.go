@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Queue is a durable queue the samples bind to
+type Queue struct {
+	Name          string
+	Subscriptions []string
+	// Samples are the samples binding to the queue, printed once it is provisioned
+	Samples string
+}
+
+// Queues are the queues provisioned on the broker, the other samples provision theirs or use non-durable ones
+var Queues = []Queue{
+	{Name: "durable-queue", Subscriptions: []string{"solace/samples/persistent/>"},
+		Samples: "guaranteed_receiver_nack.go, guaranteed_receiver_selector_nack.go, guaranteed_receiver_ack_modes.go, termination_listener.go, queue_lag_watcher.go"},
+	{Name: "orders-queue", Subscriptions: []string{"solace/samples/orders/>"}, Samples: "guaranteed_multi_queue_receiver.go"},
+	{Name: "payments-queue", Subscriptions: []string{"solace/samples/payments/>"}, Samples: "guaranteed_multi_queue_receiver.go"},
+	{Name: "audit-queue", Subscriptions: []string{"solace/samples/>"}, Samples: "guaranteed_multi_queue_receiver.go"},
+	{Name: "#DEAD_MSG_QUEUE", Samples: "cmd/dmq-monitor"},
+}
+
+// ReplayLog is the replay log of guaranteed_receiver_replay_checkpoint.go
+const ReplayLog = "samples-replay-log"
+
+// SEMP sends requests to the SEMP v2 config API of the broker
+type SEMP struct {
+	URL      string
+	VPN      string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// sempError is the error part of the SEMP responses
+type sempError struct {
+	Meta struct {
+		ResponseCode int `json:"responseCode"`
+		Error        struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+	} `json:"meta"`
+}
+
+// Ready waits until SEMP answers, the broker takes a minute or so to start
+func (s *SEMP) Ready(ctx context.Context, interval time.Duration) error {
+	for {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.vpnURL(""), nil)
+		if err != nil {
+			return err
+		}
+		request.SetBasicAuth(s.Username, s.Password)
+		response, err := s.Client.Do(request)
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("SEMP is not ready: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Provision sets the client profile up for guaranteed messaging and creates the queues and the replay log. The
+// existing objects are left as they are, so provisioning a running broker again is harmless.
+func (s *SEMP) Provision(ctx context.Context, queues []Queue) error {
+	profile := map[string]interface{}{
+		"allowGuaranteedEndpointCreateEnabled": true,
+		"allowGuaranteedMsgReceiveEnabled":     true,
+		"allowGuaranteedMsgSendEnabled":        true,
+		"allowTransactedSessionsEnabled":       true,
+	}
+	if err := s.send(ctx, http.MethodPatch, s.vpnURL("/clientProfiles/default"), profile); err != nil {
+		return fmt.Errorf("could not update the default client profile: %w", err)
+	}
+	for _, queue := range queues {
+		body := map[string]interface{}{
+			"queueName":        queue.Name,
+			"accessType":       "exclusive",
+			"permission":       "consume",
+			"ingressEnabled":   true,
+			"egressEnabled":    true,
+			"maxMsgSpoolUsage": 500,
+		}
+		if err := s.send(ctx, http.MethodPost, s.vpnURL("/queues"), body); err != nil {
+			return fmt.Errorf("could not create the queue %s: %w", queue.Name, err)
+		}
+		for _, topic := range queue.Subscriptions {
+			subscription := map[string]interface{}{"subscriptionTopic": topic}
+			if err := s.send(ctx, http.MethodPost, s.vpnURL("/queues/"+url.PathEscape(queue.Name)+"/subscriptions"), subscription); err != nil {
+				return fmt.Errorf("could not subscribe the queue %s to %s: %w", queue.Name, topic, err)
+			}
+		}
+	}
+	replayLog := map[string]interface{}{
+		"replayLogName":  ReplayLog,
+		"maxSpoolUsage":  100,
+		"ingressEnabled": true,
+		"egressEnabled":  true,
+	}
+	if err := s.send(ctx, http.MethodPost, s.vpnURL("/replayLogs"), replayLog); err != nil {
+		return fmt.Errorf("could not create the replay log: %w", err)
+	}
+	return nil
+}
+
+func (s *SEMP) vpnURL(path string) string {
+	return s.URL + "/SEMP/v2/config/msgVpns/" + url.PathEscape(s.VPN) + path
+}
+
+// send sends the request, an object that already exists is not an error
+func (s *SEMP) send(ctx context.Context, method, endpoint string, body interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(s.Username, s.Password)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+	var semp sempError
+	if err := json.NewDecoder(response.Body).Decode(&semp); err != nil {
+		return fmt.Errorf("%s %s: %s", method, endpoint, response.Status)
+	}
+	if semp.Meta.Error.Status == "ALREADY_EXISTS" {
+		return nil
+	}
+	return fmt.Errorf("%s %s: %s: %s", method, endpoint, semp.Meta.Error.Status, semp.Meta.Error.Description)
+}
@@ -0,0 +1,172 @@
+// Command dmq-monitor watches dead message queues through the SEMP v2 monitor API of the broker and posts an alert to
+// a webhook when their depth grows beyond a threshold, with the spool metadata of the oldest messages listed from the
+// queue. It alerts again each time the depth grows by another -step messages, and once more when the queue is back
+// within the threshold.
+//
+//	SOLACE_SEMP_URL=http://localhost:8080 SOLACE_SEMP_USERNAME=admin SOLACE_SEMP_PASSWORD=admin \
+//	  go run ./cmd/dmq-monitor -queues '#DEAD_MSG_QUEUE,orders-dmq' -threshold 10 -webhook https://hooks.slack.com/services/...
+//	go run ./cmd/dmq-monitor -queues orders-dmq -threshold 0 -once
+//
+// The alerts are printed, and posted to the webhook as well (see pkg/alerting): a Slack message when its URL is a Slack
+// incoming webhook, or with -format slack, the JSON of the alert otherwise (-format generic).
+//
+// The alerts do not carry the headers of the messages, their topics, application message IDs nor user properties: the
+// messages are listed through SEMP (see pkg/queuemsgs), which only exposes their spool metadata, IDs, spool times,
+// sizes and redelivery counts. Reading the headers takes receiving the messages, and without the queue browser, missing
+// from solace.dev/go/messaging v1.8.0, a receiver consumes them: the monitor would flag the dead messages redelivered,
+// and take them from the tools reprocessing the queue, on every alert. It never binds to the queues.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/alerting"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	"SolaceSamples.com/PubSub+Go/pkg/queuemsgs"
+)
+
+// GrowthEvent is the event of the alerts of the monitor
+const GrowthEvent = "dmq-growth"
+
+// growthAlert is the alert of a queue beyond the threshold, or back within it when resolved. The details hold the
+// depth, the depth of the previous alert of the queue, 0 for the first one, and the spool metadata of the oldest
+// messages.
+func growthAlert(sample queuelag.Sample, vpn string, threshold, previous int64, resolved bool) alerting.Alert {
+	alert := alerting.Alert{
+		Time: sample.Time, Severity: alerting.Warning, Source: sample.Queue, Event: GrowthEvent, Resolved: resolved,
+		Summary: fmt.Sprintf("dead message queue %s (VPN %s) holds %d message(s), above the threshold of %d (was %d)",
+			sample.Queue, vpn, sample.Spooled, threshold, previous),
+		Details: map[string]interface{}{"vpn": vpn, "spooled": sample.Spooled, "threshold": threshold, "previous": previous},
+	}
+	if resolved {
+		alert.Severity = alerting.Info
+		alert.Summary = fmt.Sprintf("dead message queue %s (VPN %s) is back to %d message(s), within the threshold of %d",
+			sample.Queue, vpn, sample.Spooled, threshold)
+	}
+	return alert
+}
+
+// queueState is what was last alerted for a queue
+type queueState struct {
+	firing  bool
+	alerted int64
+}
+
+func main() {
+	queueList := flag.String("queues", "#DEAD_MSG_QUEUE", "comma separated dead message queues to watch")
+	threshold := flag.Int64("threshold", 0, "alert when a queue holds more messages than this")
+	step := flag.Int64("step", 100, "alert again each time the depth grew by this many messages since the last alert, 0 to alert once")
+	interval := flag.Duration("interval", 30*time.Second, "SEMP polling interval")
+	samples := flag.Int("samples", 3, "spool metadata (IDs, spool times, sizes, redelivery counts, not the headers) of the oldest messages included in the alerts, 0 to list none")
+	webhook := flag.String("webhook", sampleconfig.Setting("SOLACE_ALERT_WEBHOOK", ""), "URL the alerts are posted to, they are printed when empty")
+	format := flag.String("format", sampleconfig.Setting("SOLACE_ALERT_FORMAT", ""), "webhook payload: slack or generic, slack for hooks.slack.com URLs by default")
+	once := flag.Bool("once", false, "poll once, alert and exit, e.g. from cron")
+	flag.Parse()
+
+	var queues []string
+	for _, queue := range strings.Split(*queueList, ",") {
+		if queue = strings.TrimSpace(queue); queue != "" {
+			queues = append(queues, queue)
+		}
+	}
+	if len(queues) == 0 {
+		fmt.Fprintln(os.Stderr, "-queues is required")
+		os.Exit(2)
+	}
+	// The alerts are printed, and posted to the webhook when there is one (see pkg/alerting)
+	alerter := alerting.Multi{alerting.NewStdout(os.Stdout)}
+	if *webhook != "" {
+		var options []alerting.WebhookOption
+		switch *format {
+		case "":
+		case "slack":
+			options = append(options, alerting.WithSlack(true))
+		case "generic":
+			options = append(options, alerting.WithSlack(false))
+		default:
+			fmt.Fprintln(os.Stderr, "-format must be slack or generic")
+			os.Exit(2)
+		}
+		alerter = append(alerter, alerting.NewWebhook(*webhook, options...))
+	}
+
+	vpn := sampleconfig.Setting("SOLACE_VPN", "default")
+	semp := queuelag.SEMPConfig{
+		URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+		VPN:      vpn,
+		Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+		Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+	}
+	watcher := queuelag.New(semp, queuelag.Thresholds{}, nil)
+	for _, queue := range queues {
+		watcher.AddQueue(queue)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), lifecycle.ShutdownSignals...)
+	defer cancel()
+
+	states := map[string]*queueState{}
+	for _, queue := range queues {
+		states[queue] = &queueState{}
+	}
+	check := func() {
+		if err := watcher.Poll(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read the queues from SEMP: ", err)
+		}
+		for _, queue := range queues {
+			sample, ok := watcher.Sample(queue)
+			if !ok {
+				continue
+			}
+			state := states[queue]
+			var alert alerting.Alert
+			switch {
+			case sample.Spooled > *threshold && (!state.firing || (*step > 0 && sample.Spooled >= state.alerted+*step)):
+				alert = growthAlert(sample, vpn, *threshold, state.alerted, false)
+				if *samples > 0 {
+					messages, listErr := queuemsgs.List(ctx, semp, queue, *samples)
+					if len(messages) > 0 {
+						alert.Details["spoolMetadata"] = messages
+					}
+					if listErr != nil {
+						alert.Details["listError"] = listErr.Error()
+					}
+				}
+			case sample.Spooled <= *threshold && state.firing:
+				alert = growthAlert(sample, vpn, *threshold, state.alerted, true)
+			default:
+				continue
+			}
+			if err := alerter.Send(ctx, alert); err != nil {
+				// alerted again at the next poll
+				fmt.Fprintln(os.Stderr, "Could not send the alert: ", err)
+				continue
+			}
+			state.firing, state.alerted = !alert.Resolved, sample.Spooled
+		}
+	}
+
+	check()
+	if *once {
+		return
+	}
+	fmt.Printf("Watching %s every %s, alerting above %d message(s)\n", strings.Join(queues, ", "), *interval, *threshold)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Command drain binds to a queue and settles every message it receives as fast as the broker delivers them, to clean
+// up a queue after a demo or in an operational runbook, then reports how many messages were drained by topic.
+//
+//	go run ./cmd/drain -queue durable-queue
+//	go run ./cmd/drain -queue orders-queue -selector "priority = 'low'" -count 1000
+//	go run ./cmd/drain -queue orders-queue -outcome rejected -report 5s
+//
+// With -outcome accepted (default) the messages are removed from the queue, with -outcome rejected the broker moves
+// the ones eligible for the dead message queue there and discards the others. With -selector only the matching
+// messages are delivered and drained, the others stay on the queue. The tool exits once no message arrived for -idle,
+// after -count messages, or on interrupt; the messages delivered beyond -count are left on the queue.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// outcomes are the outcomes the messages can be drained with
+var outcomes = map[string]config.MessageSettlementOutcome{
+	"accepted": config.PersistentReceiverAcceptedOutcome,
+	"rejected": config.PersistentReceiverRejectedOutcome,
+}
+
+// Counts are the counts of a drain
+type Counts struct {
+	// Drained is the number of messages settled
+	Drained int64
+	// Redelivered is the number of drained messages the broker had delivered before
+	Redelivered int64
+	// Errors is the number of messages that could not be settled, they stay on the queue
+	Errors int64
+}
+
+// drainer settles the messages delivered to the receiver and counts them
+type drainer struct {
+	receiver solace.PersistentMessageReceiver
+	outcome  config.MessageSettlementOutcome
+	max      int64
+
+	claimed     atomic.Int64
+	drained     atomic.Int64
+	redelivered atomic.Int64
+	errors      atomic.Int64
+	last        atomic.Int64
+	stopped     atomic.Bool
+
+	mu      sync.Mutex
+	byTopic map[string]int64
+}
+
+// handle is the message handler of the receiver, the messages beyond the max count or delivered once the drain is
+// stopped are not settled
+func (d *drainer) handle(msg message.InboundMessage) {
+	if d.stopped.Load() {
+		return
+	}
+	d.last.Store(time.Now().UnixNano())
+	if d.max > 0 && d.claimed.Add(1) > d.max {
+		return
+	}
+	if err := d.receiver.Settle(msg, d.outcome); err != nil {
+		if d.errors.Add(1) == 1 {
+			fmt.Fprintln(os.Stderr, "Could not settle a message: ", err)
+		}
+		return
+	}
+	d.drained.Add(1)
+	if msg.IsRedelivered() {
+		d.redelivered.Add(1)
+	}
+	d.mu.Lock()
+	d.byTopic[msg.GetDestinationName()]++
+	d.mu.Unlock()
+}
+
+func (d *drainer) counts() Counts {
+	return Counts{Drained: d.drained.Load(), Redelivered: d.redelivered.Load(), Errors: d.errors.Load()}
+}
+
+// done reports whether the drain is complete: the max count was reached, or no message arrived for the idle time
+func (d *drainer) done(started time.Time, idle time.Duration) bool {
+	if d.max > 0 && d.drained.Load()+d.errors.Load() >= d.max {
+		return true
+	}
+	last := started
+	if nanos := d.last.Load(); nanos != 0 {
+		last = time.Unix(0, nanos)
+	}
+	return time.Since(last) >= idle
+}
+
+// report prints the drained messages by topic, the most frequent first
+func (d *drainer) report(w *tabwriter.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	topics := make([]string, 0, len(d.byTopic))
+	for topic := range d.byTopic {
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if d.byTopic[topics[i]] != d.byTopic[topics[j]] {
+			return d.byTopic[topics[i]] > d.byTopic[topics[j]]
+		}
+		return topics[i] < topics[j]
+	})
+	fmt.Fprintln(w, "MESSAGES\tTOPIC")
+	for _, topic := range topics {
+		fmt.Fprintf(w, "%d\t%s\n", d.byTopic[topic], topic)
+	}
+	w.Flush()
+}
+
+func main() {
+	queueName := flag.String("queue", "", "durable queue to drain")
+	selector := flag.String("selector", "", "message selector, only the matching messages are drained")
+	outcomeName := flag.String("outcome", "accepted", "settlement outcome of the drained messages: accepted (removed) or rejected (moved to the dead message queue when eligible)")
+	count := flag.Int64("count", 0, "drain at most this many messages (0 for no limit)")
+	idle := flag.Duration("idle", 2*time.Second, "exit when no message arrived for this long")
+	reportInterval := flag.Duration("report", 0, "print the progress at this interval (0 to only print the summary)")
+	flag.Parse()
+
+	if *queueName == "" {
+		fmt.Fprintln(os.Stderr, "-queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	outcome, ok := outcomes[*outcomeName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown outcome %s, expected accepted or rejected\n", *outcomeName)
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	builder := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement()
+	if outcome != config.PersistentReceiverAcceptedOutcome {
+		builder = builder.WithRequiredMessageOutcomeSupport(outcome)
+	}
+	if *selector != "" {
+		builder = builder.WithMessageSelector(*selector)
+	}
+	persistentReceiver, err := builder.Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	d := &drainer{receiver: persistentReceiver, outcome: outcome, max: *count, byTopic: map[string]int64{}}
+	started := time.Now()
+	if err := persistentReceiver.ReceiveAsync(d.handle); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not receive from the queue: ", err)
+		persistentReceiver.Terminate(0)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	var reported time.Time
+	previous := Counts{}
+wait:
+	for !d.done(started, *idle) {
+		select {
+		case <-interrupted:
+			break wait
+		case now := <-ticker.C:
+			if *reportInterval > 0 && now.Sub(reported) >= *reportInterval {
+				counts := d.counts()
+				if !reported.IsZero() {
+					fmt.Fprintf(os.Stderr, "%d message(s) %s, %.0f msg/s\n", counts.Drained, *outcomeName,
+						float64(counts.Drained-previous.Drained)/now.Sub(reported).Seconds())
+				}
+				reported, previous = now, counts
+			}
+		}
+	}
+
+	// messages delivered while terminating are not settled, they stay on the queue
+	d.stopped.Store(true)
+	persistentReceiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+
+	counts := d.counts()
+	// the rate is the one up to the last message, not counting the idle time
+	elapsed := time.Since(started)
+	if nanos := d.last.Load(); nanos > started.UnixNano() {
+		elapsed = time.Unix(0, nanos).Sub(started)
+	}
+	fmt.Fprintf(os.Stderr, "Drained %d message(s) from %s in %s (%s), %d redelivered, %d not settled, %.0f msg/s\n",
+		counts.Drained, *queueName, elapsed.Round(time.Millisecond), *outcomeName, counts.Redelivered, counts.Errors,
+		float64(counts.Drained)/elapsed.Seconds())
+	if counts.Drained > 0 {
+		d.report(tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0))
+	}
+	if counts.Errors > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keySet is the persistent set of the keys of the messages received: an append-only file, a header naming the kind
+// of key then one key per line, loaded in memory on start
+type keySet struct {
+	file   *os.File
+	writer *bufio.Writer
+	keys   map[string]struct{}
+}
+
+// openKeySet opens the set in the file, creating it when missing. The keys already in the file are passed to loaded,
+// in the order they were added. A file holding another kind of key is refused.
+func openKeySet(path, kind string, loaded func(key string)) (*keySet, error) {
+	header := "# dup-audit key=" + kind
+	s := &keySet{keys: map[string]struct{}{}}
+	if existing, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(existing)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for first := true; scanner.Scan(); first = false {
+			line := scanner.Text()
+			if first {
+				if line != header {
+					existing.Close()
+					return nil, fmt.Errorf("%s holds another kind of key: %s", path, line)
+				}
+				continue
+			}
+			if _, ok := s.keys[line]; !ok && line != "" {
+				s.keys[line] = struct{}{}
+				loaded(line)
+			}
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.file, s.writer = file, bufio.NewWriter(file)
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		fmt.Fprintln(s.writer, header)
+	}
+	return s, nil
+}
+
+// Add adds the key to the set and writes it to the file before returning, it reports false when the key was already
+// in the set
+func (s *keySet) Add(key string) (bool, error) {
+	if _, ok := s.keys[key]; ok {
+		return false, nil
+	}
+	s.keys[key] = struct{}{}
+	fmt.Fprintln(s.writer, key)
+	return true, s.writer.Flush()
+}
+
+// Len returns the number of keys in the set
+func (s *keySet) Len() int {
+	return len(s.keys)
+}
+
+func (s *keySet) Close() error {
+	err := s.writer.Flush()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// gap is a range of sequence numbers not received, from and to included
+type gap struct {
+	from, to int64
+}
+
+func (g gap) String() string {
+	if g.from == g.to {
+		return strconv.FormatInt(g.from, 10)
+	}
+	return fmt.Sprintf("%d-%d", g.from, g.to)
+}
+
+// sequence follows the sequence numbers of a sender: the next number expected and the gaps left behind, which close
+// when the numbers arrive late
+type sequence struct {
+	next int64
+	gaps []gap
+}
+
+// observe records the sequence number, received for the first time
+func (s *sequence) observe(n int64) {
+	if s.next == 0 {
+		// the first number seen starts the sequence, the run may start in the middle of a stream
+		s.next = n + 1
+		return
+	}
+	if n >= s.next {
+		if n > s.next {
+			s.gaps = append(s.gaps, gap{s.next, n - 1})
+		}
+		s.next = n + 1
+		return
+	}
+	for i, g := range s.gaps {
+		if n < g.from || n > g.to {
+			continue
+		}
+		switch {
+		case g.from == g.to:
+			s.gaps = append(s.gaps[:i], s.gaps[i+1:]...)
+		case n == g.from:
+			s.gaps[i].from++
+		case n == g.to:
+			s.gaps[i].to--
+		default:
+			s.gaps = append(s.gaps[:i+1], s.gaps[i:]...)
+			s.gaps[i].to, s.gaps[i+1].from = n-1, n+1
+		}
+		return
+	}
+}
+
+// missing returns the number of sequence numbers in the gaps
+func (s *sequence) missing() int64 {
+	var n int64
+	for _, g := range s.gaps {
+		n += g.to - g.from + 1
+	}
+	return n
+}
+
+// sequences follows the sequence numbers of every sender
+type sequences map[string]*sequence
+
+// observe records the sequence number of the key, sender and number separated by a tab
+func (s sequences) observe(key string) {
+	sender, number, _ := strings.Cut(key, "\t")
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return
+	}
+	seq, ok := s[sender]
+	if !ok {
+		seq = &sequence{}
+		s[sender] = seq
+	}
+	seq.observe(n)
+}
+
+// missing returns the number of sequence numbers in the gaps of all of the senders, and the number of gaps
+func (s sequences) missing() (int64, int) {
+	var missing int64
+	var gaps int
+	for _, seq := range s {
+		missing += seq.missing()
+		gaps += len(seq.gaps)
+	}
+	return missing, gaps
+}
+
+// senders returns the senders having gaps, sorted
+func (s sequences) senders() []string {
+	var senders []string
+	for sender, seq := range s {
+		if len(seq.gaps) > 0 {
+			senders = append(senders, sender)
+		}
+	}
+	sort.Strings(senders)
+	return senders
+}
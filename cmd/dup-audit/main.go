@@ -0,0 +1,263 @@
+// Command dup-audit consumes a queue and audits its deliveries for duplicates and gaps, over long runs and across
+// failovers, for the teams validating their exactly-once assumptions. Every message is identified by its replication
+// group message ID (-key rgmid, the default), by the sequence number set by its publisher (-key seq) or by an
+// application sequence number held in a user property (-key property:<name>). Its key is added to a persistent set,
+// an append-only file (-state) kept across runs, before the message is acknowledged:
+//
+//   - a message whose key is already in the set is a duplicate, flagged as redelivered or not by the broker,
+//   - with sequence numbers, the numbers a sender skipped are gaps, closed when the messages arrive late.
+//
+// The sequence numbers are followed per sender ID, the replication group message IDs have no gaps to follow.
+//
+//	go run ./cmd/dup-audit -queue orders -state orders.audit
+//	go run ./cmd/dup-audit -queue orders -key property:seq -report 1m -duration 24h -provision -subscription 'orders/>'
+//
+// The duplicates are printed as they are found, and a summary every -report interval. The command exits with 1 when
+// it found duplicates or left gaps open. With -provision the queue is created first, subscribed to -subscription.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// keyOf returns the function extracting the key of the messages, sender and sequence number separated by a tab for
+// the kinds of key with a sequence
+func keyOf(kind string) (key func(message.InboundMessage) (string, bool), sequenced bool, err error) {
+	sender := func(msg message.InboundMessage) string {
+		id, _ := msg.GetSenderID()
+		return id
+	}
+	switch {
+	case kind == "rgmid":
+		return func(msg message.InboundMessage) (string, bool) {
+			id, ok := msg.GetReplicationGroupMessageID()
+			if !ok {
+				return "", false
+			}
+			return id.String(), true
+		}, false, nil
+	case kind == "seq":
+		return func(msg message.InboundMessage) (string, bool) {
+			n, ok := msg.GetSequenceNumber()
+			if !ok {
+				return "", false
+			}
+			return sender(msg) + "\t" + strconv.FormatInt(n, 10), true
+		}, true, nil
+	case strings.HasPrefix(kind, "property:") && len(kind) > len("property:"):
+		name := strings.TrimPrefix(kind, "property:")
+		return func(msg message.InboundMessage) (string, bool) {
+			value, ok := msg.GetProperty(name)
+			if !ok {
+				return "", false
+			}
+			n, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+			if err != nil {
+				return "", false
+			}
+			return sender(msg) + "\t" + strconv.FormatInt(n, 10), true
+		}, true, nil
+	}
+	return nil, false, fmt.Errorf("unknown key '%s', expected rgmid, seq or property:<name>", kind)
+}
+
+// audit counts the messages of the run
+type audit struct {
+	received, unkeyed, duplicates, redelivered int64
+}
+
+func main() {
+	queueName := flag.String("queue", "", "queue to consume")
+	kind := flag.String("key", "rgmid", "what identifies the messages: rgmid, seq (the sequence number set by the publisher) or property:<name>")
+	state := flag.String("state", "dup-audit.state", "file holding the keys received, kept across runs")
+	reportEvery := flag.Duration("report", 30*time.Second, "interval of the summaries")
+	duration := flag.Duration("duration", 0, "stop after this long (0 to run until interrupted)")
+	subscription := flag.String("subscription", "", "topic subscription of the queue created with -provision")
+	flag.Parse()
+
+	if *queueName == "" {
+		fmt.Fprintln(os.Stderr, "-queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	key, sequenced, err := keyOf(*kind)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid SOLACE_DRAIN_TIMEOUT: ", err)
+		os.Exit(2)
+	}
+	senders := sequences{}
+	set, err := openKeySet(*state, *kind, func(key string) {
+		if sequenced {
+			senders.observe(key)
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not open the state: ", err)
+		os.Exit(1)
+	}
+	if set.Len() > 0 {
+		fmt.Printf("Loaded %d key(s) from %s\n", set.Len(), *state)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+	queue := semp.Queue{Name: *queueName}
+	if *subscription != "" {
+		queue.Subscriptions = []string{*subscription}
+	}
+	if err := semp.Provision(context.Background(), queue); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the queue: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+	receiver, err := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement().
+		Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = receiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: the message in hand recorded and acknowledged, then
+	// terminated and disconnected (see pkg/lifecycle)
+	drainer := lifecycle.New(lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddReceiver(*queueName, receiver)
+	drainer.AddService(messagingService)
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, lifecycle.ShutdownSignals...)
+	var end <-chan time.Time
+	if *duration > 0 {
+		end = time.After(*duration)
+	}
+	ticker := time.NewTicker(*reportEvery)
+	defer ticker.Stop()
+
+	var counts audit
+	start := time.Now()
+	failed := false
+auditLoop:
+	for {
+		select {
+		case <-interrupted:
+			break auditLoop
+		case <-end:
+			break auditLoop
+		case <-ticker.C:
+			printSummary(&counts, set, senders, time.Since(start))
+		default:
+		}
+		msg, err := receiver.ReceiveMessage(500 * time.Millisecond)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				fmt.Fprintln(os.Stderr, "Receive failed: ", err)
+				failed = true
+				break
+			}
+			continue
+		}
+		counts.received++
+		k, ok := key(msg)
+		if !ok {
+			counts.unkeyed++
+			receiver.Ack(msg)
+			continue
+		}
+		added, err := set.Add(k)
+		if err != nil {
+			// the message is left unacknowledged, it is redelivered to the next run
+			fmt.Fprintln(os.Stderr, "Could not write the state: ", err)
+			failed = true
+			break
+		}
+		if added {
+			if sequenced {
+				senders.observe(k)
+			}
+		} else {
+			counts.duplicates++
+			if msg.IsRedelivered() {
+				counts.redelivered++
+			}
+			destination := msg.GetDestinationName()
+			fmt.Printf("DUPLICATE %s (redelivered: %t, topic: %s)\n", strings.ReplaceAll(k, "\t", " #"), msg.IsRedelivered(), destination)
+		}
+		receiver.Ack(msg)
+	}
+
+	report := drainer.Shutdown()
+	fmt.Fprintln(os.Stderr, "Drain: ", report)
+	if err := set.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write the state: ", err)
+		failed = true
+	}
+
+	fmt.Println("\nFinal summary")
+	printSummary(&counts, set, senders, time.Since(start))
+	for _, sender := range senders.senders() {
+		gaps := senders[sender].gaps
+		var ranges []string
+		for i, g := range gaps {
+			if i == 20 {
+				ranges = append(ranges, fmt.Sprintf("... %d more", len(gaps)-i))
+				break
+			}
+			ranges = append(ranges, g.String())
+		}
+		if sender == "" {
+			sender = "(no sender ID)"
+		}
+		fmt.Printf("  gaps of %s: %s\n", sender, strings.Join(ranges, ", "))
+	}
+	missing, _ := senders.missing()
+	if failed || counts.duplicates > 0 || missing > 0 {
+		os.Exit(1)
+	}
+}
+
+func printSummary(counts *audit, set *keySet, senders sequences, elapsed time.Duration) {
+	missing, gaps := senders.missing()
+	fmt.Printf("%s: received %d, distinct keys %d, duplicates %d (%d redelivered), open gaps %d (%d message(s) missing)",
+		elapsed.Round(time.Second), counts.received, set.Len(), counts.duplicates, counts.redelivered, gaps, missing)
+	if counts.unkeyed > 0 {
+		fmt.Printf(", %d without a key", counts.unkeyed)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// document is the indexed form of a message. A JSON object payload is indexed as an object, for its fields to be
+// searchable, any other payload as text, or base64 when it is not UTF-8.
+type document struct {
+	Timestamp                 time.Time         `json:"@timestamp"`
+	Topic                     string            `json:"topic"`
+	Payload                   json.RawMessage   `json:"payload,omitempty"`
+	PayloadText               string            `json:"payloadText,omitempty"`
+	PayloadBase64             []byte            `json:"payloadBase64,omitempty"`
+	ContentType               string            `json:"contentType,omitempty"`
+	ApplicationMessageID      string            `json:"applicationMessageId,omitempty"`
+	CorrelationID             string            `json:"correlationId,omitempty"`
+	ReplicationGroupMessageID string            `json:"replicationGroupMessageId,omitempty"`
+	Redelivered               bool              `json:"redelivered"`
+	Properties                map[string]string `json:"properties,omitempty"`
+}
+
+func documentOf(inbound message.InboundMessage, received time.Time) document {
+	doc := document{Timestamp: received, Topic: inbound.GetDestinationName(), Redelivered: inbound.IsRedelivered()}
+	payload, _ := inbound.GetPayloadAsBytes()
+	switch trimmed := bytes.TrimSpace(payload); {
+	case len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed):
+		doc.Payload = trimmed
+	case utf8.Valid(payload):
+		doc.PayloadText = string(payload)
+	default:
+		doc.PayloadBase64 = payload
+	}
+	doc.ContentType, _ = inbound.GetHTTPContentType()
+	doc.ApplicationMessageID, _ = inbound.GetApplicationMessageID()
+	doc.CorrelationID, _ = inbound.GetCorrelationID()
+	if id, ok := inbound.GetReplicationGroupMessageID(); ok && id != nil {
+		doc.ReplicationGroupMessageID = id.String()
+	}
+	if properties := inbound.GetProperties(); len(properties) > 0 {
+		doc.Properties = make(map[string]string, len(properties))
+		for name, value := range properties {
+			doc.Properties[name] = fmt.Sprint(value)
+		}
+	}
+	return doc
+}
+
+// appendAction appends the bulk index action of the document and the document to the body. The replication group
+// message ID is the document ID, a redelivered message overwrites its document rather than being indexed twice.
+func appendAction(body *bytes.Buffer, index string, doc document) error {
+	source, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	meta := map[string]string{"_index": index}
+	if doc.ReplicationGroupMessageID != "" {
+		meta["_id"] = doc.ReplicationGroupMessageID
+	}
+	action, err := json.Marshal(map[string]interface{}{"index": meta})
+	if err != nil {
+		return err
+	}
+	body.Write(action)
+	body.WriteByte('\n')
+	body.Write(source)
+	body.WriteByte('\n')
+	return nil
+}
+
+// bulkResponse is the part of the response of the _bulk API the indexer reads, one item per action, in order
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// result is the outcome of an action: its status and the error of the item
+type result struct {
+	status int
+	err    error
+}
+
+// outcome maps the status of an item to the settlement of its message: a throttled (429) or server error is FAILED
+// and redelivered, any other error, e.g. a mapping error, would fail again and is REJECTED
+func (r result) outcome() config.MessageSettlementOutcome {
+	switch {
+	case r.status >= 200 && r.status < 300:
+		return config.PersistentReceiverAcceptedOutcome
+	case r.status == http.StatusTooManyRequests || r.status >= 500:
+		return config.PersistentReceiverFailedOutcome
+	default:
+		return config.PersistentReceiverRejectedOutcome
+	}
+}
+
+// statusError is a _bulk request failing as a whole
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("bulk request failed: %d %s: %s", e.status, http.StatusText(e.status), e.body)
+}
+
+// throttled reports whether the bulk request was refused because the cluster is overloaded
+func throttled(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.status == http.StatusTooManyRequests
+}
+
+// bulk sends the actions of the body and returns the result of each of the n actions
+func (i *indexer) bulk(body []byte, n int) ([]result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(i.url, "/")+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	if i.username != "" {
+		request.SetBasicAuth(i.username, i.password)
+	}
+	response, err := i.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		text, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, &statusError{status: response.StatusCode, body: strings.TrimSpace(string(text))}
+	}
+	var decoded bulkResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("could not decode the bulk response: %w", err)
+	}
+	if len(decoded.Items) != n {
+		return nil, fmt.Errorf("bulk response has %d item(s), expected %d", len(decoded.Items), n)
+	}
+	results := make([]result, n)
+	for k, item := range decoded.Items {
+		// a single operation per item, "index"
+		for _, operation := range item {
+			results[k].status = operation.Status
+			if operation.Error != nil {
+				results[k].err = fmt.Errorf("%s: %s", operation.Error.Type, operation.Error.Reason)
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// maxBackoff caps the wait after a throttled bulk request
+const maxBackoff = 30 * time.Second
+
+// Counts are the messages settled and the bulk requests sent since the start
+type Counts struct {
+	Accepted  int64
+	Failed    int64
+	Rejected  int64
+	Requests  int64
+	Throttled int64
+}
+
+// pending is a message waiting in the current batch
+type pending struct {
+	inbound  message.InboundMessage
+	received time.Time
+}
+
+// indexer batches the messages of a queue into _bulk requests and settles each message with the result of its item
+type indexer struct {
+	client   *http.Client
+	url      string
+	index    string
+	username string
+	password string
+	timeout  time.Duration
+
+	maxDocs  int
+	maxBytes int
+	maxAge   time.Duration
+
+	queueName string
+	receiver  solace.PersistentMessageReceiver
+	audit     *settleaudit.Log
+
+	// mu serializes the messages and the bulk requests: the handler blocks during a request and its backoff, which
+	// holds the delivery of the next messages while the cluster catches up
+	mu       sync.Mutex
+	messages []pending
+	body     bytes.Buffer
+	opened   time.Time
+	// limit is the current max documents of a batch: halved when the cluster throttles, grown back on success
+	limit   int
+	backoff time.Duration
+	totals  Counts
+}
+
+// handle adds the message to the current batch and sends the batch once it is full
+func (i *indexer) handle(inbound message.InboundMessage) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	received := time.Now()
+	if err := appendAction(&i.body, i.index, documentOf(inbound, received)); err != nil {
+		i.settle(inbound, received, config.PersistentReceiverRejectedOutcome, err)
+		return
+	}
+	if len(i.messages) == 0 {
+		i.opened = received
+	}
+	i.messages = append(i.messages, pending{inbound: inbound, received: received})
+	if len(i.messages) >= i.limit || i.body.Len() >= i.maxBytes {
+		i.flushLocked()
+	}
+}
+
+// flushIfDue sends the current batch once its first message waited the max age, called periodically
+func (i *indexer) flushIfDue() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.messages) > 0 && time.Since(i.opened) >= i.maxAge {
+		i.flushLocked()
+	}
+}
+
+// flush sends the current batch whatever its size and age, e.g. on exit
+func (i *indexer) flush() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.flushLocked()
+}
+
+func (i *indexer) flushLocked() {
+	messages := i.messages
+	if len(messages) == 0 {
+		return
+	}
+	i.totals.Requests++
+	results, err := i.bulk(i.body.Bytes(), len(messages))
+	i.messages = nil
+	i.body.Reset()
+	if err != nil {
+		// the request failed as a whole, e.g. the cluster is unreachable or overloaded: all the messages are FAILED
+		// and redelivered
+		fmt.Fprintf(os.Stderr, "Bulk request of %d document(s) failed: %s\n", len(messages), err)
+		results = make([]result, len(messages))
+		for k := range results {
+			results[k] = result{status: http.StatusServiceUnavailable, err: err}
+			if throttled(err) {
+				results[k].status = http.StatusTooManyRequests
+			}
+		}
+	}
+	throttledItems := 0
+	for k, msg := range messages {
+		if results[k].status == http.StatusTooManyRequests {
+			throttledItems++
+		}
+		i.settle(msg.inbound, msg.received, results[k].outcome(), results[k].err)
+	}
+
+	if throttledItems > 0 || err != nil {
+		// back-pressure: smaller batches when the cluster throttles, and a wait growing while the requests fail
+		if throttledItems > 0 {
+			i.totals.Throttled++
+			i.limit = max(i.limit/2, 1)
+		}
+		i.backoff = min(max(2*i.backoff, 500*time.Millisecond), maxBackoff)
+		fmt.Fprintf(os.Stderr, "%d document(s) throttled, waiting %s before the next batch, of %d document(s) at most\n",
+			throttledItems, i.backoff, i.limit)
+		time.Sleep(i.backoff)
+		return
+	}
+	i.backoff = 0
+	i.limit = min(i.limit+max(i.limit/10, 1), i.maxDocs)
+}
+
+func (i *indexer) settle(inbound message.InboundMessage, received time.Time, outcome config.MessageSettlementOutcome, reason error) {
+	switch outcome {
+	case config.PersistentReceiverAcceptedOutcome:
+		i.totals.Accepted++
+	case config.PersistentReceiverFailedOutcome:
+		i.totals.Failed++
+	default:
+		i.totals.Rejected++
+		fmt.Fprintf(os.Stderr, "Rejected a message of %s: %s\n", inbound.GetDestinationName(), reason)
+	}
+	if err := i.audit.Settle(i.queueName, i.receiver, inbound, outcome, received, reason); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not settle the message: ", err)
+	}
+}
+
+func (i *indexer) counts() Counts {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.totals
+}
@@ -0,0 +1,168 @@
+// Command es-indexer consumes a queue into an Elasticsearch or OpenSearch index: the payload and the metadata of every
+// message are indexed as a document, in batches sent to the _bulk API, which both serve the same way. A JSON object
+// payload is indexed as an object, so its fields are searchable, any other payload as text. The replication group
+// message ID is the document ID, a redelivered message overwrites its document instead of being indexed twice.
+//
+//	go run ./cmd/es-indexer -queue orders -index solace-orders
+//	SOLACE_ELASTICSEARCH_URL=https://search.example.com:9200 SOLACE_ELASTICSEARCH_USERNAME=elastic SOLACE_ELASTICSEARCH_PASSWORD=changeme \
+//		go run ./cmd/es-indexer -queue orders -batch 1000 -flush-interval 2s
+//
+// A batch is sent once it holds -batch documents or -batch-bytes bytes, or once its first message waited
+// -flush-interval, and on exit. Every message is then settled with the result of its item in the bulk response:
+//
+//   - ACCEPTED when the document is indexed
+//   - FAILED when the item was throttled (429) or hit a server error, the broker redelivers the message
+//   - REJECTED on any other error, e.g. a mapping error, which would fail again: the broker moves the message to the
+//     dead message queue when it is eligible and discards it otherwise
+//
+// A request failing as a whole, e.g. the cluster being unreachable, fails all its messages. The indexer backs off when
+// the cluster pushes back: the batches are halved whenever items are throttled, then grow back by a tenth on every
+// successful request, and the next request waits from 500ms to 30s while the requests keep failing. The messages are
+// not delivered meanwhile, they stay on the queue. With SOLACE_AUDIT_FILE every settlement is recorded with its reason
+// (see pkg/settleaudit), and with -provision the queue is created through SEMP first.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+func main() {
+	queueName := flag.String("queue", "", "durable queue to index")
+	url := flag.String("url", sampleconfig.Setting("SOLACE_ELASTICSEARCH_URL", "http://localhost:9200"), "URL of the Elasticsearch or OpenSearch cluster (or SOLACE_ELASTICSEARCH_URL)")
+	index := flag.String("index", "solace-messages", "index the documents are written to")
+	batchSize := flag.Int("batch", 500, "send a batch once it holds this many documents")
+	batchBytes := flag.Int("batch-bytes", 5<<20, "send a batch once its request takes this many bytes")
+	flushInterval := flag.Duration("flush-interval", 1*time.Second, "send a batch once its first message waited this long")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of a bulk request")
+	flag.Parse()
+
+	if *queueName == "" {
+		fmt.Fprintln(os.Stderr, "-queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *batchSize <= 0 || *batchBytes <= 0 || *flushInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "-batch, -batch-bytes and -flush-interval must be positive")
+		os.Exit(2)
+	}
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid SOLACE_DRAIN_TIMEOUT: ", err)
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp), the
+	// failed messages are redelivered 3 times before they are moved to the dead message queue
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName, MaxRedeliveryCount: 3}); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the queue: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		WithRequiredMessageOutcomeSupport(config.PersistentReceiverFailedOutcome, config.PersistentReceiverRejectedOutcome).
+		Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	// Settlement audit log, every settlement is appended as a JSON line to SOLACE_AUDIT_FILE when it is set
+	var audit *settleaudit.Log
+	if path := sampleconfig.Setting("SOLACE_AUDIT_FILE", ""); path != "" {
+		if audit, err = settleaudit.Open(settleaudit.Options{Path: path}); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not open the audit file: ", err)
+			persistentReceiver.Terminate(0)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		defer audit.Close()
+	}
+
+	i := &indexer{
+		client:    &http.Client{},
+		url:       *url,
+		index:     *index,
+		username:  sampleconfig.Setting("SOLACE_ELASTICSEARCH_USERNAME", ""),
+		password:  sampleconfig.Setting("SOLACE_ELASTICSEARCH_PASSWORD", ""),
+		timeout:   *timeout,
+		maxDocs:   *batchSize,
+		maxBytes:  *batchBytes,
+		maxAge:    *flushInterval,
+		queueName: *queueName,
+		receiver:  persistentReceiver,
+		audit:     audit,
+		limit:     *batchSize,
+	}
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: paused, the last batch indexed and settled, then
+	// terminated and disconnected (see pkg/lifecycle)
+	drainer := lifecycle.New(lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddReceiver(*queueName, persistentReceiver)
+	drainer.AddService(messagingService)
+	if err := persistentReceiver.ReceiveAsync(drainer.Handler(i.handle)); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not receive from the queue: ", err)
+		persistentReceiver.Terminate(0)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Indexing %s into %s/%s\n", *queueName, *url, *index)
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, lifecycle.ShutdownSignals...)
+	// the age of the batch is checked 10 times per -flush-interval
+	ticker := time.NewTicker(max(*flushInterval/10, 10*time.Millisecond))
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-interrupted:
+			break wait
+		case <-ticker.C:
+			i.flushIfDue()
+		}
+	}
+
+	// no more deliveries, the last batch is indexed and settled before the receiver terminates
+	persistentReceiver.Pause()
+	i.flush()
+	report := drainer.Shutdown()
+	fmt.Fprintln(os.Stderr, "Drain: ", report)
+	counts := i.counts()
+	fmt.Fprintf(os.Stderr, "%d accepted, %d failed, %d rejected, %d bulk request(s), %d throttled\n",
+		counts.Accepted, counts.Failed, counts.Rejected, counts.Requests, counts.Throttled)
+}
@@ -0,0 +1,368 @@
+// Command loadgen runs a declarative load profile against a broker, for capacity testing: the phases of the YAML
+// profile, steady, ramp or burst, are run one after the other, each one publishing at its target rate, with its
+// payload sizes and number of concurrent publishers, then the statistics of every phase are reported.
+//
+//	go run ./cmd/loadgen -profile cmd/loadgen/example.yaml
+//	go run ./cmd/loadgen -profile capacity.yaml -mode persistent -json results.json
+//
+// A profile (see Profile and Phase, and example.yaml):
+//
+//	topic: solace/samples/load
+//	mode: persistent
+//	template: '{"orderId":"{{uuid}}","amount":{{amount 5 500}}}'
+//	phases:
+//	  - {name: warm-up, type: ramp, from: 100, rate: 2000, duration: 30s, size: 512, concurrency: 2}
+//	  - {name: steady, rate: 2000, duration: 1m, size: uniform:256-4096, concurrency: 4}
+//	  - {name: burst, type: burst, duration: 10s, concurrency: 8}
+//
+// The payloads are generated from the template with the sizes of the phase (see pkg/payloadgen). The achieved rate of
+// a phase falls short of its target when the publishers or the broker can not keep up; the back pressure shows in the
+// publish latency, and in persistent mode in the ack latency.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/payloadgen"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Result holds the statistics of a phase
+type Result struct {
+	Phase        string        `json:"phase"`
+	Type         string        `json:"type"`
+	Target       float64       `json:"target_rate"`
+	Elapsed      time.Duration `json:"elapsed_ns"`
+	Published    int64         `json:"published"`
+	Bytes        int64         `json:"bytes"`
+	Rate         float64       `json:"rate"`
+	Rejected     int64         `json:"rejected"`
+	Failed       int64         `json:"failed"`
+	Acknowledged int64         `json:"acknowledged,omitempty"`
+	Nacked       int64         `json:"nacked,omitempty"`
+	Publish      bench.Summary `json:"publish_latency"`
+	Ack          bench.Summary `json:"ack_latency"`
+}
+
+// stats are collected by the publishers of a phase
+type stats struct {
+	counter                    *bench.Counter
+	publishLatency, ackLatency *bench.Latency
+	rejected, failed           atomic.Int64
+	acknowledged, nacked       atomic.Int64
+	outstanding                sync.WaitGroup
+	generator                  *payloadgen.Generator
+	persistent                 bool
+	topic                      *resource.Topic
+	stop                       <-chan struct{}
+}
+
+// publishFunc publishes a message, hiding the difference between the direct and persistent publishers
+type publishFunc func(msg message.OutboundMessage) error
+
+// startPublisher starts a publisher of the phase, persistent publishers count their acknowledgements in the stats
+func startPublisher(messagingService solace.MessagingService, s *stats, buffer uint) (publishFunc, solace.LifecycleControl, error) {
+	if !s.persistent {
+		directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(buffer).Build()
+		if err == nil {
+			err = directPublisher.Start()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(msg message.OutboundMessage) error { return directPublisher.Publish(msg, s.topic) }, directPublisher, nil
+	}
+	persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().OnBackPressureWait(buffer).Build()
+	if err == nil {
+		err = persistentPublisher.Start()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// The publish time travels with the message as the user context of its receipt
+	persistentPublisher.SetMessagePublishReceiptListener(func(receipt solace.PublishReceipt) {
+		if sent, ok := receipt.GetUserContext().(time.Time); ok {
+			s.ackLatency.Record(time.Since(sent))
+		}
+		if receipt.GetError() != nil {
+			s.nacked.Add(1)
+		} else {
+			s.acknowledged.Add(1)
+		}
+		s.outstanding.Done()
+	})
+	publish := func(msg message.OutboundMessage) error {
+		s.outstanding.Add(1)
+		if err := persistentPublisher.Publish(msg, s.topic, nil, time.Now()); err != nil {
+			s.outstanding.Done()
+			return err
+		}
+		return nil
+	}
+	return publish, persistentPublisher, nil
+}
+
+// work publishes the share of the phase rate of a publisher until the end of the phase
+func work(phase *Phase, s *stats, messageBuilder solace.OutboundMessageBuilder, publish publishFunc, share float64, start, end time.Time) {
+	next := start
+	for {
+		now := time.Now()
+		if !now.Before(end) {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		rate := phase.rateAt(now.Sub(start)) * share
+		if rate > 0 {
+			if wait := next.Sub(now); wait > 0 {
+				if wait > end.Sub(now) {
+					wait = end.Sub(now)
+				}
+				time.Sleep(wait)
+				continue
+			}
+			// the schedule is not caught up by more than a second of messages, e.g. after a pause of the broker
+			if next = next.Add(time.Duration(float64(time.Second) / rate)); next.Before(now.Add(-time.Second)) {
+				next = now
+			}
+		} else if phase.Type != Burst {
+			// a ramp starting from 0
+			time.Sleep(10 * time.Millisecond)
+			next = time.Now()
+			continue
+		}
+
+		payload, err := s.generator.Next()
+		var msg message.OutboundMessage
+		if err == nil {
+			msg, err = messageBuilder.BuildWithByteArrayPayload(payload)
+		}
+		if err != nil {
+			if s.failed.Add(1) == 1 {
+				fmt.Fprintln(os.Stderr, "Could not build the message: ", err)
+			}
+			continue
+		}
+		before := time.Now()
+		err = publish(msg)
+		s.publishLatency.Record(time.Since(before))
+		var overflow *solace.PublisherOverflowError
+		switch {
+		case err == nil:
+			s.counter.Add(len(payload))
+		case errors.As(err, &overflow):
+			s.rejected.Add(1)
+		default:
+			if s.failed.Add(1) == 1 {
+				fmt.Fprintln(os.Stderr, "Publish failed: ", err)
+			}
+		}
+	}
+}
+
+// runPhase runs the publishers of the phase and returns its statistics once the persistent messages are acknowledged
+func runPhase(messagingService solace.MessagingService, profile *Profile, phase *Phase, template string, ackTimeout time.Duration, stop <-chan struct{}) (Result, error) {
+	generator, err := payloadgen.New(template, payloadgen.Options{Size: phase.sizes, Seed: profile.Seed})
+	if err != nil {
+		return Result{}, err
+	}
+	s := &stats{
+		counter:        bench.NewCounter(),
+		publishLatency: bench.NewLatency(time.Minute),
+		ackLatency:     bench.NewLatency(time.Minute),
+		generator:      generator,
+		persistent:     profile.Mode == "persistent",
+		topic:          resource.TopicOf(phase.Topic),
+		stop:           stop,
+	}
+
+	publishers := make([]solace.LifecycleControl, 0, phase.Concurrency)
+	defer func() {
+		for _, publisher := range publishers {
+			publisher.Terminate(5 * time.Second)
+		}
+	}()
+	publishes := make([]publishFunc, 0, phase.Concurrency)
+	for i := 0; i < phase.Concurrency; i++ {
+		publish, publisher, err := startPublisher(messagingService, s, profile.Buffer)
+		if err != nil {
+			return Result{}, fmt.Errorf("could not start the publishers: %w", err)
+		}
+		publishers = append(publishers, publisher)
+		publishes = append(publishes, publish)
+	}
+
+	start := time.Now()
+	end := start.Add(phase.Duration)
+	var workers sync.WaitGroup
+	for _, publish := range publishes {
+		workers.Add(1)
+		go func(publish publishFunc) {
+			defer workers.Done()
+			work(phase, s, messagingService.MessageBuilder(), publish, 1/float64(phase.Concurrency), start, end)
+		}(publish)
+	}
+	workers.Wait()
+	elapsed := time.Since(start)
+
+	if s.persistent {
+		acked := make(chan struct{})
+		go func() {
+			s.outstanding.Wait()
+			close(acked)
+		}()
+		select {
+		case <-acked:
+		case <-time.After(ackTimeout):
+			fmt.Fprintf(os.Stderr, "Timed out waiting for the acknowledgement of the messages of %s\n", phase.Name)
+		}
+	}
+
+	published, bytes := s.counter.Total()
+	result := Result{
+		Phase: phase.Name, Type: phase.Type, Target: phase.Rate, Elapsed: elapsed,
+		Published: published, Bytes: bytes, Rate: bench.Rate(published, elapsed),
+		Rejected: s.rejected.Load(), Failed: s.failed.Load(),
+		Publish: s.publishLatency.Summary(),
+	}
+	if s.persistent {
+		result.Acknowledged, result.Nacked = s.acknowledged.Load(), s.nacked.Load()
+		result.Ack = s.ackLatency.Summary()
+	}
+	return result, nil
+}
+
+// report prints the results of the phases as a table
+func report(results []Result, persistent bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "PHASE\tTYPE\tTARGET\tACHIEVED\tPUBLISHED\tMB\tREJECTED\tFAILED\tPUBLISH P50\tPUBLISH P99")
+	if persistent {
+		fmt.Fprint(w, "\tACKED\tNACKED\tACK P50\tACK P99")
+	}
+	fmt.Fprintln(w)
+	for _, r := range results {
+		target := fmt.Sprintf("%.0f/s", r.Target)
+		if r.Type == Burst && r.Target == 0 {
+			target = "max"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.0f/s\t%d\t%.2f\t%d\t%d\t%s\t%s", r.Phase, r.Type, target, r.Rate, r.Published,
+			float64(r.Bytes)/1e6, r.Rejected, r.Failed, r.Publish.P50, r.Publish.P99)
+		if persistent {
+			fmt.Fprintf(w, "\t%d\t%d\t%s\t%s", r.Acknowledged, r.Nacked, r.Ack.P50, r.Ack.P99)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func main() {
+	profilePath := flag.String("profile", "", "YAML load profile to run (required)")
+	mode := flag.String("mode", "", "delivery mode overriding the one of the profile: direct or persistent")
+	jsonPath := flag.String("json", "", "also write the results of the phases to this JSON file")
+	ackTimeout := flag.Duration("ack-timeout", 30*time.Second, "time to wait for the acknowledgements of the persistent messages at the end of a phase")
+	flag.Parse()
+
+	if *profilePath == "" {
+		fmt.Fprintln(os.Stderr, "the -profile flag is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	profile, err := LoadProfile(*profilePath)
+	if err == nil && *mode != "" {
+		if *mode != "direct" && *mode != "persistent" {
+			err = fmt.Errorf("unknown mode '%s', expected direct or persistent", *mode)
+		}
+		profile.Mode = *mode
+	}
+	var template string
+	if err == nil {
+		template, err = payloadgen.Load(profile.Template)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid profile: ", err)
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	stop := make(chan struct{})
+	go func() {
+		<-interrupted
+		close(stop)
+	}()
+
+	var results []Result
+	var runErr error
+phases:
+	for i := range profile.Phases {
+		phase := &profile.Phases[i]
+		fmt.Printf("Phase %s: %s %s publishing on %s for %s with %d publisher(s)\n", phase.Name, phase.Type, profile.Mode,
+			phase.Topic, phase.Duration, phase.Concurrency)
+		result, err := runPhase(messagingService, profile, phase, template, *ackTimeout, stop)
+		if err != nil {
+			runErr = fmt.Errorf("phase %s: %w", phase.Name, err)
+			break
+		}
+		results = append(results, result)
+		fmt.Printf("  published %d message(s) at %.0f msg/s, publish latency %s\n", result.Published, result.Rate, result.Publish)
+		select {
+		case <-stop:
+			break phases
+		default:
+		}
+	}
+	messagingService.Disconnect()
+
+	if len(results) > 0 {
+		fmt.Println()
+		report(results, profile.Mode == "persistent")
+	}
+	if *jsonPath != "" && len(results) > 0 {
+		encoded, err := json.MarshalIndent(results, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*jsonPath, append(encoded, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write the results: ", err)
+		}
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, runErr)
+		os.Exit(1)
+	}
+}
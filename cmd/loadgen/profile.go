@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/payloadgen"
+	"gopkg.in/yaml.v3"
+)
+
+// Phase types
+const (
+	// Steady publishes at the rate for the duration
+	Steady = "steady"
+	// Ramp publishes at a rate rising, or falling, linearly from the from rate to the rate over the duration
+	Ramp = "ramp"
+	// Burst publishes as fast as possible for the duration, at most at the rate when it is set
+	Burst = "burst"
+)
+
+// Profile is a load profile, the phases are run one after the other
+type Profile struct {
+	// Topic the messages are published on, the phases may override it
+	Topic string `yaml:"topic"`
+	// Mode is the delivery mode: direct (default) or persistent
+	Mode string `yaml:"mode"`
+	// Template of the payloads, inline or @file, see pkg/payloadgen; the payloads are random text without template
+	Template string `yaml:"template"`
+	// Seed of the generated payloads, 0 for a different run every time
+	Seed int64 `yaml:"seed"`
+	// Buffer is the size of the publisher buffers, in messages
+	Buffer uint    `yaml:"buffer"`
+	Phases []Phase `yaml:"phases"`
+}
+
+// Phase is a phase of a load profile
+type Phase struct {
+	Name string `yaml:"name"`
+	// Type is steady (default), ramp or burst
+	Type string `yaml:"type"`
+	// Rate is the target rate of the phase, in messages per second, all the publishers together
+	Rate float64 `yaml:"rate"`
+	// From is the rate a ramp starts from
+	From     float64       `yaml:"from"`
+	Duration time.Duration `yaml:"duration"`
+	// Size is the size distribution of the payloads, e.g. 1024 or uniform:100-1000, see payloadgen.ParseDistribution
+	Size string `yaml:"size"`
+	// Concurrency is the number of publishers, each one publishing its share of the rate
+	Concurrency int    `yaml:"concurrency"`
+	Topic       string `yaml:"topic"`
+
+	sizes payloadgen.Distribution
+}
+
+// rateAt returns the target rate of the phase once the time elapsed, 0 for as fast as possible
+func (p *Phase) rateAt(elapsed time.Duration) float64 {
+	if p.Type != Ramp || p.Duration <= 0 {
+		return p.Rate
+	}
+	progress := float64(elapsed) / float64(p.Duration)
+	if progress > 1 {
+		progress = 1
+	}
+	return p.From + (p.Rate-p.From)*progress
+}
+
+// LoadProfile reads the YAML profile, applies the defaults and checks it
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profile := &Profile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(profile); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	if profile.Mode == "" {
+		profile.Mode = "direct"
+	}
+	if profile.Mode != "direct" && profile.Mode != "persistent" {
+		return nil, fmt.Errorf("unknown mode '%s', expected direct or persistent", profile.Mode)
+	}
+	if profile.Buffer == 0 {
+		profile.Buffer = 1000
+	}
+	if len(profile.Phases) == 0 {
+		return nil, fmt.Errorf("%s has no phases", path)
+	}
+	for i := range profile.Phases {
+		phase := &profile.Phases[i]
+		if phase.Name == "" {
+			phase.Name = fmt.Sprintf("phase-%d", i+1)
+		}
+		if phase.Type == "" {
+			phase.Type = Steady
+		}
+		if phase.Topic == "" {
+			phase.Topic = profile.Topic
+		}
+		if phase.Size == "" {
+			phase.Size = "100"
+		}
+		if phase.Concurrency == 0 {
+			phase.Concurrency = 1
+		}
+		switch {
+		case phase.Type != Steady && phase.Type != Ramp && phase.Type != Burst:
+			return nil, fmt.Errorf("phase %s: unknown type '%s', expected steady, ramp or burst", phase.Name, phase.Type)
+		case phase.Topic == "":
+			return nil, fmt.Errorf("phase %s: no topic", phase.Name)
+		case phase.Duration <= 0:
+			return nil, fmt.Errorf("phase %s: the duration must be positive", phase.Name)
+		case phase.Concurrency < 0:
+			return nil, fmt.Errorf("phase %s: the concurrency can not be negative", phase.Name)
+		case phase.Rate < 0 || phase.From < 0:
+			return nil, fmt.Errorf("phase %s: the rates can not be negative", phase.Name)
+		case phase.Type != Burst && phase.Rate == 0 && phase.From == 0:
+			return nil, fmt.Errorf("phase %s: a %s phase needs a rate", phase.Name, phase.Type)
+		}
+		if phase.sizes, err = payloadgen.ParseDistribution(phase.Size); err != nil {
+			return nil, fmt.Errorf("phase %s: %w", phase.Name, err)
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,295 @@
+// Command msgdiff records a stream of messages to a file, replays it later, and diffs what a consumer receives against
+// the recording: the messages missing, unexpected, duplicated, out of order, or received with another topic, payload,
+// header or user property. It validates a broker configuration change or upgrade with the traffic recorded before it.
+//
+//	go run ./cmd/msgdiff -record baseline.ndjson -topic 'solace/samples/>' -count 1000
+//	go run ./cmd/msgdiff -replay baseline.ndjson -persistent -rate 100
+//	go run ./cmd/msgdiff -compare baseline.ndjson -queue durable-queue -received after.ndjson
+//
+// With -compare the consumer binds to -queue, or subscribes to -topic, then the recording is replayed (unless
+// -no-replay, when another process replays it) and the messages received until none arrived for -idle are compared
+// with it (see pkg/recording). The command exits with status 1 when there is a difference.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/recording"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// consumer receives the messages of the topic subscription or the queue
+type consumer struct {
+	receive  func(timeout time.Duration) (message.InboundMessage, error)
+	settle   func(msg message.InboundMessage)
+	receiver solace.LifecycleControl
+}
+
+func startConsumer(messagingService solace.MessagingService, topic, queue string) (*consumer, error) {
+	if topic != "" {
+		directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+			WithSubscriptions(resource.TopicSubscriptionOf(topic)).
+			Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &consumer{receive: directReceiver.ReceiveMessage, settle: func(message.InboundMessage) {}, receiver: directReceiver}, nil
+	}
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		Build(resource.QueueDurableExclusive(queue))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the messages are only acknowledged once they were recorded
+	settle := func(msg message.InboundMessage) { persistentReceiver.Ack(msg) }
+	return &consumer{receive: persistentReceiver.ReceiveMessage, settle: settle, receiver: persistentReceiver}, nil
+}
+
+// consume records the messages until count messages were received, none arrived for the idle time, or the stop
+// channel is closed
+func (c *consumer) consume(record func(msg message.InboundMessage) error, count int, idle time.Duration, stop <-chan struct{}) error {
+	for received := 0; count == 0 || received < count; received++ {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		msg, err := c.receive(idle)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if errors.As(err, &timeoutErr) {
+				return nil
+			}
+			return err
+		}
+		if err := record(msg); err != nil {
+			return err
+		}
+		c.settle(msg)
+	}
+	return nil
+}
+
+// replay publishes the records to their topic, at the rate in messages per second (0 for as fast as possible)
+func replay(messagingService solace.MessagingService, records []recording.Record, persistent bool, rate int, stop <-chan struct{}) (int, error) {
+	var publish func(msg message.OutboundMessage, topic *resource.Topic) error
+	var publisher solace.LifecycleControl
+	if persistent {
+		persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+		if err == nil {
+			err = persistentPublisher.Start()
+		}
+		if err != nil {
+			return 0, err
+		}
+		publish = func(msg message.OutboundMessage, topic *resource.Topic) error {
+			return persistentPublisher.PublishAwaitAcknowledgement(msg, topic, 10*time.Second, nil)
+		}
+		publisher = persistentPublisher
+	} else {
+		directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+		if err == nil {
+			err = directPublisher.Start()
+		}
+		if err != nil {
+			return 0, err
+		}
+		publish = directPublisher.Publish
+		publisher = directPublisher
+	}
+	defer publisher.Terminate(5 * time.Second)
+
+	pacer := bench.NewPacer(rate)
+	for i, record := range records {
+		select {
+		case <-stop:
+			return i, nil
+		default:
+		}
+		msg, err := record.Message(messagingService.MessageBuilder())
+		if err != nil {
+			return i, err
+		}
+		pacer.Wait()
+		if err := publish(msg, resource.TopicOf(record.Topic)); err != nil {
+			return i, fmt.Errorf("could not replay #%d: %w", record.Seq, err)
+		}
+	}
+	return len(records), nil
+}
+
+func main() {
+	recordFile := flag.String("record", "", "record the messages received to this file")
+	replayFile := flag.String("replay", "", "replay the messages of this recording")
+	compareFile := flag.String("compare", "", "compare the messages received with this recording, replaying it first unless -no-replay")
+	topicName := flag.String("topic", "", "topic subscription of the consumer of -record and -compare")
+	queueName := flag.String("queue", "", "durable queue of the consumer of -record and -compare, the messages are consumed from the queue")
+	receivedFile := flag.String("received", "", "with -compare, also record the messages received to this file")
+	noReplay := flag.Bool("no-replay", false, "with -compare, only receive, the recording is replayed by another process")
+	persistent := flag.Bool("persistent", false, "replay persistent messages instead of direct messages")
+	rate := flag.Int("rate", 0, "messages replayed per second, 0 replays as fast as possible")
+	count := flag.Int("count", 0, "with -record, record at most this many messages (0 for no limit)")
+	idle := flag.Duration("idle", 5*time.Second, "stop receiving when no message arrived for this long")
+	flag.Parse()
+
+	modes := 0
+	for _, file := range []string{*recordFile, *replayFile, *compareFile} {
+		if file != "" {
+			modes++
+		}
+	}
+	if modes != 1 {
+		fmt.Fprintln(os.Stderr, "exactly one of -record, -replay or -compare is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *replayFile == "" && (*topicName == "") == (*queueName == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of -topic or -queue is required with -record and -compare")
+		os.Exit(2)
+	}
+
+	var records []recording.Record
+	if file := *replayFile + *compareFile; file != "" {
+		var err error
+		if records, err = recording.ReadFile(file); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read the recording: ", err)
+			os.Exit(1)
+		}
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	stop := make(chan struct{})
+	go func() {
+		<-interrupted
+		close(stop)
+	}()
+
+	if *replayFile != "" {
+		replayed, err := replay(messagingService, records, *persistent, *rate, stop)
+		messagingService.Disconnect()
+		fmt.Fprintf(os.Stderr, "Replayed %d of %d message(s) from %s\n", replayed, len(records), *replayFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not replay the recording: ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	c, err := startConsumer(messagingService, *topicName, *queueName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the consumer: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	output := *recordFile
+	if output == "" {
+		output = *receivedFile
+	}
+	var writer *recording.Writer
+	if output != "" {
+		if writer, err = recording.Create(output); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create the recording: ", err)
+			c.receiver.Terminate(1 * time.Second)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+	}
+
+	var received []recording.Record
+	record := func(msg message.InboundMessage) error {
+		if writer != nil {
+			if err := writer.Write(msg); err != nil {
+				return err
+			}
+		}
+		if *compareFile != "" {
+			received = append(received, recording.Of(msg, int64(len(received)+1)))
+		}
+		return nil
+	}
+
+	// The recording is replayed while the messages are received, the consumer is already bound or subscribed
+	replayed := make(chan error, 1)
+	if *compareFile != "" && !*noReplay {
+		go func() {
+			_, err := replay(messagingService, records, *persistent, *rate, stop)
+			replayed <- err
+		}()
+	} else {
+		replayed <- nil
+	}
+
+	limit := *count
+	if *compareFile != "" {
+		limit = 0
+	}
+	consumeErr := c.consume(record, limit, *idle, stop)
+	replayErr := <-replayed
+	c.receiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+	if writer != nil {
+		if err := writer.Close(); err != nil && consumeErr == nil {
+			consumeErr = err
+		}
+	}
+	if consumeErr != nil {
+		fmt.Fprintln(os.Stderr, "Could not record the messages: ", consumeErr)
+	}
+	if replayErr != nil {
+		fmt.Fprintln(os.Stderr, "Could not replay the recording: ", replayErr)
+	}
+
+	if *recordFile != "" {
+		fmt.Fprintf(os.Stderr, "Recorded %d message(s) to %s\n", writer.Count(), *recordFile)
+		if consumeErr != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	differences := recording.Compare(records, received)
+	for _, difference := range differences {
+		fmt.Println(difference)
+	}
+	fmt.Fprintf(os.Stderr, "Received %d of %d recorded message(s), %d difference(s)\n", len(received), len(records), len(differences))
+	if len(differences) > 0 || consumeErr != nil || replayErr != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,183 @@
+// Command payload-inspect receives messages and renders their payloads through the decoders of pkg/payloaddecode, to
+// debug "garbage payload" reports: a hex dump, UTF-8 text, pretty printed JSON, gzip decompression and protobuf
+// messages decoded with a descriptor set. The decoders are detected from the content type and encoding of each
+// message, from its HTTP headers or its content-type and content-encoding user properties, then by sniffing the
+// payload, unless -decode names them.
+//
+//	go run ./cmd/payload-inspect -topic 'solace/samples/>'
+//	go run ./cmd/payload-inspect -queue orders -decode gzip,json -count 1
+//	go run ./cmd/payload-inspect -topic 'orders/>' -descriptor-set orders.pb -message-type shop.Order
+//	go run ./cmd/payload-inspect -topic 'orders/>' -decode hex -max-bytes 0
+//
+// The protobuf message type is taken from the messageType parameter of the content type, e.g. application/x-protobuf;
+// messageType=shop.Order, or the application message type, and -message-type otherwise.
+//
+// WARNING: -queue is not a browse, do not point it at the queue of a production consumer. Without the queue browser,
+// missing from solace.dev/go/messaging v1.8.0, the messages inspected are received by a consumer like any other: while
+// the tool runs they are taken from the other consumers of a non-exclusive queue, and not delivered to them, and an
+// exclusive queue another consumer is bound to delivers nothing. The messages are not settled, they are left on the
+// queue and delivered again once the inspection stops, flagged redelivered with a delivery attempt counted: on a queue
+// with a max redelivery count, repeated inspections move them to the dead message queue. Inspect the topics of the
+// queue with -topic instead whenever possible.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/payloaddecode"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+func main() {
+	topicName := flag.String("topic", "", "topic subscription to inspect with a direct receiver")
+	queueName := flag.String("queue", "", "durable queue to inspect, its messages are received unsettled: taken from its consumers while inspecting, then redelivered with a delivery attempt counted")
+	selector := flag.String("selector", "", "message selector of the messages inspected on -queue")
+	decode := flag.String("decode", "", "comma separated decoders applied in order, e.g. gzip,json (detected when empty)")
+	descriptorSet := flag.String("descriptor-set", "", "protobuf descriptor set (protoc --descriptor_set_out --include_imports) decoding the protobuf payloads")
+	messageType := flag.String("message-type", "", "protobuf message type of the payloads without one, e.g. shop.Order")
+	maxBytes := flag.Int("max-bytes", 4096, "bytes of the payload rendered as text or hex (0 for no limit)")
+	count := flag.Int("count", 0, "exit after this many messages (0 for no limit)")
+	flag.Parse()
+
+	if (*topicName == "") == (*queueName == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of -topic or -queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	decoders := payloaddecode.Default(*maxBytes)
+	if *descriptorSet != "" {
+		protobuf, err := payloaddecode.NewProtobuf(*descriptorSet, *messageType)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load the descriptor set: ", err)
+			os.Exit(2)
+		}
+		decoders.Register(protobuf)
+	}
+	var names []string
+	for _, name := range strings.Split(*decode, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			if _, ok := decoders.Lookup(name); !ok {
+				fmt.Fprintf(os.Stderr, "unknown decoder '%s', expected one of %s (protobuf needs -descriptor-set)\n", name, strings.Join(decoders.Names(), ", "))
+				os.Exit(2)
+			}
+			names = append(names, name)
+		}
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	var receive func(timeout time.Duration) (message.InboundMessage, error)
+	var receiver solace.LifecycleControl
+	if *topicName != "" {
+		directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+			WithSubscriptions(resource.TopicSubscriptionOf(*topicName)).
+			Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the direct receiver: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		receive, receiver = directReceiver.ReceiveMessage, directReceiver
+	} else {
+		// never settled, the broker delivers the messages again once the receiver is terminated
+		fmt.Fprintf(os.Stderr, "Inspecting %s takes its messages from its consumers until the tool exits, they are then "+
+			"redelivered, flagged redelivered with a delivery attempt counted\n", *queueName)
+		builder := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement()
+		if *selector != "" {
+			builder = builder.WithMessageSelector(*selector)
+		}
+		persistentReceiver, err := builder.Build(resource.QueueDurableExclusive(*queueName))
+		if err == nil {
+			err = persistentReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		receive, receiver = persistentReceiver.ReceiveMessage, persistentReceiver
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	received := 0
+receiveLoop:
+	for *count == 0 || received < *count {
+		select {
+		case <-interrupted:
+			break receiveLoop
+		default:
+		}
+		msg, err := receive(500 * time.Millisecond)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if errors.As(err, &timeoutErr) {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "Receive failed: ", err)
+			break
+		}
+		received++
+		inspect(received, msg, decoders, names)
+	}
+
+	receiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+	fmt.Fprintf(os.Stderr, "Inspected %d message(s)\n", received)
+}
+
+// inspect prints the headers describing the payload of the message, then its layers
+func inspect(n int, msg message.InboundMessage, decoders *payloaddecode.Registry, names []string) {
+	payload, _ := msg.GetPayloadAsBytes()
+	hints := payloaddecode.HintsOf(msg)
+	header := fmt.Sprintf("=== #%d %s, %d bytes", n, msg.GetDestinationName(), len(payload))
+	for _, hint := range []struct{ name, value string }{
+		{"content type", hints.ContentType},
+		{"encoding", hints.ContentEncoding},
+		{"message type", hints.MessageType},
+	} {
+		if hint.value != "" {
+			header += ", " + hint.name + " " + hint.value
+		}
+	}
+	fmt.Println(header)
+	if len(payload) == 0 {
+		fmt.Println("(no payload)")
+		return
+	}
+	layers, err := decoders.Decode(hints, payload, names...)
+	for _, layer := range layers {
+		fmt.Printf("--- %s\n%s\n", layer.Decoder, layer.Text)
+	}
+	if err != nil {
+		fmt.Printf("--- failed: %s\n", err)
+	}
+}
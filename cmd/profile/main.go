@@ -0,0 +1,84 @@
+// Command profile runs any sample with the profiling harness of pkg/profiling: the net/http/pprof endpoints, goroutine
+// and heap gauges, and periodic heap profiles, to investigate suspected leaks in handler code under load.
+//
+//	go run ./cmd/profile patterns/guaranteed_receiver.go
+//	go run ./cmd/profile -heap-dir heap -heap-interval 5m patterns/direct_publisher.go
+//	go run ./cmd/profile -addr localhost:6070 ./patterns/otel-tracing/otlp-consumer -- -flag value
+//
+// The sample is built unchanged, with pkg/profiling/autostart linked in (see internal/samplerun), and the harness is
+// configured with the SOLACE_PPROF_* environment variables. The sample arguments follow the sample file or directory.
+// Run it from the root of the module.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+
+	"SolaceSamples.com/PubSub+Go/internal/samplerun"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run runs the sample and returns its exit code
+func run() int {
+	address := flag.String("addr", "localhost:6060", "address of the pprof and expvar endpoints")
+	heapDir := flag.String("heap-dir", "", "directory the heap profiles are written to, none are written when empty")
+	heapInterval := flag.Duration("heap-interval", 0, "period of the heap profiles, 1m when 0")
+	keepHeaps := flag.Int("keep-heaps", 0, "number of heap profiles kept, the first one and the latest ones, 0 keeps all")
+	gaugeInterval := flag.Duration("gauge-interval", 0, "period the goroutine and heap gauges are sampled and reported, 10s when 0")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: profile [flags] <sample.go | sample directory> [--] [sample arguments]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+	sampleArgs := flag.Args()[1:]
+	if len(sampleArgs) > 0 && sampleArgs[0] == "--" {
+		sampleArgs = sampleArgs[1:]
+	}
+
+	tmp, err := os.MkdirTemp("", "solace-profile")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+
+	binary, err := samplerun.Build(tmp, flag.Arg(0), "SolaceSamples.com/PubSub+Go/pkg/profiling/autostart")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	cmd := exec.Command(binary, sampleArgs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(),
+		"SOLACE_PPROF_ADDR="+*address,
+		"SOLACE_PPROF_HEAP_DIR="+*heapDir,
+		"SOLACE_PPROF_HEAP_INTERVAL="+heapInterval.String(),
+		"SOLACE_PPROF_KEEP_HEAPS="+strconv.Itoa(*keepHeaps),
+		"SOLACE_PPROF_GAUGE_INTERVAL="+gaugeInterval.String(),
+	)
+
+	// the interrupt reaches the sample, in the same process group, which terminates gracefully
+	signal.Ignore(os.Interrupt)
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,250 @@
+// Command publish reads payloads from stdin and publishes them to a topic, one message per line
+// or the whole input as a single message, or generates them from a template (see pkg/payloadgen).
+//
+//	cat events.txt | go run ./cmd/publish -topic solace/samples/cli/events
+//	go run ./cmd/publish -topic solace/samples/cli/doc -whole -content-type application/json -persistent < doc.json
+//	echo hello | go run ./cmd/publish -topic solace/samples/cli/hello -property source=cli -property env=dev
+//	go run ./cmd/publish -topic solace/samples/orders -template '{"id":"{{uuid}}","amount":{{amount 5 500}}}' -count 100 -rate 10
+//	go run ./cmd/publish -topic solace/samples/docs -template @doc.json.tmpl -size normal:2048,512 -seed 7
+//
+// With -template or -size the payloads are generated instead of read from stdin: -count messages from the template,
+// inline or read from the file after @, padded to the sizes of the -size distribution.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/payloadgen"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// propertyFlags collects the repeated -property key=value flags
+type propertyFlags map[string]string
+
+func (p propertyFlags) String() string {
+	pairs := make([]string, 0, len(p))
+	for key, value := range p {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p propertyFlags) Set(pair string) error {
+	kv := strings.SplitN(pair, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("expected key=value, got '%s'", pair)
+	}
+	p[kv[0]] = kv[1]
+	return nil
+}
+
+// payloadPublisher publishes a single payload, hiding the difference between the direct and persistent publishers
+type payloadPublisher func(msg message.OutboundMessage) error
+
+func main() {
+	topicName := flag.String("topic", "", "topic to publish the messages on (required)")
+	persistent := flag.Bool("persistent", false, "publish persistent (guaranteed) messages instead of direct messages")
+	whole := flag.Bool("whole", false, "publish the whole input as a single message instead of one message per line")
+	skipEmpty := flag.Bool("skip-empty", true, "do not publish empty lines")
+	contentType := flag.String("content-type", "", "HTTP content type set on the messages, e.g. application/json")
+	contentEncoding := flag.String("content-encoding", "", "HTTP content encoding set on the messages")
+	maxLineSize := flag.Int("max-line-size", 1024*1024, "maximum size in bytes of a single input line")
+	ackTimeout := flag.Duration("ack-timeout", 30*time.Second, "time to wait for outstanding acknowledgements of persistent messages")
+	templateArg := flag.String("template", "", "template of the generated payloads, or @file to read it from a file, see pkg/payloadgen")
+	size := flag.String("size", "", "size distribution of the generated payloads, e.g. 1024, uniform:100-1000, normal:512,64 or exp:256")
+	count := flag.Int("count", 10, "number of messages generated with -template or -size")
+	rate := flag.Int("rate", 0, "messages generated per second, 0 publishes as fast as possible")
+	seed := flag.Int64("seed", 0, "seed of the generated values and sizes, 0 for a different run every time")
+	properties := propertyFlags{}
+	flag.Var(properties, "property", "user property key=value added to every message (repeatable)")
+	flag.Parse()
+
+	if *topicName == "" {
+		fmt.Fprintln(os.Stderr, "the -topic flag is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// The generator of the payloads, nil to read them from stdin
+	var generator *payloadgen.Generator
+	if *templateArg != "" || *size != "" {
+		options := payloadgen.Options{Seed: *seed}
+		if *size != "" {
+			distribution, err := payloadgen.ParseDistribution(*size)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			options.Size = distribution
+		}
+		text, err := payloadgen.Load(*templateArg)
+		if err == nil {
+			generator, err = payloadgen.New(text, options)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load the template: ", err)
+			os.Exit(2)
+		}
+	}
+
+	// Configuration parameters, loaded from the secrets source selected with -secrets-source (environment by default)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+	defer messagingService.Disconnect()
+
+	messageBuilder := messagingService.MessageBuilder()
+	for key, value := range properties {
+		messageBuilder = messageBuilder.WithProperty(config.MessageProperty(key), value)
+	}
+	if *contentType != "" || *contentEncoding != "" {
+		messageBuilder = messageBuilder.WithHTTPContentHeader(*contentType, *contentEncoding)
+	}
+
+	topic := resource.TopicOf(*topicName)
+	var published, failed int64
+	var outstanding sync.WaitGroup
+	var publish payloadPublisher
+	var publisher solace.LifecycleControl
+
+	if *persistent {
+		persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+		if err == nil {
+			err = persistentPublisher.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the persistent publisher: ", err)
+			os.Exit(1)
+		}
+		// Count the acknowledgements so the command only exits once the broker persisted every message
+		persistentPublisher.SetMessagePublishReceiptListener(func(receipt solace.PublishReceipt) {
+			if receipt.GetError() != nil {
+				atomic.AddInt64(&failed, 1)
+				fmt.Fprintln(os.Stderr, "Message was not persisted by the broker: ", receipt.GetError())
+			}
+			outstanding.Done()
+		})
+		publish = func(msg message.OutboundMessage) error {
+			outstanding.Add(1)
+			if err := persistentPublisher.Publish(msg, topic, nil, nil); err != nil {
+				outstanding.Done()
+				return err
+			}
+			return nil
+		}
+		publisher = persistentPublisher
+	} else {
+		directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+		if err == nil {
+			err = directPublisher.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the direct publisher: ", err)
+			os.Exit(1)
+		}
+		publish = func(msg message.OutboundMessage) error {
+			return directPublisher.Publish(msg, topic)
+		}
+		publisher = directPublisher
+	}
+
+	publishPayload := func(payload []byte) {
+		msg, err := messageBuilder.BuildWithByteArrayPayload(payload)
+		if err == nil {
+			err = publish(msg)
+		}
+		if err != nil {
+			atomic.AddInt64(&failed, 1)
+			fmt.Fprintln(os.Stderr, "Could not publish message: ", err)
+			return
+		}
+		published++
+	}
+
+	if generator != nil {
+		pacer := bench.NewPacer(*rate)
+		for i := 0; i < *count; i++ {
+			payload, err := generator.Next()
+			if err != nil {
+				atomic.AddInt64(&failed, 1)
+				fmt.Fprintln(os.Stderr, "Could not generate the payload: ", err)
+				break
+			}
+			pacer.Wait()
+			publishPayload(payload)
+		}
+	} else if *whole {
+		payload, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read stdin: ", err)
+			os.Exit(1)
+		}
+		publishPayload(payload)
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), *maxLineSize)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if *skipEmpty && len(line) == 0 {
+				continue
+			}
+			// the scanner reuses its buffer, copy the line before handing it to the API
+			publishPayload(append([]byte(nil), line...))
+		}
+		if err := scanner.Err(); err != nil && err != io.EOF {
+			fmt.Fprintln(os.Stderr, "Could not read stdin: ", err)
+		}
+	}
+
+	if *persistent {
+		done := make(chan struct{})
+		go func() {
+			outstanding.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(*ackTimeout):
+			fmt.Fprintln(os.Stderr, "Timed out waiting for the acknowledgement of all persistent messages")
+			atomic.AddInt64(&failed, 1)
+		}
+	}
+
+	// Give the publisher time to flush any buffered messages
+	publisher.Terminate(5 * time.Second)
+
+	fmt.Fprintf(os.Stderr, "Published %d message(s) on %s, %d failure(s)\n", published, *topicName, atomic.LoadInt64(&failed))
+	if atomic.LoadInt64(&failed) > 0 {
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+// Command queue-browser is a terminal UI to inspect the queues of a VPN: it lists the queues with their spooled
+// messages and consumers, pages through the messages of a queue without consuming them, shows the metadata of a
+// message, and deletes or requeues the selected messages.
+//
+//	go run ./cmd/queue-browser
+//	go run ./cmd/queue-browser -queue '#DEAD_MSG_QUEUE' -page-size 50
+//
+// The queues and their messages are listed with the SEMP v2 monitor API located by SOLACE_SEMP_URL,
+// SOLACE_SEMP_USERNAME, SOLACE_SEMP_PASSWORD and SOLACE_VPN (see pkg/queuemsgs), nothing binds to the queues to list
+// them: the messages stay on the queue, neither delivered nor flagged redelivered. Only d (delete, through the SEMP v2
+// action API) and R (requeue, to the topic the message was originally published to, see pkg/requeue) remove the
+// selected message, after confirmation. The requeue receives the message to republish it, with the messages spooled
+// before it, which are released unsettled: they stay on the queue, flagged redelivered.
+//
+// SEMP exposes the IDs, spool times, sizes and redelivery counts of the messages, not their topics, properties nor
+// payloads: there is no hex dump of the payload. Reading it takes receiving the message, and without the queue browser,
+// missing from solace.dev/go/messaging v1.8.0, a receiver would flag every message shown redelivered.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	tea "github.com/charmbracelet/bubbletea"
+	"solace.dev/go/messaging"
+)
+
+func main() {
+	queueName := flag.String("queue", "", "queue to list the messages of at start instead of listing the queues")
+	pageSize := flag.Int("page-size", 20, "number of messages per page")
+	flag.Parse()
+
+	if *pageSize <= 0 {
+		fmt.Fprintln(os.Stderr, "-page-size must be positive")
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+	semp := queuelag.SEMPConfig{
+		URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+		VPN:      sampleconfig.Setting("SOLACE_VPN", "default"),
+		Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+		Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	// The publisher republishes the requeued messages
+	publisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err == nil {
+		err = publisher.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the publisher: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	m := newModel(semp, &Requeuer{MessagingService: messagingService, Publisher: publisher}, *pageSize)
+	m.queue = *queueName
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+
+	publisher.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not run the queue browser: ", err)
+		os.Exit(1)
+	}
+}
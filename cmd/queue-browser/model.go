@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	"SolaceSamples.com/PubSub+Go/pkg/queuemsgs"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// screen is what the browser shows
+type screen int
+
+const (
+	queueScreen screen = iota
+	messageScreen
+	detailScreen
+)
+
+var (
+	titleStyle  = lipgloss.NewStyle().Bold(true)
+	helpStyle   = lipgloss.NewStyle().Faint(true)
+	statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+// The results of the commands, each run in a goroutine of its own
+type (
+	queuesMsg []QueueInfo
+	pageMsg   struct {
+		messages  []queuemsgs.Message
+		exhausted bool
+	}
+	deletedMsg  struct{ msgID int64 }
+	requeuedMsg struct {
+		msgID int64
+		topic string
+	}
+	errMsg struct{ err error }
+)
+
+// model is the bubbletea model of the browser. The messages of a queue are listed forward only, the ones listed so
+// far are kept to page back. A single command runs at a time, the keys are ignored while it is busy.
+type model struct {
+	semp     queuelag.SEMPConfig
+	requeuer *Requeuer
+	pageSize int
+
+	screen   screen
+	queues   table.Model
+	messages table.Model
+	detail   viewport.Model
+
+	queue     string
+	pager     *queuemsgs.Pager
+	browsed   []queuemsgs.Message
+	page      int
+	exhausted bool
+
+	// confirm is the key of the action on the selected message waiting for y, d (delete) or R (requeue)
+	confirm string
+	busy    bool
+	status  string
+	err     error
+}
+
+func newModel(semp queuelag.SEMPConfig, requeuer *Requeuer, pageSize int) *model {
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.Bold(true)
+	return &model{
+		semp:     semp,
+		requeuer: requeuer,
+		pageSize: pageSize,
+		queues: table.New(table.WithFocused(true), table.WithStyles(styles), table.WithColumns([]table.Column{
+			{Title: "Queue", Width: 40}, {Title: "Access", Width: 14}, {Title: "Spooled", Width: 10}, {Title: "Consumers", Width: 10},
+		})),
+		messages: table.New(table.WithFocused(true), table.WithStyles(styles), table.WithColumns([]table.Column{
+			{Title: "#", Width: 6}, {Title: "Message ID", Width: 14}, {Title: "Spooled", Width: 20},
+			{Title: "Size", Width: 10}, {Title: "Redeliveries", Width: 12}, {Title: "Delivered", Width: 9},
+		})),
+		detail: viewport.New(80, 20),
+	}
+}
+
+// Init loads the queues, or opens the queue given on the command line
+func (m *model) Init() tea.Cmd {
+	m.busy = true
+	if m.queue != "" {
+		return m.open(m.queue)
+	}
+	return loadQueues(m.semp)
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		height := msg.Height - 5
+		if height < 3 {
+			height = 3
+		}
+		m.queues.SetHeight(height)
+		m.messages.SetHeight(height)
+		m.detail.Width, m.detail.Height = msg.Width, height
+		return m, nil
+
+	case queuesMsg:
+		m.busy, m.err = false, nil
+		rows := make([]table.Row, 0, len(msg))
+		for _, queue := range msg {
+			rows = append(rows, table.Row{queue.Name, queue.AccessType, strconv.FormatInt(queue.Spooled, 10), strconv.FormatInt(queue.Consumers, 10)})
+		}
+		m.queues.SetRows(rows)
+		m.status = fmt.Sprintf("%d queue(s) in VPN %s", len(msg), m.semp.VPN)
+		return m, nil
+
+	case pageMsg:
+		m.busy, m.err = false, nil
+		m.exhausted = msg.exhausted
+		if len(msg.messages) > 0 {
+			// the page of the first message fetched
+			m.page = len(m.browsed) / m.pageSize
+		}
+		m.browsed = append(m.browsed, msg.messages...)
+		m.showPage()
+		return m, nil
+
+	case deletedMsg:
+		m.remove(msg.msgID)
+		m.status = fmt.Sprintf("Deleted message %d", msg.msgID)
+		return m, nil
+
+	case requeuedMsg:
+		m.remove(msg.msgID)
+		m.status = fmt.Sprintf("Requeued message %d to %s", msg.msgID, msg.topic)
+		return m, nil
+
+	case errMsg:
+		m.busy, m.err = false, msg.err
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.key(msg)
+	}
+	return m, nil
+}
+
+// key handles the keys of the current screen
+func (m *model) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if m.busy {
+		return m, nil
+	}
+	if m.confirm != "" {
+		action := m.confirm
+		m.confirm, m.status = "", ""
+		selected, ok := m.selected()
+		if msg.String() != "y" || !ok {
+			return m, nil
+		}
+		m.busy = true
+		if action == "R" {
+			return m, requeueMessage(m.requeuer, m.queue, selected)
+		}
+		return m, deleteMessage(m.semp, m.queue, selected.MsgID)
+	}
+
+	var cmd tea.Cmd
+	switch m.screen {
+	case queueScreen:
+		switch msg.String() {
+		case "q":
+			return m, tea.Quit
+		case "r":
+			m.busy = true
+			return m, loadQueues(m.semp)
+		case "enter":
+			if row := m.queues.SelectedRow(); row != nil {
+				m.busy = true
+				return m, m.open(row[0])
+			}
+		default:
+			m.queues, cmd = m.queues.Update(msg)
+		}
+
+	case messageScreen:
+		switch msg.String() {
+		case "q":
+			return m, tea.Quit
+		case "esc", "backspace":
+			m.pager, m.browsed = nil, nil
+			m.screen, m.status, m.busy = queueScreen, "", true
+			return m, loadQueues(m.semp)
+		case "n", "right", "pgdown":
+			if (m.page+1)*m.pageSize < len(m.browsed) {
+				m.page++
+				m.showPage()
+			} else if !m.exhausted {
+				m.busy = true
+				return m, fetch(m.pager)
+			}
+		case "p", "left", "pgup":
+			if m.page > 0 {
+				m.page--
+				m.showPage()
+			}
+		case "enter":
+			if selected, ok := m.selected(); ok {
+				m.showDetail(selected)
+			}
+		case "d":
+			if _, ok := m.selected(); ok {
+				m.confirm, m.status = "d", "Delete the message? y to confirm"
+			}
+		case "R":
+			if _, ok := m.selected(); ok {
+				m.confirmRequeue()
+			}
+		default:
+			m.messages, cmd = m.messages.Update(msg)
+		}
+
+	case detailScreen:
+		switch msg.String() {
+		case "q":
+			return m, tea.Quit
+		case "esc", "backspace":
+			m.screen = messageScreen
+		case "d":
+			m.confirm, m.status = "d", "Delete the message? y to confirm"
+		case "R":
+			m.confirmRequeue()
+		default:
+			m.detail, cmd = m.detail.Update(msg)
+		}
+	}
+	return m, cmd
+}
+
+// confirmRequeue asks for the confirmation of the requeue of the selected message, telling the messages listed before
+// it are received along with it
+func (m *model) confirmRequeue() {
+	m.confirm = "R"
+	m.status = "Requeue the message to its original topic? y to confirm"
+	if before := m.page*m.pageSize + m.messages.Cursor(); before > 0 {
+		m.status = fmt.Sprintf("Requeue the message to its original topic? The %d message(s) listed before it are received "+
+			"and released, flagged redelivered. y to confirm", before)
+	}
+}
+
+// remove removes the message deleted or requeued from the messages listed
+func (m *model) remove(msgID int64) {
+	m.busy, m.err = false, nil
+	for i, browsed := range m.browsed {
+		if browsed.MsgID == msgID {
+			m.browsed = append(m.browsed[:i], m.browsed[i+1:]...)
+			break
+		}
+	}
+	if m.page > 0 && m.page*m.pageSize >= len(m.browsed) {
+		m.page--
+	}
+	m.screen = messageScreen
+	m.showPage()
+}
+
+// selected returns the message under the cursor of the current page
+func (m *model) selected() (queuemsgs.Message, bool) {
+	index := m.page*m.pageSize + m.messages.Cursor()
+	if len(m.messages.Rows()) == 0 || index >= len(m.browsed) {
+		return queuemsgs.Message{}, false
+	}
+	return m.browsed[index], true
+}
+
+// showPage shows the messages of the current page
+func (m *model) showPage() {
+	start := m.page * m.pageSize
+	end := start + m.pageSize
+	if end > len(m.browsed) {
+		end = len(m.browsed)
+	}
+	rows := []table.Row{}
+	for i := start; i < end; i++ {
+		msg := m.browsed[i]
+		rows = append(rows, table.Row{strconv.Itoa(i + 1), strconv.FormatInt(msg.MsgID, 10), msg.Spooled().Format(time.DateTime),
+			strconv.FormatInt(msg.Size(), 10), strconv.FormatInt(msg.RedeliveryCount, 10), strconv.FormatBool(!msg.Undelivered)})
+	}
+	m.messages.SetRows(rows)
+	if m.messages.Cursor() >= len(rows) {
+		m.messages.SetCursor(len(rows) - 1)
+	}
+}
+
+// showDetail shows the metadata of the message, SEMP does not expose its topic, properties nor payload, there is no
+// hex dump of the payload
+func (m *model) showDetail(msg queuemsgs.Message) {
+	var content strings.Builder
+	fmt.Fprintf(&content, "Message ID:                 %d\n", msg.MsgID)
+	fmt.Fprintf(&content, "Replication Group Msg ID:   %s\n", msg.ReplicationGroupMsgID)
+	fmt.Fprintf(&content, "Publisher ID:               %d\n", msg.PublisherID)
+	fmt.Fprintf(&content, "Spooled:                    %s\n", msg.Spooled().Format(time.RFC3339))
+	if msg.ExpiryTime != 0 {
+		fmt.Fprintf(&content, "Expires:                    %s\n", time.Unix(msg.ExpiryTime, 0).Format(time.RFC3339))
+	}
+	fmt.Fprintf(&content, "Attachment Size:            %d\n", msg.AttachmentSize)
+	fmt.Fprintf(&content, "Content Size:               %d\n", msg.ContentSize)
+	fmt.Fprintf(&content, "Priority:                   %d\n", msg.Priority)
+	fmt.Fprintf(&content, "Redelivery Count:           %d\n", msg.RedeliveryCount)
+	fmt.Fprintf(&content, "Delivered:                  %t\n", !msg.Undelivered)
+	fmt.Fprintf(&content, "DMQ Eligible:               %t\n", msg.DMQEligible)
+	m.detail.SetContent(content.String())
+	m.detail.GotoTop()
+	m.screen = detailScreen
+}
+
+func (m *model) View() string {
+	var title, body, help string
+	switch m.screen {
+	case queueScreen:
+		title = fmt.Sprintf("Queues of VPN %s", m.semp.VPN)
+		body = m.queues.View()
+		help = "↑/↓ select • enter browse • r refresh • q quit"
+	case messageScreen:
+		more := ""
+		if !m.exhausted {
+			more = "+"
+		}
+		title = fmt.Sprintf("Queue %s, page %d, %d%s message(s) listed", m.queue, m.page+1, len(m.browsed), more)
+		body = m.messages.View()
+		help = "↑/↓ select • enter details • n/p next/previous page • d delete • R requeue • esc queues • q quit"
+	case detailScreen:
+		title = fmt.Sprintf("Queue %s, message %d", m.queue, m.page*m.pageSize+m.messages.Cursor()+1)
+		body = m.detail.View()
+		help = "↑/↓ scroll • d delete • R requeue • esc messages • q quit"
+	}
+	status := statusStyle.Render(m.status)
+	if m.busy {
+		status = statusStyle.Render("...")
+	}
+	if m.err != nil {
+		status = errorStyle.Render(m.err.Error())
+	}
+	return titleStyle.Render(title) + "\n" + body + "\n" + status + "\n" + helpStyle.Render(help)
+}
+
+func loadQueues(semp queuelag.SEMPConfig) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		queues, err := listQueues(ctx, semp)
+		if err != nil {
+			return errMsg{fmt.Errorf("could not list the queues: %w", err)}
+		}
+		return queuesMsg(queues)
+	}
+}
+
+// open lists the first page of the messages of the queue
+func (m *model) open(queue string) tea.Cmd {
+	m.screen, m.queue, m.pager = messageScreen, queue, queuemsgs.NewPager(m.semp, queue, m.pageSize)
+	m.browsed, m.page, m.exhausted = nil, 0, false
+	m.messages.SetRows(nil)
+	return fetch(m.pager)
+}
+
+// fetch lists the next page of messages of the pager
+func fetch(pager *queuemsgs.Pager) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		messages, err := pager.Next(ctx)
+		if err != nil {
+			return errMsg{fmt.Errorf("could not list the messages: %w", err)}
+		}
+		return pageMsg{messages: messages, exhausted: pager.Exhausted()}
+	}
+}
+
+// requeueMessage republishes the message to its original topic, then accepts it on the queue
+func requeueMessage(requeuer *Requeuer, queue string, msg queuemsgs.Message) tea.Cmd {
+	return func() tea.Msg {
+		topic, err := requeuer.Requeue(queue, msg)
+		if err != nil {
+			return errMsg{fmt.Errorf("could not requeue the message: %w", err)}
+		}
+		return requeuedMsg{msgID: msg.MsgID, topic: topic}
+	}
+}
+
+// deleteMessage deletes the message from the queue through the SEMP action API
+func deleteMessage(semp queuelag.SEMPConfig, queue string, msgID int64) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := queuemsgs.Delete(ctx, semp, queue, msgID); err != nil {
+			return errMsg{fmt.Errorf("could not delete the message: %w", err)}
+		}
+		return deletedMsg{msgID: msgID}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/queuemsgs"
+	"SolaceSamples.com/PubSub+Go/pkg/requeue"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// requeueTimeout is how long the requeue waits for the next message of the queue, and for the broker to acknowledge
+// the copy
+const requeueTimeout = 5 * time.Second
+
+// Requeuer republishes a message of a queue to its original topic, through pkg/requeue as cmd/requeue does
+type Requeuer struct {
+	MessagingService solace.MessagingService
+	Publisher        solace.PersistentMessagePublisher
+}
+
+// Requeue receives the messages of the queue until the one with the replication group message ID of msg, republishes
+// it to its original topic and accepts it on the queue once the copy was acknowledged, and returns the topic. SEMP
+// lists the messages without their payload, requeuing one takes receiving it: the messages spooled before it, and the
+// ones delivered along with it, are received as well and released unsettled once the receiver is terminated, the
+// broker delivers them again flagged redelivered. Nothing is received from an exclusive queue another consumer is bound
+// to, the requeue times out.
+func (r *Requeuer) Requeue(queue string, msg queuemsgs.Message) (string, error) {
+	if msg.ReplicationGroupMsgID == "" {
+		return "", errors.New("SEMP listed no replication group message ID to find the message with")
+	}
+	persistentReceiver, err := r.MessagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		Build(resource.QueueDurableExclusive(queue))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not bind to the queue: %w", err)
+	}
+	// the messages received before the requeued one are not settled, they stay on the queue
+	defer persistentReceiver.Terminate(1 * time.Second)
+
+	for {
+		inbound, err := persistentReceiver.ReceiveMessage(requeueTimeout)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if errors.As(err, &timeoutErr) {
+				return "", fmt.Errorf("message %d not received within %s, another consumer may be bound to the queue", msg.MsgID, requeueTimeout)
+			}
+			return "", err
+		}
+		if id, ok := inbound.GetReplicationGroupMessageID(); !ok || id.String() != msg.ReplicationGroupMsgID {
+			continue
+		}
+
+		outbound, topic, err := requeue.Copy(r.MessagingService.MessageBuilder(), inbound, queue, requeue.Rewrite{})
+		if err != nil {
+			return "", fmt.Errorf("could not copy the message: %w", err)
+		}
+		if topic == "" {
+			return "", errors.New("the message has no topic to requeue it to, it was published to a queue")
+		}
+		if err := r.Publisher.PublishAwaitAcknowledgement(outbound, resource.TopicOf(topic), requeueTimeout, nil); err != nil {
+			// not settled, the message stays on the queue
+			return "", fmt.Errorf("could not republish the message: %w", err)
+		}
+		if err := persistentReceiver.Ack(inbound); err != nil {
+			return "", fmt.Errorf("republished to %s but could not accept the message: %w", topic, err)
+		}
+		return topic, nil
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+)
+
+// QueueInfo is a queue of the VPN, as listed by the SEMP v2 monitor API
+type QueueInfo struct {
+	Name       string `json:"queueName"`
+	AccessType string `json:"accessType"`
+	Spooled    int64  `json:"spooledMsgCount"`
+	Consumers  int64  `json:"bindCount"`
+}
+
+// queuesResponse is a page of the SEMP v2 list of queues
+type queuesResponse struct {
+	Data []QueueInfo `json:"data"`
+	Meta struct {
+		Error *struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+		Paging *struct {
+			NextPageURI string `json:"nextPageUri"`
+		} `json:"paging"`
+	} `json:"meta"`
+}
+
+// listQueues returns the queues of the VPN by name, following the pages of the list
+func listQueues(ctx context.Context, semp queuelag.SEMPConfig) ([]QueueInfo, error) {
+	endpoint := fmt.Sprintf("%s/SEMP/v2/monitor/msgVpns/%s/queues?count=100&select=queueName,accessType,spooledMsgCount,bindCount",
+		semp.URL, url.PathEscape(semp.VPN))
+	client := semp.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	var queues []QueueInfo
+	for endpoint != "" {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		request.SetBasicAuth(semp.Username, semp.Password)
+		request.Header.Set("Accept", "application/json")
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		var page queuesResponse
+		err = json.NewDecoder(response.Body).Decode(&page)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s, invalid SEMP response: %w", response.Status, err)
+		}
+		if page.Meta.Error != nil {
+			return nil, fmt.Errorf("%s: %s", page.Meta.Error.Status, page.Meta.Error.Description)
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s", response.Status)
+		}
+		queues = append(queues, page.Data...)
+		endpoint = ""
+		if page.Meta.Paging != nil {
+			endpoint = page.Meta.Paging.NextPageURI
+		}
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	return queues, nil
+}
@@ -0,0 +1,200 @@
+// Command queue-export exports the messages spooled on a queue to a newline delimited JSON file, one record per
+// message with its metadata and base64 payload (see pkg/recording), for offline analysis with jq or any other tool.
+//
+//	go run ./cmd/queue-export -queue '#DEAD_MSG_QUEUE' -mode drain -out dmq.ndjson
+//	go run ./cmd/queue-export -queue orders-queue -mode drain -out orders.ndjson -count 10000
+//	go run ./cmd/queue-export -queue orders-dmq -mode redeliver -out orders-dmq.ndjson -resume
+//
+// Exporting the payloads takes receiving the messages, there is no non-destructive export: the queue browser is
+// missing from solace.dev/go/messaging v1.8.0, and SEMP lists the metadata of the messages only (see cmd/queue-browser).
+// The -mode is required:
+//
+//   - drain: the messages are removed from the queue once written to the file.
+//   - redeliver: the messages are received without being settled, and delivered again once the export stops. It is not
+//     a browse: every export flags the messages redelivered and counts a delivery attempt, on a queue with a max
+//     redelivery count repeated exports move them to the dead message queue. While the export runs its messages are
+//     taken from the other consumers of a non-exclusive queue, and on an exclusive queue another consumer is bound to
+//     nothing is exported. The broker stops delivering past the max delivered unacknowledged messages per flow of the
+//     queue, 10000 by default, the messages skipped by -after included: larger queues are exported with -mode drain.
+//
+// The export stops once the queue has no more message for -idle, after -count messages, or on interrupt, and prints the
+// cursor, the replication group message ID of the last message exported.
+//
+// An export can be resumed: -resume appends to the -out file and, in redeliver mode, skips the messages up to the last
+// one of the file, -after skips the messages up to the one of a cursor. In drain mode the exported messages are already
+// gone from the queue, so -resume only appends. The records are flushed before the messages are acknowledged, an
+// interrupted export loses no message but can export the last ones twice.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/recording"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message/rgmid"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+func main() {
+	queueName := flag.String("queue", "", "queue to export")
+	mode := flag.String("mode", "", "drain (the messages are removed from the queue) or redeliver (received unsettled: flagged redelivered, a delivery attempt counted, taken from the other consumers while exporting, at most the max delivered unacknowledged messages of the queue)")
+	selector := flag.String("selector", "", "export only the messages matching this selector")
+	out := flag.String("out", "queue.ndjson", "file the messages are exported to")
+	resume := flag.Bool("resume", false, "append to -out and, in redeliver mode, resume after its last message")
+	after := flag.String("after", "", "export only the messages after this cursor (replication group message ID), redeliver mode only; the skipped messages are received as well")
+	count := flag.Int64("count", 0, "stop after exporting this many messages (0 for no limit)")
+	idle := flag.Duration("idle", 2*time.Second, "stop when no message arrived for this long")
+	flag.Parse()
+
+	if *queueName == "" {
+		fmt.Fprintln(os.Stderr, "-queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *mode == "" {
+		fmt.Fprintln(os.Stderr, "-mode is required, drain or redeliver: both receive the messages from the queue (see -help)")
+		os.Exit(2)
+	}
+	if *mode != "redeliver" && *mode != "drain" {
+		fmt.Fprintf(os.Stderr, "unknown mode '%s', expected drain or redeliver\n", *mode)
+		os.Exit(2)
+	}
+	if *after != "" && *mode == "drain" {
+		fmt.Fprintln(os.Stderr, "-after is only supported with -mode redeliver")
+		os.Exit(2)
+	}
+
+	var writer *recording.Writer
+	var err error
+	if *resume {
+		var last recording.Record
+		writer, last, err = recording.Append(*out)
+		if err == nil && *after == "" && *mode == "redeliver" {
+			*after = last.ReplicationGroupMessageID
+		}
+	} else {
+		writer, err = recording.Create(*out)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not open the export file: ", err)
+		os.Exit(1)
+	}
+	var cursor rgmid.ReplicationGroupMessageID
+	if *after != "" {
+		if cursor, err = messaging.ReplicationGroupMessageIDOf(*after); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid cursor: ", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "Resuming after %s\n", *after)
+	}
+	exported := writer.Count()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	// The messages are acknowledged once exported in drain mode, never settled in redeliver mode: the broker delivers
+	// them again once the receiver is terminated
+	builder := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement()
+	if *selector != "" {
+		builder = builder.WithMessageSelector(*selector)
+	}
+	persistentReceiver, err := builder.Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	last, skipped := *after, 0
+	lastMessage := time.Now()
+	failed := false
+exportLoop:
+	for *count == 0 || writer.Count()-exported < *count {
+		select {
+		case <-interrupted:
+			break exportLoop
+		default:
+		}
+		msg, err := persistentReceiver.ReceiveMessage(500 * time.Millisecond)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				fmt.Fprintln(os.Stderr, "Receive failed: ", err)
+				failed = true
+				break
+			}
+			if time.Since(lastMessage) >= *idle {
+				break
+			}
+			continue
+		}
+		lastMessage = time.Now()
+		id, hasID := msg.GetReplicationGroupMessageID()
+		if cursor != nil && hasID {
+			// the unsettled messages are delivered again in spool order, skip the ones up to the cursor
+			if order, err := id.Compare(cursor); err == nil && order <= 0 {
+				skipped++
+				continue
+			}
+		}
+		if err := writer.Write(msg); err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not export the message: ", err)
+			failed = true
+			break
+		}
+		if *mode == "drain" {
+			persistentReceiver.Ack(msg)
+		}
+		if hasID {
+			last = id.String()
+		}
+	}
+
+	persistentReceiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+	if err := writer.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write the export file: ", err)
+		failed = true
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d message(s) from %s to %s", writer.Count()-exported, *queueName, *out)
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, ", skipped %d already exported", skipped)
+	}
+	fmt.Fprintln(os.Stderr)
+	if last != "" {
+		fmt.Fprintf(os.Stderr, "Cursor: %s\n", last)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
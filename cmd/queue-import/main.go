@@ -0,0 +1,268 @@
+// Command queue-import publishes the messages of a newline delimited JSON file, exported by cmd/queue-export or
+// recorded by cmd/record, back to a queue or a topic, with their original payload, headers, user properties, priority
+// and time to live.
+//
+//	go run ./cmd/queue-import -in dmq.ndjson -queue orders-queue
+//	go run ./cmd/queue-import -in orders.ndjson -rate 100 -ttl remaining
+//	go run ./cmd/queue-import -in orders.ndjson -topic solace/samples/orders/replayed -set-property imported=true
+//	go run ./cmd/queue-import -in orders.ndjson -transform "jq -c 'select(.topic | startswith(\"orders/eu\"))'"
+//
+// The messages are published as persistent messages to their original topic, to -topic, or straight to -queue. Their
+// time to live is, with -ttl original (default), the one they were published with, with -ttl remaining what was left of
+// it when they were exported, the messages already expired are then skipped, or a fixed duration, or none.
+//
+// The records can be transformed before they are published: -set-property adds or replaces user properties, and
+// -transform pipes the file through a command, e.g. jq, that reads and writes the records as newline delimited JSON
+// and can rewrite, add or drop them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/recording"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// propertyFlags collects the repeated -set-property key=value flags
+type propertyFlags map[string]string
+
+func (p propertyFlags) String() string {
+	pairs := make([]string, 0, len(p))
+	for key, value := range p {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p propertyFlags) Set(pair string) error {
+	kv := strings.SplitN(pair, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("expected key=value, got '%s'", pair)
+	}
+	p[kv[0]] = kv[1]
+	return nil
+}
+
+// Transform rewrites a record before it is published, it returns false to skip the record
+type Transform func(record *recording.Record) (bool, error)
+
+// setProperties returns the transform adding or replacing the user properties, as strings
+func setProperties(properties map[string]string) Transform {
+	return func(record *recording.Record) (bool, error) {
+		if record.Properties == nil {
+			record.Properties = map[string]string{}
+		}
+		for name, value := range properties {
+			record.Properties[name] = value
+			delete(record.PropertyTypes, name)
+		}
+		return true, nil
+	}
+}
+
+// timeToLive returns the function computing the time to live of a message for the -ttl mode, 0 for none, and whether
+// it already expired
+func timeToLive(mode string) (func(record recording.Record) (ttl time.Duration, expired bool), error) {
+	switch mode {
+	case "none":
+		return func(recording.Record) (time.Duration, bool) { return 0, false }, nil
+	case "original":
+		return func(record recording.Record) (time.Duration, bool) {
+			if record.Expiration == nil {
+				return 0, false
+			}
+			published := record.Received
+			if record.SenderTimestamp != nil {
+				published = *record.SenderTimestamp
+			}
+			return record.Expiration.Sub(published), false
+		}, nil
+	case "remaining":
+		return func(record recording.Record) (time.Duration, bool) {
+			if record.Expiration == nil {
+				return 0, false
+			}
+			remaining := time.Until(*record.Expiration)
+			return remaining, remaining <= 0
+		}, nil
+	}
+	ttl, err := time.ParseDuration(mode)
+	if err != nil || ttl < 0 {
+		return nil, fmt.Errorf("unknown -ttl '%s', expected original, remaining, none or a duration", mode)
+	}
+	return func(recording.Record) (time.Duration, bool) { return ttl, false }, nil
+}
+
+func main() {
+	in := flag.String("in", "queue.ndjson", "file to import, - for the standard input")
+	queueName := flag.String("queue", "", "publish the messages straight to this queue")
+	topic := flag.String("topic", "", "publish the messages to this topic instead of their original topic")
+	rate := flag.Int("rate", 0, "publish at this rate in messages per second (0 for as fast as possible)")
+	ttlMode := flag.String("ttl", "original", "time to live of the messages: original, remaining, none or a duration")
+	transformCommand := flag.String("transform", "", "shell command the records are piped through, as newline delimited JSON")
+	properties := propertyFlags{}
+	flag.Var(properties, "set-property", "user property key=value set on every message (repeatable)")
+	count := flag.Int("count", 0, "stop after publishing this many messages (0 for all)")
+	ackTimeout := flag.Duration("ack-timeout", 10*time.Second, "how long to wait for the broker to acknowledge each message")
+	flag.Parse()
+
+	if *queueName != "" && *topic != "" {
+		fmt.Fprintln(os.Stderr, "-queue and -topic are exclusive")
+		os.Exit(2)
+	}
+	ttl, err := timeToLive(*ttlMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var transforms []Transform
+	if len(properties) > 0 {
+		transforms = append(transforms, setProperties(properties))
+	}
+
+	var source io.Reader = os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not open the file to import: ", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		source = file
+	}
+	var transformer *exec.Cmd
+	if *transformCommand != "" {
+		transformer = exec.Command("sh", "-c", *transformCommand)
+		transformer.Stdin, transformer.Stderr = source, os.Stderr
+		if source, err = transformer.StdoutPipe(); err == nil {
+			err = transformer.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the transform: ", err)
+			os.Exit(1)
+		}
+	}
+	reader := recording.NewReader(source)
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+	publisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err == nil {
+		err = publisher.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the persistent publisher: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	destination := func(record recording.Record) *resource.Topic {
+		switch {
+		case *queueName != "":
+			// the broker delivers the messages published to the network topic of a queue straight to the queue
+			return resource.TopicOf("#P2P/QUE/" + *queueName)
+		case *topic != "":
+			return resource.TopicOf(*topic)
+		}
+		return resource.TopicOf(record.Topic)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	pacer := bench.NewPacer(*rate)
+	published, skipped, failed := 0, 0, 0
+	var readErr error
+	drained := false
+importLoop:
+	for *count == 0 || published < *count {
+		select {
+		case <-interrupted:
+			break importLoop
+		default:
+		}
+		record, err := reader.Next()
+		if err != nil {
+			drained = errors.Is(err, io.EOF)
+			if !drained {
+				readErr = err
+			}
+			break
+		}
+		keep := true
+		for _, transform := range transforms {
+			if keep, err = transform(&record); err != nil || !keep {
+				break
+			}
+		}
+		recordTTL, expired := ttl(record)
+		if err == nil && (!keep || expired) {
+			skipped++
+			continue
+		}
+		if err == nil {
+			pacer.Wait()
+			builder := messagingService.MessageBuilder()
+			if recordTTL > 0 {
+				builder = builder.WithProperty(config.MessagePropertyPersistentTimeToLive, recordTTL.Milliseconds())
+			}
+			msg, buildErr := record.Restore(builder)
+			if err = buildErr; err == nil {
+				err = publisher.PublishAwaitAcknowledgement(msg, destination(record), *ackTimeout, nil)
+			}
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Could not import #%d: %s\n", record.Seq, err)
+			continue
+		}
+		published++
+	}
+
+	publisher.Terminate(5 * time.Second)
+	messagingService.Disconnect()
+	if transformer != nil {
+		if !drained {
+			// the import was interrupted or limited by -count, the transform has more records to write
+			transformer.Process.Kill()
+			transformer.Wait()
+		} else if err := transformer.Wait(); err != nil {
+			fmt.Fprintln(os.Stderr, "The transform failed: ", err)
+			failed++
+		}
+	}
+	if readErr != nil {
+		fmt.Fprintln(os.Stderr, "Could not read the file to import: ", readErr)
+		failed++
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d message(s), skipped %d, %d failure(s)\n", published, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
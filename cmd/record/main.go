@@ -0,0 +1,174 @@
+// Command record captures live messages, their payload and all their metadata, into a fixture file that cmd/replay
+// republishes later, e.g. to reproduce a production issue locally with the traffic that caused it.
+//
+//	go run ./cmd/record -topic 'solace/samples/orders/>' -out orders.ndjson -duration 5m
+//	go run ./cmd/record -topic 'solace/samples/orders/>,solace/samples/payments/>' -out checkout.ndjson -count 500
+//	go run ./cmd/record -queue durable-queue -keep -out spooled.ndjson
+//
+// The fixture is newline delimited JSON, one record per message with its base64 payload, headers and typed user
+// properties (see pkg/recording), so it can be inspected, edited or filtered with jq before it is replayed. With
+// -queue the messages are consumed from the queue; with -keep they are not settled, they stay on the queue, flagged
+// redelivered, and are delivered again once the recording stops.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/recording"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+func main() {
+	topicList := flag.String("topic", "", "comma separated topic subscriptions to record with a direct receiver")
+	queueName := flag.String("queue", "", "durable queue to record, the messages are consumed from the queue unless -keep")
+	keep := flag.Bool("keep", false, "with -queue, leave the messages on the queue instead of acknowledging them")
+	selector := flag.String("selector", "", "message selector applied with -queue")
+	out := flag.String("out", "fixture.ndjson", "fixture file the messages are recorded to")
+	count := flag.Int64("count", 0, "stop after this many messages (0 for no limit)")
+	duration := flag.Duration("duration", 0, "stop after this long (0 for no limit)")
+	idle := flag.Duration("idle", 0, "stop when no message arrived for this long (0 waits until -count, -duration or interrupt)")
+	flag.Parse()
+
+	var topics []string
+	for _, topic := range strings.Split(*topicList, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	if (len(topics) == 0) == (*queueName == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of -topic or -queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if (*keep || *selector != "") && *queueName == "" {
+		fmt.Fprintln(os.Stderr, "-keep and -selector are only supported with -queue")
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	// receive waits for the next message, settle acknowledges it once it was recorded, unless -keep
+	var receive func(timeout time.Duration) (message.InboundMessage, error)
+	settle := func(message.InboundMessage) {}
+	var receiver solace.LifecycleControl
+	switch {
+	case len(topics) > 0:
+		subscriptions := make([]resource.Subscription, len(topics))
+		for i, topic := range topics {
+			subscriptions[i] = resource.TopicSubscriptionOf(topic)
+		}
+		directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().WithSubscriptions(subscriptions...).Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not subscribe: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		receive, receiver = directReceiver.ReceiveMessage, directReceiver
+	default:
+		builder := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement()
+		if *selector != "" {
+			builder = builder.WithMessageSelector(*selector)
+		}
+		persistentReceiver, err := builder.Build(resource.QueueDurableExclusive(*queueName))
+		if err == nil {
+			err = persistentReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		receive, receiver = persistentReceiver.ReceiveMessage, persistentReceiver
+		if !*keep {
+			settle = func(msg message.InboundMessage) { persistentReceiver.Ack(msg) }
+		}
+	}
+
+	writer, err := recording.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not create the fixture: ", err)
+		receiver.Terminate(1 * time.Second)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	var deadline <-chan time.Time
+	if *duration > 0 {
+		deadline = time.After(*duration)
+	}
+	lastMessage := time.Now()
+	failed := false
+recordLoop:
+	for *count == 0 || writer.Count() < *count {
+		select {
+		case <-interrupted:
+			break recordLoop
+		case <-deadline:
+			break recordLoop
+		default:
+		}
+		// wake up regularly to check for interrupts and the deadline
+		msg, err := receive(500 * time.Millisecond)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				fmt.Fprintln(os.Stderr, "Receive failed: ", err)
+				failed = true
+				break
+			}
+			if *idle > 0 && time.Since(lastMessage) >= *idle {
+				break
+			}
+			continue
+		}
+		lastMessage = time.Now()
+		if err := writer.Write(msg); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not record the message: ", err)
+			failed = true
+			break
+		}
+		settle(msg)
+	}
+
+	receiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+	if err := writer.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write the fixture: ", err)
+		failed = true
+	}
+	fmt.Fprintf(os.Stderr, "Recorded %d message(s) to %s\n", writer.Count(), *out)
+	if failed {
+		os.Exit(1)
+	}
+}
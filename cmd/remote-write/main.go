@@ -0,0 +1,134 @@
+// Command remote-write polls the statistics of the sample VPN through the SEMP v2 monitor API of the broker and pushes
+// them to a Prometheus remote write endpoint, for the dashboards of a demo environment without running an exporter:
+// Prometheus itself, started with --web.enable-remote-write-receiver, Grafana Mimir, Thanos or Grafana Cloud.
+//
+//	prometheus --web.enable-remote-write-receiver &
+//	go run ./cmd/remote-write -interval 15s -label env=demo
+//	SOLACE_REMOTE_WRITE_URL=https://prometheus-prod-01.grafana.net/api/prom/push SOLACE_REMOTE_WRITE_USERNAME=123456 \
+//		SOLACE_REMOTE_WRITE_PASSWORD=glc_... go run ./cmd/remote-write -queues 'orders*'
+//
+// Every poll pushes, labeled with the VPN and the -label ones:
+//
+//   - the message and byte rates, the spooled messages and the spool usage of the VPN, and its clients
+//   - the messages received, sent and discarded by the VPN, as totals: the discard rate is
+//     rate(solace_vpn_discarded_messages_total[5m])
+//   - the spooled messages and bytes, the consumers and the rates of every queue, and its messages discarded by reason
+//
+// A push refused with a 5xx or 429 status is sent again up to -attempts times, one refused with another status is
+// dropped, the next poll pushes the statistics of then. SEMP is located by SOLACE_SEMP_URL, SOLACE_SEMP_USERNAME,
+// SOLACE_SEMP_PASSWORD and SOLACE_VPN; the remote write endpoint is authenticated with SOLACE_REMOTE_WRITE_USERNAME and
+// SOLACE_REMOTE_WRITE_PASSWORD, or a bearer token with SOLACE_REMOTE_WRITE_TOKEN.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+)
+
+// labelFlags are the labels added to every series
+type labelFlags map[string]string
+
+func (l labelFlags) String() string {
+	pairs := make([]string, 0, len(l))
+	for name, value := range l {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlags) Set(pair string) error {
+	name, value, ok := strings.Cut(pair, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got '%s'", pair)
+	}
+	if strings.HasPrefix(name, "__") || name == "vpn" || name == "queue" {
+		return fmt.Errorf("label '%s' is reserved", name)
+	}
+	l[name] = value
+	return nil
+}
+
+func main() {
+	endpoint := flag.String("url", sampleconfig.Setting("SOLACE_REMOTE_WRITE_URL", "http://localhost:9090/api/v1/write"), "remote write endpoint")
+	interval := flag.Duration("interval", 15*time.Second, "SEMP polling and push interval")
+	queues := flag.String("queues", "*", "queues pushed, a SEMP where pattern of their names, e.g. orders*")
+	attempts := flag.Int("attempts", 3, "times a push refused with a 5xx or 429 status is sent")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of a push")
+	once := flag.Bool("once", false, "poll and push once, then exit, e.g. from cron")
+	labels := labelFlags{"job": "solace-remote-write"}
+	flag.Var(labels, "label", "label name=value added to every series (repeatable), job=solace-remote-write by default")
+	flag.Parse()
+
+	if *attempts < 1 || *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "-attempts must be 1 or more and -interval positive")
+		os.Exit(2)
+	}
+
+	vpn := sampleconfig.Setting("SOLACE_VPN", "default")
+	poller := &poller{
+		semp: queuelag.SEMPConfig{
+			URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+			VPN:      vpn,
+			Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+			Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+		},
+		queues: *queues,
+	}
+	writer := &Writer{
+		URL:      *endpoint,
+		Username: sampleconfig.Setting("SOLACE_REMOTE_WRITE_USERNAME", ""),
+		Password: sampleconfig.Setting("SOLACE_REMOTE_WRITE_PASSWORD", ""),
+		Token:    sampleconfig.Setting("SOLACE_REMOTE_WRITE_TOKEN", ""),
+		Client:   &http.Client{Timeout: *timeout},
+		Attempts: *attempts,
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), lifecycle.ShutdownSignals...)
+	defer cancel()
+
+	var failed bool
+	push := func() {
+		series, err := poller.poll(ctx, labels)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read the statistics from SEMP: ", err)
+			failed = true
+			return
+		}
+		if err := writer.Write(ctx, series); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not push the statistics: ", err)
+			failed = true
+			return
+		}
+		failed = false
+		fmt.Printf("%s pushed %d series of VPN %s\n", time.Now().Format(time.TimeOnly), len(series), vpn)
+	}
+
+	push()
+	if *once {
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Printf("Pushing the statistics of VPN %s to %s every %s\n", vpn, *endpoint, *interval)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			push()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Series is a sample of a time series: its labels, the metric name in __name__, and its value now
+type Series struct {
+	Labels map[string]string
+	Value  float64
+	Time   time.Time
+}
+
+// encodeWriteRequest encodes the series in a WriteRequest of the remote write protocol, as defined in prompb:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+//
+// The labels of a series are sorted by name, as the receivers require.
+func encodeWriteRequest(series []Series) []byte {
+	var request []byte
+	for _, s := range series {
+		names := make([]string, 0, len(s.Labels))
+		for name := range s.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var timeSeries []byte
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, s.Labels[name])
+			timeSeries = protowire.AppendTag(timeSeries, 1, protowire.BytesType)
+			timeSeries = protowire.AppendBytes(timeSeries, label)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.Time.UnixMilli()))
+		timeSeries = protowire.AppendTag(timeSeries, 2, protowire.BytesType)
+		timeSeries = protowire.AppendBytes(timeSeries, sample)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, timeSeries)
+	}
+	return request
+}
+
+// statusError is a push refused by the receiver, a status other than 2xx
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("remote write failed: %d %s: %s", e.status, http.StatusText(e.status), e.body)
+}
+
+// retryable reports whether the push is sent again: a 5xx or 429 status, or no response at all. A 4xx status is the
+// data refused, e.g. out of order samples, it fails again.
+func retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= 500 || statusErr.status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// Writer pushes series to a remote write endpoint, e.g. Prometheus started with --web.enable-remote-write-receiver,
+// Grafana Mimir, Thanos or the Grafana Cloud one
+type Writer struct {
+	URL string
+	// Username and Password authenticate with HTTP basic authentication when the username is set, Token as a bearer
+	// token when it is set
+	Username string
+	Password string
+	Token    string
+	Client   *http.Client
+	// Attempts is the number of times a push is sent, the retries backing off from one second
+	Attempts int
+}
+
+// Write pushes the series, in one request
+func (w *Writer) Write(ctx context.Context, series []Series) error {
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+	backoff := 1 * time.Second
+	for attempt := 1; ; attempt++ {
+		err := w.send(ctx, body)
+		if err == nil || attempt >= w.Attempts || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (w *Writer) send(ctx context.Context, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	request.Header.Set("User-Agent", "solace-samples-remote-write")
+	switch {
+	case w.Token != "":
+		request.Header.Set("Authorization", "Bearer "+w.Token)
+	case w.Username != "":
+		request.SetBasicAuth(w.Username, w.Password)
+	}
+
+	response, err := w.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return &statusError{status: response.StatusCode, body: strings.TrimSpace(string(text))}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+)
+
+// vpnStats are the statistics of the message VPN read from the SEMP v2 monitor API, the counters since the last
+// clear of the statistics
+type vpnStats struct {
+	RxMsgRate           float64 `json:"rxMsgRate"`
+	TxMsgRate           float64 `json:"txMsgRate"`
+	RxByteRate          float64 `json:"rxByteRate"`
+	TxByteRate          float64 `json:"txByteRate"`
+	MsgSpoolMsgCount    float64 `json:"msgSpoolMsgCount"`
+	MsgSpoolUsage       float64 `json:"msgSpoolUsage"`
+	DataRxMsgCount      float64 `json:"dataRxMsgCount"`
+	DataTxMsgCount      float64 `json:"dataTxMsgCount"`
+	RxDiscardedMsgCount float64 `json:"rxDiscardedMsgCount"`
+	TxDiscardedMsgCount float64 `json:"txDiscardedMsgCount"`
+}
+
+// queueStats are the statistics of a queue
+type queueStats struct {
+	Name             string  `json:"queueName"`
+	SpooledMsgCount  float64 `json:"spooledMsgCount"`
+	SpooledByteCount float64 `json:"spooledByteCount"`
+	BindCount        float64 `json:"bindCount"`
+	RxMsgRate        float64 `json:"rxMsgRate"`
+	TxMsgRate        float64 `json:"txMsgRate"`
+	// the messages discarded, by reason
+	MaxRedeliveryExceededDiscardedMsgCount    float64 `json:"maxRedeliveryExceededDiscardedMsgCount"`
+	MaxMsgSpoolUsageExceededDiscardedMsgCount float64 `json:"maxMsgSpoolUsageExceededDiscardedMsgCount"`
+	MaxTTLExpiredDiscardedMsgCount            float64 `json:"maxTtlExpiredDiscardedMsgCount"`
+}
+
+// sempResponse is a page of a SEMP v2 monitor response
+type sempResponse struct {
+	Data json.RawMessage `json:"data"`
+	Meta struct {
+		Error *struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+		Paging *struct {
+			NextPageURI string `json:"nextPageUri"`
+		} `json:"paging"`
+	} `json:"meta"`
+}
+
+// poller reads the statistics of a message VPN
+type poller struct {
+	semp queuelag.SEMPConfig
+	// queues is a SEMP where pattern of the queue names, e.g. orders*, all the queues when *
+	queues string
+}
+
+func (p *poller) vpnURL(path string) string {
+	return fmt.Sprintf("%s/SEMP/v2/monitor/msgVpns/%s%s", p.semp.URL, url.PathEscape(p.semp.VPN), path)
+}
+
+// get decodes the data of the pages of the endpoint, following the pages of a list, with add
+func (p *poller) get(ctx context.Context, endpoint string, add func(json.RawMessage) error) error {
+	client := p.semp.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	for endpoint != "" {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		request.SetBasicAuth(p.semp.Username, p.semp.Password)
+		request.Header.Set("Accept", "application/json")
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+		var page sempResponse
+		err = json.NewDecoder(response.Body).Decode(&page)
+		response.Body.Close()
+		if err != nil {
+			return fmt.Errorf("%s, invalid SEMP response: %w", response.Status, err)
+		}
+		if page.Meta.Error != nil {
+			return fmt.Errorf("%s: %s", page.Meta.Error.Status, page.Meta.Error.Description)
+		}
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s", response.Status)
+		}
+		if err := add(page.Data); err != nil {
+			return fmt.Errorf("invalid SEMP data: %w", err)
+		}
+		endpoint = ""
+		if page.Meta.Paging != nil {
+			endpoint = page.Meta.Paging.NextPageURI
+		}
+	}
+	return nil
+}
+
+// poll returns the series of the VPN, its clients and its queues, labeled with the VPN and the extra labels
+func (p *poller) poll(ctx context.Context, labels map[string]string) ([]Series, error) {
+	now := time.Now()
+	var series []Series
+	add := func(name string, value float64, extra ...string) {
+		seriesLabels := map[string]string{"__name__": name, "vpn": p.semp.VPN}
+		for label, labelValue := range labels {
+			seriesLabels[label] = labelValue
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			seriesLabels[extra[i]] = extra[i+1]
+		}
+		series = append(series, Series{Labels: seriesLabels, Value: value, Time: now})
+	}
+
+	var vpn vpnStats
+	if err := p.get(ctx, p.vpnURL(""), func(data json.RawMessage) error { return json.Unmarshal(data, &vpn) }); err != nil {
+		return nil, fmt.Errorf("VPN %s: %w", p.semp.VPN, err)
+	}
+	add("solace_vpn_rx_messages_per_second", vpn.RxMsgRate)
+	add("solace_vpn_tx_messages_per_second", vpn.TxMsgRate)
+	add("solace_vpn_rx_bytes_per_second", vpn.RxByteRate)
+	add("solace_vpn_tx_bytes_per_second", vpn.TxByteRate)
+	add("solace_vpn_spooled_messages", vpn.MsgSpoolMsgCount)
+	add("solace_vpn_spool_usage_bytes", vpn.MsgSpoolUsage)
+	add("solace_vpn_rx_messages_total", vpn.DataRxMsgCount)
+	add("solace_vpn_tx_messages_total", vpn.DataTxMsgCount)
+	// the discard rates are rate() of the totals, e.g. rate(solace_vpn_discarded_messages_total[5m])
+	add("solace_vpn_discarded_messages_total", vpn.RxDiscardedMsgCount, "direction", "rx")
+	add("solace_vpn_discarded_messages_total", vpn.TxDiscardedMsgCount, "direction", "tx")
+
+	var clients int
+	err := p.get(ctx, p.vpnURL("/clients?count=100&select=clientName"), func(data json.RawMessage) error {
+		var page []json.RawMessage
+		err := json.Unmarshal(data, &page)
+		clients += len(page)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clients of VPN %s: %w", p.semp.VPN, err)
+	}
+	add("solace_vpn_clients", float64(clients))
+
+	endpoint := p.vpnURL("/queues?count=100")
+	if p.queues != "" && p.queues != "*" {
+		endpoint += "&where=" + url.QueryEscape("queueName=="+p.queues)
+	}
+	err = p.get(ctx, endpoint, func(data json.RawMessage) error {
+		var page []queueStats
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		for _, q := range page {
+			add("solace_queue_spooled_messages", q.SpooledMsgCount, "queue", q.Name)
+			add("solace_queue_spooled_bytes", q.SpooledByteCount, "queue", q.Name)
+			add("solace_queue_consumers", q.BindCount, "queue", q.Name)
+			add("solace_queue_rx_messages_per_second", q.RxMsgRate, "queue", q.Name)
+			add("solace_queue_tx_messages_per_second", q.TxMsgRate, "queue", q.Name)
+			add("solace_queue_discarded_messages_total", q.MaxRedeliveryExceededDiscardedMsgCount, "queue", q.Name, "reason", "max_redelivery_exceeded")
+			add("solace_queue_discarded_messages_total", q.MaxMsgSpoolUsageExceededDiscardedMsgCount, "queue", q.Name, "reason", "spool_quota_exceeded")
+			add("solace_queue_discarded_messages_total", q.MaxTTLExpiredDiscardedMsgCount, "queue", q.Name, "reason", "ttl_expired")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("queues of VPN %s: %w", p.semp.VPN, err)
+	}
+	return series, nil
+}
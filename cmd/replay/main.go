@@ -0,0 +1,190 @@
+// Command replay republishes the messages of a fixture recorded by cmd/record, with their original payload, headers
+// and user properties, to reproduce a production issue locally.
+//
+//	go run ./cmd/replay -in orders.ndjson
+//	go run ./cmd/replay -in orders.ndjson -speed 10 -persistent
+//	go run ./cmd/replay -in orders.ndjson -rate 200 -topic-prefix replay -loop 3
+//
+// By default the messages are paced as they were recorded, at the original intervals divided by -speed; with -rate
+// they are published at a fixed rate instead, and with -pacing none as fast as possible. The messages are published
+// to their original topic, under -topic-prefix, or all to -topic. Each one carries the sequence number
+// of its record in the recording-seq user property (see pkg/recording).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/bench"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/recording"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// pacer holds the replay to the pacing of the records
+type pacer interface {
+	// wait blocks until the record is due
+	wait(record recording.Record)
+}
+
+// originalPacer replays the records at their recorded intervals, divided by the speed
+type originalPacer struct {
+	speed   float64
+	started time.Time
+	first   time.Time
+}
+
+func (p *originalPacer) wait(record recording.Record) {
+	if p.first.IsZero() {
+		p.started, p.first = time.Now(), record.Received
+		return
+	}
+	due := p.started.Add(time.Duration(float64(record.Received.Sub(p.first)) / p.speed))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// ratePacer replays the records at a fixed rate
+type ratePacer struct{ *bench.Pacer }
+
+func (p ratePacer) wait(recording.Record) { p.Wait() }
+
+func main() {
+	in := flag.String("in", "fixture.ndjson", "fixture file to replay")
+	pacing := flag.String("pacing", "original", "pacing of the messages: original (recorded intervals) or none")
+	speed := flag.Float64("speed", 1, "with -pacing original, replay this many times faster than recorded")
+	rate := flag.Int("rate", 0, "publish at this fixed rate in messages per second instead of the recorded intervals")
+	topic := flag.String("topic", "", "publish all the messages to this topic instead of their original topic")
+	topicPrefix := flag.String("topic-prefix", "", "publish the messages to their original topic under this prefix")
+	persistent := flag.Bool("persistent", false, "publish persistent (guaranteed) messages instead of direct messages")
+	loop := flag.Int("loop", 1, "replay the fixture this many times")
+	dryRun := flag.Bool("dry-run", false, "print the messages that would be published without connecting")
+	flag.Parse()
+
+	if *pacing != "original" && *pacing != "none" {
+		fmt.Fprintf(os.Stderr, "unknown pacing '%s', expected original or none\n", *pacing)
+		os.Exit(2)
+	}
+	if *speed <= 0 || *loop < 1 || *rate < 0 {
+		fmt.Fprintln(os.Stderr, "-speed and -loop must be positive, -rate can not be negative")
+		os.Exit(2)
+	}
+	if *topic != "" && *topicPrefix != "" {
+		fmt.Fprintln(os.Stderr, "-topic and -topic-prefix are exclusive")
+		os.Exit(2)
+	}
+
+	records, err := recording.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read the fixture: ", err)
+		os.Exit(1)
+	}
+	destination := func(record recording.Record) string {
+		switch {
+		case *topic != "":
+			return *topic
+		case *topicPrefix != "":
+			return *topicPrefix + "/" + record.Topic
+		}
+		return record.Topic
+	}
+
+	if *dryRun {
+		for _, record := range records {
+			fmt.Printf("#%d %s -> %s, %d bytes, %d propert(ies)\n", record.Seq, record.Received.Format(time.RFC3339Nano),
+				destination(record), len(record.Payload), len(record.Properties))
+		}
+		return
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	var publish func(msg message.OutboundMessage, topic *resource.Topic) error
+	var publisher solace.LifecycleControl
+	if *persistent {
+		persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+		if err == nil {
+			err = persistentPublisher.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the persistent publisher: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		publish = func(msg message.OutboundMessage, topic *resource.Topic) error {
+			return persistentPublisher.PublishAwaitAcknowledgement(msg, topic, 10*time.Second, nil)
+		}
+		publisher = persistentPublisher
+	} else {
+		directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+		if err == nil {
+			err = directPublisher.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the direct publisher: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		publish = directPublisher.Publish
+		publisher = directPublisher
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	published, failed := 0, 0
+replayLoop:
+	for i := 0; i < *loop; i++ {
+		var p pacer = &originalPacer{speed: *speed}
+		if *rate > 0 || *pacing == "none" {
+			p = ratePacer{bench.NewPacer(*rate)}
+		}
+		for _, record := range records {
+			select {
+			case <-interrupted:
+				break replayLoop
+			default:
+			}
+			p.wait(record)
+			msg, err := record.Message(messagingService.MessageBuilder())
+			if err == nil {
+				err = publish(msg, resource.TopicOf(destination(record)))
+			}
+			if err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "Could not replay #%d: %s\n", record.Seq, err)
+				continue
+			}
+			published++
+		}
+	}
+
+	publisher.Terminate(5 * time.Second)
+	messagingService.Disconnect()
+	fmt.Fprintf(os.Stderr, "Replayed %d message(s) from %s, %d failure(s)\n", published, *in, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,231 @@
+// Command requeue reprocesses the messages of a dead message queue: it republishes each message to the topic it was
+// originally published to, and only once the broker acknowledged the copy accepts the message on the dead message
+// queue, so a message is never lost, at worst republished twice when the tool stops in between.
+//
+//	go run ./cmd/requeue -dry-run
+//	go run ./cmd/requeue -queue orders-dmq -count 100
+//	go run ./cmd/requeue -queue orders-dmq -topic solace/samples/orders/retry -set retry=true,origin=dmq -unset error
+//
+// The copy keeps the payload, the user properties, the application message ID and the correlation ID of the message,
+// with the rewrites of -topic, -set and -unset applied, and the dead message queue it was requeued from in the
+// requeued-from user property (see pkg/requeue). Messages without a topic, e.g. published to a queue, are skipped
+// unless -topic is set; they stay on the queue.
+//
+// With -dry-run nothing binds to the queue: the messages that would be requeued are listed through the SEMP v2 monitor
+// API located by SOLACE_SEMP_URL, SOLACE_SEMP_USERNAME, SOLACE_SEMP_PASSWORD and SOLACE_VPN (see pkg/queuemsgs), and
+// stay on the queue untouched, neither flagged redelivered nor counted as a delivery attempt. SEMP only exposes their
+// spool metadata: the dry run prints their IDs and spool times, not their topics, and cannot apply -selector.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	"SolaceSamples.com/PubSub+Go/pkg/queuemsgs"
+	"SolaceSamples.com/PubSub+Go/pkg/requeue"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// describe returns the line printed for a message
+func describe(inbound message.InboundMessage, topic string) string {
+	id, ok := inbound.GetApplicationMessageID()
+	if !ok {
+		id = "-"
+	}
+	if topic == "" {
+		return fmt.Sprintf("%s from %q: no topic, skipped", id, inbound.GetDestinationName())
+	}
+	return fmt.Sprintf("%s from %q to %q", id, inbound.GetDestinationName(), topic)
+}
+
+// parseList splits a comma separated list, dropping the empty entries
+func parseList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// listDryRun prints the messages of the queue that would be requeued, at most count (0 for no limit), listed through
+// SEMP without receiving them
+func listDryRun(semp queuelag.SEMPConfig, queue string, count int, topic string) error {
+	destination := "their original topic"
+	if topic != "" {
+		destination = fmt.Sprintf("%q", topic)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	listed := 0
+	pager := queuemsgs.NewPager(semp, queue, 100)
+	for !pager.Exhausted() && (count == 0 || listed < count) {
+		messages, err := pager.Next(ctx)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if count > 0 && listed == count {
+				break
+			}
+			fmt.Printf("Would requeue message %d (%s) spooled at %s, %d byte(s)\n", msg.MsgID, msg.ReplicationGroupMsgID,
+				msg.Spooled().Format(time.RFC3339), msg.Size())
+			listed++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Would requeue %d message(s) from %s to %s, except the ones without a topic\n", listed, queue, destination)
+	return nil
+}
+
+func main() {
+	queueName := flag.String("queue", "#DEAD_MSG_QUEUE", "dead message queue to requeue the messages of")
+	selector := flag.String("selector", "", "message selector, only the matching messages are requeued")
+	topic := flag.String("topic", "", "topic to republish the messages to instead of their original topic")
+	set := flag.String("set", "", "comma separated name=value user properties set on the republished messages")
+	unset := flag.String("unset", "", "comma separated user properties removed from the republished messages")
+	count := flag.Int("count", 0, "requeue at most this many messages (0 for no limit)")
+	dryRun := flag.Bool("dry-run", false, "list the messages that would be requeued through SEMP, without receiving them")
+	idle := flag.Duration("idle", 2*time.Second, "exit when no message arrived for this long")
+	ackTimeout := flag.Duration("ack-timeout", 5*time.Second, "how long to wait for the broker to acknowledge a republished message")
+	flag.Parse()
+
+	rewrite := requeue.Rewrite{Topic: *topic, Set: map[string]string{}, Unset: parseList(*unset)}
+	for _, entry := range parseList(*set) {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "invalid -set entry %q, expected name=value\n", entry)
+			os.Exit(2)
+		}
+		rewrite.Set[name] = value
+	}
+
+	if *dryRun {
+		semp := queuelag.SEMPConfig{
+			URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+			VPN:      sampleconfig.Setting("SOLACE_VPN", "default"),
+			Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+			Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+		}
+		if *selector != "" {
+			fmt.Fprintln(os.Stderr, "-selector is not applied by -dry-run, SEMP does not expose the headers it matches")
+		}
+		if err := listDryRun(semp, *queueName, *count, *topic); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not list the messages: ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	// The messages are accepted on the dead message queue once republished
+	builder := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement()
+	if *selector != "" {
+		builder = builder.WithMessageSelector(*selector)
+	}
+	persistentReceiver, err := builder.Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	publisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err == nil {
+		err = publisher.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start the publisher: ", err)
+		persistentReceiver.Terminate(1 * time.Second)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+
+	requeued, skipped, failed := 0, 0, 0
+receiveLoop:
+	for *count == 0 || requeued+skipped < *count {
+		select {
+		case <-interrupted:
+			break receiveLoop
+		default:
+		}
+		inbound, err := persistentReceiver.ReceiveMessage(*idle)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				fmt.Fprintln(os.Stderr, "Receive failed: ", err)
+				failed++
+			}
+			break
+		}
+
+		outbound, destination, err := requeue.Copy(messagingService.MessageBuilder(), inbound, *queueName, rewrite)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not copy the message: ", err)
+			failed++
+			break
+		}
+		if destination == "" {
+			fmt.Println(describe(inbound, destination))
+			skipped++
+			continue
+		}
+		if err := publisher.PublishAwaitAcknowledgement(outbound, resource.TopicOf(destination), *ackTimeout, nil); err != nil {
+			// not settled, the message stays on the dead message queue
+			fmt.Fprintln(os.Stderr, "Could not republish the message: ", err)
+			failed++
+			break
+		}
+		if err := persistentReceiver.Ack(inbound); err != nil {
+			// republished but not removed, it would be republished again by the next run
+			fmt.Fprintln(os.Stderr, "Republished but could not accept the message: ", err)
+			failed++
+			break
+		}
+		fmt.Println("Requeued", describe(inbound, destination))
+		requeued++
+	}
+
+	// the messages received but not requeued are not settled, they stay on the dead message queue
+	persistentReceiver.Terminate(1 * time.Second)
+	publisher.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+
+	fmt.Fprintf(os.Stderr, "Requeued %d message(s) from %s, %d skipped without a topic\n", requeued, *queueName, skipped)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+	"solace.dev/go/messaging/pkg/solace/subcode"
+)
+
+// Result of a check
+type Result string
+
+// Results of the checks
+const (
+	Pass Result = "PASS"
+	Fail Result = "FAIL"
+	// Skip is the result of the checks that do not apply, e.g. refresh with a source without renewal
+	Skip Result = "SKIP"
+)
+
+// Check is the outcome of a check of the drill
+type Check struct {
+	Name    string  `json:"name"`
+	Result  Result  `json:"result"`
+	Seconds float64 `json:"seconds"`
+	Detail  string  `json:"detail,omitempty"`
+}
+
+// Report is written at the end of the drill
+type Report struct {
+	Username string    `json:"username"`
+	VPN      string    `json:"vpn"`
+	Source   string    `json:"source"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Checks   []Check   `json:"checks"`
+	// Passed is true when no check failed and the drill was not interrupted
+	Passed bool `json:"passed"`
+}
+
+// drill runs the checks of a rotation of the password of the client username
+type drill struct {
+	ctx           context.Context
+	sempClient    *semp.Client
+	brokerConfig  *sampleconfig.Config
+	username      string
+	password      string
+	newPassword   string
+	updateCommand string
+	topic         string
+	report        Report
+}
+
+// run runs the checks in order, it stops at the first check the next ones depend on that failed
+func (d *drill) run(rotateAfter, refreshTimeout time.Duration, restore bool) {
+	// The refresh path listens before the rotation so that no change is missed, only the latest change is kept
+	changed := make(chan config.ServicePropertyMap, 1)
+	renewCtx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+	go d.brokerConfig.Renew(renewCtx, func(properties config.ServicePropertyMap) {
+		select {
+		case <-changed:
+		default:
+		}
+		changed <- properties
+	})
+
+	var current solace.MessagingService
+	connected := d.check("connect", func() (string, error) {
+		service, err := connect(d.brokerConfig.Properties)
+		if err != nil {
+			return "", err
+		}
+		current = service
+		return "connected as " + d.username + " with the current password", roundTrip(service, d.topic)
+	})
+	defer func() {
+		if current != nil {
+			current.Disconnect()
+		}
+	}()
+	if !connected {
+		return
+	}
+
+	fmt.Printf("Running with the current password for %s before the rotation\n", rotateAfter)
+	select {
+	case <-time.After(rotateAfter):
+	case <-d.ctx.Done():
+		return
+	}
+	if !d.check("rotate", func() (string, error) {
+		return "password of " + d.username + " changed through SEMP", d.sempClient.SetClientPassword(d.ctx, d.username, d.newPassword)
+	}) {
+		return
+	}
+	if restore {
+		defer d.check("restore", d.restore)
+	}
+
+	if d.updateCommand == "" {
+		d.skip("update-source", "no -update-command, the secrets source is expected to be updated by the procedure")
+	} else {
+		d.check("update-source", func() (string, error) {
+			return "the update command stored the new password", d.update(d.ctx, d.newPassword)
+		})
+	}
+
+	d.check("existing-session", func() (string, error) {
+		if !current.IsConnected() {
+			return "", errors.New("the service connected before the rotation was disconnected")
+		}
+		return "the service connected before the rotation still carries messages", roundTrip(current, d.topic)
+	})
+
+	d.check("old-password-rejected", func() (string, error) {
+		service, err := connect(d.brokerConfig.Properties)
+		if err == nil {
+			service.Disconnect()
+			return "", errors.New("a new connection with the previous password was accepted")
+		}
+		if !isLoginFailure(err) {
+			return "", fmt.Errorf("the connection failed for another reason than the login: %w", err)
+		}
+		return "the login with the previous password was refused", nil
+	})
+
+	var refreshed config.ServicePropertyMap
+	if d.brokerConfig.Renewable() {
+		d.check("refresh", func() (string, error) {
+			timeout := time.After(refreshTimeout)
+			for {
+				select {
+				case properties := <-changed:
+					if properties[config.AuthenticationPropertySchemeBasicPassword] == d.newPassword {
+						refreshed = properties
+						return "the " + d.report.Source + " source handed out the new password", nil
+					}
+					// credentials renewed before the source was updated, wait for the next change
+				case <-timeout:
+					return "", fmt.Errorf("the %s source did not hand out the new password within %s", d.report.Source, refreshTimeout)
+				case <-d.ctx.Done():
+					return "", d.ctx.Err()
+				}
+			}
+		})
+	} else {
+		refreshed = config.ServicePropertyMap{}
+		for name, value := range d.brokerConfig.Properties {
+			refreshed[name] = value
+		}
+		refreshed[config.AuthenticationPropertySchemeBasicPassword] = d.newPassword
+		d.skip("refresh", "the "+d.report.Source+" source does not renew its credentials, the new password is handed to the refresh path directly")
+	}
+
+	if refreshed == nil {
+		d.skip("reconnect", "no refreshed credentials to reconnect with")
+		return
+	}
+	d.check("reconnect", func() (string, error) {
+		replacement, err := connect(refreshed)
+		if err != nil {
+			return "", err
+		}
+		if err := roundTrip(replacement, d.topic); err != nil {
+			replacement.Disconnect()
+			return "", err
+		}
+		// As the samples do, the new service replaces the previous one
+		current.Disconnect()
+		current = replacement
+		return "a new service connected with the new password and replaced the previous one", nil
+	})
+}
+
+// restore sets the previous password back, in the secrets source as well with -update-command. It runs after an
+// interrupt too, so that the drill does not leave the username with a password nobody knows.
+func (d *drill) restore() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := d.sempClient.SetClientPassword(ctx, d.username, d.password); err != nil {
+		return "", err
+	}
+	if d.updateCommand != "" {
+		if err := d.update(ctx, d.password); err != nil {
+			return "", err
+		}
+	}
+	return "the previous password was set back", nil
+}
+
+// update runs the -update-command storing the password in the secrets source
+func (d *drill) update(ctx context.Context, password string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", d.updateCommand)
+	cmd.Env = append(os.Environ(), "SOLACE_ROTATED_USERNAME="+d.username, "SOLACE_NEW_PASSWORD="+password)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("the update command failed: %w", err)
+	}
+	return nil
+}
+
+// check runs a check and records its outcome, the error fails the check. It reports whether the check passed.
+func (d *drill) check(name string, f func() (string, error)) bool {
+	start := time.Now()
+	detail, err := f()
+	c := Check{Name: name, Result: Pass, Seconds: time.Since(start).Seconds(), Detail: detail}
+	if err != nil {
+		c.Result, c.Detail = Fail, err.Error()
+	}
+	d.record(c)
+	return err == nil
+}
+
+// skip records a check that does not apply
+func (d *drill) skip(name, reason string) {
+	d.record(Check{Name: name, Result: Skip, Detail: reason})
+}
+
+func (d *drill) record(c Check) {
+	d.report.Checks = append(d.report.Checks, c)
+	fmt.Printf("%s %s (%.2fs): %s\n", c.Result, c.Name, c.Seconds, c.Detail)
+}
+
+// connect builds and connects a messaging service with the properties
+func connect(properties config.ServicePropertyMap) (solace.MessagingService, error) {
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(properties).Build()
+	if err != nil {
+		return nil, err
+	}
+	if err := messagingService.Connect(); err != nil {
+		return nil, err
+	}
+	return messagingService, nil
+}
+
+// roundTrip publishes a direct message to the topic and waits until the service receives it back
+func roundTrip(service solace.MessagingService, topic string) error {
+	receiver, err := service.CreateDirectMessageReceiverBuilder().WithSubscriptions(resource.TopicSubscriptionOf(topic)).Build()
+	if err != nil {
+		return err
+	}
+	if err := receiver.Start(); err != nil {
+		return err
+	}
+	defer receiver.Terminate(time.Second)
+	publisher, err := service.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		return err
+	}
+	if err := publisher.Start(); err != nil {
+		return err
+	}
+	defer publisher.Terminate(time.Second)
+
+	payload := fmt.Sprintf("rotation drill %d", time.Now().UnixNano())
+	if err := publisher.PublishString(payload, resource.TopicOf(topic)); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		message, err := receiver.ReceiveMessage(500 * time.Millisecond)
+		if err != nil {
+			var timeout *solace.TimeoutError
+			if errors.As(err, &timeout) {
+				continue
+			}
+			return err
+		}
+		if received, _ := message.GetPayloadAsString(); received == payload {
+			return nil
+		}
+	}
+	return fmt.Errorf("the message published to %s was not received back within 5s", topic)
+}
+
+// isLoginFailure reports whether the connection was refused by the broker for the credentials
+func isLoginFailure(err error) bool {
+	var nativeErr *solace.NativeError
+	return errors.As(err, &nativeErr) && nativeErr.SubCode() == subcode.LoginFailure
+}
+
+func printReport(report Report) {
+	fmt.Printf("\nRotation drill of %s on the VPN %s (%s source): %s\n",
+		report.Username, report.VPN, report.Source, report.End.Sub(report.Start).Round(time.Second))
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHECK\tRESULT\tSECONDS\tDETAIL")
+	for _, c := range report.Checks {
+		fmt.Fprintf(table, "%s\t%s\t%.2f\t%s\n", c.Name, c.Result, c.Seconds, c.Detail)
+	}
+	table.Flush()
+	if report.Passed {
+		fmt.Println("PASS")
+	} else {
+		fmt.Println("FAIL")
+	}
+}
@@ -0,0 +1,121 @@
+// Command rotation-drill rehearses the rotation of the password of a client username, for the security teams
+// validating their rotation procedures. It connects with the credentials of the secrets source (see
+// internal/sampleconfig), rotates the password through SEMP in the middle of the run, and verifies that the
+// credential-refresh path of the samples reconnects: the source hands out the new credentials through Renew, a new
+// messaging service connects with them and replaces the previous one (see howtos/how_to_load_credentials_from_vault.go).
+// Every check of the drill passes or fails:
+//
+//   - connect: a service connects and carries messages with the current credentials,
+//   - rotate: SEMP accepted the new password,
+//   - update-source: the -update-command stored the new password where the secrets source reads it,
+//   - existing-session: the service connected before the rotation still carries messages,
+//   - old-password-rejected: a new connection with the previous password is refused,
+//   - refresh: the secrets source handed out the new password within -refresh-timeout,
+//   - reconnect: a new service connects and carries messages with the refreshed credentials,
+//   - restore: the previous password is set back, with -restore,
+//
+// and the report is printed, and written to -report as JSON.
+//
+//	go run ./cmd/rotation-drill
+//	go run ./cmd/rotation-drill -secrets-source vault -report drill.json \
+//	  -update-command 'vault kv patch secret/solace/samples password="$SOLACE_NEW_PASSWORD"'
+//
+// The -update-command is run through sh with the rotated username in SOLACE_ROTATED_USERNAME and the new password in
+// SOLACE_NEW_PASSWORD. Sources without renewal, e.g. env, never refresh their credentials: the drill then hands the
+// new password to the refresh path itself and skips the refresh check. SEMP is located by SOLACE_SEMP_URL,
+// SOLACE_SEMP_USERNAME, SOLACE_SEMP_PASSWORD and SOLACE_VPN. The command exits with 1 when a check failed.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	newPassword := flag.String("new-password", "", "password to rotate to, a random one when empty")
+	rotateAfter := flag.Duration("rotate-after", 5*time.Second, "how long the service runs with the current password before the rotation")
+	updateCommand := flag.String("update-command", "", "shell command storing $SOLACE_NEW_PASSWORD in the secrets source after the rotation")
+	refreshTimeout := flag.Duration("refresh-timeout", 2*time.Minute, "how long the secrets source has to hand out the new password")
+	restore := flag.Bool("restore", true, "set the previous password back at the end of the drill")
+	topic := flag.String("topic", "solace/samples/go/rotation-drill", "topic of the messages checking the connections")
+	reportFile := flag.String("report", "", "file the JSON report is written to")
+	flag.Parse()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		return 1
+	}
+	username, _ := brokerConfig.Properties[config.AuthenticationPropertySchemeBasicUserName].(string)
+	password, _ := brokerConfig.Properties[config.AuthenticationPropertySchemeBasicPassword].(string)
+	if username == "" || password == "" {
+		fmt.Fprintln(os.Stderr, "The drill rotates the password of basic authentication, the secrets source holds no username and password")
+		return 2
+	}
+	if *newPassword == "" {
+		random := make([]byte, 18)
+		if _, err := rand.Read(random); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not generate the new password: ", err)
+			return 1
+		}
+		*newPassword = base64.RawURLEncoding.EncodeToString(random)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	d := &drill{
+		ctx:           ctx,
+		sempClient:    semp.FromSettings(),
+		brokerConfig:  brokerConfig,
+		username:      username,
+		password:      password,
+		newPassword:   *newPassword,
+		updateCommand: *updateCommand,
+		topic:         *topic,
+		report: Report{
+			Username: username,
+			VPN:      sampleconfig.Setting("SOLACE_VPN", sampleconfig.DefaultVPN),
+			Source:   sampleconfig.Setting("SOLACE_SECRETS_SOURCE", "env"),
+			Start:    time.Now(),
+		},
+	}
+	d.run(*rotateAfter, *refreshTimeout, *restore)
+	d.report.End = time.Now()
+	d.report.Passed = ctx.Err() == nil
+	for _, c := range d.report.Checks {
+		d.report.Passed = d.report.Passed && c.Result != Fail
+	}
+
+	printReport(d.report)
+	if *reportFile != "" {
+		encoded, err := json.MarshalIndent(d.report, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*reportFile, append(encoded, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write the report: ", err)
+			return 1
+		}
+	}
+	if !d.report.Passed {
+		return 1
+	}
+	return 0
+}
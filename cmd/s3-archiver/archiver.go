@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// Counts are the objects uploaded and the messages settled since the start
+type Counts struct {
+	Objects  int64
+	Archived int64
+	Failed   int64
+}
+
+// archiver batches the messages of a queue and settles them once their object is uploaded
+type archiver struct {
+	client        *s3.Client
+	bucket        string
+	prefix        string
+	queueName     string
+	compress      bool
+	maxMessages   int
+	maxBytes      int
+	maxAge        time.Duration
+	uploadTimeout time.Duration
+
+	receiver solace.PersistentMessageReceiver
+	audit    *settleaudit.Log
+
+	// mu serializes the messages and the flushes: the handler blocks during an upload, which holds the delivery of
+	// the next messages rather than buffering them in memory
+	mu      sync.Mutex
+	current batch
+	seq     int64
+	totals  Counts
+}
+
+// handle adds the message to the current batch and flushes the batch once it is full
+func (a *archiver) handle(inbound message.InboundMessage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seq++
+	if err := a.current.add(inbound, a.seq); err != nil {
+		a.settle(inbound, time.Now(), config.PersistentReceiverFailedOutcome, err)
+		a.totals.Failed++
+		return
+	}
+	if a.current.len() >= a.maxMessages || a.current.size() >= a.maxBytes {
+		a.flushLocked()
+	}
+}
+
+// flushIfDue flushes the current batch once it is older than the max age, called periodically
+func (a *archiver) flushIfDue() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.current.len() > 0 && a.current.age() >= a.maxAge {
+		a.flushLocked()
+	}
+}
+
+// flush flushes the current batch whatever its size and age, e.g. on exit
+func (a *archiver) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flushLocked()
+}
+
+// flushLocked uploads the current batch, then settles its messages: ACCEPTED once the object is stored, FAILED when
+// the upload failed, the broker then redelivers them into a later batch
+func (a *archiver) flushLocked() {
+	b := a.current
+	a.current = batch{}
+	if b.len() == 0 {
+		return
+	}
+	key := b.key(a.prefix, a.queueName, a.compress)
+	outcome := config.PersistentReceiverAcceptedOutcome
+	err := a.upload(key, &b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not upload %s, failing its %d message(s): %s\n", key, b.len(), err)
+		outcome = config.PersistentReceiverFailedOutcome
+		a.totals.Failed += int64(b.len())
+	} else {
+		fmt.Fprintf(os.Stderr, "Uploaded %s, %d message(s), %d bytes\n", key, b.len(), b.size())
+		a.totals.Objects++
+		a.totals.Archived += int64(b.len())
+	}
+	for i, inbound := range b.messages {
+		a.settle(inbound, b.received[i], outcome, err)
+	}
+}
+
+func (a *archiver) upload(key string, b *batch) error {
+	body, err := b.body(a.compress)
+	if err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/x-ndjson"),
+		Metadata: map[string]string{
+			"solace-queue":    a.queueName,
+			"solace-messages": strconv.Itoa(b.len()),
+		},
+	}
+	if a.compress {
+		input.ContentEncoding = aws.String("gzip")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.uploadTimeout)
+	defer cancel()
+	_, err = a.client.PutObject(ctx, input)
+	return err
+}
+
+func (a *archiver) settle(inbound message.InboundMessage, received time.Time, outcome config.MessageSettlementOutcome, reason error) {
+	// a message not settled is redelivered when the archiver reconnects, and archived again in another object
+	if err := a.audit.Settle(a.queueName, a.receiver, inbound, outcome, received, reason); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not settle the message: ", err)
+	}
+}
+
+func (a *archiver) counts() Counts {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.totals
+}
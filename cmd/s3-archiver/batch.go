@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/recording"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// batch is the messages of one object, settled together once the object is uploaded
+type batch struct {
+	messages []message.InboundMessage
+	received []time.Time
+	// lines is the newline delimited JSON content of the object, one recording.Record per message
+	lines bytes.Buffer
+	// opened is the time the first message of the batch was received, the partition of the object
+	opened time.Time
+}
+
+// add appends the message to the batch, encoded as a line of the object
+func (b *batch) add(inbound message.InboundMessage, seq int64) error {
+	record := recording.Of(inbound, seq)
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if len(b.messages) == 0 {
+		b.opened = record.Received
+	}
+	b.lines.Write(line)
+	b.lines.WriteByte('\n')
+	b.messages = append(b.messages, inbound)
+	b.received = append(b.received, record.Received)
+	return nil
+}
+
+func (b *batch) len() int {
+	return len(b.messages)
+}
+
+func (b *batch) size() int {
+	return b.lines.Len()
+}
+
+// age is the time since the first message of the batch was received
+func (b *batch) age() time.Duration {
+	if len(b.messages) == 0 {
+		return 0
+	}
+	return time.Since(b.opened)
+}
+
+// key is the object key of the batch, partitioned by the UTC date and hour the batch was opened, e.g.
+// archive/orders/dt=2024-05-02/hour=13/20240502T131502.123456789Z-500.ndjson.gz. The name sorts by time and is unique
+// per batch for a single archiver of the queue.
+func (b *batch) key(prefix, queueName string, compress bool) string {
+	opened := b.opened.UTC()
+	name := fmt.Sprintf("%s-%d.ndjson", opened.Format("20060102T150405.000000000Z"), b.len())
+	if compress {
+		name += ".gz"
+	}
+	return path.Join(prefix, queueName, "dt="+opened.Format("2006-01-02"), "hour="+opened.Format("15"), name)
+}
+
+// body is the content of the object, gzip compressed with compress
+func (b *batch) body(compress bool) ([]byte, error) {
+	if !compress {
+		return b.lines.Bytes(), nil
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(b.lines.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
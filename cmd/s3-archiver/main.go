@@ -0,0 +1,178 @@
+// Command s3-archiver consumes a queue into S3 or any S3-compatible storage such as MinIO: the messages are batched
+// into newline delimited JSON objects, one record per message with its metadata and base64 payload (see
+// pkg/recording), partitioned by the date and hour their batch was opened, e.g.
+// archive/orders/dt=2024-05-02/hour=13/20240502T131502.123456789Z-500.ndjson.gz.
+//
+//	go run ./cmd/s3-archiver -queue orders -bucket solace-archive
+//	SOLACE_S3_ENDPOINT=http://localhost:9000 AWS_ACCESS_KEY_ID=minioadmin AWS_SECRET_ACCESS_KEY=minioadmin AWS_REGION=us-east-1 \
+//		go run ./cmd/s3-archiver -queue orders -bucket solace-archive -max-messages 1000 -flush-interval 1m -gzip
+//
+// A batch is flushed once it holds -max-messages messages or -max-bytes bytes of records, or once its first message
+// is -flush-interval old, and on exit. Its messages are settled once the object is uploaded: ACCEPTED after a
+// successful upload, FAILED when the upload failed, the broker then redelivers them into a later batch. An archiver
+// stopped between an upload and the settlements archives these messages again on the next run, the records hold the
+// replication group message ID to deduplicate them downstream.
+//
+// The broker stops delivering once a flow has as many unsettled messages as the max delivered unacked messages per
+// flow of the queue (10000 by default), keep -max-messages below it, or the batches only flush on -flush-interval.
+// The region and the credentials come from the default AWS configuration chain, SOLACE_S3_ENDPOINT selects another
+// endpoint with path-style addressing, as MinIO expects. With SOLACE_AUDIT_FILE every settlement is recorded (see
+// pkg/settleaudit), and with -provision the queue is created through SEMP first.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+func main() {
+	queueName := flag.String("queue", "", "durable queue to archive")
+	bucket := flag.String("bucket", sampleconfig.Setting("SOLACE_S3_BUCKET", ""), "bucket the objects are uploaded to (or SOLACE_S3_BUCKET)")
+	prefix := flag.String("prefix", "archive", "key prefix of the objects, followed by the queue name and the partition")
+	maxMessages := flag.Int("max-messages", 500, "flush a batch once it holds this many messages")
+	maxBytes := flag.Int("max-bytes", 8<<20, "flush a batch once its records take this many bytes, before compression")
+	flushInterval := flag.Duration("flush-interval", 5*time.Minute, "flush a batch once its first message is this old")
+	compress := flag.Bool("gzip", false, "gzip the objects")
+	uploadTimeout := flag.Duration("upload-timeout", 1*time.Minute, "timeout of an upload")
+	flag.Parse()
+
+	if *queueName == "" || *bucket == "" {
+		fmt.Fprintln(os.Stderr, "-queue and -bucket are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *maxMessages <= 0 || *maxBytes <= 0 || *flushInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "-max-messages, -max-bytes and -flush-interval must be positive")
+		os.Exit(2)
+	}
+
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid SOLACE_DRAIN_TIMEOUT: ", err)
+		os.Exit(2)
+	}
+
+	awsConfig, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the AWS configuration: ", err)
+		os.Exit(1)
+	}
+	client := s3.NewFromConfig(awsConfig, func(options *s3.Options) {
+		if endpoint := sampleconfig.Setting("SOLACE_S3_ENDPOINT", ""); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+			options.UsePathStyle = true
+		}
+	})
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp)
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName, MaxRedeliveryCount: 3}); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the queue: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		WithRequiredMessageOutcomeSupport(config.PersistentReceiverFailedOutcome).
+		Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	// Settlement audit log, every settlement is appended as a JSON line to SOLACE_AUDIT_FILE when it is set
+	var audit *settleaudit.Log
+	if path := sampleconfig.Setting("SOLACE_AUDIT_FILE", ""); path != "" {
+		if audit, err = settleaudit.Open(settleaudit.Options{Path: path}); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not open the audit file: ", err)
+			persistentReceiver.Terminate(0)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		defer audit.Close()
+	}
+
+	a := &archiver{
+		client:        client,
+		bucket:        *bucket,
+		prefix:        *prefix,
+		queueName:     *queueName,
+		compress:      *compress,
+		maxMessages:   *maxMessages,
+		maxBytes:      *maxBytes,
+		maxAge:        *flushInterval,
+		uploadTimeout: *uploadTimeout,
+		receiver:      persistentReceiver,
+		audit:         audit,
+	}
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: paused, the last batch uploaded and settled, then
+	// terminated and disconnected (see pkg/lifecycle)
+	drainer := lifecycle.New(lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddReceiver(*queueName, persistentReceiver)
+	drainer.AddService(messagingService)
+	if err := persistentReceiver.ReceiveAsync(drainer.Handler(a.handle)); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not receive from the queue: ", err)
+		persistentReceiver.Terminate(0)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Archiving %s to s3://%s/%s\n", *queueName, *bucket, *prefix)
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, lifecycle.ShutdownSignals...)
+	// the age of the batch is checked every second, or more often with a shorter -flush-interval
+	ticker := time.NewTicker(min(*flushInterval, 1*time.Second))
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-interrupted:
+			break wait
+		case <-ticker.C:
+			a.flushIfDue()
+		}
+	}
+
+	// no more deliveries, the last batch is uploaded and settled before the receiver terminates
+	persistentReceiver.Pause()
+	a.flush()
+	report := drainer.Shutdown()
+	fmt.Fprintln(os.Stderr, "Drain: ", report)
+	counts := a.counts()
+	fmt.Fprintf(os.Stderr, "%d object(s) uploaded, %d message(s) archived, %d failed\n", counts.Objects, counts.Archived, counts.Failed)
+}
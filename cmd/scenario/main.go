@@ -0,0 +1,93 @@
+// Command scenario runs a scripted demo scenario from a YAML file (see pkg/scenario): timed steps publishing bursts,
+// starting, pausing and resuming receivers, killing the connection and asserting queue depths, narrated as they run,
+// for workshops and reproducible bug reports.
+//
+//	go run ./cmd/scenario -f cmd/scenario/slow-consumer.yaml -provision
+//	go run ./cmd/scenario -f bug-1234.yaml -log bug-1234.log
+//
+// The queue depths are read and the connections killed through the SEMP v2 API located by SOLACE_SEMP_URL,
+// SOLACE_SEMP_USERNAME, SOLACE_SEMP_PASSWORD and SOLACE_VPN. With -provision the queues the receivers bind to are
+// created first. The command exits with 1 when a step fails, e.g. an assertion.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	"SolaceSamples.com/PubSub+Go/pkg/scenario"
+	"solace.dev/go/messaging"
+)
+
+func main() {
+	file := flag.String("f", "scenario.yaml", "YAML scenario to run")
+	logFile := flag.String("log", "", "also write the narration to this file")
+	flag.Parse()
+
+	s, err := scenario.Load(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the scenario: ", err)
+		os.Exit(2)
+	}
+	var log io.Writer = os.Stdout
+	if *logFile != "" {
+		f, err := os.Create(*logFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not create the log: ", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		log = io.MultiWriter(os.Stdout, f)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	var queues []semp.Queue
+	for _, timed := range s.Steps {
+		if receive, ok := timed.Step.(scenario.Receive); ok && receive.Queue != "" {
+			queues = append(queues, semp.Queue{Name: receive.Queue})
+		}
+	}
+	if err := semp.Provision(context.Background(), queues...); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the queues: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	env := scenario.NewEnv(messagingService, queuelag.SEMPConfig{
+		URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+		VPN:      sampleconfig.Setting("SOLACE_VPN", "default"),
+		Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+		Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+	}, log)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err = s.Run(ctx, env)
+	stop()
+	env.Close()
+	messagingService.Disconnect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Scenario failed: ", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+// Command shell is an interactive prompt to poke at a broker without writing a throwaway program: it publishes
+// messages, subscribes to topics and consumes queues, printing the messages received as they arrive.
+//
+//	go run ./cmd/shell
+//	solace> props set region eu
+//	solace> sub solace/samples/>
+//	solace> pub solace/samples/orders {"id": 42}
+//	<< solace/samples/orders: {"id": 42} {region=eu}
+//	solace> mode persistent
+//	solace> bind durable-queue
+//
+// The commands are read line by line from the standard input, so a session can also be scripted:
+//
+//	printf 'mode persistent\npub solace/samples/orders hello\n' | go run ./cmd/shell
+//
+// Type help for the list of the commands, exit, quit or Ctrl-D to leave.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+const prompt = "solace> "
+
+func main() {
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	// the prompt is only shown to a terminal, not when the commands are piped
+	shellPrompt := ""
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		shellPrompt = prompt
+		fmt.Printf("Connected to %v, type help for the commands\n", brokerConfig.Properties[config.TransportLayerPropertyHost])
+	}
+	s := newShell(messagingService, os.Stdout, shellPrompt)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		s.printf("%s", shellPrompt)
+		if !scanner.Scan() || !s.run(scanner.Text()) {
+			break
+		}
+	}
+	if shellPrompt != "" {
+		fmt.Println()
+	}
+	s.close()
+	messagingService.Disconnect()
+}
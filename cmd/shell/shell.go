@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// command is a shell command, run with the arguments following its name
+type command struct {
+	usage string
+	help  string
+	run   func(s *shell, args string) error
+}
+
+var commands map[string]command
+
+func init() {
+	// initialized here as the help command refers to the table
+	commands = map[string]command{
+		"pub":    {"pub <topic> <payload>", "publish the payload, the rest of the line, to the topic", (*shell).publish},
+		"sub":    {"sub <topic subscription>", "print the messages published to the topics matching the subscription", (*shell).subscribe},
+		"unsub":  {"unsub <topic subscription>", "remove a subscription added with sub", (*shell).unsubscribe},
+		"bind":   {"bind <queue>", "print and acknowledge the messages of the durable queue", (*shell).bind},
+		"unbind": {"unbind", "stop consuming the queue bound with bind", (*shell).unbind},
+		"props":  {"props [set <key> <value> | unset <key> | clear]", "show or change the user properties of the messages published", (*shell).props},
+		"mode":   {"mode [direct | persistent]", "show or change how the messages are published", (*shell).mode},
+		"status": {"status", "show the publishing mode, the subscriptions and the queue bound", (*shell).status},
+		"help":   {"help", "list the commands", (*shell).help},
+	}
+}
+
+// shell holds the state of a session: the publishers and receivers are created when first needed
+type shell struct {
+	service solace.MessagingService
+	out     io.Writer
+	// prompt is reprinted after the messages received, empty when the input is not a terminal
+	prompt string
+	// outMu serializes the output of the commands and of the messages received
+	outMu sync.Mutex
+
+	persistent          bool
+	properties          config.MessagePropertyMap
+	directPublisher     solace.DirectMessagePublisher
+	persistentPublisher solace.PersistentMessagePublisher
+	receiver            solace.DirectMessageReceiver
+	subscriptions       map[string]bool
+	queueReceiver       solace.PersistentMessageReceiver
+	queueName           string
+}
+
+func newShell(service solace.MessagingService, out io.Writer, prompt string) *shell {
+	return &shell{service: service, out: out, prompt: prompt, properties: config.MessagePropertyMap{},
+		subscriptions: map[string]bool{}}
+}
+
+// printf writes to the output, safely with the messages received concurrently
+func (s *shell) printf(format string, args ...interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format, args...)
+}
+
+// run runs a line of input, it returns false on exit or quit
+func (s *shell) run(line string) bool {
+	name, args := split(line)
+	switch name {
+	case "":
+		return true
+	case "exit", "quit":
+		return false
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		s.printf("unknown command '%s', try help\n", name)
+		return true
+	}
+	if err := cmd.run(s, args); err != nil {
+		s.printf("error: %s\n", err)
+	}
+	return true
+}
+
+// split returns the first word of the line and the rest of it
+func split(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return line, ""
+}
+
+// close terminates the publishers and receivers
+func (s *shell) close() {
+	for _, lifecycle := range []solace.LifecycleControl{s.directPublisher, s.persistentPublisher, s.receiver, s.queueReceiver} {
+		if lifecycle != nil && lifecycle.IsRunning() {
+			lifecycle.Terminate(1 * time.Second)
+		}
+	}
+}
+
+func (s *shell) publish(args string) error {
+	topic, payload := split(args)
+	if topic == "" {
+		return fmt.Errorf("usage: %s", commands["pub"].usage)
+	}
+	msg, err := s.service.MessageBuilder().FromConfigurationProvider(s.properties).BuildWithStringPayload(payload)
+	if err != nil {
+		return err
+	}
+	if s.persistent {
+		if s.persistentPublisher == nil {
+			publisher, err := s.service.CreatePersistentMessagePublisherBuilder().Build()
+			if err == nil {
+				err = publisher.Start()
+			}
+			if err != nil {
+				return err
+			}
+			s.persistentPublisher = publisher
+		}
+		if err := s.persistentPublisher.PublishAwaitAcknowledgement(msg, resource.TopicOf(topic), 10*time.Second, nil); err != nil {
+			return err
+		}
+		s.printf("published to %s, acknowledged\n", topic)
+		return nil
+	}
+	if s.directPublisher == nil {
+		publisher, err := s.service.CreateDirectMessagePublisherBuilder().OnBackPressureWait(100).Build()
+		if err == nil {
+			err = publisher.Start()
+		}
+		if err != nil {
+			return err
+		}
+		s.directPublisher = publisher
+	}
+	return s.directPublisher.Publish(msg, resource.TopicOf(topic))
+}
+
+func (s *shell) subscribe(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: %s", commands["sub"].usage)
+	}
+	if s.receiver == nil {
+		receiver, err := s.service.CreateDirectMessageReceiverBuilder().Build()
+		if err == nil {
+			err = receiver.Start()
+		}
+		if err == nil {
+			err = receiver.ReceiveAsync(s.received)
+		}
+		if err != nil {
+			return err
+		}
+		s.receiver = receiver
+	}
+	if err := s.receiver.AddSubscription(resource.TopicSubscriptionOf(args)); err != nil {
+		return err
+	}
+	s.subscriptions[args] = true
+	return nil
+}
+
+func (s *shell) unsubscribe(args string) error {
+	if !s.subscriptions[args] {
+		return fmt.Errorf("not subscribed to '%s'", args)
+	}
+	if err := s.receiver.RemoveSubscription(resource.TopicSubscriptionOf(args)); err != nil {
+		return err
+	}
+	delete(s.subscriptions, args)
+	return nil
+}
+
+func (s *shell) bind(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: %s", commands["bind"].usage)
+	}
+	if s.queueReceiver != nil {
+		return fmt.Errorf("already bound to %s, unbind first", s.queueName)
+	}
+	// the messages are acknowledged once printed
+	receiver, err := s.service.CreatePersistentMessageReceiverBuilder().WithMessageAutoAcknowledgement().
+		Build(resource.QueueDurableExclusive(args))
+	if err == nil {
+		err = receiver.Start()
+	}
+	if err == nil {
+		err = receiver.ReceiveAsync(s.received)
+	}
+	if err != nil {
+		return err
+	}
+	s.queueReceiver, s.queueName = receiver, args
+	return nil
+}
+
+func (s *shell) unbind(string) error {
+	if s.queueReceiver == nil {
+		return fmt.Errorf("no queue bound")
+	}
+	err := s.queueReceiver.Terminate(1 * time.Second)
+	s.queueReceiver, s.queueName = nil, ""
+	return err
+}
+
+// received prints a message received by a subscription or from the queue bound
+func (s *shell) received(msg message.InboundMessage) {
+	payload, ok := msg.GetPayloadAsString()
+	if !ok {
+		bytes, _ := msg.GetPayloadAsBytes()
+		payload = fmt.Sprintf("<%d bytes>", len(bytes))
+	}
+	var properties []string
+	for key, value := range msg.GetProperties() {
+		properties = append(properties, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(properties)
+	line := fmt.Sprintf("<< %s: %s", msg.GetDestinationName(), payload)
+	if len(properties) > 0 {
+		line += " {" + strings.Join(properties, ", ") + "}"
+	}
+	if s.prompt == "" {
+		s.printf("%s\n", line)
+		return
+	}
+	// the message interrupts the line being typed, print it on its own line and print the prompt again
+	s.printf("\n%s\n%s", line, s.prompt)
+}
+
+func (s *shell) props(args string) error {
+	action, rest := split(args)
+	switch action {
+	case "":
+		if len(s.properties) == 0 {
+			s.printf("no user properties\n")
+		}
+		keys := make([]string, 0, len(s.properties))
+		for key := range s.properties {
+			keys = append(keys, string(key))
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.printf("%s=%v\n", key, s.properties[config.MessageProperty(key)])
+		}
+	case "set":
+		key, value := split(rest)
+		if key == "" {
+			return fmt.Errorf("usage: props set <key> <value>")
+		}
+		s.properties[config.MessageProperty(key)] = value
+	case "unset":
+		if rest == "" {
+			return fmt.Errorf("usage: props unset <key>")
+		}
+		delete(s.properties, config.MessageProperty(rest))
+	case "clear":
+		s.properties = config.MessagePropertyMap{}
+	default:
+		return fmt.Errorf("usage: %s", commands["props"].usage)
+	}
+	return nil
+}
+
+func (s *shell) mode(args string) error {
+	switch args {
+	case "":
+	case "direct":
+		s.persistent = false
+	case "persistent":
+		s.persistent = true
+	default:
+		return fmt.Errorf("usage: %s", commands["mode"].usage)
+	}
+	s.printf("publishing %s messages\n", s.modeName())
+	return nil
+}
+
+func (s *shell) modeName() string {
+	if s.persistent {
+		return "persistent"
+	}
+	return "direct"
+}
+
+func (s *shell) status(string) error {
+	s.printf("mode: %s\nuser properties: %d\n", s.modeName(), len(s.properties))
+	subscriptions := make([]string, 0, len(s.subscriptions))
+	for subscription := range s.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Strings(subscriptions)
+	s.printf("subscriptions: %s\n", strings.Join(subscriptions, ", "))
+	if s.queueName != "" {
+		s.printf("bound to: %s\n", s.queueName)
+	}
+	return nil
+}
+
+func (s *shell) help(string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.printf("  %-48s %s\n", commands[name].usage, commands[name].help)
+	}
+	s.printf("  %-48s %s\n", "exit", "leave the shell, also quit or Ctrl-D")
+	return nil
+}
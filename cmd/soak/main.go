@@ -0,0 +1,398 @@
+// Command soak runs a sample continuously for a long time, hours or days, to catch leaks in handler code and in the
+// samples themselves. The sample is built with pkg/profiling/autostart linked in (see internal/samplerun), and its
+// goroutines and live heap are snapshotted every interval, along with the metrics it serves in the Prometheus format
+// when -metrics-url is set. At the end of the run the growth trends of the goroutines and of the live heap, the slope
+// of a linear regression over the snapshots after the warmup, are checked against the thresholds: the command fails
+// with a report when a trend exceeds them.
+//
+//	go run ./cmd/soak -duration 72h -interval 5m patterns/guaranteed_receiver.go
+//	SOLACE_METRICS_ADDR=localhost:2112 go run ./cmd/soak -duration 8h -metrics-url http://localhost:2112/metrics \
+//	  -max-heap-growth 1048576 -heap-dir heap patterns/direct_receiver.go
+//
+// The snapshots are appended to -snapshots as newline delimited JSON while the run goes on, the report is written to
+// -report at the end. With -heap-dir the sample also writes heap profiles, to compare with go tool pprof -base once a
+// heap trend is reported. An interrupt or SIGTERM ends the run early, with a report.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/samplerun"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/profiling"
+)
+
+// Snapshot is the state of the sample at a point of the run
+type Snapshot struct {
+	Time        time.Time          `json:"time"`
+	Goroutines  int                `json:"goroutines"`
+	HeapAlloc   uint64             `json:"heap_alloc_bytes"`
+	HeapLive    uint64             `json:"heap_live_bytes"`
+	HeapObjects uint64             `json:"heap_objects"`
+	Metrics     map[string]float64 `json:"metrics,omitempty"`
+}
+
+// Trend is the growth of a value over the run, per hour
+type Trend struct {
+	Name           string  `json:"name"`
+	First          float64 `json:"first"`
+	Last           float64 `json:"last"`
+	SlopePerHour   float64 `json:"slope_per_hour"`
+	MaxPerHour     float64 `json:"max_per_hour"`
+	Exceeded       bool    `json:"exceeded"`
+	SamplesInTrend int     `json:"samples_in_trend"`
+}
+
+// Report is written at the end of the run
+type Report struct {
+	Sample    string    `json:"sample"`
+	Args      []string  `json:"args,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Snapshots int       `json:"snapshots"`
+	// Interrupted is true when the run was ended by an interrupt before the duration
+	Interrupted bool `json:"interrupted"`
+	// ExitedEarly is true when the sample stopped by itself before the end of the run
+	ExitedEarly bool   `json:"exited_early"`
+	ExitError   string `json:"exit_error,omitempty"`
+	// PollFailures counts the snapshots that could not be taken
+	PollFailures int                `json:"poll_failures"`
+	Trends       []Trend            `json:"trends"`
+	MetricDeltas map[string]float64 `json:"metric_deltas,omitempty"`
+	Passed       bool               `json:"passed"`
+}
+
+// slope returns the least squares slope of the values over the times, per hour
+func slope(times []time.Time, values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, t := range times {
+		x := t.Sub(times[0]).Hours()
+		sumX += x
+		sumY += values[i]
+		sumXY += x * values[i]
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// trend computes the trend of a value over the snapshots
+func trend(name string, snapshots []Snapshot, value func(Snapshot) float64, maxPerHour float64) Trend {
+	t := Trend{Name: name, MaxPerHour: maxPerHour, SamplesInTrend: len(snapshots)}
+	if len(snapshots) == 0 {
+		return t
+	}
+	times := make([]time.Time, len(snapshots))
+	values := make([]float64, len(snapshots))
+	for i, s := range snapshots {
+		times[i], values[i] = s.Time, value(s)
+	}
+	t.First, t.Last = values[0], values[len(values)-1]
+	t.SlopePerHour = slope(times, values)
+	t.Exceeded = maxPerHour > 0 && t.SlopePerHour > maxPerHour
+	return t
+}
+
+// fetchGauges reads the gauges published by pkg/profiling on /debug/vars
+func fetchGauges(client *http.Client, address string) (profiling.Gauges, error) {
+	var vars struct {
+		Profiling *profiling.Gauges `json:"profiling"`
+	}
+	response, err := client.Get("http://" + address + "/debug/vars")
+	if err != nil {
+		return profiling.Gauges{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return profiling.Gauges{}, fmt.Errorf("/debug/vars: %s", response.Status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(&vars); err != nil {
+		return profiling.Gauges{}, err
+	}
+	if vars.Profiling == nil {
+		return profiling.Gauges{}, errors.New("/debug/vars: no profiling gauges")
+	}
+	return *vars.Profiling, nil
+}
+
+// fetchMetrics reads the samples of the metrics whose names start with the prefix from a Prometheus text endpoint,
+// keyed by name and labels
+func fetchMetrics(client *http.Client, url, prefix string) (map[string]float64, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, response.Status)
+	}
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		// name{labels} value, the labels may hold spaces but the value does not
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(line[:i])] = value
+	}
+	return values, scanner.Err()
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run runs the soak test and returns the exit code, 1 when the test failed
+func run() int {
+	duration := flag.Duration("duration", time.Hour, "duration of the run")
+	interval := flag.Duration("interval", time.Minute, "period of the snapshots")
+	warmup := flag.Duration("warmup", 10*time.Minute, "start of the run left out of the trends, while caches and buffers fill up")
+	maxHeapGrowth := flag.Float64("max-heap-growth", 10*1024*1024, "maximum growth of the live heap, in bytes per hour, 0 disables the check")
+	maxGoroutineGrowth := flag.Float64("max-goroutine-growth", 10, "maximum growth of the goroutines, per hour, 0 disables the check")
+	address := flag.String("addr", "localhost:6061", "address of the pprof and expvar endpoints of the sample")
+	metricsURL := flag.String("metrics-url", "", "Prometheus endpoint of the sample snapshotted with the gauges, e.g. http://localhost:2112/metrics")
+	metricsPrefix := flag.String("metrics-prefix", "solace_", "prefix of the metrics snapshotted from -metrics-url")
+	heapDir := flag.String("heap-dir", "", "directory the sample writes heap profiles to, none are written when empty")
+	snapshotsFile := flag.String("snapshots", "soak-snapshots.ndjson", "file the snapshots are appended to")
+	reportFile := flag.String("report", "soak-report.json", "file the report is written to")
+	stopTimeout := flag.Duration("stop-timeout", 30*time.Second, "time given to the sample to terminate after the interrupt, before it is killed")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: soak [flags] <sample.go | sample directory> [--] [sample arguments]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+	if *interval <= 0 || *duration <= *warmup {
+		fmt.Fprintln(os.Stderr, "-interval must be positive and -duration longer than -warmup")
+		return 2
+	}
+	sampleArgs := flag.Args()[1:]
+	if len(sampleArgs) > 0 && sampleArgs[0] == "--" {
+		sampleArgs = sampleArgs[1:]
+	}
+
+	tmp, err := os.MkdirTemp("", "solace-soak")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+
+	binary, err := samplerun.Build(tmp, flag.Arg(0), "SolaceSamples.com/PubSub+Go/pkg/profiling/autostart")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	snapshotsOut, err := os.OpenFile(*snapshotsFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer snapshotsOut.Close()
+
+	cmd := exec.Command(binary, sampleArgs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(),
+		"SOLACE_PPROF_ADDR="+*address,
+		"SOLACE_PPROF_HEAP_DIR="+*heapDir,
+		"SOLACE_PPROF_HEAP_INTERVAL="+interval.String(),
+		// the gauges are read fresh at every snapshot, the sample reports them at the same pace
+		"SOLACE_PPROF_GAUGE_INTERVAL="+interval.String(),
+	)
+
+	// an interrupt or SIGTERM ends the run with a report, the sample is interrupted by stopSample
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, lifecycle.ShutdownSignals...)
+
+	report := Report{Sample: flag.Arg(0), Args: sampleArgs, Start: time.Now()}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	encoder := json.NewEncoder(snapshotsOut)
+	var snapshots []Snapshot
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	end := time.After(*duration)
+	running := true
+soak:
+	for {
+		select {
+		case <-end:
+			break soak
+		case <-interrupt:
+			report.Interrupted = true
+			break soak
+		case err := <-exited:
+			running = false
+			report.ExitedEarly = true
+			if err != nil {
+				report.ExitError = err.Error()
+			}
+			break soak
+		case <-ticker.C:
+		}
+
+		gauges, err := fetchGauges(client, *address)
+		var metrics map[string]float64
+		if err == nil && *metricsURL != "" {
+			metrics, err = fetchMetrics(client, *metricsURL, *metricsPrefix)
+		}
+		if err != nil {
+			report.PollFailures++
+			fmt.Fprintln(os.Stderr, "soak: snapshot failed:", err)
+			continue
+		}
+		snapshot := Snapshot{
+			Time:        time.Now(),
+			Goroutines:  gauges.Goroutines,
+			HeapAlloc:   gauges.HeapAlloc,
+			HeapLive:    gauges.HeapLive,
+			HeapObjects: gauges.HeapObjects,
+			Metrics:     metrics,
+		}
+		snapshots = append(snapshots, snapshot)
+		if err := encoder.Encode(snapshot); err != nil {
+			fmt.Fprintln(os.Stderr, "soak: could not write the snapshot:", err)
+		}
+		fmt.Fprintf(os.Stderr, "soak: %s goroutines=%d heap live=%d bytes\n",
+			time.Since(report.Start).Round(time.Second), snapshot.Goroutines, snapshot.HeapLive)
+	}
+
+	if running {
+		stopSample(cmd, exited, *stopTimeout)
+	}
+	report.End = time.Now()
+	report.Snapshots = len(snapshots)
+
+	// the trends leave the warmup out
+	var steady []Snapshot
+	for _, s := range snapshots {
+		if s.Time.Sub(report.Start) >= *warmup {
+			steady = append(steady, s)
+		}
+	}
+	report.Trends = []Trend{
+		trend("goroutines", steady, func(s Snapshot) float64 { return float64(s.Goroutines) }, *maxGoroutineGrowth),
+		trend("heap_live_bytes", steady, func(s Snapshot) float64 { return float64(s.HeapLive) }, *maxHeapGrowth),
+		trend("heap_objects", steady, func(s Snapshot) float64 { return float64(s.HeapObjects) }, 0),
+	}
+	if len(snapshots) > 1 && snapshots[0].Metrics != nil {
+		first, last := snapshots[0].Metrics, snapshots[len(snapshots)-1].Metrics
+		report.MetricDeltas = map[string]float64{}
+		for name, value := range last {
+			report.MetricDeltas[name] = value - first[name]
+		}
+	}
+	report.Passed = !report.ExitedEarly && len(steady) >= 2
+	for _, t := range report.Trends {
+		report.Passed = report.Passed && !t.Exceeded
+	}
+
+	printReport(os.Stderr, report, len(steady))
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(*reportFile, append(encoded, '\n'), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "soak: could not write the report:", err)
+		return 1
+	}
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// stopSample interrupts the sample, for a graceful termination, and kills it after the timeout
+func stopSample(cmd *exec.Cmd, exited <-chan error, timeout time.Duration) {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+	case <-time.After(timeout):
+		fmt.Fprintln(os.Stderr, "soak: the sample did not terminate, killing it")
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func printReport(w io.Writer, report Report, steady int) {
+	fmt.Fprintf(w, "\nSoak run of %s: %s, %d snapshot(s), %d after the warmup\n",
+		report.Sample, report.End.Sub(report.Start).Round(time.Second), report.Snapshots, steady)
+	if report.ExitedEarly {
+		fmt.Fprintf(w, "FAIL: the sample exited before the end of the run: %s\n", report.ExitError)
+	}
+	if steady < 2 {
+		fmt.Fprintln(w, "FAIL: not enough snapshots after the warmup to compute the trends")
+	}
+	for _, t := range report.Trends {
+		status := "ok  "
+		if t.Exceeded {
+			status = "FAIL"
+		}
+		limit := "no limit"
+		if t.MaxPerHour > 0 {
+			limit = fmt.Sprintf("max %.0f/h", t.MaxPerHour)
+		}
+		fmt.Fprintf(w, "%s %-16s %.0f -> %.0f, trend %+.1f/h (%s)\n", status, t.Name, t.First, t.Last, t.SlopePerHour, limit)
+	}
+	if len(report.MetricDeltas) > 0 {
+		names := make([]string, 0, len(report.MetricDeltas))
+		for name := range report.MetricDeltas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(w, "Metric changes over the run:")
+		for _, name := range names {
+			if delta := report.MetricDeltas[name]; delta != 0 {
+				fmt.Fprintf(w, "  %s %+g\n", name, delta)
+			}
+		}
+	}
+	if report.Passed {
+		fmt.Fprintln(w, "PASS")
+	} else {
+		fmt.Fprintln(w, "FAIL")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Flag is a flag of a sample, as declared in its source
+type Flag struct {
+	Name    string
+	Default string
+	Usage   string
+}
+
+// flagFuncs are the functions of the flag package declaring a flag, with the index of the name argument
+var flagFuncs = map[string]int{
+	"String": 0, "Int": 0, "Int64": 0, "Uint": 0, "Uint64": 0, "Bool": 0, "Duration": 0, "Float64": 0,
+	"StringVar": 1, "IntVar": 1, "Int64Var": 1, "UintVar": 1, "Uint64Var": 1, "BoolVar": 1, "DurationVar": 1, "Float64Var": 1,
+}
+
+// describe prints the sample, the comments documenting it and the flags it declares
+func describe(w io.Writer, sample Sample) error {
+	files, err := sourceFiles(sample.Path)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	var notes []string
+	var flags []Flag
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		notes = append(notes, fileNotes(file)...)
+		flags = append(flags, fileFlags(file)...)
+	}
+
+	fmt.Fprintf(w, "%s  %s\n%s\n", sample.Name, sample.Path, sample.Summary)
+	if len(sample.With) > 0 {
+		fmt.Fprintf(w, "Runs after %s, in the background\n", strings.Join(sample.With, ", "))
+	}
+	if len(sample.Requires) > 0 {
+		requires := make([]string, len(sample.Requires))
+		for i, feature := range sample.Requires {
+			requires[i] = string(feature)
+		}
+		fmt.Fprintf(w, "Requires %s on the broker, see solace-samples doctor\n", strings.Join(requires, ", "))
+	}
+	for _, note := range notes {
+		fmt.Fprintf(w, "\n%s", note)
+	}
+	if len(flags) > 0 {
+		fmt.Fprintln(w, "\nFlags, after -- on the run command line:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, flag := range flags {
+			fmt.Fprintf(tw, "  -%s\t%s\t%s\n", flag.Name, flag.Default, flag.Usage)
+		}
+		tw.Flush()
+	}
+	return nil
+}
+
+// sourceFiles returns the .go file, or the .go files of the package directory
+func sourceFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w, run solace-samples from the root of the module", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	return filepath.Glob(filepath.Join(path, "*.go"))
+}
+
+// fileNotes returns the package documentation and the free-standing comments at the top level of the file, the
+// samples explain what they show in those rather than in the documentation of their declarations
+func fileNotes(file *ast.File) []string {
+	var notes []string
+	if file.Doc != nil {
+		notes = append(notes, file.Doc.Text())
+	}
+	for _, group := range file.Comments {
+		if group == file.Doc || group.Pos() < file.Name.End() {
+			continue
+		}
+		free := true
+		for _, decl := range file.Decls {
+			// within a declaration, or its documentation
+			if group.Pos() >= decl.Pos() && group.End() <= decl.End() || group == declDoc(decl) {
+				free = false
+				break
+			}
+		}
+		if free {
+			notes = append(notes, group.Text())
+		}
+	}
+	return notes
+}
+
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc
+	case *ast.GenDecl:
+		return decl.Doc
+	}
+	return nil
+}
+
+// fileFlags returns the flags declared with the flag package, the name and the usage are the literal ones of the
+// source and the default its expression
+func fileFlags(file *ast.File) []Flag {
+	var flags []Flag
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := selector.X.(*ast.Ident); !ok || pkg.Name != "flag" {
+			return true
+		}
+		index, ok := flagFuncs[selector.Sel.Name]
+		if !ok || len(call.Args) != index+3 {
+			return true
+		}
+		flags = append(flags, Flag{
+			Name:    literal(call.Args[index]),
+			Default: types.ExprString(call.Args[index+1]),
+			Usage:   literal(call.Args[index+2]),
+		})
+		return true
+	})
+	return flags
+}
+
+// literal returns the value of a string literal, or the expression
+func literal(expr ast.Expr) string {
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if value, err := strconv.Unquote(lit.Value); err == nil {
+			return value
+		}
+	}
+	return types.ExprString(expr)
+}
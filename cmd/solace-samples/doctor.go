@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// doctorTopic is the topic of the messages of the guaranteed probe
+const doctorTopic = "solace/samples/go/doctor"
+
+// Probe is the outcome of the probe of a feature
+type Probe struct {
+	// Supported is yes, no, or unknown when the probe could not tell, e.g. SEMP is not reachable
+	Supported string
+	Detail    string
+}
+
+// probeOf returns the probe of a check: yes without an error, no with it
+func probeOf(detail string, err error) Probe {
+	if err != nil {
+		return Probe{Supported: "no", Detail: err.Error()}
+	}
+	return Probe{Supported: "yes", Detail: detail}
+}
+
+// doctor connects to the broker with the shared settings, probes the features the samples need and reports which
+// samples will work
+func doctor(w io.Writer, env []string) error {
+	// the shared flags set on the command line take precedence, as they do for the samples run
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		os.Setenv(name, value)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	brokerConfig, err := sampleconfig.LoadFrom(ctx, sampleconfig.Setting("SOLACE_SECRETS_SOURCE", "env"))
+	if err != nil {
+		return fmt.Errorf("could not load the broker configuration: %w", err)
+	}
+	host, _ := brokerConfig.Properties[config.TransportLayerPropertyHost].(string)
+	vpn, _ := brokerConfig.Properties[config.ServicePropertyVPNName].(string)
+	fmt.Fprintf(w, "Broker %s, VPN %s\n\n", host, vpn)
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err == nil {
+		err = messagingService.Connect()
+	}
+	if err != nil {
+		fmt.Fprintf(w, "Could not connect, none of the samples will work: %s\n", err)
+		return fmt.Errorf("could not connect to the broker: %w", err)
+	}
+	defer messagingService.Disconnect()
+
+	probes := map[Feature]Probe{}
+	probes[Guaranteed] = probeOf("persistent message published and received on a temporary queue", probeGuaranteed(messagingService))
+	probes[Outcomes] = Probe{Supported: "unknown", Detail: "needs guaranteed messaging"}
+	if probes[Guaranteed].Supported == "yes" {
+		probes[Outcomes] = probeOf("failed and rejected outcomes supported", probeOutcomes(messagingService))
+	}
+	probes[Compression] = probeCompression(brokerConfig.Properties)
+
+	sempClient := &sempMonitor{
+		url:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+		vpn:      vpn,
+		username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+		password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	probes[SEMP] = sempClient.probeAPI(ctx)
+	unknown := Probe{Supported: "unknown", Detail: "needs SEMP, see SOLACE_SEMP_URL, SOLACE_SEMP_USERNAME and SOLACE_SEMP_PASSWORD"}
+	probes[Replay], probes[PartitionedQueues] = unknown, unknown
+	if probes[SEMP].Supported == "yes" {
+		probes[Replay] = sempClient.probeReplay(ctx)
+		probes[PartitionedQueues] = sempClient.probePartitionedQueues(ctx)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FEATURE\tSUPPORTED\tDETAIL")
+	for _, feature := range Features {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", feature, probes[feature].Supported, probes[feature].Detail)
+	}
+	table.Flush()
+
+	fmt.Fprintln(w)
+	table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SAMPLE\tSTATUS\tMISSING")
+	for _, sample := range Samples {
+		status := "works"
+		var missing, unknown []string
+		for _, feature := range sample.Requires {
+			switch probes[feature].Supported {
+			case "no":
+				missing = append(missing, string(feature))
+			case "unknown":
+				unknown = append(unknown, string(feature)+"?")
+			}
+		}
+		switch {
+		case len(missing) > 0:
+			status = "will not work"
+		case len(unknown) > 0:
+			status = "unknown"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", sample.Name, status, strings.Join(append(missing, unknown...), ", "))
+	}
+	return table.Flush()
+}
+
+// probeGuaranteed publishes a persistent message to a topic a temporary queue subscribes to, and receives it
+func probeGuaranteed(messagingService solace.MessagingService) error {
+	receiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(doctorTopic)).
+		Build(resource.QueueNonDurableExclusiveAnonymous())
+	if err != nil {
+		return err
+	}
+	if err := receiver.Start(); err != nil {
+		return fmt.Errorf("could not bind to a temporary queue, the client profile may not allow guaranteed messages or endpoint creation: %w", err)
+	}
+	defer receiver.Terminate(time.Second)
+
+	publisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err != nil {
+		return err
+	}
+	if err := publisher.Start(); err != nil {
+		return err
+	}
+	defer publisher.Terminate(time.Second)
+	message, err := messagingService.MessageBuilder().BuildWithStringPayload("doctor")
+	if err != nil {
+		return err
+	}
+	if err := publisher.PublishAwaitAcknowledgement(message, resource.TopicOf(doctorTopic), 5*time.Second, nil); err != nil {
+		return fmt.Errorf("the persistent message was not acknowledged: %w", err)
+	}
+	received, err := receiver.ReceiveMessage(5 * time.Second)
+	if err != nil {
+		return fmt.Errorf("the persistent message was not received: %w", err)
+	}
+	return receiver.Ack(received)
+}
+
+// probeOutcomes binds a receiver requiring the failed and rejected outcomes, which brokers without the support of the
+// settlement outcomes refuse
+func probeOutcomes(messagingService solace.MessagingService) error {
+	receiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithRequiredMessageOutcomeSupport(config.PersistentReceiverFailedOutcome, config.PersistentReceiverRejectedOutcome).
+		Build(resource.QueueNonDurableExclusiveAnonymous())
+	if err != nil {
+		return err
+	}
+	if err := receiver.Start(); err != nil {
+		return fmt.Errorf("the broker refused the settlement outcomes, the nack samples need a more recent broker: %w", err)
+	}
+	return receiver.Terminate(time.Second)
+}
+
+// probeCompression connects to the compressed SMF port, SOLACE_COMPRESSED_HOST or port 55003 of the first host
+func probeCompression(properties config.ServicePropertyMap) Probe {
+	host, _ := properties[config.TransportLayerPropertyHost].(string)
+	first, _, _ := strings.Cut(host, ",")
+	_, address, ok := strings.Cut(first, "://")
+	if !ok {
+		address = first
+	}
+	hostname, _, err := net.SplitHostPort(address)
+	if err != nil {
+		hostname = address
+	}
+	compressedHost := sampleconfig.Setting("SOLACE_COMPRESSED_HOST", "tcp://"+net.JoinHostPort(strings.Trim(hostname, "[]"), "55003"))
+
+	compressed := config.ServicePropertyMap{}
+	for name, value := range properties {
+		compressed[name] = value
+	}
+	compressed[config.TransportLayerPropertyHost] = compressedHost
+	compressed[config.TransportLayerPropertyCompressionLevel] = 1
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(compressed).Build()
+	if err == nil {
+		err = messagingService.Connect()
+	}
+	if err != nil {
+		return Probe{Supported: "no", Detail: fmt.Sprintf("could not connect to %s, set SOLACE_COMPRESSED_HOST to the compressed port: %s", compressedHost, err)}
+	}
+	messagingService.Disconnect()
+	return Probe{Supported: "yes", Detail: "connected to " + compressedHost}
+}
+
+// sempMonitor sends requests to the SEMP v2 monitor API of the broker
+type sempMonitor struct {
+	url, vpn           string
+	username, password string
+	client             *http.Client
+}
+
+// sempResponse is the part of the SEMP monitor responses read by the probes
+type sempResponse struct {
+	Data json.RawMessage `json:"data"`
+	Meta struct {
+		Error *struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+	} `json:"meta"`
+}
+
+// get sends a GET request to the monitor API and decodes the data of the response into data
+func (m *sempMonitor) get(ctx context.Context, path string, data interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(m.url, "/")+"/SEMP/v2/monitor"+path, nil)
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(m.username, m.password)
+	request.Header.Set("Accept", "application/json")
+	response, err := m.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	var body sempResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return fmt.Errorf("%s, invalid SEMP response: %w", response.Status, err)
+	}
+	if body.Meta.Error != nil {
+		return fmt.Errorf("%s: %s", body.Meta.Error.Status, body.Meta.Error.Description)
+	}
+	if response.StatusCode != http.StatusOK {
+		return errors.New(response.Status)
+	}
+	return json.Unmarshal(body.Data, data)
+}
+
+// probeAPI reads the version of the SEMP API
+func (m *sempMonitor) probeAPI(ctx context.Context) Probe {
+	var about struct {
+		Platform    string `json:"platform"`
+		SEMPVersion string `json:"sempVersion"`
+	}
+	if err := m.get(ctx, "/about/api", &about); err != nil {
+		return Probe{Supported: "no", Detail: fmt.Sprintf("%s: %s", m.url, err)}
+	}
+	return Probe{Supported: "yes", Detail: fmt.Sprintf("%s, SEMP %s", about.Platform, about.SEMPVersion)}
+}
+
+// probeReplay looks for an enabled replay log on the VPN
+func (m *sempMonitor) probeReplay(ctx context.Context) Probe {
+	var replayLogs []struct {
+		Name           string `json:"replayLogName"`
+		IngressEnabled bool   `json:"ingressEnabled"`
+		EgressEnabled  bool   `json:"egressEnabled"`
+	}
+	if err := m.get(ctx, "/msgVpns/"+url.PathEscape(m.vpn)+"/replayLogs", &replayLogs); err != nil {
+		return Probe{Supported: "no", Detail: err.Error()}
+	}
+	for _, replayLog := range replayLogs {
+		if replayLog.IngressEnabled && replayLog.EgressEnabled {
+			return Probe{Supported: "yes", Detail: "replay log " + replayLog.Name}
+		}
+	}
+	return Probe{Supported: "no", Detail: "no enabled replay log on the VPN, create one with -provision, see internal/semp"}
+}
+
+// probePartitionedQueues reads the partition count of a queue, an attribute brokers without partitioned queues
+// do not know
+func (m *sempMonitor) probePartitionedQueues(ctx context.Context) Probe {
+	var queues []struct {
+		PartitionCount int `json:"partitionCount"`
+	}
+	path := "/msgVpns/" + url.PathEscape(m.vpn) + "/queues?count=1&select=queueName,partitionCount"
+	if err := m.get(ctx, path, &queues); err != nil {
+		return Probe{Supported: "no", Detail: "the broker does not know the partition count of queues: " + err.Error()}
+	}
+	return Probe{Supported: "yes", Detail: "queues have a partition count"}
+}
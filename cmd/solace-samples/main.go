@@ -0,0 +1,144 @@
+// Command solace-samples lists, describes and runs the samples of the repository by name, with the connection and
+// logging settings given once, as flags or environment variables, rather than per sample:
+//
+//	go run ./cmd/solace-samples list
+//	go run ./cmd/solace-samples describe nack-receiver
+//	go run ./cmd/solace-samples run nack-receiver --host tcp://broker:55555 --vpn orders --username app
+//	go run ./cmd/solace-samples run request-reply
+//	go run ./cmd/solace-samples run latency-publisher --log-level debug -- -count 1000
+//	go run ./cmd/solace-samples doctor --host tcp://broker:55555
+//
+// The run command has a subcommand per sample. The sample is built from its source (see internal/samplerun) and run
+// with the shared flags set on the command line passed as the SOLACE_* environment variables the samples read, the
+// others are inherited from the environment; the arguments after -- are the flags of the sample. The samples of a
+// pair, e.g. request-reply, are run together: the replier first, in the background, then the requestor, their output
+// prefixed with their name, and the replier is interrupted once the requestor exits. Run it from the root of the
+// module.
+//
+// The doctor command connects with the same settings and probes the features of the broker and VPN the samples need
+// (guaranteed messaging, settlement outcomes, replay, partitioned queues, compression and SEMP), then reports which
+// samples will work, rather than leaving a sample to fail cryptically on an older broker.
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// SharedFlag is a flag of every sample, passed to the sample as an environment variable
+type SharedFlag struct {
+	Name  string
+	Env   string
+	Usage string
+}
+
+// SharedFlags are the persistent flags of the command
+var SharedFlags = []SharedFlag{
+	{Name: "config", Env: "SOLACE_CONFIG_FILE", Usage: "YAML or JSON file of the connection settings, see internal/sampleconfig"},
+	{Name: "host", Env: "SOLACE_HOST", Usage: "broker URI, or comma separated host list"},
+	{Name: "vpn", Env: "SOLACE_VPN", Usage: "message VPN"},
+	{Name: "username", Env: "SOLACE_USERNAME", Usage: "client username"},
+	{Name: "password", Env: "SOLACE_PASSWORD", Usage: "client password"},
+	{Name: "auth-scheme", Env: "SOLACE_AUTH_SCHEME", Usage: "authentication scheme: basic, client-certificate, oauth2 or kerberos"},
+	{Name: "secrets-source", Env: "SOLACE_SECRETS_SOURCE", Usage: "where the connection properties are loaded from: env, vault, aws or azure"},
+	{Name: "log-level", Env: "SOLACE_LOG_LEVEL", Usage: "debug, info, warn or error, see pkg/apilog"},
+	{Name: "log-format", Env: "SOLACE_LOG_FORMAT", Usage: "text or json, see pkg/apilog"},
+	{Name: "metrics-addr", Env: "SOLACE_METRICS_ADDR", Usage: "address the samples serve their Prometheus metrics on"},
+}
+
+// exitCode is the exit code of the sample run
+var exitCode int
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(exitCode)
+}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "solace-samples",
+		Short:        "List, describe and run the samples of the PubSub+ Go API, and check the broker they run against",
+		SilenceUsage: true,
+	}
+	for _, shared := range SharedFlags {
+		// no default, the environment variable is inherited unless the flag is set
+		root.PersistentFlags().String(shared.Name, "", shared.Usage+", overrides "+shared.Env)
+	}
+
+	root.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the samples",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tPATH\tSUMMARY")
+			for _, sample := range Samples {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", sample.Name, sample.Path, sample.Summary)
+			}
+			w.Flush()
+		},
+	})
+
+	names := make([]string, 0, len(Samples))
+	for _, sample := range Samples {
+		names = append(names, sample.Name)
+	}
+	root.AddCommand(&cobra.Command{
+		Use:       "describe <sample>",
+		Short:     "Describe a sample: its documentation and its flags, read from its source",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: names,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sample, ok := lookup(args[0])
+			if !ok {
+				return fmt.Errorf("unknown sample %q, see solace-samples list", args[0])
+			}
+			return describe(cmd.OutOrStdout(), sample)
+		},
+	})
+
+	run := &cobra.Command{
+		Use:   "run <sample> [-- sample flags]",
+		Short: "Build and run a sample",
+	}
+	stagger := run.PersistentFlags().Duration("stagger", defaultStagger, "delay between starting the background samples of a pair and the sample")
+	for _, sample := range Samples {
+		sample := sample
+		run.AddCommand(&cobra.Command{
+			Use:   sample.Name + " [-- sample flags]",
+			Short: sample.Summary,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				code, err := runSample(sample, args, sharedEnv(root), *stagger)
+				exitCode = code
+				return err
+			},
+		})
+	}
+	root.AddCommand(run)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "doctor",
+		Short: "Probe the features of the broker and report which samples will work",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doctor(cmd.OutOrStdout(), sharedEnv(root))
+		},
+	})
+	return root
+}
+
+// sharedEnv returns the environment variables of the shared flags set on the command line
+func sharedEnv(root *cobra.Command) []string {
+	var env []string
+	for _, shared := range SharedFlags {
+		if flag := root.PersistentFlags().Lookup(shared.Name); flag != nil && flag.Changed {
+			env = append(env, shared.Env+"="+flag.Value.String())
+		}
+	}
+	return env
+}
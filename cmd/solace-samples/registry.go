@@ -0,0 +1,169 @@
+package main
+
+// Sample is a runnable sample of the repository
+type Sample struct {
+	// Name is the name of the run subcommand
+	Name string
+	// Path is the .go file or the package directory of the sample, relative to the root of the module
+	Path string
+	// Summary is the one line description printed by list
+	Summary string
+	// With names the samples started before this one, in the background, and interrupted once it exits, e.g. the
+	// replier of a requestor
+	With []string
+	// Requires are the broker features the sample needs beyond a connection, see doctor
+	Requires []Feature
+}
+
+// Feature is a broker feature a sample may need, probed by the doctor command
+type Feature string
+
+// Features probed by the doctor command
+const (
+	// Guaranteed is the publication and the receipt of persistent messages on a temporary queue
+	Guaranteed Feature = "guaranteed"
+	// Outcomes is the support of the failed and rejected settlement outcomes by the broker
+	Outcomes Feature = "settlement-outcomes"
+	// Replay is an enabled replay log on the VPN
+	Replay Feature = "replay"
+	// PartitionedQueues is the support of partitioned queues by the broker
+	PartitionedQueues Feature = "partitioned-queues"
+	// Compression is a connection to the compressed SMF port
+	Compression Feature = "compression"
+	// SEMP is the SEMP v2 monitor API, with the management credentials
+	SEMP Feature = "semp"
+)
+
+// Features are the features probed, in order
+var Features = []Feature{Guaranteed, Outcomes, Replay, PartitionedQueues, Compression, SEMP}
+
+// Samples is the registry of the samples, one run subcommand each
+var Samples = []Sample{
+	{Name: "hello-world", Path: "patterns/hello_world.go", Summary: "publish and receive a direct message on the same connection"},
+
+	{Name: "direct-publisher", Path: "patterns/direct_publisher.go", Summary: "publish direct messages"},
+	{Name: "direct-receiver", Path: "patterns/direct_receiver.go", Summary: "receive direct messages"},
+	{Name: "direct-processor", Path: "patterns/direct_processor.go", Summary: "receive direct messages and publish a processed copy"},
+	{Name: "rate-limited-publisher", Path: "patterns/rate_limited_publisher.go", Summary: "cap the publishing rate with a token bucket"},
+	{Name: "publisher-readiness", Path: "patterns/publisher_readiness.go", Summary: "wait for the readiness listener when the publish buffer is full"},
+	{Name: "large-payload-profile", Path: "patterns/large_payload_profile.go", Summary: "compare the allocations of the payload getters on large messages"},
+	{Name: "compression-benchmark", Path: "patterns/compression_benchmark.go", Summary: "benchmark the compression levels on a payload profile",
+		Requires: []Feature{Compression}},
+	{Name: "sampled-logging", Path: "patterns/sampled_logging.go", Summary: "log every message through a sampling zap logger"},
+
+	{Name: "guaranteed-publisher", Path: "patterns/guaranteed_publisher.go", Summary: "publish persistent messages and track their acknowledgements",
+		Requires: []Feature{Guaranteed}},
+	{Name: "guaranteed-receiver", Path: "patterns/guaranteed_receiver.go", Summary: "receive persistent messages from a queue",
+		Requires: []Feature{Guaranteed}},
+	{Name: "guaranteed-processor", Path: "patterns/guaranteed_processor.go", Summary: "receive persistent messages and publish a processed copy",
+		Requires: []Feature{Guaranteed}},
+	{Name: "nack-receiver", Path: "patterns/guaranteed_receiver_nack.go", Summary: "settle persistent messages as accepted, failed or rejected",
+		Requires: []Feature{Guaranteed, Outcomes}},
+	{Name: "selector-nack-receiver", Path: "patterns/guaranteed_receiver_selector_nack.go", Summary: "receive with a message selector and reject what is not processed",
+		Requires: []Feature{Guaranteed, Outcomes}},
+	{Name: "ack-modes", Path: "patterns/guaranteed_receiver_ack_modes.go", Summary: "compare the auto and client acknowledgement modes",
+		Requires: []Feature{Guaranteed}},
+	{Name: "replay-checkpoint", Path: "patterns/guaranteed_receiver_replay_checkpoint.go", Summary: "resume a message replay from a checkpointed replication group message ID",
+		Requires: []Feature{Guaranteed, Replay}},
+	{Name: "provisioned-queue", Path: "patterns/guaranteed_receiver_provisioned_queue.go", Summary: "provision the queue of a receiver when it is missing",
+		Requires: []Feature{Guaranteed}},
+	{Name: "receiver-reconnection", Path: "patterns/guaranteed_receiver_reconnection.go", Summary: "keep receiving persistent messages across reconnections",
+		Requires: []Feature{Guaranteed}},
+	{Name: "cert-hot-reload", Path: "patterns/guaranteed_receiver_cert_hot_reload.go", Summary: "reconnect a receiver with a rotated client certificate",
+		Requires: []Feature{Guaranteed}},
+	{Name: "multi-queue-receiver", Path: "patterns/guaranteed_multi_queue_receiver.go", Summary: "receive from several queues on one connection",
+		Requires: []Feature{Guaranteed}},
+	{Name: "queue-topic-mapping", Path: "patterns/guaranteed_queue_topic_mapping.go", Summary: "attract messages to a queue with topic subscriptions",
+		Requires: []Feature{Guaranteed}},
+	{Name: "dr-switchover", Path: "patterns/guaranteed_dr_switchover.go", Summary: "survive a disaster recovery switchover of the broker",
+		Requires: []Feature{Guaranteed}},
+	{Name: "postgres-sink", Path: "patterns/guaranteed_receiver_postgres_sink.go", Summary: "store persistent messages in PostgreSQL exactly once, deduplicated by message ID",
+		Requires: []Feature{Guaranteed}},
+	{Name: "sqlite-outbox", Path: "patterns/guaranteed_publisher_sqlite_outbox.go", Summary: "publish the events of a transactional outbox in SQLite, marked sent on their receipt",
+		Requires: []Feature{Guaranteed}},
+	{Name: "redis-cache", Path: "patterns/redis_cache_updater.go", Summary: "warm a Redis cache from last value queues, then keep it up to date with live updates",
+		Requires: []Feature{Guaranteed}},
+	{Name: "debezium-cdc", Path: "patterns/debezium_cdc_consumer.go", Summary: "apply Debezium change events to a local store, in order per key with a partitioned queue",
+		Requires: []Feature{Guaranteed, PartitionedQueues}},
+	{Name: "queue-lag-watcher", Path: "patterns/queue_lag_watcher.go", Summary: "watch the backlog of queues through SEMP",
+		Requires: []Feature{SEMP}},
+
+	{Name: "request-reply", Path: "patterns/request-reply/direct_requestor_blocking.go", Summary: "blocking requestor with its replier",
+		With: []string{"replier-blocking"}},
+	{Name: "request-reply-non-blocking", Path: "patterns/request-reply/direct_requestor_non_blocking.go", Summary: "non-blocking requestor with its replier",
+		With: []string{"replier-non-blocking"}},
+	{Name: "requestor-blocking", Path: "patterns/request-reply/direct_requestor_blocking.go", Summary: "send requests and wait for each reply"},
+	{Name: "requestor-non-blocking", Path: "patterns/request-reply/direct_requestor_non_blocking.go", Summary: "send requests and receive the replies in a callback"},
+	{Name: "replier-blocking", Path: "patterns/request-reply/direct_replier_blocking.go", Summary: "reply to the requests received in a loop"},
+	{Name: "replier-non-blocking", Path: "patterns/request-reply/direct_replier_non_blocking.go", Summary: "reply to the requests received in a callback"},
+
+	{Name: "latency", Path: "patterns/latency/latency_publisher.go", Summary: "measure the end to end latency, publisher and consumer",
+		With: []string{"latency-consumer"}},
+	{Name: "latency-publisher", Path: "patterns/latency/latency_publisher.go", Summary: "publish timestamped messages for latency-consumer"},
+	{Name: "latency-consumer", Path: "patterns/latency/latency_consumer.go", Summary: "report the latency percentiles of the received messages"},
+
+	{Name: "otel-tracing", Path: "patterns/otel-tracing/otel-publisher", Summary: "trace a message from the publisher to the subscriber, printed to stdout",
+		With: []string{"otel-subscriber"}},
+	{Name: "otel-publisher", Path: "patterns/otel-tracing/otel-publisher", Summary: "publish messages in spans exported to stdout"},
+	{Name: "otel-subscriber", Path: "patterns/otel-tracing/otel-subscriber", Summary: "receive messages in child spans of the publish spans"},
+	{Name: "otlp-tracing", Path: "patterns/otel-tracing/otlp-publisher", Summary: "trace a message from the publisher to the consumer over OTLP",
+		With: []string{"otlp-consumer"}},
+	{Name: "otlp-publisher", Path: "patterns/otel-tracing/otlp-publisher", Summary: "publish messages in spans exported over OTLP"},
+	{Name: "otlp-consumer", Path: "patterns/otel-tracing/otlp-consumer", Summary: "receive messages in spans exported over OTLP"},
+
+	{Name: "grpc-gateway", Path: "patterns/grpc-gateway/gateway-client", Summary: "publish and stream messages through a gRPC gateway to the broker",
+		With: []string{"grpc-gateway-server"}, Requires: []Feature{Guaranteed}},
+	{Name: "grpc-gateway-server", Path: "patterns/grpc-gateway/gateway", Summary: "serve a gRPC publish and streaming subscribe API backed by the broker",
+		Requires: []Feature{Guaranteed}},
+	{Name: "grpc-gateway-client", Path: "patterns/grpc-gateway/gateway-client", Summary: "publish and stream messages through the gRPC gateway"},
+
+	{Name: "cloudevents", Path: "patterns/cloudevents_consumer.go", Summary: "exchange CloudEvents in binary and structured mode, publisher and consumer",
+		With: []string{"cloudevents-publisher"}},
+	{Name: "cloudevents-publisher", Path: "patterns/cloudevents_publisher.go", Summary: "publish CloudEvents in binary and structured mode"},
+	{Name: "cloudevents-consumer", Path: "patterns/cloudevents_consumer.go", Summary: "decode the CloudEvents received, whatever their mode"},
+
+	{Name: "avro", Path: "patterns/avro_consumer.go", Summary: "exchange Avro payloads with their schemas in a Confluent Schema Registry",
+		With: []string{"avro-publisher"}},
+	{Name: "avro-publisher", Path: "patterns/avro_publisher.go", Summary: "publish Avro payloads in the Confluent wire format"},
+	{Name: "avro-consumer", Path: "patterns/avro_consumer.go", Summary: "decode Avro payloads with the schemas of their IDs"},
+
+	{Name: "protobuf-bsr", Path: "patterns/protobuf_bsr_consumer.go", Summary: "exchange protobuf messages with their types resolved from the Buf Schema Registry",
+		With: []string{"protobuf-bsr-publisher"}},
+	{Name: "protobuf-bsr-publisher", Path: "patterns/protobuf_bsr_publisher.go", Summary: "publish protobuf messages of a type of the Buf Schema Registry"},
+	{Name: "protobuf-bsr-consumer", Path: "patterns/protobuf_bsr_consumer.go", Summary: "decode protobuf messages of any type of a Buf module as JSON"},
+
+	{Name: "reconnection-strategies", Path: "patterns/reconnection_strategies.go", Summary: "compare the reconnection retry strategies"},
+	{Name: "reconnection-monitor", Path: "patterns/reconnection_monitor.go", Summary: "alert when an outage lasts longer than a threshold"},
+	{Name: "host-list-failover", Path: "patterns/host_list_failover.go", Summary: "fail over between the brokers of a host list"},
+	{Name: "keepalive-tuning", Path: "patterns/keepalive_tuning.go", Summary: "detect dead connections with the keepalive settings"},
+	{Name: "supervised-restart", Path: "patterns/supervised_restart.go", Summary: "rebuild the service once it is interrupted for good"},
+	{Name: "termination-listener", Path: "patterns/termination_listener.go", Summary: "be notified when the API terminates a publisher or a receiver"},
+	{Name: "service-pool", Path: "patterns/service_pool.go", Summary: "spread the load over a pool of connections"},
+	{Name: "services-bridge", Path: "patterns/multiple_services_bridge.go", Summary: "bridge topics between two messaging services"},
+	{Name: "mqtt-interoperability", Path: "patterns/mqtt_interoperability.go", Summary: "exchange messages with an MQTT client, both ways",
+		Requires: []Feature{Guaranteed}},
+	{Name: "application-identification", Path: "patterns/application_identification.go", Summary: "name the client and describe the application to the broker"},
+	{Name: "api-metrics-report", Path: "patterns/api_metrics_report.go", Summary: "report the API metrics per interval"},
+	{Name: "metadata-explorer", Path: "patterns/inbound_message_metadata_explorer.go", Summary: "print every header and property of the received messages"},
+
+	{Name: "websocket-connection", Path: "patterns/websocket_connection.go", Summary: "connect over WebSocket"},
+	{Name: "websocket-fanout", Path: "patterns/websocket_fanout_server.go", Summary: "fan messages out to browsers over WebSockets, with per-client subscriptions"},
+	{Name: "trust-store", Path: "patterns/secure_connection_trust_store.go", Summary: "validate the broker certificate against a trust store"},
+	{Name: "strict-tls", Path: "patterns/secure_connection_strict_tls.go", Summary: "refuse insecure TLS settings"},
+	{Name: "cipher-suites", Path: "patterns/secure_connection_cipher_suites.go", Summary: "restrict the TLS versions and cipher suites"},
+	{Name: "client-certificate", Path: "patterns/secure_connection_client_certificate.go", Summary: "authenticate with a client certificate"},
+	{Name: "certificate-pinning", Path: "patterns/secure_connection_certificate_pinning.go", Summary: "pin the keys of the broker certificate"},
+	{Name: "auth-scheme-selection", Path: "patterns/authentication_scheme_selection.go", Summary: "select the authentication scheme on the command line"},
+	{Name: "oauth2-client-credentials", Path: "patterns/oauth2_client_credentials.go", Summary: "authenticate with OAuth2 tokens of the client credentials flow"},
+	{Name: "oidc-token-watcher", Path: "patterns/oidc_token_file_watcher.go", Summary: "refresh the OIDC token of the connection from a watched file"},
+}
+
+// lookup returns the sample with the name
+func lookup(name string) (Sample, bool) {
+	for _, sample := range Samples {
+		if sample.Name == name {
+			return sample, true
+		}
+	}
+	return Sample{}, false
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/samplerun"
+)
+
+// defaultStagger leaves the background samples of a pair the time to connect and subscribe
+const defaultStagger = 2 * time.Second
+
+// companionGrace is how long a background sample has to terminate once interrupted before it is killed
+const companionGrace = 10 * time.Second
+
+// runSample builds and runs the sample, after its background samples, and returns its exit code
+func runSample(sample Sample, args, env []string, stagger time.Duration) (int, error) {
+	companions := make([]Sample, 0, len(sample.With))
+	for _, name := range sample.With {
+		companion, ok := lookup(name)
+		if !ok {
+			return 1, fmt.Errorf("%s runs with the unknown sample %s", sample.Name, name)
+		}
+		companions = append(companions, companion)
+	}
+
+	tmp, err := os.MkdirTemp("", "solace-samples")
+	if err != nil {
+		return 1, err
+	}
+	defer os.RemoveAll(tmp)
+
+	// the samples are built first, so that a build failure does not leave background samples running
+	binaries := map[string]string{}
+	for _, s := range append(companions, sample) {
+		if _, err := os.Stat(s.Path); err != nil {
+			return 1, fmt.Errorf("%s: %w, run solace-samples from the root of the module", s.Name, err)
+		}
+		dir := filepath.Join(tmp, s.Name)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			return 1, err
+		}
+		binary, err := samplerun.Build(dir, s.Path)
+		if err != nil {
+			return 1, err
+		}
+		binaries[s.Name] = binary
+	}
+
+	// the interrupt reaches the samples, in the same process group, which terminate gracefully
+	signal.Ignore(os.Interrupt)
+
+	var mu sync.Mutex
+	var background []*exec.Cmd
+	var outputs []*prefixWriter
+	for _, companion := range companions {
+		cmd := exec.Command(binaries[companion.Name])
+		stdout, stderr := newPrefixWriter(&mu, os.Stdout, companion.Name), newPrefixWriter(&mu, os.Stderr, companion.Name)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		cmd.Env = append(os.Environ(), env...)
+		if err := cmd.Start(); err != nil {
+			stopAll(background)
+			return 1, fmt.Errorf("could not start %s: %w", companion.Name, err)
+		}
+		background = append(background, cmd)
+		outputs = append(outputs, stdout, stderr)
+	}
+	if len(background) > 0 {
+		time.Sleep(stagger)
+	}
+
+	cmd := exec.Command(binaries[sample.Name], args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if len(background) > 0 {
+		stdout, stderr := newPrefixWriter(&mu, os.Stdout, sample.Name), newPrefixWriter(&mu, os.Stderr, sample.Name)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		outputs = append(outputs, stdout, stderr)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	err = cmd.Run()
+
+	stopAll(background)
+	for _, output := range outputs {
+		output.Flush()
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// stopAll interrupts the background samples and waits for them to terminate, killing the ones that take too long
+func stopAll(cmds []*exec.Cmd) {
+	var wg sync.WaitGroup
+	for _, cmd := range cmds {
+		wg.Add(1)
+		go func(cmd *exec.Cmd) {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() {
+				cmd.Wait()
+				close(done)
+			}()
+			cmd.Process.Signal(os.Interrupt)
+			select {
+			case <-done:
+			case <-time.After(companionGrace):
+				cmd.Process.Kill()
+				<-done
+			}
+		}(cmd)
+	}
+	wg.Wait()
+}
+
+// prefixWriter writes the lines of a sample prefixed with its name, whole lines at a time so that the outputs of the
+// samples run together do not interleave within a line
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix []byte
+	line   []byte
+}
+
+func newPrefixWriter(mu *sync.Mutex, w io.Writer, name string) *prefixWriter {
+	return &prefixWriter{mu: mu, w: w, prefix: []byte(name + " | ")}
+}
+
+// Write writes the complete lines of b and keeps the rest until the end of the line is written
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.line = append(p.line, b...)
+	for {
+		end := bytes.IndexByte(p.line, '\n')
+		if end < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.line[:end+1]); err != nil {
+			return len(b), err
+		}
+		p.line = p.line[end+1:]
+	}
+}
+
+// Flush writes the last line when it does not end with a new line
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.line) > 0 {
+		p.writeLine(append(p.line, '\n'))
+		p.line = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	_, err := p.w.Write(append(append([]byte(nil), p.prefix...), line...))
+	return err
+}
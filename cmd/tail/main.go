@@ -0,0 +1,200 @@
+// Command tail binds to a topic or a queue and streams the received messages to stdout as
+// newline delimited JSON, one object per message holding the selected fields.
+//
+//	go run ./cmd/tail -topic 'solace/samples/>' -follow
+//	go run ./cmd/tail -queue durable-queue -consume -count 10 -fields destination,payload,userProperties
+//	go run ./cmd/tail -queue durable-queue -consume -selector "priority = 'high'" -follow | jq .payload
+//
+// Tailing a queue consumes it: every message written is acknowledged and removed from the queue, and the messages are
+// taken from the other consumers of the queue while the tool runs, so -queue requires -consume. Tail the topics the
+// queue subscribes to with -topic to watch its messages without consuming them.
+//
+// The available fields are the JSON keys of the msgdump package, e.g. destination, applicationMessageId,
+// correlationId, senderTimestamp, userProperties, redelivered, replicationGroupMessageId, payloadSize and payload.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/msgdump"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// receiveFunc waits up to the timeout for the next message, the acknowledge func settles it once it was written
+type receiveFunc func(timeout time.Duration) (msg message.InboundMessage, acknowledge func(), err error)
+
+// selectFields renders the message through msgdump and keeps the requested fields only,
+// all fields are kept when none are requested
+func selectFields(msg message.InboundMessage, fields []string) (map[string]interface{}, error) {
+	encoded, err := msgdump.JSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	all := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return all, nil
+	}
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+func main() {
+	topicName := flag.String("topic", "", "topic subscription to tail with a direct receiver")
+	queueName := flag.String("queue", "", "durable queue to tail, the messages are consumed from the queue (requires -consume)")
+	consume := flag.Bool("consume", false, "acknowledge the messages of -queue once written, removing them from the queue")
+	selector := flag.String("selector", "", "message selector applied when tailing a queue")
+	fieldList := flag.String("fields", "destination,payload", "comma separated list of fields to output, empty for all fields")
+	count := flag.Int("count", 0, "exit after this many messages (0 for no limit)")
+	follow := flag.Bool("follow", false, "keep waiting for new messages instead of exiting once no message arrived for the idle time")
+	idle := flag.Duration("idle", 2*time.Second, "exit when no message arrived for this long (ignored with -follow)")
+	flag.Parse()
+
+	if (*topicName == "") == (*queueName == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of -topic or -queue is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *queueName != "" && !*consume {
+		fmt.Fprintln(os.Stderr, "-queue removes the messages tailed from the queue, confirm with -consume")
+		os.Exit(2)
+	}
+	if *selector != "" && *queueName == "" {
+		fmt.Fprintln(os.Stderr, "-selector is only supported with -queue")
+		os.Exit(2)
+	}
+
+	var fields []string
+	for _, field := range strings.Split(*fieldList, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	// Configuration parameters, loaded from the secrets source selected with -secrets-source (environment by default)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+	brokerConfig.Properties[config.ServicePropertyGenerateReceiveTimestamps] = true
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	var receive receiveFunc
+	var receiver solace.LifecycleControl
+
+	if *topicName != "" {
+		directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+			WithSubscriptions(resource.TopicSubscriptionOf(*topicName)).
+			Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not start the direct receiver: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		receive = func(timeout time.Duration) (message.InboundMessage, func(), error) {
+			msg, err := directReceiver.ReceiveMessage(timeout)
+			return msg, func() {}, err
+		}
+		receiver = directReceiver
+	} else {
+		builder := messagingService.CreatePersistentMessageReceiverBuilder().WithMessageClientAcknowledgement()
+		if *selector != "" {
+			builder = builder.WithMessageSelector(*selector)
+		}
+		persistentReceiver, err := builder.Build(resource.QueueDurableExclusive(*queueName))
+		if err == nil {
+			err = persistentReceiver.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		receive = func(timeout time.Duration) (message.InboundMessage, func(), error) {
+			msg, err := persistentReceiver.ReceiveMessage(timeout)
+			// only acknowledge messages once they were written to stdout
+			return msg, func() { persistentReceiver.Ack(msg) }, err
+		}
+		receiver = persistentReceiver
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+
+	encoder := json.NewEncoder(os.Stdout)
+	received := 0
+receiveLoop:
+	for *count == 0 || received < *count {
+		select {
+		case <-interrupted:
+			break receiveLoop
+		default:
+		}
+
+		timeout := *idle
+		if *follow {
+			// wake up regularly to check for interrupts
+			timeout = 500 * time.Millisecond
+		}
+		msg, acknowledge, err := receive(timeout)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if errors.As(err, &timeoutErr) && *follow {
+				continue
+			}
+			if !errors.As(err, &timeoutErr) {
+				fmt.Fprintln(os.Stderr, "Receive failed: ", err)
+			}
+			break
+		}
+
+		record, err := selectFields(msg, fields)
+		if err == nil {
+			err = encoder.Encode(record)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write the message: ", err)
+			break
+		}
+		acknowledge()
+		received++
+	}
+
+	receiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+	fmt.Fprintf(os.Stderr, "Received %d message(s)\n", received)
+}
@@ -0,0 +1,116 @@
+// Command topictree subscribes to a wildcard root and builds the topic hierarchy of the messages flowing on the
+// broker, with the messages, bytes and rate of every level, then renders it periodically as an ASCII or JSON tree, to
+// discover what is actually published.
+//
+//	go run ./cmd/topictree
+//	go run ./cmd/topictree -root 'solace/samples/>' -depth 3 -interval 2s
+//	go run ./cmd/topictree -root '>' -format json -duration 1m > topics.json
+//
+// The counts include the messages of the levels below, the rates are the ones since the previous rendering. Each
+// level is sorted by rate, then name, and shows at most -max-children children. With -duration the tree is rendered
+// once more and the command exits after that long, it otherwise runs until interrupted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+func main() {
+	root := flag.String("root", ">", "wildcard topic subscription of the messages to count")
+	interval := flag.Duration("interval", 5*time.Second, "period the tree is rendered")
+	duration := flag.Duration("duration", 0, "exit after this long, 0 runs until interrupted")
+	format := flag.String("format", "text", "output format: text (ASCII tree) or json (one tree per line)")
+	depth := flag.Int("depth", 0, "render the levels down to this depth, 0 for all of them")
+	maxChildren := flag.Int("max-children", 20, "render at most this many children per level, 0 for all of them")
+	flag.Parse()
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s', expected text or json\n", *format)
+		os.Exit(2)
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "-interval must be positive")
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	tree := NewTree()
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(*root)).
+		Build()
+	if err == nil {
+		err = directReceiver.Start()
+	}
+	if err == nil {
+		err = directReceiver.ReceiveAsync(func(msg message.InboundMessage) {
+			payload, _ := msg.GetPayloadAsBytes()
+			tree.Add(msg.GetDestinationName(), len(payload))
+		})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not subscribe to the root: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	output := func() {
+		snapshot := tree.Snapshot(*depth, *maxChildren)
+		if *format == "json" {
+			json.NewEncoder(os.Stdout).Encode(snapshot)
+			return
+		}
+		fmt.Printf("\n%s %s: ", time.Now().Format(time.TimeOnly), *root)
+		snapshot.WriteText(os.Stdout)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+render:
+	for {
+		select {
+		case <-ticker.C:
+			output()
+		case <-timeout:
+			output()
+			break render
+		case <-interrupted:
+			break render
+		}
+	}
+
+	directReceiver.Terminate(1 * time.Second)
+	messagingService.Disconnect()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// node is a level of the topic hierarchy
+type node struct {
+	name     string
+	children map[string]*node
+	// own counts the messages published to the topic of the node, total the ones published to the node or below it
+	own, total int64
+	bytes      int64
+	// previous is the total of the previous snapshot, to compute the rate
+	previous int64
+	rate     float64
+}
+
+// Tree is the topic hierarchy of the messages received, safe for concurrent use
+type Tree struct {
+	mu       sync.Mutex
+	root     *node
+	snapshot time.Time
+}
+
+// NewTree returns an empty tree
+func NewTree() *Tree {
+	return &Tree{root: &node{children: map[string]*node{}}, snapshot: time.Now()}
+}
+
+// Add counts a message published to the topic
+func (t *Tree) Add(topic string, size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	current := t.root
+	current.total++
+	current.bytes += int64(size)
+	for _, level := range strings.Split(topic, "/") {
+		child, ok := current.children[level]
+		if !ok {
+			child = &node{name: level, children: map[string]*node{}}
+			current.children[level] = child
+		}
+		child.total++
+		child.bytes += int64(size)
+		current = child
+	}
+	current.own++
+}
+
+// Node is a level of the hierarchy as rendered, with the messages counted since the start and their rate since the
+// previous snapshot
+type Node struct {
+	Name     string  `json:"name"`
+	Topic    string  `json:"topic"`
+	Messages int64   `json:"messages"`
+	Own      int64   `json:"own,omitempty"`
+	Bytes    int64   `json:"bytes"`
+	Rate     float64 `json:"rate"`
+	Children []*Node `json:"children,omitempty"`
+	// Hidden is the number of children beyond the max children and depth, not rendered
+	Hidden int `json:"hidden,omitempty"`
+}
+
+// Snapshot computes the rates since the previous snapshot and returns the hierarchy, each level sorted by rate then
+// name, down to the depth (0 for no limit) and with at most maxChildren children per level (0 for no limit)
+func (t *Tree) Snapshot(depth, maxChildren int) *Node {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(t.snapshot).Seconds()
+	t.snapshot = now
+	update(t.root, elapsed)
+	return render(t.root, "", 0, depth, maxChildren)
+}
+
+func update(n *node, elapsed float64) {
+	if elapsed > 0 {
+		n.rate = float64(n.total-n.previous) / elapsed
+	}
+	n.previous = n.total
+	for _, child := range n.children {
+		update(child, elapsed)
+	}
+}
+
+func render(n *node, topic string, level, depth, maxChildren int) *Node {
+	rendered := &Node{Name: n.name, Topic: topic, Messages: n.total, Own: n.own, Bytes: n.bytes, Rate: n.rate}
+	if depth > 0 && level >= depth {
+		rendered.Hidden = len(n.children)
+		return rendered
+	}
+	children := make([]*node, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].rate != children[j].rate {
+			return children[i].rate > children[j].rate
+		}
+		return children[i].name < children[j].name
+	})
+	if maxChildren > 0 && len(children) > maxChildren {
+		rendered.Hidden = len(children) - maxChildren
+		children = children[:maxChildren]
+	}
+	for _, child := range children {
+		childTopic := child.name
+		if topic != "" {
+			childTopic = topic + "/" + child.name
+		}
+		rendered.Children = append(rendered.Children, render(child, childTopic, level+1, depth, maxChildren))
+	}
+	return rendered
+}
+
+// WriteText renders the hierarchy as an ASCII tree, one level per line
+func (n *Node) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "%d message(s), %.1f msg/s\n", n.Messages, n.Rate)
+	n.writeChildren(w, "")
+}
+
+func (n *Node) writeChildren(w io.Writer, indent string) {
+	for i, child := range n.Children {
+		branch, next := "├── ", "│   "
+		if i == len(n.Children)-1 && n.Hidden == 0 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s  %d msg(s) %.1f msg/s %s\n", indent, branch, child.Name, child.Messages, child.Rate, formatBytes(child.Bytes))
+		child.writeChildren(w, indent+next)
+	}
+	if n.Hidden > 0 {
+		fmt.Fprintf(w, "%s└── … %d more\n", indent, n.Hidden)
+	}
+}
+
+// formatBytes formats the size with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
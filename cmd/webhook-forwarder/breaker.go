@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// breakerState is the state of the circuit breaker
+type breakerState string
+
+const (
+	// closed lets every request through
+	closed breakerState = "closed"
+	// open lets no request through until the cooldown is over
+	open breakerState = "open"
+	// halfOpen lets one request through, the probe closing the breaker again or opening it for another cooldown
+	halfOpen breakerState = "half-open"
+)
+
+// breaker is a circuit breaker opening after a number of consecutive failed requests, so a down endpoint is not
+// hammered with the retries of every message: while it is open, the forwarder waits instead of sending requests
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	// onChange is called with the new state on every transition, outside of the lock
+	onChange func(breakerState)
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probing is set while the request of the half-open breaker is in flight
+	probing bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration, onChange func(breakerState)) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, onChange: onChange, state: closed}
+}
+
+// wait blocks until a request may be sent: the breaker is closed, or half-open with no probe in flight. It returns
+// the error of the context when it is done first.
+func (b *breaker) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		var changed bool
+		if b.state == open && time.Since(b.openedAt) >= b.cooldown {
+			b.state, changed = halfOpen, true
+		}
+		allowed := b.state == closed || (b.state == halfOpen && !b.probing)
+		if b.state == halfOpen && allowed {
+			b.probing = true
+		}
+		delay := 100 * time.Millisecond
+		if b.state == open {
+			delay = b.cooldown - time.Since(b.openedAt)
+		}
+		b.mu.Unlock()
+		if changed {
+			b.onChange(halfOpen)
+		}
+		if allowed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// success records a request the endpoint answered, closing the breaker
+func (b *breaker) success() {
+	b.mu.Lock()
+	changed := b.state != closed
+	b.state, b.failures, b.probing = closed, 0, false
+	b.mu.Unlock()
+	if changed {
+		b.onChange(closed)
+	}
+}
+
+// failure records a failed request, opening the breaker after threshold failures in a row or when the probe failed
+func (b *breaker) failure() {
+	b.mu.Lock()
+	b.failures++
+	changed := b.state == halfOpen || (b.state == closed && b.failures >= b.threshold)
+	if changed {
+		b.state, b.openedAt = open, time.Now()
+	}
+	b.probing = false
+	b.mu.Unlock()
+	if changed {
+		b.onChange(open)
+	}
+}
+
+// current returns the state of the breaker
+func (b *breaker) current() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/payloaddecode"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// maxRetryAfter caps the delay a Retry-After response header asks for
+const maxRetryAfter = time.Minute
+
+// Counts are the counts of the forwarder, by settlement outcome
+type Counts struct {
+	Accepted int64
+	Failed   int64
+	Rejected int64
+	// Requests is the number of requests sent, retries included
+	Requests int64
+}
+
+// forwarder posts the messages to the endpoint and decides their settlement outcome: ACCEPTED once the endpoint
+// answered 2xx, REJECTED on a permanent error (any other 4xx or 3xx status), FAILED once the retries of a retryable
+// error (no response, 408, 425, 429 or 5xx) are exhausted, so the broker redelivers the message later
+type forwarder struct {
+	ctx     context.Context
+	client  *http.Client
+	url     string
+	headers http.Header
+	retries int
+	backoff time.Duration
+	breaker *breaker
+
+	accepted, failed, rejected, requests atomic.Int64
+}
+
+// attempt is the response to a request, or the error of a request left without one
+type attempt struct {
+	status     int
+	body       string
+	retryAfter time.Duration
+	err        error
+}
+
+// retryable reports whether the request may succeed when sent again
+func (a attempt) retryable() bool {
+	switch {
+	case a.err != nil:
+		return true
+	case a.status == http.StatusRequestTimeout, a.status == http.StatusTooEarly, a.status == http.StatusTooManyRequests:
+		return true
+	default:
+		return a.status >= 500
+	}
+}
+
+func (a attempt) Error() string {
+	if a.err != nil {
+		return a.err.Error()
+	}
+	if a.body == "" {
+		return fmt.Sprintf("%d %s", a.status, http.StatusText(a.status))
+	}
+	return fmt.Sprintf("%d %s: %s", a.status, http.StatusText(a.status), a.body)
+}
+
+// handle is the settling handler of the receiver: it posts the message, retrying the retryable errors with an
+// exponential backoff, and returns the outcome with its reason
+func (f *forwarder) handle(inbound message.InboundMessage) (config.MessageSettlementOutcome, error) {
+	outcome, reason := f.forward(inbound)
+	switch outcome {
+	case config.PersistentReceiverAcceptedOutcome:
+		f.accepted.Add(1)
+	case config.PersistentReceiverRejectedOutcome:
+		f.rejected.Add(1)
+	default:
+		f.failed.Add(1)
+	}
+	return outcome, reason
+}
+
+func (f *forwarder) forward(inbound message.InboundMessage) (config.MessageSettlementOutcome, error) {
+	var last attempt
+	for i := 0; i <= f.retries; i++ {
+		if i > 0 {
+			delay := f.backoff << (i - 1)
+			if last.retryAfter > delay {
+				delay = last.retryAfter
+			}
+			select {
+			case <-f.ctx.Done():
+				return config.PersistentReceiverFailedOutcome, fmt.Errorf("interrupted before retrying: %w", last)
+			case <-time.After(delay):
+			}
+		}
+		// while the breaker is open the message waits, its redeliveries are not spent on an endpoint known to be down
+		if err := f.breaker.wait(f.ctx); err != nil {
+			return config.PersistentReceiverFailedOutcome, fmt.Errorf("interrupted while the circuit breaker is open: %w", err)
+		}
+		last = f.post(inbound)
+		if !last.retryable() {
+			// the endpoint answered, even a permanent error shows it is up
+			f.breaker.success()
+			if last.status/100 == 2 {
+				return config.PersistentReceiverAcceptedOutcome, nil
+			}
+			return config.PersistentReceiverRejectedOutcome, fmt.Errorf("permanent error: %w", last)
+		}
+		f.breaker.failure()
+	}
+	return config.PersistentReceiverFailedOutcome, fmt.Errorf("gave up after %d attempt(s): %w", f.retries+1, last)
+}
+
+// post sends the payload of the message to the endpoint, with its metadata in headers
+func (f *forwarder) post(inbound message.InboundMessage) attempt {
+	f.requests.Add(1)
+	payload, _ := inbound.GetPayloadAsBytes()
+	request, err := http.NewRequestWithContext(f.ctx, http.MethodPost, f.url, bytes.NewReader(payload))
+	if err != nil {
+		return attempt{err: err}
+	}
+	for name, values := range f.headers {
+		request.Header[name] = values
+	}
+	hints := payloaddecode.HintsOf(inbound)
+	if hints.ContentType == "" {
+		hints.ContentType = "application/octet-stream"
+	}
+	request.Header.Set("Content-Type", hints.ContentType)
+	if hints.ContentEncoding != "" {
+		request.Header.Set("Content-Encoding", hints.ContentEncoding)
+	}
+	request.Header.Set("Solace-Destination", inbound.GetDestinationName())
+	request.Header.Set("Solace-Redelivered", strconv.FormatBool(inbound.IsRedelivered()))
+	if id, ok := inbound.GetApplicationMessageID(); ok {
+		request.Header.Set("Solace-Message-Id", id)
+	}
+	if id, ok := inbound.GetCorrelationID(); ok {
+		request.Header.Set("Solace-Correlation-Id", id)
+	}
+	// the same key on every delivery of the message, for the endpoint to drop the redeliveries it already processed
+	if id, ok := inbound.GetReplicationGroupMessageID(); ok && id != nil {
+		request.Header.Set("Idempotency-Key", id.String())
+	}
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return attempt{err: err}
+	}
+	defer response.Body.Close()
+	// the start of the body explains the errors, the rest is read so the connection is reused
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<20))
+	a := attempt{status: response.StatusCode, retryAfter: retryAfter(response.Header.Get("Retry-After"))}
+	if response.StatusCode/100 != 2 {
+		a.body = strings.TrimSpace(string(body))
+	}
+	return a
+}
+
+// retryAfter parses a Retry-After header, seconds or an HTTP date, capped to maxRetryAfter
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = time.Until(at)
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return max(delay, 0)
+}
+
+func (f *forwarder) counts() Counts {
+	return Counts{Accepted: f.accepted.Load(), Failed: f.failed.Load(), Rejected: f.rejected.Load(), Requests: f.requests.Load()}
+}
@@ -0,0 +1,201 @@
+// Command webhook-forwarder consumes a queue and POSTs every message to an HTTP endpoint, the glue between the broker
+// and a service that only takes webhooks. The payload is the body of the request, with the content type of the message
+// (application/octet-stream when it has none) and its metadata in Solace-* headers; the replication group message ID
+// is the Idempotency-Key, the same on every redelivery.
+//
+//	go run ./cmd/webhook-forwarder -queue orders -url https://hooks.example.com/orders
+//	go run ./cmd/webhook-forwarder -queue orders -url http://localhost:9000/hook -header 'Authorization: Bearer token' -retries 5
+//
+// The messages are settled with the response of the endpoint:
+//
+//   - ACCEPTED on a 2xx status
+//   - REJECTED on a permanent error, any other 4xx or a 3xx status: the broker moves the message to the dead message
+//     queue when it is eligible and discards it otherwise
+//   - FAILED once -retries retries of a retryable error (no response, 408, 425, 429 or 5xx) failed, with an
+//     exponential backoff from -backoff or the delay of a Retry-After header: the broker redelivers the message
+//     until the max redelivery count of the queue
+//
+// After -breaker-failures failed requests in a row the circuit breaker opens: the receiver is paused and the message in
+// hand waits -breaker-cooldown, then probes the endpoint, the breaker closing again once it answers. With
+// SOLACE_AUDIT_FILE every settlement is recorded with its reason (see pkg/settleaudit), and with -provision the queue is
+// created through SEMP first.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// headerFlags collects the repeated -header 'Name: value' flags
+type headerFlags http.Header
+
+func (h headerFlags) String() string {
+	pairs := make([]string, 0, len(h))
+	for name, values := range h {
+		pairs = append(pairs, name+": "+strings.Join(values, ", "))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h headerFlags) Set(pair string) error {
+	name, value, ok := strings.Cut(pair, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected 'Name: value', got '%s'", pair)
+	}
+	http.Header(h).Add(textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value))
+	return nil
+}
+
+func main() {
+	queueName := flag.String("queue", "", "durable queue to forward")
+	url := flag.String("url", sampleconfig.Setting("SOLACE_WEBHOOK_URL", ""), "endpoint the messages are posted to (or SOLACE_WEBHOOK_URL)")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of a request")
+	retries := flag.Int("retries", 3, "retries of a retryable error before the message is failed")
+	backoff := flag.Duration("backoff", 1*time.Second, "delay before the first retry, doubled on every retry")
+	breakerFailures := flag.Int("breaker-failures", 5, "failed requests in a row opening the circuit breaker")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "time the circuit breaker stays open before probing the endpoint")
+	reportInterval := flag.Duration("report", 30*time.Second, "print the counts at this interval (0 to only print them on exit)")
+	headers := headerFlags{}
+	flag.Var(headers, "header", "header 'Name: value' added to every request (repeatable)")
+	flag.Parse()
+
+	if *queueName == "" || *url == "" {
+		fmt.Fprintln(os.Stderr, "-queue and -url are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *retries < 0 || *breakerFailures <= 0 {
+		fmt.Fprintln(os.Stderr, "-retries must not be negative and -breaker-failures must be positive")
+		os.Exit(2)
+	}
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid SOLACE_DRAIN_TIMEOUT: ", err)
+		os.Exit(2)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load the broker configuration: ", err)
+		os.Exit(1)
+	}
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp), the
+	// failed messages are redelivered 3 times before they are moved to the dead message queue
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName, MaxRedeliveryCount: 3}); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not provision the queue: ", err)
+		os.Exit(1)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build the messaging service: ", err)
+		os.Exit(1)
+	}
+	if err := messagingService.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not connect to the broker: ", err)
+		os.Exit(1)
+	}
+
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		WithRequiredMessageOutcomeSupport(config.PersistentReceiverFailedOutcome, config.PersistentReceiverRejectedOutcome).
+		Build(resource.QueueDurableExclusive(*queueName))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not bind to the queue: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	// Settlement audit log, every settlement is appended as a JSON line to SOLACE_AUDIT_FILE when it is set
+	var audit *settleaudit.Log
+	if path := sampleconfig.Setting("SOLACE_AUDIT_FILE", ""); path != "" {
+		if audit, err = settleaudit.Open(settleaudit.Options{Path: path}); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not open the audit file: ", err)
+			persistentReceiver.Terminate(0)
+			messagingService.Disconnect()
+			os.Exit(1)
+		}
+		defer audit.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &forwarder{
+		ctx:     ctx,
+		client:  &http.Client{Timeout: *timeout},
+		url:     *url,
+		headers: http.Header(headers),
+		retries: *retries,
+		backoff: *backoff,
+	}
+	// no message is delivered while the breaker is open, the one in hand probes the endpoint after the cooldown
+	f.breaker = newBreaker(*breakerFailures, *breakerCooldown, func(state breakerState) {
+		fmt.Fprintf(os.Stderr, "Circuit breaker %s\n", state)
+		switch state {
+		case open:
+			persistentReceiver.Pause()
+		case closed:
+			persistentReceiver.Resume()
+		}
+	})
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: paused, the message in hand settled, then terminated and
+	// disconnected (see pkg/lifecycle)
+	drainer := lifecycle.New(lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddReceiver(*queueName, persistentReceiver)
+	drainer.AddService(messagingService)
+	if err := persistentReceiver.ReceiveAsync(drainer.Handler(audit.Handler(*queueName, persistentReceiver, f.handle))); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not receive from the queue: ", err)
+		persistentReceiver.Terminate(0)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Forwarding %s to %s\n", *queueName, *url)
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, lifecycle.ShutdownSignals...)
+	var ticks <-chan time.Time
+	if *reportInterval > 0 {
+		ticker := time.NewTicker(*reportInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+wait:
+	for {
+		select {
+		case <-interrupted:
+			break wait
+		case <-ticks:
+			printCounts(f.counts(), f.breaker.current())
+		}
+	}
+
+	// the message in hand is failed rather than waiting for its retries, it is redelivered on the next run
+	cancel()
+	report := drainer.Shutdown()
+	fmt.Fprintln(os.Stderr, "Drain: ", report)
+	printCounts(f.counts(), f.breaker.current())
+}
+
+func printCounts(counts Counts, state breakerState) {
+	fmt.Fprintf(os.Stderr, "%d accepted, %d failed, %d rejected, %d request(s), circuit breaker %s\n",
+		counts.Accepted, counts.Failed, counts.Rejected, counts.Requests, state)
+}
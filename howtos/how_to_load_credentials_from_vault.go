@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Code example of how to load the broker credentials from HashiCorp Vault instead of plaintext environment variables.
+//
+//	vault kv put secret/solace/samples username=sample-user password=s3cr3t
+//	VAULT_ADDR=http://127.0.0.1:8200 VAULT_TOKEN=<token> go run how_to_load_credentials_from_vault.go -secrets-source vault
+//
+// Leased credentials are renewed in the background. Basic credentials cannot be updated on a connected messaging
+// service, so when Vault hands out new credentials a new messaging service is connected with them and the previous
+// one is disconnected.
+
+// ConnectMessagingService - builds and connects a messaging service with the given properties
+func ConnectMessagingService(properties config.ServicePropertyMap) (solace.MessagingService, error) {
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(properties).Build()
+	if err != nil {
+		return nil, err
+	}
+	if err := messagingService.Connect(); err != nil {
+		return nil, err
+	}
+	return messagingService, nil
+}
+
+func main() {
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Load the connection properties from the secrets source selected with -secrets-source
+	brokerConfig, err := sampleconfig.Load(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := ConnectMessagingService(brokerConfig.Properties)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Renew the leased credentials, switch to a new messaging service when the credentials change
+	changed := make(chan config.ServicePropertyMap, 1)
+	go brokerConfig.Renew(ctx, func(properties config.ServicePropertyMap) {
+		changed <- properties
+	})
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	for {
+		select {
+		case properties := <-changed:
+			fmt.Println("Credentials changed in Vault, reconnecting with the new credentials")
+			replacement, err := ConnectMessagingService(properties)
+			if err != nil {
+				fmt.Println("Could not connect with the new credentials, keeping the current connection: ", err)
+				continue
+			}
+			messagingService.Disconnect()
+			messagingService = replacement
+			fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+		case <-c:
+			// Disconnect the Message Service
+			messagingService.Disconnect()
+			fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+			return
+		}
+	}
+}
@@ -1,22 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace"
 	"solace.dev/go/messaging/pkg/solace/config"
 )
 
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 // Code examples of how to use the Endpoint Provisioner to Provision
 // queues on a Solace broker and Deprovision queues from a Solace broker.
 //
@@ -27,18 +22,17 @@ func getEnv(key, def string) string {
 // Possible Deprovision errors include:
 // [x] Unknown Queue - when a queue with the provided queue name does not exists on the broker
 func main() {
-	// logging.SetLogLevel(logging.LogLevelInfo)
-
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
 	messagingService, err := messaging.NewMessagingServiceBuilder().
-		FromConfigurationProvider(brokerConfig).
+		FromConfigurationProvider(brokerConfig.Properties).
 		WithProvisionTimeoutMs(10 * time.Millisecond). // set a provision timeout on the session
 		Build()
 
@@ -2,6 +2,10 @@
 package main
 
 import (
+    "context"
+    "fmt"
+
+    "SolaceSamples.com/PubSub+Go/internal/sampleconfig"
     "solace.dev/go/messaging"
     "solace.dev/go/messaging/pkg/solace/config"
     "solace.dev/go/messaging/pkg/solace"
@@ -28,19 +32,17 @@ func SetQueuePartitionKeyUsingFromConfigurationProvider(queuePartitionKeyValue s
     return outboundMessage
 }
 
-func Run() {
+func main() {
     payload := "Hello Solace"
     var service solace.MessagingService
-    var err error
 
-    brokerConfig := config.ServicePropertyMap{
-        config.TransportLayerPropertyHost:  getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-        config.ServicePropertyVPNName:  getEnv("SOLACE_VPN", "default"),
-        config.AuthenticationPropertySchemeBasicPassword:   getEnv("SOLACE_PASSWORD", "default"),
-        config.AuthenticationPropertySchemeBasicUserName:   getEnv("SOLACE_USERNAME", "default"),
-        }
+    // Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+    brokerConfig, err := sampleconfig.Load(context.Background())
+    if err != nil {
+        panic(err)
+    }
 
-    service, err = messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+    service, err = messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
     if err != nil {
         panic(err)
@@ -53,4 +55,7 @@ func Run() {
     messageWithPropertyPartitionKey := SetQueuePartitionKeyUsingWithProperty(partitionKeyValue, service, payload)
 
     messageWithFromConfigPartitionKey := SetQueuePartitionKeyUsingFromConfigurationProvider(partitionKeyValue, service, payload)
+
+    fmt.Println(messageWithPropertyPartitionKey)
+    fmt.Println(messageWithFromConfigPartitionKey)
 }
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/msgdump"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace/config"
 	"solace.dev/go/messaging/pkg/solace/message"
@@ -14,14 +18,7 @@ import (
 
 // Message Handler
 func MessageHandler(message message.InboundMessage) {
-	fmt.Printf("Message Dump %s \n", message)
-}
-
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
+	fmt.Printf("Message Dump\n%s", msgdump.Text(message))
 }
 
 func main() {
@@ -29,23 +26,23 @@ func main() {
 	// Define Topic Subscriptions
 	TOPIC_PREFIX := "solace/samples"
 
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the second listen port of a local broker by default
+	sampleconfig.SetDefault("SOLACE_HOST", "tcp://localhost:55554")
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
 	// Skip certificate validation
 	messagingService, err := messaging.NewMessagingServiceBuilder().
-		FromConfigurationProvider(brokerConfig).
+		FromConfigurationProvider(brokerConfig.Properties).
 		WithTransportSecurityStrategy(config.NewTransportSecurityStrategy().WithoutCertificateValidation()).
 		Build()
 
 	// With Certificate Validation. Note: assuming ou have a /trust_store dir with the .pem file stored in it
 	// messagingService, err := messaging.NewMessagingServiceBuilder().
-	// 	FromConfigurationProvider(brokerConfig).
+	// 	FromConfigurationProvider(brokerConfig.Properties).
 	// 	WithTransportSecurityStrategy(config.NewTransportSecurityStrategy().WithCertificateValidation(false, true, "./trust_store", "")).
 	// 	Build()
 
@@ -101,7 +98,7 @@ func main() {
 	// Handle interrupts
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 
 	// Block until a signal is received.
 	<-c
@@ -2,6 +2,9 @@
 package main
 
 import (
+        "context"
+
+        "SolaceSamples.com/PubSub+Go/internal/sampleconfig"
         "solace.dev/go/messaging"
         "solace.dev/go/messaging/pkg/solace"
         "solace.dev/go/messaging/pkg/solace/config"
@@ -32,22 +35,20 @@ func UpdateOAuth2Tokens(messagingService solace.MessagingService, newAccessToken
         return nil
 }
 
-func Run () {
+func main() {
 
         // Create a messaging service with invalid OAuth2 authentication tokens. These tokens will be updated later with valid values.
 
-        var err error
         var messagingService solace.MessagingService
 
-        brokerConfig := config.ServicePropertyMap{
-                config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-                config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-                config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-                config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+        // Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+        brokerConfig, err := sampleconfig.Load(context.Background())
+        if err != nil {
+                panic(err)
         }
 
         // Initialize the messaging service with invalid tokens
-        messagingService, err = messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).WithAuthenticationStrategy(config.OAuth2Authentication(
+        messagingService, err = messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).WithAuthenticationStrategy(config.OAuth2Authentication(
                         "invalid access token",
                         "invalid id token",
                         "",
@@ -0,0 +1,155 @@
+// Package bench holds the measurement helpers shared by the benchmark commands, cmd/bench-pub and cmd/bench-sub:
+// latency histograms summarized as percentiles, a pacer holding a fixed message rate, and throughput counters.
+package bench
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latency records durations in an HDR histogram, from 1µs up to the highest trackable latency with 3 significant
+// digits. It is safe for concurrent use.
+type Latency struct {
+	mu        sync.Mutex
+	histogram *hdrhistogram.Histogram
+}
+
+// NewLatency returns a histogram tracking latencies up to highest, higher latencies are recorded as highest
+func NewLatency(highest time.Duration) *Latency {
+	return &Latency{histogram: hdrhistogram.New(1, highest.Microseconds(), 3)}
+}
+
+// Record records a latency
+func (l *Latency) Record(d time.Duration) {
+	micros := d.Microseconds()
+	if micros < 1 {
+		micros = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if highest := l.histogram.HighestTrackableValue(); micros > highest {
+		micros = highest
+	}
+	l.histogram.RecordValue(micros)
+}
+
+// Summary returns the percentiles of the recorded latencies
+func (l *Latency) Summary() Summary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.histogram
+	us := func(v int64) time.Duration { return time.Duration(v) * time.Microsecond }
+	return Summary{
+		Count: h.TotalCount(),
+		Min:   us(h.Min()),
+		Mean:  time.Duration(h.Mean() * float64(time.Microsecond)),
+		P50:   us(h.ValueAtQuantile(50)),
+		P90:   us(h.ValueAtQuantile(90)),
+		P99:   us(h.ValueAtQuantile(99)),
+		P999:  us(h.ValueAtQuantile(99.9)),
+		Max:   us(h.Max()),
+	}
+}
+
+// Summary holds the percentiles of a latency histogram, encoded in JSON as nanoseconds
+type Summary struct {
+	Count int64         `json:"count"`
+	Min   time.Duration `json:"min_ns"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	P999  time.Duration `json:"p99_9_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// String renders the percentiles
+func (s Summary) String() string {
+	if s.Count == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("min=%s p50=%s p90=%s p99=%s p99.9=%s max=%s", s.Min, s.P50, s.P90, s.P99, s.P999, s.Max)
+}
+
+// Pacer spreads messages evenly at a fixed rate. It schedules every message from the start rather than from the
+// previous one, so the rate holds on average: after a slow publish the next messages are sent without waiting until
+// the schedule is caught up.
+type Pacer struct {
+	start    time.Time
+	interval time.Duration
+	sent     int64
+}
+
+// NewPacer returns a pacer for the rate in messages per second, 0 or less does not pace
+func NewPacer(rate int) *Pacer {
+	p := &Pacer{start: time.Now()}
+	if rate > 0 {
+		p.interval = time.Second / time.Duration(rate)
+	}
+	return p
+}
+
+// Wait blocks until the next message is due
+func (p *Pacer) Wait() {
+	if p.interval == 0 {
+		return
+	}
+	due := p.start.Add(time.Duration(p.sent) * p.interval)
+	p.sent++
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// Counter counts messages and bytes, and the rate since the previous interval. It is safe for concurrent use.
+type Counter struct {
+	messages, bytes int64
+
+	mu           sync.Mutex
+	last         time.Time
+	lastMessages int64
+	lastBytes    int64
+}
+
+// NewCounter returns a counter whose first interval starts now
+func NewCounter() *Counter {
+	return &Counter{last: time.Now()}
+}
+
+// Add counts a message of the size in bytes
+func (c *Counter) Add(size int) {
+	atomic.AddInt64(&c.messages, 1)
+	atomic.AddInt64(&c.bytes, int64(size))
+}
+
+// Total returns the messages and bytes counted
+func (c *Counter) Total() (messages, bytes int64) {
+	return atomic.LoadInt64(&c.messages), atomic.LoadInt64(&c.bytes)
+}
+
+// Interval returns the rates since the previous interval, in messages and bytes per second, and starts a new one
+func (c *Counter) Interval() (messagesPerSecond, bytesPerSecond float64) {
+	messages, bytes := c.Total()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(c.last).Seconds()
+	if elapsed > 0 {
+		messagesPerSecond = float64(messages-c.lastMessages) / elapsed
+		bytesPerSecond = float64(bytes-c.lastBytes) / elapsed
+	}
+	c.last, c.lastMessages, c.lastBytes = now, messages, bytes
+	return messagesPerSecond, bytesPerSecond
+}
+
+// Rate returns the count per second over the duration, e.g. the messages or bytes of a whole run
+func Rate(count int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(count) / d.Seconds()
+}
@@ -0,0 +1,395 @@
+//go:build integration
+
+// Package brokertest runs the integration tests of the samples against a PubSub+ software broker started in Docker
+// with testcontainers-go. The tests are behind the integration build tag, and run with the sample they test, e.g.
+//
+//	go test -tags integration -v patterns/guaranteed_receiver_nack.go patterns/guaranteed_receiver_nack_test.go
+//
+// A single broker is started for the test binary, on the first call to Start, and removed by the reaper of
+// testcontainers once the binary exits. Every test provisions its own queues, named after the test, so the tests do
+// not see the messages of each other. The image is solace/solace-pubsub-standard:latest, or SOLACE_TEST_IMAGE.
+package brokertest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"SolaceSamples.com/PubSub+Go/pkg/waitfor"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// DefaultImage is the image of the broker, unless SOLACE_TEST_IMAGE is set
+const DefaultImage = "solace/solace-pubsub-standard:latest"
+
+// StartupTimeout is how long the broker has to start, longer on the first run which pulls the image
+const StartupTimeout = 5 * time.Minute
+
+// The credentials of the broker, the default client username of the default VPN and the admin user for SEMP
+const (
+	VPN           = "default"
+	Username      = "default"
+	Password      = "default"
+	AdminUsername = "admin"
+	AdminPassword = "admin"
+)
+
+// Broker is the broker of the tests
+type Broker struct {
+	// Host is the SMF URI of the broker, e.g. tcp://localhost:49153
+	Host string
+	// SEMPURL is the URL of the SEMP v2 API, e.g. http://localhost:49154
+	SEMPURL string
+
+	client *http.Client
+}
+
+var (
+	startOnce sync.Once
+	broker    *Broker
+	startErr  error
+)
+
+// Start returns the broker of the test binary, started on the first call. The test fails when the broker can not be
+// started, e.g. without Docker.
+func Start(t testing.TB) *Broker {
+	t.Helper()
+	startOnce.Do(func() {
+		broker, startErr = start(context.Background())
+	})
+	if startErr != nil {
+		t.Fatalf("could not start the broker: %s", startErr)
+	}
+	return broker
+}
+
+func start(ctx context.Context) (*Broker, error) {
+	image := DefaultImage
+	if value := os.Getenv("SOLACE_TEST_IMAGE"); value != "" {
+		image = value
+	}
+	request := testcontainers.ContainerRequest{
+		Image: image,
+		Env: map[string]string{
+			"username_admin_globalaccesslevel":  "admin",
+			"username_admin_password":           AdminPassword,
+			"system_scaling_maxconnectioncount": "100",
+		},
+		ExposedPorts: []string{"55555/tcp", "8080/tcp"},
+		// the broker needs a larger shared memory than the default of Docker, and more open files
+		ShmSize: 1 << 30,
+		Resources: container.Resources{
+			Ulimits: []*units.Ulimit{{Name: "nofile", Soft: 2448, Hard: 1048576}},
+		},
+		// SEMP answers, without credentials, once the broker is up
+		WaitingFor: wait.ForAll(
+			wait.ForListeningPort("55555/tcp"),
+			wait.ForHTTP("/SEMP/v2/config/msgVpns/"+VPN).WithPort("8080/tcp").
+				WithStatusCodeMatcher(func(status int) bool { return status == http.StatusUnauthorized || status == http.StatusOK }),
+		).WithStartupTimeout(StartupTimeout),
+	}
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: request, Started: true})
+	if err != nil {
+		return nil, err
+	}
+	host, err := c.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+	smf, err := c.MappedPort(ctx, "55555/tcp")
+	if err != nil {
+		return nil, err
+	}
+	semp, err := c.MappedPort(ctx, "8080/tcp")
+	if err != nil {
+		return nil, err
+	}
+	b := &Broker{
+		Host:    fmt.Sprintf("tcp://%s:%s", host, smf.Port()),
+		SEMPURL: fmt.Sprintf("http://%s:%s", host, semp.Port()),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	// SEMP answers before the VPN is up, wait until the VPN takes connections through the mapped ports
+	err = waitfor.Broker(ctx, waitfor.Options{
+		Host:     b.Host,
+		SEMPURL:  b.SEMPURL,
+		Username: AdminUsername,
+		Password: AdminPassword,
+		VPN:      VPN,
+		Timeout:  StartupTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Properties returns the service properties connecting to the broker
+func (b *Broker) Properties() config.ServicePropertyMap {
+	return config.ServicePropertyMap{
+		config.TransportLayerPropertyHost:                b.Host,
+		config.ServicePropertyVPNName:                    VPN,
+		config.AuthenticationPropertySchemeBasicUserName: Username,
+		config.AuthenticationPropertySchemeBasicPassword: Password,
+	}
+}
+
+// Connect returns a messaging service connected to the broker, disconnected when the test ends
+func (b *Broker) Connect(t testing.TB) solace.MessagingService {
+	t.Helper()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(b.Properties()).Build()
+	if err != nil {
+		t.Fatalf("could not build the messaging service: %s", err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		t.Fatalf("could not connect to the broker: %s", err)
+	}
+	t.Cleanup(func() { messagingService.Disconnect() })
+	return messagingService
+}
+
+// QueueOptions are the settings of a provisioned queue
+type QueueOptions struct {
+	// Topics are the topic subscriptions of the queue
+	Topics []string
+	// MaxRedeliveryCount is the number of times a message is redelivered before it is moved to the dead message queue,
+	// 0 redelivers it forever
+	MaxRedeliveryCount int
+}
+
+// Queue provisions a durable exclusive queue named after the test, with a dead message queue of its own, DMQ(name).
+// Both are deprovisioned when the test ends.
+func (b *Broker) Queue(t testing.TB, options QueueOptions) *resource.Queue {
+	t.Helper()
+	name := Name(t)
+	for _, queue := range []map[string]interface{}{
+		{"queueName": DMQ(name)},
+		{"queueName": name, "deadMsgQueue": DMQ(name), "maxRedeliveryCount": options.MaxRedeliveryCount},
+	} {
+		queue["accessType"] = "exclusive"
+		queue["permission"] = "consume"
+		queue["ingressEnabled"] = true
+		queue["egressEnabled"] = true
+		if err := b.semp(http.MethodPost, "/config/msgVpns/"+VPN+"/queues", queue, nil); err != nil {
+			t.Fatalf("could not create the queue %s: %s", queue["queueName"], err)
+		}
+		queueName := queue["queueName"].(string)
+		t.Cleanup(func() {
+			if err := b.semp(http.MethodDelete, "/config/msgVpns/"+VPN+"/queues/"+url.PathEscape(queueName), nil, nil); err != nil {
+				t.Logf("could not delete the queue %s: %s", queueName, err)
+			}
+		})
+	}
+	for _, topic := range options.Topics {
+		subscription := map[string]interface{}{"subscriptionTopic": topic}
+		if err := b.semp(http.MethodPost, "/config/msgVpns/"+VPN+"/queues/"+url.PathEscape(name)+"/subscriptions", subscription, nil); err != nil {
+			t.Fatalf("could not subscribe the queue %s to %s: %s", name, topic, err)
+		}
+	}
+	return resource.QueueDurableExclusive(name)
+}
+
+// Name returns the name of the queue of the test, its name in lower case with its subtests separated by dashes
+func Name(t testing.TB) string {
+	return strings.NewReplacer("/", "-", " ", "-").Replace(strings.ToLower(t.Name()))
+}
+
+// Topic returns a topic of the test, under solace/samples/integration, for the queue of the test to subscribe to
+func Topic(t testing.TB) string {
+	return "solace/samples/integration/" + Name(t)
+}
+
+// DMQ returns the name of the dead message queue of a queue provisioned with Queue
+func DMQ(queue string) string {
+	return queue + "-dmq"
+}
+
+// QueueStats are the counters of a queue, as reported by the SEMP monitor API
+type QueueStats struct {
+	// Spooled is the number of messages on the queue
+	Spooled int64 `json:"spooledMsgCount"`
+	// Redelivered is the number of messages the queue redelivered
+	Redelivered int64 `json:"redeliveredMsgCount"`
+}
+
+// Stats returns the counters of the queue
+func (b *Broker) Stats(t testing.TB, queue string) QueueStats {
+	t.Helper()
+	var stats QueueStats
+	path := "/monitor/msgVpns/" + VPN + "/queues/" + url.PathEscape(queue) + "?select=spooledMsgCount,redeliveredMsgCount"
+	if err := b.semp(http.MethodGet, path, nil, &stats); err != nil {
+		t.Fatalf("could not read the counters of the queue %s: %s", queue, err)
+	}
+	return stats
+}
+
+// WaitFor polls the counters of the queue until the condition holds, and fails the test after the timeout with the
+// last counters read
+func (b *Broker) WaitFor(t testing.TB, queue string, timeout time.Duration, condition func(QueueStats) bool) QueueStats {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		stats := b.Stats(t, queue)
+		if condition(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue %s: condition not met within %s, %+v", queue, timeout, stats)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// semp sends a request to the SEMP v2 API, decoding the data of the response into data unless it is nil
+func (b *Broker) semp(method, path string, body, data interface{}) error {
+	var encoded bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&encoded).Encode(body); err != nil {
+			return err
+		}
+	}
+	request, err := http.NewRequest(method, b.SEMPURL+"/SEMP/v2"+path, &encoded)
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(AdminUsername, AdminPassword)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	response, err := b.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+		Meta struct {
+			Error *struct {
+				Description string `json:"description"`
+				Status      string `json:"status"`
+			} `json:"error"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("%s, invalid SEMP response: %w", response.Status, err)
+	}
+	if envelope.Meta.Error != nil {
+		return fmt.Errorf("%s: %s", envelope.Meta.Error.Status, envelope.Meta.Error.Description)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", response.Status)
+	}
+	if data != nil {
+		return json.Unmarshal(envelope.Data, data)
+	}
+	return nil
+}
+
+// Publish publishes the messages to the topic with a persistent publisher, and waits until the broker acknowledged
+// every one of them. The messages are eligible for the dead message queue.
+func Publish(t testing.TB, messagingService solace.MessagingService, topic string, messages ...Message) {
+	t.Helper()
+	publisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err != nil {
+		t.Fatalf("could not build the publisher: %s", err)
+	}
+	if err := publisher.Start(); err != nil {
+		t.Fatalf("could not start the publisher: %s", err)
+	}
+	defer publisher.Terminate(time.Second)
+	for i, m := range messages {
+		builder := messagingService.MessageBuilder().
+			WithProperty(config.MessagePropertyPersistentDMQEligible, true)
+		// the properties that are not API properties are user properties
+		for name, value := range m.Properties {
+			builder = builder.WithProperty(config.MessageProperty(name), value)
+		}
+		var outbound message.OutboundMessage
+		if m.Payload != "" {
+			outbound, err = builder.BuildWithStringPayload(m.Payload)
+		} else {
+			outbound, err = builder.Build()
+		}
+		if err != nil {
+			t.Fatalf("could not build message %d: %s", i, err)
+		}
+		if err := publisher.PublishAwaitAcknowledgement(outbound, resource.TopicOf(topic), 5*time.Second, nil); err != nil {
+			t.Fatalf("could not publish message %d: %s", i, err)
+		}
+	}
+}
+
+// Message is a test message, without payload when Payload is empty
+type Message struct {
+	Payload    string
+	Properties map[string]string
+}
+
+// Recorder collects the records of a settlement audit log, to check the outcomes the handlers of a sample settled
+// the messages with
+type Recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Log returns an audit log writing to the recorder
+func (r *Recorder) Log() *settleaudit.Log {
+	return settleaudit.New(r)
+}
+
+// Write implements io.Writer
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(b)
+}
+
+// Records returns the records written so far
+func (r *Recorder) Records(t testing.TB) []settleaudit.Record {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []settleaudit.Record
+	scanner := bufio.NewScanner(bytes.NewReader(r.buf.Bytes()))
+	for scanner.Scan() {
+		var record settleaudit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid audit record %s: %s", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// WaitFor waits until n records were written, and fails the test after the timeout with the records written so far
+func (r *Recorder) WaitFor(t testing.TB, n int, timeout time.Duration) []settleaudit.Record {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		records := r.Records(t)
+		if len(records) >= n {
+			return records
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d settlement(s) recorded within %s, expected %d: %+v", len(records), timeout, n, records)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
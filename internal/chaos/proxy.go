@@ -0,0 +1,149 @@
+// Package chaos holds the TCP proxy of the chaos harness, cmd/chaos: the samples connect to the broker through the
+// proxy, which severs, delays or refuses their connections on demand.
+package chaos
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Proxy forwards the connections accepted on a local address to the target, the broker
+type Proxy struct {
+	target   string
+	listener net.Listener
+	// delay added before forwarding every chunk of data, in nanoseconds
+	delay int64
+	// refuse is 1 while new connections are refused
+	refuse int32
+
+	mu    sync.Mutex
+	links map[*link]struct{}
+	wg    sync.WaitGroup
+}
+
+// link is a proxied connection, the client side and the target side
+type link struct {
+	client, target net.Conn
+	once           sync.Once
+}
+
+func (l *link) close() {
+	l.once.Do(func() {
+		l.client.Close()
+		l.target.Close()
+	})
+}
+
+// Listen starts a proxy on the address, e.g. localhost:0, forwarding to the target address
+func Listen(address, target string) (*Proxy, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	p := &Proxy{target: target, listener: listener, links: map[*link]struct{}{}}
+	p.wg.Add(1)
+	go p.accept()
+	return p, nil
+}
+
+// Addr returns the address the proxy listens on
+func (p *Proxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *Proxy) accept() {
+	defer p.wg.Done()
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		if atomic.LoadInt32(&p.refuse) == 1 {
+			client.Close()
+			continue
+		}
+		target, err := net.DialTimeout("tcp", p.target, 10*time.Second)
+		if err != nil {
+			client.Close()
+			continue
+		}
+		l := &link{client: client, target: target}
+		p.mu.Lock()
+		p.links[l] = struct{}{}
+		p.mu.Unlock()
+		p.wg.Add(2)
+		go p.forward(l, client, target)
+		go p.forward(l, target, client)
+	}
+}
+
+// forward copies from src to dst until either side is closed, then closes both
+func (p *Proxy) forward(l *link, dst, src net.Conn) {
+	defer p.wg.Done()
+	defer func() {
+		l.close()
+		p.mu.Lock()
+		delete(p.links, l)
+		p.mu.Unlock()
+	}()
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buffer)
+		if n > 0 {
+			if delay := time.Duration(atomic.LoadInt64(&p.delay)); delay > 0 {
+				time.Sleep(delay)
+			}
+			if _, err := dst.Write(buffer[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Sever closes all of the open connections, as a network failure would, and returns how many were closed
+func (p *Proxy) Sever() int {
+	p.mu.Lock()
+	links := make([]*link, 0, len(p.links))
+	for l := range p.links {
+		links = append(links, l)
+	}
+	p.mu.Unlock()
+	for _, l := range links {
+		l.close()
+	}
+	return len(links)
+}
+
+// SetDelay delays every chunk of data forwarded in either direction, 0 forwards without delay
+func (p *Proxy) SetDelay(delay time.Duration) {
+	atomic.StoreInt64(&p.delay, int64(delay))
+}
+
+// SetRefuse refuses the new connections, as a broker down would, or accepts them again
+func (p *Proxy) SetRefuse(refuse bool) {
+	var value int32
+	if refuse {
+		value = 1
+	}
+	atomic.StoreInt32(&p.refuse, value)
+}
+
+// Connections returns the number of open connections
+func (p *Proxy) Connections() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.links)
+}
+
+// Close stops the proxy and closes the open connections
+func (p *Proxy) Close() error {
+	err := p.listener.Close()
+	p.Sever()
+	p.wg.Wait()
+	return err
+}
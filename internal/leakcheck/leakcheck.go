@@ -0,0 +1,303 @@
+// Package leakcheck verifies that the publishers, receivers and messaging services of a sample shut down cleanly: it
+// snapshots the goroutines and the open file descriptors before the sample starts messaging and compares them with the
+// ones left once everything is terminated and disconnected. The goroutines started in between and still running, and
+// the file descriptors opened in between and still open, are leaks.
+//
+// The samples check their shutdown when SOLACE_LEAK_CHECK is set, exiting with status 1 and the stacks of the leaked
+// goroutines when something was left behind:
+//
+//	guard := leakcheck.FromEnv()
+//	messagingService.Connect()
+//	...
+//	persistentReceiver.Terminate(1 * time.Second)
+//	messagingService.Disconnect()
+//	guard.Verify()
+//
+// Tests use Take and Check directly:
+//
+//	before := leakcheck.Take()
+//	...
+//	leakcheck.VerifyNone(t, before)
+//
+// The file descriptors are read from /proc/self/fd, they are only checked on Linux.
+package leakcheck
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long Check waits for the goroutines and file descriptors to go away, the API winds down some
+// of its goroutines asynchronously after Terminate and Disconnect return
+const DefaultTimeout = 5 * time.Second
+
+// ignored are the goroutines started once per process by the standard library, e.g. by the first signal.Notify
+var ignored = []string{
+	"os/signal.loop",
+	"os/signal.signal_recv",
+	"runtime.ensureSigM",
+	"runtime/pprof.profileWriter",
+}
+
+// ignoredFDs are the file descriptors the runtime opens once per process, e.g. the network poller
+var ignoredFDs = []string{
+	"anon_inode:[eventpoll]",
+	"anon_inode:[eventfd]",
+	"anon_inode:[pidfd]",
+}
+
+// Goroutine is a goroutine of a snapshot
+type Goroutine struct {
+	ID    int64
+	State string
+	// Function is the function the goroutine is in, the top of its stack
+	Function string
+	// CreatedBy is the function that started the goroutine
+	CreatedBy string
+	Stack     string
+}
+
+// Snapshot holds the goroutines and the open file descriptors at a point in time
+type Snapshot struct {
+	Time       time.Time
+	Goroutines map[int64]Goroutine
+	// FDs maps the open file descriptors to what they point to, e.g. socket:[1234]
+	FDs map[int]string
+}
+
+// Take snapshots the goroutines and the file descriptors
+func Take() Snapshot {
+	return Snapshot{Time: time.Now(), Goroutines: goroutines(), FDs: fds()}
+}
+
+func goroutines() map[int64]Goroutine {
+	buffer := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buffer, true)
+		if n < len(buffer) {
+			buffer = buffer[:n]
+			break
+		}
+		buffer = make([]byte, 2*len(buffer))
+	}
+	current := currentID()
+	all := map[int64]Goroutine{}
+	for _, stack := range bytes.Split(buffer, []byte("\n\n")) {
+		g, ok := parse(string(stack))
+		if ok && g.ID != current {
+			all[g.ID] = g
+		}
+	}
+	return all
+}
+
+// parse parses a goroutine of a runtime.Stack dump:
+//
+//	goroutine 7 [chan receive]:
+//	main.worker(...)
+//		/path/main.go:12 +0x1d
+//	created by main.main in goroutine 1
+//		/path/main.go:8 +0x25
+func parse(stack string) (Goroutine, bool) {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	var g Goroutine
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "goroutine ") {
+		return g, false
+	}
+	header := strings.TrimSuffix(strings.TrimPrefix(lines[0], "goroutine "), ":")
+	id, state, _ := strings.Cut(header, " ")
+	var err error
+	if g.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return g, false
+	}
+	g.State = strings.Trim(state, "[]")
+	g.Function = function(lines[1])
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "created by ") {
+			g.CreatedBy, _, _ = strings.Cut(strings.TrimPrefix(line, "created by "), " in goroutine")
+		}
+	}
+	g.Stack = stack
+	return g, true
+}
+
+// function returns the function of a frame line, without its arguments
+func function(line string) string {
+	if index := strings.LastIndex(line, "("); index > 0 {
+		return line[:index]
+	}
+	return line
+}
+
+func currentID() int64 {
+	buffer := make([]byte, 64)
+	buffer = buffer[:runtime.Stack(buffer, false)]
+	id, _, _ := strings.Cut(strings.TrimPrefix(string(buffer), "goroutine "), " ")
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return n
+}
+
+func fds() map[int]string {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return nil
+	}
+	open := map[int]string{}
+	for _, entry := range entries {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// the descriptor of the directory being read is gone by now and fails to resolve
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name()))
+		if err != nil {
+			continue
+		}
+		open[fd] = target
+	}
+	return open
+}
+
+// Report lists what was left behind since a snapshot
+type Report struct {
+	Goroutines []Goroutine
+	// FDs are the file descriptors left open, with what they point to
+	FDs map[int]string
+}
+
+// Empty is true when nothing was left behind
+func (r Report) Empty() bool {
+	return len(r.Goroutines) == 0 && len(r.FDs) == 0
+}
+
+// String renders the leaked file descriptors and the stacks of the leaked goroutines
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d goroutine(s) and %d file descriptor(s) left behind\n", len(r.Goroutines), len(r.FDs))
+	fds := make([]int, 0, len(r.FDs))
+	for fd := range r.FDs {
+		fds = append(fds, fd)
+	}
+	sort.Ints(fds)
+	for _, fd := range fds {
+		fmt.Fprintf(&b, "fd %d -> %s\n", fd, r.FDs[fd])
+	}
+	for _, g := range r.Goroutines {
+		fmt.Fprintf(&b, "\n%s\n", g.Stack)
+	}
+	return b.String()
+}
+
+// diff returns what the current snapshot has that the previous one did not
+func (s Snapshot) diff(previous Snapshot) Report {
+	var report Report
+	for id, g := range s.Goroutines {
+		if _, ok := previous.Goroutines[id]; ok || isIgnored(g) {
+			continue
+		}
+		report.Goroutines = append(report.Goroutines, g)
+	}
+	sort.Slice(report.Goroutines, func(i, j int) bool { return report.Goroutines[i].ID < report.Goroutines[j].ID })
+	for fd, target := range s.FDs {
+		if previous.FDs[fd] == target || isIgnoredFD(target) {
+			continue
+		}
+		if report.FDs == nil {
+			report.FDs = map[int]string{}
+		}
+		report.FDs[fd] = target
+	}
+	return report
+}
+
+func isIgnored(g Goroutine) bool {
+	for _, function := range ignored {
+		if g.Function == function || g.CreatedBy == function {
+			return true
+		}
+	}
+	return false
+}
+
+func isIgnoredFD(target string) bool {
+	for _, ignored := range ignoredFDs {
+		if target == ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// Check compares the current goroutines and file descriptors with the snapshot, waiting up to the timeout for the
+// ones winding down, and returns what is still left behind
+func Check(before Snapshot, timeout time.Duration) Report {
+	deadline := time.Now().Add(timeout)
+	for {
+		report := Take().diff(before)
+		if report.Empty() || time.Now().After(deadline) {
+			return report
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TB is the part of testing.TB used by VerifyNone
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// VerifyNone fails the test when goroutines or file descriptors are left behind since the snapshot
+func VerifyNone(t TB, before Snapshot) {
+	t.Helper()
+	if report := Check(before, DefaultTimeout); !report.Empty() {
+		t.Errorf("leakcheck: %s", report)
+	}
+}
+
+// Guard checks the shutdown of a sample, a nil guard checks nothing
+type Guard struct {
+	before  Snapshot
+	timeout time.Duration
+}
+
+// Start snapshots the goroutines and the file descriptors, before the sample starts messaging
+func Start() *Guard {
+	return &Guard{before: Take(), timeout: DefaultTimeout}
+}
+
+// FromEnv starts a guard when SOLACE_LEAK_CHECK is set, e.g. SOLACE_LEAK_CHECK=1, and returns nil otherwise. A
+// duration, e.g. SOLACE_LEAK_CHECK=10s, sets how long the goroutines are given to wind down.
+func FromEnv() *Guard {
+	value := os.Getenv("SOLACE_LEAK_CHECK")
+	if value == "" {
+		return nil
+	}
+	guard := Start()
+	if timeout, err := time.ParseDuration(value); err == nil {
+		guard.timeout = timeout
+	}
+	return guard
+}
+
+// Verify checks that nothing was left behind since the guard started, once everything is terminated and
+// disconnected. It prints the report and exits with status 1 on a leak.
+func (g *Guard) Verify() {
+	if g == nil {
+		return
+	}
+	report := Check(g.before, g.timeout)
+	if report.Empty() {
+		fmt.Println("Leak check: no goroutine or file descriptor left behind")
+		return
+	}
+	fmt.Fprint(os.Stderr, "Leak check failed: ", report)
+	os.Exit(1)
+}
@@ -0,0 +1,118 @@
+package sampleconfig
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Authentication schemes selectable with the -auth-scheme flag (or the SOLACE_AUTH_SCHEME environment variable)
+const (
+	SchemeBasic             = "basic"
+	SchemeClientCertificate = "client-certificate"
+	SchemeOAuth2            = "oauth2"
+	SchemeKerberos          = "kerberos"
+)
+
+// Schemes lists the authentication schemes in the order they are documented
+var Schemes = []string{SchemeBasic, SchemeClientCertificate, SchemeOAuth2, SchemeKerberos}
+
+var authScheme = flag.String("auth-scheme", "",
+	"how the env secrets source authenticates: basic (default), client-certificate, oauth2 or kerberos (or SOLACE_AUTH_SCHEME)")
+
+// AuthScheme returns the authentication scheme selected on the command line, the environment or the configuration file
+func AuthScheme() string {
+	return strings.ToLower(strings.TrimSpace(Setting("SOLACE_AUTH_SCHEME", SchemeBasic)))
+}
+
+// AuthProperties assembles the authentication properties of the scheme from the settings (see Setting):
+//
+//	basic               SOLACE_USERNAME, SOLACE_PASSWORD
+//	client-certificate  SOLACE_CLIENT_CERT, SOLACE_CLIENT_KEY (PEM files), SOLACE_CLIENT_KEY_PASSWORD and
+//	                    SOLACE_USERNAME (optional, the certificate common name is used otherwise)
+//	oauth2              SOLACE_OAUTH_ACCESS_TOKEN and/or SOLACE_OIDC_ID_TOKEN, SOLACE_OAUTH_ISSUER (optional)
+//	kerberos            SOLACE_KERBEROS_INSTANCE (optional, the broker default is used otherwise) and
+//	                    SOLACE_USERNAME (optional, the principal of the ticket is used otherwise)
+//
+// Client certificates and OAuth2 tokens are only accepted over TLS, the host must be a tcps:// or wss:// URI.
+func AuthProperties(scheme string) (config.ServicePropertyMap, error) {
+	switch scheme {
+	case SchemeBasic, "":
+		return config.ServicePropertyMap{
+			config.AuthenticationPropertyScheme:              config.AuthenticationSchemeBasic,
+			config.AuthenticationPropertySchemeBasicUserName: Setting("SOLACE_USERNAME", DefaultUsername),
+			config.AuthenticationPropertySchemeBasicPassword: Setting("SOLACE_PASSWORD", DefaultPassword),
+		}, nil
+
+	case SchemeClientCertificate:
+		certFile, keyFile := Setting("SOLACE_CLIENT_CERT", ""), Setting("SOLACE_CLIENT_KEY", "")
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("the %s scheme needs SOLACE_CLIENT_CERT and SOLACE_CLIENT_KEY", scheme)
+		}
+		properties := config.ServicePropertyMap{
+			config.AuthenticationPropertyScheme:                        config.AuthenticationSchemeClientCertificate,
+			config.AuthenticationPropertySchemeSSLClientCertFile:       certFile,
+			config.AuthenticationPropertySchemeSSLClientPrivateKeyFile: keyFile,
+		}
+		if password := Setting("SOLACE_CLIENT_KEY_PASSWORD", ""); password != "" {
+			properties[config.AuthenticationPropertySchemeClientCertPrivateKeyFilePassword] = password
+		}
+		if username := Setting("SOLACE_USERNAME", ""); username != "" {
+			properties[config.AuthenticationPropertySchemeClientCertUserName] = username
+		}
+		return properties, nil
+
+	case SchemeOAuth2:
+		accessToken, idToken := Setting("SOLACE_OAUTH_ACCESS_TOKEN", ""), Setting("SOLACE_OIDC_ID_TOKEN", "")
+		if accessToken == "" && idToken == "" {
+			return nil, fmt.Errorf("the %s scheme needs SOLACE_OAUTH_ACCESS_TOKEN or SOLACE_OIDC_ID_TOKEN", scheme)
+		}
+		properties := config.ServicePropertyMap{
+			config.AuthenticationPropertyScheme: config.AuthenticationSchemeOAuth2,
+		}
+		if accessToken != "" {
+			properties[config.AuthenticationPropertySchemeOAuth2AccessToken] = accessToken
+		}
+		if idToken != "" {
+			properties[config.AuthenticationPropertySchemeOAuth2OIDCIDToken] = idToken
+		}
+		if issuer := Setting("SOLACE_OAUTH_ISSUER", ""); issuer != "" {
+			properties[config.AuthenticationPropertySchemeOAuth2IssuerIdentifier] = issuer
+		}
+		return properties, nil
+
+	case SchemeKerberos:
+		properties := config.ServicePropertyMap{
+			config.AuthenticationPropertyScheme: config.AuthenticationSchemeKerberos,
+		}
+		if instance := Setting("SOLACE_KERBEROS_INSTANCE", ""); instance != "" {
+			properties[config.AuthenticationPropertySchemeKerberosInstanceName] = instance
+		}
+		if username := Setting("SOLACE_USERNAME", ""); username != "" {
+			properties[config.AuthenticationPropertySchemeKerberosUserName] = username
+		}
+		return properties, nil
+
+	default:
+		return nil, fmt.Errorf("unknown authentication scheme '%s', expected one of %s", scheme, strings.Join(Schemes, ", "))
+	}
+}
+
+// Redacted returns a copy of the properties with the passwords, keys and tokens masked, for printing
+func Redacted(properties config.ServicePropertyMap) config.ServicePropertyMap {
+	redacted := make(config.ServicePropertyMap, len(properties))
+	for name, value := range properties {
+		switch name {
+		case config.AuthenticationPropertySchemeBasicPassword,
+			config.AuthenticationPropertySchemeClientCertPrivateKeyFilePassword,
+			config.AuthenticationPropertySchemeOAuth2AccessToken,
+			config.AuthenticationPropertySchemeOAuth2OIDCIDToken:
+			redacted[name] = "********"
+		default:
+			redacted[name] = value
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,153 @@
+package sampleconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// The aws source reads the connection properties from an AWS Secrets Manager secret holding a JSON object with the
+// fields described in secrets.go, e.g. {"host": "tcps://broker.example.com:55443", "vpn": "prod", "username":
+// "sample-user", "password": "s3cr3t"}. It is configured with the environment variables:
+//
+//	SOLACE_AWS_SECRET_ID  name or ARN of the secret (required)
+//	SOLACE_AWS_REFRESH    how often the secret is checked for a new version (default 5m)
+//
+// The region and the AWS credentials come from the default AWS configuration chain (AWS_REGION, AWS_PROFILE,
+// instance or task role, ...). The secret value is cached and only fetched again when Secrets Manager reports a
+// new AWSCURRENT version, e.g. after a rotation.
+
+func init() {
+	Register("aws", func() (Source, error) { return newAWSSource() })
+}
+
+type awsSource struct {
+	secretID string
+	refresh  time.Duration
+
+	once   sync.Once
+	client *secretsmanager.Client
+	err    error
+
+	// cached value of the secret, by version
+	versionID  string
+	properties config.ServicePropertyMap
+	certs      certFiles
+}
+
+func newAWSSource() (*awsSource, error) {
+	secretID := os.Getenv("SOLACE_AWS_SECRET_ID")
+	if secretID == "" {
+		return nil, fmt.Errorf("SOLACE_AWS_SECRET_ID is required")
+	}
+	refresh, err := time.ParseDuration(Setting("SOLACE_AWS_REFRESH", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOLACE_AWS_REFRESH: %w", err)
+	}
+	return &awsSource{secretID: secretID, refresh: refresh}, nil
+}
+
+func (a *awsSource) secretsManager(ctx context.Context) (*secretsmanager.Client, error) {
+	a.once.Do(func() {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			a.err = fmt.Errorf("could not load the AWS configuration: %w", err)
+			return
+		}
+		a.client = secretsmanager.NewFromConfig(cfg)
+	})
+	return a.client, a.err
+}
+
+// currentVersion returns the version ID of the secret labeled AWSCURRENT, without fetching the secret value
+func (a *awsSource) currentVersion(ctx context.Context) (string, error) {
+	client, err := a.secretsManager(ctx)
+	if err != nil {
+		return "", err
+	}
+	description, err := client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(a.secretID)})
+	if err != nil {
+		return "", err
+	}
+	for versionID, stages := range description.VersionIdsToStages {
+		for _, stage := range stages {
+			if stage == "AWSCURRENT" {
+				return versionID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("secret %s has no AWSCURRENT version", a.secretID)
+}
+
+func (a *awsSource) Load(ctx context.Context) (config.ServicePropertyMap, error) {
+	client, err := a.secretsManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(a.secretID)})
+	if err != nil {
+		return nil, err
+	}
+	if value.SecretString == nil {
+		return nil, fmt.Errorf("secret %s holds binary data, expected a JSON object", a.secretID)
+	}
+
+	var fields secretFields
+	if err := json.Unmarshal([]byte(*value.SecretString), &fields); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object: %w", a.secretID, err)
+	}
+	properties, err := fields.toProperties(&a.certs)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", a.secretID, err)
+	}
+
+	a.versionID = aws.ToString(value.VersionId)
+	a.properties = properties
+	return properties, nil
+}
+
+// Renew checks the secret for a new AWSCURRENT version every refresh interval, the value is only fetched again
+// once the version changed
+func (a *awsSource) Renew(ctx context.Context, onChange func(config.ServicePropertyMap)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.refresh):
+		}
+
+		versionID, err := a.currentVersion(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintln(os.Stderr, "Could not check the AWS secret for rotation: ", err)
+			continue
+		}
+		if versionID == a.versionID {
+			continue
+		}
+
+		previous := a.properties
+		properties, err := a.Load(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintln(os.Stderr, "Could not read the rotated AWS secret: ", err)
+			continue
+		}
+		if !reflect.DeepEqual(properties, previous) {
+			onChange(properties)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package sampleconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// The azure source reads the connection properties from an Azure Key Vault secret holding a JSON object with the
+// fields described in secrets.go, mirroring the aws source. It authenticates with the managed identity of the
+// Azure VM, App Service, Container App or AKS pod (with the identity endpoint exposed), and is configured with the
+// environment variables:
+//
+//	SOLACE_AZURE_VAULT_URL    URL of the key vault, e.g. https://my-vault.vault.azure.net (required)
+//	SOLACE_AZURE_SECRET_NAME  name of the secret (required)
+//	SOLACE_AZURE_CLIENT_ID    client ID of a user-assigned managed identity (optional)
+//	SOLACE_AZURE_REFRESH      how often the secret is checked for a new version (default 5m)
+//
+// The Key Vault REST API is called directly, the managed identity token is fetched from the identity endpoint of
+// the hosting service (IDENTITY_ENDPOINT and IDENTITY_HEADER) or from the instance metadata service otherwise.
+
+const (
+	azureKeyVaultResource   = "https://vault.azure.net"
+	azureKeyVaultAPIVersion = "7.4"
+	azureIMDSTokenURL       = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+func init() {
+	Register("azure", func() (Source, error) { return newAzureSource() })
+}
+
+type azureSource struct {
+	vaultURL   string
+	secretName string
+	clientID   string
+	refresh    time.Duration
+	client     *http.Client
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpiry time.Time
+
+	// cached value of the secret, by version
+	secretID   string
+	properties config.ServicePropertyMap
+	certs      certFiles
+}
+
+func newAzureSource() (*azureSource, error) {
+	vaultURL := strings.TrimRight(os.Getenv("SOLACE_AZURE_VAULT_URL"), "/")
+	secretName := os.Getenv("SOLACE_AZURE_SECRET_NAME")
+	if vaultURL == "" || secretName == "" {
+		return nil, fmt.Errorf("SOLACE_AZURE_VAULT_URL and SOLACE_AZURE_SECRET_NAME are required")
+	}
+	refresh, err := time.ParseDuration(Setting("SOLACE_AZURE_REFRESH", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOLACE_AZURE_REFRESH: %w", err)
+	}
+	return &azureSource{
+		vaultURL:   vaultURL,
+		secretName: secretName,
+		clientID:   os.Getenv("SOLACE_AZURE_CLIENT_ID"),
+		refresh:    refresh,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// accessToken returns a managed identity token for Key Vault, cached until shortly before it expires
+func (a *azureSource) accessToken(ctx context.Context) (string, error) {
+	a.tokenMu.Lock()
+	defer a.tokenMu.Unlock()
+	if a.token != "" && time.Until(a.tokenExpiry) > time.Minute {
+		return a.token, nil
+	}
+
+	query := url.Values{"resource": {azureKeyVaultResource}}
+	var request *http.Request
+	var err error
+	if endpoint := os.Getenv("IDENTITY_ENDPOINT"); endpoint != "" {
+		// App Service, Functions and Container Apps
+		query.Set("api-version", "2019-08-01")
+		if a.clientID != "" {
+			query.Set("client_id", a.clientID)
+		}
+		request, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+		if err == nil {
+			request.Header.Set("X-IDENTITY-HEADER", os.Getenv("IDENTITY_HEADER"))
+		}
+	} else {
+		// Virtual machines, scale sets and AKS
+		query.Set("api-version", "2018-02-01")
+		if a.clientID != "" {
+			query.Set("client_id", a.clientID)
+		}
+		request, err = http.NewRequestWithContext(ctx, http.MethodGet, azureIMDSTokenURL+"?"+query.Encode(), nil)
+		if err == nil {
+			request.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var token struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresOn   json.Number `json:"expires_on"`
+	}
+	if err := a.getJSON(request, &token); err != nil {
+		return "", fmt.Errorf("could not get a managed identity token: %w", err)
+	}
+	expiresOn, err := token.ExpiresOn.Int64()
+	if err != nil {
+		// expiry not reported as a number, refresh the token on the next call after a few minutes
+		expiresOn = time.Now().Add(5 * time.Minute).Unix()
+	}
+	a.token = token.AccessToken
+	a.tokenExpiry = time.Unix(expiresOn, 0)
+	return a.token, nil
+}
+
+func (a *azureSource) getJSON(request *http.Request, value interface{}) error {
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s: %s", request.URL.Host, response.Status, body)
+	}
+	return json.Unmarshal(body, value)
+}
+
+func (a *azureSource) Load(ctx context.Context) (config.ServicePropertyMap, error) {
+	token, err := a.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secretURL := a.vaultURL + "/secrets/" + url.PathEscape(a.secretName) + "?api-version=" + azureKeyVaultAPIVersion
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	var secret struct {
+		Value string `json:"value"`
+		ID    string `json:"id"`
+	}
+	if err := a.getJSON(request, &secret); err != nil {
+		return nil, err
+	}
+
+	var fields secretFields
+	if err := json.Unmarshal([]byte(secret.Value), &fields); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object: %w", a.secretName, err)
+	}
+	properties, err := fields.toProperties(&a.certs)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", a.secretName, err)
+	}
+
+	// the ID ends with the version of the secret
+	a.secretID = secret.ID
+	a.properties = properties
+	return properties, nil
+}
+
+// Renew reads the secret again every refresh interval and calls onChange when a new version changed the properties
+func (a *azureSource) Renew(ctx context.Context, onChange func(config.ServicePropertyMap)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.refresh):
+		}
+
+		previousID, previous := a.secretID, a.properties
+		properties, err := a.Load(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintln(os.Stderr, "Could not check the Azure Key Vault secret for rotation: ", err)
+			continue
+		}
+		if a.secretID != previousID && !reflect.DeepEqual(properties, previous) {
+			onChange(properties)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package sampleconfig
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"SolaceSamples.com/PubSub+Go/pkg/faultproxy"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+var faultProxyFlag = flag.String("fault-proxy", "",
+	"connect through a fault injection proxy with its admin API on this address, e.g. localhost:6062, see pkg/faultproxy (or SOLACE_FAULT_PROXY)")
+
+// proxyPorts are the ports of the transport protocols when the host omits it
+var proxyPorts = map[string]string{"tcp": "55555", "tcps": "55443", "ws": "80", "wss": "443"}
+
+// StartFaultProxy starts, when the sample was run with -fault-proxy (or SOLACE_FAULT_PROXY), a fault injection
+// proxy in front of the first host of the host list, serves its admin API on the address of the setting, and points
+// the host of the properties at the proxy: the other hosts of the list are left out so that every connection goes
+// through the proxy. With tcps or wss the broker certificate must be valid for the local address of the proxy. It
+// is called by Load, which returns the proxy in Config.FaultProxy.
+func StartFaultProxy(properties config.ServicePropertyMap) (*faultproxy.Proxy, error) {
+	admin := Setting("SOLACE_FAULT_PROXY", "")
+	if admin == "" {
+		return nil, nil
+	}
+	host, _ := properties[config.TransportLayerPropertyHost].(string)
+	first, _, _ := strings.Cut(host, ",")
+	scheme, address, ok := strings.Cut(strings.TrimSpace(first), "://")
+	if !ok {
+		scheme, address = "tcp", scheme
+	}
+	scheme = strings.ToLower(scheme)
+	// a WebSocket URI may have a path
+	address, path, hasPath := strings.Cut(address, "/")
+	target := address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		target = net.JoinHostPort(strings.Trim(address, "[]"), proxyPorts[scheme])
+	}
+
+	proxy, err := faultproxy.Listen("localhost:0", target)
+	if err != nil {
+		return nil, fmt.Errorf("could not start the fault proxy: %w", err)
+	}
+	if err := proxy.ServeAdmin(admin); err != nil {
+		proxy.Close()
+		return nil, fmt.Errorf("could not serve the admin API of the fault proxy: %w", err)
+	}
+	proxied := scheme + "://" + proxy.Addr()
+	if hasPath {
+		proxied += "/" + path
+	}
+	properties[config.TransportLayerPropertyHost] = proxied
+	fmt.Fprintf(os.Stderr, "Connecting to %s through the fault proxy %s, admin API on http://%s/faults\n", target, proxy.Addr(), admin)
+	return proxy, nil
+}
@@ -0,0 +1,185 @@
+// Package sampleconfig loads the broker connection properties shared by the samples. The properties come from a
+// secrets source selected with the -secrets-source flag (or the SOLACE_SECRETS_SOURCE environment variable):
+//
+//	env    the host and VPN settings, plus the credentials of the authentication scheme selected with -auth-scheme
+//	       (or SOLACE_AUTH_SCHEME): basic (username and password, default), client-certificate, oauth2 or kerberos,
+//	       see AuthProperties (default)
+//	vault  the broker credentials are read from HashiCorp Vault, see vault.go
+//	aws    the connection properties are read from an AWS Secrets Manager secret, see aws.go
+//	azure  the connection properties are read from an Azure Key Vault secret with a managed identity, see azure.go
+//
+// Each setting is named after its environment variable, e.g. SOLACE_HOST, and is read with Setting from, by
+// precedence: its command line flag (-host, -vpn, -username, -password), the environment variable, the YAML or JSON
+// configuration file set with -config (or SOLACE_CONFIG_FILE, see File), then its default. The loaded properties are
+// checked by Validate, so that a missing or malformed setting is reported by name before connecting.
+//
+// Samples load the properties once at startup and hand them to the service builder:
+//
+//	flag.Parse()
+//	brokerConfig, err := sampleconfig.Load(context.Background())
+//	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+//
+// and read their other settings with Setting as well:
+//
+//	metricsAddress := sampleconfig.Setting("SOLACE_METRICS_ADDR", "")
+package sampleconfig
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"SolaceSamples.com/PubSub+Go/pkg/faultproxy"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Defaults of the connection properties, matching the ones used throughout the samples
+const (
+	DefaultHost     = "tcp://localhost:55555,tcp://localhost:55554"
+	DefaultVPN      = "default"
+	DefaultUsername = "default"
+	DefaultPassword = "default"
+)
+
+// Source loads the connection properties from a secrets backend
+type Source interface {
+	// Load returns the connection properties held by the source
+	Load(ctx context.Context) (config.ServicePropertyMap, error)
+}
+
+// Renewer is implemented by the sources whose credentials are leased or rotated. Renew keeps the credentials alive
+// until the context is canceled and calls onChange with the new properties whenever the credentials changed.
+type Renewer interface {
+	Renew(ctx context.Context, onChange func(config.ServicePropertyMap))
+}
+
+var (
+	sourcesMu sync.Mutex
+	sources   = map[string]func() (Source, error){}
+)
+
+// Register makes a secrets source selectable by name with the -secrets-source flag
+func Register(name string, factory func() (Source, error)) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[name] = factory
+}
+
+// SourceNames returns the names of the registered secrets sources
+func SourceNames() []string {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var secretsSource = flag.String("secrets-source", "",
+	"where the broker connection properties are loaded from: env (default), vault, aws or azure (or SOLACE_SECRETS_SOURCE)")
+
+// Config holds the connection properties loaded from the selected secrets source
+type Config struct {
+	// Properties are the service properties to build the messaging service from
+	Properties config.ServicePropertyMap
+	// FaultProxy is the fault injection proxy the host points at, when the sample was run with -fault-proxy, see
+	// StartFaultProxy
+	FaultProxy *faultproxy.Proxy
+	source     Source
+}
+
+// Load loads the connection properties from the secrets source selected on the command line, and validates them.
+// The flags are parsed first if the sample did not do it already. With -wait-for-broker it then waits for the broker
+// to be ready, see WaitForBroker, and with -fault-proxy it points the host at a fault injection proxy, see
+// StartFaultProxy.
+func Load(ctx context.Context) (*Config, error) {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	brokerConfig, err := LoadFrom(ctx, Setting("SOLACE_SECRETS_SOURCE", "env"))
+	if err != nil {
+		return nil, err
+	}
+	if err := WaitForBroker(ctx, brokerConfig.Properties); err != nil {
+		return nil, err
+	}
+	// after the wait, the proxy accepts connections whether the broker is ready or not
+	if brokerConfig.FaultProxy, err = StartFaultProxy(brokerConfig.Properties); err != nil {
+		return nil, err
+	}
+	return brokerConfig, nil
+}
+
+// LoadFrom loads the connection properties from the named secrets source, adds the properties of the configuration
+// file and validates them, see Validate
+func LoadFrom(ctx context.Context, sourceName string) (*Config, error) {
+	sourcesMu.Lock()
+	factory, ok := sources[sourceName]
+	sourcesMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown secrets source '%s', expected one of %s", sourceName, strings.Join(SourceNames(), ", "))
+	}
+
+	source, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("could not create the %s secrets source: %w", sourceName, err)
+	}
+	properties, err := source.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the connection properties from %s: %w", sourceName, err)
+	}
+	file, err := loadFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the configuration file: %w", err)
+	}
+	if file != nil {
+		// the properties of the file are set under the ones of the source
+		for name, value := range file.Properties {
+			if _, ok := properties[config.ServiceProperty(name)]; !ok {
+				properties[config.ServiceProperty(name)] = value
+			}
+		}
+	}
+	if err := Validate(properties); err != nil {
+		return nil, err
+	}
+	return &Config{Properties: properties, source: source}, nil
+}
+
+// Renew keeps the leased credentials of the source alive until the context is canceled, see Renewer.
+// It returns immediately for sources without leased credentials.
+func (c *Config) Renew(ctx context.Context, onChange func(config.ServicePropertyMap)) {
+	renewer, ok := c.source.(Renewer)
+	if !ok {
+		return
+	}
+	renewer.Renew(ctx, onChange)
+}
+
+// Renewable reports whether the credentials of the source are renewed, i.e. whether Renew ever calls onChange
+func (c *Config) Renewable() bool {
+	_, ok := c.source.(Renewer)
+	return ok
+}
+
+// envSource reads the connection properties from the environment variables
+type envSource struct{}
+
+func (envSource) Load(ctx context.Context) (config.ServicePropertyMap, error) {
+	properties, err := AuthProperties(AuthScheme())
+	if err != nil {
+		return nil, err
+	}
+	properties[config.TransportLayerPropertyHost] = Setting("SOLACE_HOST", DefaultHost)
+	properties[config.ServicePropertyVPNName] = Setting("SOLACE_VPN", DefaultVPN)
+	return properties, nil
+}
+
+func init() {
+	Register("env", func() (Source, error) { return envSource{}, nil })
+}
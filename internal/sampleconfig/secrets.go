@@ -0,0 +1,106 @@
+package sampleconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// secretFields are the fields of a secret holding broker connection properties, as stored by the secrets backends:
+//
+//	username, password                            basic authentication
+//	client_cert, client_key, client_key_password  client certificate authentication (PEM contents)
+//	host, vpn                                     override SOLACE_HOST and SOLACE_VPN
+//
+// Fields named after a service property (e.g. solace.messaging.transport.compression-level) are set as is.
+type secretFields map[string]interface{}
+
+func (f secretFields) get(name string) string {
+	switch value := f[name].(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// toProperties maps the fields to service properties, client certificates are written to files in the cert dir
+func (f secretFields) toProperties(certs *certFiles) (config.ServicePropertyMap, error) {
+	properties := config.ServicePropertyMap{
+		config.TransportLayerPropertyHost: Setting("SOLACE_HOST", DefaultHost),
+		config.ServicePropertyVPNName:     Setting("SOLACE_VPN", DefaultVPN),
+	}
+	if host := f.get("host"); host != "" {
+		properties[config.TransportLayerPropertyHost] = host
+	}
+	if vpn := f.get("vpn"); vpn != "" {
+		properties[config.ServicePropertyVPNName] = vpn
+	}
+
+	switch {
+	case f.get("client_cert") != "":
+		certFile, keyFile, err := certs.write(f.get("client_cert"), f.get("client_key"))
+		if err != nil {
+			return nil, err
+		}
+		properties[config.AuthenticationPropertyScheme] = config.AuthenticationSchemeClientCertificate
+		properties[config.AuthenticationPropertySchemeSSLClientCertFile] = certFile
+		properties[config.AuthenticationPropertySchemeSSLClientPrivateKeyFile] = keyFile
+		if password := f.get("client_key_password"); password != "" {
+			properties[config.AuthenticationPropertySchemeClientCertPrivateKeyFilePassword] = password
+		}
+		if username := f.get("username"); username != "" {
+			properties[config.AuthenticationPropertySchemeClientCertUserName] = username
+		}
+	case f.get("username") != "":
+		properties[config.AuthenticationPropertySchemeBasicUserName] = f.get("username")
+		properties[config.AuthenticationPropertySchemeBasicPassword] = f.get("password")
+	default:
+		return nil, fmt.Errorf("the secret holds neither username/password nor client_cert/client_key")
+	}
+
+	for name, value := range f {
+		if strings.HasPrefix(name, "solace.messaging.") {
+			properties[config.ServiceProperty(name)] = value
+		}
+	}
+	return properties, nil
+}
+
+// certFiles writes client certificates read from a secret to files, the API loads client certificates from files
+type certFiles struct {
+	dir string
+}
+
+// write writes the PEM certificate and key to files readable by the owner only. The file names hold a hash of the
+// contents, so a rotated certificate changes the properties.
+func (c *certFiles) write(cert, key string) (string, string, error) {
+	if key == "" {
+		return "", "", fmt.Errorf("the secret holds client_cert without client_key")
+	}
+	if c.dir == "" {
+		dir, err := ioutil.TempDir("", "solace-client-cert")
+		if err != nil {
+			return "", "", err
+		}
+		c.dir = dir
+	}
+	sum := sha256.Sum256([]byte(cert + key))
+	suffix := hex.EncodeToString(sum[:8])
+	certFile := filepath.Join(c.dir, "client-"+suffix+".pem")
+	keyFile := filepath.Join(c.dir, "client-"+suffix+".key")
+	if err := ioutil.WriteFile(certFile, []byte(cert), 0600); err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(keyFile, []byte(key), 0600); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
@@ -0,0 +1,193 @@
+package sampleconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The flags of the connection settings, empty when not set
+var (
+	configFile = flag.String("config", "", "YAML or JSON file of the connection settings (or SOLACE_CONFIG_FILE), see sampleconfig.File")
+	hostFlag   = flag.String("host", "", "broker URI or comma separated host list (or SOLACE_HOST)")
+	vpnFlag    = flag.String("vpn", "", "message VPN (or SOLACE_VPN)")
+	userFlag   = flag.String("username", "", "client username (or SOLACE_USERNAME)")
+	passFlag   = flag.String("password", "", "client password (or SOLACE_PASSWORD)")
+)
+
+// settingFlags are the flags overriding the settings, by name
+var settingFlags = map[string]*string{
+	"SOLACE_HOST":            hostFlag,
+	"SOLACE_VPN":             vpnFlag,
+	"SOLACE_USERNAME":        userFlag,
+	"SOLACE_PASSWORD":        passFlag,
+	"SOLACE_AUTH_SCHEME":     authScheme,
+	"SOLACE_SECRETS_SOURCE":  secretsSource,
+	"SOLACE_WAIT_FOR_BROKER": waitFlag,
+	"SOLACE_FAULT_PROXY":     faultProxyFlag,
+}
+
+var (
+	defaultsMu sync.Mutex
+	defaults   = map[string]string{}
+)
+
+// SetDefault changes the default of a setting, for the samples connecting differently from the others, e.g. to
+// tcps://localhost:55443 for the TLS samples. Call it before Load.
+func SetDefault(key, value string) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaults[key] = value
+}
+
+// Setting returns the value of the setting named after its environment variable, e.g. SOLACE_HOST, from the highest
+// precedence to the lowest:
+//
+//  1. the command line flag, for the settings having one (-host, -vpn, -username, -password, -auth-scheme,
+//     -secrets-source, -wait-for-broker and -fault-proxy), when it is set
+//  2. the environment variable, when it is set, even to an empty value
+//  3. the configuration file, see File, when it holds the setting
+//  4. the default set with SetDefault, then def
+//
+// Before the flags are parsed, e.g. in the default values of the flags of a sample, only the environment variables,
+// the file set with SOLACE_CONFIG_FILE and the defaults are considered. A file that can not be read is ignored here
+// and reported by Load.
+func Setting(key, def string) string {
+	if value, ok := settingFlags[key]; ok && *value != "" {
+		return *value
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	if file, err := loadFile(); err == nil && file != nil {
+		if value := file.setting(key); value != "" {
+			return value
+		}
+	}
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	if value, ok := defaults[key]; ok {
+		return value
+	}
+	return def
+}
+
+// File is the configuration file set with -config or SOLACE_CONFIG_FILE, YAML, or JSON when its extension is .json.
+// Its settings are named after their environment variable, without the SOLACE_ prefix and in lower case, and its
+// properties are service properties, set as they are under the settings:
+//
+//	host: tcps://broker.example.com:55443
+//	vpn: orders
+//	username: orders-app
+//	password: secret
+//	properties:
+//	  solace.messaging.transport.compression-level: 6
+//
+// Unknown settings are reported as errors, to catch misspelled names.
+type File struct {
+	Host              string                 `json:"host" yaml:"host"`
+	VPN               string                 `json:"vpn" yaml:"vpn"`
+	Username          string                 `json:"username" yaml:"username"`
+	Password          string                 `json:"password" yaml:"password"`
+	AuthScheme        string                 `json:"auth_scheme" yaml:"auth_scheme"`
+	SecretsSource     string                 `json:"secrets_source" yaml:"secrets_source"`
+	ClientCert        string                 `json:"client_cert" yaml:"client_cert"`
+	ClientKey         string                 `json:"client_key" yaml:"client_key"`
+	ClientKeyPassword string                 `json:"client_key_password" yaml:"client_key_password"`
+	OAuthAccessToken  string                 `json:"oauth_access_token" yaml:"oauth_access_token"`
+	OIDCIDToken       string                 `json:"oidc_id_token" yaml:"oidc_id_token"`
+	OAuthIssuer       string                 `json:"oauth_issuer" yaml:"oauth_issuer"`
+	KerberosInstance  string                 `json:"kerberos_instance" yaml:"kerberos_instance"`
+	TrustStore        string                 `json:"trust_store" yaml:"trust_store"`
+	Properties        map[string]interface{} `json:"properties" yaml:"properties"`
+}
+
+// setting returns the value of the setting held by the file, empty when it does not hold it
+func (f *File) setting(key string) string {
+	switch key {
+	case "SOLACE_HOST":
+		return f.Host
+	case "SOLACE_VPN":
+		return f.VPN
+	case "SOLACE_USERNAME":
+		return f.Username
+	case "SOLACE_PASSWORD":
+		return f.Password
+	case "SOLACE_AUTH_SCHEME":
+		return f.AuthScheme
+	case "SOLACE_SECRETS_SOURCE":
+		return f.SecretsSource
+	case "SOLACE_CLIENT_CERT":
+		return f.ClientCert
+	case "SOLACE_CLIENT_KEY":
+		return f.ClientKey
+	case "SOLACE_CLIENT_KEY_PASSWORD":
+		return f.ClientKeyPassword
+	case "SOLACE_OAUTH_ACCESS_TOKEN":
+		return f.OAuthAccessToken
+	case "SOLACE_OIDC_ID_TOKEN":
+		return f.OIDCIDToken
+	case "SOLACE_OAUTH_ISSUER":
+		return f.OAuthIssuer
+	case "SOLACE_KERBEROS_INSTANCE":
+		return f.KerberosInstance
+	case "SOLACE_TRUST_STORE":
+		return f.TrustStore
+	}
+	return ""
+}
+
+// ReadFile reads a configuration file, see File
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return file, nil
+}
+
+var (
+	fileMu    sync.Mutex
+	filePath  string
+	fileValue *File
+	fileErr   error
+)
+
+// loadFile returns the configuration file set with -config or SOLACE_CONFIG_FILE, nil when none is set. The file is
+// read once per path.
+func loadFile() (*File, error) {
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("SOLACE_CONFIG_FILE")
+	}
+	if path == "" {
+		return nil, nil
+	}
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if path != filePath {
+		filePath = path
+		fileValue, fileErr = ReadFile(path)
+	}
+	return fileValue, fileErr
+}
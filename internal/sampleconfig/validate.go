@@ -0,0 +1,142 @@
+package sampleconfig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// ValidationError lists the problems of the connection properties, each naming the setting to fix
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid connection settings: " + strings.Join(e.Problems, "; ")
+}
+
+// hostSchemes are the schemes of the broker URIs, TLS ones mapped to true
+var hostSchemes = map[string]bool{"tcp": false, "tcps": true, "ws": false, "wss": true, "http": false, "https": true}
+
+// Validate checks the connection properties before they are handed to the service builder, so that a mistake is
+// reported with the setting to fix rather than as a failure to connect. It returns a *ValidationError listing every
+// problem found:
+//
+//   - the host (SOLACE_HOST) is set, and every URI of the host list has a known scheme and a valid port
+//   - the VPN (SOLACE_VPN) is not empty
+//   - basic authentication has a username (SOLACE_USERNAME)
+//   - client certificate authentication has readable certificate and key files (SOLACE_CLIENT_CERT, SOLACE_CLIENT_KEY)
+//   - OAuth2 authentication has a token (SOLACE_OAUTH_ACCESS_TOKEN or SOLACE_OIDC_ID_TOKEN)
+//   - client certificates and OAuth2 tokens are only sent over TLS, tcps:// or wss://
+func Validate(properties config.ServicePropertyMap) error {
+	var problems []string
+	text := func(property config.ServiceProperty) (string, bool) {
+		value, ok := properties[property]
+		if !ok {
+			return "", false
+		}
+		return strings.TrimSpace(fmt.Sprint(value)), true
+	}
+
+	secure := false
+	host, _ := text(config.TransportLayerPropertyHost)
+	if host == "" {
+		problems = append(problems, "the host (SOLACE_HOST or -host) is not set")
+	} else {
+		secure = true
+		for _, uri := range strings.Split(host, ",") {
+			tls, err := checkHost(strings.TrimSpace(uri))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("host %q: %s", uri, err))
+			}
+			secure = secure && tls
+		}
+	}
+	if vpn, ok := text(config.ServicePropertyVPNName); ok && vpn == "" {
+		problems = append(problems, "the VPN (SOLACE_VPN or -vpn) is empty")
+	}
+
+	scheme, _ := text(config.AuthenticationPropertyScheme)
+	switch scheme {
+	case "", config.AuthenticationSchemeBasic:
+		if username, _ := text(config.AuthenticationPropertySchemeBasicUserName); username == "" {
+			problems = append(problems, "basic authentication needs a username (SOLACE_USERNAME or -username)")
+		}
+	case config.AuthenticationSchemeClientCertificate:
+		for _, file := range []struct {
+			property config.ServiceProperty
+			setting  string
+		}{
+			{config.AuthenticationPropertySchemeSSLClientCertFile, "SOLACE_CLIENT_CERT"},
+			{config.AuthenticationPropertySchemeSSLClientPrivateKeyFile, "SOLACE_CLIENT_KEY"},
+		} {
+			path, _ := text(file.property)
+			if path == "" {
+				problems = append(problems, fmt.Sprintf("client certificate authentication needs %s", file.setting))
+			} else if _, err := os.Stat(path); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", file.setting, err))
+			}
+		}
+	case config.AuthenticationSchemeOAuth2:
+		accessToken, _ := text(config.AuthenticationPropertySchemeOAuth2AccessToken)
+		idToken, _ := text(config.AuthenticationPropertySchemeOAuth2OIDCIDToken)
+		if accessToken == "" && idToken == "" {
+			problems = append(problems, "OAuth2 authentication needs SOLACE_OAUTH_ACCESS_TOKEN or SOLACE_OIDC_ID_TOKEN")
+		}
+	}
+	if host != "" && !secure {
+		switch scheme {
+		case config.AuthenticationSchemeClientCertificate:
+			problems = append(problems, "client certificate authentication needs tcps:// or wss:// hosts")
+		case config.AuthenticationSchemeOAuth2:
+			problems = append(problems, "OAuth2 authentication needs tcps:// or wss:// hosts")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkHost checks a URI of the host list, [scheme://]host[:port], and reports whether it is a TLS one
+func checkHost(uri string) (bool, error) {
+	if uri == "" {
+		return false, fmt.Errorf("empty entry in the host list")
+	}
+	tls := false
+	if scheme, rest, ok := strings.Cut(uri, "://"); ok {
+		secure, known := hostSchemes[strings.ToLower(scheme)]
+		if !known {
+			return false, fmt.Errorf("unknown scheme %s, expected tcp, tcps, ws or wss", scheme)
+		}
+		tls, uri = secure, rest
+	}
+	if i := strings.Index(uri, "/"); i >= 0 {
+		// path of a WebSocket URI
+		uri = uri[:i]
+	}
+	name, port := uri, ""
+	if strings.HasPrefix(uri, "[") {
+		// IPv6 address
+		end := strings.Index(uri, "]")
+		if end < 0 {
+			return tls, fmt.Errorf("missing ] after the IPv6 address")
+		}
+		name, port = uri[1:end], strings.TrimPrefix(uri[end+1:], ":")
+	} else if i := strings.LastIndex(uri, ":"); i >= 0 {
+		name, port = uri[:i], uri[i+1:]
+	}
+	if name == "" {
+		return tls, fmt.Errorf("missing host name")
+	}
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return tls, fmt.Errorf("invalid port %s", port)
+		}
+	}
+	return tls, nil
+}
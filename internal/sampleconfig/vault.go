@@ -0,0 +1,180 @@
+package sampleconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// The vault source reads the broker credentials from a Vault secret, configured with the environment variables:
+//
+//	VAULT_ADDR         address of the Vault server (default http://127.0.0.1:8200)
+//	VAULT_TOKEN        token used to read the secret, or VAULT_TOKEN_FILE to read it from a file
+//	VAULT_NAMESPACE    Vault Enterprise namespace (optional)
+//	SOLACE_VAULT_PATH  path of the secret (default secret/data/solace/samples, a KV version 2 secret)
+//
+// The fields of the secret are described in secrets.go: username and password, or client_cert and client_key for
+// client certificate authentication, optionally host and vpn. Dynamic secrets (e.g. from a custom secrets engine) are renewed while their lease is renewable and
+// read again when the lease ends, static KV secrets are read again every SOLACE_VAULT_REFRESH (default 5m).
+
+func init() {
+	Register("vault", func() (Source, error) { return newVaultSource() })
+}
+
+type vaultSource struct {
+	address   string
+	token     string
+	namespace string
+	path      string
+	refresh   time.Duration
+	client    *http.Client
+
+	// client certificate files written from the secret
+	certs certFiles
+
+	// properties and lease of the last secret read, the lease is empty for static secrets
+	current       config.ServicePropertyMap
+	leaseID       string
+	leaseDuration time.Duration
+	renewable     bool
+}
+
+func newVaultSource() (*vaultSource, error) {
+	token := os.Getenv("VAULT_TOKEN")
+	if tokenFile := os.Getenv("VAULT_TOKEN_FILE"); tokenFile != "" {
+		contents, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(contents))
+	}
+	if token == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE is required")
+	}
+	refresh, err := time.ParseDuration(Setting("SOLACE_VAULT_REFRESH", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOLACE_VAULT_REFRESH: %w", err)
+	}
+	return &vaultSource{
+		address:   strings.TrimRight(Setting("VAULT_ADDR", "http://127.0.0.1:8200"), "/"),
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		path:      strings.Trim(Setting("SOLACE_VAULT_PATH", "secret/data/solace/samples"), "/"),
+		refresh:   refresh,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// vaultResponse is the subset of the Vault API response used by the source
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Errors        []string               `json:"errors"`
+}
+
+func (v *vaultSource) do(ctx context.Context, method, path string, body interface{}) (*vaultResponse, error) {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, method, v.address+"/v1/"+path, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		request.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	response, err := v.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var decoded vaultResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid response from Vault (%s): %w", response.Status, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s for %s: %s", response.Status, path, strings.Join(decoded.Errors, "; "))
+	}
+	return &decoded, nil
+}
+
+func (v *vaultSource) Load(ctx context.Context) (config.ServicePropertyMap, error) {
+	secret, err := v.do(ctx, http.MethodGet, v.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	v.leaseID = secret.LeaseID
+	v.leaseDuration = time.Duration(secret.LeaseDuration) * time.Second
+	v.renewable = secret.Renewable
+
+	fields := secret.Data
+	// KV version 2 nests the secret fields under data.data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		fields = nested
+	}
+	properties, err := secretFields(fields).toProperties(&v.certs)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", v.path, err)
+	}
+	v.current = properties
+	return properties, nil
+}
+
+// Renew renews the lease of dynamic secrets at two thirds of its duration. Once the lease cannot be renewed anymore
+// (or for static secrets, every refresh interval) the secret is read again and onChange is called if it changed.
+func (v *vaultSource) Renew(ctx context.Context, onChange func(config.ServicePropertyMap)) {
+	for {
+		wait := v.refresh
+		if v.leaseID != "" && v.leaseDuration > 0 {
+			wait = v.leaseDuration * 2 / 3
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if v.leaseID != "" && v.renewable {
+			renewed, err := v.do(ctx, http.MethodPut, "sys/leases/renew", map[string]interface{}{
+				"lease_id":  v.leaseID,
+				"increment": int(v.leaseDuration.Seconds()),
+			})
+			// A lease reaching its max TTL is renewed for less than requested, fetch new credentials then
+			if err == nil && time.Duration(renewed.LeaseDuration)*time.Second >= v.leaseDuration/2 {
+				v.leaseDuration = time.Duration(renewed.LeaseDuration) * time.Second
+				continue
+			}
+		}
+
+		previous := v.current
+		properties, err := v.Load(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintln(os.Stderr, "Could not read the Vault secret: ", err)
+			continue
+		}
+		if !reflect.DeepEqual(properties, previous) {
+			onChange(properties)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package sampleconfig
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/waitfor"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+var waitFlag = flag.String("wait-for-broker", "",
+	"wait up to this long for the broker to be ready before connecting, e.g. 2m, see pkg/waitfor (or SOLACE_WAIT_FOR_BROKER)")
+
+// WaitForBroker waits, when the sample was run with -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), until the SMF port
+// of the host accepts connections and, when SOLACE_SEMP_URL is set, until SEMP answers and the VPN is up. It is
+// called by Load, so every sample waits before connecting.
+func WaitForBroker(ctx context.Context, properties config.ServicePropertyMap) error {
+	setting := Setting("SOLACE_WAIT_FOR_BROKER", "")
+	if setting == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(setting)
+	if err != nil {
+		return fmt.Errorf("SOLACE_WAIT_FOR_BROKER: %w", err)
+	}
+	host, _ := properties[config.TransportLayerPropertyHost].(string)
+	vpn, _ := properties[config.ServicePropertyVPNName].(string)
+	return waitfor.Broker(ctx, waitfor.Options{
+		Host:     host,
+		SEMPURL:  Setting("SOLACE_SEMP_URL", ""),
+		Username: Setting("SOLACE_SEMP_USERNAME", "admin"),
+		Password: Setting("SOLACE_SEMP_PASSWORD", "admin"),
+		VPN:      vpn,
+		Timeout:  timeout,
+		Progress: func(err error) { fmt.Fprintln(os.Stderr, "Waiting for the broker: ", err) },
+	})
+}
@@ -0,0 +1,99 @@
+// Package samplerun builds a sample with extra packages linked in, without changing its code, for the commands
+// running samples under a harness (cmd/profile, cmd/soak). The extra packages are imported for their side effects
+// by a file added to the package of the sample through a build overlay, e.g. pkg/profiling/autostart.
+//
+//	binary, err := samplerun.Build(tmp, "patterns/guaranteed_receiver.go", "SolaceSamples.com/PubSub+Go/pkg/profiling/autostart")
+//	cmd := exec.Command(binary, args...)
+//
+// The go command must be on the PATH and the sample is built within the module of the working directory.
+package samplerun
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// importsName is the name of the added file, in the directory of the sample where it must not exist
+const importsName = "zz_samplerun_imports.go"
+
+// overlay is the format of the -overlay file of the go command
+type overlay struct {
+	Replace map[string]string
+}
+
+// Build builds the sample, a .go file or a package directory, with the imports into the directory and returns the
+// path of the binary. Without imports the sample is built as is. The build output is written to standard error.
+func Build(dir, sample string, imports ...string) (string, error) {
+	absolute, err := filepath.Abs(sample)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absolute)
+	if err != nil {
+		return "", err
+	}
+
+	sampleDir := absolute
+	if !info.IsDir() {
+		if !strings.HasSuffix(sample, ".go") {
+			return "", fmt.Errorf("%s is neither a .go file nor a package directory", sample)
+		}
+		sampleDir = filepath.Dir(absolute)
+	}
+	binary := filepath.Join(dir, strings.TrimSuffix(filepath.Base(absolute), ".go"))
+	if len(imports) == 0 {
+		if err := goBuild(sample, "-o", binary, absolute); err != nil {
+			return "", err
+		}
+		return binary, nil
+	}
+
+	added := filepath.Join(sampleDir, importsName)
+	if _, err := os.Stat(added); err == nil {
+		return "", fmt.Errorf("%s already exists", added)
+	}
+
+	source := "package main\n\nimport (\n"
+	for _, path := range imports {
+		source += "\t_ \"" + path + "\"\n"
+	}
+	source += ")\n"
+	replacement := filepath.Join(dir, importsName)
+	if err := os.WriteFile(replacement, []byte(source), 0o644); err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(overlay{Replace: map[string]string{added: replacement}})
+	if err != nil {
+		return "", err
+	}
+	overlayPath := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayPath, encoded, 0o644); err != nil {
+		return "", err
+	}
+
+	args := []string{"-overlay", overlayPath, "-o", binary}
+	if info.IsDir() {
+		args = append(args, absolute)
+	} else {
+		// with a list of files, only the listed ones are built, all named the same way
+		args = append(args, absolute, added)
+	}
+	if err := goBuild(sample, args...); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+// goBuild runs go build with the arguments
+func goBuild(sample string, args ...string) error {
+	cmd := exec.Command("go", append([]string{"build"}, args...)...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not build %s: %w", sample, err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Package semp wraps the few SEMP v2 config endpoints the samples need to create their prerequisites on the broker:
+// queues, partitioned or not, their topic subscriptions, max redelivery count and spool quota, and the replay log of the message VPN. The
+// broker management service is located by the SOLACE_SEMP_URL, SOLACE_SEMP_USERNAME, SOLACE_SEMP_PASSWORD and SOLACE_VPN
+// settings (see internal/sampleconfig), the admin/admin defaults match the local broker of cmd/devbroker.
+//
+// Samples self-provision when run with the -provision flag (or SOLACE_PROVISION=true), once the flags are parsed,
+// e.g. after sampleconfig.Load:
+//
+//	err := semp.Provision(ctx, semp.Queue{Name: "durable-queue", Subscriptions: []string{"solace/samples/persistent/>"}})
+//
+// Objects that already exist are left as they are, so provisioning again is harmless.
+package semp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+)
+
+var provision = flag.Bool("provision", false, "create the queues and the replay log the sample needs through SEMP before running (or SOLACE_PROVISION=true)")
+
+// Client sends requests to the SEMP v2 config API of the broker, for a message VPN
+type Client struct {
+	// URL of the broker management service, e.g. http://localhost:8080
+	URL string
+	VPN string
+	// Username and Password of a management user allowed to configure the VPN
+	Username string
+	Password string
+	// HTTPClient sends the requests, an http.Client with a 10 seconds timeout when nil
+	HTTPClient *http.Client
+}
+
+// FromSettings returns the client of the broker located by the SEMP settings
+func FromSettings() *Client {
+	return &Client{
+		URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+		VPN:      sampleconfig.Setting("SOLACE_VPN", sampleconfig.DefaultVPN),
+		Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+		Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+	}
+}
+
+// Queue is a durable exclusive queue needed by a sample, or a partitioned one
+type Queue struct {
+	Name          string
+	Subscriptions []string
+	// MaxRedeliveryCount is the number of times a message is redelivered before it is discarded, or moved to the
+	// dead message queue when eligible; 0 leaves the broker default, unlimited
+	MaxRedeliveryCount int
+	// LastValue makes the queue a last value queue: with a spool quota of 0 it only keeps the last message spooled.
+	// Its max redelivery count is unlimited whatever MaxRedeliveryCount, the readers of a last value queue receive its
+	// message without acknowledging it, it is not discarded after so many reads.
+	LastValue bool
+	// Partitions makes the queue a non-exclusive partitioned queue with that many partitions: the messages of a
+	// partition key go to one partition, consumed in order by one of the consumers bound to the queue
+	Partitions int
+}
+
+// Requested reports whether the sample was run with -provision (or SOLACE_PROVISION=true)
+func Requested() bool {
+	if *provision {
+		return true
+	}
+	requested, _ := strconv.ParseBool(sampleconfig.Setting("SOLACE_PROVISION", "false"))
+	return requested
+}
+
+// Provision creates the queues, with their subscriptions and max redelivery count, when the sample was run with
+// -provision, it does nothing otherwise
+func Provision(ctx context.Context, queues ...Queue) error {
+	if !Requested() {
+		return nil
+	}
+	client := FromSettings()
+	for _, queue := range queues {
+		if err := client.ProvisionQueue(ctx, queue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProvisionReplayLog enables the replay log of the VPN when the sample was run with -provision, it does nothing
+// otherwise
+func ProvisionReplayLog(ctx context.Context, name string) error {
+	if !Requested() {
+		return nil
+	}
+	return FromSettings().EnableReplayLog(ctx, name)
+}
+
+// Ready waits until SEMP answers, a broker takes a minute or so to start
+func (c *Client) Ready(ctx context.Context, interval time.Duration) error {
+	for {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.vpnURL(""), nil)
+		if err != nil {
+			return err
+		}
+		request.SetBasicAuth(c.Username, c.Password)
+		response, err := c.httpClient().Do(request)
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("SEMP is not ready: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ProvisionQueue creates the queue, partitioned when requested, adds its subscriptions, sets its max redelivery count
+// and makes it a last value queue when requested, with unlimited redeliveries
+func (c *Client) ProvisionQueue(ctx context.Context, queue Queue) error {
+	if queue.Partitions > 0 {
+		if err := c.CreatePartitionedQueue(ctx, queue.Name, queue.Partitions); err != nil {
+			return err
+		}
+	} else if err := c.CreateQueue(ctx, queue.Name); err != nil {
+		return err
+	}
+	for _, topic := range queue.Subscriptions {
+		if err := c.AddSubscription(ctx, queue.Name, topic); err != nil {
+			return err
+		}
+	}
+	if queue.LastValue {
+		// set even though unlimited is the default, the queue may exist with a limit
+		if err := c.SetMaxRedelivery(ctx, queue.Name, 0); err != nil {
+			return err
+		}
+		return c.SetMaxMsgSpoolUsage(ctx, queue.Name, 0)
+	}
+	if queue.MaxRedeliveryCount > 0 {
+		if err := c.SetMaxRedelivery(ctx, queue.Name, queue.MaxRedeliveryCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateQueue creates a durable exclusive queue, enabled, that the client usernames can consume from
+func (c *Client) CreateQueue(ctx context.Context, name string) error {
+	return c.createQueue(ctx, name, map[string]interface{}{"accessType": "exclusive"})
+}
+
+// CreatePartitionedQueue creates a durable non-exclusive queue with the partitions, enabled, that the client usernames
+// can consume from. The partitions of a queue cannot be changed once created.
+func (c *Client) CreatePartitionedQueue(ctx context.Context, name string, partitions int) error {
+	return c.createQueue(ctx, name, map[string]interface{}{"accessType": "non-exclusive", "partitionCount": partitions})
+}
+
+func (c *Client) createQueue(ctx context.Context, name string, attributes map[string]interface{}) error {
+	body := map[string]interface{}{
+		"queueName":        name,
+		"permission":       "consume",
+		"ingressEnabled":   true,
+		"egressEnabled":    true,
+		"maxMsgSpoolUsage": 500,
+	}
+	for attribute, value := range attributes {
+		body[attribute] = value
+	}
+	if err := c.send(ctx, http.MethodPost, c.vpnURL("/queues"), body); err != nil {
+		return fmt.Errorf("could not create the queue %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddSubscription subscribes the queue to the topic
+func (c *Client) AddSubscription(ctx context.Context, queue, topic string) error {
+	body := map[string]interface{}{"subscriptionTopic": topic}
+	if err := c.send(ctx, http.MethodPost, c.vpnURL("/queues/"+url.PathEscape(queue)+"/subscriptions"), body); err != nil {
+		return fmt.Errorf("could not subscribe the queue %s to %s: %w", queue, topic, err)
+	}
+	return nil
+}
+
+// SetMaxRedelivery sets the number of times the messages of the queue are redelivered, 0 for unlimited
+func (c *Client) SetMaxRedelivery(ctx context.Context, queue string, count int) error {
+	body := map[string]interface{}{"maxRedeliveryCount": count}
+	if err := c.send(ctx, http.MethodPatch, c.vpnURL("/queues/"+url.PathEscape(queue)), body); err != nil {
+		return fmt.Errorf("could not set the max redelivery count of the queue %s: %w", queue, err)
+	}
+	return nil
+}
+
+// SetMaxMsgSpoolUsage sets the spool quota of the queue in MB, 0 makes it a last value queue
+func (c *Client) SetMaxMsgSpoolUsage(ctx context.Context, queue string, megabytes int) error {
+	body := map[string]interface{}{"maxMsgSpoolUsage": megabytes}
+	if err := c.send(ctx, http.MethodPatch, c.vpnURL("/queues/"+url.PathEscape(queue)), body); err != nil {
+		return fmt.Errorf("could not set the spool quota of the queue %s: %w", queue, err)
+	}
+	return nil
+}
+
+// EnableReplayLog creates the replay log of the VPN, or enables it when it already exists. A VPN has at most one
+// replay log, creating one under another name fails.
+func (c *Client) EnableReplayLog(ctx context.Context, name string) error {
+	body := map[string]interface{}{
+		"replayLogName":  name,
+		"maxSpoolUsage":  100,
+		"ingressEnabled": true,
+		"egressEnabled":  true,
+	}
+	if err := c.send(ctx, http.MethodPost, c.vpnURL("/replayLogs"), body); err != nil {
+		return fmt.Errorf("could not create the replay log %s: %w", name, err)
+	}
+	enabled := map[string]interface{}{"ingressEnabled": true, "egressEnabled": true}
+	if err := c.send(ctx, http.MethodPatch, c.vpnURL("/replayLogs/"+url.PathEscape(name)), enabled); err != nil {
+		return fmt.Errorf("could not enable the replay log %s: %w", name, err)
+	}
+	return nil
+}
+
+// AllowGuaranteed lets the clients of the client profile send and receive guaranteed messages, create endpoints and
+// use transacted sessions
+func (c *Client) AllowGuaranteed(ctx context.Context, clientProfile string) error {
+	body := map[string]interface{}{
+		"allowGuaranteedEndpointCreateEnabled": true,
+		"allowGuaranteedMsgReceiveEnabled":     true,
+		"allowGuaranteedMsgSendEnabled":        true,
+		"allowTransactedSessionsEnabled":       true,
+	}
+	if err := c.send(ctx, http.MethodPatch, c.vpnURL("/clientProfiles/"+url.PathEscape(clientProfile)), body); err != nil {
+		return fmt.Errorf("could not update the client profile %s: %w", clientProfile, err)
+	}
+	return nil
+}
+
+// SetClientPassword sets the password the client username authenticates with, e.g. to rotate it. The broker checks
+// the password when a client connects, the clients already connected are left as they are.
+func (c *Client) SetClientPassword(ctx context.Context, username, password string) error {
+	body := map[string]interface{}{"password": password}
+	if err := c.send(ctx, http.MethodPatch, c.vpnURL("/clientUsernames/"+url.PathEscape(username)), body); err != nil {
+		return fmt.Errorf("could not set the password of the client username %s: %w", username, err)
+	}
+	return nil
+}
+
+// sempError is the error part of the SEMP responses
+type sempError struct {
+	Meta struct {
+		Error struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+	} `json:"meta"`
+}
+
+func (c *Client) vpnURL(path string) string {
+	return c.URL + "/SEMP/v2/config/msgVpns/" + url.PathEscape(c.VPN) + path
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// send sends the request, an object that already exists is not an error
+func (c *Client) send(ctx context.Context, method, endpoint string, body interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(c.Username, c.Password)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	response, err := c.httpClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+	var semp sempError
+	if err := json.NewDecoder(response.Body).Decode(&semp); err != nil {
+		return fmt.Errorf("%s %s: %s", method, endpoint, response.Status)
+	}
+	if semp.Meta.Error.Status == "ALREADY_EXISTS" {
+		return nil
+	}
+	return fmt.Errorf("%s %s: %s: %s", method, endpoint, semp.Meta.Error.Status, semp.Meta.Error.Description)
+}
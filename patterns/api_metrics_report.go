@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/metricsnap"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// API metrics: messagingService.Metrics() holds counters kept by the API since the service was created (or since the
+// last Reset). This sample runs a direct and a persistent publisher and a direct receiver, reads the counters every
+// -interval and prints how much each one changed since the previous report (see pkg/metricsnap):
+//
+//	go run api_metrics_report.go -interval 5s -rate 200
+//	go run api_metrics_report.go -interval 5s -reset-every 3
+//
+// Discards show up e.g. when the receiver falls behind (-slow-receiver) and its buffer overflows. With -reset-every
+// the counters are reset every few reports: the totals start over from zero and the report after a reset counts the
+// change from zero, marking the counters with a *.
+
+func main() {
+	interval := flag.Duration("interval", 5*time.Second, "how often the metrics are reported")
+	rate := flag.Int("rate", 100, "messages published per second by each publisher")
+	slowReceiver := flag.Bool("slow-receiver", false, "make the receiver slower than the publishers to see discards")
+	resetEvery := flag.Int("reset-every", 0, "reset the API metrics every given number of reports, 0 never resets them")
+	flag.Parse()
+	if *rate <= 0 {
+		fmt.Fprintln(os.Stderr, "-rate must be positive")
+		os.Exit(2)
+	}
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Direct Receiver of the direct messages, with a small buffer so a slow receiver discards
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/metrics/direct/>")).
+		OnBackPressureDropOldest(100).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := directReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		if *slowReceiver {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}); regErr != nil {
+		panic(regErr)
+	}
+
+	// Publishers, persistent messages published to a topic without a matching queue are acknowledged all the same
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err != nil {
+		panic(err)
+	}
+	persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err != nil {
+		panic(err)
+	}
+	for _, publisher := range []solace.LifecycleControl{directPublisher, persistentPublisher} {
+		if err := publisher.Start(); err != nil {
+			panic(err)
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Second / time.Duration(*rate))
+		defer ticker.Stop()
+		for msgSeqNum := 1; ; msgSeqNum++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			body := "Hello from the metrics sample --> " + strconv.Itoa(msgSeqNum)
+			if err := directPublisher.PublishString(body, resource.TopicOf(TopicPrefix+"/metrics/direct/"+strconv.Itoa(msgSeqNum%10))); err != nil {
+				fmt.Println("Direct publish failed: ", err)
+			}
+			if err := persistentPublisher.PublishString(body, resource.TopicOf(TopicPrefix+"/metrics/persistent/"+strconv.Itoa(msgSeqNum%10))); err != nil {
+				fmt.Println("Persistent publish failed: ", err)
+			}
+		}
+	}()
+
+	fmt.Println("\n===Interrupt (CTR+C) to stop the workload===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	tracker := metricsnap.NewTracker(messagingService.Metrics())
+report:
+	for reports := 1; ; reports++ {
+		select {
+		case <-ticker.C:
+			diff := tracker.Interval()
+			fmt.Printf("\n--- %s (last %s)\n", diff.To.Format(time.RFC3339), diff.Elapsed().Round(time.Millisecond))
+			diff.Render(os.Stdout, true)
+			// Reset the counters of the service, the next report counts from zero
+			if *resetEvery > 0 && reports%*resetEvery == 0 {
+				fmt.Println("Resetting the API metrics")
+				messagingService.Metrics().Reset()
+			}
+		case <-c:
+			break report
+		}
+	}
+
+	close(stop)
+	<-done
+
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	persistentPublisher.Terminate(1 * time.Second)
+	fmt.Println("Persistent Publisher Terminated? ", persistentPublisher.IsTerminated())
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("Direct Receiver Terminated? ", directReceiver.IsTerminated())
+
+	// The complete set of counters, as formatted by the API
+	fmt.Printf("\nFinal metrics:\n%s\n", messagingService.Metrics())
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Application identification: by default the API generates a client name from the host name, the process ID and a
+// counter, which tells operations little about what is connected and changes on every restart. This sample sets:
+//   - the application ID (the client name on the broker) built from the application name and instance, the same on
+//     every restart, so it can be found with "show client <name>" or in SEMP and broker logs, and client-specific
+//     access control can refer to it
+//   - the application description, shown next to the client name, with the version of the application
+//   - the sender ID of the published messages, set from the client name, so receivers know where messages came from
+//
+//	go run application_identification.go -app orders-service -version 2.3.1 -instance eu-west-1a-0
+//
+// Client names are unique per message VPN: a second connection with the same name is refused while the first one
+// is connected, so the instance must tell apart the replicas running at the same time, e.g. the pod name of a
+// StatefulSet (the HOSTNAME environment variable in Kubernetes).
+
+// ClientName - a deterministic client name <application>/<instance>, without the characters that are not allowed
+// in client names (wildcards) and within the maximum length of 160 characters
+func ClientName(application, instance string) string {
+	name := strings.NewReplacer("*", "_", ">", "_", " ", "_").Replace(application + "/" + instance)
+	if len(name) > 160 {
+		name = name[:160]
+	}
+	return name
+}
+
+// MessageHandler - prints who sent the message
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	senderID, _ := message.GetSenderID()
+	fmt.Printf("Received Message Body %s from %s\n", payload, senderID)
+}
+
+func main() {
+	hostname, _ := os.Hostname()
+	application := flag.String("app", "go-samples", "name of the application")
+	version := flag.String("version", "1.0.0", "version of the application")
+	instance := flag.String("instance", sampleconfig.Setting("HOSTNAME", hostname), "instance of the application, unique among the instances running at the same time")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	clientName := ClientName(*application, *instance)
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	// Shown by the broker next to the client name
+	brokerConfig.Properties[config.ClientPropertyApplicationDescription] = fmt.Sprintf("%s %s (%s)", *application, *version, runtime.Version())
+	// Set the sender ID of every published message to the client name
+	brokerConfig.Properties[config.ServicePropertyGenerateSenderID] = true
+
+	// The application ID is the client name on the broker
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		BuildWithApplicationID(clientName)
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		// e.g. the name is in use by another instance, or the previous run of this one was not disconnected yet
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Read the identification back from the service
+	info := messagingService.Info()
+	fmt.Printf("Application ID (client name): %s\n", messagingService.GetApplicationID())
+	fmt.Printf("Application description:      %s\n", brokerConfig.Properties[config.ClientPropertyApplicationDescription])
+	fmt.Printf("API:                          %s %s built %s\n", info.GetAPIImplementationVendor(), info.GetAPIVersion(), info.GetAPIBuildDate())
+	fmt.Printf("API user ID:                  %s\n", info.GetAPIUserID())
+
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/identification/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher and receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	topic := resource.TopicOf(TopicPrefix + "/identification/hello")
+	for {
+		select {
+		case <-c:
+			// Terminate the Direct Publisher and Receiver
+			directPublisher.Terminate(1 * time.Second)
+			fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+			directReceiver.Terminate(1 * time.Second)
+			fmt.Println("Direct Receiver Terminated? ", directReceiver.IsTerminated())
+			// Disconnect the Message Service, releasing the client name
+			messagingService.Disconnect()
+			fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+			return
+		case <-time.After(1 * time.Second):
+		}
+		if err := directPublisher.PublishString("Hello from "+clientName, topic); err != nil {
+			fmt.Println("Publish failed: ", err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Authentication scheme selection: the shared config loader (internal/sampleconfig) picks the authentication scheme
+// from SOLACE_AUTH_SCHEME (or the -auth-scheme flag) and assembles its properties, so every sample loading its
+// configuration with sampleconfig.Load can connect with any of them without a change:
+//
+//	go run authentication_scheme_selection.go
+//	SOLACE_AUTH_SCHEME=client-certificate SOLACE_HOST=tcps://localhost:55443 \
+//		SOLACE_CLIENT_CERT=client.pem SOLACE_CLIENT_KEY=client.key go run authentication_scheme_selection.go
+//	SOLACE_AUTH_SCHEME=oauth2 SOLACE_HOST=tcps://localhost:55443 SOLACE_OAUTH_ACCESS_TOKEN=eyJ... \
+//		go run authentication_scheme_selection.go
+//	SOLACE_AUTH_SCHEME=kerberos go run authentication_scheme_selection.go
+//
+// Client certificates and OAuth2 tokens are only sent over TLS, the broker certificate is validated with the PEM
+// files of SOLACE_TRUST_STORE. Kerberos uses the ticket of the current user (kinit) and needs the API built with
+// Kerberos support on the host.
+
+// PrintProperties - prints the properties sorted by name, with the secrets masked
+func PrintProperties(properties config.ServicePropertyMap) {
+	redacted := sampleconfig.Redacted(properties)
+	names := make([]string, 0, len(redacted))
+	for name := range redacted {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %s = %v\n", name, redacted[config.ServiceProperty(name)])
+	}
+}
+
+func main() {
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, with the credentials of the scheme selected by SOLACE_AUTH_SCHEME
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	host, _ := brokerConfig.Properties[config.TransportLayerPropertyHost].(string)
+	if strings.Contains(host, "tcps://") || strings.Contains(host, "wss://") {
+		brokerConfig.Properties[config.TransportLayerSecurityPropertyTrustStorePath] = sampleconfig.Setting("SOLACE_TRUST_STORE", "./trust_store")
+	}
+
+	fmt.Printf("Authentication scheme: %s\n", sampleconfig.AuthScheme())
+	PrintProperties(brokerConfig.Properties)
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
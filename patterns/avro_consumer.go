@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/schemaregistry"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Avro consumer: receives the orders of avro_publisher.go, or any payload in the wire format of the Confluent
+// serializers, and decodes them with the schema of their ID, fetched once from the Confluent Schema Registry (see
+// pkg/schemaregistry), then prints them in the JSON encoding of Avro. The consumer needs no compiled schema: a
+// publisher evolving the schema, e.g. adding a field with a default, is decoded with its new schema right away.
+//
+//	go run avro_consumer.go
+//	go run avro_publisher.go
+//
+// The registry is at SOLACE_SCHEMA_REGISTRY_URL, http://localhost:8081 by default, authenticated with
+// SOLACE_SCHEMA_REGISTRY_USERNAME and SOLACE_SCHEMA_REGISTRY_PASSWORD when set.
+
+// OrderHandler - decodes and prints the Avro payload of the message
+func OrderHandler(deserializer *schemaregistry.Deserializer) solace.MessageHandler {
+	return func(inbound message.InboundMessage) {
+		payload, _ := inbound.GetPayloadAsBytes()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		value, schemaID, err := deserializer.JSON(ctx, payload)
+		switch {
+		case errors.Is(err, schemaregistry.ErrNotWireFormat):
+			fmt.Printf("Skipped a message of %s, not Avro in the wire format\n", inbound.GetDestinationName())
+		case err != nil:
+			fmt.Printf("Skipped a message of %s: %s\n", inbound.GetDestinationName(), err)
+		default:
+			fmt.Printf("Received on %s with schema %d: %s\n", inbound.GetDestinationName(), schemaID, value)
+		}
+	}
+}
+
+func main() {
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	registry := schemaregistry.New(sampleconfig.Setting("SOLACE_SCHEMA_REGISTRY_URL", "http://localhost:8081"))
+	registry.Username = sampleconfig.Setting("SOLACE_SCHEMA_REGISTRY_USERNAME", "")
+	registry.Password = sampleconfig.Setting("SOLACE_SCHEMA_REGISTRY_PASSWORD", "")
+	deserializer := schemaregistry.NewDeserializer(registry)
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/avro/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	if err := directReceiver.ReceiveAsync(OrderHandler(deserializer)); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/schemaregistry"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Avro publisher: registers the order schema in a Confluent Schema Registry, then publishes an order every second,
+// serialized as Avro in the wire format of the Confluent serializers, the schema ID ahead of the value (see
+// pkg/schemaregistry). The payloads are read by avro_consumer.go, as well as by the Kafka Avro deserializers once the
+// messages are bridged to Kafka, e.g. by a Kafka connector.
+//
+//	docker run -d -p 8081:8081 -e SCHEMA_REGISTRY_HOST_NAME=localhost \
+//	  -e SCHEMA_REGISTRY_KAFKASTORE_BOOTSTRAP_SERVERS=kafka:9092 confluentinc/cp-schema-registry
+//	go run avro_consumer.go
+//	go run avro_publisher.go -subject orders-value
+//
+// The registry is at SOLACE_SCHEMA_REGISTRY_URL, http://localhost:8081 by default, authenticated with
+// SOLACE_SCHEMA_REGISTRY_USERNAME and SOLACE_SCHEMA_REGISTRY_PASSWORD when set, e.g. an API key and secret of
+// Confluent Cloud.
+
+// OrderSchema - the Avro schema of the orders, the note is optional
+const OrderSchema = `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "com.solace.samples",
+	"fields": [
+		{"name": "orderId", "type": "string"},
+		{"name": "customer", "type": "string"},
+		{"name": "amount", "type": "double"},
+		{"name": "placedAt", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "note", "type": ["null", "string"], "default": null}
+	]
+}`
+
+// AvroContentType - the HTTP content type of the messages
+const AvroContentType = "avro/binary"
+
+func main() {
+	subject := flag.String("subject", "orders-value", "registry subject of the schema, the Kafka topic with a -value suffix for the Kafka deserializers")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	registry := schemaregistry.New(sampleconfig.Setting("SOLACE_SCHEMA_REGISTRY_URL", "http://localhost:8081"))
+	registry.Username = sampleconfig.Setting("SOLACE_SCHEMA_REGISTRY_USERNAME", "")
+	registry.Password = sampleconfig.Setting("SOLACE_SCHEMA_REGISTRY_PASSWORD", "")
+	serializer, err := schemaregistry.NewSerializer(context.Background(), registry, *subject, OrderSchema)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Registered the order schema under %s with ID %d\n", *subject, serializer.SchemaID())
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Publisher running? ", directPublisher.IsRunning())
+
+	go func() {
+		customers := []string{"alice", "bob", "carol"}
+		for msgSeqNum := 1; directPublisher.IsReady(); msgSeqNum++ {
+			order := map[string]interface{}{
+				"orderId":  "order-" + strconv.Itoa(msgSeqNum),
+				"customer": customers[msgSeqNum%len(customers)],
+				"amount":   12.5 * float64(msgSeqNum),
+				"placedAt": time.Now(),
+				"note":     nil,
+			}
+			// a union value is keyed by the name of its type
+			if msgSeqNum%3 == 0 {
+				order["note"] = map[string]interface{}{"string": "gift wrapped"}
+			}
+			payload, err := serializer.Serialize(order)
+			if err != nil {
+				panic(err)
+			}
+			msg, err := messagingService.MessageBuilder().
+				WithHTTPContentHeader(AvroContentType, "").
+				BuildWithByteArrayPayload(payload)
+			if err != nil {
+				panic(err)
+			}
+			topic := resource.TopicOf(TopicPrefix + "/avro/orders")
+			if err := directPublisher.Publish(msg, topic); err != nil {
+				panic(err)
+			}
+			fmt.Printf("Published %s, %d bytes with schema %d, on %s\n", order["orderId"], len(payload), serializer.SchemaID(), topic.GetName())
+			time.Sleep(1 * time.Second)
+		}
+	}()
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/cloudevents"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// CloudEvents consumer: receives the events of solace/samples/cloudevents/> and decodes them with pkg/cloudevents,
+// whatever their content mode, binary or structured, then prints their attributes and data. The messages that are not
+// CloudEvents are reported and skipped, as is an event missing a required attribute.
+//
+//	go run cloudevents_consumer.go
+//	go run cloudevents_publisher.go
+
+// EventHandler - decodes and prints the event carried by the message
+func EventHandler(inbound message.InboundMessage) {
+	event, mode, err := cloudevents.Decode(inbound)
+	switch {
+	case errors.Is(err, cloudevents.ErrNotCloudEvent):
+		fmt.Printf("Skipped a message of %s, not a CloudEvent\n", inbound.GetDestinationName())
+		return
+	case err != nil:
+		fmt.Printf("Skipped an invalid CloudEvent of %s: %s\n", inbound.GetDestinationName(), err)
+		return
+	}
+	fmt.Printf("Received %s event %s from %s in %s mode\n", event.Type, event.ID, event.Source, mode)
+	if event.Subject != "" {
+		fmt.Printf("  subject: %s\n", event.Subject)
+	}
+	if !event.Time.IsZero() {
+		fmt.Printf("  time: %s\n", event.Time.Format(time.RFC3339Nano))
+	}
+	names := make([]string, 0, len(event.Extensions))
+	for name := range event.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %s: %s\n", name, event.Extensions[name])
+	}
+	fmt.Printf("  data (%s): %s\n", event.DataContentType, event.Data)
+}
+
+func main() {
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/cloudevents/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	if err := directReceiver.ReceiveAsync(EventHandler); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/cloudevents"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// CloudEvents publisher: publishes an order created event every second as a CloudEvent (see pkg/cloudevents), in the
+// binary content mode, the attributes in ce_ user properties and the JSON order as the payload, or in the structured
+// mode, the whole event as an application/cloudevents+json payload, alternately with -mode both. Any CloudEvents SDK,
+// or cloudevents_consumer.go, reads them the same way whatever the mode.
+//
+//	go run cloudevents_publisher.go
+//	go run cloudevents_publisher.go -mode structured -source /samples/orders-service
+
+// OrderCreatedType - the CloudEvents type of the events
+const OrderCreatedType = "com.solace.samples.order.created"
+
+func main() {
+	modeName := flag.String("mode", "both", "content mode of the events: binary, structured or both, alternately")
+	source := flag.String("source", "/solace/samples/cloudevents_publisher", "source attribute of the events")
+	flag.Parse()
+
+	var modes []cloudevents.Mode
+	switch *modeName {
+	case "binary":
+		modes = []cloudevents.Mode{cloudevents.Binary}
+	case "structured":
+		modes = []cloudevents.Mode{cloudevents.Structured}
+	case "both":
+		modes = []cloudevents.Mode{cloudevents.Binary, cloudevents.Structured}
+	default:
+		panic(fmt.Sprintf("unknown mode %s, expected binary, structured or both", *modeName))
+	}
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Publisher running? ", directPublisher.IsRunning())
+
+	go func() {
+		for msgSeqNum := 1; directPublisher.IsReady(); msgSeqNum++ {
+			orderID := "order-" + strconv.Itoa(msgSeqNum)
+			data, err := json.Marshal(map[string]interface{}{"orderId": orderID, "amount": 10 * msgSeqNum, "currency": "EUR"})
+			if err != nil {
+				panic(err)
+			}
+			event := cloudevents.Event{
+				ID:              strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.Itoa(msgSeqNum),
+				Source:          *source,
+				Type:            OrderCreatedType,
+				Subject:         orderID,
+				Time:            time.Now(),
+				DataContentType: "application/json",
+				Data:            data,
+				// an extension attribute, e.g. a tenant, routed along with the event
+				Extensions: map[string]string{"tenant": "samples"},
+			}
+			mode := modes[msgSeqNum%len(modes)]
+			msg, err := cloudevents.Encode(messagingService.MessageBuilder(), event, mode)
+			if err != nil {
+				panic(err)
+			}
+			topic := resource.TopicOf(TopicPrefix + "/cloudevents/orders/created")
+			if err := directPublisher.Publish(msg, topic); err != nil {
+				panic(err)
+			}
+			fmt.Printf("Published event %s (%s) in %s mode on %s\n", event.ID, event.Subject, mode, topic.GetName())
+			time.Sleep(1 * time.Second)
+		}
+	}()
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
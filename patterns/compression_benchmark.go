@@ -0,0 +1,221 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/metrics"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Compression benchmark: connects once per compression level, publishes the same set of payloads on a topic it is
+// subscribed to and reports for each level the throughput, the CPU time used by the process and the number of
+// compressed bytes received, to help picking a compression level for a given payload profile and network.
+//
+// Level 0 disables compression. With any other level the API compresses the traffic with zlib and must connect to
+// the compressed port of the broker (55003 on a software broker by default), set with SOLACE_COMPRESSED_HOST.
+//
+//	SOLACE_HOST=tcp://broker:55555 SOLACE_COMPRESSED_HOST=tcp://broker:55003 go run compression_benchmark.go -messages 20000 -size 4096
+//
+// The payloads are JSON-like text documents built from a fixed seed so every level sends exactly the same bytes.
+// Replace Payloads with samples of the real traffic for meaningful numbers: random or already compressed data
+// (images, encrypted payloads) does not compress and only costs CPU.
+
+// Result - measurements of one benchmark run
+type Result struct {
+	Level           int
+	Published       int
+	Received        uint64
+	Elapsed         time.Duration
+	CPU             time.Duration
+	PayloadBytes    uint64
+	BytesReceived   uint64
+	CompressedBytes uint64
+}
+
+// Payloads - builds count text payloads of about size bytes each, deterministic for a given seed
+func Payloads(count, size int, seed int64) [][]byte {
+	words := []string{"order", "customer", "status", "shipped", "pending", "amount", "currency", "EUR", "USD",
+		"warehouse", "priority", "quantity", "sku", "region", "timestamp", "true", "false"}
+	random := rand.New(rand.NewSource(seed))
+	payloads := make([][]byte, count)
+	for i := range payloads {
+		payload := make([]byte, 0, size+32)
+		payload = append(payload, '{')
+		for len(payload) < size {
+			payload = append(payload, fmt.Sprintf(`"%s":"%s-%d",`, words[random.Intn(len(words))], words[random.Intn(len(words))], random.Intn(100000))...)
+		}
+		payload[len(payload)-1] = '}'
+		payloads[i] = payload
+	}
+	return payloads
+}
+
+// cpuTime - user and system CPU time used by the process so far
+func cpuTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// Run - connects at the given compression level, publishes the payloads to a topic the same connection subscribes
+// to and waits until all of them came back (or the timeout expired)
+func Run(brokerConfig config.ServicePropertyMap, level int, payloads [][]byte, timeout time.Duration) (Result, error) {
+	levelConfig := config.ServicePropertyMap{config.TransportLayerPropertyCompressionLevel: level}
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig).
+		FromConfigurationProvider(levelConfig).
+		Build()
+	if err != nil {
+		return Result{}, err
+	}
+	if err := messagingService.Connect(); err != nil {
+		return Result{}, err
+	}
+	defer messagingService.Disconnect()
+
+	topic := resource.TopicOf(fmt.Sprintf("%s/compression/level/%d", TopicPrefix, level))
+	var received uint64
+	done := make(chan struct{})
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(topic.GetName())).
+		Build()
+	if err != nil {
+		return Result{}, err
+	}
+	if err := directReceiver.Start(); err != nil {
+		return Result{}, err
+	}
+	defer directReceiver.Terminate(1 * time.Second)
+	if err := directReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		if atomic.AddUint64(&received, 1) == uint64(len(payloads)) {
+			close(done)
+		}
+	}); err != nil {
+		return Result{}, err
+	}
+
+	// Wait for the publisher to have room in its buffer rather than failing when the network is the bottleneck
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err != nil {
+		return Result{}, err
+	}
+	if err := directPublisher.Start(); err != nil {
+		return Result{}, err
+	}
+	defer directPublisher.Terminate(1 * time.Second)
+
+	result := Result{Level: level}
+	startCPU, start := cpuTime(), time.Now()
+	for _, payload := range payloads {
+		if err := directPublisher.PublishBytes(payload, topic); err != nil {
+			return result, err
+		}
+		result.Published++
+		result.PayloadBytes += uint64(len(payload))
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		// direct messages may be discarded, report what was received
+	}
+	result.Elapsed = time.Since(start)
+	result.CPU = cpuTime() - startCPU
+	result.Received = atomic.LoadUint64(&received)
+
+	apiMetrics := messagingService.Metrics()
+	result.BytesReceived = apiMetrics.GetValue(metrics.DirectBytesReceived)
+	result.CompressedBytes = apiMetrics.GetValue(metrics.CompressedBytesReceived)
+	return result, nil
+}
+
+func main() {
+	count := flag.Int("messages", 10000, "number of messages published at each compression level")
+	size := flag.Int("size", 1024, "approximate size of each payload in bytes")
+	minLevel := flag.Int("min-level", 0, "first compression level to benchmark (0 disables compression)")
+	maxLevel := flag.Int("max-level", 9, "last compression level to benchmark (9 is the best compression)")
+	timeout := flag.Duration("timeout", 30*time.Second, "how long to wait for the published messages to be received at each level")
+	flag.Parse()
+
+	if *minLevel < 0 || *maxLevel > 9 || *minLevel > *maxLevel {
+		fmt.Fprintln(os.Stderr, "compression levels must be between 0 and 9, with -min-level <= -max-level")
+		os.Exit(2)
+	}
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters
+	brokerConfig := config.ServicePropertyMap{
+		config.ServicePropertyVPNName:                    sampleconfig.Setting("SOLACE_VPN", "default"),
+		config.AuthenticationPropertySchemeBasicPassword: sampleconfig.Setting("SOLACE_PASSWORD", "default"),
+		config.AuthenticationPropertySchemeBasicUserName: sampleconfig.Setting("SOLACE_USERNAME", "default"),
+	}
+	host := sampleconfig.Setting("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554")
+	compressedHost := sampleconfig.Setting("SOLACE_COMPRESSED_HOST", "tcp://localhost:55003")
+
+	// With -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), wait for the broker to be ready before connecting
+	brokerConfig[config.TransportLayerPropertyHost] = host
+	if err := sampleconfig.WaitForBroker(context.Background(), brokerConfig); err != nil {
+		fmt.Fprintln(os.Stderr, "The broker is not ready: ", err)
+		os.Exit(1)
+	}
+
+	payloads := Payloads(*count, *size, 1)
+	fmt.Printf("Publishing %d messages of about %d bytes at compression levels %d to %d\n", *count, *size, *minLevel, *maxLevel)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "LEVEL\tRECEIVED\tSECONDS\tMSG/S\tPAYLOAD MB/S\tCPU SECONDS\tCPU µs/MSG\tBYTES RECEIVED\tCOMPRESSED BYTES\tRATIO\t")
+	for level := *minLevel; level <= *maxLevel; level++ {
+		brokerConfig[config.TransportLayerPropertyHost] = host
+		if level > 0 {
+			brokerConfig[config.TransportLayerPropertyHost] = compressedHost
+		}
+		fmt.Printf("Benchmarking compression level %d on %s\n", level, brokerConfig[config.TransportLayerPropertyHost])
+		result, err := Run(brokerConfig, level, payloads, *timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Compression level %d failed: %s\n", level, err)
+			continue
+		}
+
+		seconds := result.Elapsed.Seconds()
+		ratio := "-"
+		if result.CompressedBytes > 0 {
+			ratio = fmt.Sprintf("%.2f", float64(result.BytesReceived)/float64(result.CompressedBytes))
+		}
+		fmt.Fprintf(table, "%d\t%d/%d\t%.2f\t%.0f\t%.2f\t%.2f\t%.1f\t%d\t%d\t%s\t\n",
+			result.Level,
+			result.Received, result.Published,
+			seconds,
+			float64(result.Received)/seconds,
+			float64(result.PayloadBytes)/seconds/1e6,
+			result.CPU.Seconds(),
+			float64(result.CPU.Microseconds())/float64(result.Published),
+			result.BytesReceived,
+			result.CompressedBytes,
+			ratio)
+	}
+	fmt.Println()
+	table.Flush()
+	fmt.Println("\nCPU is the time used by the whole process, compressing on publish and decompressing on receive.")
+	fmt.Println("RATIO is the bytes received divided by the compressed bytes received, higher is better.")
+}
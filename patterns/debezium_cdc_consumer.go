@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Change data capture consumer: parses the change events of Debezium, e.g. published by Debezium Server on
+// solace/samples/cdc/<server>/<schema>/<table>, and applies them to a local state store, a copy of the captured tables
+// saved to a JSON file every -save-interval and loaded on the next run. A message is acknowledged once the store it was
+// applied to is saved: after a crash the events since the last save are redelivered, and applied again.
+//
+//	go run debezium_cdc_consumer.go -provision -simulate
+//
+// The events of a row have to be applied in order, an update applied before the insert it follows loses the row. The
+// queue is partitioned: the events are published with the primary key of their row as the partition key, the broker
+// spools all of the events of a key to the same partition, and a partition is consumed by one consumer at a time, in
+// order. The -consumers receivers bound to the queue share the partitions, each key sticks to one of them; add or stop
+// instances of the sample, the broker rebalances the partitions between them. The store still checks the position of
+// the events in the log of the database, an event older than the last one applied to its row is reported and skipped.
+//
+// With -simulate the sample publishes the events of a customers table itself, inserts, updates and deletes of random
+// rows in the Debezium format, without a database.
+
+// ChangeEvent - a Debezium change event, the payload of the JSON envelope when the schemas are enabled
+type ChangeEvent struct {
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source struct {
+		Connector string `json:"connector"`
+		Name      string `json:"name"`
+		DB        string `json:"db"`
+		Schema    string `json:"schema"`
+		Table     string `json:"table"`
+		TsMs      int64  `json:"ts_ms"`
+		// LSN - the position in the write-ahead log of PostgreSQL
+		LSN *int64 `json:"lsn"`
+		// File and Pos - the position in the binlog of MySQL
+		File string `json:"file"`
+		Pos  int64  `json:"pos"`
+	} `json:"source"`
+	// Op - c (create), u (update), d (delete), r (read, a snapshot) or t (truncate)
+	Op   string `json:"op"`
+	TsMs int64  `json:"ts_ms"`
+}
+
+// ErrTombstone - the empty event following a delete, for Kafka log compaction, nothing to apply
+var ErrTombstone = errors.New("tombstone")
+
+// ParseChangeEvent - parses a change event, with or without its schema envelope
+func ParseChangeEvent(payload []byte) (ChangeEvent, error) {
+	var event ChangeEvent
+	if len(payload) == 0 || string(payload) == "null" {
+		return event, ErrTombstone
+	}
+	var envelope struct {
+		Schema  json.RawMessage `json:"schema"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return event, fmt.Errorf("not a change event: %w", err)
+	}
+	if envelope.Schema != nil {
+		if envelope.Payload == nil || string(envelope.Payload) == "null" {
+			return event, ErrTombstone
+		}
+		payload = envelope.Payload
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, fmt.Errorf("not a change event: %w", err)
+	}
+	switch event.Op {
+	case "c", "u", "d", "r", "t":
+	default:
+		return event, fmt.Errorf("unknown operation '%s'", event.Op)
+	}
+	return event, nil
+}
+
+// Position - orders the events of a row: the LSN for PostgreSQL, the binlog position for MySQL, else the time of
+// the change in the database
+func (e ChangeEvent) Position() Position {
+	switch {
+	case e.Source.LSN != nil:
+		return Position{Offset: *e.Source.LSN}
+	case e.Source.File != "":
+		return Position{File: e.Source.File, Offset: e.Source.Pos}
+	default:
+		return Position{Offset: e.Source.TsMs}
+	}
+}
+
+// Position - a position in the log of the database
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Offset int64  `json:"offset"`
+}
+
+func (p Position) String() string {
+	if p.File != "" {
+		return p.File + ":" + strconv.FormatInt(p.Offset, 10)
+	}
+	return strconv.FormatInt(p.Offset, 10)
+}
+
+// Before - reports whether the position comes before the other one, the binlog file names sort in order
+func (p Position) Before(other Position) bool {
+	if p.File != other.File {
+		return p.File < other.File
+	}
+	return p.Offset < other.Offset
+}
+
+// Row - a row of the store and the position of the last event applied to it
+type Row struct {
+	Values   map[string]interface{} `json:"values"`
+	Position Position               `json:"position"`
+	Deleted  bool                   `json:"deleted,omitempty"`
+}
+
+// Store - the local copy of the captured tables, by table then by key, safe for concurrent use
+type Store struct {
+	mu     sync.Mutex
+	Tables map[string]map[string]*Row `json:"tables"`
+}
+
+// LoadStore - reads the store saved to the file, an empty store when there is none
+func LoadStore(path string) (*Store, error) {
+	store := &Store{Tables: map[string]map[string]*Row{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid state store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save - writes the store to the file, through a temporary file so that a crash leaves the previous one
+func (s *Store) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Apply - applies the event to the row of the key, and reports whether it was applied: false for an event older than
+// the last one applied to the row, delivered out of order. A deleted row is kept, without its values, to detect an
+// older event still coming.
+func (s *Store) Apply(key string, event ChangeEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	table := event.Source.Schema + "." + event.Source.Table
+	rows := s.Tables[table]
+	if rows == nil {
+		rows = map[string]*Row{}
+		s.Tables[table] = rows
+	}
+	if event.Op == "t" {
+		s.Tables[table] = map[string]*Row{}
+		return true
+	}
+	position := event.Position()
+	row := rows[key]
+	if row != nil && position.Before(row.Position) {
+		return false
+	}
+	if event.Op == "d" {
+		rows[key] = &Row{Position: position, Deleted: true}
+	} else {
+		// the events carry the whole row, applying one again, when redelivered, changes nothing
+		rows[key] = &Row{Values: event.After, Position: position}
+	}
+	return true
+}
+
+// Counts - the rows of every table, deleted rows excluded
+func (s *Store) Counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[string]int{}
+	for table, rows := range s.Tables {
+		for _, row := range rows {
+			if !row.Deleted {
+				counts[table]++
+			}
+		}
+	}
+	return counts
+}
+
+// Checkpoint - saves the store periodically and acknowledges the messages applied to it once it is saved, an
+// acknowledged event is never lost by a crash
+type Checkpoint struct {
+	Store   *Store
+	Path    string
+	Drainer *lifecycle.Drainer
+
+	mu      sync.Mutex
+	pending []pendingAck
+}
+
+// pendingAck - a message applied to the store, acknowledged by the next save
+type pendingAck struct {
+	receiver solace.PersistentMessageReceiver
+	inbound  message.InboundMessage
+	done     func()
+}
+
+// Ack - acknowledges the message after the next save, the drain waits for it
+func (c *Checkpoint) Ack(receiver solace.PersistentMessageReceiver, inbound message.InboundMessage) {
+	done := c.Drainer.Begin()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, pendingAck{receiver: receiver, inbound: inbound, done: done})
+}
+
+// Save - saves the store, then acknowledges the messages applied before the save. They stay pending when the store
+// cannot be saved, for the next save. The messages of a receiver terminated meanwhile, by a drain past its grace period,
+// are not acknowledged: the broker redelivers them, they are applied again.
+func (c *Checkpoint) Save() error {
+	// taken before the save, every one of them is applied to the store being saved
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	if err := c.Store.Save(c.Path); err != nil {
+		c.mu.Lock()
+		c.pending = append(pending, c.pending...)
+		c.mu.Unlock()
+		return err
+	}
+	for _, applied := range pending {
+		if applied.receiver.IsTerminating() || applied.receiver.IsTerminated() {
+			applied.done()
+			continue
+		}
+		if err := applied.receiver.Ack(applied.inbound); err != nil {
+			fmt.Println("Could not acknowledge a change event: ", err)
+		}
+		applied.done()
+	}
+	return nil
+}
+
+// Run - saves every interval until the context is canceled
+func (c *Checkpoint) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Save(); err != nil {
+				fmt.Println("Could not save the state store: ", err)
+			}
+		}
+	}
+}
+
+// KeyOf - the key of the row of the event: the partition key of the message, set by the publisher to the primary key,
+// else the id column of the row
+func KeyOf(inbound message.InboundMessage, event ChangeEvent) (string, bool) {
+	if key, ok := inbound.GetProperty(string(config.QueuePartitionKey)); ok && key != nil {
+		return fmt.Sprint(key), true
+	}
+	for _, values := range []map[string]interface{}{event.After, event.Before} {
+		if id, ok := values["id"]; ok {
+			return fmt.Sprint(id), true
+		}
+	}
+	return "", false
+}
+
+// Consumer - applies the events received by one of the receivers bound to the partitioned queue
+type Consumer struct {
+	Name       string
+	Store      *Store
+	Checkpoint *Checkpoint
+	Receiver   solace.PersistentMessageReceiver
+
+	// Owners - the consumer the events of every key were applied by, shared by the consumers
+	Owners *sync.Map
+}
+
+// OnMessage - applies the event, the message is acknowledged once the store is saved
+func (c *Consumer) OnMessage(inbound message.InboundMessage) {
+	// acknowledged in every case: an event that cannot be parsed would fail again on every redelivery
+	defer c.Checkpoint.Ack(c.Receiver, inbound)
+
+	payload, _ := inbound.GetPayloadAsBytes()
+	event, err := ParseChangeEvent(payload)
+	if errors.Is(err, ErrTombstone) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("[%s] Skipped a message of %s: %s\n", c.Name, inbound.GetDestinationName(), err)
+		return
+	}
+	key, ok := KeyOf(inbound, event)
+	if !ok && event.Op != "t" {
+		fmt.Printf("[%s] Skipped a %s event of %s without a key\n", c.Name, event.Op, event.Source.Table)
+		return
+	}
+	if previous, loaded := c.Owners.Swap(event.Source.Table+"/"+key, c.Name); loaded && previous != c.Name {
+		// only after a rebalance of the partitions, e.g. a consumer stopped
+		fmt.Printf("[%s] %s/%s moved from %s\n", c.Name, event.Source.Table, key, previous)
+	}
+	if !c.Store.Apply(key, event) {
+		fmt.Printf("[%s] Skipped %s/%s %s at %s, older than the row\n", c.Name, event.Source.Table, key, event.Op, event.Position())
+		return
+	}
+	fmt.Printf("[%s] Applied %s/%s %s at %s\n", c.Name, event.Source.Table, key, event.Op, event.Position())
+}
+
+// SimulateChanges - publishes the change events of a customers table until the context is canceled, the primary key
+// as the partition key
+func SimulateChanges(ctx context.Context, messagingService solace.MessagingService, publisher solace.PersistentMessagePublisher, rows int, interval time.Duration) {
+	topic := resource.TopicOf(TopicPrefix + "/cdc/inventory/public/customers")
+	existing := map[int]map[string]interface{}{}
+	var lsn int64 = 1000
+	for ctx.Err() == nil {
+		id := 1 + rand.Intn(rows)
+		event := map[string]interface{}{"before": existing[id], "after": nil, "ts_ms": time.Now().UnixMilli()}
+		switch row := existing[id]; {
+		case row == nil:
+			existing[id] = map[string]interface{}{"id": id, "name": "customer-" + strconv.Itoa(id), "visits": 1}
+			event["op"], event["after"] = "c", existing[id]
+		case rand.Intn(4) == 0:
+			delete(existing, id)
+			event["op"] = "d"
+		default:
+			updated := map[string]interface{}{"id": id, "name": row["name"], "visits": row["visits"].(int) + 1}
+			existing[id] = updated
+			event["op"], event["after"] = "u", updated
+		}
+		lsn += int64(8 + rand.Intn(64))
+		event["source"] = map[string]interface{}{"connector": "postgresql", "name": "inventory", "db": "inventory",
+			"schema": "public", "table": "customers", "ts_ms": time.Now().UnixMilli(), "lsn": lsn}
+
+		payload, err := json.Marshal(map[string]interface{}{"schema": map[string]interface{}{}, "payload": event})
+		if err != nil {
+			panic(err)
+		}
+		msg, err := messagingService.MessageBuilder().
+			WithProperty(config.MessageProperty(config.QueuePartitionKey), strconv.Itoa(id)).
+			WithHTTPContentHeader("application/json", "").
+			BuildWithByteArrayPayload(payload)
+		if err != nil {
+			panic(err)
+		}
+		if err := publisher.Publish(msg, topic, nil, nil); err != nil {
+			fmt.Println("Could not publish the change event: ", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+func main() {
+	queueName := flag.String("queue", "cdc-changes", "partitioned queue of the change events")
+	partitions := flag.Int("partitions", 4, "partitions of the queue, when provisioned")
+	consumers := flag.Int("consumers", 2, "receivers bound to the queue, sharing its partitions")
+	statePath := flag.String("state", "cdc-state.json", "file the state store is saved to and loaded from on start")
+	saveInterval := flag.Duration("save-interval", time.Second, "interval of the saves of the state store, the events are acknowledged once saved")
+	simulate := flag.Bool("simulate", false, "publish the change events of a customers table")
+	simulateRows := flag.Int("simulate-rows", 10, "rows of the simulated table")
+	simulateInterval := flag.Duration("simulate-interval", 200*time.Millisecond, "interval of the simulated changes")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	store, err := LoadStore(*statePath)
+	if err != nil {
+		panic(err)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	// With -provision (or SOLACE_PROVISION=true) the partitioned queue is created through SEMP first (see internal/semp)
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName,
+		Subscriptions: []string{TopicPrefix + "/cdc/>"}, Partitions: *partitions}); err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Graceful drain on SIGTERM, the events being applied are saved and acknowledged before the receivers terminate
+	// (see pkg/lifecycle)
+	drainer := lifecycle.New()
+	checkpoint := &Checkpoint{Store: store, Path: *statePath, Drainer: drainer}
+
+	// The receivers bind to the non-exclusive partitioned queue, the broker assigns each of them some partitions
+	owners := &sync.Map{}
+	for i := 1; i <= *consumers; i++ {
+		persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+			WithMessageClientAcknowledgement().
+			Build(resource.QueueDurableNonExclusive(*queueName))
+		if err != nil {
+			panic(err)
+		}
+		if err := persistentReceiver.Start(); err != nil {
+			panic(err)
+		}
+		consumer := &Consumer{Name: "consumer-" + strconv.Itoa(i), Store: store, Checkpoint: checkpoint, Receiver: persistentReceiver, Owners: owners}
+		if err := persistentReceiver.ReceiveAsync(drainer.Handler(consumer.OnMessage)); err != nil {
+			panic(err)
+		}
+		drainer.AddReceiver(consumer.Name, persistentReceiver)
+		fmt.Printf("Persistent Receiver %s running? %t\n", consumer.Name, persistentReceiver.IsRunning())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if *simulate {
+		persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+		if err != nil {
+			panic(err)
+		}
+		if err := persistentPublisher.Start(); err != nil {
+			panic(err)
+		}
+		drainer.AddPublisher(persistentPublisher)
+		go SimulateChanges(ctx, messagingService, persistentPublisher, *simulateRows, *simulateInterval)
+	}
+	drainer.AddService(messagingService)
+
+	// Saved until the receivers are drained, the drain waits for the events applied to be acknowledged
+	saving, stopSaving := context.WithCancel(context.Background())
+	saved := make(chan struct{})
+	go func() {
+		checkpoint.Run(saving, *saveInterval)
+		close(saved)
+	}()
+
+	fmt.Printf("\n Applying the change events of %s to %s\n", *queueName, *statePath)
+	fmt.Println("\n===Interrupt (CTR+C) or SIGTERM to handle graceful termination of the consumers===")
+
+	lifecycle.WaitForSignal()
+
+	cancel()
+	fmt.Println("\nDrain: ", drainer.Shutdown())
+	// stopped before the last save, nothing is saved or acknowledged after it
+	stopSaving()
+	<-saved
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+
+	// the events still pending after a drain timeout are saved but not acknowledged, the receivers are terminated:
+	// they are redelivered on the next run and applied again
+	if err := checkpoint.Save(); err != nil {
+		fmt.Println("Could not save the state store: ", err)
+		os.Exit(1)
+	}
+	counts := store.Counts()
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Printf("%s: %d row(s)\n", table, counts[table])
+	}
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"strings"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/propagation"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace"
-	"solace.dev/go/messaging/pkg/solace/config"
 	"solace.dev/go/messaging/pkg/solace/message"
 	"solace.dev/go/messaging/pkg/solace/resource"
 )
@@ -23,27 +26,18 @@ func ReconnectionHandler(e solace.ServiceEvent) {
 	}
 }
 
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 // Define Topic Prefix
 const TopicPrefix = "solace/samples"
 
 func main() {
 
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
@@ -111,13 +105,19 @@ func main() {
 		fmt.Printf("Received a message: %s on topic %s\n", messageBody, receivedTopic)
 		fmt.Printf("Uppercasing to %s and publishing on %s\n\n", processedMsg, processedTopic)
 
+		// The processed message carries on the flow of the received one: the correlation ID is kept, or a new one
+		// starts the flow, and the trace context and baggage are passed on (see pkg/propagation)
+		flow := propagation.EnsureCorrelationID(propagation.Extract(context.Background(), message))
+		correlationID, _ := propagation.CorrelationID(flow)
+		fmt.Printf("Flow %s\n", correlationID)
+
 		outMessage, err := messageBuilder.BuildWithStringPayload(processedMsg)
 		if err != nil {
 			panic(err)
 		}
 
 		// Publish on dynamic topic with dynamic body
-		publishErr := directPublisher.Publish(outMessage, resource.TopicOf(processedTopic))
+		publishErr := directPublisher.PublishWithProperties(outMessage, resource.TopicOf(processedTopic), propagation.Properties(flow))
 		if publishErr != nil {
 			panic(publishErr)
 		}
@@ -145,7 +145,7 @@ func main() {
 
 	// Run forever until an interrupt signal is received
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 	// Block until a interrupt signal is received.
 	<-c
 }
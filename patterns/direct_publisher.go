@@ -1,44 +1,44 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/leakcheck"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
 	"solace.dev/go/messaging"
-	"solace.dev/go/messaging/pkg/solace/config"
 	"solace.dev/go/messaging/pkg/solace/resource"
 )
 
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 // Define Topic Prefix
 const TopicPrefix = "solace/samples"
 
 func main() {
-	// logging.SetLogLevel(logging.LogLevelInfo)
-
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
 	}
 
+	// Checks that the publishers, receivers and the service leave no goroutine or file descriptor behind once
+	// terminated and disconnected, when SOLACE_LEAK_CHECK is set (see internal/leakcheck)
+	guard := leakcheck.FromEnv()
+
 	// Connect to the messaging serice
 	if err := messagingService.Connect(); err != nil {
 		panic(err)
@@ -94,7 +94,7 @@ func main() {
 
 	// Handle OS interrupts
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 
 	// Block until an OS interrupt signal is received.
 	<-c
@@ -105,5 +105,6 @@ func main() {
 	// Disconnect the Message Service
 	messagingService.Disconnect()
 	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+	guard.Verify()
 
 }
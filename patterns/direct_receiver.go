@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/leakcheck"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/metricsink"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace"
-	"solace.dev/go/messaging/pkg/solace/config"
 	"solace.dev/go/messaging/pkg/solace/message"
 	"solace.dev/go/messaging/pkg/solace/resource"
 )
@@ -24,7 +29,7 @@ func MessageHandler(message message.InboundMessage) {
 	}
 
 	fmt.Printf("Received Message Body %s \n", messageBody)
-	// fmt.Printf("Message Dump %s \n", message)
+	// fmt.Printf("Message Dump\n%s", msgdump.Text(message))
 }
 
 func ReconnectionHandler(e solace.ServiceEvent) {
@@ -36,36 +41,41 @@ func ReconnectionHandler(e solace.ServiceEvent) {
 	}
 }
 
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 // Define Topic Prefix
 const TopicPrefix = "solace/samples"
 
 func main() {
 
-	// logging.SetLogLevel(logging.LogLevelInfo)
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
 
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
 	}
 
+	// Metrics, served to Prometheus on /metrics when SOLACE_METRICS_ADDR is set, e.g. SOLACE_METRICS_ADDR=:2112, or sent
+	// to a StatsD agent with SOLACE_METRICS_SINK=statsd or dogstatsd (see pkg/metricsink)
+	exporter, err := metricsink.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+	defer exporter.Close()
+	exporter.AddService("direct-receiver", messagingService)
+
 	messagingService.AddReconnectionListener(ReconnectionHandler)
 
+	// Checks that the publishers, receivers and the service leave no goroutine or file descriptor behind once
+	// terminated and disconnected, when SOLACE_LEAK_CHECK is set (see internal/leakcheck)
+	guard := leakcheck.FromEnv()
+
 	// Connect to the messaging serice
 	if err := messagingService.Connect(); err != nil {
 		panic(err)
@@ -104,7 +114,7 @@ func main() {
 	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
 
 	// Register Message callback handler to the Message Receiver
-	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+	if regErr := directReceiver.ReceiveAsync(exporter.Handler("direct-receiver", MessageHandler)); regErr != nil {
 		panic(regErr)
 	}
 
@@ -118,11 +128,12 @@ func main() {
 		// Disconnect the Message Service
 		messagingService.Disconnect()
 		fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+		guard.Verify()
 	}()
 
 	// Run forever until an interrupt signal is received
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 	// Block until a interrupt signal is received.
 	<-c
 }
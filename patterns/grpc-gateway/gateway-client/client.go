@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/patterns/grpc-gateway/gatewaypb"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// gRPC gateway client: a microservice that only knows the protobuf contract of the gateway (see
+// gatewaypb/gateway.proto), no messaging API. It streams the messages of solace/samples/grpc/> and publishes one every
+// second through the gateway, started first:
+//
+//	go run ./patterns/grpc-gateway/gateway
+//	go run ./patterns/grpc-gateway/gateway-client -gateway localhost:50051
+
+func main() {
+	gateway := flag.String("gateway", "localhost:50051", "address of the gRPC gateway")
+	flag.Parse()
+
+	// The gateway of the sample listens without TLS, use credentials.NewTLS beyond localhost
+	conn, err := grpc.Dial(*gateway, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	client := gatewaypb.NewGatewayClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Server streaming: the messages arrive until the context is canceled. WaitForReady waits for the gateway to be up,
+	// e.g. when both are started together, instead of failing with UNAVAILABLE
+	stream, err := client.Subscribe(ctx, &gatewaypb.SubscribeRequest{Subscriptions: []string{TopicPrefix + "/grpc/>"}}, grpc.WaitForReady(true))
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					fmt.Println("Stream ended: ", status.Convert(err).Message())
+				}
+				return
+			}
+			fmt.Printf("Received Message Body %s on %s, properties %v\n", msg.GetPayload(), msg.GetTopic(), msg.GetProperties())
+		}
+	}()
+
+	go func() {
+		for msgSeqNum := 1; ctx.Err() == nil; msgSeqNum++ {
+			publishCtx, cancelPublish := context.WithTimeout(ctx, 5*time.Second)
+			response, err := client.Publish(publishCtx, &gatewaypb.PublishRequest{
+				Topic:                TopicPrefix + "/grpc/hello/" + strconv.Itoa(msgSeqNum),
+				Payload:              []byte("Hello from the gRPC gateway client --> " + strconv.Itoa(msgSeqNum)),
+				Properties:           map[string]string{"language": "go", "via": "grpc"},
+				ApplicationMessageId: "grpc-" + strconv.Itoa(msgSeqNum),
+				ContentType:          "text/plain",
+			})
+			cancelPublish()
+			if err != nil {
+				// the status code tells a retryable error (UNAVAILABLE, DEADLINE_EXCEEDED) from a permanent one
+				st := status.Convert(err)
+				fmt.Printf("Publish failed: %s %s\n", st.Code(), st.Message())
+			} else {
+				fmt.Printf("Published message %d, acknowledged at %s\n", msgSeqNum, time.UnixMilli(response.GetAcknowledgedAtMs()).Format(time.RFC3339Nano))
+			}
+			time.Sleep(1 * time.Second)
+		}
+	}()
+
+	fmt.Println("\n===Interrupt (CTR+C) to stop the client===")
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+}
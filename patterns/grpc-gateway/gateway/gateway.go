@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/patterns/grpc-gateway/gatewaypb"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// gRPC gateway: fronts the broker with a protobuf service contract (see gatewaypb/gateway.proto), for the
+// microservices that speak gRPC rather than a messaging API. The unary Publish RPC is backed by a persistent publisher
+// and returns once the broker acknowledged the message; the server streaming Subscribe RPC is backed by a direct
+// receiver per call, subscribed to the topics of the request and terminated when the call is canceled.
+//
+//	go run ./patterns/grpc-gateway/gateway -listen localhost:50051
+//	go run ./patterns/grpc-gateway/gateway-client -gateway localhost:50051
+//
+// The broker errors map to gRPC status codes, so the clients handle them like any other gRPC error: an invalid topic
+// is INVALID_ARGUMENT, a publish not acknowledged in time DEADLINE_EXCEEDED, a full publish buffer RESOURCE_EXHAUSTED
+// and a lost connection UNAVAILABLE.
+
+// SubscribeBuffer - messages buffered per Subscribe call for a slow client, the oldest are dropped beyond
+const SubscribeBuffer = 1000
+
+// Gateway - the gRPC service, sharing one connection to the broker between the calls
+type Gateway struct {
+	gatewaypb.UnimplementedGatewayServer
+	MessagingService solace.MessagingService
+	Publisher        solace.PersistentMessagePublisher
+	// AckTimeout - how long Publish waits for the acknowledgement of the broker, within the deadline of the call
+	AckTimeout time.Duration
+	// Shutdown - closed when the gateway shuts down, ending the streams
+	Shutdown <-chan struct{}
+}
+
+// Publish - publishes the message of the request and waits for its acknowledgement
+func (g *Gateway) Publish(ctx context.Context, request *gatewaypb.PublishRequest) (*gatewaypb.PublishResponse, error) {
+	if request.GetTopic() == "" {
+		return nil, status.Error(codes.InvalidArgument, "the topic is required")
+	}
+	properties := config.MessagePropertyMap{}
+	for name, value := range request.GetProperties() {
+		properties[config.MessageProperty(name)] = value
+	}
+	builder := g.MessagingService.MessageBuilder().FromConfigurationProvider(properties)
+	if id := request.GetApplicationMessageId(); id != "" {
+		builder = builder.WithApplicationMessageID(id)
+	}
+	if id := request.GetCorrelationId(); id != "" {
+		builder = builder.WithCorrelationID(id)
+	}
+	if contentType := request.GetContentType(); contentType != "" {
+		builder = builder.WithHTTPContentHeader(contentType, "")
+	}
+	outbound, err := builder.BuildWithByteArrayPayload(request.GetPayload())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	timeout := g.AckTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	if err := g.Publisher.PublishAwaitAcknowledgement(outbound, resource.TopicOf(request.GetTopic()), timeout, nil); err != nil {
+		return nil, StatusOf(err)
+	}
+	return &gatewaypb.PublishResponse{AcknowledgedAtMs: time.Now().UnixMilli()}, nil
+}
+
+// Subscribe - streams the messages of the subscriptions of the request until the call is canceled
+func (g *Gateway) Subscribe(request *gatewaypb.SubscribeRequest, stream gatewaypb.Gateway_SubscribeServer) error {
+	if len(request.GetSubscriptions()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one subscription is required")
+	}
+	var subscriptions []resource.Subscription
+	for _, subscription := range request.GetSubscriptions() {
+		subscriptions = append(subscriptions, resource.TopicSubscriptionOf(subscription))
+	}
+	directReceiver, err := g.MessagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(subscriptions...).
+		OnBackPressureDropOldest(SubscribeBuffer).
+		Build()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := directReceiver.Start(); err != nil {
+		return StatusOf(err)
+	}
+	defer directReceiver.Terminate(1 * time.Second)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-g.Shutdown:
+			return status.Error(codes.Unavailable, "the gateway is shutting down")
+		default:
+		}
+		inbound, err := directReceiver.ReceiveMessage(500 * time.Millisecond)
+		if err != nil {
+			var timeoutErr *solace.TimeoutError
+			if errors.As(err, &timeoutErr) {
+				continue
+			}
+			return StatusOf(err)
+		}
+		// Send blocks while the client does not read, the receiver buffers meanwhile
+		if err := stream.Send(MessageOf(inbound)); err != nil {
+			return err
+		}
+	}
+}
+
+// MessageOf - converts a received message to its protobuf message
+func MessageOf(inbound message.InboundMessage) *gatewaypb.Message {
+	payload, _ := inbound.GetPayloadAsBytes()
+	discard := inbound.GetMessageDiscardNotification()
+	msg := &gatewaypb.Message{
+		Topic:             inbound.GetDestinationName(),
+		Payload:           payload,
+		Properties:        map[string]string{},
+		DiscardIndication: discard.HasInternalDiscardIndication() || discard.HasBrokerDiscardIndication(),
+	}
+	for name, value := range inbound.GetProperties() {
+		msg.Properties[name] = fmt.Sprint(value)
+	}
+	msg.ApplicationMessageId, _ = inbound.GetApplicationMessageID()
+	msg.CorrelationId, _ = inbound.GetCorrelationID()
+	msg.ContentType, _ = inbound.GetHTTPContentType()
+	if timestamp, ok := inbound.GetSenderTimestamp(); ok {
+		msg.SenderTimestampMs = timestamp.UnixMilli()
+	}
+	return msg
+}
+
+// StatusOf - maps an error of the API to a gRPC status
+func StatusOf(err error) error {
+	var timeoutErr *solace.TimeoutError
+	var illegalArgumentErr *solace.IllegalArgumentError
+	var overflowErr *solace.PublisherOverflowError
+	switch {
+	case errors.As(err, &timeoutErr):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.As(err, &illegalArgumentErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &overflowErr):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+}
+
+func main() {
+	listen := flag.String("listen", "localhost:50051", "address the gRPC server listens on")
+	ackTimeout := flag.Duration("ack-timeout", 10*time.Second, "how long Publish waits for the acknowledgement of the broker")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := persistentPublisher.Start(); err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		panic(err)
+	}
+	shutdown := make(chan struct{})
+	server := grpc.NewServer()
+	gatewaypb.RegisterGatewayServer(server, &Gateway{
+		MessagingService: messagingService,
+		Publisher:        persistentPublisher,
+		AckTimeout:       *ackTimeout,
+		Shutdown:         shutdown,
+	})
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			fmt.Println("gRPC server stopped: ", err)
+		}
+	}()
+
+	fmt.Printf("\n gRPC gateway listening on %s\n", listener.Addr())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the gateway===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	// Stop serving first: the streams end and the pending publishes complete, then terminate the publisher
+	close(shutdown)
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		server.Stop()
+	}
+	persistentPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Publisher Terminated? ", persistentPublisher.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
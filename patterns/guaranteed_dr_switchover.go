@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Disaster recovery switchover: with replication the message VPN is active on one site and standby on the other,
+// the host list names both sites so the API reconnects to the other site after a switchover (the standby site
+// refuses connections until it becomes active).
+//
+//	SOLACE_HOST=tcp://site-a.example.com:55555,tcp://site-b.example.com:55555 SOLACE_QUEUE=durable-queue go run guaranteed_dr_switchover.go
+//
+// What the application sees and must handle:
+//   - the connection is lost and the API keeps reconnecting (the reconnection attempt events) until the other site is
+//     active, which can take minutes: the reconnection strategy must retry long enough, forever here
+//   - once reconnected (the reconnection event) the publisher resends the messages that were not acknowledged yet,
+//     with asynchronous replication some of them were already spooled and replicated so the consumer may get them
+//     twice, and messages acknowledged by the old site but not yet replicated are lost
+//   - messages published while reconnecting are buffered, or block when the buffer is full
+//   - the receiver gets the messages it had not acknowledged on the old site again, not always flagged as redelivered
+//
+// So the publisher gives every message an application message ID and tracks it, by correlation, until the broker
+// acknowledged it, republishing with the same ID on failure; the consumer drops the IDs it already processed.
+
+// PendingPublishes - messages published but not acknowledged by the broker yet, keyed by their application message ID
+// which is also the user context of the publish so the receipt finds its message
+type PendingPublishes struct {
+	mu      sync.Mutex
+	pending map[string]message.OutboundMessage
+}
+
+// NewPendingPublishes - creates an empty set of pending publishes
+func NewPendingPublishes() *PendingPublishes {
+	return &PendingPublishes{pending: make(map[string]message.OutboundMessage)}
+}
+
+// Add - tracks a message about to be published
+func (p *PendingPublishes) Add(id string, message message.OutboundMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending[id] = message
+}
+
+// Settle - stops tracking the message of an acknowledged publish, a failed publish stays pending and its message is
+// returned to be published again
+func (p *PendingPublishes) Settle(receipt solace.PublishReceipt) (string, message.OutboundMessage) {
+	id, _ := receipt.GetUserContext().(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if receipt.GetError() != nil {
+		return id, p.pending[id]
+	}
+	delete(p.pending, id)
+	return id, nil
+}
+
+// IDs - the IDs of the pending messages, sorted
+func (p *PendingPublishes) IDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.pending))
+	for id := range p.pending {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DuplicateFilter - remembers the last size application message IDs processed
+type DuplicateFilter struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+	ring []string
+	next int
+}
+
+// NewDuplicateFilter - creates a filter remembering size IDs, more than can be in flight on the queue
+func NewDuplicateFilter(size int) *DuplicateFilter {
+	return &DuplicateFilter{seen: make(map[string]struct{}, size), ring: make([]string, size)}
+}
+
+// Seen - reports whether the ID was processed already, and remembers it otherwise
+func (f *DuplicateFilter) Seen(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.seen[id]; ok {
+		return true
+	}
+	delete(f.seen, f.ring[f.next])
+	f.ring[f.next] = id
+	f.next = (f.next + 1) % len(f.ring)
+	f.seen[id] = struct{}{}
+	return false
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the host list must name the hosts of both sites
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	// Try every host twice per reconnection attempt
+	brokerConfig.Properties[config.TransportLayerPropertyConnectionRetriesPerHost] = 1
+	queueName := sampleconfig.Setting("SOLACE_QUEUE", "durable-queue")
+
+	// A switchover takes longer than a broker restart, keep retrying until the other site is active
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(3 * time.Second)).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	pending := NewPendingPublishes()
+	var connected int32 = 1
+	var outageStart time.Time
+
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		if atomic.CompareAndSwapInt32(&connected, 1, 0) {
+			outageStart = event.GetTimestamp()
+			fmt.Printf("Connection to %s lost (%v), %d publish(es) not acknowledged yet\n", event.GetBrokerURI(), event.GetCause(), len(pending.IDs()))
+		}
+	})
+	messagingService.AddReconnectionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("Reconnected to %s after %s, the publisher resends %v\n", event.GetBrokerURI(),
+			event.GetTimestamp().Sub(outageStart).Round(time.Millisecond), pending.IDs())
+		atomic.StoreInt32(&connected, 1)
+	})
+	messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		// does not happen with the forever retry strategy unless the broker refuses the client, e.g. on a login failure
+		fmt.Printf("Service interrupted: %v, the messages %v were not acknowledged\n", event.GetCause(), pending.IDs())
+	})
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Receiver: client acknowledgement, after the message was processed or recognized as a duplicate
+	duplicates := NewDuplicateFilter(10000)
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		Build(resource.QueueDurableExclusive(queueName))
+	if err != nil {
+		panic(err)
+	}
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := persistentReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		id, ok := message.GetApplicationMessageID()
+		if ok && duplicates.Seen(id) {
+			fmt.Printf("Dropped duplicate %s (redelivered: %t)\n", id, message.IsRedelivered())
+		} else {
+			payload, _ := message.GetPayloadAsString()
+			fmt.Printf("Processed %s: %s (redelivered: %t)\n", id, payload, message.IsRedelivered())
+		}
+		if err := persistentReceiver.Ack(message); err != nil {
+			// not acknowledged, the message comes again and is dropped as a duplicate
+			fmt.Println("Ack failed: ", err)
+		}
+	}); regErr != nil {
+		panic(regErr)
+	}
+
+	// Publisher: publishes block while the buffer is full, e.g. during the switchover
+	persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().
+		OnBackPressureWait(100).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	topic := resource.TopicOf(TopicPrefix + "/persistent/dr")
+	publish := func(id string, message message.OutboundMessage) {
+		pending.Add(id, message)
+		if err := persistentPublisher.Publish(message, topic, nil, id); err != nil {
+			// not sent, e.g. the publisher was terminated: it stays pending
+			fmt.Printf("Publish of %s failed: %s\n", id, err)
+		}
+	}
+	persistentPublisher.SetMessagePublishReceiptListener(func(receipt solace.PublishReceipt) {
+		id, failed := pending.Settle(receipt)
+		if failed != nil {
+			// rejected by the broker, e.g. the queue was full on the new site: publish it again with the same ID so
+			// the consumer can tell if it got both
+			fmt.Printf("Publish of %s failed: %s, publishing it again\n", id, receipt.GetError())
+			go publish(id, failed)
+		}
+	})
+	if err := persistentPublisher.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Publisher running? ", persistentPublisher.IsRunning())
+	fmt.Printf("\n Bound to queue: %s, publishing on: %s, please ensure the queue has a matching subscription.\n", queueName, topic.GetName())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher and receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// The application message ID must be unique across restarts of the publisher too, prefix it with the start time
+	idPrefix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	messageBuilder := messagingService.MessageBuilder()
+	for msgSeqNum := 0; ; {
+		select {
+		case <-c:
+			// Terminate the publisher first, waiting for the outstanding acknowledgements
+			persistentPublisher.Terminate(5 * time.Second)
+			fmt.Println("\nPersistent Publisher Terminated? ", persistentPublisher.IsTerminated())
+			if ids := pending.IDs(); len(ids) > 0 {
+				fmt.Println("Not acknowledged by the broker, to publish again on the next run: ", ids)
+			}
+			persistentReceiver.Terminate(1 * time.Second)
+			fmt.Println("Persistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+			// Disconnect the Message Service
+			messagingService.Disconnect()
+			fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+			return
+		case <-time.After(1 * time.Second):
+		}
+		if atomic.LoadInt32(&connected) == 0 {
+			// the API buffers the publishes while reconnecting, rather hold new ones until the switchover is done
+			continue
+		}
+		id := idPrefix + "-" + strconv.Itoa(msgSeqNum)
+		message, err := messageBuilder.
+			WithApplicationMessageID(id).
+			BuildWithStringPayload("Hello across sites --> " + strconv.Itoa(msgSeqNum))
+		if err != nil {
+			panic(err)
+		}
+		publish(id, message)
+		msgSeqNum++
+	}
+}
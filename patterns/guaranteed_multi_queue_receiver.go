@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/endpoints"
+	"SolaceSamples.com/PubSub+Go/pkg/health"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// QueueConsumer - pairs a queue with the handler that processes the messages bound from it
+type QueueConsumer struct {
+	QueueName string
+	Handler   func(queueName string, message message.InboundMessage)
+	receiver  solace.PersistentMessageReceiver
+}
+
+// OrdersHandler - handler for messages received from the orders queue
+func OrdersHandler(queueName string, message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("[%s] Processing order: %s\n", queueName, payload)
+}
+
+// PaymentsHandler - handler for messages received from the payments queue
+func PaymentsHandler(queueName string, message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsBytes()
+	fmt.Printf("[%s] Processing payment of %d bytes\n", queueName, len(payload))
+}
+
+// AuditHandler - handler for messages received from any other queue
+func AuditHandler(queueName string, message message.InboundMessage) {
+	fmt.Printf("[%s] Audit record from topic %s, redelivered: %t\n", queueName, message.GetDestinationName(), message.IsRedelivered())
+}
+
+// StartQueueConsumers - builds and starts one persistent receiver per queue on the shared messaging service.
+// If any receiver fails to start, the receivers started so far are terminated before the error is returned.
+func StartQueueConsumers(messagingService solace.MessagingService, consumers []*QueueConsumer) error {
+	for i, consumer := range consumers {
+		receiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+			WithMessageAutoAcknowledgement().
+			Build(resource.QueueDurableExclusive(consumer.QueueName))
+		if err == nil {
+			err = receiver.Start()
+		}
+		if err != nil {
+			TerminateQueueConsumers(consumers[:i], 1*time.Second)
+			return fmt.Errorf("could not bind to queue '%s': %w", consumer.QueueName, err)
+		}
+
+		// Capture the consumer for this iteration so every queue is routed to its own handler
+		c := consumer
+		if regErr := receiver.ReceiveAsync(func(message message.InboundMessage) {
+			c.Handler(c.QueueName, message)
+		}); regErr != nil {
+			receiver.Terminate(0)
+			TerminateQueueConsumers(consumers[:i], 1*time.Second)
+			return regErr
+		}
+		consumer.receiver = receiver
+		fmt.Printf("Bound to queue: %s\n", consumer.QueueName)
+	}
+	return nil
+}
+
+// TerminateQueueConsumers - terminates all of the receivers concurrently, each within the given grace period,
+// and waits until every one of them has finished
+func TerminateQueueConsumers(consumers []*QueueConsumer, gracePeriod time.Duration) {
+	var wg sync.WaitGroup
+	for _, consumer := range consumers {
+		if consumer.receiver == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(c *QueueConsumer) {
+			defer wg.Done()
+			if err := c.receiver.Terminate(gracePeriod); err != nil {
+				fmt.Printf("Error terminating receiver for queue '%s': %s\n", c.QueueName, err)
+			}
+			fmt.Printf("Persistent Receiver for queue '%s' Terminated? %t\n", c.QueueName, c.receiver.IsTerminated())
+		}(consumer)
+	}
+	wg.Wait()
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Kubernetes probes, /healthz and /readyz served when SOLACE_HEALTH_ADDR is set, e.g. SOLACE_HEALTH_ADDR=:8080
+	checker := health.New()
+	checker.AddService("multi-queue-receiver", messagingService)
+	if address := sampleconfig.Setting("SOLACE_HEALTH_ADDR", ""); address != "" {
+		if _, err := checker.ListenAndServe(address); err != nil {
+			panic(err)
+		}
+	}
+
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: not ready, every receiver paused, the handlers in flight
+	// finished, then the receivers terminated (see pkg/lifecycle)
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		panic(err)
+	}
+	drainer := lifecycle.New(lifecycle.WithChecker(checker), lifecycle.WithGracePeriod(gracePeriod))
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// All receivers share the single messaging service (one connection to the broker), each one bound to its own queue.
+	// The queues must already exist on the broker. Override the list with a comma separated SOLACE_QUEUES value,
+	// queues after the first two are routed to the audit handler.
+	// With SOLACE_EPHEMERAL_QUEUES=true the queues are provisioned under a name tagged with this run instead,
+	// and deprovisioned again on exit.
+	queueNames := strings.Split(sampleconfig.Setting("SOLACE_QUEUES", "orders-queue,payments-queue,audit-queue"), ",")
+	handlers := []func(string, message.InboundMessage){OrdersHandler, PaymentsHandler}
+
+	var ephemeralQueues *endpoints.Manager
+	if sampleconfig.Setting("SOLACE_EPHEMERAL_QUEUES", "false") == "true" {
+		ephemeralQueues = endpoints.NewManager(messagingService, "")
+		fmt.Println("Provisioning ephemeral queues tagged: ", ephemeralQueues.Tag())
+	}
+
+	consumers := make([]*QueueConsumer, len(queueNames))
+	for i, queueName := range queueNames {
+		handler := AuditHandler
+		if i < len(handlers) {
+			handler = handlers[i]
+		}
+		queueName = strings.TrimSpace(queueName)
+		if ephemeralQueues != nil {
+			queue, err := ephemeralQueues.ProvisionQueue(queueName)
+			if err != nil {
+				fmt.Println(err)
+				ephemeralQueues.DeprovisionAll()
+				messagingService.Disconnect()
+				os.Exit(1)
+			}
+			queueName = queue.GetName()
+		}
+		// Record the activity of every queue for the health checks, and the handlers in flight for the drain
+		process := handler
+		consumers[i] = &QueueConsumer{QueueName: queueName, Handler: func(queueName string, message message.InboundMessage) {
+			defer drainer.Begin()()
+			process(queueName, message)
+			checker.Activity()
+		}}
+	}
+
+	if err := StartQueueConsumers(messagingService, consumers); err != nil {
+		fmt.Println("Make sure all of the queues exist on the broker.\n", err)
+		if ephemeralQueues != nil {
+			ephemeralQueues.DeprovisionAll()
+		}
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+	for _, consumer := range consumers {
+		checker.AddReceiver(consumer.QueueName, consumer.receiver)
+		drainer.AddReceiver(consumer.QueueName, consumer.receiver)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) or SIGTERM to handle graceful termination of the receivers===")
+
+	// Run forever until an interrupt or a SIGTERM is received
+	lifecycle.WaitForSignal()
+
+	// Drain and terminate all of the Persistent Receivers before disconnecting the shared Messaging Service
+	fmt.Println("\nDrain: ", drainer.Shutdown())
+	for _, consumer := range consumers {
+		fmt.Printf("Persistent Receiver for queue '%s' Terminated? %t\n", consumer.QueueName, consumer.receiver.IsTerminated())
+	}
+
+	// Remove the ephemeral queues once no receiver is bound to them anymore
+	if ephemeralQueues != nil {
+		if err := ephemeralQueues.DeprovisionAll(); err != nil {
+			fmt.Println("Error deprovisioning the ephemeral queues: ", err)
+		}
+	}
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
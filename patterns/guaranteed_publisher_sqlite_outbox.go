@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	_ "modernc.org/sqlite"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Transactional outbox: the application writes its business row, an order, and the event announcing it, an outbox
+// entry, in one local SQLite transaction, so there is never an order without its event or an event without its order,
+// whatever fails. A relay goroutine then publishes the unsent entries as persistent messages and marks each one sent
+// once the broker acknowledged it, in its publish receipt.
+//
+//	go run guaranteed_publisher_sqlite_outbox.go -db outbox.db
+//	go run guaranteed_receiver.go
+//
+// An entry is published at least once: a relay stopped between a publish and its receipt publishes the entry again on
+// the next run, and an entry whose publish failed is published again after the entries that followed it. The
+// application message ID of the messages is outbox-<entry id>, the same on every publish, for the consumers to discard
+// the duplicates. Interrupt the sample, or stop the broker, while it runs: the orders keep being placed, and the
+// entries are published once the broker is back. The SQLite driver is pure Go, no cgo is needed.
+
+// Schema - the business table and the outbox, the partial index finds the unsent entries
+const Schema = `
+CREATE TABLE IF NOT EXISTS orders (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer   TEXT NOT NULL,
+	amount     INTEGER NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE TABLE IF NOT EXISTS outbox (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	topic      TEXT NOT NULL,
+	payload    BLOB NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+	sent_at    TEXT
+);
+CREATE INDEX IF NOT EXISTS outbox_unsent ON outbox (id) WHERE sent_at IS NULL;`
+
+// OrderTopic - the topic of the order events, consumed by guaranteed_receiver.go through durable-queue
+const OrderTopic = TopicPrefix + "/persistent/outbox/orders"
+
+// PlaceOrder - inserts the order and its outbox entry in one transaction, returns the ID of the order
+func PlaceOrder(ctx context.Context, db *sql.DB, customer string, amount int) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO orders (customer, amount) VALUES (?, ?)`, customer, amount)
+	if err != nil {
+		return 0, err
+	}
+	orderID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	event, err := json.Marshal(map[string]interface{}{"event": "order-placed", "orderId": orderID, "customer": customer, "amount": amount})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO outbox (topic, payload) VALUES (?, ?)`, OrderTopic, event); err != nil {
+		return 0, err
+	}
+	return orderID, tx.Commit()
+}
+
+// Relay - publishes the unsent outbox entries and marks them sent on their publish receipt
+type Relay struct {
+	DB               *sql.DB
+	MessagingService solace.MessagingService
+	Publisher        solace.PersistentMessagePublisher
+	// Batch - entries read from the outbox at a time
+	Batch int
+
+	// wake - signaled when entries were added, the relay also polls the outbox
+	wake chan struct{}
+
+	mu sync.Mutex
+	// inFlight - the entries published and waiting for their receipt, not published again meanwhile
+	inFlight map[int64]bool
+	sent     int64
+	failed   int64
+}
+
+// NewRelay - a relay for the outbox of the database, publishing with the publisher
+func NewRelay(db *sql.DB, messagingService solace.MessagingService, publisher solace.PersistentMessagePublisher, batch int) *Relay {
+	relay := &Relay{DB: db, MessagingService: messagingService, Publisher: publisher, Batch: batch,
+		wake: make(chan struct{}, 1), inFlight: map[int64]bool{}}
+	publisher.SetMessagePublishReceiptListener(relay.onReceipt)
+	return relay
+}
+
+// Wake - has the relay read the outbox now rather than at its next poll
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run - publishes the unsent entries until the context is canceled
+func (r *Relay) Run(ctx context.Context, poll time.Duration) {
+	for ctx.Err() == nil {
+		if err := r.publishUnsent(ctx); err != nil && ctx.Err() == nil {
+			fmt.Println("Relay: ", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-r.wake:
+		case <-time.After(poll):
+		}
+	}
+}
+
+func (r *Relay) publishUnsent(ctx context.Context) error {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, topic, payload FROM outbox WHERE sent_at IS NULL ORDER BY id LIMIT ?`, r.Batch)
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		id      int64
+		topic   string
+		payload []byte
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.topic, &e.payload); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !r.claim(e.id) {
+			continue
+		}
+		msg, err := r.MessagingService.MessageBuilder().
+			WithApplicationMessageID("outbox-"+strconv.FormatInt(e.id, 10)).
+			WithHTTPContentHeader("application/json", "").
+			BuildWithByteArrayPayload(e.payload)
+		if err == nil {
+			// the entry ID comes back in the publish receipt
+			err = r.Publisher.Publish(msg, resource.TopicOf(e.topic), nil, e.id)
+		}
+		if err != nil {
+			// e.g. while reconnecting, or the publish buffer is full: published again at the next poll
+			r.release(e.id)
+			return fmt.Errorf("could not publish outbox entry %d: %w", e.id, err)
+		}
+	}
+	return nil
+}
+
+func (r *Relay) claim(id int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inFlight[id] {
+		return false
+	}
+	r.inFlight[id] = true
+	return true
+}
+
+func (r *Relay) release(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.inFlight, id)
+}
+
+func (r *Relay) onReceipt(receipt solace.PublishReceipt) {
+	id, ok := receipt.GetUserContext().(int64)
+	if !ok {
+		return
+	}
+	defer r.release(id)
+	if err := receipt.GetError(); err != nil {
+		r.mu.Lock()
+		r.failed++
+		r.mu.Unlock()
+		fmt.Printf("Outbox entry %d not acknowledged, it will be published again: %s\n", id, err)
+		r.Wake()
+		return
+	}
+	// a relay stopped before this update publishes the entry again on the next run
+	if _, err := r.DB.Exec(`UPDATE outbox SET sent_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') WHERE id = ?`, id); err != nil {
+		fmt.Printf("Could not mark outbox entry %d sent, it will be published again: %s\n", id, err)
+		return
+	}
+	r.mu.Lock()
+	r.sent++
+	r.mu.Unlock()
+}
+
+// Stats - entries sent and not acknowledged since the start
+func (r *Relay) Stats() (sent, failed int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sent, r.failed
+}
+
+func main() {
+	dbPath := flag.String("db", "outbox.db", "SQLite database of the orders and the outbox, created when missing")
+	orderInterval := flag.Duration("order-interval", 1*time.Second, "place an order at this interval")
+	poll := flag.Duration("poll", 1*time.Second, "read the outbox at least at this interval")
+	batch := flag.Int("batch", 100, "outbox entries read at a time")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	db, err := sql.Open("sqlite", *dbPath)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	// One connection: SQLite has a single writer, the application and the relay take turns rather than failing busy
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(Schema); err != nil {
+		panic(err)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	// Keep retrying to reconnect every 3 seconds, the outbox fills up meanwhile and is published once reconnected
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(3 * time.Second)).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	relay := NewRelay(db, messagingService, persistentPublisher, *batch)
+	if err := persistentPublisher.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Persistent Publisher running? ", persistentPublisher.IsRunning())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		relay.Run(ctx, *poll)
+	}()
+
+	// The business side: places orders, unaware of the broker, whether it is up or not
+	go func() {
+		defer wg.Done()
+		customers := []string{"alice", "bob", "carol"}
+		for ctx.Err() == nil {
+			orderID, err := PlaceOrder(ctx, db, customers[rand.Intn(len(customers))], 10+rand.Intn(90))
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Println("Could not place the order: ", err)
+				}
+			} else {
+				fmt.Printf("Placed order %d\n", orderID)
+				relay.Wake()
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(*orderInterval):
+			}
+		}
+	}()
+
+	fmt.Printf("\n Publishing the outbox of %s on %s\n", *dbPath, OrderTopic)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	cancel()
+	wg.Wait()
+	// waits for the receipts of the messages in flight, the entries are marked sent as they arrive
+	persistentPublisher.Terminate(5 * time.Second)
+	fmt.Println("\nPersistent Publisher Terminated? ", persistentPublisher.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+
+	sent, failed := relay.Stats()
+	var unsent int64
+	if err := db.QueryRow(`SELECT count(*) FROM outbox WHERE sent_at IS NULL`).Scan(&unsent); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%d entries sent, %d not acknowledged, %d left to send on the next run\n", sent, failed, unsent)
+}
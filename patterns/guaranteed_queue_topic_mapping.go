@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Topic-to-queue mapping: topic subscriptions added to a durable queue are stored on the broker with the queue,
+// so messages published on matching topics are attracted to (and spooled on) the queue even while no consumer is bound.
+//
+// Step 1, map the topics to the queue and exit:
+//
+//	go run guaranteed_queue_topic_mapping.go -map-only
+//
+// Step 2, publish on the mapped topics, e.g. with guaranteed_publisher.go (publishes on solace/samples/persistent/publisher)
+//
+// Step 3, bind to the queue and receive everything that accumulated while the consumer was down:
+//
+//	go run guaranteed_queue_topic_mapping.go
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	var messageBody string
+
+	if payload, ok := message.GetPayloadAsString(); ok {
+		messageBody = payload
+	} else if payload, ok := message.GetPayloadAsBytes(); ok {
+		messageBody = string(payload)
+	}
+
+	fmt.Printf("Received Message Body %s (attracted from topic %s)\n", messageBody, message.GetDestinationName())
+}
+
+// BuildReceiverWithQueueSubscriptions - builds a persistent receiver which adds the given topic subscriptions
+// to the queue when it is started. The queue is created on start when it does not exist on the broker yet.
+// Note: the client-username needs permission to modify the topic subscriptions of the queue.
+func BuildReceiverWithQueueSubscriptions(messagingService solace.MessagingService, durableExclusiveQueue *resource.Queue, topics []string) (solace.PersistentMessageReceiver, error) {
+	queueSubscriptions := make([]resource.Subscription, len(topics))
+	for i, topic := range topics {
+		queueSubscriptions[i] = resource.TopicSubscriptionOf(topic)
+	}
+
+	return messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMissingResourcesCreationStrategy(config.PersistentReceiverCreateOnStartMissingResources).
+		WithSubscriptions(queueSubscriptions...).
+		Build(durableExclusiveQueue)
+}
+
+// AddQueueSubscription - example of how to map an additional topic to the queue of an already running receiver
+func AddQueueSubscription(persistentReceiver solace.PersistentMessageReceiver, topic string) error {
+	return persistentReceiver.AddSubscription(resource.TopicSubscriptionOf(topic))
+}
+
+func main() {
+	queueName := flag.String("queue", "topic-mapping-queue", "name of the durable exclusive queue to map the topics to")
+	topicList := flag.String("topics", TopicPrefix+"/persistent/>,"+TopicPrefix+"/orders/*/created", "comma separated list of topic subscriptions to add to the queue")
+	extraTopic := flag.String("add-topic", TopicPrefix+"/orders/*/cancelled", "topic subscription added to the queue after the receiver started (empty to skip)")
+	mapOnly := flag.Bool("map-only", false, "add the topic subscriptions to the queue and exit without consuming")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	topics := strings.Split(*topicList, ",")
+	persistentReceiver, err := BuildReceiverWithQueueSubscriptions(messagingService, resource.QueueDurableExclusive(*queueName), topics)
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Persistent Message Receiver, the topic subscriptions are added to the queue here
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	for _, topic := range topics {
+		fmt.Printf("Mapped topic %s to queue %s\n", topic, *queueName)
+	}
+
+	if *extraTopic != "" {
+		if err := AddQueueSubscription(persistentReceiver, *extraTopic); err != nil {
+			fmt.Printf("Could not map topic %s to queue %s: %s\n", *extraTopic, *queueName, err)
+		} else {
+			fmt.Printf("Mapped topic %s to queue %s on the running receiver\n", *extraTopic, *queueName)
+		}
+	}
+
+	if !*mapOnly {
+		if regErr := persistentReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+			panic(regErr)
+		}
+
+		fmt.Printf("\n Bound to queue: %s\n", *queueName)
+		fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+		// Run forever until an interrupt signal is received
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, lifecycle.ShutdownSignals...)
+
+		// Block until a signal is received.
+		<-c
+	} else {
+		fmt.Println("\nThe subscriptions stay on the durable queue after the receiver terminates, publish some messages and run again without -map-only.")
+	}
+
+	// Terminate the Persistent Receiver, this does not remove the topic subscriptions from the durable queue
+	persistentReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
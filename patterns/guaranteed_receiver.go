@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"os/signal"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/leakcheck"
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/health"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/metricsink"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace/config"
 	"solace.dev/go/messaging/pkg/solace/message"
@@ -23,36 +28,59 @@ func MessageHandler(message message.InboundMessage) {
 	}
 
 	fmt.Printf("Received Message Body %s \n", messageBody)
-	// fmt.Printf("Message Dump %s \n", message)
-}
-
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
+	// fmt.Printf("Message Dump\n%s", msgdump.Text(message))
 }
 
 // Define Topic Prefix
 const TopicPrefix = "solace/samples"
 
 func main() {
-	// logging.SetLogLevel(logging.LogLevelInfo)
-
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
 	}
 
+	// Metrics, served to Prometheus on /metrics when SOLACE_METRICS_ADDR is set, e.g. SOLACE_METRICS_ADDR=:2112, or sent
+	// to a StatsD agent with SOLACE_METRICS_SINK=statsd or dogstatsd (see pkg/metricsink)
+	exporter, err := metricsink.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+	defer exporter.Close()
+	exporter.AddService("guaranteed-receiver", messagingService)
+
+	// Kubernetes probes, /healthz and /readyz served when SOLACE_HEALTH_ADDR is set, e.g. SOLACE_HEALTH_ADDR=:8080
+	checker := health.New()
+	checker.AddService("guaranteed-receiver", messagingService)
+	if address := sampleconfig.Setting("SOLACE_HEALTH_ADDR", ""); address != "" {
+		if _, err := checker.ListenAndServe(address); err != nil {
+			panic(err)
+		}
+	}
+
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: not ready, paused, the handlers in flight finished, then
+	// terminated and disconnected (see pkg/lifecycle)
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		panic(err)
+	}
+	drainer := lifecycle.New(lifecycle.WithChecker(checker), lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddService(messagingService)
+
+	// Checks that the publishers, receivers and the service leave no goroutine or file descriptor behind once
+	// terminated and disconnected, when SOLACE_LEAK_CHECK is set (see internal/leakcheck)
+	guard := leakcheck.FromEnv()
+
 	// Connect to the messaging serice
 	if err := messagingService.Connect(); err != nil {
 		panic(err)
@@ -92,28 +120,24 @@ func main() {
 	}
 
 	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+	checker.AddReceiver(queueName, persistentReceiver)
+	drainer.AddReceiver(queueName, persistentReceiver)
 
 	// Register Message callback handler to the Message Receiver
-	if regErr := persistentReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+	if regErr := persistentReceiver.ReceiveAsync(drainer.Handler(checker.Handler(exporter.Handler(queueName, MessageHandler)))); regErr != nil {
 		panic(regErr)
 	}
 	fmt.Printf("\n Bound to queue: %s\n", queueName)
-	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===\n")
-
-	// Run forever until an interrupt signal is received
-	// Handle interrupts
-
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	fmt.Println("\n===Interrupt (CTR+C) or SIGTERM to handle graceful termination of the receiver===\n")
 
-	// Block until a signal is received.
-	<-c
+	// Run forever until an interrupt or a SIGTERM is received
+	lifecycle.WaitForSignal()
 
-	// Terminate the Persistent Receiver
-	persistentReceiver.Terminate(1 * time.Second)
-	fmt.Println("\nPersistent Receiver Terminated? ", persistentReceiver.IsTerminated())
-	// Disconnect the Message Service
-	messagingService.Disconnect()
+	// Drain, then terminate the Persistent Receiver and disconnect the Message Service
+	report := drainer.Shutdown()
+	fmt.Println("\nDrain: ", report)
+	fmt.Println("Persistent Receiver Terminated? ", persistentReceiver.IsTerminated())
 	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+	guard.Verify()
 
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Comparison of the auto-acknowledgement and client-acknowledgement receiver configurations.
+//
+// The same handler is used in both modes: it hands each message over to a worker that takes a while to process it,
+// which is how most real applications offload work from the receive callback.
+//   - With auto-acknowledgement the API acknowledges the message as soon as the receive callback returns,
+//     so the broker forgets about it before the worker has processed it.
+//   - With client-acknowledgement the worker acknowledges the message only once processing completed.
+//
+// Run with -crash-after=N to kill the process after N messages were processed, then run again (without the flag)
+// and look for the REDELIVERED marker: only client-acknowledgement mode gets the in-flight messages back.
+//
+//	go run guaranteed_receiver_ack_modes.go -mode=auto -crash-after=5
+//	go run guaranteed_receiver_ack_modes.go -mode=auto
+//	go run guaranteed_receiver_ack_modes.go -mode=client -crash-after=5
+//	go run guaranteed_receiver_ack_modes.go -mode=client
+
+// BuildReceiverForAckMode - builds a persistent receiver bound to the queue using the given acknowledgement mode ("auto" or "client")
+func BuildReceiverForAckMode(messagingService solace.MessagingService, queue *resource.Queue, mode string) (solace.PersistentMessageReceiver, error) {
+	builder := messagingService.CreatePersistentMessageReceiverBuilder()
+	switch mode {
+	case "auto":
+		builder = builder.WithMessageAutoAcknowledgement()
+	case "client":
+		builder = builder.WithMessageClientAcknowledgement()
+	default:
+		return nil, fmt.Errorf("unknown acknowledgement mode '%s', expected 'auto' or 'client'", mode)
+	}
+	return builder.Build(queue)
+}
+
+// ProcessMessages - worker processing the messages handed over by the receive callback. In client-acknowledgement
+// mode the message is acknowledged after it is processed. When crashAfter is greater than zero, the process exits
+// without any cleanup once that many messages were processed.
+func ProcessMessages(persistentReceiver solace.PersistentMessageReceiver, work <-chan message.InboundMessage, mode string, processingTime time.Duration, crashAfter int) {
+	processed := 0
+	for message := range work {
+		payload, _ := message.GetPayloadAsString()
+		marker := ""
+		if message.IsRedelivered() {
+			marker = " REDELIVERED"
+		}
+		fmt.Printf("Processing message: %s%s\n", payload, marker)
+
+		// Simulate slow processing of the message
+		time.Sleep(processingTime)
+		processed++
+
+		if mode == "client" {
+			if err := persistentReceiver.Ack(message); err != nil {
+				fmt.Println("Message Acknowledgement Error: ", err)
+			}
+		}
+
+		if crashAfter > 0 && processed >= crashAfter {
+			fmt.Printf("\n!!! Crash injected after %d processed messages, %d messages were still waiting for processing !!!\n", processed, len(work))
+			os.Exit(1)
+		}
+	}
+}
+
+func main() {
+	mode := flag.String("mode", "client", "acknowledgement mode of the receiver: auto or client")
+	crashAfter := flag.Int("crash-after", 0, "exit abruptly after processing this many messages (0 disables crash injection)")
+	processingTime := flag.Duration("processing-time", 500*time.Millisecond, "time spent processing each message")
+	queueName := flag.String("queue", "durable-queue", "name of the durable exclusive queue to bind to")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp)
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName, Subscriptions: []string{"solace/samples/persistent/>"}}); err != nil {
+		panic(err)
+	}
+
+	persistentReceiver, err := BuildReceiverForAckMode(messagingService, resource.QueueDurableExclusive(*queueName), *mode)
+	if err != nil {
+		panic(err)
+	}
+
+	// Handling a panic from a non existing queue
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("Make sure queue name '%s' exists on the broker.\nThe following error occurred when attempting to connect to create a Persistent Message Receiver:\n%s", *queueName, err)
+		}
+	}()
+
+	// Start Persistent Message Receiver
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+
+	// The receive callback only hands the message over to the worker and returns straight away
+	work := make(chan message.InboundMessage, 100)
+	go ProcessMessages(persistentReceiver, work, *mode, *processingTime, *crashAfter)
+
+	if regErr := persistentReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		work <- message
+	}); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\n Bound to queue: %s using %s acknowledgement\n", *queueName, *mode)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Persistent Receiver
+	persistentReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/brokertest"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// Integration tests of the acknowledgement modes, against a broker started in Docker (see internal/brokertest):
+//
+//	go test -tags integration -v guaranteed_receiver_ack_modes.go guaranteed_receiver_ack_modes_test.go
+
+// settleTimeout is how long the messages of a test have to be acknowledged
+const settleTimeout = 30 * time.Second
+
+// published is the number of messages published by each test
+const published = 5
+
+func publishMessages(t *testing.T, broker *brokertest.Broker, topic string) {
+	t.Helper()
+	messages := make([]brokertest.Message, published)
+	for i := range messages {
+		messages[i].Payload = fmt.Sprintf("message %d", i)
+	}
+	brokertest.Publish(t, broker.Connect(t), topic, messages...)
+}
+
+// Processed messages are removed from the queue in both modes
+func TestAckModesRemoveProcessedMessages(t *testing.T) {
+	broker := brokertest.Start(t)
+	for _, mode := range []string{"auto", "client"} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			messagingService := broker.Connect(t)
+			topic := brokertest.Topic(t)
+			queue := broker.Queue(t, brokertest.QueueOptions{Topics: []string{topic}})
+
+			// closed once the receiver is terminated, the cleanups run last registered first
+			work := make(chan message.InboundMessage, published)
+			t.Cleanup(func() { close(work) })
+
+			persistentReceiver, err := BuildReceiverForAckMode(messagingService, queue, mode)
+			if err != nil {
+				t.Fatalf("could not build the receiver: %s", err)
+			}
+			if err := persistentReceiver.Start(); err != nil {
+				t.Fatalf("could not start the receiver: %s", err)
+			}
+			t.Cleanup(func() { persistentReceiver.Terminate(time.Second) })
+			go ProcessMessages(persistentReceiver, work, mode, 10*time.Millisecond, 0)
+			if err := persistentReceiver.ReceiveAsync(func(message message.InboundMessage) { work <- message }); err != nil {
+				t.Fatalf("could not receive: %s", err)
+			}
+
+			publishMessages(t, broker, topic)
+			broker.WaitFor(t, queue.GetName(), settleTimeout, func(stats brokertest.QueueStats) bool { return stats.Spooled == 0 })
+		})
+	}
+}
+
+// The messages handed over to the worker but not processed yet when the receiver terminates are lost with
+// auto-acknowledgement, acknowledged once the callback returned, and stay on the queue with client-acknowledgement
+func TestAckModesInFlightMessages(t *testing.T) {
+	broker := brokertest.Start(t)
+	for _, test := range []struct {
+		mode    string
+		spooled int64
+	}{
+		{"auto", 0},
+		{"client", published},
+	} {
+		test := test
+		t.Run(test.mode, func(t *testing.T) {
+			messagingService := broker.Connect(t)
+			topic := brokertest.Topic(t)
+			queue := broker.Queue(t, brokertest.QueueOptions{Topics: []string{topic}})
+
+			persistentReceiver, err := BuildReceiverForAckMode(messagingService, queue, test.mode)
+			if err != nil {
+				t.Fatalf("could not build the receiver: %s", err)
+			}
+			if err := persistentReceiver.Start(); err != nil {
+				t.Fatalf("could not start the receiver: %s", err)
+			}
+			// the worker never processes the messages, as if the process crashed
+			work := make(chan message.InboundMessage, published)
+			if err := persistentReceiver.ReceiveAsync(func(message message.InboundMessage) { work <- message }); err != nil {
+				t.Fatalf("could not receive: %s", err)
+			}
+
+			publishMessages(t, broker, topic)
+			deadline := time.Now().Add(settleTimeout)
+			for len(work) < published {
+				if time.Now().After(deadline) {
+					t.Fatalf("%d message(s) received within %s, expected %d", len(work), settleTimeout, published)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			persistentReceiver.Terminate(time.Second)
+
+			broker.WaitFor(t, queue.GetName(), settleTimeout, func(stats brokertest.QueueStats) bool { return stats.Spooled == test.spooled })
+		})
+	}
+}
+
+func TestBuildReceiverForUnknownAckMode(t *testing.T) {
+	broker := brokertest.Start(t)
+	messagingService := broker.Connect(t)
+	queue := broker.Queue(t, brokertest.QueueOptions{})
+	if _, err := BuildReceiverForAckMode(messagingService, queue, "manual"); err == nil {
+		t.Fatal("expected an error for the acknowledgement mode manual")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/certwatch"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Client certificate hot reload: the client certificate and key are watched (see pkg/certwatch) and when they are
+// rotated, e.g. by cert-manager or a Vault agent, the connection is re-established with the new certificate.
+//
+// The switch is make-before-break so no guaranteed message is left unacknowledged by the switch itself:
+//  1. a new messaging service connects with the new certificate and binds a new receiver to the queue (on an
+//     exclusive queue it waits as standby until the current receiver unbinds); if this fails the current connection
+//     is kept, the certificate it connected with stays valid until it expires
+//  2. the current receiver is paused and the messages already handed to the application are processed and
+//     acknowledged
+//  3. the current receiver is terminated and its messaging service disconnected, messages the broker had sent but
+//     that were not delivered to the application yet are redelivered to the new receiver
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 SOLACE_CLIENT_CERT=/etc/solace/tls.crt SOLACE_CLIENT_KEY=/etc/solace/tls.key \
+//		go run guaranteed_receiver_cert_hot_reload.go
+
+// Connection - a messaging service with a persistent receiver bound to the queue, counting the messages being
+// processed by the application
+type Connection struct {
+	messagingService solace.MessagingService
+	receiver         solace.PersistentMessageReceiver
+	inFlight         int64
+}
+
+// Connect - connects with the client certificate and starts a client-ack receiver on the queue
+func Connect(brokerConfig config.ServicePropertyMap, queueName string) (*Connection, error) {
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	if err != nil {
+		return nil, err
+	}
+	if err := messagingService.Connect(); err != nil {
+		return nil, err
+	}
+
+	connection := &Connection{messagingService: messagingService}
+	receiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		Build(resource.QueueDurableExclusive(queueName))
+	if err == nil {
+		err = receiver.Start()
+	}
+	if err == nil {
+		err = receiver.ReceiveAsync(func(message message.InboundMessage) {
+			atomic.AddInt64(&connection.inFlight, 1)
+			defer atomic.AddInt64(&connection.inFlight, -1)
+			ProcessMessage(message)
+			if err := receiver.Ack(message); err != nil {
+				fmt.Println("Ack failed: ", err)
+			}
+		})
+	}
+	if err != nil {
+		messagingService.Disconnect()
+		return nil, err
+	}
+	connection.receiver = receiver
+	return connection, nil
+}
+
+// Close - stops the delivery of messages, waits up to the grace period for the messages being processed to be
+// acknowledged, then terminates the receiver and disconnects
+func (c *Connection) Close(gracePeriod time.Duration) {
+	if err := c.receiver.Pause(); err != nil {
+		fmt.Println("Could not pause the receiver: ", err)
+	}
+	deadline := time.Now().Add(gracePeriod)
+	for atomic.LoadInt64(&c.inFlight) > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if remaining := atomic.LoadInt64(&c.inFlight); remaining > 0 {
+		fmt.Printf("%d message(s) still being processed after %s, they will be redelivered\n", remaining, gracePeriod)
+	}
+	c.receiver.Terminate(gracePeriod)
+	c.messagingService.Disconnect()
+}
+
+// ProcessMessage - simulates some processing time
+func ProcessMessage(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("Processed Message Body %s (redelivered: %t)\n", payload, message.IsRedelivered())
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	certFile := sampleconfig.Setting("SOLACE_CLIENT_CERT", "client.pem")
+	keyFile := sampleconfig.Setting("SOLACE_CLIENT_KEY", "client.key")
+	queueName := sampleconfig.Setting("SOLACE_QUEUE", "durable-queue")
+
+	// Configuration parameters, the certificate and key are read again by the API on every connection
+	brokerConfig := config.ServicePropertyMap{
+		config.TransportLayerPropertyHost:                                   sampleconfig.Setting("SOLACE_HOST", "tcps://localhost:55443"),
+		config.ServicePropertyVPNName:                                       sampleconfig.Setting("SOLACE_VPN", "default"),
+		config.AuthenticationPropertyScheme:                                 config.AuthenticationSchemeClientCertificate,
+		config.AuthenticationPropertySchemeSSLClientCertFile:                certFile,
+		config.AuthenticationPropertySchemeSSLClientPrivateKeyFile:          keyFile,
+		config.AuthenticationPropertySchemeClientCertPrivateKeyFilePassword: sampleconfig.Setting("SOLACE_CLIENT_KEY_PASSWORD", ""),
+		config.TransportLayerSecurityPropertyTrustStorePath:                 sampleconfig.Setting("SOLACE_TRUST_STORE", "./trust_store"),
+	}
+
+	watcher, err := certwatch.New(certFile, keyFile)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Client certificate '%s' valid until %s\n", watcher.Certificate().Subject, watcher.Certificate().NotAfter.Format(time.RFC3339))
+
+	// With -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), wait for the broker to be ready before connecting
+	if err := sampleconfig.WaitForBroker(context.Background(), brokerConfig); err != nil {
+		panic(err)
+	}
+
+	connection, err := Connect(brokerConfig, queueName)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", connection.messagingService.IsConnected())
+	fmt.Printf("\n Bound to queue: %s\n", queueName)
+
+	rotated := make(chan *x509.Certificate, 1)
+	if err := watcher.Start(func(certificate *x509.Certificate) {
+		select {
+		case rotated <- certificate:
+		default:
+			// a reconnection is already pending, it picks up the latest files
+		}
+	}); err != nil {
+		panic(err)
+	}
+	defer watcher.Close()
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	for {
+		select {
+		case certificate := <-rotated:
+			fmt.Printf("Client certificate rotated, new certificate '%s' valid until %s\n", certificate.Subject, certificate.NotAfter.Format(time.RFC3339))
+			replacement, err := Connect(brokerConfig, queueName)
+			if err != nil {
+				fmt.Println("Could not connect with the new certificate, keeping the current connection: ", err)
+				continue
+			}
+			connection.Close(5 * time.Second)
+			connection = replacement
+			fmt.Println("Reconnected with the new certificate? ", connection.messagingService.IsConnected())
+		case <-c:
+			connection.Close(5 * time.Second)
+			fmt.Println("\nPersistent Receiver Terminated? ", connection.receiver.IsTerminated())
+			fmt.Println("Messaging Service Disconnected? ", !connection.messagingService.IsConnected())
+			return
+		}
+	}
+}
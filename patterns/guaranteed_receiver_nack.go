@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace"
 	"solace.dev/go/messaging/pkg/solace/config"
@@ -13,13 +20,6 @@ import (
 	"solace.dev/go/messaging/pkg/solace/resource"
 )
 
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 // BuildNackPersistentMessageReceiverWithBuilderMethod - example of how to build a Gauranteed message receiver
 // with NACK support and bind to the given queue and set the required message settlement outcome(s) on the
 // flow using the WithRequiredMessageOutcomeSupport() builder method
@@ -47,10 +47,11 @@ func BuildNackPersistentMessageReceiverWithConfigurationProvider(messagingServic
 }
 
 // HandleMessageSettlementWithAcceptedOutcome - example of how to set up the persistent receive to
-// settle messages with the ACCEPTED message settlement outcome
-func HandleMessageSettlementWithAcceptedOutcome(persistentReceiver solace.PersistentMessageReceiver) {
+// settle messages with the ACCEPTED message settlement outcome, recorded to the audit log unless it is nil
+func HandleMessageSettlementWithAcceptedOutcome(persistentReceiver solace.PersistentMessageReceiver, audit *settleaudit.Log) {
 	// Message Handler
 	messageHandler := func(message message.InboundMessage) {
+		started := time.Now()
 		var messageBody string
 
 		if payload, ok := message.GetPayloadAsString(); ok {
@@ -60,10 +61,11 @@ func HandleMessageSettlementWithAcceptedOutcome(persistentReceiver solace.Persis
 		}
 
 		fmt.Printf("Received Message Body %s \n", messageBody)
-		// fmt.Printf("Message Dump %s \n", message)
+		// fmt.Printf("Message Dump\n%s", msgdump.Text(message))
 
-		// Settle the message here with one of the three supported settlement outcomes: ACCEPTED, FAILED and REJECTED
-		messageSettlementError := persistentReceiver.Settle(message, config.PersistentReceiverAcceptedOutcome) // Accept(acknowlegde) the message
+		// Settle the message here with one of the three supported settlement outcomes: ACCEPTED, FAILED and REJECTED,
+		// the settlement is recorded to the audit log with the reason of the outcome
+		messageSettlementError := audit.Settle("durable-queue", persistentReceiver, message, config.PersistentReceiverAcceptedOutcome, started, nil) // Accept(acknowlegde) the message
 		fmt.Println("Message Settlement Error: ", messageSettlementError)
 	}
 
@@ -74,10 +76,11 @@ func HandleMessageSettlementWithAcceptedOutcome(persistentReceiver solace.Persis
 }
 
 // HandleMessageSettlementWithFailedOutcome - example of how to set up the persistent receive to
-// settle messages with the FAILED message settlement outcome
-func HandleMessageSettlementWithFailedOutcome(persistentReceiver solace.PersistentMessageReceiver) {
+// settle messages with the FAILED message settlement outcome, recorded to the audit log unless it is nil
+func HandleMessageSettlementWithFailedOutcome(persistentReceiver solace.PersistentMessageReceiver, audit *settleaudit.Log) {
 	// Message Handler
 	messageHandler := func(message message.InboundMessage) {
+		started := time.Now()
 		var messageBody string
 
 		if payload, ok := message.GetPayloadAsString(); ok {
@@ -87,10 +90,11 @@ func HandleMessageSettlementWithFailedOutcome(persistentReceiver solace.Persiste
 		}
 
 		fmt.Printf("Received Message Body %s \n", messageBody)
-		// fmt.Printf("Message Dump %s \n", message)
+		// fmt.Printf("Message Dump\n%s", msgdump.Text(message))
 
-		// Settle the message here with one of the three supported settlement outcomes: ACCEPTED, FAILED and REJECTED
-		messageSettlementError := persistentReceiver.Settle(message, config.PersistentReceiverFailedOutcome) // fail the message
+		// Settle the message here with one of the three supported settlement outcomes: ACCEPTED, FAILED and REJECTED,
+		// the settlement is recorded to the audit log with the reason of the outcome
+		messageSettlementError := audit.Settle("durable-queue", persistentReceiver, message, config.PersistentReceiverFailedOutcome, started, errors.New("the sample fails every message")) // fail the message
 		fmt.Println("Message Settlement Error: ", messageSettlementError)
 	}
 
@@ -101,10 +105,11 @@ func HandleMessageSettlementWithFailedOutcome(persistentReceiver solace.Persiste
 }
 
 // HandleMessageSettlementWithRejectedOutcome - example of how to set up the persistent receive to
-// settle messages with the REJECTED message settlement outcome
-func HandleMessageSettlementWithRejectedOutcome(persistentReceiver solace.PersistentMessageReceiver) {
+// settle messages with the REJECTED message settlement outcome, recorded to the audit log unless it is nil
+func HandleMessageSettlementWithRejectedOutcome(persistentReceiver solace.PersistentMessageReceiver, audit *settleaudit.Log) {
 	// Message Handler
 	messageHandler := func(message message.InboundMessage) {
+		started := time.Now()
 		var messageBody string
 
 		if payload, ok := message.GetPayloadAsString(); ok {
@@ -114,10 +119,11 @@ func HandleMessageSettlementWithRejectedOutcome(persistentReceiver solace.Persis
 		}
 
 		fmt.Printf("Received Message Body %s \n", messageBody)
-		// fmt.Printf("Message Dump %s \n", message)
+		// fmt.Printf("Message Dump\n%s", msgdump.Text(message))
 
-		// Settle the message here with one of the three supported settlement outcomes: ACCEPTED, FAILED and REJECTED
-		messageSettlementError := persistentReceiver.Settle(message, config.PersistentReceiverRejectedOutcome) // reject the message
+		// Settle the message here with one of the three supported settlement outcomes: ACCEPTED, FAILED and REJECTED,
+		// the settlement is recorded to the audit log with the reason of the outcome
+		messageSettlementError := audit.Settle("durable-queue", persistentReceiver, message, config.PersistentReceiverRejectedOutcome, started, errors.New("the sample rejects every message")) // reject the message
 		fmt.Println("Message Settlement Error: ", messageSettlementError)
 	}
 
@@ -128,17 +134,16 @@ func HandleMessageSettlementWithRejectedOutcome(persistentReceiver solace.Persis
 }
 
 func main() {
-	// logging.SetLogLevel(logging.LogLevelInfo)
-
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
@@ -156,6 +161,13 @@ func main() {
 	}
 
 	queueName := "durable-queue"
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp), the
+	// failed messages are redelivered 3 times before they are discarded or moved to the dead message queue
+	if err := semp.Provision(context.Background(), semp.Queue{Name: queueName,
+		Subscriptions: []string{"solace/samples/persistent/>"}, MaxRedeliveryCount: 3}); err != nil {
+		panic(err)
+	}
 	durableExclusiveQueue := resource.QueueDurableExclusive(queueName)
 
 	// Build a Gauranteed message receiver with NACK support and bind to the given queue
@@ -179,11 +191,21 @@ func main() {
 
 	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
 
+	// Settlement audit log, every settlement is appended as a JSON line to SOLACE_AUDIT_FILE when it is set, e.g.
+	// SOLACE_AUDIT_FILE=settlements.ndjson, in a file rotated every 100 MB
+	var audit *settleaudit.Log
+	if path := sampleconfig.Setting("SOLACE_AUDIT_FILE", ""); path != "" {
+		if audit, err = settleaudit.Open(settleaudit.Options{Path: path}); err != nil {
+			panic(err)
+		}
+		defer audit.Close()
+	}
+
 	// Example snippet on how to settle a message with the ACCEPTED outcome
 	// Code example for other message settlement outcomes are implemented in these functions:
-	// 	-	FAILED Outcome 		=> HandleMessageSettlementWithFailedOutcome(persistentReceiver)
-	// 	-	REJECTED Outcome 	=> HandleMessageSettlementWithRejectedOutcome(persistentReceiver)
-	HandleMessageSettlementWithAcceptedOutcome(persistentReceiver)
+	// 	-	FAILED Outcome 		=> HandleMessageSettlementWithFailedOutcome(persistentReceiver, audit)
+	// 	-	REJECTED Outcome 	=> HandleMessageSettlementWithRejectedOutcome(persistentReceiver, audit)
+	HandleMessageSettlementWithAcceptedOutcome(persistentReceiver, audit)
 
 	fmt.Printf("\n Bound to queue: %s\n", queueName)
 	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===\n")
@@ -192,7 +214,7 @@ func main() {
 	// Handle interrupts
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 
 	// Block until a signal is received.
 	<-c
@@ -0,0 +1,100 @@
+//go:build integration
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/brokertest"
+	"SolaceSamples.com/PubSub+Go/pkg/settleaudit"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Integration tests of the NACK receiver, against a broker started in Docker (see internal/brokertest):
+//
+//	go test -tags integration -v guaranteed_receiver_nack.go guaranteed_receiver_nack_test.go
+
+// maxRedeliveries is the max redelivery count of the queues of the tests, a failed message is delivered once more
+// than that before it is moved to the dead message queue
+const maxRedeliveries = 2
+
+// settleTimeout is how long the messages of a test have to be settled
+const settleTimeout = 30 * time.Second
+
+func TestNackReceiverSettlesMessages(t *testing.T) {
+	broker := brokertest.Start(t)
+	builders := []struct {
+		name  string
+		build func(solace.MessagingService, *resource.Queue) (solace.PersistentMessageReceiver, error)
+	}{
+		{"builder-method", BuildNackPersistentMessageReceiverWithBuilderMethod},
+		{"configuration-provider", BuildNackPersistentMessageReceiverWithConfigurationProvider},
+	}
+	handlers := []struct {
+		name    string
+		handle  func(solace.PersistentMessageReceiver, *settleaudit.Log)
+		outcome config.MessageSettlementOutcome
+		// deliveries is the number of times each message is delivered
+		deliveries int
+		// dead is true when the messages end on the dead message queue
+		dead bool
+	}{
+		{"accepted", HandleMessageSettlementWithAcceptedOutcome, config.PersistentReceiverAcceptedOutcome, 1, false},
+		{"failed", HandleMessageSettlementWithFailedOutcome, config.PersistentReceiverFailedOutcome, maxRedeliveries + 1, true},
+		{"rejected", HandleMessageSettlementWithRejectedOutcome, config.PersistentReceiverRejectedOutcome, 1, true},
+	}
+	const published = 3
+
+	for _, builder := range builders {
+		for _, handler := range handlers {
+			builder, handler := builder, handler
+			t.Run(builder.name+"/"+handler.name, func(t *testing.T) {
+				messagingService := broker.Connect(t)
+				topic := brokertest.Topic(t)
+				queue := broker.Queue(t, brokertest.QueueOptions{Topics: []string{topic}, MaxRedeliveryCount: maxRedeliveries})
+
+				persistentReceiver, err := builder.build(messagingService, queue)
+				if err != nil {
+					t.Fatalf("could not build the receiver: %s", err)
+				}
+				if err := persistentReceiver.Start(); err != nil {
+					t.Fatalf("could not start the receiver: %s", err)
+				}
+				t.Cleanup(func() { persistentReceiver.Terminate(time.Second) })
+				var recorder brokertest.Recorder
+				handler.handle(persistentReceiver, recorder.Log())
+
+				messages := make([]brokertest.Message, published)
+				for i := range messages {
+					messages[i].Payload = fmt.Sprintf("message %d", i)
+				}
+				brokertest.Publish(t, messagingService, topic, messages...)
+
+				records := recorder.WaitFor(t, published*handler.deliveries, settleTimeout)
+				redelivered := 0
+				for _, record := range records {
+					if record.Outcome != string(handler.outcome) || record.Error != "" {
+						t.Errorf("settled with %s (error %q), expected %s", record.Outcome, record.Error, handler.outcome)
+					}
+					if record.Redelivered {
+						redelivered++
+					}
+				}
+				if expected := published * (handler.deliveries - 1); redelivered != expected {
+					t.Errorf("%d redelivered message(s), expected %d", redelivered, expected)
+				}
+
+				broker.WaitFor(t, queue.GetName(), settleTimeout, func(stats brokertest.QueueStats) bool { return stats.Spooled == 0 })
+				dead := int64(0)
+				if handler.dead {
+					dead = published
+				}
+				broker.WaitFor(t, brokertest.DMQ(queue.GetName()), settleTimeout, func(stats brokertest.QueueStats) bool { return stats.Spooled == dead })
+			})
+		}
+	}
+}
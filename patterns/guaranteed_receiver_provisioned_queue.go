@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/health"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+	"solace.dev/go/messaging/pkg/solace/subcode"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Programmatic endpoint provisioning: instead of requiring the queue to be created upfront from the broker management
+// console, the sample provisions the durable queue with the endpoint provisioner before binding to it.
+// The client-username needs the permission to create endpoints (client-profile "allow-guaranteed-endpoint-create").
+//
+//	go run guaranteed_receiver_provisioned_queue.go -queue provisioned-queue -exclusive=false -quota 50 -max-redelivery 3
+
+// QueueProperties - the properties of the provisioned queue
+type QueueProperties struct {
+	Exclusive     bool
+	QuotaMB       uint
+	MaxRedelivery uint
+	RespectTTL    bool
+}
+
+// ProvisionQueue - provisions the durable queue, a queue that already exists with the same properties is reused.
+// An existing queue with different properties is reported as an error, the provisioner never modifies a queue.
+func ProvisionQueue(messagingService solace.MessagingService, queueName string, properties QueueProperties) error {
+	outcome := messagingService.EndpointProvisioner().
+		WithDurability(true).
+		WithExclusiveAccess(properties.Exclusive).
+		WithQuotaMB(properties.QuotaMB).
+		WithMaxMessageRedelivery(properties.MaxRedelivery).
+		WithTTLPolicy(properties.RespectTTL).
+		WithPermission(config.EndpointPermissionConsume).
+		Provision(queueName, true)
+
+	if err := outcome.GetError(); err != nil {
+		var nativeErr *solace.NativeError
+		if errors.As(err, &nativeErr) && nativeErr.SubCode() == subcode.EndpointPropertyMismatch {
+			return fmt.Errorf("queue '%s' already exists with different properties, delete it or provision it with the same properties: %w", queueName, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s (redelivered: %t)\n", payload, message.IsRedelivered())
+}
+
+func main() {
+	queueName := flag.String("queue", "provisioned-queue", "name of the durable queue to provision and bind to")
+	exclusive := flag.Bool("exclusive", true, "provision an exclusive queue, non-exclusive queues load balance between consumers")
+	quota := flag.Uint("quota", 100, "message spool quota of the queue in MB")
+	maxRedelivery := flag.Uint("max-redelivery", 10, "number of redeliveries before a message is moved to the DMQ (0 for no limit)")
+	respectTTL := flag.Bool("respect-ttl", true, "discard messages on the queue once their time to live expired")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithProvisionTimeoutMs(10 * time.Second). // time to wait for the broker to confirm the provisioning
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Kubernetes probes, /healthz and /readyz served when SOLACE_HEALTH_ADDR is set, e.g. SOLACE_HEALTH_ADDR=:8080
+	checker := health.New()
+	checker.AddService("provisioned-queue-receiver", messagingService)
+	if address := sampleconfig.Setting("SOLACE_HEALTH_ADDR", ""); address != "" {
+		if _, err := checker.ListenAndServe(address); err != nil {
+			panic(err)
+		}
+	}
+
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: not ready, paused, the handlers in flight finished, then
+	// terminated and disconnected (see pkg/lifecycle)
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		panic(err)
+	}
+	drainer := lifecycle.New(lifecycle.WithChecker(checker), lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddService(messagingService)
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Provision the queue before binding to it
+	properties := QueueProperties{
+		Exclusive:     *exclusive,
+		QuotaMB:       *quota,
+		MaxRedelivery: *maxRedelivery,
+		RespectTTL:    *respectTTL,
+	}
+	if err := ProvisionQueue(messagingService, *queueName, properties); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Provisioned queue %s %+v\n", *queueName, properties)
+
+	// Bind to the provisioned queue and attract the sample topic to it
+	topicString := TopicPrefix + "/persistent/>"
+	queue := resource.QueueDurableExclusive(*queueName)
+	if !*exclusive {
+		queue = resource.QueueDurableNonExclusive(*queueName)
+	}
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageAutoAcknowledgement().
+		WithSubscriptions(resource.TopicSubscriptionOf(topicString)).
+		Build(queue)
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Persistent Message Receiver
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+	checker.AddReceiver(*queueName, persistentReceiver)
+	drainer.AddReceiver(*queueName, persistentReceiver)
+
+	// Register Message callback handler to the Message Receiver
+	if regErr := persistentReceiver.ReceiveAsync(drainer.Handler(checker.Handler(MessageHandler))); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\n Bound to queue: %s, subscribed to: %s\n", *queueName, topicString)
+	fmt.Println("\n===Interrupt (CTR+C) or SIGTERM to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt or a SIGTERM is received
+	lifecycle.WaitForSignal()
+
+	// Drain, then terminate the Persistent Receiver and disconnect the Message Service, the provisioned queue (and the
+	// messages spooled on it) stays on the broker
+	report := drainer.Shutdown()
+	fmt.Println("\nDrain: ", report)
+	fmt.Println("Persistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/alerting"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/health"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/metricsink"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Persistent receiver across a broker disconnect/reconnect.
+//
+// While the messaging service reconnects, the persistent receiver stays running and its flow is re-bound to the queue
+// by the API once the connection is back. What the application needs to know about the messages it still holds:
+//   - Messages delivered before the connection was lost and not yet acknowledged are unacknowledged from the broker's
+//     point of view, the broker redelivers them on the new flow (IsRedelivered() returns true).
+//   - Acknowledgements/settlements of messages delivered before the reconnection are no longer valid: they either
+//     fail or are silently ignored, the redelivered copy is the one that has to be settled.
+//   - Processing must therefore be idempotent, a message processed before the gap may be processed a second time.
+//
+// To try it, start the sample, publish to the queue and bounce the broker (or disable/enable the client-username or
+// the message VPN, or disconnect the client from the broker management console) while messages are in flight.
+
+// InFlightMessages - tracks the messages delivered to the application and not settled yet, together with the
+// connection epoch they were delivered in. The epoch increments on each reconnection.
+type InFlightMessages struct {
+	mu       sync.Mutex
+	epoch    int
+	nextID   uint64
+	inFlight map[uint64]inFlightMessage
+}
+
+type inFlightMessage struct {
+	message message.InboundMessage
+	epoch   int
+}
+
+// NewInFlightMessages - creates an empty tracker
+func NewInFlightMessages() *InFlightMessages {
+	return &InFlightMessages{inFlight: map[uint64]inFlightMessage{}}
+}
+
+// Track - records a delivered message and returns the tracking ID used to settle it
+func (t *InFlightMessages) Track(message message.InboundMessage) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.inFlight[t.nextID] = inFlightMessage{message: message, epoch: t.epoch}
+	return t.nextID
+}
+
+// Settle - removes the message from the tracker and reports whether it was delivered on the current connection,
+// i.e. whether its acknowledgement is still meaningful
+func (t *InFlightMessages) Settle(id uint64) (message.InboundMessage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := t.inFlight[id]
+	delete(t.inFlight, id)
+	return entry.message, entry.epoch == t.epoch
+}
+
+// Reconnected - starts a new epoch and returns the number of messages that were in flight across the gap
+func (t *InFlightMessages) Reconnected() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.epoch++
+	return len(t.inFlight)
+}
+
+// ProcessAndAcknowledge - simulates slow processing so messages are in flight while the connection drops
+func ProcessAndAcknowledge(exporter metricsink.Sink, persistentReceiver solace.PersistentMessageReceiver, tracker *InFlightMessages, id uint64, processingTime time.Duration) {
+	time.Sleep(processingTime)
+
+	message, current := tracker.Settle(id)
+	payload, _ := message.GetPayloadAsString()
+	err := exporter.Ack("durable-queue", persistentReceiver, message)
+	if current {
+		fmt.Printf("Acknowledged message %s (redelivered: %t), error: %v\n", payload, message.IsRedelivered(), err)
+	} else {
+		// This acknowledgement refers to the flow that existed before the reconnection,
+		// the broker will (or already did) redeliver the message on the new flow.
+		fmt.Printf("Stale acknowledgement of message %s delivered before the reconnection, error: %v\n", payload, err)
+	}
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	// Keep retrying to reconnect every 3 seconds so the sample survives a broker restart
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(3 * time.Second)).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Metrics, served to Prometheus on /metrics when SOLACE_METRICS_ADDR is set, e.g. SOLACE_METRICS_ADDR=:2112, or sent
+	// to a StatsD agent with SOLACE_METRICS_SINK=statsd or dogstatsd (see pkg/metricsink)
+	exporter, err := metricsink.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+	defer exporter.Close()
+	exporter.AddService("guaranteed-receiver", messagingService)
+
+	// Kubernetes probes, /healthz and /readyz served when SOLACE_HEALTH_ADDR is set, e.g. SOLACE_HEALTH_ADDR=:8080.
+	// While reconnecting the sample is not ready but still alive, it is only restarted once the service is interrupted
+	checker := health.New()
+	checker.AddService("guaranteed-receiver", messagingService)
+	if address := sampleconfig.Setting("SOLACE_HEALTH_ADDR", ""); address != "" {
+		if _, err := checker.ListenAndServe(address); err != nil {
+			panic(err)
+		}
+	}
+
+	// Graceful drain on SIGTERM, within SOLACE_DRAIN_TIMEOUT: not ready, paused, the messages in flight acknowledged,
+	// then terminated and disconnected (see pkg/lifecycle)
+	gracePeriod, err := time.ParseDuration(sampleconfig.Setting("SOLACE_DRAIN_TIMEOUT", "25s"))
+	if err != nil {
+		panic(err)
+	}
+	drainer := lifecycle.New(lifecycle.WithChecker(checker), lifecycle.WithGracePeriod(gracePeriod))
+	drainer.AddService(messagingService)
+
+	tracker := NewInFlightMessages()
+	// The listeners are called on the goroutines of the API, the start of the outage is guarded by a mutex
+	var outageMu sync.Mutex
+	var outageStart time.Time
+
+	// Called on every reconnection attempt, the first one marks the start of the outage
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		outageMu.Lock()
+		if outageStart.IsZero() {
+			outageStart = event.GetTimestamp()
+		}
+		outageMu.Unlock()
+		fmt.Printf("Reconnecting to %s: %v\n", event.GetBrokerURI(), event.GetCause())
+	})
+
+	// Called once the connection is back, the persistent receiver flow is re-bound by the API
+	messagingService.AddReconnectionListener(func(event solace.ServiceEvent) {
+		inFlight := tracker.Reconnected()
+		outageMu.Lock()
+		outage := event.GetTimestamp().Sub(outageStart)
+		outageStart = time.Time{}
+		outageMu.Unlock()
+		fmt.Printf("Reconnected to %s after %s, %d message(s) in flight across the gap will be redelivered\n",
+			event.GetBrokerURI(), outage.Round(time.Millisecond), inFlight)
+	})
+
+	// Called when the reconnection attempts are exhausted, the service (and receiver) are unusable afterwards
+	messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("Service interrupted, giving up: %v\n", event.GetCause())
+	})
+
+	// Operational notification of the outages, printed and posted to SOLACE_ALERT_WEBHOOK when set (see pkg/alerting)
+	alerting.Watch("guaranteed-receiver", messagingService, alerting.FromEnv())
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	queueName := "durable-queue"
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		Build(resource.QueueDurableExclusive(queueName))
+	if err != nil {
+		panic(err)
+	}
+
+	// Handling a panic from a non existing queue
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("Make sure queue name '%s' exists on the broker.\nThe following error occurred when attempting to connect to create a Persistent Message Receiver:\n%s", queueName, err)
+		}
+	}()
+
+	// Start Persistent Message Receiver
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+	checker.AddReceiver(queueName, persistentReceiver)
+	drainer.AddReceiver(queueName, persistentReceiver)
+
+	if regErr := persistentReceiver.ReceiveAsync(checker.Handler(exporter.Handler(queueName, func(message message.InboundMessage) {
+		id := tracker.Track(message)
+		// acknowledged after the handler returned, the drain waits for it
+		done := drainer.Begin()
+		go func() {
+			defer done()
+			ProcessAndAcknowledge(exporter, persistentReceiver, tracker, id, 5*time.Second)
+		}()
+	}))); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\n Bound to queue: %s\n", queueName)
+	fmt.Println("\n===Interrupt (CTR+C) or SIGTERM to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt or a SIGTERM is received
+	lifecycle.WaitForSignal()
+
+	// Drain, then terminate the Persistent Receiver and disconnect the Message Service
+	report := drainer.Shutdown()
+	fmt.Println("\nDrain: ", report)
+	fmt.Println("Persistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Checkpoint/resume with message replay: the receiver stores the replication group message ID of the last
+// processed message in a checkpoint file. On restart, replay is requested from the broker starting after that ID,
+// so processing resumes exactly where it left off, even if the messages were already removed from the queue.
+//
+// Note: message replay must be enabled on the message VPN (replay log) and the client-profile must allow it.
+
+// LoadCheckpoint - reads the replication group message ID stored in the checkpoint file.
+// Returns an empty string when no checkpoint was written yet.
+func LoadCheckpoint(checkpointFile string) (string, error) {
+	content, err := ioutil.ReadFile(checkpointFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// SaveCheckpoint - atomically replaces the checkpoint file with the given replication group message ID,
+// a crash while writing leaves the previous checkpoint in place
+func SaveCheckpoint(checkpointFile string, replicationGroupMessageID string) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(checkpointFile), filepath.Base(checkpointFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.WriteString(replicationGroupMessageID + "\n"); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), checkpointFile)
+}
+
+// BuildReceiverFromCheckpoint - builds a client-acknowledged persistent receiver. When a checkpoint is available,
+// the receiver requests replay of all the messages after the checkpointed replication group message ID.
+func BuildReceiverFromCheckpoint(messagingService solace.MessagingService, queue *resource.Queue, checkpoint string) (solace.PersistentMessageReceiver, error) {
+	builder := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement()
+
+	if checkpoint != "" {
+		replicationGroupMessageID, err := messaging.ReplicationGroupMessageIDOf(checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint '%s': %w", checkpoint, err)
+		}
+		// Replay starts with the message following the given replication group message ID
+		builder = builder.WithMessageReplay(config.ReplayStrategyReplicationGroupMessageID(replicationGroupMessageID))
+	}
+
+	return builder.Build(queue)
+}
+
+// HandleMessagesWithCheckpoint - registers a handler that processes the message, acknowledges it and
+// then moves the checkpoint forward
+func HandleMessagesWithCheckpoint(persistentReceiver solace.PersistentMessageReceiver, checkpointFile string) error {
+	return persistentReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		payload, _ := message.GetPayloadAsString()
+		fmt.Printf("Processing message: %s (redelivered: %t)\n", payload, message.IsRedelivered())
+
+		if err := persistentReceiver.Ack(message); err != nil {
+			fmt.Println("Message Acknowledgement Error: ", err)
+			return
+		}
+
+		replicationGroupMessageID, ok := message.GetReplicationGroupMessageID()
+		if !ok {
+			fmt.Println("Message has no replication group message ID, checkpoint not updated")
+			return
+		}
+		if err := SaveCheckpoint(checkpointFile, replicationGroupMessageID.String()); err != nil {
+			fmt.Println("Could not save checkpoint: ", err)
+		}
+	})
+}
+
+func main() {
+	queueName := flag.String("queue", "durable-queue", "name of the durable exclusive queue to bind to")
+	checkpointFile := flag.String("checkpoint", "replay-checkpoint.txt", "file storing the replication group message ID of the last processed message")
+	reset := flag.Bool("reset", false, "discard the stored checkpoint and start without replay")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// With -provision (or SOLACE_PROVISION=true) the queue and the replay log of the VPN are created through SEMP
+	// first (see internal/semp)
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName, Subscriptions: []string{"solace/samples/persistent/>"}}); err != nil {
+		panic(err)
+	}
+	if err := semp.ProvisionReplayLog(context.Background(), "samples-replay-log"); err != nil {
+		panic(err)
+	}
+
+	if *reset {
+		os.Remove(*checkpointFile)
+	}
+
+	checkpoint, err := LoadCheckpoint(*checkpointFile)
+	if err != nil {
+		panic(err)
+	}
+
+	if checkpoint == "" {
+		fmt.Println("No checkpoint found, receiving from the queue without replay")
+	} else {
+		fmt.Printf("Resuming with replay after replication group message ID %s\n", checkpoint)
+	}
+
+	persistentReceiver, err := BuildReceiverFromCheckpoint(messagingService, resource.QueueDurableExclusive(*queueName), checkpoint)
+	if err != nil {
+		panic(err)
+	}
+
+	// Handling a panic from a non existing queue or a replay request the broker could not satisfy
+	// (e.g. the checkpointed message is no longer in the replay log, run again with -reset to start over)
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("Make sure queue name '%s' exists on the broker and replay is enabled on the message VPN.\nThe following error occurred when attempting to start the Persistent Message Receiver:\n%s\n", *queueName, err)
+		}
+	}()
+
+	// Start Persistent Message Receiver
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+
+	if regErr := HandleMessagesWithCheckpoint(persistentReceiver, *checkpointFile); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\n Bound to queue: %s\n", *queueName)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Persistent Receiver
+	persistentReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
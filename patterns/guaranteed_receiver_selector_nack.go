@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// MessageSelector - only messages matching this SQL-92 selector on their user properties are delivered on the flow,
+// the selector is evaluated by the broker so non-matching messages are never sent to this receiver
+const MessageSelector = "application = 'samples' AND priority IN ('high', 'medium')"
+
+// BuildSelectorNackPersistentMessageReceiverWithBuilderMethods - example of how to compose a message selector,
+// client acknowledgement and the required message settlement outcomes on a single flow with the builder methods.
+// All three options are independent of each other and can be combined in any order.
+func BuildSelectorNackPersistentMessageReceiverWithBuilderMethods(messagingService solace.MessagingService, durableExclusiveQueue *resource.Queue) (receiver solace.PersistentMessageReceiver, err error) {
+	return messagingService.CreatePersistentMessageReceiverBuilder().
+		// Filter the messages delivered on the flow
+		WithMessageSelector(MessageSelector).
+		// Messages are only removed from the queue once they are settled by the application
+		WithMessageClientAcknowledgement().
+		// Add message settlement outcomes support on the created Flow here (Failed and Reject for NACK(ing) messages),
+		// the ACCEPTED outcome is always supported
+		WithRequiredMessageOutcomeSupport(config.PersistentReceiverFailedOutcome, config.PersistentReceiverRejectedOutcome).
+		Build(durableExclusiveQueue)
+}
+
+// BuildSelectorNackPersistentMessageReceiverWithConfigurationProvider - the same receiver as above, configured
+// with the configuration provider instead of the builder methods
+func BuildSelectorNackPersistentMessageReceiverWithConfigurationProvider(messagingService solace.MessagingService, durableExclusiveQueue *resource.Queue) (receiver solace.PersistentMessageReceiver, err error) {
+	return messagingService.CreatePersistentMessageReceiverBuilder().
+		FromConfigurationProvider(config.ReceiverPropertyMap{
+			config.ReceiverPropertyPersistentMessageSelectorQuery:          MessageSelector,
+			config.ReceiverPropertyPersistentMessageAckStrategy:            config.PersistentReceiverClientAck,
+			config.ReceiverPropertyPersistentMessageRequiredOutcomeSupport: fmt.Sprintf("%s,%s", config.PersistentReceiverFailedOutcome, config.PersistentReceiverRejectedOutcome),
+		}).
+		Build(durableExclusiveQueue)
+}
+
+// SettlementOutcomeFor - decides how a message is settled: messages without a payload can never be processed and
+// are REJECTED (moved to the DMQ if configured), messages flagged as "retry" are FAILED (redelivered by the broker)
+// and all others are ACCEPTED
+func SettlementOutcomeFor(message message.InboundMessage) config.MessageSettlementOutcome {
+	if payload, ok := message.GetPayloadAsBytes(); !ok || len(payload) == 0 {
+		return config.PersistentReceiverRejectedOutcome
+	}
+	if value, ok := message.GetProperty("retry"); ok && fmt.Sprint(value) == "true" {
+		return config.PersistentReceiverFailedOutcome
+	}
+	return config.PersistentReceiverAcceptedOutcome
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	queueName := "durable-queue"
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp), the
+	// failed messages are redelivered 3 times before they are discarded or moved to the dead message queue
+	if err := semp.Provision(context.Background(), semp.Queue{Name: queueName,
+		Subscriptions: []string{"solace/samples/persistent/>"}, MaxRedeliveryCount: 3}); err != nil {
+		panic(err)
+	}
+	durableExclusiveQueue := resource.QueueDurableExclusive(queueName)
+
+	// Code example for ways to configure the combined receiver:
+	// 	-	using the builder methods => BuildSelectorNackPersistentMessageReceiverWithBuilderMethods(messagingService, durableExclusiveQueue)
+	// 	-	using the configuration provider => BuildSelectorNackPersistentMessageReceiverWithConfigurationProvider(messagingService, durableExclusiveQueue)
+	persistentReceiver, err := BuildSelectorNackPersistentMessageReceiverWithBuilderMethods(messagingService, durableExclusiveQueue)
+	if err != nil {
+		panic(err)
+	}
+
+	// Handling a panic from a non existing queue or an invalid selector
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("Make sure queue name '%s' exists on the broker.\nThe following error occurred when attempting to connect to create a Persistent Message Receiver:\n%s", queueName, err)
+		}
+	}()
+
+	// Start Persistent Message Receiver
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+
+	// Message Handler
+	messageHandler := func(message message.InboundMessage) {
+		outcome := SettlementOutcomeFor(message)
+		payload, _ := message.GetPayloadAsString()
+		fmt.Printf("Received Message Body %s, settling with outcome %s\n", payload, outcome)
+
+		if err := persistentReceiver.Settle(message, outcome); err != nil {
+			fmt.Println("Message Settlement Error: ", err)
+		}
+	}
+
+	// Register Message callback handler to the Message Receiver
+	if regErr := persistentReceiver.ReceiveAsync(messageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\n Bound to queue: %s with selector: %s\n", queueName, MessageSelector)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Persistent Receiver
+	persistentReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
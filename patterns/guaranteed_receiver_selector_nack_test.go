@@ -0,0 +1,96 @@
+//go:build integration
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/brokertest"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Integration tests of the selector and NACK receiver, against a broker started in Docker (see internal/brokertest):
+//
+//	go test -tags integration -v guaranteed_receiver_selector_nack.go guaranteed_receiver_selector_nack_test.go
+
+// maxRedeliveries is the max redelivery count of the queues of the tests
+const maxRedeliveries = 2
+
+// settleTimeout is how long the messages of a test have to be settled
+const settleTimeout = 30 * time.Second
+
+func TestSelectorNackReceiverSettlesMessages(t *testing.T) {
+	broker := brokertest.Start(t)
+	builders := []struct {
+		name  string
+		build func(solace.MessagingService, *resource.Queue) (solace.PersistentMessageReceiver, error)
+	}{
+		{"builder-methods", BuildSelectorNackPersistentMessageReceiverWithBuilderMethods},
+		{"configuration-provider", BuildSelectorNackPersistentMessageReceiverWithConfigurationProvider},
+	}
+	selected := map[string]string{"application": "samples", "priority": "high"}
+	retry := map[string]string{"application": "samples", "priority": "medium", "retry": "true"}
+	filtered := map[string]string{"application": "samples", "priority": "low"}
+
+	for _, builder := range builders {
+		builder := builder
+		t.Run(builder.name, func(t *testing.T) {
+			messagingService := broker.Connect(t)
+			topic := brokertest.Topic(t)
+			queue := broker.Queue(t, brokertest.QueueOptions{Topics: []string{topic}, MaxRedeliveryCount: maxRedeliveries})
+
+			persistentReceiver, err := builder.build(messagingService, queue)
+			if err != nil {
+				t.Fatalf("could not build the receiver: %s", err)
+			}
+			if err := persistentReceiver.Start(); err != nil {
+				t.Fatalf("could not start the receiver: %s", err)
+			}
+			t.Cleanup(func() { persistentReceiver.Terminate(time.Second) })
+			var recorder brokertest.Recorder
+			audit := recorder.Log()
+			// the handler of the sample, settling each message with the outcome SettlementOutcomeFor decides
+			handler := audit.Handler(queue.GetName(), persistentReceiver, func(inbound message.InboundMessage) (config.MessageSettlementOutcome, error) {
+				return SettlementOutcomeFor(inbound), nil
+			})
+			if err := persistentReceiver.ReceiveAsync(handler); err != nil {
+				t.Fatalf("could not receive: %s", err)
+			}
+
+			brokertest.Publish(t, messagingService, topic,
+				brokertest.Message{Payload: "processed", Properties: selected},
+				brokertest.Message{Payload: "", Properties: selected},
+				brokertest.Message{Payload: "retried", Properties: retry},
+				brokertest.Message{Payload: "not selected", Properties: filtered},
+			)
+
+			// accepted once, rejected once and failed on every delivery, the message not selected is never delivered
+			expected := map[string]int{
+				string(config.PersistentReceiverAcceptedOutcome): 1,
+				string(config.PersistentReceiverRejectedOutcome): 1,
+				string(config.PersistentReceiverFailedOutcome):   maxRedeliveries + 1,
+			}
+			records := recorder.WaitFor(t, 2+maxRedeliveries+1, settleTimeout)
+			outcomes := map[string]int{}
+			for _, record := range records {
+				if record.Error != "" {
+					t.Errorf("settlement error: %s", record.Error)
+				}
+				outcomes[record.Outcome]++
+			}
+			for outcome, n := range expected {
+				if outcomes[outcome] != n {
+					t.Errorf("%d message(s) settled with %s, expected %d (%v)", outcomes[outcome], outcome, n, outcomes)
+				}
+			}
+
+			// the message not selected stays on the queue, the rejected one and the one failed too often are dead
+			broker.WaitFor(t, queue.GetName(), settleTimeout, func(stats brokertest.QueueStats) bool { return stats.Spooled == 1 })
+			broker.WaitFor(t, brokertest.DMQ(queue.GetName()), settleTimeout, func(stats brokertest.QueueStats) bool { return stats.Spooled == 2 })
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/alerting"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Host list failover: TransportLayerPropertyHost accepts a comma separated list of hosts, the API connects to the
+// first one that answers and, once connected, reconnects through the same list when the connection is lost. This
+// sample puts two local TCP redirectors, "primary" and "secondary", in front of a single broker and uses them as the
+// host list, then simulates outages by closing them:
+//
+//  1. the primary goes down: the connection is lost and the API reconnects to the secondary
+//  2. the primary comes back: the API stays on the secondary, there is no fail back while connected
+//  3. the secondary goes down: the API reconnects to the primary, the list is always tried from the first host
+//
+//	SOLACE_BROKER_ADDRESS=localhost:55555 go run host_list_failover.go -step 15s
+//
+// Both redirectors forward to the same broker, standing in for the two hosts of a broker pair. The API re-applies the
+// subscriptions of the receivers once reconnected, direct messages published during the outage are lost. The
+// retries per host and the wait between attempts set how long a failover takes.
+
+// Redirector - forwards the TCP connections accepted on a local address to a target address, and can be taken
+// down (closing the listener and every forwarded connection, as a crashed host would) and brought back up
+type Redirector struct {
+	Name   string
+	Listen string
+	Target string
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+// Up - starts listening and forwarding connections
+func (r *Redirector) Up() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", r.Listen)
+	if err != nil {
+		return err
+	}
+	r.listener = listener
+	if r.conns == nil {
+		r.conns = make(map[net.Conn]struct{})
+	}
+	go r.accept(listener)
+	return nil
+}
+
+// Down - stops listening and resets the forwarded connections, new connections are refused until Up
+func (r *Redirector) Down() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listener == nil {
+		return
+	}
+	r.listener.Close()
+	r.listener = nil
+	for conn := range r.conns {
+		conn.Close()
+		delete(r.conns, conn)
+	}
+}
+
+func (r *Redirector) accept(listener net.Listener) {
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			// closed by Down
+			return
+		}
+		go r.forward(client)
+	}
+}
+
+func (r *Redirector) forward(client net.Conn) {
+	broker, err := net.DialTimeout("tcp", r.Target, 5*time.Second)
+	if err != nil {
+		fmt.Printf("[%s] could not reach %s: %s\n", r.Name, r.Target, err)
+		client.Close()
+		return
+	}
+	if !r.track(client, broker) {
+		// went down while dialing
+		client.Close()
+		broker.Close()
+		return
+	}
+	fmt.Printf("[%s] forwarding %s to %s\n", r.Name, client.RemoteAddr(), r.Target)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	pipe := func(to, from net.Conn) {
+		defer wg.Done()
+		io.Copy(to, from)
+		// one side is done, close both to unblock the other copy
+		to.Close()
+		from.Close()
+	}
+	go pipe(broker, client)
+	go pipe(client, broker)
+	wg.Wait()
+
+	r.mu.Lock()
+	delete(r.conns, client)
+	delete(r.conns, broker)
+	r.mu.Unlock()
+}
+
+func (r *Redirector) track(conns ...net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listener == nil {
+		return false
+	}
+	for _, conn := range conns {
+		r.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	step := flag.Duration("step", 15*time.Second, "time between the simulated outages")
+	primaryAddress := flag.String("primary", "127.0.0.1:55601", "local address of the primary redirector")
+	secondaryAddress := flag.String("secondary", "127.0.0.1:55602", "local address of the secondary redirector")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	brokerAddress := sampleconfig.Setting("SOLACE_BROKER_ADDRESS", "localhost:55555")
+	primary := &Redirector{Name: "primary", Listen: *primaryAddress, Target: brokerAddress}
+	secondary := &Redirector{Name: "secondary", Listen: *secondaryAddress, Target: brokerAddress}
+	for _, redirector := range []*Redirector{primary, secondary} {
+		if err := redirector.Up(); err != nil {
+			panic(err)
+		}
+		defer redirector.Down()
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the host list is tried in order on connection and on every reconnection
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	brokerConfig.Properties[config.TransportLayerPropertyHost] = "tcp://" + primary.Listen + ",tcp://" + secondary.Listen
+	// Each host is tried ConnectionRetriesPerHost + 1 times before moving to the next one, and each pass through
+	// the list counts as one reconnection attempt, with the wait interval between tries
+	brokerConfig.Properties[config.TransportLayerPropertyConnectionRetriesPerHost] = 1
+	brokerConfig.Properties[config.TransportLayerPropertyReconnectionAttempts] = 20
+	brokerConfig.Properties[config.TransportLayerPropertyReconnectionAttemptsWaitInterval] = 1000
+	// Detect a silently dead host (no TCP reset) after 3 unanswered keep-alives
+	brokerConfig.Properties[config.TransportLayerPropertyKeepAliveInterval] = 1000
+	brokerConfig.Properties[config.TransportLayerPropertyKeepAliveWithoutResponseLimit] = 3
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// The events an application observes during a failover
+	var outageStart time.Time
+	messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		// only called once the reconnection attempts are exhausted
+		fmt.Printf("%s EVENT service interrupted, giving up: %v\n", event.GetTimestamp().Format("15:04:05.000"), event.GetCause())
+	})
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		if outageStart.IsZero() {
+			outageStart = event.GetTimestamp()
+		}
+		fmt.Printf("%s EVENT reconnecting (last host %s): %v\n", event.GetTimestamp().Format("15:04:05.000"), event.GetBrokerURI(), event.GetCause())
+	})
+	messagingService.AddReconnectionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("%s EVENT reconnected to %s after %s\n", event.GetTimestamp().Format("15:04:05.000"), event.GetBrokerURI(),
+			event.GetTimestamp().Sub(outageStart).Round(time.Millisecond))
+		outageStart = time.Time{}
+	})
+
+	// Operational notification of the outages, printed and posted to SOLACE_ALERT_WEBHOOK when set (see pkg/alerting)
+	alerting.Watch("host-list-failover", messagingService, alerting.FromEnv())
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Publish and receive a message every second to show when traffic stops and resumes
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/failover/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher and receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// The simulated outages, one every step
+	scenario := []struct {
+		description string
+		action      func()
+	}{
+		{"primary goes down, expect a failover to the secondary", primary.Down},
+		{"primary comes back, expect no fail back", func() { primary.Up() }},
+		{"secondary goes down, expect a failover to the primary", secondary.Down},
+		{"secondary comes back", func() { secondary.Up() }},
+	}
+	next := time.After(*step)
+	topic := resource.TopicOf(TopicPrefix + "/failover/hello")
+	for msgSeqNum := 0; ; msgSeqNum++ {
+		select {
+		case <-c:
+			// Terminate the Direct Publisher and Receiver
+			directPublisher.Terminate(1 * time.Second)
+			fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+			directReceiver.Terminate(1 * time.Second)
+			fmt.Println("Direct Receiver Terminated? ", directReceiver.IsTerminated())
+			// Disconnect the Message Service
+			messagingService.Disconnect()
+			fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+			return
+		case <-next:
+			simulated := scenario[0]
+			scenario = append(scenario[1:], simulated)
+			fmt.Printf("%s SIMULATED %s\n", time.Now().Format("15:04:05.000"), simulated.description)
+			simulated.action()
+			next = time.After(*step)
+		case <-time.After(1 * time.Second):
+			if err := directPublisher.PublishString("Hello through the host list --> "+strconv.Itoa(msgSeqNum), topic); err != nil {
+				fmt.Println("Publish failed: ", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// notSet - placeholder printed for the getters reporting that the value is not present on the message
+const notSet = "<not set>"
+
+// valueOrNotSet - renders the value returned by a getter together with its "ok" flag
+func valueOrNotSet(value interface{}, ok bool) interface{} {
+	if !ok {
+		return notSet
+	}
+	return value
+}
+
+// ExploreMessageMetadata - queries every metadata getter available on the inbound message and prints the results
+// as a labeled table. Getters returning a (value, ok) pair print <not set> when the header is absent on the message.
+func ExploreMessageMetadata(message message.InboundMessage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(getter string, value interface{}) {
+		fmt.Fprintf(w, "%s\t%v\n", getter, value)
+	}
+
+	fmt.Fprintln(w, "GETTER\tVALUE")
+	fmt.Fprintln(w, "------\t-----")
+
+	// Destination and delivery details
+	row("GetDestinationName()", message.GetDestinationName())
+	row("IsRedelivered()", message.IsRedelivered())
+	discard := message.GetMessageDiscardNotification()
+	row("GetMessageDiscardNotification().HasBrokerDiscardIndication()", discard.HasBrokerDiscardIndication())
+	row("GetMessageDiscardNotification().HasInternalDiscardIndication()", discard.HasInternalDiscardIndication())
+	if rgmid, ok := message.GetReplicationGroupMessageID(); ok {
+		row("GetReplicationGroupMessageID()", rgmid.String())
+	} else {
+		row("GetReplicationGroupMessageID()", notSet)
+	}
+
+	// Headers set by the publisher (or generated by the API when enabled on the publishing service)
+	row("GetApplicationMessageID()", valueOrNotSet(message.GetApplicationMessageID()))
+	row("GetApplicationMessageType()", valueOrNotSet(message.GetApplicationMessageType()))
+	row("GetCorrelationID()", valueOrNotSet(message.GetCorrelationID()))
+	row("GetSenderID()", valueOrNotSet(message.GetSenderID()))
+	row("GetSenderTimestamp()", valueOrNotSet(message.GetSenderTimestamp()))
+	row("GetSequenceNumber()", valueOrNotSet(message.GetSequenceNumber()))
+	row("GetPriority()", valueOrNotSet(message.GetPriority()))
+	row("GetClassOfService()", message.GetClassOfService())
+	row("GetExpiration()", message.GetExpiration())
+	row("GetHTTPContentType()", valueOrNotSet(message.GetHTTPContentType()))
+	row("GetHTTPContentEncoding()", valueOrNotSet(message.GetHTTPContentEncoding()))
+
+	// Headers added by the receiving API
+	row("GetTimeStamp()", valueOrNotSet(message.GetTimeStamp()))
+
+	// User properties
+	properties := message.GetProperties()
+	row("GetProperties() count", len(properties))
+	for key := range properties {
+		row(fmt.Sprintf("GetProperty(%q)", key), valueOrNotSet(message.GetProperty(key)))
+	}
+
+	// Payload, only one of the payload getters succeeds depending on how the message was built
+	if payload, ok := message.GetPayloadAsBytes(); ok {
+		row("GetPayloadAsBytes() length", len(payload))
+	} else {
+		row("GetPayloadAsBytes()", notSet)
+	}
+	row("GetPayloadAsString()", valueOrNotSet(message.GetPayloadAsString()))
+	row("GetPayloadAsMap()", valueOrNotSet(message.GetPayloadAsMap()))
+	row("GetPayloadAsStream()", valueOrNotSet(message.GetPayloadAsStream()))
+
+	w.Flush()
+	fmt.Println()
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	// Have the API stamp received messages with the time of arrival, see GetTimeStamp()
+	brokerConfig.Properties[config.ServicePropertyGenerateReceiveTimestamps] = true
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Build a Direct Message Receiver on all of the sample topics
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/>")).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Receiver
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	// Register Message callback handler to the Message Receiver
+	if regErr := directReceiver.ReceiveAsync(ExploreMessageMetadata); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\nSubscribed to: %s, run any of the publisher samples to explore their messages\n", TopicPrefix+"/>")
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
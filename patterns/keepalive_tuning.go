@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Keep-alive and timeout tuning: the API sends a keep-alive every keep-alive interval and declares the connection
+// dead after the limit of keep-alives went unanswered, then reconnects. Together with the connect timeout and the
+// reconnection settings they decide how fast a dead connection is noticed and how long the application keeps trying.
+//
+//	go run keepalive_tuning.go -keepalive 3s -keepalive-limit 3 -connect-timeout 10s -reconnect-wait 3s -reconnect-attempts 20
+//
+// Diagnose prints the symptoms to expect from the chosen values before connecting.
+
+// Settings - the tunable connection timings
+type Settings struct {
+	KeepAliveInterval time.Duration
+	KeepAliveLimit    int
+	ConnectTimeout    time.Duration
+	ReconnectWait     time.Duration
+	ReconnectAttempts int
+}
+
+// DetectionTime - how long a dead connection goes unnoticed at most
+func (s Settings) DetectionTime() time.Duration {
+	return s.KeepAliveInterval * time.Duration(s.KeepAliveLimit)
+}
+
+// ReconnectWindow - how long the API tries to reconnect before reporting a service interruption, at most
+func (s Settings) ReconnectWindow() time.Duration {
+	return time.Duration(s.ReconnectAttempts) * (s.ConnectTimeout + s.ReconnectWait)
+}
+
+// Properties - the settings as service properties, in milliseconds
+func (s Settings) Properties() config.ServicePropertyMap {
+	return config.ServicePropertyMap{
+		config.TransportLayerPropertyKeepAliveInterval:                int(s.KeepAliveInterval.Milliseconds()),
+		config.TransportLayerPropertyKeepAliveWithoutResponseLimit:    s.KeepAliveLimit,
+		config.TransportLayerPropertyConnectionAttemptsTimeout:        int(s.ConnectTimeout.Milliseconds()),
+		config.TransportLayerPropertyReconnectionAttemptsWaitInterval: int(s.ReconnectWait.Milliseconds()),
+		config.TransportLayerPropertyReconnectionAttempts:             s.ReconnectAttempts,
+	}
+}
+
+// Diagnose - the symptoms to expect from mis-tuned settings
+func Diagnose(s Settings) []string {
+	var symptoms []string
+	detection := s.DetectionTime()
+
+	// A connection that dies without a TCP reset (cable pulled, VM frozen, NAT or firewall state dropped) is only
+	// noticed by the missing keep-alive responses: until then publishes are buffered and silently lost on direct
+	// messaging, and the application believes it is connected
+	if detection > 60*time.Second {
+		symptoms = append(symptoms, fmt.Sprintf("slow failure detection: a dead connection goes unnoticed for up to %s", detection))
+	}
+
+	// Keep-alives are answered by the broker and processed by the API context thread: a short interval with a low
+	// limit turns a GC pause, a CPU starved container or a busy broker into a disconnect and a reconnection storm
+	if detection < 2*time.Second || s.KeepAliveLimit < 2 {
+		symptoms = append(symptoms, fmt.Sprintf("false disconnects: %s without response (limit %d) is tripped by short stalls", detection, s.KeepAliveLimit))
+	}
+
+	// Load balancers, NAT gateways and firewalls drop idle flows without telling either end (AWS NLB after 350s,
+	// Azure load balancer after 4 minutes by default): a connection idle longer than that becomes half-open, writes
+	// vanish and nothing is received until the keep-alives finally fail
+	if s.KeepAliveInterval > 4*time.Minute {
+		symptoms = append(symptoms, fmt.Sprintf("half-open connections: a %s keep-alive interval is longer than common idle timeouts of network devices", s.KeepAliveInterval))
+	}
+
+	// A TLS handshake over a long distance, or a broker under load, takes a few seconds; a host that does not
+	// answer at all holds the connection for the whole timeout before the next host of the list is tried
+	if s.ConnectTimeout < 3*time.Second {
+		symptoms = append(symptoms, fmt.Sprintf("failed connections to a slow but healthy broker: %s connect timeout", s.ConnectTimeout))
+	}
+	if s.ConnectTimeout > time.Minute {
+		symptoms = append(symptoms, fmt.Sprintf("slow failover: %s is spent on an unreachable host before the next one is tried", s.ConnectTimeout))
+	}
+
+	// A broker restart or an HA failover takes tens of seconds, a DR switchover minutes
+	if s.ReconnectAttempts >= 0 && s.ReconnectWindow() < time.Minute {
+		symptoms = append(symptoms, fmt.Sprintf("gives up too soon: reconnections stop after about %s, shorter than a broker restart", s.ReconnectWindow()))
+	}
+	if s.ReconnectWait < 500*time.Millisecond {
+		symptoms = append(symptoms, fmt.Sprintf("reconnection storm: every client retries every %s while the broker is starting", s.ReconnectWait))
+	}
+	return symptoms
+}
+
+func main() {
+	settings := Settings{}
+	flag.DurationVar(&settings.KeepAliveInterval, "keepalive", 3*time.Second, "interval between keep-alives")
+	flag.IntVar(&settings.KeepAliveLimit, "keepalive-limit", 3, "keep-alives without response before the connection is declared dead")
+	flag.DurationVar(&settings.ConnectTimeout, "connect-timeout", 30*time.Second, "timeout of each connection attempt")
+	flag.DurationVar(&settings.ReconnectWait, "reconnect-wait", 3*time.Second, "wait between reconnection attempts")
+	flag.IntVar(&settings.ReconnectAttempts, "reconnect-attempts", 20, "reconnection attempts before giving up, -1 for forever")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	fmt.Printf("Dead connections detected within %s\n", settings.DetectionTime())
+	if settings.ReconnectAttempts < 0 {
+		fmt.Println("Reconnecting forever")
+	} else {
+		fmt.Printf("Reconnecting for up to %s\n", settings.ReconnectWindow())
+	}
+	for _, symptom := range Diagnose(settings) {
+		fmt.Println("Warning: ", symptom)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		FromConfigurationProvider(settings.Properties()).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Observe the timings: disconnect the network (or pause the broker) and compare with the expected values
+	var lastSeen time.Time
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		if lastSeen.IsZero() {
+			lastSeen = event.GetTimestamp()
+			fmt.Printf("%s connection declared dead: %v\n", event.GetTimestamp().Format("15:04:05.000"), event.GetCause())
+		}
+	})
+	messagingService.AddReconnectionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("%s reconnected to %s, %s after the connection was declared dead\n", event.GetTimestamp().Format("15:04:05.000"),
+			event.GetBrokerURI(), event.GetTimestamp().Sub(lastSeen).Round(time.Millisecond))
+		lastSeen = time.Time{}
+	})
+	messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("%s gave up reconnecting: %v\n", event.GetTimestamp().Format("15:04:05.000"), event.GetCause())
+	})
+
+	// Connect to the messaging service
+	start := time.Now()
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Connected to the broker? %t (in %s)\n", messagingService.IsConnected(), time.Since(start).Round(time.Millisecond))
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Large payload profile: publishes multi-megabyte payloads on a topic the same service subscribes to, and records the
+// Go heap allocations per received message (runtime.MemStats) for several ways of reading the payload, followed by a
+// benchmark of each way on a received message (testing.Benchmark, without go test):
+//
+//	go run large_payload_profile.go -size 8388608 -count 20
+//
+// The payload of a received message lives in memory of the C library under the API. GetPayloadAsBytes copies it to
+// the Go heap on every call, and GetPayloadAsString copies it once more into a string: with multi-megabyte payloads
+// each extra copy is megabytes of garbage per message. Read the payload once with GetPayloadAsBytes, process the
+// slice in place, and Dispose the message when done so its C memory, which the Go garbage collector does not see, is
+// released right away rather than when the message is finalized.
+
+// PayloadStrategy - a way of reading the payload of a received message, returning a checksum of the payload
+type PayloadStrategy struct {
+	Name        string
+	Description string
+	Read        func(message message.InboundMessage) uint32
+}
+
+// Strategies - the ways of reading the payload compared by the sample, from the most copies to the fewest
+var Strategies = []PayloadStrategy{
+	{"string", "GetPayloadAsString, then converted to []byte", func(message message.InboundMessage) uint32 {
+		// the pattern of many handlers: read as a string, fall back to bytes, then convert for a []byte API,
+		// i.e. three copies of the payload
+		var body string
+		if payload, ok := message.GetPayloadAsString(); ok {
+			body = payload
+		} else if payload, ok := message.GetPayloadAsBytes(); ok {
+			body = string(payload)
+		}
+		return crc32.ChecksumIEEE([]byte(body))
+	}},
+	{"bytes-twice", "GetPayloadAsBytes called to validate, then to process", func(message message.InboundMessage) uint32 {
+		// every call copies the payload again, e.g. once in a validation step and once in the processing
+		if payload, ok := message.GetPayloadAsBytes(); !ok || len(payload) == 0 {
+			return 0
+		}
+		payload, _ := message.GetPayloadAsBytes()
+		return crc32.ChecksumIEEE(payload)
+	}},
+	{"bytes-once", "GetPayloadAsBytes once, processed in place", func(message message.InboundMessage) uint32 {
+		// the one unavoidable copy, from the C library to the Go heap
+		payload, ok := message.GetPayloadAsBytes()
+		if !ok {
+			return 0
+		}
+		return crc32.ChecksumIEEE(payload)
+	}},
+}
+
+// Allocations - the heap allocation counters of the process, see runtime.MemStats
+type Allocations struct {
+	Mallocs    uint64
+	TotalAlloc uint64
+}
+
+// ReadAllocations - reads the allocation counters, stopping the world for a moment
+func ReadAllocations() Allocations {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return Allocations{Mallocs: stats.Mallocs, TotalAlloc: stats.TotalAlloc}
+}
+
+func main() {
+	size := flag.Int("size", 4<<20, "payload size in bytes")
+	count := flag.Int("count", 20, "messages published for each way of reading the payload")
+	interval := flag.Duration("interval", 50*time.Millisecond, "pause between two messages, so the receiver keeps up")
+	timeout := flag.Duration("timeout", 30*time.Second, "how long to wait for the messages of each way to be received")
+	flag.Parse()
+	if *size <= 0 || *count <= 0 {
+		fmt.Fprintln(os.Stderr, "-size and -count must be positive")
+		os.Exit(2)
+	}
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	topic := resource.TopicOf(TopicPrefix + "/large/payload")
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(topic.GetName())).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	// The handler reads the payload with the current strategy and disposes of the message, except the last one which
+	// is kept for the benchmarks
+	var current atomic.Value
+	current.Store(Strategies[0])
+	var last atomic.Value
+	received := make(chan struct{}, *count)
+	if regErr := directReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		current.Load().(PayloadStrategy).Read(message)
+		if previous, ok := last.Swap(message).(interface{ Dispose() }); ok {
+			previous.Dispose()
+		}
+		// late messages of a phase that timed out must not block the handler
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	}); regErr != nil {
+		panic(regErr)
+	}
+
+	// Wait for room in the buffer rather than failing, a few large messages are enough to fill the socket
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(10).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+
+	// The payload is built once, building the message copies it to the C library, outside of the Go heap
+	payload := make([]byte, *size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	fmt.Printf("\nPublishing %d message(s) of %d bytes for each way of reading the payload\n", *count, *size)
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "STRATEGY\tRECEIVED\tALLOCS/MSG\tBYTES/MSG\tPAYLOAD COPIES/MSG\t")
+	for _, strategy := range Strategies {
+		current.Store(strategy)
+		runtime.GC()
+		before := ReadAllocations()
+		for i := 0; i < *count; i++ {
+			outbound, err := messagingService.MessageBuilder().BuildWithByteArrayPayload(payload)
+			if err != nil {
+				panic(err)
+			}
+			if err := directPublisher.Publish(outbound, topic); err != nil {
+				fmt.Println("Publish failed: ", err)
+			}
+			// release the C memory of the outbound message now, it is not needed once published
+			outbound.Dispose()
+			time.Sleep(*interval)
+		}
+		n := 0
+		deadline := time.After(*timeout)
+	wait:
+		for n < *count {
+			select {
+			case <-received:
+				n++
+			case <-deadline:
+				// direct messages may be discarded, report on what was received
+				break wait
+			}
+		}
+		after := ReadAllocations()
+		if n == 0 {
+			fmt.Fprintf(table, "%s\t0\t-\t-\t-\t\n", strategy.Name)
+			continue
+		}
+		bytesPerMessage := float64(after.TotalAlloc-before.TotalAlloc) / float64(n)
+		fmt.Fprintf(table, "%s\t%d\t%.0f\t%.0f\t%.2f\t\n", strategy.Name, n,
+			float64(after.Mallocs-before.Mallocs)/float64(n), bytesPerMessage, bytesPerMessage/float64(*size))
+	}
+	table.Flush()
+
+	// Terminate the receiver first so no late message replaces the one kept for the benchmarks, the received messages
+	// stay valid until disposed
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+
+	// Benchmark each strategy on the last message received, the per message figures above include the allocations of
+	// the API and of the publishing, the benchmark isolates the handler
+	if message, ok := last.Load().(message.InboundMessage); ok {
+		fmt.Println("\nBenchmark of the handler on a received message:")
+		table = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(table, "STRATEGY\tNS/OP\tB/OP\tALLOCS/OP\tDESCRIPTION\t")
+		for _, strategy := range Strategies {
+			result := testing.Benchmark(func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					strategy.Read(message)
+				}
+			})
+			fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%s\t\n", strategy.Name, result.NsPerOp(), result.AllocedBytesPerOp(),
+				result.AllocsPerOp(), strategy.Description)
+		}
+		table.Flush()
+		message.Dispose()
+	}
+
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// SendTimeProperty - the user property holding the send time in nanoseconds since the Unix epoch
+const SendTimeProperty = "latency-send-ns"
+
+// End-to-end latency, the consumer side of latency_publisher.go: computes the one-way latency of every received
+// message from the send time embedded by the publisher, and records it in HDR histograms, which keep the tail of the
+// distribution (p99.9 and above) exact to 3 significant digits in constant memory. The percentiles of the last
+// interval and of the whole run are printed every -interval, and for the whole run on exit:
+//
+//	go run latency_consumer.go -interval 5s
+//
+// Measuring at a fixed rate below the capacity of the system shows the latency of the broker and the network, above
+// it the latency grows with the time spent in the buffers.
+
+// LatencyRecorder - records the latencies, in microseconds, in a histogram per interval and one for the whole run
+type LatencyRecorder struct {
+	mu       sync.Mutex
+	interval *hdrhistogram.Histogram
+	total    *hdrhistogram.Histogram
+	// missing counts the messages without a send time, negative the ones received before they were sent,
+	// which happens when the clock of the consumer is behind the clock of the publisher
+	missing, negative int64
+}
+
+// NewLatencyRecorder - tracks latencies from 1µs up to the highest latency, 3 significant digits
+func NewLatencyRecorder(highest time.Duration) *LatencyRecorder {
+	return &LatencyRecorder{
+		interval: hdrhistogram.New(1, highest.Microseconds(), 3),
+		total:    hdrhistogram.New(1, highest.Microseconds(), 3),
+	}
+}
+
+// Record - records the latency of a message received at the given time
+func (r *LatencyRecorder) Record(message message.InboundMessage, received time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := message.GetProperty(SendTimeProperty)
+	sent, isInt := value.(int64)
+	if !ok || !isInt {
+		r.missing++
+		return
+	}
+	latency := received.Sub(time.Unix(0, sent))
+	if latency < 0 {
+		r.negative++
+		return
+	}
+	micros := latency.Microseconds()
+	if micros < 1 {
+		micros = 1
+	}
+	// values above the highest trackable value are recorded as the highest
+	if micros > r.total.HighestTrackableValue() {
+		micros = r.total.HighestTrackableValue()
+	}
+	r.interval.RecordValue(micros)
+	r.total.RecordValue(micros)
+}
+
+// Interval - returns a copy of the histogram of the interval and starts a new interval
+func (r *LatencyRecorder) Interval() *hdrhistogram.Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := hdrhistogram.Import(r.interval.Export())
+	r.interval.Reset()
+	return snapshot
+}
+
+// Total - returns a copy of the histogram of the whole run
+func (r *LatencyRecorder) Total() *hdrhistogram.Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return hdrhistogram.Import(r.total.Export())
+}
+
+// FormatPercentiles - renders the percentiles of a histogram of microseconds
+func FormatPercentiles(h *hdrhistogram.Histogram) string {
+	if h.TotalCount() == 0 {
+		return "no message"
+	}
+	us := func(v int64) time.Duration { return time.Duration(v) * time.Microsecond }
+	return fmt.Sprintf("count=%d min=%s p50=%s p90=%s p99=%s p99.9=%s max=%s",
+		h.TotalCount(), us(h.Min()), us(h.ValueAtQuantile(50)), us(h.ValueAtQuantile(90)),
+		us(h.ValueAtQuantile(99)), us(h.ValueAtQuantile(99.9)), us(h.Max()))
+}
+
+func main() {
+	interval := flag.Duration("interval", 5*time.Second, "period the percentiles are printed")
+	highest := flag.Duration("highest", time.Minute, "highest latency tracked, higher latencies are recorded as this value")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	//  Build a Direct Message Receiver
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/latency")).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Receiver
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	recorder := NewLatencyRecorder(*highest)
+	// The receive time is taken first thing in the handler, the time spent recording is not measured
+	if regErr := directReceiver.ReceiveAsync(func(message message.InboundMessage) {
+		recorder.Record(message, time.Now())
+	}); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+report:
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Printf("interval: %s\n", FormatPercentiles(recorder.Interval()))
+			fmt.Printf("total:    %s\n", FormatPercentiles(recorder.Total()))
+		case <-c:
+			break report
+		}
+	}
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+
+	fmt.Printf("\nLatency of the run: %s\n", FormatPercentiles(recorder.Total()))
+	recorder.mu.Lock()
+	if recorder.missing > 0 || recorder.negative > 0 {
+		fmt.Printf("Not measured: %d message(s) without send time, %d received before they were sent (clock offset)\n", recorder.missing, recorder.negative)
+	}
+	recorder.mu.Unlock()
+}
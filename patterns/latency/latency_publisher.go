@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// SendTimeProperty - the user property holding the send time in nanoseconds since the Unix epoch
+const SendTimeProperty = "latency-send-ns"
+
+// End-to-end latency, the publisher side of latency_consumer.go: publishes direct messages at a fixed rate, each with
+// its send time embedded as a user property, for the consumer to compute the one-way latency on receive. Start the
+// consumer first:
+//
+//	go run latency_consumer.go
+//	go run latency_publisher.go -rate 1000 -size 256
+//
+// The latency is the difference between the clocks of the two hosts: run both on the same host, or synchronize the
+// clocks (e.g. PTP or chrony), an offset between the clocks adds up to every measurement.
+
+// PublishTimestamped - publishes a message with the time it is sent, taken as late as possible before publishing
+func PublishTimestamped(messagingService solace.MessagingService, publisher solace.DirectMessagePublisher, topic *resource.Topic, payload []byte, msgSeqNum int) error {
+	message, err := messagingService.MessageBuilder().
+		WithApplicationMessageID(strconv.Itoa(msgSeqNum)).
+		WithProperty(SendTimeProperty, time.Now().UnixNano()).
+		BuildWithByteArrayPayload(payload)
+	if err != nil {
+		return err
+	}
+	return publisher.Publish(message, topic)
+}
+
+func main() {
+	rate := flag.Int("rate", 1000, "messages published per second")
+	size := flag.Int("size", 100, "payload size in bytes")
+	count := flag.Int("count", 0, "messages to publish, 0 publishes until interrupted")
+	flag.Parse()
+	if *rate <= 0 || *size < 0 {
+		fmt.Fprintln(os.Stderr, "-rate must be positive and -size not negative")
+		os.Exit(2)
+	}
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Wait rather than buffer on back pressure, a message waiting in a buffer measures the buffer, not the broker
+	directPublisher, builderErr := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1).Build()
+	if builderErr != nil {
+		panic(builderErr)
+	}
+
+	if startErr := directPublisher.Start(); startErr != nil {
+		panic(startErr)
+	}
+
+	fmt.Println("Direct Publisher running? ", directPublisher.IsRunning())
+
+	fmt.Println("\n===Interrupt (CTR+C) to stop publishing===")
+
+	topic := resource.TopicOf(TopicPrefix + "/latency")
+	payload := make([]byte, *size)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Second / time.Duration(*rate))
+		defer ticker.Stop()
+		for msgSeqNum := 1; *count == 0 || msgSeqNum <= *count; msgSeqNum++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if err := PublishTimestamped(messagingService, directPublisher, topic, payload, msgSeqNum); err != nil {
+				fmt.Println("Publish failed: ", err)
+			}
+		}
+		fmt.Printf("Published %d message(s)\n", *count)
+	}()
+
+	// Run until an interrupt signal is received or all of the messages are published
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	select {
+	case <-c:
+		close(stop)
+		<-done
+	case <-done:
+	}
+
+	// Terminate the Direct Publisher
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
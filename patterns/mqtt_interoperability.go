@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// MQTT interoperability: the broker speaks MQTT next to SMF, the protocol of the PubSub+ Go API, and both share the
+// same topics, so IoT devices publishing with MQTT reach the Go services and the other way around. This sample pairs
+// the Go API with an MQTT client (Eclipse Paho) on the same message VPN, in both directions:
+//
+//	go run mqtt_interoperability.go
+//	SOLACE_MQTT_HOST=tcp://broker.example.com:1883 go run mqtt_interoperability.go -direction mqtt-to-solace
+//
+// SOLACE_MQTT_HOST is the MQTT service of the message VPN (tcp://localhost:1883 by default, ssl://host:8883 with TLS),
+// the MQTT client authenticates with SOLACE_USERNAME and SOLACE_PASSWORD like the Go API. The topics are the same in
+// both protocols, levels separated by /, only the wildcards differ (see MQTTFiltersOf and SMFSubscriptionsOf). The
+// quality of service maps to the delivery mode:
+//
+//   - a direct message of the Go API is delivered at QoS 0, a persistent one at QoS 1 (at most the QoS of the MQTT
+//     subscription)
+//   - an MQTT message published at QoS 0 is a direct message, one published at QoS 1 a persistent message: the
+//     broker acknowledges it (PUBACK) once spooled, and the queues subscribed to its topic spool it
+//
+// QoS 2 is not supported by the broker, MQTT subscriptions at QoS 2 are granted QoS 1.
+
+// MQTTFiltersOf - maps a Solace topic subscription to the MQTT topic filters matching the same topics: * as a whole
+// level is +, a trailing > is #. The MQTT filter a/# also matches the topic a, which a/> does not. A prefix wildcard,
+// e.g. temp*, has no MQTT counterpart.
+func MQTTFiltersOf(subscription string) ([]string, error) {
+	levels := strings.Split(subscription, "/")
+	for i, level := range levels {
+		switch {
+		case level == "*":
+			levels[i] = "+"
+		case level == ">" && i == len(levels)-1:
+			levels[i] = "#"
+		case strings.HasSuffix(level, "*"):
+			return nil, fmt.Errorf("the prefix wildcard %s of %s has no MQTT counterpart", level, subscription)
+		case strings.ContainsAny(level, "+#"):
+			return nil, fmt.Errorf("the level %s of %s is an MQTT wildcard", level, subscription)
+		}
+	}
+	return []string{strings.Join(levels, "/")}, nil
+}
+
+// SMFSubscriptionsOf - maps an MQTT topic filter to the Solace topic subscriptions matching the same topics: + is *,
+// a trailing # is > plus the parent level, which # also matches, and $share/group/filter is the shared subscription
+// #share/group/subscription. A level * or >, plain text in MQTT, can not be subscribed to with SMF.
+func SMFSubscriptionsOf(filter string) ([]string, error) {
+	prefix := ""
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("the shared subscription %s has no topic filter", filter)
+		}
+		prefix, filter = "#share/"+parts[1]+"/", parts[2]
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		switch level {
+		case "+":
+			levels[i] = "*"
+		case "#":
+			if i != len(levels)-1 {
+				return nil, fmt.Errorf("# is not the last level of %s", filter)
+			}
+			levels[i] = ">"
+		case "*", ">":
+			return nil, fmt.Errorf("the level %s of %s is an SMF wildcard", level, filter)
+		}
+	}
+	subscriptions := []string{prefix + strings.Join(levels, "/")}
+	if len(levels) > 1 && levels[len(levels)-1] == ">" {
+		subscriptions = append(subscriptions, prefix+strings.Join(levels[:len(levels)-1], "/"))
+	}
+	return subscriptions, nil
+}
+
+// ConnectMQTT - connects an MQTT client to the MQTT service of the message VPN, with the credentials of the Go API
+func ConnectMQTT(brokerConfig config.ServicePropertyMap) (mqtt.Client, error) {
+	username, _ := brokerConfig[config.AuthenticationPropertySchemeBasicUserName].(string)
+	password, _ := brokerConfig[config.AuthenticationPropertySchemeBasicPassword].(string)
+	options := mqtt.NewClientOptions().
+		AddBroker(sampleconfig.Setting("SOLACE_MQTT_HOST", "tcp://localhost:1883")).
+		SetClientID("go-samples-mqtt-" + strconv.Itoa(os.Getpid())).
+		SetUsername(username).
+		SetPassword(password).
+		SetCleanSession(true).
+		SetConnectTimeout(10 * time.Second)
+	client := mqtt.NewClient(options)
+	if err := wait(client.Connect()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// wait - waits for the completion of an MQTT operation
+func wait(token mqtt.Token) error {
+	if !token.WaitTimeout(10 * time.Second) {
+		return errors.New("the MQTT operation timed out")
+	}
+	return token.Error()
+}
+
+// SolaceToMQTT - publishes direct and persistent messages with the Go API to an MQTT subscriber at QoS 1
+func SolaceToMQTT(messagingService solace.MessagingService, client mqtt.Client, count int) error {
+	subscription := TopicPrefix + "/mqtt/from-solace/>"
+	filters, err := MQTTFiltersOf(subscription)
+	if err != nil {
+		return err
+	}
+	for _, filter := range filters {
+		fmt.Printf("MQTT subscription %s for the Solace subscription %s\n", filter, subscription)
+		if err := wait(client.Subscribe(filter, 1, func(_ mqtt.Client, msg mqtt.Message) {
+			fmt.Printf("  MQTT subscriber received %q on %s at QoS %d\n", msg.Payload(), msg.Topic(), msg.Qos())
+		})); err != nil {
+			return fmt.Errorf("could not subscribe to %s: %w", filter, err)
+		}
+	}
+	defer wait(client.Unsubscribe(filters...))
+
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		return err
+	}
+	if err := directPublisher.Start(); err != nil {
+		return err
+	}
+	defer directPublisher.Terminate(1 * time.Second)
+	persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+	if err != nil {
+		return err
+	}
+	if err := persistentPublisher.Start(); err != nil {
+		return err
+	}
+	defer persistentPublisher.Terminate(1 * time.Second)
+
+	for i := 1; i <= count; i++ {
+		direct, _ := messagingService.MessageBuilder().BuildWithStringPayload(fmt.Sprintf("direct %d", i))
+		if err := directPublisher.Publish(direct, resource.TopicOf(fmt.Sprintf("%s/mqtt/from-solace/direct/%d", TopicPrefix, i))); err != nil {
+			return err
+		}
+		persistent, _ := messagingService.MessageBuilder().BuildWithStringPayload(fmt.Sprintf("persistent %d", i))
+		if err := persistentPublisher.PublishAwaitAcknowledgement(persistent, resource.TopicOf(fmt.Sprintf("%s/mqtt/from-solace/persistent/%d", TopicPrefix, i)), 5*time.Second, nil); err != nil {
+			return err
+		}
+	}
+	// let the deliveries arrive before unsubscribing
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+// MQTTToSolace - publishes MQTT messages at QoS 0 and 1 to a direct receiver and to a temporary queue of the Go API
+func MQTTToSolace(messagingService solace.MessagingService, client mqtt.Client, count int) error {
+	filter := TopicPrefix + "/mqtt/from-mqtt/#"
+	subscriptionNames, err := SMFSubscriptionsOf(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Solace subscriptions %s for the MQTT subscription %s\n", strings.Join(subscriptionNames, ", "), filter)
+	// the same topic subscriptions for the direct and the persistent receivers
+	var subscriptions []resource.Subscription
+	for _, name := range subscriptionNames {
+		subscriptions = append(subscriptions, resource.TopicSubscriptionOf(name))
+	}
+
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().WithSubscriptions(subscriptions...).Build()
+	if err != nil {
+		return err
+	}
+	if err := directReceiver.Start(); err != nil {
+		return err
+	}
+	defer directReceiver.Terminate(1 * time.Second)
+	if err := directReceiver.ReceiveAsync(func(msg message.InboundMessage) {
+		payload, _ := msg.GetPayloadAsBytes()
+		fmt.Printf("  direct receiver received %q on %s\n", payload, msg.GetDestinationName())
+	}); err != nil {
+		return err
+	}
+
+	// the queue spools the persistent messages, the MQTT messages published at QoS 1
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithSubscriptions(subscriptions...).
+		Build(resource.QueueNonDurableExclusiveAnonymous())
+	if err != nil {
+		return err
+	}
+	if err := persistentReceiver.Start(); err != nil {
+		return err
+	}
+	defer persistentReceiver.Terminate(1 * time.Second)
+	if err := persistentReceiver.ReceiveAsync(func(msg message.InboundMessage) {
+		payload, _ := msg.GetPayloadAsBytes()
+		fmt.Printf("  queue received %q on %s\n", payload, msg.GetDestinationName())
+	}); err != nil {
+		return err
+	}
+
+	for i := 1; i <= count; i++ {
+		for _, qos := range []byte{0, 1} {
+			topic := fmt.Sprintf("%s/mqtt/from-mqtt/qos%d/%d", TopicPrefix, qos, i)
+			// a QoS 1 publication completes once the broker acknowledged it, a QoS 0 one once it is sent
+			if err := wait(client.Publish(topic, qos, false, fmt.Sprintf("QoS %d message %d", qos, i))); err != nil {
+				return fmt.Errorf("could not publish to %s: %w", topic, err)
+			}
+		}
+	}
+	// let the deliveries arrive before terminating the receivers
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+func main() {
+	direction := flag.String("direction", "both", "solace-to-mqtt, mqtt-to-solace or both")
+	count := flag.Int("count", 3, "messages published per delivery mode")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	client, err := ConnectMQTT(brokerConfig.Properties)
+	if err != nil {
+		messagingService.Disconnect()
+		panic(err)
+	}
+	fmt.Println("MQTT client connected? ", client.IsConnected())
+
+	if *direction == "both" || *direction == "solace-to-mqtt" {
+		fmt.Println("\n=== Go API publisher to MQTT subscriber ===")
+		if err := SolaceToMQTT(messagingService, client, *count); err != nil {
+			fmt.Println("Solace to MQTT failed: ", err)
+		}
+	}
+	if *direction == "both" || *direction == "mqtt-to-solace" {
+		fmt.Println("\n=== MQTT publisher to Go API receivers ===")
+		if err := MQTTToSolace(messagingService, client, *count); err != nil {
+			fmt.Println("MQTT to Solace failed: ", err)
+		}
+	}
+
+	client.Disconnect(250)
+	messagingService.Disconnect()
+	fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Multiple messaging services in one process: each messaging service is an independent connection with its own
+// configuration, publishers and receivers, and a process can create as many as it needs, e.g. to connect to
+// several message VPNs or brokers. This sample bridges topics from a source service to a target service:
+//
+//	SOLACE_HOST=tcp://broker-a:55555 SOLACE_VPN=east \
+//	SOLACE_TARGET_HOST=tcp://broker-b:55555 SOLACE_TARGET_VPN=west \
+//		go run multiple_services_bridge.go -topics "solace/samples/direct/>,solace/samples/orders/>"
+//
+// The SOLACE_TARGET_* variables default to the source ones. The services are started target first (nothing is
+// received before it can be forwarded) and stopped source first (nothing is received that can not be forwarded),
+// and when either one is interrupted for good both are stopped. Bridged messages carry the BridgedFrom property and
+// are never bridged again, which prevents loops when bridging both ways or within the same message VPN.
+
+// BridgedFrom - user property holding the message VPN a bridged message came from
+const BridgedFrom = "bridged-from"
+
+// ConnectService - builds and connects a messaging service, named after its role so both can be told apart on the
+// brokers
+func ConnectService(role string, brokerConfig config.ServicePropertyMap) (solace.MessagingService, error) {
+	hostname, _ := os.Hostname()
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig).
+		WithReconnectionRetryStrategy(config.RetryStrategyParameterizedRetry(20, 3*time.Second)).
+		BuildWithApplicationID(fmt.Sprintf("bridge-%s/%s/%d", role, hostname, os.Getpid()))
+	if err != nil {
+		return nil, err
+	}
+	if err := messagingService.Connect(); err != nil {
+		return nil, err
+	}
+	return messagingService, nil
+}
+
+// Forward - copies the payload and the properties of the received message to a new message for the target service
+func Forward(builder solace.OutboundMessageBuilder, inbound message.InboundMessage, sourceVPN string) (message.OutboundMessage, error) {
+	properties := config.MessagePropertyMap{}
+	for name, value := range inbound.GetProperties() {
+		properties[config.MessageProperty(name)] = value
+	}
+	properties[BridgedFrom] = sourceVPN
+	builder = builder.FromConfigurationProvider(properties)
+	if id, ok := inbound.GetApplicationMessageID(); ok {
+		builder = builder.WithApplicationMessageID(id)
+	}
+	if correlationID, ok := inbound.GetCorrelationID(); ok {
+		builder = builder.WithCorrelationID(correlationID)
+	}
+	payload, _ := inbound.GetPayloadAsBytes()
+	return builder.BuildWithByteArrayPayload(payload)
+}
+
+func main() {
+	topics := flag.String("topics", TopicPrefix+"/direct/>", "comma separated topic subscriptions to bridge")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters of both services
+	sourceConfig := config.ServicePropertyMap{
+		config.TransportLayerPropertyHost:                sampleconfig.Setting("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
+		config.ServicePropertyVPNName:                    sampleconfig.Setting("SOLACE_VPN", "default"),
+		config.AuthenticationPropertySchemeBasicPassword: sampleconfig.Setting("SOLACE_PASSWORD", "default"),
+		config.AuthenticationPropertySchemeBasicUserName: sampleconfig.Setting("SOLACE_USERNAME", "default"),
+	}
+	targetConfig := config.ServicePropertyMap{}
+	for property, env := range map[config.ServiceProperty]string{
+		config.TransportLayerPropertyHost:                "SOLACE_TARGET_HOST",
+		config.ServicePropertyVPNName:                    "SOLACE_TARGET_VPN",
+		config.AuthenticationPropertySchemeBasicPassword: "SOLACE_TARGET_PASSWORD",
+		config.AuthenticationPropertySchemeBasicUserName: "SOLACE_TARGET_USERNAME",
+	} {
+		targetConfig[property] = sampleconfig.Setting(env, sourceConfig[property].(string))
+	}
+	sourceVPN := sourceConfig[config.ServicePropertyVPNName].(string)
+
+	// With -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), wait for both brokers to be ready before connecting
+	for _, brokerConfig := range []config.ServicePropertyMap{targetConfig, sourceConfig} {
+		if err := sampleconfig.WaitForBroker(context.Background(), brokerConfig); err != nil {
+			panic(err)
+		}
+	}
+
+	// Target first: a publisher ready to forward
+	targetService, err := ConnectService("target", targetConfig)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the target broker? ", targetService.IsConnected())
+	targetPublisher, err := targetService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := targetPublisher.Start(); err != nil {
+		panic(err)
+	}
+
+	// Then the source
+	sourceService, err := ConnectService("source", sourceConfig)
+	if err != nil {
+		targetPublisher.Terminate(1 * time.Second)
+		targetService.Disconnect()
+		panic(err)
+	}
+	fmt.Println("Connected to the source broker? ", sourceService.IsConnected())
+
+	var subscriptions []resource.Subscription
+	for _, topic := range strings.Split(*topics, ",") {
+		subscriptions = append(subscriptions, resource.TopicSubscriptionOf(strings.TrimSpace(topic)))
+	}
+	sourceReceiver, err := sourceService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(subscriptions...).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	var forwarded, skipped uint64
+	if err := sourceReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := sourceReceiver.ReceiveAsync(func(inbound message.InboundMessage) {
+		if _, bridged := inbound.GetProperty(BridgedFrom); bridged {
+			atomic.AddUint64(&skipped, 1)
+			return
+		}
+		// a new builder per message, a builder keeps the properties of the messages built before
+		outbound, err := Forward(targetService.MessageBuilder(), inbound, sourceVPN)
+		if err != nil {
+			fmt.Println("Could not copy the message: ", err)
+			return
+		}
+		if err := targetPublisher.Publish(outbound, resource.TopicOf(inbound.GetDestinationName())); err != nil {
+			fmt.Println("Could not forward the message: ", err)
+			return
+		}
+		atomic.AddUint64(&forwarded, 1)
+	}); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Printf("\n Bridging %s from %s to %s\n", *topics, sourceVPN, targetConfig[config.ServicePropertyVPNName])
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the bridge===")
+
+	// Either service giving up stops the bridge
+	interrupted := make(chan string, 2)
+	for role, service := range map[string]solace.MessagingService{"source": sourceService, "target": targetService} {
+		role := role
+		service.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+			interrupted <- fmt.Sprintf("%s service interrupted: %v", role, event.GetCause())
+		})
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Printf("forwarded=%d skipped=%d\n", atomic.LoadUint64(&forwarded), atomic.LoadUint64(&skipped))
+		case reason := <-interrupted:
+			fmt.Printf("%s, stopping the bridge\n", reason)
+			break wait
+		case <-c:
+			break wait
+		}
+	}
+
+	// Source first, then flush what was received to the target
+	sourceReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nSource Receiver Terminated? ", sourceReceiver.IsTerminated())
+	sourceService.Disconnect()
+	fmt.Println("Source Messaging Service Disconnected? ", !sourceService.IsConnected())
+	targetPublisher.Terminate(5 * time.Second)
+	fmt.Println("Target Publisher Terminated? ", targetPublisher.IsTerminated())
+	targetService.Disconnect()
+	fmt.Println("Target Messaging Service Disconnected? ", !targetService.IsConnected())
+	fmt.Printf("forwarded=%d skipped=%d\n", atomic.LoadUint64(&forwarded), atomic.LoadUint64(&skipped))
+}
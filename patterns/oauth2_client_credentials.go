@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// OAuth2 client credentials: the sample obtains an access token from the authorization server's token endpoint,
+// connects with it and keeps refreshing it before it expires. The refreshed token is set on the live messaging
+// service with UpdateProperty, the broker validates it the next time the API (re)connects, so a long running
+// consumer reconnecting after the original token expired does not get rejected.
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 \
+//	SOLACE_OAUTH_TOKEN_URL=https://idp.example.com/oauth2/token \
+//	SOLACE_OAUTH_CLIENT_ID=my-client SOLACE_OAUTH_CLIENT_SECRET=my-secret SOLACE_OAUTH_SCOPE=solace \
+//		go run oauth2_client_credentials.go
+//
+// The message VPN needs an OAuth profile matching the authorization server, SOLACE_OAUTH_ISSUER selects it when
+// the VPN has more than one.
+
+// AccessToken - an access token and the time it expires
+type AccessToken struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+// ClientCredentials - fetches access tokens with the OAuth2 client credentials grant (RFC 6749 section 4.4)
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+	HTTPClient   *http.Client
+}
+
+// Token - requests a new access token from the token endpoint
+func (c *ClientCredentials) Token(ctx context.Context) (AccessToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if c.Scope != "" {
+		form.Set("scope", c.Scope)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return AccessToken{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return AccessToken{}, fmt.Errorf("token endpoint returned %s: %s", response.Status, body)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return AccessToken{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return AccessToken{}, fmt.Errorf("token response holds no access token")
+	}
+	if token.ExpiresIn <= 0 {
+		// expiry not reported, assume the common default of one hour
+		token.ExpiresIn = 3600
+	}
+	return AccessToken{Value: token.AccessToken, ExpiresAt: time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)}, nil
+}
+
+// refreshDelay - refresh once 80% of the token lifetime elapsed, leaving time to retry failed refreshes
+func refreshDelay(token AccessToken) time.Duration {
+	delay := time.Until(token.ExpiresAt) * 8 / 10
+	if delay < 5*time.Second {
+		delay = 5 * time.Second
+	}
+	return delay
+}
+
+// RefreshTokens - keeps fetching new access tokens before the current one expires and updates the messaging service
+// with them, until the context is canceled. Failed refreshes are retried with a capped backoff.
+func RefreshTokens(ctx context.Context, messagingService solace.MessagingService, credentials *ClientCredentials, current AccessToken) {
+	retryInterval := time.Second
+	wait := refreshDelay(current)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		token, err := credentials.Token(ctx)
+		if err == nil {
+			err = messagingService.UpdateProperty(config.AuthenticationPropertySchemeOAuth2AccessToken, token.Value)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("Token refresh failed, retrying in %s (current token expires at %s): %s\n",
+				retryInterval, current.ExpiresAt.Format(time.RFC3339), err)
+			wait = retryInterval
+			if retryInterval *= 2; retryInterval > time.Minute {
+				retryInterval = time.Minute
+			}
+			continue
+		}
+
+		fmt.Printf("Access token refreshed, expires at %s\n", token.ExpiresAt.Format(time.RFC3339))
+		current = token
+		retryInterval = time.Second
+		wait = refreshDelay(current)
+	}
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	credentials := &ClientCredentials{
+		TokenURL:     sampleconfig.Setting("SOLACE_OAUTH_TOKEN_URL", "http://localhost:8080/oauth2/token"),
+		ClientID:     sampleconfig.Setting("SOLACE_OAUTH_CLIENT_ID", "solace-samples"),
+		ClientSecret: sampleconfig.Setting("SOLACE_OAUTH_CLIENT_SECRET", ""),
+		Scope:        sampleconfig.Setting("SOLACE_OAUTH_SCOPE", ""),
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	token, err := credentials.Token(ctx)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Access token obtained, expires at %s\n", token.ExpiresAt.Format(time.RFC3339))
+
+	// Configuration parameters, OAuth authentication requires a tcps:// connection
+	brokerConfig := config.ServicePropertyMap{
+		config.TransportLayerPropertyHost: sampleconfig.Setting("SOLACE_HOST", "tcps://localhost:55443"),
+		config.ServicePropertyVPNName:     sampleconfig.Setting("SOLACE_VPN", "default"),
+	}
+
+	// With -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), wait for the broker to be ready before connecting
+	if err := sampleconfig.WaitForBroker(ctx, brokerConfig); err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig).
+		WithAuthenticationStrategy(config.OAuth2Authentication(token.Value, "", sampleconfig.Setting("SOLACE_OAUTH_ISSUER", ""))).
+		WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(3 * time.Second)).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Keep the token fresh for the lifetime of the messaging service
+	go RefreshTokens(ctx, messagingService, credentials, token)
+
+	// Build a Direct Message Receiver on all of the sample topics
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/>")).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Receiver
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	// Register Message callback handler to the Message Receiver
+	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Stop refreshing the token
+	cancel()
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"github.com/fsnotify/fsnotify"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+	"solace.dev/go/messaging/pkg/solace/subcode"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// OIDC ID token read from a file, e.g. a Kubernetes projected service account token:
+//
+//	volumes:
+//	- name: solace-token
+//	  projected:
+//	    sources:
+//	    - serviceAccountToken:
+//	        path: solace-token
+//	        audience: solace
+//	        expirationSeconds: 3600
+//
+// The kubelet rotates the token before it expires. The file is watched with fsnotify and every new token is set on
+// the live messaging service, so the API presents a valid token whenever it reconnects. If the broker still rejects
+// the token (e.g. the service reconnected before the new token was written), the messaging service is re-created
+// as soon as a fresh token is available.
+
+// TokenFile - the latest token read from a watched file
+type TokenFile struct {
+	path    string
+	mu      sync.Mutex
+	token   string
+	changed chan struct{}
+}
+
+// NewTokenFile - reads the token file once, it must exist
+func NewTokenFile(path string) (*TokenFile, error) {
+	t := &TokenFile{path: path, changed: make(chan struct{}, 1)}
+	if _, err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Current - returns the latest token
+func (t *TokenFile) Current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.token
+}
+
+// Changed - receives a value when the token changed since it was last received
+func (t *TokenFile) Changed() <-chan struct{} {
+	return t.changed
+}
+
+// reload - reads the token file and reports whether the token changed
+func (t *TokenFile) reload() (bool, error) {
+	contents, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return false, err
+	}
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return false, fmt.Errorf("token file '%s' is empty", t.path)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if token == t.token {
+		return false, nil
+	}
+	t.token = token
+	return true, nil
+}
+
+// Watch - watches the directory of the token file until done is closed. The directory is watched rather than the
+// file: projected volumes replace the file through a symlink swap, which a watch on the file itself does not survive.
+func (t *TokenFile) Watch(done <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(t.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-done:
+				return
+			case err := <-watcher.Errors:
+				fmt.Println("Token file watch error: ", err)
+			case event := <-watcher.Events:
+				if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+					continue
+				}
+				changed, err := t.reload()
+				if err != nil {
+					// the file may be caught in the middle of the swap, the next event reloads it
+					continue
+				}
+				if changed {
+					select {
+					case t.changed <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// ConnectWithToken - builds and connects a messaging service authenticating with the OIDC ID token,
+// and starts a direct receiver on the sample topics
+func ConnectWithToken(brokerConfig config.ServicePropertyMap, token string) (solace.MessagingService, solace.DirectMessageReceiver, error) {
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig).
+		WithAuthenticationStrategy(config.OAuth2Authentication("", token, sampleconfig.Setting("SOLACE_OAUTH_ISSUER", ""))).
+		WithReconnectionRetryStrategy(config.RetryStrategyParameterizedRetry(10, 3*time.Second)).
+		Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := messagingService.Connect(); err != nil {
+		return nil, nil, err
+	}
+
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/>")).
+		Build()
+	if err == nil {
+		err = directReceiver.Start()
+	}
+	if err == nil {
+		err = directReceiver.ReceiveAsync(MessageHandler)
+	}
+	if err != nil {
+		messagingService.Disconnect()
+		return nil, nil, err
+	}
+	return messagingService, directReceiver, nil
+}
+
+// isLoginFailure - reports whether the broker rejected the credentials, i.e. the token is stale or invalid
+func isLoginFailure(err error) bool {
+	var nativeErr *solace.NativeError
+	return errors.As(err, &nativeErr) && nativeErr.SubCode() == subcode.LoginFailure
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	tokenFile, err := NewTokenFile(sampleconfig.Setting("SOLACE_OIDC_TOKEN_FILE", "/var/run/secrets/tokens/solace-token"))
+	if err != nil {
+		panic(err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	if err := tokenFile.Watch(done); err != nil {
+		panic(err)
+	}
+
+	// Configuration parameters, OAuth authentication requires a tcps:// connection
+	brokerConfig := config.ServicePropertyMap{
+		config.TransportLayerPropertyHost: sampleconfig.Setting("SOLACE_HOST", "tcps://localhost:55443"),
+		config.ServicePropertyVPNName:     sampleconfig.Setting("SOLACE_VPN", "default"),
+	}
+
+	// With -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), wait for the broker to be ready before connecting
+	if err := sampleconfig.WaitForBroker(context.Background(), brokerConfig); err != nil {
+		panic(err)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	for {
+		messagingService, directReceiver, err := ConnectWithToken(brokerConfig, tokenFile.Current())
+		if err != nil {
+			if !isLoginFailure(err) {
+				panic(err)
+			}
+			// The token was rejected, wait for the next rotation (or retry after a while) instead of hammering the broker
+			fmt.Println("Token rejected by the broker, waiting for a new token: ", err)
+			select {
+			case <-tokenFile.Changed():
+			case <-time.After(30 * time.Second):
+			case <-c:
+				return
+			}
+			continue
+		}
+
+		fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+		interrupted := make(chan error, 1)
+		messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+			interrupted <- event.GetCause()
+		})
+
+	connected:
+		for {
+			select {
+			case <-tokenFile.Changed():
+				// Used by the API on its next (re)connection, the current session stays up
+				if err := messagingService.UpdateProperty(config.AuthenticationPropertySchemeOAuth2OIDCIDToken, tokenFile.Current()); err != nil {
+					fmt.Println("Could not update the token: ", err)
+				} else {
+					fmt.Println("Token rotated, messaging service updated")
+				}
+			case cause := <-interrupted:
+				// Reconnection gave up, most likely because the broker rejected a stale token: start over
+				fmt.Println("Messaging service interrupted, reconnecting with the latest token: ", cause)
+				directReceiver.Terminate(0)
+				messagingService.Disconnect()
+				break connected
+			case <-c:
+				// Terminate the Direct Receiver
+				directReceiver.Terminate(1 * time.Second)
+				fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+				// Disconnect the Message Service
+				messagingService.Disconnect()
+				fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+				return
+			}
+		}
+	}
+}
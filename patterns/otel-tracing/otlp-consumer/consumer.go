@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/otelmetrics"
+	"SolaceSamples.com/PubSub+Go/pkg/propagation"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+
+	// Dependency below is for Solace PubSub+ OTel integration:
+	solpropagation "solace.dev/go/messaging-trace/opentelemetry"
+)
+
+// Distributed tracing over OTLP, the consumer side of ../otlp-publisher: the trace context is extracted from the
+// received message, from the Solace creation/transport context or else from the traceparent/tracestate user
+// properties, and the message is received and processed in child spans of the publish span. The spans, and the
+// metrics of the consumer (see pkg/otelmetrics), are exported to the OTLP/HTTP endpoint set with
+// OTEL_EXPORTER_OTLP_ENDPOINT (http://localhost:4318 by default):
+//
+//	go run ./patterns/otel-tracing/otlp-consumer
+//
+// Open the trace printed for each message in the tracing backend (e.g. Jaeger on http://localhost:16686) to see the
+// publish, receive and process spans of both services in one trace.
+
+// TopicPrefix - Define Topic Prefix
+const TopicPrefix = "solace/samples/otel-tracing/otlp"
+
+// InitTracing - registers a tracer provider exporting over OTLP/HTTP and the W3C propagators, shut the provider down
+// before exiting to flush the spans
+func InitTracing(ctx context.Context, serviceName string) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	traceProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(traceProvider)
+	// Solace supports the W3C trace context format
+	otel.SetTextMapPropagator(otelpropagation.NewCompositeTextMapPropagator(otelpropagation.TraceContext{}, otelpropagation.Baggage{}))
+	return traceProvider, nil
+}
+
+// ExtractContext - returns the context carrying the trace context of the publisher and where it was found
+func ExtractContext(inbound message.InboundMessage) (context.Context, string) {
+	propagator := otel.GetTextMapPropagator()
+	ctx := propagator.Extract(context.Background(), solpropagation.NewInboundMessageCarrier(inbound))
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, "solace"
+	}
+	// traceparent, tracestate and baggage user properties (see pkg/propagation)
+	ctx = propagation.Extract(context.Background(), inbound)
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, "properties"
+	}
+	// a new trace, the message was published without trace context
+	return ctx, "none"
+}
+
+// Process - the processing of the application, traced in its own span
+func Process(ctx context.Context, inbound message.InboundMessage) error {
+	_, span := otel.Tracer("otlp-consumer").Start(ctx, "process")
+	defer span.End()
+
+	payload, _ := inbound.GetPayloadAsString()
+	span.SetAttributes(attribute.Int("payload.length", len(payload)))
+	// simulated work
+	time.Sleep(10 * time.Millisecond)
+	fmt.Printf("Processed message %s\n", payload)
+	return nil
+}
+
+// NewMessageHandler - a message handler continuing the trace of the publisher with a receive span, and a process
+// span below it
+func NewMessageHandler(counters *otelmetrics.Counters) solace.MessageHandler {
+	tracer := otel.Tracer("otlp-consumer", trace.WithInstrumentationVersion(solpropagation.Version()))
+	return func(inbound message.InboundMessage) {
+		parent, found := ExtractContext(inbound)
+
+		ctx, span := tracer.Start(parent, inbound.GetDestinationName()+" receive",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				semconv.MessagingSystem("PubSub+"),
+				semconv.MessagingDestinationKindTopic,
+				semconv.MessagingDestinationName(inbound.GetDestinationName()),
+				semconv.MessagingOperationReceive,
+				attribute.String("messaging.trace_context.carrier", found),
+			))
+		defer span.End()
+		if id, ok := inbound.GetApplicationMessageID(); ok {
+			span.SetAttributes(semconv.MessagingMessageID(id))
+		}
+
+		counters.Received.Add(ctx, 1)
+		start := time.Now()
+		if err := Process(ctx, inbound); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "processing failed")
+		}
+		counters.ProcessingDuration.Record(ctx, time.Since(start).Seconds())
+		fmt.Printf("  trace %s (context from %s)\n", span.SpanContext().TraceID(), found)
+	}
+}
+
+func main() {
+	serviceName := sampleconfig.Setting("OTEL_SERVICE_NAME", "solace-otlp-consumer")
+	traceProvider, err := InitTracing(context.Background(), serviceName)
+	if err != nil {
+		panic(err)
+	}
+	meterProvider, err := otelmetrics.NewMeterProvider(context.Background(), serviceName, 10*time.Second)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		// flush the spans still batched and export the last metric values
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := traceProvider.Shutdown(ctx); err != nil {
+			fmt.Println("Could not flush the spans: ", err)
+		}
+		if err := meterProvider.Shutdown(ctx); err != nil {
+			fmt.Println("Could not export the metrics: ", err)
+		}
+	}()
+	meter := meterProvider.Meter(serviceName)
+	counters, err := otelmetrics.NewCounters(meter)
+	if err != nil {
+		panic(err)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// API metrics of the service, exported with the counters of the sample
+	if _, err := otelmetrics.ObserveService(meter, "consumer", messagingService); err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	//  Build a Direct Message Receiver
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix)).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Receiver
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	if regErr := directReceiver.ReceiveAsync(NewMessageHandler(counters)); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/otelmetrics"
+	"SolaceSamples.com/PubSub+Go/pkg/propagation"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+
+	// Dependency below is for Solace PubSub+ OTel integration:
+	solpropagation "solace.dev/go/messaging-trace/opentelemetry"
+)
+
+// Distributed tracing over OTLP: the publisher starts a producer span per message and propagates its W3C trace
+// context with the message, the consumer (../otlp-consumer) continues the trace with child spans, so a trace spans
+// the broker hop. Both export their spans, and their metrics (see pkg/otelmetrics), to an OTLP/HTTP endpoint, e.g.
+// an OpenTelemetry collector, or a Jaeger all-in-one for the spans only:
+//
+//	docker run -d -p 16686:16686 -p 4318:4318 jaegertracing/all-in-one
+//	go run ./patterns/otel-tracing/otlp-consumer
+//	go run ./patterns/otel-tracing/otlp-publisher -count 10
+//
+// The endpoint is set with the standard OTEL_EXPORTER_OTLP_ENDPOINT variable (http://localhost:4318 by default),
+// see the otlptracehttp package for the other OTEL_EXPORTER_OTLP_* variables.
+//
+// With -carrier solace (default) the trace context is set as the creation and transport context of the message by the
+// Solace PubSub+ OTel integration, the broker can then add its own spans. With -carrier properties it is set as the
+// traceparent/tracestate user properties instead, readable by any consumer.
+
+// PublishTopicName - topic the traced messages are published on
+const PublishTopicName = "solace/samples/otel-tracing/otlp"
+
+// InitTracing - registers a tracer provider exporting over OTLP/HTTP and the W3C propagators, shut the provider down
+// before exiting to flush the spans
+func InitTracing(ctx context.Context, serviceName string) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	traceProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(traceProvider)
+	// Solace supports the W3C trace context format
+	otel.SetTextMapPropagator(otelpropagation.NewCompositeTextMapPropagator(otelpropagation.TraceContext{}, otelpropagation.Baggage{}))
+	return traceProvider, nil
+}
+
+// PublishTraced - builds and publishes a message within a producer span, the trace context of the span travels with
+// the message
+func PublishTraced(messagingService solace.MessagingService, publisher solace.DirectMessagePublisher, counters *otelmetrics.Counters, carrier string, body string, msgSeqNum int) error {
+	tracer := otel.Tracer("otlp-publisher", trace.WithInstrumentationVersion(solpropagation.Version()))
+	ctx, span := tracer.Start(context.Background(), PublishTopicName+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("PubSub+"),
+			semconv.MessagingDestinationKindTopic,
+			semconv.MessagingDestinationName(PublishTopicName),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingMessageID(strconv.Itoa(msgSeqNum)),
+		))
+	defer span.End()
+
+	// a new builder per message, a builder keeps the properties of the messages built before
+	builder := messagingService.MessageBuilder().WithApplicationMessageID(strconv.Itoa(msgSeqNum))
+	var outMessage message.OutboundMessage
+	var err error
+	switch carrier {
+	case "properties":
+		// traceparent, tracestate and baggage as user properties (see pkg/propagation)
+		outMessage, err = builder.FromConfigurationProvider(propagation.Properties(ctx)).BuildWithStringPayload(body)
+	case "solace":
+		outMessage, err = builder.BuildWithStringPayload(body)
+		if err == nil {
+			messageCarrier := solpropagation.NewOutboundMessageCarrier(outMessage)
+			// the first call sets the creation context, the second one the transport context
+			otel.GetTextMapPropagator().Inject(ctx, messageCarrier)
+			otel.GetTextMapPropagator().Inject(ctx, messageCarrier)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := publisher.Publish(outMessage, resource.TopicOf(PublishTopicName)); err != nil {
+		span.RecordError(err)
+		counters.PublishFailures.Add(ctx, 1)
+		return err
+	}
+	counters.Published.Add(ctx, 1)
+	span.SetAttributes(semconv.MessagingMessagePayloadSizeBytes(len(body)))
+	fmt.Printf("Published message %d in trace %s\n", msgSeqNum, span.SpanContext().TraceID())
+	return nil
+}
+
+func main() {
+	carrier := flag.String("carrier", "solace", "how the trace context travels with the message: solace or properties")
+	count := flag.Int("count", 0, "number of messages to publish, 0 to publish until interrupted")
+	interval := flag.Duration("interval", 1*time.Second, "time between two messages")
+	flag.Parse()
+	if *carrier != "solace" && *carrier != "properties" {
+		fmt.Fprintln(os.Stderr, "-carrier must be solace or properties")
+		os.Exit(2)
+	}
+
+	serviceName := sampleconfig.Setting("OTEL_SERVICE_NAME", "solace-otlp-publisher")
+	traceProvider, err := InitTracing(context.Background(), serviceName)
+	if err != nil {
+		panic(err)
+	}
+	meterProvider, err := otelmetrics.NewMeterProvider(context.Background(), serviceName, 10*time.Second)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		// flush the spans still batched and export the last metric values
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := traceProvider.Shutdown(ctx); err != nil {
+			fmt.Println("Could not flush the spans: ", err)
+		}
+		if err := meterProvider.Shutdown(ctx); err != nil {
+			fmt.Println("Could not export the metrics: ", err)
+		}
+	}()
+	meter := meterProvider.Meter(serviceName)
+	counters, err := otelmetrics.NewCounters(meter)
+	if err != nil {
+		panic(err)
+	}
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// API metrics of the service, exported with the counters of the sample
+	if _, err := otelmetrics.ObserveService(meter, "publisher", messagingService); err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	//  Build a Direct Message Publisher
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Publisher
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Publisher running? ", directPublisher.IsRunning())
+	fmt.Println("\n===Interrupt (CTR+C) to stop publishing===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+publish:
+	for msgSeqNum := 1; *count == 0 || msgSeqNum <= *count; msgSeqNum++ {
+		body := "Hello from Go OTLP Tracing Publisher Sample --> " + strconv.Itoa(msgSeqNum)
+		if err := PublishTraced(messagingService, directPublisher, counters, *carrier, body, msgSeqNum); err != nil {
+			fmt.Println("Publish failed: ", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-c:
+			break publish
+		}
+	}
+
+	// Terminate the Direct Publisher
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
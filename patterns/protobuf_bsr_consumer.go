@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/bufregistry"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/payloaddecode"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Protobuf consumer with the Buf Schema Registry: decodes protobuf payloads of any type generically, the type named
+// by the message, in the messageType parameter of its content type or its application message type, and resolved
+// from a module of the registry the first time it is received (see pkg/bufregistry), then prints them as JSON. A
+// publisher of a new type, or a new version of a type, needs no new build of the consumer.
+//
+//	BUF_TOKEN=... go run protobuf_bsr_consumer.go -module buf.build/connectrpc/eliza
+//	BUF_TOKEN=... go run protobuf_bsr_publisher.go
+//
+// The token of the registry is read from SOLACE_BUF_TOKEN, or BUF_TOKEN as with the buf CLI.
+
+// ProtobufHandler - resolves the type of the message, decodes and prints it
+func ProtobufHandler(resolver *bufregistry.Resolver) solace.MessageHandler {
+	return func(inbound message.InboundMessage) {
+		hints := payloaddecode.HintsOf(inbound)
+		if hints.MessageType == "" {
+			fmt.Printf("Skipped a message of %s without a message type\n", inbound.GetDestinationName())
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		descriptor, err := resolver.Resolve(ctx, hints.MessageType)
+		if err != nil {
+			fmt.Printf("Skipped a message of %s: %s\n", inbound.GetDestinationName(), err)
+			return
+		}
+		payload, _ := inbound.GetPayloadAsBytes()
+		decoded := dynamicpb.NewMessage(descriptor)
+		if err := proto.Unmarshal(payload, decoded); err != nil {
+			fmt.Printf("Skipped a message of %s, not a %s: %s\n", inbound.GetDestinationName(), descriptor.FullName(), err)
+			return
+		}
+		fmt.Printf("Received %s on %s: %s\n", descriptor.FullName(), inbound.GetDestinationName(), protojson.Format(decoded))
+	}
+}
+
+func main() {
+	module := flag.String("module", "buf.build/connectrpc/eliza", "module of the Buf Schema Registry defining the message types")
+	version := flag.String("version", "", "label, tag or commit of the module, the latest commit when empty")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	resolver := bufregistry.NewResolver(bufregistry.New(sampleconfig.Setting("SOLACE_BUF_TOKEN", os.Getenv("BUF_TOKEN"))), *module, *version)
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/protobuf/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	if err := directReceiver.ReceiveAsync(ProtobufHandler(resolver)); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("\n Resolving the message types from %s\n", *module)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
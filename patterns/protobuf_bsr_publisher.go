@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/bufregistry"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Protobuf publisher with the Buf Schema Registry: resolves the message type from a module of the registry at runtime
+// (see pkg/bufregistry), builds the message from a JSON document, then publishes it every second in the protobuf
+// binary encoding, the type named in the content type, application/x-protobuf; messageType=<full name>. No code is
+// generated: any type of any module is published by changing the flags.
+//
+//	BUF_TOKEN=... go run protobuf_bsr_consumer.go
+//	BUF_TOKEN=... go run protobuf_bsr_publisher.go -module buf.build/connectrpc/eliza \
+//	  -type connectrpc.eliza.v1.SayRequest -json '{"sentence": "Hello from Solace"}'
+//
+// The token of the registry is read from SOLACE_BUF_TOKEN, or BUF_TOKEN as with the buf CLI.
+
+func main() {
+	module := flag.String("module", "buf.build/connectrpc/eliza", "module of the Buf Schema Registry defining the message type")
+	version := flag.String("version", "", "label, tag or commit of the module, the latest commit when empty")
+	messageType := flag.String("type", "connectrpc.eliza.v1.SayRequest", "full name of the message type")
+	document := flag.String("json", `{"sentence": "Hello from Solace"}`, "the message, in the JSON mapping of protobuf")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	resolver := bufregistry.NewResolver(bufregistry.New(sampleconfig.Setting("SOLACE_BUF_TOKEN", os.Getenv("BUF_TOKEN"))), *module, *version)
+	descriptor, err := resolver.Resolve(context.Background(), *messageType)
+	if err != nil {
+		panic(err)
+	}
+	// the JSON document is checked against the type before anything is published
+	msgTemplate := dynamicpb.NewMessage(descriptor)
+	if err := protojson.Unmarshal([]byte(*document), msgTemplate); err != nil {
+		panic(fmt.Sprintf("the JSON document is not a %s: %s", descriptor.FullName(), err))
+	}
+	payload, err := proto.Marshal(msgTemplate)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Resolved %s from %s, %d bytes per message\n", descriptor.FullName(), *module, len(payload))
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Publisher running? ", directPublisher.IsRunning())
+
+	// The consumers read the message type from the content type, see pkg/payloaddecode HintsOf
+	contentType := "application/x-protobuf; messageType=" + string(descriptor.FullName())
+	topic := resource.TopicOf(TopicPrefix + "/protobuf/" + string(descriptor.Name()))
+
+	go func() {
+		for msgSeqNum := 1; directPublisher.IsReady(); msgSeqNum++ {
+			msg, err := messagingService.MessageBuilder().
+				WithHTTPContentHeader(contentType, "").
+				BuildWithByteArrayPayload(payload)
+			if err != nil {
+				panic(err)
+			}
+			if err := directPublisher.Publish(msg, topic); err != nil {
+				panic(err)
+			}
+			fmt.Printf("Published %s #%d on %s\n", descriptor.FullName(), msgSeqNum, topic.GetName())
+			time.Sleep(1 * time.Second)
+		}
+	}()
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/promexporter"
+	"SolaceSamples.com/PubSub+Go/pkg/pubgauge"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Publisher readiness: publishes as fast as it can through a publisher with a small buffer rejecting the publish calls
+// once full (OnBackPressureReject). On a PublisherOverflowError the producer loop stops and waits for the readiness
+// listener set with SetPublisherReadinessListener, rather than spinning on IsReady or dropping messages. The gauge of
+// pkg/pubgauge prints the buffer utilization and the readiness every second, and serves them to Prometheus on /metrics
+// when SOLACE_METRICS_ADDR is set, so back pressure shows before publishes fail:
+//
+//	go run publisher_readiness.go -buffer 50 -size 65536
+//	go run publisher_readiness.go -direct -buffer 10
+//	SOLACE_METRICS_ADDR=:2112 go run publisher_readiness.go && curl -s localhost:2112/metrics | grep solace_publisher
+
+// ReadinessPublisher - the calls shared by the tracked direct and persistent publishers
+type ReadinessPublisher interface {
+	PublishBytes(message []byte, destination *resource.Topic) error
+	SetPublisherReadinessListener(listener solace.PublisherReadinessListener)
+	Terminate(gracePeriod time.Duration) error
+	IsTerminated() bool
+}
+
+func main() {
+	direct := flag.Bool("direct", false, "publish direct messages rather than persistent ones")
+	buffer := flag.Int("buffer", 50, "capacity of the publish buffer, the publish calls are rejected once it is full")
+	size := flag.Int("size", 1024, "payload size in bytes")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// The gauge of the publish buffers, served with the API metrics when SOLACE_METRICS_ADDR is set, e.g. :2112
+	gauge := pubgauge.New()
+	if address := sampleconfig.Setting("SOLACE_METRICS_ADDR", ""); address != "" {
+		exporter := promexporter.New()
+		exporter.Registry().MustRegister(gauge)
+		exporter.AddService("publisher", messagingService)
+		server, err := exporter.ListenAndServe(address)
+		if err != nil {
+			panic(err)
+		}
+		defer server.Close()
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	var publisher ReadinessPublisher
+	topic := resource.TopicOf(TopicPrefix + "/persistent/pub/readiness")
+	if *direct {
+		directPublisher, builderErr := messagingService.CreateDirectMessagePublisherBuilder().
+			OnBackPressureReject(uint(*buffer)).
+			Build()
+		if builderErr != nil {
+			panic(builderErr)
+		}
+		if startErr := directPublisher.Start(); startErr != nil {
+			panic(startErr)
+		}
+		publisher = gauge.Direct("readiness", directPublisher, *buffer)
+		topic = resource.TopicOf(TopicPrefix + "/direct/pub/readiness")
+	} else {
+		persistentPublisher, builderErr := messagingService.CreatePersistentMessagePublisherBuilder().
+			OnBackPressureReject(uint(*buffer)).
+			Build()
+		if builderErr != nil {
+			panic(builderErr)
+		}
+		if startErr := persistentPublisher.Start(); startErr != nil {
+			panic(startErr)
+		}
+		publisher = gauge.Persistent("readiness", persistentPublisher, *buffer)
+	}
+
+	// The readiness listener is called once the publisher is ready again after a publish call was rejected, it must
+	// not block the API: it only wakes up the producer loop
+	ready := make(chan struct{}, 1)
+	publisher.SetPublisherReadinessListener(func() {
+		select {
+		case ready <- struct{}{}:
+		default:
+		}
+	})
+
+	fmt.Printf("Publishing %d byte messages on %s with a buffer of %d\n", *size, topic.GetName(), *buffer)
+	fmt.Println("\n===Interrupt (CTR+C) to stop publishing===")
+
+	payload := make([]byte, *size)
+	var published, waits int64
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			err := publisher.PublishBytes(payload, topic)
+			var overflow *solace.PublisherOverflowError
+			var illegalState *solace.IllegalStateError
+			switch {
+			case err == nil:
+				atomic.AddInt64(&published, 1)
+			case errors.As(err, &overflow):
+				// the buffer is full, wait to be told there is room rather than retrying right away
+				atomic.AddInt64(&waits, 1)
+				select {
+				case <-ready:
+				case <-stop:
+					return
+				}
+			case errors.As(err, &illegalState):
+				return
+			default:
+				fmt.Println("Publish failed: ", err)
+			}
+		}
+	}()
+
+	// Handle OS interrupts
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var last int64
+report:
+	for {
+		select {
+		case <-ticker.C:
+			total := atomic.LoadInt64(&published)
+			for _, stats := range gauge.Stats() {
+				fmt.Printf("published=%d rate=%d msg/s ready=%t unacknowledged=%d utilization=%.0f%% overflows=%d waits=%d not-ready=%s\n",
+					total, total-last, stats.Ready, stats.Unacknowledged, 100*stats.Utilization, stats.Overflows,
+					atomic.LoadInt64(&waits), stats.NotReady.Round(time.Millisecond))
+			}
+			last = total
+		case <-c:
+			break report
+		}
+	}
+
+	close(stop)
+	<-done
+	// Terminate the Publisher
+	publisher.Terminate(1 * time.Second)
+	fmt.Println("\nPublisher Terminated? ", publisher.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
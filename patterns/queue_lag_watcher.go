@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/promexporter"
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Queue lag watcher: a slow consumer of a durable queue, watched through the SEMP v2 monitor API of the broker (see
+// pkg/queuelag). The watcher polls the messages spooled on the queue, computes the lag from the processing rate of the
+// handler, and warns when the lag crosses -max-lag and when it recovers. Publish to solace/samples/persistent/... faster
+// than -processing-time allows (e.g. with guaranteed_publisher.go) to see the lag grow:
+//
+//	SOLACE_SEMP_URL=http://localhost:8080 SOLACE_SEMP_USERNAME=admin SOLACE_SEMP_PASSWORD=admin \
+//	  go run queue_lag_watcher.go -queue durable-queue -processing-time 50ms -max-lag 30s
+//
+// With SOLACE_METRICS_ADDR set, the lag is also served with the API metrics on /metrics, as solace_queue_lag_seconds.
+
+// SlowMessageHandler - processes a message in the given time
+func SlowMessageHandler(processingTime time.Duration) func(message.InboundMessage) {
+	return func(message message.InboundMessage) {
+		time.Sleep(processingTime)
+	}
+}
+
+// PrintLagEvent - reports a queue crossing the thresholds
+func PrintLagEvent(event queuelag.Event) {
+	lag := event.Lag.Round(time.Second).String()
+	if event.Stalled {
+		lag = "infinite, the queue is not processed"
+	}
+	if event.Exceeded {
+		fmt.Printf("WARNING queue %s is falling behind: %d message(s) spooled, lag %s\n", event.Queue, event.Spooled, lag)
+	} else {
+		fmt.Printf("Queue %s caught up: %d message(s) spooled, lag %s\n", event.Queue, event.Spooled, lag)
+	}
+}
+
+func main() {
+	queueName := flag.String("queue", "durable-queue", "durable queue to consume and watch")
+	processingTime := flag.Duration("processing-time", 50*time.Millisecond, "time spent processing each message")
+	maxLag := flag.Duration("max-lag", 30*time.Second, "lag threshold")
+	maxSpooled := flag.Int64("max-spooled", 0, "spooled messages threshold, 0 disables it")
+	interval := flag.Duration("interval", 5*time.Second, "SEMP polling interval")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// The lag of the queue, polled from SEMP
+	watcher := queuelag.New(queuelag.SEMPConfig{
+		URL:      sampleconfig.Setting("SOLACE_SEMP_URL", "http://localhost:8080"),
+		VPN:      sampleconfig.Setting("SOLACE_VPN", "default"),
+		Username: sampleconfig.Setting("SOLACE_SEMP_USERNAME", "admin"),
+		Password: sampleconfig.Setting("SOLACE_SEMP_PASSWORD", "admin"),
+	}, queuelag.Thresholds{MaxLag: *maxLag, MaxSpooled: *maxSpooled}, PrintLagEvent)
+	watcher.AddQueue(*queueName)
+
+	// Prometheus metrics, served on /metrics when SOLACE_METRICS_ADDR is set, e.g. SOLACE_METRICS_ADDR=:2112
+	exporter := promexporter.New()
+	exporter.AddService("queue-lag-watcher", messagingService)
+	exporter.Registry().MustRegister(watcher)
+	if address := sampleconfig.Setting("SOLACE_METRICS_ADDR", ""); address != "" {
+		if _, err := exporter.ListenAndServe(address); err != nil {
+			panic(err)
+		}
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// With -provision (or SOLACE_PROVISION=true) the queue is created through SEMP first (see internal/semp)
+	if err := semp.Provision(context.Background(), semp.Queue{Name: *queueName, Subscriptions: []string{TopicPrefix + "/persistent/>"}}); err != nil {
+		panic(err)
+	}
+
+	// Fail early on SEMP errors, e.g. wrong credentials or a queue that does not exist
+	if err := watcher.Poll(context.Background()); err != nil {
+		fmt.Println("Could not read the queue from SEMP: ", err)
+		messagingService.Disconnect()
+		os.Exit(1)
+	}
+
+	persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageAutoAcknowledgement().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/persistent/>")).
+		Build(resource.QueueDurableExclusive(*queueName))
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Persistent Message Receiver
+	if err := persistentReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Persistent Receiver running? ", persistentReceiver.IsRunning())
+
+	// The watcher counts the processed messages for the processing rate
+	if regErr := persistentReceiver.ReceiveAsync(watcher.Handler(*queueName, SlowMessageHandler(*processingTime))); regErr != nil {
+		panic(regErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx, *interval)
+	}()
+
+	fmt.Printf("\n Bound to queue: %s\n", *queueName)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+report:
+	for {
+		select {
+		case <-ticker.C:
+			if sample, ok := watcher.Sample(*queueName); ok {
+				fmt.Printf("spooled=%d consumers=%d processing=%.1f msg/s lag=%s stalled=%t\n",
+					sample.Spooled, sample.Consumers, sample.ProcessingRate, sample.Lag.Round(time.Second), sample.Stalled)
+			}
+		case <-c:
+			break report
+		}
+	}
+
+	cancel()
+	<-done
+
+	// Terminate the Persistent Receiver
+	persistentReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Receiver Terminated? ", persistentReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/ratelimit"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Rate limited publisher: the producer loop runs as fast as it can, the publisher wrapped with a token bucket (see
+// pkg/ratelimit) holds the publishing at -rate messages per second. The first -burst messages go out back to back,
+// then the rate applies, as the throughput printed every second shows:
+//
+//	go run rate_limited_publisher.go -rate 100 -burst 10
+//	go run rate_limited_publisher.go -rate 20 -burst 1 -persistent
+
+// StringPublisher - the publish call shared by the rate limited direct and persistent publishers
+type StringPublisher interface {
+	PublishString(message string, destination *resource.Topic) error
+	Terminate(gracePeriod time.Duration) error
+	IsTerminated() bool
+}
+
+func main() {
+	rate := flag.Float64("rate", 100, "messages published per second")
+	burst := flag.Int("burst", 10, "messages published back to back after an idle period")
+	persistent := flag.Bool("persistent", false, "publish persistent messages rather than direct ones")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// The token bucket, it could be shared by several publishers to cap their combined rate
+	limiter := ratelimit.NewLimiter(*rate, *burst)
+
+	var publisher StringPublisher
+	topic := resource.TopicOf(TopicPrefix + "/direct/pub/ratelimited")
+	if *persistent {
+		persistentPublisher, builderErr := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+		if builderErr != nil {
+			panic(builderErr)
+		}
+		if startErr := persistentPublisher.Start(); startErr != nil {
+			panic(startErr)
+		}
+		publisher = ratelimit.Persistent(persistentPublisher, limiter)
+		topic = resource.TopicOf(TopicPrefix + "/persistent/pub/ratelimited")
+	} else {
+		directPublisher, builderErr := messagingService.CreateDirectMessagePublisherBuilder().Build()
+		if builderErr != nil {
+			panic(builderErr)
+		}
+		if startErr := directPublisher.Start(); startErr != nil {
+			panic(startErr)
+		}
+		publisher = ratelimit.Direct(directPublisher, limiter)
+	}
+
+	fmt.Printf("Publishing on %s at %.0f msg/s with bursts of %d\n", topic.GetName(), limiter.Rate(), limiter.Burst())
+	fmt.Println("\n===Interrupt (CTR+C) to stop publishing===")
+
+	// The producer loop does not pace itself, every publish call waits for a token
+	var published int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msgSeqNum := 0; ; msgSeqNum++ {
+			err := publisher.PublishString("Hello from Go Rate Limited Publisher --> "+strconv.Itoa(msgSeqNum), topic)
+			if errors.Is(err, ratelimit.ErrTerminated) {
+				return
+			}
+			var illegalState *solace.IllegalStateError
+			if errors.As(err, &illegalState) {
+				return
+			}
+			if err != nil {
+				fmt.Println("Publish failed: ", err)
+				continue
+			}
+			atomic.AddInt64(&published, 1)
+		}
+	}()
+
+	// Handle OS interrupts
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var last int64
+report:
+	for {
+		select {
+		case <-ticker.C:
+			total := atomic.LoadInt64(&published)
+			fmt.Printf("published=%d rate=%d msg/s\n", total, total-last)
+			last = total
+		case <-c:
+			break report
+		}
+	}
+
+	// Terminate the Publisher, releasing the publish call waiting for a token
+	publisher.Terminate(1 * time.Second)
+	<-done
+	fmt.Println("\nPublisher Terminated? ", publisher.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/alerting"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Reconnection monitor: the reconnection attempt and reconnection listeners are used to count the attempts and time
+// the outages, and an alert is sent once an outage lasts longer than a threshold (and again when it is resolved), so
+// short network blips do not page anyone. The alerter is pluggable (see pkg/alerting): the alerts are printed, and
+// posted as JSON or as a Slack message when SOLACE_ALERT_WEBHOOK is set, e.g. to a chat or incident management webhook.
+//
+//	SOLACE_ALERT_THRESHOLD=30s SOLACE_ALERT_WEBHOOK=https://hooks.example.com/solace go run reconnection_monitor.go
+
+// OutageEvent - the event of the alerts of the monitor
+const OutageEvent = "outage"
+
+// ReconnectionStats - counters of the reconnection monitor
+type ReconnectionStats struct {
+	Outages         int
+	Attempts        int
+	Alerts          int
+	LastOutage      time.Duration
+	LongestOutage   time.Duration
+	TotalOutageTime time.Duration
+	CurrentOutage   time.Duration
+}
+
+// ReconnectionMonitor - counts the reconnection attempts and times the outages of a messaging service
+type ReconnectionMonitor struct {
+	name      string
+	threshold time.Duration
+	alerter   alerting.Alerter
+
+	mu          sync.Mutex
+	stats       ReconnectionStats
+	outageStart time.Time
+	attempts    int
+	lastCause   error
+	alerted     bool
+	timer       *time.Timer
+}
+
+// NewReconnectionMonitor - registers the listeners on the messaging service, the alerts are sent in their own goroutine
+// under the name
+func NewReconnectionMonitor(name string, messagingService solace.MessagingService, threshold time.Duration, alerter alerting.Alerter) *ReconnectionMonitor {
+	monitor := &ReconnectionMonitor{name: name, threshold: threshold, alerter: alerter}
+	messagingService.AddReconnectionAttemptListener(monitor.onAttempt)
+	messagingService.AddReconnectionListener(monitor.onReconnected)
+	return monitor
+}
+
+func (m *ReconnectionMonitor) onAttempt(event solace.ServiceEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.outageStart.IsZero() {
+		m.outageStart = event.GetTimestamp()
+		m.stats.Outages++
+		// alert even if the attempts are far apart
+		m.timer = time.AfterFunc(m.threshold, m.checkThreshold)
+	}
+	m.attempts++
+	m.stats.Attempts++
+	m.lastCause = event.GetCause()
+}
+
+func (m *ReconnectionMonitor) checkThreshold() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.outageStart.IsZero() || m.alerted {
+		return
+	}
+	m.alerted = true
+	m.stats.Alerts++
+	duration := time.Since(m.outageStart)
+	details := map[string]interface{}{"started": m.outageStart, "duration": duration.String(), "attempts": m.attempts}
+	if m.lastCause != nil {
+		details["cause"] = m.lastCause.Error()
+	}
+	go m.send(alerting.Alert{
+		Time: time.Now(), Severity: alerting.Critical, Source: m.name, Event: OutageEvent,
+		Summary: fmt.Sprintf("disconnected from the broker for %s, %d reconnection attempt(s) so far",
+			duration.Round(time.Millisecond), m.attempts),
+		Details: details,
+	})
+}
+
+func (m *ReconnectionMonitor) send(alert alerting.Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), alerting.SendTimeout)
+	defer cancel()
+	if err := m.alerter.Send(ctx, alert); err != nil {
+		fmt.Println("Could not send the alert: ", err)
+	}
+}
+
+func (m *ReconnectionMonitor) onReconnected(event solace.ServiceEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.outageStart.IsZero() {
+		return
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	duration := event.GetTimestamp().Sub(m.outageStart)
+	m.stats.LastOutage = duration
+	m.stats.TotalOutageTime += duration
+	if duration > m.stats.LongestOutage {
+		m.stats.LongestOutage = duration
+	}
+	if m.alerted {
+		go m.send(alerting.Alert{
+			Time: event.GetTimestamp(), Severity: alerting.Info, Source: m.name, Event: OutageEvent, Resolved: true,
+			Summary: fmt.Sprintf("reconnected to %s after %s and %d attempt(s)", event.GetBrokerURI(),
+				duration.Round(time.Millisecond), m.attempts),
+			Details: map[string]interface{}{"brokerUri": event.GetBrokerURI(), "started": m.outageStart,
+				"duration": duration.String(), "attempts": m.attempts},
+		})
+	}
+	m.outageStart, m.attempts, m.lastCause, m.alerted = time.Time{}, 0, nil, false
+}
+
+// Stats - a snapshot of the counters
+func (m *ReconnectionMonitor) Stats() ReconnectionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	if !m.outageStart.IsZero() {
+		stats.CurrentOutage = time.Since(m.outageStart)
+	}
+	return stats
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	threshold, err := time.ParseDuration(sampleconfig.Setting("SOLACE_ALERT_THRESHOLD", "30s"))
+	if err != nil {
+		panic(err)
+	}
+	// Printed, and posted to SOLACE_ALERT_WEBHOOK when set
+	alerter := alerting.FromEnv()
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(3 * time.Second)).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Register the listeners before connecting
+	monitor := NewReconnectionMonitor("reconnection-monitor", messagingService, threshold, alerter)
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stats := monitor.Stats()
+			fmt.Printf("outages=%d attempts=%d alerts=%d last=%s longest=%s total=%s current=%s\n",
+				stats.Outages, stats.Attempts, stats.Alerts,
+				stats.LastOutage.Round(time.Millisecond), stats.LongestOutage.Round(time.Millisecond),
+				stats.TotalOutageTime.Round(time.Millisecond), stats.CurrentOutage.Round(time.Millisecond))
+		case <-c:
+			// Disconnect the Message Service
+			messagingService.Disconnect()
+			fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+			return
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/alerting"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Reconnection strategies: how often and how long the API tries to connect, and to reconnect after the connection
+// was lost, is set either with the builder methods or with service properties:
+//
+//	builder                                                properties
+//	WithConnectionRetryStrategy(strategy)                  TransportLayerPropertyConnectionRetries
+//	WithReconnectionRetryStrategy(strategy)                TransportLayerPropertyReconnectionAttempts
+//	                                                       TransportLayerPropertyReconnectionAttemptsWaitInterval (ms)
+//	(properties only)                                      TransportLayerPropertyConnectionRetriesPerHost
+//
+// where strategy is config.RetryStrategyParameterizedRetry(retries, interval), RetryStrategyForeverRetry(),
+// RetryStrategyForeverRetryWithInterval(interval) or RetryStrategyNeverRetry(); with properties -1 retries forever
+// and 0 never retries. Retries per host applies to both: each host of the host list is tried that many times again
+// before the next one, a pass through the whole list is one retry or reconnection attempt.
+//
+// With -force-disconnect the sample connects through a local relay that drops the connection every -every and
+// refuses new connections for -outage, to observe each strategy reconnecting or giving up:
+//
+//	go run reconnection_strategies.go -strategy parameterized -retries 3 -interval 2s -force-disconnect -outage 10s
+//	go run reconnection_strategies.go -strategy properties -retries -1 -interval 1s -retries-per-host 2 -force-disconnect
+
+// Relay - forwards local connections to the broker, Outage drops them and refuses new ones for a while
+type Relay struct {
+	target   string
+	listener net.Listener
+
+	mu    sync.Mutex
+	until time.Time
+	conns []net.Conn
+}
+
+// NewRelay - listens on a local port chosen by the system and forwards to target
+func NewRelay(target string) (*Relay, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	relay := &Relay{target: target, listener: listener}
+	go relay.accept()
+	return relay, nil
+}
+
+// Host - the host to connect to instead of the broker
+func (r *Relay) Host() string {
+	return "tcp://" + r.listener.Addr().String()
+}
+
+// Outage - closes the forwarded connections and refuses (closes) new ones for the given duration
+func (r *Relay) Outage(duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.until = time.Now().Add(duration)
+	for _, conn := range r.conns {
+		conn.Close()
+	}
+	r.conns = nil
+}
+
+// Close - stops the relay
+func (r *Relay) Close() {
+	r.listener.Close()
+	r.Outage(0)
+}
+
+func (r *Relay) accept() {
+	for {
+		client, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		down := time.Now().Before(r.until)
+		r.mu.Unlock()
+		if down {
+			client.Close()
+			continue
+		}
+		broker, err := net.DialTimeout("tcp", r.target, 5*time.Second)
+		if err != nil {
+			client.Close()
+			continue
+		}
+		r.mu.Lock()
+		r.conns = append(r.conns, client, broker)
+		r.mu.Unlock()
+		go func() {
+			io.Copy(broker, client)
+			broker.Close()
+		}()
+		go func() {
+			io.Copy(client, broker)
+			client.Close()
+		}()
+	}
+}
+
+// RetryStrategy - the builder strategy for the given name, retries and interval
+func RetryStrategy(name string, retries int, interval time.Duration) (config.RetryStrategy, error) {
+	switch name {
+	case "parameterized":
+		if retries < 0 {
+			return config.RetryStrategy{}, fmt.Errorf("the parameterized strategy needs a number of retries, use forever to retry forever")
+		}
+		return config.RetryStrategyParameterizedRetry(uint(retries), interval), nil
+	case "forever":
+		return config.RetryStrategyForeverRetryWithInterval(interval), nil
+	case "never":
+		return config.RetryStrategyNeverRetry(), nil
+	}
+	return config.RetryStrategy{}, fmt.Errorf("unknown strategy %s, use properties, parameterized, forever or never", name)
+}
+
+func main() {
+	strategy := flag.String("strategy", "properties", "how the retries are configured: properties, or the builder strategies parameterized, forever or never")
+	retries := flag.Int("retries", 3, "connection retries and reconnection attempts (properties and parameterized, -1 for forever with properties)")
+	interval := flag.Duration("interval", 3*time.Second, "wait between the retries and reconnection attempts")
+	retriesPerHost := flag.Int("retries-per-host", 0, "times each host of the host list is retried before the next one")
+	forceDisconnect := flag.Bool("force-disconnect", false, "connect through a local relay that drops the connection regularly")
+	every := flag.Duration("every", 20*time.Second, "time between the forced disconnects")
+	outage := flag.Duration("outage", 10*time.Second, "how long new connections are refused after a forced disconnect")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	// There is no builder method for the retries per host
+	brokerConfig.Properties[config.TransportLayerPropertyConnectionRetriesPerHost] = *retriesPerHost
+
+	var relay *Relay
+	if *forceDisconnect {
+		var err error
+		relay, err = NewRelay(sampleconfig.Setting("SOLACE_BROKER_ADDRESS", "localhost:55555"))
+		if err != nil {
+			panic(err)
+		}
+		defer relay.Close()
+		brokerConfig.Properties[config.TransportLayerPropertyHost] = relay.Host()
+		fmt.Printf("Connecting through the relay %s\n", relay.Host())
+	}
+
+	builder := messaging.NewMessagingServiceBuilder()
+	if *strategy == "properties" {
+		// The same settings as service properties, e.g. read from a configuration file
+		brokerConfig.Properties[config.TransportLayerPropertyConnectionRetries] = *retries
+		brokerConfig.Properties[config.TransportLayerPropertyReconnectionAttempts] = *retries
+		brokerConfig.Properties[config.TransportLayerPropertyReconnectionAttemptsWaitInterval] = int(interval.Milliseconds())
+		builder = builder.FromConfigurationProvider(brokerConfig.Properties)
+	} else {
+		retryStrategy, err := RetryStrategy(*strategy, *retries, *interval)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		// The builder methods override the properties given before them
+		builder = builder.FromConfigurationProvider(brokerConfig.Properties).
+			WithConnectionRetryStrategy(retryStrategy).
+			WithReconnectionRetryStrategy(retryStrategy)
+	}
+
+	messagingService, err := builder.Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	interrupted := make(chan struct{})
+	attempts := 0
+	var outageStart time.Time
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		if attempts == 0 {
+			outageStart = event.GetTimestamp()
+		}
+		attempts++
+		fmt.Printf("%s reconnection attempt %d to %s: %v\n", event.GetTimestamp().Format("15:04:05.000"), attempts, event.GetBrokerURI(), event.GetCause())
+	})
+	messagingService.AddReconnectionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("%s reconnected to %s after %d attempt(s) and %s\n", event.GetTimestamp().Format("15:04:05.000"), event.GetBrokerURI(),
+			attempts, event.GetTimestamp().Sub(outageStart).Round(time.Millisecond))
+		attempts = 0
+	})
+	messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		fmt.Printf("%s gave up after %d attempt(s): %v\n", event.GetTimestamp().Format("15:04:05.000"), attempts, event.GetCause())
+		close(interrupted)
+	})
+
+	// Operational notification of the outages, printed and posted to SOLACE_ALERT_WEBHOOK when set (see pkg/alerting)
+	alerting.Watch("reconnection-strategies", messagingService, alerting.FromEnv())
+
+	// Connect to the messaging service, the connection retries apply here
+	start := time.Now()
+	if err := messagingService.Connect(); err != nil {
+		fmt.Printf("Could not connect after %s: %s\n", time.Since(start).Round(time.Millisecond), err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	var disconnect <-chan time.Time
+	if relay != nil {
+		ticker := time.NewTicker(*every)
+		defer ticker.Stop()
+		disconnect = ticker.C
+	}
+	for {
+		select {
+		case <-disconnect:
+			fmt.Printf("%s forced disconnect, refusing connections for %s\n", time.Now().Format("15:04:05.000"), *outage)
+			relay.Outage(*outage)
+		case <-interrupted:
+			fmt.Println("Messaging Service Connected? ", messagingService.IsConnected())
+			return
+		case <-c:
+			// Disconnect the Message Service
+			messagingService.Disconnect()
+			fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+			return
+		}
+	}
+}
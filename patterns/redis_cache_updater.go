@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/internal/semp"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"github.com/redis/go-redis/v9"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Redis cache updater: materializes the latest payload of every price topic into Redis, the Redis key being the
+// topic, e.g. GET solace/samples/prices/AAPL. The cache is warmed from a last value queue (LVQ) per symbol, a queue
+// with a spool quota of 0 keeping only the last message published on its topic, then kept up to date with the live
+// updates of a direct receiver, written to Redis in pipelined batches with the updates of a key coalesced.
+// The message of an LVQ is received without being acknowledged, it stays on the queue for the next warm-up, flagged
+// redelivered: the LVQs are provisioned with unlimited redeliveries so it is never discarded however many times the
+// cache is warmed.
+//
+//	SOLACE_REDIS_URL=redis://localhost:6379/0 go run redis_cache_updater.go -provision
+//	go run redis_cache_updater.go -symbols AAPL,MSFT -publish 0
+//
+// The direct receiver is subscribed before the cache is warmed, so no update is missed in between: its updates wait
+// while the LVQs are read and are applied after, the cache converging to the latest payloads. The cache is warmed
+// again whenever live updates may have been lost: after a reconnection to the broker (the direct messages published
+// during the outage are gone), when the updates come faster than Redis takes them, or after a failed write to Redis,
+// retried until Redis is back. An LVQ is exclusive: while another instance of the updater warms its cache from it,
+// or any other consumer is bound to it, the receiver is on standby and the LVQ cannot be read; the keys of the other
+// LVQs are written and the warm-up is retried. With -publish the sample also publishes a price per symbol at that
+// interval; the LVQs must exist first, run once with -provision (or SOLACE_PROVISION=true) to create them through SEMP.
+
+// RetryInterval - wait between two attempts to warm the cache or write to Redis
+const RetryInterval = 3 * time.Second
+
+// MaxBatch - keys written to Redis in one pipeline
+const MaxBatch = 500
+
+// ActivationTimeout - wait for the broker to make the receiver of an LVQ the active consumer of the exclusive queue
+const ActivationTimeout = 1 * time.Second
+
+// errStandby - the LVQ is held by another consumer, e.g. another instance warming its cache
+var errStandby = errors.New("held by another consumer, the receiver is on standby")
+
+// PriceTopic - the topic of the price updates of a symbol
+func PriceTopic(symbol string) string {
+	return TopicPrefix + "/prices/" + symbol
+}
+
+// LVQName - the last value queue of a symbol
+func LVQName(symbol string) string {
+	return "lvq-prices-" + symbol
+}
+
+// Update - the payload of a message and the topic it was published on
+type Update struct {
+	Topic   string
+	Payload []byte
+}
+
+// CacheUpdater - writes the latest payload of every topic to Redis, warmed from the LVQs then updated live
+type CacheUpdater struct {
+	Redis            *redis.Client
+	MessagingService solace.MessagingService
+	LVQs             []string
+
+	updates chan Update
+	// rewarm - set when live updates may have been lost, the cache is warmed again
+	rewarm  atomic.Bool
+	dropped atomic.Int64
+	written atomic.Int64
+	warmed  atomic.Int64
+}
+
+// NewCacheUpdater - an updater queueing up to buffer live updates while it warms the cache or waits for Redis
+func NewCacheUpdater(client *redis.Client, messagingService solace.MessagingService, lvqs []string, buffer int) *CacheUpdater {
+	return &CacheUpdater{Redis: client, MessagingService: messagingService, LVQs: lvqs, updates: make(chan Update, buffer)}
+}
+
+// OnMessage - the handler of the direct receiver, it never blocks: an update that does not fit the queue is dropped
+// and the cache warmed again
+func (u *CacheUpdater) OnMessage(inbound message.InboundMessage) {
+	payload, _ := inbound.GetPayloadAsBytes()
+	select {
+	case u.updates <- Update{Topic: inbound.GetDestinationName(), Payload: payload}:
+	default:
+		u.dropped.Add(1)
+		u.rewarm.Store(true)
+	}
+}
+
+// OnReconnected - the direct messages published while disconnected are lost, the cache is warmed again
+func (u *CacheUpdater) OnReconnected(event solace.ServiceEvent) {
+	fmt.Printf("Reconnected to %s, warming the cache again\n", event.GetBrokerURI())
+	u.rewarm.Store(true)
+}
+
+// Run - warms the cache, then writes the live updates until the context is canceled
+func (u *CacheUpdater) Run(ctx context.Context) {
+	u.rewarm.Store(true)
+	for ctx.Err() == nil {
+		if u.rewarm.Swap(false) {
+			if err := u.Warm(ctx); err != nil {
+				if ctx.Err() == nil {
+					fmt.Println("Could not warm the cache, retrying: ", err)
+				}
+				u.rewarm.Store(true)
+				sleep(ctx, RetryInterval)
+				continue
+			}
+		}
+		batch := u.next(ctx)
+		if len(batch) == 0 {
+			continue
+		}
+		if err := u.write(ctx, batch); err != nil {
+			// the updates of the batch are lost, the cache is warmed again once Redis answers
+			if ctx.Err() == nil {
+				fmt.Println("Could not write to Redis, warming the cache again once it is back: ", err)
+			}
+			u.rewarm.Store(true)
+			sleep(ctx, RetryInterval)
+		}
+	}
+}
+
+// Warm - writes the last message of every LVQ to Redis. The updates queued so far are discarded first: they were
+// published before the LVQs are read, which hold them or a later one, and the updates queued from now on are
+// applied after, in order.
+func (u *CacheUpdater) Warm(ctx context.Context) error {
+drain:
+	for {
+		select {
+		case <-u.updates:
+		default:
+			break drain
+		}
+	}
+	batch := map[string][]byte{}
+	var held []string
+	for _, lvq := range u.LVQs {
+		update, found, err := u.readLast(lvq)
+		if errors.Is(err, errStandby) {
+			// not empty, unknown: the keys of the others are written and the warm-up retried
+			held = append(held, lvq)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", lvq, err)
+		}
+		if found {
+			batch[update.Topic] = update.Payload
+		}
+	}
+	if err := u.write(ctx, batch); err != nil {
+		return err
+	}
+	if len(held) > 0 {
+		return fmt.Errorf("%d key(s) written, %s %w", len(batch), strings.Join(held, ", "), errStandby)
+	}
+	u.warmed.Add(1)
+	fmt.Printf("Cache warmed with %d key(s) from %d LVQ(s)\n", len(batch), len(u.LVQs))
+	return nil
+}
+
+// readLast - the last message of the LVQ, received without being acknowledged so it stays on the queue for the next
+// warm-up. errStandby when another consumer is bound to the LVQ: the receiver is not made active and receives
+// nothing, which would otherwise pass for an empty LVQ.
+func (u *CacheUpdater) readLast(lvq string) (Update, bool, error) {
+	active := make(chan struct{}, 1)
+	persistentReceiver, err := u.MessagingService.CreatePersistentMessageReceiverBuilder().
+		WithMessageClientAcknowledgement().
+		WithActivationPassivationSupport(func(oldState, newState solace.ReceiverState, timestamp time.Time) {
+			if newState == solace.ReceiverActive {
+				select {
+				case active <- struct{}{}:
+				default:
+				}
+			}
+		}).
+		Build(resource.QueueDurableExclusive(lvq))
+	if err == nil {
+		err = persistentReceiver.Start()
+	}
+	if err != nil {
+		return Update{}, false, err
+	}
+	defer persistentReceiver.Terminate(1 * time.Second)
+	select {
+	case <-active:
+	case <-time.After(ActivationTimeout):
+		return Update{}, false, errStandby
+	}
+	inbound, err := persistentReceiver.ReceiveMessage(1 * time.Second)
+	if err != nil {
+		// nothing published on the topic of an empty LVQ yet
+		var timeoutErr *solace.TimeoutError
+		if errors.As(err, &timeoutErr) {
+			return Update{}, false, nil
+		}
+		return Update{}, false, err
+	}
+	payload, _ := inbound.GetPayloadAsBytes()
+	return Update{Topic: inbound.GetDestinationName(), Payload: payload}, true, nil
+}
+
+// next - the queued updates, at most MaxBatch keys with the latest payload of each, waiting up to a second for one
+func (u *CacheUpdater) next(ctx context.Context) map[string][]byte {
+	batch := map[string][]byte{}
+	select {
+	case update := <-u.updates:
+		batch[update.Topic] = update.Payload
+	case <-ctx.Done():
+		return nil
+	case <-time.After(1 * time.Second):
+		return nil
+	}
+	for len(batch) < MaxBatch {
+		select {
+		case update := <-u.updates:
+			batch[update.Topic] = update.Payload
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// write - sets the keys in one round trip, go-redis reconnects to Redis on the next write after a failure
+func (u *CacheUpdater) write(ctx context.Context, batch map[string][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	pipe := u.Redis.Pipeline()
+	for key, payload := range batch {
+		pipe.Set(ctx, key, payload, 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	u.written.Add(int64(len(batch)))
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// PublishPrices - publishes a random walk price per symbol at the interval until the context is canceled
+func PublishPrices(ctx context.Context, messagingService solace.MessagingService, publisher solace.PersistentMessagePublisher, symbols []string, interval time.Duration) {
+	prices := map[string]float64{}
+	for _, symbol := range symbols {
+		prices[symbol] = 100
+	}
+	for ctx.Err() == nil {
+		for _, symbol := range symbols {
+			prices[symbol] *= 1 + (rand.Float64()-0.5)/50
+			payload, _ := json.Marshal(map[string]interface{}{"symbol": symbol, "price": prices[symbol], "time": time.Now()})
+			msg, err := messagingService.MessageBuilder().WithHTTPContentHeader("application/json", "").BuildWithByteArrayPayload(payload)
+			if err != nil {
+				panic(err)
+			}
+			// fails while the service reconnects, the next price replaces this one anyway
+			if err := publisher.Publish(msg, resource.TopicOf(PriceTopic(symbol)), nil, nil); err != nil {
+				fmt.Println("Could not publish the price: ", err)
+			}
+		}
+		sleep(ctx, interval)
+	}
+}
+
+func main() {
+	symbolList := flag.String("symbols", "AAPL,MSFT,GOOG", "comma separated symbols, each with its LVQ")
+	publishInterval := flag.Duration("publish", 1*time.Second, "publish a price per symbol at this interval (0 to only consume)")
+	buffer := flag.Int("buffer", 10000, "live updates queued while the cache is warmed or Redis is unavailable")
+	flag.Parse()
+
+	var symbols, lvqs []string
+	var queues []semp.Queue
+	for _, symbol := range strings.Split(*symbolList, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+			lvqs = append(lvqs, LVQName(symbol))
+			queues = append(queues, semp.Queue{Name: LVQName(symbol), Subscriptions: []string{PriceTopic(symbol)}, LastValue: true})
+		}
+	}
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	redisOptions, err := redis.ParseURL(sampleconfig.Setting("SOLACE_REDIS_URL", "redis://localhost:6379/0"))
+	if err != nil {
+		panic(err)
+	}
+	redisClient := redis.NewClient(redisOptions)
+	defer redisClient.Close()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	// With -provision (or SOLACE_PROVISION=true) the LVQs are created through SEMP first (see internal/semp)
+	if err := semp.Provision(context.Background(), queues...); err != nil {
+		panic(err)
+	}
+
+	// Keep retrying to reconnect every 3 seconds, the cache is warmed again once reconnected
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithReconnectionRetryStrategy(config.RetryStrategyForeverRetryWithInterval(3 * time.Second)).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	updater := NewCacheUpdater(redisClient, messagingService, lvqs, *buffer)
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		fmt.Printf("Reconnecting to %s: %v\n", event.GetBrokerURI(), event.GetCause())
+	})
+	messagingService.AddReconnectionListener(updater.OnReconnected)
+
+	// Subscribed before the cache is warmed, the live updates are queued meanwhile
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/prices/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+	if err := directReceiver.ReceiveAsync(updater.OnMessage); err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		updater.Run(ctx)
+		close(done)
+	}()
+
+	var persistentPublisher solace.PersistentMessagePublisher
+	if *publishInterval > 0 {
+		persistentPublisher, err = messagingService.CreatePersistentMessagePublisherBuilder().Build()
+		if err != nil {
+			panic(err)
+		}
+		if err := persistentPublisher.Start(); err != nil {
+			panic(err)
+		}
+		go PublishPrices(ctx, messagingService, persistentPublisher, symbols, *publishInterval)
+	}
+
+	fmt.Printf("\n Caching the prices of %s in %s\n", strings.Join(symbols, ", "), redisOptions.Addr)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the updater===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	<-c
+
+	cancel()
+	<-done
+	if persistentPublisher != nil {
+		persistentPublisher.Terminate(1 * time.Second)
+	}
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+	fmt.Printf("%d key(s) written, %d warm-up(s), %d update(s) dropped\n", updater.written.Load(), updater.warmed.Load(), updater.dropped.Load())
+}
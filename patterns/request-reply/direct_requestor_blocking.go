@@ -1,38 +1,34 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/propagation"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace"
 	"solace.dev/go/messaging/pkg/solace/config"
 	"solace.dev/go/messaging/pkg/solace/resource"
 )
 
-// getEnv function
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 func main() {
-	// logging.SetLogLevel(logging.LogLevelInfo)
-
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
@@ -88,19 +84,29 @@ func main() {
 		fmt.Printf("Publishing message with sequence number: %d on topic: %s\n", msgSeqNum, topic.GetName())
 		// fmt.Printf("Publishing message: %s\n", message)
 
+		// A new flow per request: its correlation ID, and trace context when tracing, travel in the user properties of the
+		// request and are passed on to the reply by the replier (see pkg/propagation). The correlation ID header is the
+		// one the API matches the reply with.
+		ctx := propagation.EnsureCorrelationID(context.Background())
+		properties := propagation.Properties(ctx)
+		properties[config.MessagePropertyCorrelationID] = fmt.Sprint(msgSeqNum)
+		correlationID, _ := propagation.CorrelationID(ctx)
+		fmt.Printf("Request %d in flow %s\n", msgSeqNum, correlationID)
+
 		// Publish to the given topic
 		// Block until reply message is received
 		replyTimeout := 5 * time.Second
 		// The PublishAwaitResponse() function waits until the specified replyTimeout to receive a published message's reply or waits
 		// indefinitely if replyTimeout value is negative.
 		// Reference: https://pkg.go.dev/solace.dev/go/messaging@v1.6.1/pkg/solace#RequestReplyMessagePublisher
-		messageReply, publishErr := requestReplyPublisher.PublishAwaitResponse(message, topic, replyTimeout, config.MessagePropertyMap{
-			config.MessagePropertyCorrelationID: fmt.Sprint(msgSeqNum),
-		})
+		messageReply, publishErr := requestReplyPublisher.PublishAwaitResponse(message, topic, replyTimeout, properties)
 
 		if publishErr == nil { // Good, a reply was received
 			messageReplyPayload, _ := messageReply.GetPayloadAsString()
 			fmt.Printf("The reply inbound payload: %s\n", messageReplyPayload)
+			if replyID, ok := propagation.CorrelationID(propagation.Extract(context.Background(), messageReply)); ok {
+				fmt.Printf("The reply is in flow %s\n", replyID)
+			}
 		} else if terr, ok := publishErr.(*solace.TimeoutError); ok { // Not good, a timeout occurred and no reply was received
 			// message should be nil
 			// This handles the situation that the requester application did not receive a reply for the published message within the specified timeout.
@@ -117,7 +123,7 @@ func main() {
 
 	// Handle OS interrupts
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 
 	// Block until an OS interrupt signal is received.
 	<-c
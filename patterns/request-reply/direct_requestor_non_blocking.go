@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
 	"time"
 
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/propagation"
 	"solace.dev/go/messaging"
 	"solace.dev/go/messaging/pkg/solace"
 	"solace.dev/go/messaging/pkg/solace/config"
@@ -14,19 +19,14 @@ import (
 	"solace.dev/go/messaging/pkg/solace/resource"
 )
 
-// getEnv function
-func getEnv(key, def string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return def
-}
-
 // requester reply handler function for reply message, this should also include basic error handling
 func ReplyMessageHandler(message message.InboundMessage, userContext interface{}, err error) {
 	if err == nil { // Good, a reply was received
 		payload, _ := message.GetPayloadAsString()
 		fmt.Printf("The reply inbound payload: %s\n", payload)
+		if replyID, ok := propagation.CorrelationID(propagation.Extract(context.Background(), message)); ok {
+			fmt.Printf("The reply is in flow %s\n", replyID)
+		}
 	} else if terr, ok := err.(*solace.TimeoutError); ok { // Not good, a timeout occurred and no reply was received
 		// message should be nil
 		// This handles the situation that the requester application did not receive a reply for the published message within the specified timeout.
@@ -38,17 +38,16 @@ func ReplyMessageHandler(message message.InboundMessage, userContext interface{}
 }
 
 func main() {
-	// logging.SetLogLevel(logging.LogLevelInfo)
-
-	// Configuration parameters
-	brokerConfig := config.ServicePropertyMap{
-		config.TransportLayerPropertyHost:                getEnv("SOLACE_HOST", "tcp://localhost:55555,tcp://localhost:55554"),
-		config.ServicePropertyVPNName:                    getEnv("SOLACE_VPN", "default"),
-		config.AuthenticationPropertySchemeBasicPassword: getEnv("SOLACE_PASSWORD", "default"),
-		config.AuthenticationPropertySchemeBasicUserName: getEnv("SOLACE_USERNAME", "default"),
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
 	}
 
-	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
 
 	if err != nil {
 		panic(err)
@@ -102,12 +101,19 @@ func main() {
 			panic(err)
 		}
 
+		// A new flow per request: its correlation ID, and trace context when tracing, travel in the user properties of the
+		// request and are passed on to the reply by the replier (see pkg/propagation). The correlation ID header is the
+		// one the API matches the reply with.
+		ctx := propagation.EnsureCorrelationID(context.Background())
+		properties := propagation.Properties(ctx)
+		properties[config.MessagePropertyCorrelationID] = fmt.Sprint(msgSeqNum)
+		correlationID, _ := propagation.CorrelationID(ctx)
+		fmt.Printf("Request %d in flow %s\n", msgSeqNum, correlationID)
+
 		replyTimeout := 5 * time.Second
 
 		// Publish to the given topic
-		publishErr := requestReplyPublisher.Publish(message, ReplyMessageHandler, topic, replyTimeout, config.MessagePropertyMap{
-			config.MessagePropertyCorrelationID: fmt.Sprint(msgSeqNum),
-		}, nil /* usercontext */)
+		publishErr := requestReplyPublisher.Publish(message, ReplyMessageHandler, topic, replyTimeout, properties, nil /* usercontext */)
 		// // Publish string message to topic
 		// stringMessage := messageBody + " --> " + strconv.Itoa(msgSeqNum)
 		// publishErr := requestReplyPublisher.PublishString(stringMessage, ReplyMessageHandler, topic, replyTimeout, nil /* usercontext */)
@@ -126,7 +132,7 @@ func main() {
 
 	// Handle OS interrupts
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
 
 	// Block until an OS interrupt signal is received.
 	<-c
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/zaplog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Sampled logging: a log line per message published and received, written by a zap logger sampling the records
+// below the error level (see pkg/zaplog), so the logging keeps up with the messaging. One message in -fail-every
+// fails its processing and is logged as an error, errors are never sampled. Compare the log volume with and without
+// sampling:
+//
+//	go run sampled_logging.go -rate 5000 2>sampled.log
+//	go run sampled_logging.go -rate 5000 -sampled=false 2>all.log
+//	grep -c '"level":"error"' sampled.log all.log
+
+// SampledMessageHandler - logs every received message, the logger drops most of the lines
+func SampledMessageHandler(logger *zap.Logger, failEvery uint64, received, failed *uint64) func(message.InboundMessage) {
+	return func(inbound message.InboundMessage) {
+		n := atomic.AddUint64(received, 1)
+		id, _ := inbound.GetApplicationMessageID()
+		// a constant message, the sampling counts the records by message
+		logger.Info("message received", zap.String("topic", inbound.GetDestinationName()), zap.String("id", id))
+		if failEvery > 0 && n%failEvery == 0 {
+			atomic.AddUint64(failed, 1)
+			logger.Error("message processing failed", zap.String("id", id), zap.Uint64("received", n))
+		}
+	}
+}
+
+func main() {
+	rate := flag.Int("rate", 2000, "messages published per second")
+	sampled := flag.Bool("sampled", true, "sample the records below the error level")
+	failEvery := flag.Uint64("fail-every", 5000, "fail the processing of one received message in every n, 0 never fails")
+	flag.Parse()
+	if *rate <= 0 {
+		fmt.Fprintln(os.Stderr, "-rate must be positive")
+		os.Exit(2)
+	}
+
+	sampling := zaplog.DefaultSampling
+	if !*sampled {
+		sampling = nil
+	}
+	logger := zaplog.New(zapcore.InfoLevel, sampling)
+	defer logger.Sync()
+	zaplog.RouteAPILogs(logger)
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging serice
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	var received, failed, published uint64
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/logging/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := directReceiver.ReceiveAsync(SampledMessageHandler(logger, *failEvery, &received, &failed)); regErr != nil {
+		panic(regErr)
+	}
+
+	directPublisher, err := messagingService.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directPublisher.Start(); err != nil {
+		panic(err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Second / time.Duration(*rate))
+		defer ticker.Stop()
+		for msgSeqNum := 1; ; msgSeqNum++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			topic := TopicPrefix + "/logging/" + strconv.Itoa(msgSeqNum%10)
+			outbound, err := messagingService.MessageBuilder().
+				WithApplicationMessageID(strconv.Itoa(msgSeqNum)).
+				BuildWithStringPayload("Hello from the sampled logging sample")
+			if err != nil {
+				logger.Error("could not build the message", zap.Error(err))
+				continue
+			}
+			if err := directPublisher.Publish(outbound, resource.TopicOf(topic)); err != nil {
+				logger.Error("publish failed", zap.String("topic", topic), zap.Error(err))
+				continue
+			}
+			atomic.AddUint64(&published, 1)
+			logger.Info("message published", zap.String("topic", topic), zap.Int("id", msgSeqNum))
+		}
+	}()
+
+	fmt.Println("\n===Interrupt (CTR+C) to stop the workload===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+report:
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Printf("published=%d received=%d failed=%d\n", atomic.LoadUint64(&published), atomic.LoadUint64(&received), atomic.LoadUint64(&failed))
+		case <-c:
+			break report
+		}
+	}
+
+	close(stop)
+	<-done
+
+	directPublisher.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Publisher Terminated? ", directPublisher.IsTerminated())
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("Direct Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+	fmt.Printf("published=%d received=%d failed=%d\n", atomic.LoadUint64(&published), atomic.LoadUint64(&received), atomic.LoadUint64(&failed))
+}
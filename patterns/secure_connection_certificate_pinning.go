@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/certpin"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// Certificate pinning: on top of the trust store validation, the public key of the broker certificate (or of its
+// CA) must be one of the pinned keys, see pkg/certpin. Every host of the host list is checked before connecting,
+// and the host reconnected to after every reconnection.
+//
+// Print the pins of the broker chain, then connect with one of them pinned:
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 go run secure_connection_certificate_pinning.go -print-pins
+//	SOLACE_HOST=tcps://broker.example.com:55443 SOLACE_PINS=sha256/AbC...= go run secure_connection_certificate_pinning.go
+//
+// -simulate-swap pins the key of a certificate generated on the fly instead, as if the broker certificate had been
+// swapped for another one also signed by a trusted CA: the connection is refused.
+
+// RandomPin - the pin of a newly generated key, matching no real certificate
+func RandomPin() (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "expected-broker"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", err
+	}
+	return certpin.Hash(certificate), nil
+}
+
+func main() {
+	printPins := flag.Bool("print-pins", false, "print the pins of the certificate chain of every host and exit")
+	simulateSwap := flag.Bool("simulate-swap", false, "pin a key the broker does not have, to see a swapped certificate refused")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	hosts := sampleconfig.Setting("SOLACE_HOST", "tcps://localhost:55443")
+	trustStore := sampleconfig.Setting("SOLACE_TRUST_STORE", "./trust_store")
+	pins := certpin.ParsePins(sampleconfig.Setting("SOLACE_PINS", ""))
+	if *simulateSwap {
+		pin, err := RandomPin()
+		if err != nil {
+			panic(err)
+		}
+		pins = []string{pin}
+		fmt.Println("Simulating a swapped broker certificate, pinned: ", pin)
+	}
+
+	pinner, err := certpin.New(trustStore, pins)
+	if err != nil {
+		panic(err)
+	}
+
+	if *printPins {
+		for _, host := range strings.Split(hosts, ",") {
+			chain, err := pinner.Chain(host)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", host, err)
+				continue
+			}
+			fmt.Println(strings.TrimSpace(host))
+			for _, certificate := range chain {
+				fmt.Printf("  %s  %s (valid until %s)\n", certpin.Hash(certificate), certificate.Subject, certificate.NotAfter.Format("2006-01-02"))
+			}
+		}
+		return
+	}
+	if len(pins) == 0 {
+		fmt.Fprintln(os.Stderr, "SOLACE_PINS is required, run with -print-pins to get the pins of the broker")
+		os.Exit(2)
+	}
+
+	// Check the pins before giving the hosts to the API
+	if err := pinner.CheckHosts(hosts); err != nil {
+		if errors.Is(err, certpin.ErrPinMismatch) {
+			fmt.Fprintln(os.Stderr, "Refusing to connect: ", err)
+			os.Exit(1)
+		}
+		panic(err)
+	}
+	fmt.Println("Broker certificates match the pins")
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the standard trust store validation still applies
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	brokerConfig.Properties[config.TransportLayerPropertyHost] = hosts
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithTransportSecurityStrategy(config.NewTransportSecurityStrategy().
+			WithCertificateValidation(false, true, trustStore, "")).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Check again after every reconnection, disconnecting on a mismatch
+	mismatch := make(chan error, 1)
+	pinner.Guard(messagingService, func(err error) {
+		select {
+		case mismatch <- err:
+		default:
+		}
+	})
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	select {
+	case err := <-mismatch:
+		fmt.Println("Disconnected after a reconnection: ", err)
+	case <-c:
+		// Disconnect the Message Service
+		messagingService.Disconnect()
+		fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+	}
+}
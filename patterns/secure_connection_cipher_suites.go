@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// TLS cipher suites and protocols: the transport security strategy restricts the TLS handshake of the API to a list
+// of cipher suites (OpenSSL names, in order of preference) and excludes protocol versions.
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 go run secure_connection_cipher_suites.go \
+//		-ciphers ECDHE-RSA-AES256-GCM-SHA384,ECDHE-RSA-AES128-GCM-SHA256 -exclude SSLv3,TLSv1,TLSv1.1
+//
+// The API does not report what was negotiated, so after connecting the sample audits the broker with its own
+// handshake restricted to the same protocols and cipher suites, and prints the negotiated protocol and cipher suite
+// and the certificate presented by the broker.
+
+// openSSLCipherSuites - the OpenSSL names of the TLSv1.2 cipher suites known to the crypto/tls package
+var openSSLCipherSuites = map[string]uint16{
+	"ECDHE-ECDSA-AES256-GCM-SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"ECDHE-RSA-AES256-GCM-SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"ECDHE-ECDSA-AES128-GCM-SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"ECDHE-RSA-AES128-GCM-SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"ECDHE-ECDSA-CHACHA20-POLY1305": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	"ECDHE-RSA-CHACHA20-POLY1305":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	"ECDHE-ECDSA-AES256-SHA":        tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"ECDHE-RSA-AES256-SHA":          tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"ECDHE-ECDSA-AES128-SHA":        tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"ECDHE-RSA-AES128-SHA":          tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"AES256-GCM-SHA384":             tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"AES128-GCM-SHA256":             tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"AES256-SHA":                    tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"AES128-SHA":                    tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+}
+
+// tlsVersions - the protocols of the API with their crypto/tls version, from the oldest to the newest
+var tlsVersions = []struct {
+	protocol config.TransportSecurityProtocol
+	version  uint16
+}{
+	{config.TransportSecurityProtocolSSLv3, 0x0300},
+	{config.TransportSecurityProtocolTLSv1, tls.VersionTLS10},
+	{config.TransportSecurityProtocolTLSv1_1, tls.VersionTLS11},
+	{config.TransportSecurityProtocolTLSv1_2, tls.VersionTLS12},
+}
+
+// ParseProtocols - the protocols of a comma separated list
+func ParseProtocols(list string) ([]config.TransportSecurityProtocol, error) {
+	var protocols []config.TransportSecurityProtocol
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, known := range tlsVersions {
+			if strings.EqualFold(name, string(known.protocol)) {
+				protocols = append(protocols, known.protocol)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown protocol %s", name)
+		}
+	}
+	return protocols, nil
+}
+
+// AuditConfig - a crypto/tls configuration restricted like the API: the versions not excluded and the cipher
+// suites of the list, the broker certificate validated against the trust store
+func AuditConfig(serverName, ciphers string, excluded []config.TransportSecurityProtocol, trustStore string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: serverName}
+	for _, name := range strings.Split(ciphers, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := openSSLCipherSuites[name]
+		if !ok {
+			fmt.Printf("Cipher suite %s can not be audited, it is not supported by crypto/tls\n", name)
+			continue
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+	}
+
+	for _, known := range tlsVersions {
+		isExcluded := false
+		for _, protocol := range excluded {
+			isExcluded = isExcluded || protocol == known.protocol
+		}
+		if isExcluded {
+			continue
+		}
+		if tlsConfig.MinVersion == 0 {
+			tlsConfig.MinVersion = known.version
+		}
+		tlsConfig.MaxVersion = known.version
+	}
+	if tlsConfig.MinVersion == 0 {
+		return nil, fmt.Errorf("every protocol is excluded")
+	}
+	if tlsConfig.MinVersion < tls.VersionTLS10 {
+		// SSLv3 is not supported by crypto/tls
+		tlsConfig.MinVersion = tls.VersionTLS10
+	}
+
+	// The trust store directory holds PEM files, like the one of the API
+	tlsConfig.RootCAs = x509.NewCertPool()
+	files, err := ioutil.ReadDir(trustStore)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(filepath.Join(trustStore, file.Name()))
+		if err == nil {
+			tlsConfig.RootCAs.AppendCertsFromPEM(contents)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// AuditTLS - performs a handshake with the first host of the host list and prints the negotiated parameters
+func AuditTLS(hosts string, tlsConfig *tls.Config) error {
+	host, err := url.Parse(strings.TrimSpace(strings.Split(hosts, ",")[0]))
+	if err != nil {
+		return err
+	}
+	address := host.Host
+	if host.Port() == "" {
+		address = net.JoinHostPort(host.Hostname(), "55443")
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host.Hostname()
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", address, tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	fmt.Printf("TLS audit of %s at %s\n", address, time.Now().Format(time.RFC3339))
+	fmt.Printf("  protocol:      %s\n", versionName(state.Version))
+	fmt.Printf("  cipher suite:  %s\n", tls.CipherSuiteName(state.CipherSuite))
+	for i, certificate := range state.PeerCertificates {
+		fmt.Printf("  certificate %d: %s\n", i, certificate.Subject)
+		fmt.Printf("    issuer:      %s\n", certificate.Issuer)
+		fmt.Printf("    valid until: %s\n", certificate.NotAfter.Format(time.RFC3339))
+		if len(certificate.DNSNames) > 0 {
+			fmt.Printf("    names:       %s\n", strings.Join(certificate.DNSNames, ", "))
+		}
+	}
+	return nil
+}
+
+// versionName - the name of a TLS version as the API spells it
+func versionName(version uint16) string {
+	for _, known := range tlsVersions {
+		if known.version == version {
+			return string(known.protocol)
+		}
+	}
+	if version == tls.VersionTLS13 {
+		return "TLSv1.3"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func main() {
+	ciphers := flag.String("ciphers", "ECDHE-RSA-AES256-GCM-SHA384,ECDHE-ECDSA-AES256-GCM-SHA384,ECDHE-RSA-AES128-GCM-SHA256,ECDHE-ECDSA-AES128-GCM-SHA256",
+		"cipher suites allowed in the handshake, OpenSSL names in order of preference")
+	exclude := flag.String("exclude", "SSLv3,TLSv1,TLSv1.1", "protocols excluded from the handshake")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	excluded, err := ParseProtocols(*exclude)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	trustStore := sampleconfig.Setting("SOLACE_TRUST_STORE", "./trust_store")
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the default host is the TLS port of a local broker
+	sampleconfig.SetDefault("SOLACE_HOST", "tcps://localhost:55443")
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	transportSecurity := config.NewTransportSecurityStrategy().
+		WithCertificateValidation(false, true, trustStore, "").
+		WithCipherSuites(*ciphers).
+		WithExcludedProtocols(excluded...)
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithTransportSecurityStrategy(transportSecurity).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+	fmt.Printf("Configured cipher suites: %s\n", *ciphers)
+	fmt.Printf("Excluded protocols:       %v\n", excluded)
+
+	auditConfig, err := AuditConfig("", *ciphers, excluded, trustStore)
+	if err == nil {
+		err = AuditTLS(brokerConfig.Properties[config.TransportLayerPropertyHost].(string), auditConfig)
+	}
+	if err != nil {
+		fmt.Println("TLS audit failed: ", err)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the messaging service===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("\nMessaging Service Disconnected? ", !messagingService.IsConnected())
+}
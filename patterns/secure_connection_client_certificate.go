@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+	"solace.dev/go/messaging/pkg/solace/subcode"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Mutual TLS: the client authenticates with a client certificate instead of a username and password.
+// The broker must have client certificate authentication enabled on the message VPN and trust the CA that issued
+// the client certificate. The client username is taken from the certificate common name by default.
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 SOLACE_CLIENT_CERT=client.pem SOLACE_CLIENT_KEY=client.key \
+//		go run secure_connection_client_certificate.go -trust-store ./trust_store
+//
+// Both the certificate and the private key are PEM files, an encrypted private key needs SOLACE_CLIENT_KEY_PASSWORD.
+
+// CheckClientCertificate - loads the client certificate to report problems before connecting: a missing file, a key
+// not matching the certificate or an expired certificate fail much more clearly here than during the TLS handshake.
+// Encrypted private keys are left to the API to decrypt.
+func CheckClientCertificate(certFile, keyFile, keyPassword string) error {
+	contents, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return fmt.Errorf("could not read the client certificate: %w", err)
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return fmt.Errorf("no PEM encoded certificate found in '%s'", certFile)
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("could not parse the client certificate: %w", err)
+	}
+
+	now := time.Now()
+	if now.Before(certificate.NotBefore) {
+		return fmt.Errorf("client certificate '%s' is not valid before %s", certificate.Subject, certificate.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(certificate.NotAfter) {
+		return fmt.Errorf("client certificate '%s' expired on %s", certificate.Subject, certificate.NotAfter.Format(time.RFC3339))
+	}
+
+	if keyPassword == "" {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return fmt.Errorf("could not load the private key of the client certificate: %w", err)
+		}
+	}
+
+	fmt.Printf("Client certificate '%s' valid until %s\n", certificate.Subject, certificate.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// ExplainConnectError - turns the subcodes of the common client certificate failures into an actionable message
+func ExplainConnectError(err error) string {
+	var nativeErr *solace.NativeError
+	if !errors.As(err, &nativeErr) {
+		return err.Error()
+	}
+	switch nativeErr.SubCode() {
+	case subcode.FailedLoadingCertificateAndKey:
+		return "the client certificate or private key could not be loaded, check the files and the key password: " + err.Error()
+	case subcode.UntrustedClientCertificate:
+		return "the broker does not trust the client certificate, add its issuing CA to the broker certificate authorities: " + err.Error()
+	case subcode.ClientCertificateDateInvalid:
+		return "the broker rejected the client certificate as expired or not yet valid: " + err.Error()
+	case subcode.UntrustedCertificate, subcode.FailedLoadingTruststore:
+		return "the broker certificate is not trusted, check the trust store: " + err.Error()
+	case subcode.CertificateDateInvalid:
+		return "the broker certificate is expired or not yet valid: " + err.Error()
+	case subcode.LoginFailure:
+		return "the broker rejected the login, check client certificate authentication is enabled on the message VPN " +
+			"and the certificate maps to an existing client username: " + err.Error()
+	}
+	return err.Error()
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	trustStore := flag.String("trust-store", "./trust_store", "trust store directory holding the PEM CA certificates of the broker certificate")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	certFile := sampleconfig.Setting("SOLACE_CLIENT_CERT", "client.pem")
+	keyFile := sampleconfig.Setting("SOLACE_CLIENT_KEY", "client.key")
+	keyPassword := sampleconfig.Setting("SOLACE_CLIENT_KEY_PASSWORD", "")
+
+	if err := CheckClientCertificate(certFile, keyFile, keyPassword); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Configuration parameters, client certificate authentication requires a tcps:// connection
+	brokerConfig := config.ServicePropertyMap{
+		config.TransportLayerPropertyHost:                                   sampleconfig.Setting("SOLACE_HOST", "tcps://localhost:55443"),
+		config.ServicePropertyVPNName:                                       sampleconfig.Setting("SOLACE_VPN", "default"),
+		config.AuthenticationPropertyScheme:                                 config.AuthenticationSchemeClientCertificate,
+		config.AuthenticationPropertySchemeSSLClientCertFile:                certFile,
+		config.AuthenticationPropertySchemeSSLClientPrivateKeyFile:          keyFile,
+		config.AuthenticationPropertySchemeClientCertPrivateKeyFilePassword: keyPassword,
+		config.TransportLayerSecurityPropertyTrustStorePath:                 *trustStore,
+	}
+	// Override the client username derived from the certificate, if the broker allows it
+	if username := sampleconfig.Setting("SOLACE_USERNAME", ""); username != "" {
+		brokerConfig[config.AuthenticationPropertySchemeClientCertUserName] = username
+	}
+
+	// With -wait-for-broker (or SOLACE_WAIT_FOR_BROKER), wait for the broker to be ready before connecting
+	if err := sampleconfig.WaitForBroker(context.Background(), brokerConfig); err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		fmt.Println("Could not connect with the client certificate:", ExplainConnectError(err))
+		os.Exit(1)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// Build a Direct Message Receiver on all of the sample topics
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/>")).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Receiver
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	// Register Message callback handler to the Message Receiver
+	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/securedefaults"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Strict TLS: the messaging service is built with pkg/securedefaults, which starts from full certificate
+// validation with only TLSv1.2 and newer allowed, and refuses to build the service when the configuration weakens
+// it: a plaintext host, certificate validation turned off, a downgrade to plaintext after login...
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 SOLACE_TRUST_STORE=./trust_store go run secure_connection_strict_tls.go
+//
+// Any of these is refused, as they would be if they came from a forgotten development configuration:
+//
+//	SOLACE_HOST=tcp://broker.example.com:55555 go run secure_connection_strict_tls.go
+//	SOLACE_TLS_DOWNGRADE=PLAIN_TEXT go run secure_connection_strict_tls.go
+//	SOLACE_TLS_VALIDATE=false go run secure_connection_strict_tls.go
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the default host is the TLS port of a local broker
+	sampleconfig.SetDefault("SOLACE_HOST", "tcps://localhost:55443")
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	// Settings typically found in development configurations, refused by securedefaults
+	if validate, ok := os.LookupEnv("SOLACE_TLS_VALIDATE"); ok {
+		brokerConfig.Properties[config.TransportLayerSecurityPropertyCertValidated] = validate
+	}
+	if downgrade, ok := os.LookupEnv("SOLACE_TLS_DOWNGRADE"); ok {
+		brokerConfig.Properties[config.TransportLayerSecurityPropertyProtocolDowngradeTo] = downgrade
+	}
+
+	messagingService, err := securedefaults.NewMessagingServiceBuilder(sampleconfig.Setting("SOLACE_TRUST_STORE", "./trust_store")).
+		FromConfigurationProvider(brokerConfig.Properties).
+		Build()
+
+	if errors.Is(err, securedefaults.ErrInsecure) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker with strict TLS? ", messagingService.IsConnected())
+
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/direct/sub/>")).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// TLS connection validating the broker certificate against a custom trust store, e.g. a private CA.
+//
+//	SOLACE_HOST=tcps://broker.example.com:55443 go run secure_connection_trust_store.go -ca-file ./ca.pem
+//	SOLACE_HOST=tcps://broker.example.com:55443 go run secure_connection_trust_store.go -trust-store ./trust_store -min-tls TLSv1.2
+//
+// The API reads the trusted CA certificates from a trust store directory holding PEM files. A single CA file is
+// copied into a temporary trust store directory.
+
+// tlsProtocols - the protocols supported by the API, from the oldest to the newest
+var tlsProtocols = []config.TransportSecurityProtocol{
+	config.TransportSecurityProtocolSSLv3,
+	config.TransportSecurityProtocolTLSv1,
+	config.TransportSecurityProtocolTLSv1_1,
+	config.TransportSecurityProtocolTLSv1_2,
+}
+
+// ProtocolsBelow - returns the protocols older than the minimum version, they are excluded from the TLS handshake
+func ProtocolsBelow(minimum string) ([]config.TransportSecurityProtocol, error) {
+	for i, protocol := range tlsProtocols {
+		if string(protocol) == minimum {
+			return tlsProtocols[:i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown TLS protocol '%s', expected one of %v", minimum, tlsProtocols)
+}
+
+// TrustStoreFromCAFile - validates the PEM encoded CA certificate file and copies it into a new trust store directory.
+// The caller removes the directory once the messaging service is disconnected.
+func TrustStoreFromCAFile(caFile string) (string, error) {
+	contents, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail early with a clear message instead of a handshake failure later on
+	certificates := 0
+	for rest := contents; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("invalid certificate in '%s': %w", caFile, err)
+		}
+		if time.Now().After(certificate.NotAfter) {
+			fmt.Printf("Warning: CA certificate '%s' expired on %s\n", certificate.Subject, certificate.NotAfter.Format(time.RFC3339))
+		}
+		certificates++
+	}
+	if certificates == 0 {
+		return "", fmt.Errorf("no PEM encoded certificate found in '%s'", caFile)
+	}
+
+	trustStore, err := ioutil.TempDir("", "trust_store")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(trustStore, filepath.Base(caFile)), contents, 0600); err != nil {
+		os.RemoveAll(trustStore)
+		return "", err
+	}
+	return trustStore, nil
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	caFile := flag.String("ca-file", "", "PEM file holding the CA certificate(s) the broker certificate is validated against")
+	trustStore := flag.String("trust-store", "./trust_store", "trust store directory holding PEM CA certificates (ignored with -ca-file)")
+	validateServerName := flag.Bool("validate-server-name", true, "check that the broker certificate matches the host name in SOLACE_HOST")
+	ignoreExpiration := flag.Bool("ignore-expiration", false, "accept an expired broker certificate (never do this in production)")
+	trustedCommonNames := flag.String("trusted-common-names", "", "space separated list of accepted certificate common names (legacy, prefer -validate-server-name)")
+	minTLS := flag.String("min-tls", "TLSv1.2", "oldest TLS protocol version accepted: SSLv3, TLSv1, TLSv1.1 or TLSv1.2")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	excludedProtocols, err := ProtocolsBelow(*minTLS)
+	if err != nil {
+		panic(err)
+	}
+
+	trustStorePath := *trustStore
+	removeTrustStore := func() {}
+	if *caFile != "" {
+		trustStorePath, err = TrustStoreFromCAFile(*caFile)
+		if err != nil {
+			panic(err)
+		}
+		removeTrustStore = func() { os.RemoveAll(trustStorePath) }
+	}
+	defer removeTrustStore()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig),
+	// the tcps:// scheme of the default host enables TLS
+	sampleconfig.SetDefault("SOLACE_HOST", "tcps://localhost:55443")
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	transportSecurity := config.NewTransportSecurityStrategy().
+		WithCertificateValidation(*ignoreExpiration, *validateServerName, trustStorePath, *trustedCommonNames)
+	if len(excludedProtocols) > 0 {
+		transportSecurity = transportSecurity.WithExcludedProtocols(excludedProtocols...)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig.Properties).
+		WithTransportSecurityStrategy(transportSecurity).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service, an untrusted or invalid broker certificate fails here
+	if err := messagingService.Connect(); err != nil {
+		fmt.Printf("TLS connection failed, check the trust store '%s' holds the CA of the broker certificate: %s\n", trustStorePath, err)
+		removeTrustStore()
+		os.Exit(1)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+	fmt.Printf("Excluded protocols: %v\n", excludedProtocols)
+
+	// Build a Direct Message Receiver on all of the sample topics
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/>")).
+		Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Start Direct Message Receiver
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Direct Receiver running? ", directReceiver.IsRunning())
+
+	// Register Message callback handler to the Message Receiver
+	if regErr := directReceiver.ReceiveAsync(MessageHandler); regErr != nil {
+		panic(regErr)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the receiver===")
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+
+	// Terminate the Direct Receiver
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
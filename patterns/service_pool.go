@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"SolaceSamples.com/PubSub+Go/pkg/servicepool"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Service pool: pkg/servicepool keeps a few messaging services connected, publishes are spread over them round
+// robin and the receivers are placed on the least loaded one. Services that give up reconnecting are replaced, with
+// their receivers, by the health check of the pool. Disconnect one of the clients (e.g. from the broker CLI or
+// the PubSub+ Manager) to see it replaced.
+//
+//	go run service_pool.go -size 3
+
+// MessageHandler - Message Handler
+func MessageHandler(name string) solace.MessageHandler {
+	return func(message message.InboundMessage) {
+		payload, _ := message.GetPayloadAsString()
+		fmt.Printf("[%s] Received Message Body %s\n", name, payload)
+	}
+}
+
+// DirectReceiver - a receiver factory for a direct receiver of the topic subscriptions
+func DirectReceiver(handler solace.MessageHandler, topics ...string) servicepool.ReceiverFactory {
+	return func(messagingService solace.MessagingService) (solace.LifecycleControl, error) {
+		var subscriptions []resource.Subscription
+		for _, topic := range topics {
+			subscriptions = append(subscriptions, resource.TopicSubscriptionOf(topic))
+		}
+		receiver, err := messagingService.CreateDirectMessageReceiverBuilder().WithSubscriptions(subscriptions...).Build()
+		if err != nil {
+			return nil, err
+		}
+		if err := receiver.Start(); err != nil {
+			return nil, err
+		}
+		if err := receiver.ReceiveAsync(handler); err != nil {
+			receiver.Terminate(0)
+			return nil, err
+		}
+		return receiver, nil
+	}
+}
+
+func main() {
+	size := flag.Int("size", 3, "number of messaging services in the pool")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	hostname, _ := os.Hostname()
+	pool, err := servicepool.New(brokerConfig.Properties, *size,
+		servicepool.WithApplicationID(fmt.Sprintf("pool/%s/%d", hostname, os.Getpid())),
+		// give up reconnecting after about 30 seconds, the pool replaces the service then
+		servicepool.WithReconnectionRetryStrategy(config.RetryStrategyParameterizedRetry(10, 3*time.Second)),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Pool of %d messaging services connected\n", *size)
+
+	for _, name := range []string{"orders", "payments"} {
+		topic := TopicPrefix + "/pool/" + name
+		if err := pool.AddReceiver(name, DirectReceiver(MessageHandler(name), topic)); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the pool===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	status := time.NewTicker(10 * time.Second)
+	defer status.Stop()
+	for msgSeqNum := 0; ; msgSeqNum++ {
+		select {
+		case <-c:
+			// Terminates the receivers and publishers and disconnects every service
+			pool.Close(1 * time.Second)
+			fmt.Println("\nPool closed")
+			return
+		case <-status.C:
+			for _, member := range pool.Status() {
+				fmt.Printf("service %d %s connected=%t reconnecting=%t healthy=%t replacements=%d receivers=%v\n",
+					member.Index, member.ApplicationID, member.Connected, member.Reconnecting, member.Healthy, member.Replacements, member.Receivers)
+			}
+		case <-time.After(1 * time.Second):
+		}
+
+		// Ask the pool for a publisher on every publish, the one of a replaced service is terminated
+		publisher, err := pool.DirectPublisher()
+		if err != nil {
+			fmt.Println("Could not publish: ", err)
+			continue
+		}
+		for _, name := range []string{"orders", "payments"} {
+			if err := publisher.PublishString("Hello from the pool --> "+strconv.Itoa(msgSeqNum), resource.TopicOf(TopicPrefix+"/pool/"+name)); err != nil {
+				fmt.Println("Publish failed: ", err)
+			}
+		}
+	}
+}
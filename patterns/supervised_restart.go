@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Supervised restart: the service interruption listener is called when the connection is lost for good, i.e. the
+// reconnection attempts are exhausted or the broker refused the client (e.g. it was shut down by an administrator or
+// its credentials were revoked). The messaging service and everything created from it can not be used anymore, so
+// the supervisor tears them down and builds a new messaging service from scratch, with an exponential backoff
+// between the restarts that is reset once a session stayed up long enough.
+
+const (
+	minRestartDelay = 1 * time.Second
+	maxRestartDelay = 1 * time.Minute
+	// a session up for this long resets the backoff
+	stableAfter = 2 * time.Minute
+)
+
+// Session - a messaging service and the receivers and publishers created from it
+type Session struct {
+	messagingService solace.MessagingService
+	directReceiver   solace.DirectMessageReceiver
+	// receives the cause of the interruption, at most once
+	interrupted chan error
+}
+
+// StartSession - builds and connects a new messaging service and starts the receiver, everything started is torn
+// down again on error
+func StartSession(brokerConfig config.ServicePropertyMap) (*Session, error) {
+	// Give up reconnecting after about a minute, the supervisor takes over from there
+	messagingService, err := messaging.NewMessagingServiceBuilder().
+		FromConfigurationProvider(brokerConfig).
+		WithReconnectionRetryStrategy(config.RetryStrategyParameterizedRetry(20, 3*time.Second)).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{messagingService: messagingService, interrupted: make(chan error, 1)}
+	messagingService.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		select {
+		case session.interrupted <- event.GetCause():
+		default:
+		}
+	})
+	messagingService.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		fmt.Printf("Reconnecting to %s: %v\n", event.GetBrokerURI(), event.GetCause())
+	})
+
+	if err := messagingService.Connect(); err != nil {
+		return nil, err
+	}
+
+	session.directReceiver, err = messagingService.CreateDirectMessageReceiverBuilder().
+		WithSubscriptions(resource.TopicSubscriptionOf(TopicPrefix + "/direct/sub/>")).
+		Build()
+	if err == nil {
+		err = session.directReceiver.Start()
+	}
+	if err == nil {
+		err = session.directReceiver.ReceiveAsync(MessageHandler)
+	}
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// Close - terminates the receiver and disconnects, errors are ignored: after an interruption the service is already
+// down and only its resources are released
+func (s *Session) Close() {
+	if s.directReceiver != nil {
+		s.directReceiver.Terminate(1 * time.Second)
+	}
+	s.messagingService.Disconnect()
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the supervisor===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	delay := minRestartDelay
+	for restarts := 0; ; restarts++ {
+		if restarts > 0 {
+			fmt.Printf("Restarting in %s\n", delay)
+			select {
+			case <-c:
+				fmt.Println("\nSupervisor stopped while waiting to restart")
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		session, err := StartSession(brokerConfig.Properties)
+		if err != nil {
+			fmt.Println("Could not start the session: ", err)
+			delay = nextDelay(delay)
+			continue
+		}
+		started := time.Now()
+		fmt.Printf("Session started (restarts: %d), connected to the broker? %t\n", restarts, session.messagingService.IsConnected())
+
+		select {
+		case <-c:
+			session.Close()
+			fmt.Println("\nDirect Receiver Terminated? ", session.directReceiver.IsTerminated())
+			fmt.Println("Messaging Service Disconnected? ", !session.messagingService.IsConnected())
+			return
+		case cause := <-session.interrupted:
+			fmt.Printf("Service interrupted after %s: %v, tearing the session down\n", time.Since(started).Round(time.Second), cause)
+			session.Close()
+		}
+
+		if time.Since(started) >= stableAfter {
+			delay = minRestartDelay
+		} else {
+			delay = nextDelay(delay)
+		}
+	}
+}
+
+// nextDelay - doubles the restart delay up to the maximum
+func nextDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxRestartDelay {
+		delay = maxRestartDelay
+	}
+	return delay
+}
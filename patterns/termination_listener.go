@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// Termination notifications: a publisher or receiver can be terminated by the application (graceful termination)
+// or by the API when something goes wrong that it cannot recover from, e.g. the queue a receiver is bound to is
+// deleted or shut down on the broker. The termination listener registered on the publisher/receiver is notified
+// in the second case, which is the signal for the application to re-create the resource.
+//
+// To see it in action, run this sample and then delete or shutdown the queue (durable-queue by default)
+// from the broker management console. Re-create/enable the queue and the receiver is bound again.
+
+// ManagedResource - keeps a publisher or receiver running: when it is terminated by the API, a new one is created
+// until the application shuts the resource down itself
+type ManagedResource struct {
+	name          string
+	create        func(listener solace.TerminationNotificationListener) (solace.LifecycleControl, error)
+	retryInterval time.Duration
+
+	mu           sync.Mutex
+	current      solace.LifecycleControl
+	shuttingDown bool
+}
+
+// NewManagedResource - the create function must build the resource, register the given termination listener on it
+// and start it
+func NewManagedResource(name string, retryInterval time.Duration, create func(listener solace.TerminationNotificationListener) (solace.LifecycleControl, error)) *ManagedResource {
+	return &ManagedResource{name: name, create: create, retryInterval: retryInterval}
+}
+
+// Start - creates the resource for the first time
+func (r *ManagedResource) Start() error {
+	created, err := r.create(r.onTermination)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.current = created
+	r.mu.Unlock()
+	return nil
+}
+
+// onTermination - termination listener distinguishing graceful and failure-driven termination
+func (r *ManagedResource) onTermination(event solace.TerminationEvent) {
+	r.mu.Lock()
+	graceful := r.shuttingDown
+	r.mu.Unlock()
+
+	if graceful {
+		fmt.Printf("[%s] terminated gracefully at %s: %s\n", r.name, event.GetTimestamp().Format(time.RFC3339), event.GetMessage())
+		return
+	}
+
+	fmt.Printf("[%s] terminated unexpectedly at %s: %s\n", r.name, event.GetTimestamp().Format(time.RFC3339), event.GetMessage())
+	if cause := event.GetCause(); cause != nil {
+		fmt.Printf("[%s] cause: %s\n", r.name, cause)
+	}
+
+	// Never block the API callback, re-create the resource on a separate goroutine
+	go r.recreate()
+}
+
+// recreate - attempts to create the resource again until it succeeds or the application shuts down
+func (r *ManagedResource) recreate() {
+	for attempt := 1; ; attempt++ {
+		r.mu.Lock()
+		if r.shuttingDown {
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+
+		created, err := r.create(r.onTermination)
+		if err == nil {
+			r.mu.Lock()
+			if r.shuttingDown {
+				// Terminate was called while the resource was being created, it would never be terminated otherwise
+				r.mu.Unlock()
+				created.Terminate(0)
+				return
+			}
+			r.current = created
+			r.mu.Unlock()
+			fmt.Printf("[%s] re-created after %d attempt(s)\n", r.name, attempt)
+			return
+		}
+		fmt.Printf("[%s] re-create attempt %d failed, retrying in %s: %s\n", r.name, attempt, r.retryInterval, err)
+		time.Sleep(r.retryInterval)
+	}
+}
+
+// Current - returns the resource currently in use
+func (r *ManagedResource) Current() solace.LifecycleControl {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Terminate - gracefully terminates the resource, it is not re-created afterwards
+func (r *ManagedResource) Terminate(gracePeriod time.Duration) error {
+	r.mu.Lock()
+	r.shuttingDown = true
+	current := r.current
+	r.mu.Unlock()
+
+	if current == nil {
+		return nil
+	}
+	return current.Terminate(gracePeriod)
+}
+
+// MessageHandler - Message Handler
+func MessageHandler(message message.InboundMessage) {
+	payload, _ := message.GetPayloadAsString()
+	fmt.Printf("Received Message Body %s\n", payload)
+}
+
+func main() {
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Connect to the messaging service
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	queueName := "durable-queue"
+	topic := resource.TopicOf(TopicPrefix + "/persistent/publisher")
+
+	// Persistent receiver, re-bound to the queue whenever it is terminated by the API
+	receiver := NewManagedResource("receiver", 5*time.Second, func(listener solace.TerminationNotificationListener) (solace.LifecycleControl, error) {
+		persistentReceiver, err := messagingService.CreatePersistentMessageReceiverBuilder().
+			WithMessageAutoAcknowledgement().
+			Build(resource.QueueDurableExclusive(queueName))
+		if err != nil {
+			return nil, err
+		}
+		// Register the listener before starting so no termination event can be missed
+		persistentReceiver.SetTerminationNotificationListener(listener)
+		if err := persistentReceiver.Start(); err != nil {
+			return nil, err
+		}
+		if err := persistentReceiver.ReceiveAsync(MessageHandler); err != nil {
+			persistentReceiver.Terminate(0)
+			return nil, err
+		}
+		return persistentReceiver, nil
+	})
+
+	// Persistent publisher, re-created whenever it is terminated by the API
+	publisher := NewManagedResource("publisher", 5*time.Second, func(listener solace.TerminationNotificationListener) (solace.LifecycleControl, error) {
+		persistentPublisher, err := messagingService.CreatePersistentMessagePublisherBuilder().Build()
+		if err != nil {
+			return nil, err
+		}
+		persistentPublisher.SetTerminationNotificationListener(listener)
+		if err := persistentPublisher.Start(); err != nil {
+			return nil, err
+		}
+		return persistentPublisher, nil
+	})
+
+	if err := receiver.Start(); err != nil {
+		fmt.Printf("Make sure queue name '%s' exists on the broker.\n", queueName)
+		panic(err)
+	}
+	if err := publisher.Start(); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("\n Bound to queue: %s, publishing on: %s\n", queueName, topic.GetName())
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the publisher and receiver===")
+
+	stop := make(chan struct{})
+	go func() {
+		for msgSeqNum := 0; ; msgSeqNum++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(1 * time.Second):
+			}
+			// Always publish with the current publisher, it may have been re-created in the meantime
+			persistentPublisher, ok := publisher.Current().(solace.PersistentMessagePublisher)
+			if !ok || !persistentPublisher.IsReady() {
+				continue
+			}
+			if err := persistentPublisher.PublishString("Hello from Go Termination Listener Sample --> "+strconv.Itoa(msgSeqNum), topic); err != nil {
+				fmt.Println("Publish failed: ", err)
+			}
+		}
+	}()
+
+	// Run forever until an interrupt signal is received
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+
+	// Block until a signal is received.
+	<-c
+	close(stop)
+
+	// Graceful termination, the termination listeners see this as an application initiated termination
+	publisher.Terminate(1 * time.Second)
+	receiver.Terminate(1 * time.Second)
+	fmt.Println("\nPersistent Publisher Terminated? ", publisher.Current().IsTerminated())
+	fmt.Println("Persistent Receiver Terminated? ", receiver.Current().IsTerminated())
+
+	// Disconnect the Message Service
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"SolaceSamples.com/PubSub+Go/pkg/lifecycle"
+	"github.com/gorilla/websocket"
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Define Topic Prefix
+const TopicPrefix = "solace/samples"
+
+// WebSocket fan-out: browsers do not speak SMF, this server holds one connection to the broker and fans the messages
+// out to the browsers connected over WebSockets, each with its own topic subscriptions:
+//
+//	go run websocket_fanout_server.go -listen localhost:8090
+//
+// then open http://localhost:8090 in a few browser tabs and publish to solace/samples/... . A client subscribes with
+// the subscribe query parameters of the upgrade request, ws://localhost:8090/ws?subscribe=solace/samples/>, and
+// manages its subscriptions afterwards with JSON requests:
+//
+//	{"action": "subscribe", "topic": "solace/samples/*/hello/>"}
+//	{"action": "unsubscribe", "topic": "solace/samples/*/hello/>"}
+//
+// The messages arrive as {"type": "message", "topic": ..., "payload": ..., "properties": {...}}. The broker sees one
+// subscription per topic subscribed to by any client, added to the direct receiver by the first client and removed
+// with the last one. A client only subscribes under the -allowed-prefixes, at most -max-subscriptions topics. The
+// messages of a client are queued up to -client-buffer: a client too slow to keep up is evicted, closed with the
+// policy violation status, rather than slowing the others down or growing the memory of the server.
+
+// Request - a request of a client
+type Request struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+}
+
+// Event - a message or a reply sent to a client
+type Event struct {
+	Type       string            `json:"type"`
+	Topic      string            `json:"topic,omitempty"`
+	Payload    string            `json:"payload,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// TopicMatches - reports whether the topic matches the Solace subscription: * matches a level or, after a prefix, the
+// rest of a level, and a trailing > one or more levels
+func TopicMatches(subscription, topic string) bool {
+	subscriptionLevels, topicLevels := strings.Split(subscription, "/"), strings.Split(topic, "/")
+	for i, level := range subscriptionLevels {
+		if level == ">" && i == len(subscriptionLevels)-1 {
+			return len(topicLevels) > i
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if strings.HasSuffix(level, "*") {
+			if !strings.HasPrefix(topicLevels[i], strings.TrimSuffix(level, "*")) {
+				return false
+			}
+		} else if level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(subscriptionLevels) == len(topicLevels)
+}
+
+// Client - a browser connected over a WebSocket
+type Client struct {
+	conn *websocket.Conn
+	send chan []byte
+	// subscriptions are guarded by the lock of the hub
+	subscriptions map[string]bool
+	closeOnce     sync.Once
+	done          chan struct{}
+}
+
+// Matches - reports whether a subscription of the client matches the topic; the lock of the hub is held
+func (c *Client) Matches(topic string) bool {
+	for subscription := range c.subscriptions {
+		if TopicMatches(subscription, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close - closes the connection with the status and the reason, once
+func (c *Client) Close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		c.conn.Close()
+	})
+}
+
+// Hub - fans the messages of the direct receiver out to the clients, counting the clients of each subscription
+type Hub struct {
+	Receiver         solace.DirectMessageReceiver
+	AllowedPrefixes  []string
+	MaxSubscriptions int
+	ClientBuffer     int
+
+	// subscribing serializes the changes of subscriptions, held across the round trips to the broker so the
+	// dispatch of the messages does not wait for them
+	subscribing sync.Mutex
+	// mu guards the clients, their subscriptions and the counts
+	mu      sync.RWMutex
+	clients map[*Client]bool
+	refs    map[string]int
+	evicted atomic.Int64
+}
+
+// Add - registers a client
+func (h *Hub) Add(conn *websocket.Conn) *Client {
+	c := &Client{conn: conn, send: make(chan []byte, h.ClientBuffer), subscriptions: map[string]bool{}, done: make(chan struct{})}
+	h.mu.Lock()
+	h.clients[c] = true
+	h.mu.Unlock()
+	return c
+}
+
+// Remove - unregisters a client, removing the subscriptions no other client has from the receiver
+func (h *Hub) Remove(c *Client) {
+	h.subscribing.Lock()
+	defer h.subscribing.Unlock()
+	h.mu.Lock()
+	if !h.clients[c] {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.clients, c)
+	h.mu.Unlock()
+	for topic := range c.subscriptions {
+		h.release(c, topic)
+	}
+}
+
+// Subscribe - subscribes the client to the topic, the receiver too for the first client
+func (h *Hub) Subscribe(c *Client, topic string) error {
+	allowed := false
+	for _, prefix := range h.AllowedPrefixes {
+		allowed = allowed || strings.HasPrefix(topic, prefix)
+	}
+	if !allowed {
+		return fmt.Errorf("%s is not under the allowed prefixes %s", topic, strings.Join(h.AllowedPrefixes, ", "))
+	}
+	h.subscribing.Lock()
+	defer h.subscribing.Unlock()
+	h.mu.RLock()
+	registered := h.clients[c]
+	h.mu.RUnlock()
+	if !registered {
+		return errors.New("the client is closed")
+	}
+	if c.subscriptions[topic] {
+		return nil
+	}
+	if len(c.subscriptions) >= h.MaxSubscriptions {
+		return fmt.Errorf("at most %d subscriptions per client", h.MaxSubscriptions)
+	}
+	if h.refs[topic] == 0 {
+		// the first client of the topic, the broker is asked for it
+		if err := h.Receiver.AddSubscription(resource.TopicSubscriptionOf(topic)); err != nil {
+			return err
+		}
+	}
+	h.mu.Lock()
+	h.refs[topic]++
+	c.subscriptions[topic] = true
+	h.mu.Unlock()
+	return nil
+}
+
+// Unsubscribe - unsubscribes the client from the topic, the receiver too for the last client
+func (h *Hub) Unsubscribe(c *Client, topic string) {
+	h.subscribing.Lock()
+	defer h.subscribing.Unlock()
+	if c.subscriptions[topic] {
+		h.release(c, topic)
+	}
+}
+
+// release - removes the subscription of the client, and from the receiver with the last client; subscribing is held
+func (h *Hub) release(c *Client, topic string) {
+	h.mu.Lock()
+	delete(c.subscriptions, topic)
+	h.refs[topic]--
+	last := h.refs[topic] == 0
+	if last {
+		delete(h.refs, topic)
+	}
+	h.mu.Unlock()
+	if last {
+		if err := h.Receiver.RemoveSubscription(resource.TopicSubscriptionOf(topic)); err != nil {
+			fmt.Println("Could not remove the subscription: ", err)
+		}
+	}
+}
+
+// Dispatch - the message handler of the receiver, queuing the message for every client subscribed to its topic. It
+// never blocks: the clients with a full queue are evicted.
+func (h *Hub) Dispatch(inbound message.InboundMessage) {
+	event := Event{Type: "message", Topic: inbound.GetDestinationName(), Properties: map[string]string{}}
+	if payload, ok := inbound.GetPayloadAsString(); ok {
+		event.Payload = payload
+	} else if payload, ok := inbound.GetPayloadAsBytes(); ok {
+		event.Payload = string(payload)
+	}
+	for name, value := range inbound.GetProperties() {
+		event.Properties[name] = fmt.Sprint(value)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	var slow []*Client
+	h.mu.RLock()
+	for c := range h.clients {
+		if !c.Matches(event.Topic) {
+			continue
+		}
+		select {
+		case c.send <- data:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+	for _, c := range slow {
+		h.evicted.Add(1)
+		c.Close(websocket.ClosePolicyViolation, "too slow, evicted")
+		// the subscriptions are removed away from the callback of the receiver
+		go h.Remove(c)
+	}
+}
+
+// Clients - the number of clients and of the subscriptions of the receiver
+func (h *Hub) Clients() (clients, subscriptions int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients), len(h.refs)
+}
+
+// Serve - upgrades the request to a WebSocket and serves the client until it disconnects or is evicted
+func (h *Hub) Serve(upgrader *websocket.Upgrader, writeTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader replied with the error
+			return
+		}
+		c := h.Add(conn)
+		defer func() {
+			c.Close(websocket.CloseNormalClosure, "")
+			h.Remove(c)
+		}()
+		reply := func(event Event) {
+			data, _ := json.Marshal(event)
+			select {
+			case c.send <- data:
+			default:
+			}
+		}
+		for _, topic := range r.URL.Query()["subscribe"] {
+			if err := h.Subscribe(c, topic); err != nil {
+				reply(Event{Type: "error", Topic: topic, Error: err.Error()})
+			} else {
+				reply(Event{Type: "subscribed", Topic: topic})
+			}
+		}
+
+		go writeLoop(c, writeTimeout)
+
+		// the pongs keep the read deadline ahead, a client gone without closing is detected within two pings
+		conn.SetReadLimit(4096)
+		conn.SetReadDeadline(time.Now().Add(2 * PingInterval))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * PingInterval))
+		})
+		for {
+			var request Request
+			if err := conn.ReadJSON(&request); err != nil {
+				var syntaxErr *json.SyntaxError
+				if errors.As(err, &syntaxErr) {
+					reply(Event{Type: "error", Error: "invalid request: " + err.Error()})
+					continue
+				}
+				return
+			}
+			switch request.Action {
+			case "subscribe":
+				if err := h.Subscribe(c, request.Topic); err != nil {
+					reply(Event{Type: "error", Topic: request.Topic, Error: err.Error()})
+				} else {
+					reply(Event{Type: "subscribed", Topic: request.Topic})
+				}
+			case "unsubscribe":
+				h.Unsubscribe(c, request.Topic)
+				reply(Event{Type: "unsubscribed", Topic: request.Topic})
+			default:
+				reply(Event{Type: "error", Error: fmt.Sprintf("unknown action '%s', expected subscribe or unsubscribe", request.Action)})
+			}
+		}
+	}
+}
+
+// PingInterval - interval of the pings keeping the connections alive through proxies and detecting dead clients
+const PingInterval = 30 * time.Second
+
+// writeLoop - the only writer of the connection, sending the queued messages and the pings; a write that times out
+// evicts the client
+func writeLoop(c *Client, writeTimeout time.Duration) {
+	ping := time.NewTicker(PingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.Close(websocket.ClosePolicyViolation, "write timed out")
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				c.Close(websocket.CloseGoingAway, "ping failed")
+				return
+			}
+		}
+	}
+}
+
+// IndexPage - a page subscribing to the topic typed in and printing the messages
+const IndexPage = `<!DOCTYPE html>
+<html>
+<head><title>Solace WebSocket fan-out</title></head>
+<body>
+<h3>Solace WebSocket fan-out</h3>
+<input id="topic" size="50" value="solace/samples/>">
+<button onclick="send('subscribe')">Subscribe</button>
+<button onclick="send('unsubscribe')">Unsubscribe</button>
+<pre id="log"></pre>
+<script>
+const log = document.getElementById("log");
+const ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+ws.onmessage = (e) => { log.textContent = e.data + "\n" + log.textContent.slice(0, 20000); };
+ws.onclose = (e) => { log.textContent = "closed: " + e.code + " " + e.reason + "\n" + log.textContent; };
+function send(action) { ws.send(JSON.stringify({action: action, topic: document.getElementById("topic").value})); }
+</script>
+</body>
+</html>
+`
+
+func main() {
+	listen := flag.String("listen", "localhost:8090", "address the HTTP server listens on")
+	allowedPrefixes := flag.String("allowed-prefixes", TopicPrefix+"/", "comma separated topic prefixes the clients may subscribe under")
+	maxSubscriptions := flag.Int("max-subscriptions", 20, "subscriptions per client")
+	clientBuffer := flag.Int("client-buffer", 256, "messages queued per client, a client falling further behind is evicted")
+	writeTimeout := flag.Duration("write-timeout", 10*time.Second, "timeout of a write to a client, a client not reading for longer is evicted")
+	flag.Parse()
+
+	// API logs through log/slog, configured with SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE
+	apilog.Setup()
+
+	// Configuration parameters, from the flags, the environment variables and the -config file (see internal/sampleconfig)
+	brokerConfig, err := sampleconfig.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	messagingService, err := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(brokerConfig.Properties).Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := messagingService.Connect(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Connected to the broker? ", messagingService.IsConnected())
+
+	// One receiver for all the clients, its subscriptions follow the ones of the clients
+	directReceiver, err := messagingService.CreateDirectMessageReceiverBuilder().Build()
+	if err != nil {
+		panic(err)
+	}
+	if err := directReceiver.Start(); err != nil {
+		panic(err)
+	}
+	hub := &Hub{
+		Receiver:         directReceiver,
+		AllowedPrefixes:  strings.Split(*allowedPrefixes, ","),
+		MaxSubscriptions: *maxSubscriptions,
+		ClientBuffer:     *clientBuffer,
+		clients:          map[*Client]bool{},
+		refs:             map[string]int{},
+	}
+	if err := directReceiver.ReceiveAsync(hub.Dispatch); err != nil {
+		panic(err)
+	}
+
+	// The default origin check only accepts the pages served by this server, set CheckOrigin to accept other sites
+	upgrader := &websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", hub.Serve(upgrader, *writeTimeout))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, IndexPage)
+	})
+	server := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("HTTP server stopped: ", err)
+		}
+	}()
+
+	fmt.Printf("\n Open http://%s in a browser\n", *listen)
+	fmt.Println("\n===Interrupt (CTR+C) to handle graceful termination of the server===")
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, lifecycle.ShutdownSignals...)
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-ticker.C:
+			clients, subscriptions := hub.Clients()
+			fmt.Printf("clients=%d subscriptions=%d evicted=%d\n", clients, subscriptions, hub.evicted.Load())
+		case <-c:
+			break wait
+		}
+	}
+
+	// Shutdown does not wait for the hijacked WebSocket connections, they are closed with the receiver
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	hub.mu.RLock()
+	for client := range hub.clients {
+		client.Close(websocket.CloseGoingAway, "server shutting down")
+	}
+	hub.mu.RUnlock()
+	directReceiver.Terminate(1 * time.Second)
+	fmt.Println("\nDirect Receiver Terminated? ", directReceiver.IsTerminated())
+	messagingService.Disconnect()
+	fmt.Println("Messaging Service Disconnected? ", !messagingService.IsConnected())
+}
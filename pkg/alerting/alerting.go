@@ -0,0 +1,341 @@
+// Package alerting notifies operators of what happens to the messaging services, rather than only recovering from it:
+// an Alerter sends an Alert, to stdout or to a webhook (generic JSON or a Slack incoming webhook), and Watch fires
+// alerts from the reconnection and interruption listeners of a service:
+//
+//   - warning when the connection is lost and the service starts reconnecting, once per outage
+//   - info, resolved, when the service is reconnected, with the duration of the outage and the attempts it took
+//   - critical when the service is interrupted, the reconnection attempts are exhausted
+//
+// Usage:
+//
+//	alerter := alerting.FromEnv()
+//	alerting.Watch("orders-receiver", messagingService, alerter)
+//	messagingService.Connect()
+//
+// FromEnv prints the alerts to stdout and posts them to SOLACE_ALERT_WEBHOOK as well when it is set, as a Slack message
+// for Slack URLs or with SOLACE_ALERT_FORMAT=slack, as the JSON of the Alert otherwise (SOLACE_ALERT_FORMAT=generic).
+// Other notification channels implement Alerter, or use Func.
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace"
+)
+
+// Severity of an alert
+type Severity string
+
+// The severities, from the least to the most urgent
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+// The events of the alerts fired by Watch
+const (
+	EventReconnecting = "reconnecting"
+	EventReconnected  = "reconnected"
+	EventInterrupted  = "interrupted"
+)
+
+// Alert is a notification, it is posted as JSON to the generic webhooks
+type Alert struct {
+	Time     time.Time `json:"time"`
+	Severity Severity  `json:"severity"`
+	// Source is what the alert is about, e.g. the name of the service or of the queue
+	Source string `json:"source"`
+	// Event is what happened, e.g. reconnecting
+	Event   string `json:"event"`
+	Summary string `json:"summary"`
+	// Resolved is true when the alert ends a previous one, e.g. reconnected after reconnecting
+	Resolved bool                   `json:"resolved"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// Alerter sends alerts, it must be safe for concurrent use
+type Alerter interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// Func is an Alerter calling the function
+type Func func(ctx context.Context, alert Alert) error
+
+// Send calls the function
+func (f Func) Send(ctx context.Context, alert Alert) error {
+	return f(ctx, alert)
+}
+
+// Multi sends the alerts to every alerter, it returns their errors joined
+type Multi []Alerter
+
+// Send sends the alert to every alerter, even when one of them fails
+func (m Multi) Send(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, alerter := range m {
+		if err := alerter.Send(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stdout prints the alerts, one line each followed by the details
+type Stdout struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdout returns an alerter printing to w, os.Stdout when nil
+func NewStdout(w io.Writer) *Stdout {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &Stdout{w: w}
+}
+
+// Send prints the alert
+func (s *Stdout) Send(ctx context.Context, alert Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintf(s.w, "%s %s\n", alert.Time.Format("15:04:05.000"), headline(alert))
+	for _, key := range sortedKeys(alert.Details) {
+		if err == nil {
+			_, err = fmt.Fprintf(s.w, "  %s: %s\n", key, formatValue(alert.Details[key]))
+		}
+	}
+	return err
+}
+
+// headline renders the severity, the source, the event and the summary of the alert
+func headline(alert Alert) string {
+	state := "ALERT"
+	if alert.Resolved {
+		state = "RESOLVED"
+	}
+	return fmt.Sprintf("%s [%s] %s %s: %s", state, alert.Severity, alert.Source, alert.Event, alert.Summary)
+}
+
+// formatValue prints the scalar details as is and the others, e.g. lists of headers, as JSON
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case string, bool, int, int64, uint64, float64, error, fmt.Stringer:
+		return fmt.Sprint(value)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+func sortedKeys(details map[string]interface{}) []string {
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Webhook posts the alerts to a URL
+type Webhook struct {
+	url    string
+	slack  bool
+	client *http.Client
+}
+
+// WebhookOption customizes a webhook
+type WebhookOption func(w *Webhook)
+
+// WithSlack posts the alerts as Slack incoming webhook messages, or as the JSON of the Alert when false
+func WithSlack(slack bool) WebhookOption {
+	return func(w *Webhook) { w.slack = slack }
+}
+
+// WithClient sets the client posting the alerts, an http.Client with a 10 seconds timeout by default
+func WithClient(client *http.Client) WebhookOption {
+	return func(w *Webhook) { w.client = client }
+}
+
+// NewWebhook returns an alerter posting to the URL, as Slack messages when the URL is a Slack incoming webhook
+func NewWebhook(url string, options ...WebhookOption) *Webhook {
+	w := &Webhook{
+		url:    url,
+		slack:  strings.Contains(url, "hooks.slack.com"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Send posts the alert, a response other than 2xx is an error
+func (w *Webhook) Send(ctx context.Context, alert Alert) error {
+	var payload interface{} = alert
+	if w.slack {
+		payload = slackMessage(alert)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("alerting: webhook: %s: %s", response.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// slackMessage renders the alert as a Slack message, the details in a code block
+func slackMessage(alert Alert) interface{} {
+	text := headline(alert)
+	if len(alert.Details) > 0 {
+		details, _ := json.MarshalIndent(alert.Details, "", "  ")
+		text += "\n```" + string(details) + "```"
+	}
+	return map[string]string{"text": text}
+}
+
+// FromEnv returns an alerter printing the alerts, and posting them to SOLACE_ALERT_WEBHOOK when it is set.
+// SOLACE_ALERT_FORMAT, slack or generic, overrides the payload guessed from the URL.
+func FromEnv() Alerter {
+	alerters := Multi{NewStdout(os.Stdout)}
+	if url := os.Getenv("SOLACE_ALERT_WEBHOOK"); url != "" {
+		var options []WebhookOption
+		switch os.Getenv("SOLACE_ALERT_FORMAT") {
+		case "slack":
+			options = append(options, WithSlack(true))
+		case "generic":
+			options = append(options, WithSlack(false))
+		}
+		alerters = append(alerters, NewWebhook(url, options...))
+	}
+	return alerters
+}
+
+// SendTimeout bounds the alerts sent by Watch
+const SendTimeout = 10 * time.Second
+
+// watch is the outage state of a watched service
+type watch struct {
+	name    string
+	alerter Alerter
+
+	mu          sync.Mutex
+	outageStart time.Time
+	attempts    int
+	// last is closed once the previous alert is sent, the alerts are sent in order
+	last chan struct{}
+}
+
+// Watch fires alerts from the reconnection and interruption listeners of the messaging service, the name is the
+// source of the alerts. Watch the service before connecting it. The alerts are sent in the background, so a slow
+// webhook does not hold the API, and the send errors are logged.
+func Watch(name string, messagingService solace.MessagingService, alerter Alerter) {
+	w := &watch{name: name, alerter: alerter}
+	messagingService.AddReconnectionAttemptListener(w.onAttempt)
+	messagingService.AddReconnectionListener(w.onReconnected)
+	messagingService.AddServiceInterruptionListener(w.onInterrupted)
+}
+
+func (w *watch) onAttempt(event solace.ServiceEvent) {
+	w.mu.Lock()
+	w.attempts++
+	first := w.outageStart.IsZero()
+	if first {
+		w.outageStart = event.GetTimestamp()
+	}
+	w.mu.Unlock()
+	if !first {
+		return
+	}
+	w.send(Alert{
+		Time: event.GetTimestamp(), Severity: Warning, Source: w.name, Event: EventReconnecting,
+		Summary: "connection to the broker lost, reconnecting",
+		Details: details(event, nil),
+	})
+}
+
+func (w *watch) onReconnected(event solace.ServiceEvent) {
+	w.mu.Lock()
+	start, attempts := w.outageStart, w.attempts
+	w.outageStart, w.attempts = time.Time{}, 0
+	w.mu.Unlock()
+	outage := event.GetTimestamp().Sub(start)
+	w.send(Alert{
+		Time: event.GetTimestamp(), Severity: Info, Source: w.name, Event: EventReconnected, Resolved: true,
+		Summary: fmt.Sprintf("reconnected after %s and %d attempt(s)", outage.Round(time.Millisecond), attempts),
+		Details: details(event, map[string]interface{}{"outage": outage.String(), "attempts": attempts}),
+	})
+}
+
+func (w *watch) onInterrupted(event solace.ServiceEvent) {
+	w.mu.Lock()
+	attempts := w.attempts
+	w.mu.Unlock()
+	w.send(Alert{
+		Time: event.GetTimestamp(), Severity: Critical, Source: w.name, Event: EventInterrupted,
+		Summary: fmt.Sprintf("service interrupted after %d reconnection attempt(s), it is no longer usable", attempts),
+		Details: details(event, map[string]interface{}{"attempts": attempts}),
+	})
+}
+
+// details adds the broker and the cause of the event to the details
+func details(event solace.ServiceEvent, details map[string]interface{}) map[string]interface{} {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	if uri := event.GetBrokerURI(); uri != "" {
+		details["brokerUri"] = uri
+	}
+	if cause := event.GetCause(); cause != nil {
+		details["cause"] = cause.Error()
+	}
+	return details
+}
+
+func (w *watch) send(alert Alert) {
+	done := make(chan struct{})
+	w.mu.Lock()
+	previous := w.last
+	w.last = done
+	w.mu.Unlock()
+	go func() {
+		defer close(done)
+		if previous != nil {
+			<-previous
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), SendTimeout)
+		defer cancel()
+		if err := w.alerter.Send(ctx, alert); err != nil {
+			slog.Warn("alerting: could not send the alert", "source", alert.Source, "event", alert.Event, "error", err)
+		}
+	}()
+}
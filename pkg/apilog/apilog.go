@@ -0,0 +1,253 @@
+// Package apilog routes the log output of the messaging API into log/slog, as structured records with the level
+// and the subsystem (the API source file, or ccsmp for the native library) of each API log line, and configures the
+// logging of the samples from the environment:
+//
+//	SOLACE_LOG_LEVEL       debug, info, warn (default) or error, applies to the API and to the slog records of the sample
+//	SOLACE_LOG_FORMAT      text (default) or json
+//	SOLACE_LOG_FILE        file the logs are appended to, standard error by default
+//	SOLACE_LOG_ADMIN_ADDR  address of the endpoint changing the level at runtime, e.g. localhost:6061, see LevelHandler
+//
+// Samples call Setup first thing in main:
+//
+//	apilog.Setup()
+//	slog.Info("connected", "host", host)
+//
+// The format of the API log lines is not part of the API, they are parsed on a best effort basis: a line without a
+// recognizable level is logged at the info level with the whole line as message.
+package apilog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace/logging"
+)
+
+// SubsystemKey is the attribute holding the part of the API a log line comes from
+const SubsystemKey = "subsystem"
+
+var (
+	// 2024/01/02 15:04:05.000000 and similar timestamps added by the API logger, slog adds its own
+	timestampPattern = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)?\s*`)
+	levelPattern     = regexp.MustCompile(`\b(CRITICAL|ERROR|WARNING|WARN|NOTICE|INFO|DEBUG)\b:?\s*`)
+	sourcePattern    = regexp.MustCompile(`(\S+)\.(go|c):\d+:?\s*`)
+)
+
+// Writer is an io.Writer turning the API log lines written to it into slog records
+type Writer struct {
+	logger *slog.Logger
+}
+
+// NewWriter returns a writer logging the API log lines with the logger
+func NewWriter(logger *slog.Logger) *Writer {
+	return &Writer{logger: logger.With(slog.String("component", "solace-api"))}
+}
+
+// Write logs every line of p as a record
+func (w *Writer) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if text := strings.TrimSpace(string(line)); text != "" {
+			level, subsystem, message := ParseLine(text)
+			w.logger.Log(context.Background(), level, message, slog.String(SubsystemKey, subsystem))
+		}
+	}
+	return len(p), nil
+}
+
+// ParseLine returns the level, the subsystem and the message of an API log line
+func ParseLine(line string) (slog.Level, string, string) {
+	line = timestampPattern.ReplaceAllString(line, "")
+
+	level := slog.LevelInfo
+	if match := levelPattern.FindStringSubmatchIndex(line); match != nil {
+		switch line[match[2]:match[3]] {
+		case "CRITICAL", "ERROR":
+			level = slog.LevelError
+		case "WARNING", "WARN":
+			level = slog.LevelWarn
+		case "DEBUG":
+			level = slog.LevelDebug
+		}
+		line = line[:match[0]] + line[match[1]:]
+	}
+
+	subsystem := "api"
+	if match := sourcePattern.FindStringSubmatchIndex(line); match != nil {
+		if line[match[4]:match[5]] == "c" {
+			subsystem = "ccsmp"
+		} else {
+			subsystem = filepath.Base(line[match[2]:match[3]])
+		}
+		line = line[:match[0]] + line[match[1]:]
+	}
+	return level, subsystem, strings.TrimSpace(line)
+}
+
+// APILevel returns the API log level logging the records of the slog level and above
+func APILevel(level slog.Level) logging.LogLevel {
+	switch {
+	case level <= slog.LevelDebug:
+		return logging.LogLevelDebug
+	case level <= slog.LevelInfo:
+		return logging.LogLevelInfo
+	case level <= slog.LevelWarn:
+		return logging.LogLevelWarning
+	case level <= slog.LevelError:
+		return logging.LogLevelError
+	default:
+		return logging.LogLevelCritical
+	}
+}
+
+// level is shared by the slog handler installed by Setup and the API
+var level = new(slog.LevelVar)
+
+// Level returns the current log level
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the log level of the API and of the slog handler installed by Setup
+func SetLevel(l slog.Level) {
+	level.Set(l)
+	logging.SetLogLevel(APILevel(l))
+}
+
+// ParseLevel parses debug, info, warn, warning or error, case insensitively
+func ParseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	if strings.EqualFold(name, "warning") {
+		name = "warn"
+	}
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level '%s', expected debug, info, warn or error", name)
+	}
+	return l, nil
+}
+
+// Install routes the API logs to the logger at the level, and makes it the default slog logger
+func Install(logger *slog.Logger, l slog.Level) {
+	slog.SetDefault(logger)
+	logging.SetLogOutput(NewWriter(logger))
+	SetLevel(l)
+}
+
+// Setup installs a logger configured from SOLACE_LOG_LEVEL, SOLACE_LOG_FORMAT and SOLACE_LOG_FILE and returns it,
+// and serves the admin endpoint on SOLACE_LOG_ADMIN_ADDR when it is set. Invalid settings are reported on standard
+// error and replaced by the defaults.
+func Setup() *slog.Logger {
+	l := slog.LevelWarn
+	if name, ok := os.LookupEnv("SOLACE_LOG_LEVEL"); ok {
+		parsed, err := ParseLevel(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "SOLACE_LOG_LEVEL:", err)
+		} else {
+			l = parsed
+		}
+	}
+
+	var out io.Writer = os.Stderr
+	if path := os.Getenv("SOLACE_LOG_FILE"); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "SOLACE_LOG_FILE:", err)
+		} else {
+			out = file
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format := os.Getenv("SOLACE_LOG_FORMAT"); format {
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	case "", "text":
+		handler = slog.NewTextHandler(out, options)
+	default:
+		fmt.Fprintf(os.Stderr, "SOLACE_LOG_FORMAT: unknown format '%s', expected text or json\n", format)
+		handler = slog.NewTextHandler(out, options)
+	}
+
+	logger := slog.New(handler)
+	Install(logger, l)
+
+	if address := os.Getenv("SOLACE_LOG_ADMIN_ADDR"); address != "" {
+		if err := ServeAdmin(address); err != nil {
+			fmt.Fprintln(os.Stderr, "SOLACE_LOG_ADMIN_ADDR:", err)
+		}
+	}
+	return logger
+}
+
+// levelResponse is the body of the responses of LevelHandler
+type levelResponse struct {
+	Level    string `json:"level"`
+	APILevel string `json:"api_level"`
+}
+
+var apiLevelNames = map[logging.LogLevel]string{
+	logging.LogLevelCritical: "critical",
+	logging.LogLevelError:    "error",
+	logging.LogLevelWarning:  "warning",
+	logging.LogLevelInfo:     "info",
+	logging.LogLevelDebug:    "debug",
+}
+
+// LevelHandler serves the log level: GET returns it, PUT or POST with a level parameter (debug, info, warn or
+// error) changes the level of the API and of the sample, without restarting it:
+//
+//	curl localhost:6061/loglevel
+//	curl -X PUT 'localhost:6061/loglevel?level=debug'
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			name := r.FormValue("level")
+			if name == "" {
+				http.Error(w, "missing level parameter, expected debug, info, warn or error", http.StatusBadRequest)
+				return
+			}
+			newLevel, err := ParseLevel(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			previous := Level()
+			SetLevel(newLevel)
+			// logged at the warning level so the change shows whatever the new level
+			slog.Warn("log level changed", "from", previous.String(), "to", newLevel.String(), "remote", r.RemoteAddr)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levelResponse{Level: Level().String(), APILevel: apiLevelNames[APILevel(Level())]})
+	})
+}
+
+// ServeAdmin serves LevelHandler on /loglevel at the address in the background. It has no authentication, bind it
+// to a loopback address. The listen error, e.g. an address already in use, is returned right away.
+func ServeAdmin(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/loglevel", LevelHandler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return nil
+}
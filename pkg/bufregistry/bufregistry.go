@@ -0,0 +1,153 @@
+// Package bufregistry resolves protobuf message types at runtime from the Buf Schema Registry (https://buf.build),
+// through its reflection API: the descriptors of a type, and of the files it depends on, are downloaded from a module
+// of the registry the first time the type is used, then cached. A consumer decodes the payloads of any type of the
+// module without generated code, nor a descriptor set shipped along with it:
+//
+//	resolver := bufregistry.NewResolver(bufregistry.New(token), "buf.build/acme/orders", "main")
+//	descriptor, err := resolver.Resolve(ctx, "acme.orders.v1.Order")
+//	order := dynamicpb.NewMessage(descriptor)
+//	err = proto.Unmarshal(payload, order)
+//	text := protojson.Format(order)
+//
+// The reflection API needs a token of the registry, from buf registry login or the settings of the account, even for
+// public modules.
+package bufregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultURL is the URL of the public Buf Schema Registry
+const DefaultURL = "https://buf.build"
+
+// reflectionPath is the path of the GetFileDescriptorSet method, called with the Connect protocol in JSON
+const reflectionPath = "/buf.reflect.v1beta1.FileDescriptorSetService/GetFileDescriptorSet"
+
+// Client is a client of the reflection API of a registry
+type Client struct {
+	// URL is the base URL of the registry, DefaultURL or the one of a private instance
+	URL string
+	// Token authenticates the requests
+	Token  string
+	Client *http.Client
+}
+
+// New creates a client of the public registry
+func New(token string) *Client {
+	return &Client{URL: DefaultURL, Token: token, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// connectError is the body of the error responses of the Connect protocol
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FileDescriptorSet returns the descriptors of the files of the module defining the symbols, e.g. message types, and
+// of their dependencies, all of the files of the module without symbols. The version is a label, a tag or a commit,
+// the latest commit of the default label when empty; the version resolved is returned along with the set.
+func (c *Client) FileDescriptorSet(ctx context.Context, module, version string, symbols ...string) (*descriptorpb.FileDescriptorSet, string, error) {
+	body, err := json.Marshal(map[string]interface{}{"module": module, "version": version, "symbols": symbols})
+	if err != nil {
+		return nil, "", err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.URL, "/")+reflectionPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Connect-Protocol-Version", "1")
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		var failure connectError
+		if json.Unmarshal(data, &failure) == nil && failure.Message != "" {
+			return nil, "", fmt.Errorf("module %s: %s: %s", module, failure.Code, failure.Message)
+		}
+		return nil, "", fmt.Errorf("module %s: %s", module, response.Status)
+	}
+
+	var result struct {
+		FileDescriptorSet json.RawMessage `json:"fileDescriptorSet"`
+		Version           string          `json:"version"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, "", fmt.Errorf("module %s: invalid reflection response: %w", module, err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(result.FileDescriptorSet, &set); err != nil {
+		return nil, "", fmt.Errorf("module %s: invalid file descriptor set: %w", module, err)
+	}
+	return &set, result.Version, nil
+}
+
+// Resolver resolves the message types of a module, each one downloaded once, it is safe for concurrent use
+type Resolver struct {
+	client  *Client
+	module  string
+	version string
+
+	mu    sync.Mutex
+	types map[string]protoreflect.MessageDescriptor
+}
+
+// NewResolver creates a resolver of the message types of the module at the version, the latest one when empty
+func NewResolver(client *Client, module, version string) *Resolver {
+	return &Resolver{client: client, module: module, version: version, types: map[string]protoreflect.MessageDescriptor{}}
+}
+
+// Resolve returns the descriptor of the message type, a full name with or without a leading dot
+func (r *Resolver) Resolve(ctx context.Context, messageType string) (protoreflect.MessageDescriptor, error) {
+	name := strings.TrimPrefix(messageType, ".")
+	r.mu.Lock()
+	descriptor, ok := r.types[name]
+	r.mu.Unlock()
+	if ok {
+		return descriptor, nil
+	}
+
+	set, _, err := r.client.FileDescriptorSet(ctx, r.module, r.version, name)
+	if err != nil {
+		return nil, err
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("module %s: invalid file descriptor set: %w", r.module, err)
+	}
+	found, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("message type %s of module %s: %w", name, r.module, err)
+	}
+	descriptor, ok = found.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s of module %s is not a message type", name, r.module)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[name] = descriptor
+	return descriptor, nil
+}
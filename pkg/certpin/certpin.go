@@ -0,0 +1,159 @@
+// Package certpin pins the public keys of the broker certificates on top of the trust store validation: a broker
+// presenting a certificate signed by a trusted CA but with a key that is not pinned, e.g. a certificate issued by
+// a compromised or overly permissive CA, or an intercepting proxy trusted by the host, is refused.
+//
+// The API does not expose its TLS handshake, so the hosts are checked with a handshake of their own right before
+// connecting, and again after every reconnection by Guard. Pins are the base64 encoded SHA-256 of the subject
+// public key info of a certificate of the chain (the leaf, an intermediate or the CA), prefixed with "sha256/" as
+// in HTTP public key pinning:
+//
+//	openssl x509 -in broker.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// Pin the next key as well before rotating the broker certificate, or pin the issuing CA.
+package certpin
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace"
+)
+
+// ErrPinMismatch is wrapped by the errors returned when no certificate of the chain has a pinned key
+var ErrPinMismatch = errors.New("broker certificate does not match the pinned keys")
+
+// DialTimeout is the timeout of the handshake checking a host
+var DialTimeout = 10 * time.Second
+
+// Hash returns the pin of the certificate public key, sha256/<base64>
+func Hash(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParsePins splits a comma separated list of pins, adding the sha256/ prefix where it is missing
+func ParsePins(list string) []string {
+	var pins []string
+	for _, pin := range strings.Split(list, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		if !strings.HasPrefix(pin, "sha256/") {
+			pin = "sha256/" + pin
+		}
+		pins = append(pins, pin)
+	}
+	return pins
+}
+
+// Pinner checks the hosts of a host list against the pins, validating the certificates with the trust store first
+type Pinner struct {
+	pins  map[string]bool
+	roots *x509.CertPool
+}
+
+// New creates a pinner for the pins, the certificates are validated with the PEM files of the trust store
+// directory, as the API does. Without pins every check fails, Chain still returns the chain to pin.
+func New(trustStorePath string, pins []string) (*Pinner, error) {
+	roots := x509.NewCertPool()
+	files, err := ioutil.ReadDir(trustStorePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if contents, err := ioutil.ReadFile(filepath.Join(trustStorePath, file.Name())); err == nil {
+			roots.AppendCertsFromPEM(contents)
+		}
+	}
+	p := &Pinner{pins: make(map[string]bool), roots: roots}
+	for _, pin := range pins {
+		p.pins[pin] = true
+	}
+	return p, nil
+}
+
+// Chain performs a handshake with the host (a tcps:// or wss:// URI) and returns its validated certificate chain
+func (p *Pinner) Chain(host string) ([]*x509.Certificate, error) {
+	uri, err := url.Parse(strings.TrimSpace(host))
+	if err != nil {
+		return nil, err
+	}
+	address := uri.Host
+	switch uri.Scheme {
+	case "tcps":
+		if uri.Port() == "" {
+			address = net.JoinHostPort(uri.Hostname(), "55443")
+		}
+	case "wss":
+		if uri.Port() == "" {
+			address = net.JoinHostPort(uri.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a TLS host, pinning needs tcps:// or wss://", host)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: DialTimeout}, "tcp", address,
+		&tls.Config{RootCAs: p.roots, ServerName: uri.Hostname()})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	chains := conn.ConnectionState().VerifiedChains
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("%s presented no verified certificate chain", host)
+	}
+	return chains[0], nil
+}
+
+// Check validates the certificate chain of the host and checks that one of its keys is pinned
+func (p *Pinner) Check(host string) error {
+	chain, err := p.Chain(host)
+	if err != nil {
+		return err
+	}
+	presented := make([]string, 0, len(chain))
+	for _, certificate := range chain {
+		hash := Hash(certificate)
+		if p.pins[hash] {
+			return nil
+		}
+		presented = append(presented, hash)
+	}
+	return fmt.Errorf("%w: %s presented %s (%s)", ErrPinMismatch, host, chain[0].Subject, strings.Join(presented, ", "))
+}
+
+// CheckHosts checks every host of a comma separated host list, so a failover can not land on an unpinned broker
+func (p *Pinner) CheckHosts(hosts string) error {
+	for _, host := range strings.Split(hosts, ",") {
+		if strings.TrimSpace(host) == "" {
+			continue
+		}
+		if err := p.Check(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Guard checks the host the messaging service reconnected to and disconnects the service when the check fails,
+// onFailure is called with the error before disconnecting. It returns the ID of the reconnection listener.
+func (p *Pinner) Guard(messagingService solace.MessagingService, onFailure func(err error)) uint64 {
+	return messagingService.AddReconnectionListener(func(event solace.ServiceEvent) {
+		if err := p.Check(event.GetBrokerURI()); err != nil {
+			onFailure(err)
+			// not from the listener goroutine of the API
+			go messagingService.Disconnect()
+		}
+	})
+}
@@ -0,0 +1,164 @@
+// Package certwatch watches a client certificate and its private key on disk and reports when they were rotated,
+// i.e. once a new certificate and matching key are both in place. It is used by the samples authenticating with a
+// client certificate to re-establish their connection with the new certificate, see
+// patterns/guaranteed_receiver_cert_hot_reload.go.
+//
+// The directories holding the files are watched rather than the files: cert-manager, Vault agent or Kubernetes
+// secret volumes replace the files (or a symlink to them) instead of writing them in place.
+package certwatch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultSettleTime is how long the files must stay unchanged before a rotation is reported, the certificate and the
+// key are usually written one after the other
+const DefaultSettleTime = 500 * time.Millisecond
+
+// Watcher reports rotations of a certificate and key pair
+type Watcher struct {
+	certFile string
+	keyFile  string
+	settle   time.Duration
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mu          sync.Mutex
+	fingerprint [sha256.Size]byte
+	certificate *x509.Certificate
+}
+
+// New checks the current certificate and key and returns a watcher for them, Start begins watching
+func New(certFile, keyFile string) (*Watcher, error) {
+	w := &Watcher{certFile: certFile, keyFile: keyFile, settle: DefaultSettleTime, done: make(chan struct{})}
+	certificate, fingerprint, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.certificate, w.fingerprint = certificate, fingerprint
+	return w, nil
+}
+
+// Certificate returns the certificate currently on disk, as of the last rotation
+func (w *Watcher) Certificate() *x509.Certificate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.certificate
+}
+
+// load reads and validates the certificate and key. The pair is checked for a matching key unless the key is
+// encrypted, the API decrypts it with the key password.
+func (w *Watcher) load() (*x509.Certificate, [sha256.Size]byte, error) {
+	var fingerprint [sha256.Size]byte
+	certPEM, err := ioutil.ReadFile(w.certFile)
+	if err != nil {
+		return nil, fingerprint, err
+	}
+	keyPEM, err := ioutil.ReadFile(w.keyFile)
+	if err != nil {
+		return nil, fingerprint, err
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fingerprint, fmt.Errorf("no PEM encoded certificate found in '%s'", w.certFile)
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fingerprint, fmt.Errorf("invalid certificate in '%s': %w", w.certFile, err)
+	}
+	if !isEncrypted(keyPEM) {
+		if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+			return nil, fingerprint, fmt.Errorf("'%s' does not hold the key of '%s': %w", w.keyFile, w.certFile, err)
+		}
+	}
+	return certificate, sha256.Sum256(append(append([]byte(nil), certPEM...), keyPEM...)), nil
+}
+
+func isEncrypted(keyPEM []byte) bool {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return false
+	}
+	return block.Type == "ENCRYPTED PRIVATE KEY" || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
+}
+
+// Start watches the files and calls onRotate with the new certificate after each rotation. Changes leaving an
+// invalid pair behind (e.g. the certificate was replaced but not the key yet) are ignored until the pair is valid.
+func (w *Watcher) Start(onRotate func(certificate *x509.Certificate)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{filepath.Dir(w.certFile), filepath.Dir(w.keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	w.watcher = watcher
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		// settled fires once no event was received for the settle time
+		settled := time.NewTimer(w.settle)
+		settled.Stop()
+		for {
+			select {
+			case <-w.done:
+				settled.Stop()
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				settled.Reset(w.settle)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Println("Certificate watch error: ", err)
+			case <-settled.C:
+				certificate, fingerprint, err := w.load()
+				if err != nil {
+					continue
+				}
+				w.mu.Lock()
+				rotated := !bytes.Equal(fingerprint[:], w.fingerprint[:])
+				if rotated {
+					w.certificate, w.fingerprint = certificate, fingerprint
+				}
+				w.mu.Unlock()
+				if rotated {
+					onRotate(certificate)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops watching the files
+func (w *Watcher) Close() error {
+	close(w.done)
+	w.wg.Wait()
+	if w.watcher == nil {
+		return nil
+	}
+	return w.watcher.Close()
+}
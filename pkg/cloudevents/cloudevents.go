@@ -0,0 +1,244 @@
+// Package cloudevents maps CloudEvents (https://cloudevents.io, version 1.0) to Solace messages and back, for the
+// applications of an event mesh to exchange events with any CloudEvents SDK. An event is carried in one of the two
+// content modes of the spec:
+//
+//   - Binary: the data is the payload, its datacontenttype the HTTP content type of the message and every other
+//     attribute a user property named with the ce_ prefix, e.g. ce_id, ce_source, ce_type, ce_time. The attributes can
+//     be read, or filtered with a selector, without decoding the payload.
+//   - Structured: the payload is the JSON event format, attributes and data in one document, with the
+//     application/cloudevents+json content type. The event goes through intermediaries that only forward payloads.
+//
+// Encoding an event, then decoding a received message, whatever the mode it was sent in:
+//
+//	msg, err := cloudevents.Encode(messagingService.MessageBuilder(), event, cloudevents.Binary)
+//	event, mode, err := cloudevents.Decode(inbound)
+//
+// The extension attributes are carried as strings, the canonical string encoding of their CloudEvents type.
+package cloudevents
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// SpecVersion is the version of the CloudEvents spec the package implements
+const SpecVersion = "1.0"
+
+// PropertyPrefix prefixes the user properties of the attributes in binary mode
+const PropertyPrefix = "ce_"
+
+// StructuredContentType is the content type of the messages in structured mode, the JSON event format
+const StructuredContentType = "application/cloudevents+json"
+
+// ErrNotCloudEvent is returned by Decode for a message carrying no event: neither a structured content type nor a
+// ce_specversion user property
+var ErrNotCloudEvent = errors.New("not a CloudEvent")
+
+// Mode is the content mode of an event in a message
+type Mode int
+
+const (
+	// Binary carries the attributes in user properties and the data in the payload
+	Binary Mode = iota
+	// Structured carries the event in the payload, in the JSON event format
+	Structured
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Binary:
+		return "binary"
+	case Structured:
+		return "structured"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// Event is a CloudEvent, its context attributes and its data
+type Event struct {
+	// SpecVersion is the version of the spec, SpecVersion when empty
+	SpecVersion string
+	// ID identifies the event within its source, required
+	ID string
+	// Source identifies the context the event happened in, a URI reference, required
+	Source string
+	// Type is the type of the event, e.g. com.example.order.created, required
+	Type string
+	// Subject is the subject of the event within the source, optional
+	Subject string
+	// Time is when the event happened, optional
+	Time time.Time
+	// DataContentType is the content type of the data, e.g. application/json, optional
+	DataContentType string
+	// DataSchema is the URI of the schema of the data, optional
+	DataSchema string
+	// Extensions are the extension attributes, by name: lowercase letters and digits
+	Extensions map[string]string
+	// Data is the data of the event
+	Data []byte
+}
+
+// coreAttributes are the attributes of the spec, the other ones are extensions
+var coreAttributes = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true, "subject": true, "time": true,
+	"datacontenttype": true, "dataschema": true, "data": true, "data_base64": true,
+}
+
+// Validate checks the event has the required attributes and valid extension names
+func (e Event) Validate() error {
+	if e.SpecVersion != "" && e.SpecVersion != SpecVersion {
+		return fmt.Errorf("unsupported specversion %s, expected %s", e.SpecVersion, SpecVersion)
+	}
+	switch {
+	case e.ID == "":
+		return errors.New("the id attribute is required")
+	case e.Source == "":
+		return errors.New("the source attribute is required")
+	case e.Type == "":
+		return errors.New("the type attribute is required")
+	}
+	for name := range e.Extensions {
+		if !validName(name) {
+			return fmt.Errorf("invalid extension name '%s', expected lowercase letters and digits", name)
+		}
+		if coreAttributes[name] {
+			return fmt.Errorf("extension '%s' redefines an attribute of the spec", name)
+		}
+	}
+	return nil
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode builds the message carrying the event in the mode
+func Encode(builder solace.OutboundMessageBuilder, event Event, mode Mode) (message.OutboundMessage, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	switch mode {
+	case Binary:
+		properties := config.MessagePropertyMap{}
+		for name, value := range attributesOf(event) {
+			properties[config.MessageProperty(PropertyPrefix+name)] = value
+		}
+		builder = builder.FromConfigurationProvider(properties)
+		if event.DataContentType != "" {
+			builder = builder.WithHTTPContentHeader(event.DataContentType, "")
+		}
+		return builder.BuildWithByteArrayPayload(event.Data)
+	case Structured:
+		payload, err := event.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return builder.WithHTTPContentHeader(StructuredContentType, "").BuildWithByteArrayPayload(payload)
+	default:
+		return nil, fmt.Errorf("unknown mode %s", mode)
+	}
+}
+
+// attributesOf returns the attributes of the event carried in user properties in binary mode, all but
+// datacontenttype, which is the content type of the message
+func attributesOf(event Event) map[string]string {
+	attributes := map[string]string{
+		"specversion": SpecVersion,
+		"id":          event.ID,
+		"source":      event.Source,
+		"type":        event.Type,
+	}
+	if event.Subject != "" {
+		attributes["subject"] = event.Subject
+	}
+	if !event.Time.IsZero() {
+		attributes["time"] = event.Time.Format(time.RFC3339Nano)
+	}
+	if event.DataSchema != "" {
+		attributes["dataschema"] = event.DataSchema
+	}
+	for name, value := range event.Extensions {
+		attributes[name] = value
+	}
+	return attributes
+}
+
+// Decode returns the event carried by the message and the mode it was carried in, ErrNotCloudEvent when the message
+// carries no event
+func Decode(msg message.Message) (Event, Mode, error) {
+	payload, _ := msg.GetPayloadAsBytes()
+	if contentType, ok := msg.GetHTTPContentType(); ok {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "application/cloudevents") {
+			if mediaType != StructuredContentType {
+				return Event{}, Structured, fmt.Errorf("unsupported event format %s", mediaType)
+			}
+			var event Event
+			if err := event.UnmarshalJSON(payload); err != nil {
+				return Event{}, Structured, err
+			}
+			if event.SpecVersion == "" {
+				return Event{}, Structured, errors.New("the specversion attribute is required")
+			}
+			return event, Structured, event.Validate()
+		}
+	}
+	if !msg.HasProperty(PropertyPrefix + "specversion") {
+		return Event{}, Binary, ErrNotCloudEvent
+	}
+	event := Event{Data: payload}
+	event.DataContentType, _ = msg.GetHTTPContentType()
+	for name, value := range msg.GetProperties() {
+		attribute, ok := strings.CutPrefix(name, PropertyPrefix)
+		if !ok {
+			continue
+		}
+		text := fmt.Sprint(value)
+		switch attribute {
+		case "specversion":
+			event.SpecVersion = text
+		case "id":
+			event.ID = text
+		case "source":
+			event.Source = text
+		case "type":
+			event.Type = text
+		case "subject":
+			event.Subject = text
+		case "dataschema":
+			event.DataSchema = text
+		case "datacontenttype":
+			// the content type of the message comes first, some producers set both
+			if event.DataContentType == "" {
+				event.DataContentType = text
+			}
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, text)
+			if err != nil {
+				return Event{}, Binary, fmt.Errorf("invalid time attribute: %w", err)
+			}
+			event.Time = t
+		default:
+			if event.Extensions == nil {
+				event.Extensions = map[string]string{}
+			}
+			event.Extensions[attribute] = text
+		}
+	}
+	return event, Binary, event.Validate()
+}
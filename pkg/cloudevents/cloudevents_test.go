@@ -0,0 +1,126 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Event{ID: "1", Source: "/orders", Type: "com.example.order.created"}
+	tests := []struct {
+		name   string
+		modify func(*Event)
+		err    string
+	}{
+		{"valid", func(e *Event) {}, ""},
+		{"spec version", func(e *Event) { e.SpecVersion = SpecVersion }, ""},
+		{"extension", func(e *Event) { e.Extensions = map[string]string{"traceparent2": "x"} }, ""},
+		{"unsupported spec version", func(e *Event) { e.SpecVersion = "0.3" }, "unsupported specversion 0.3, expected 1.0"},
+		{"no id", func(e *Event) { e.ID = "" }, "the id attribute is required"},
+		{"no source", func(e *Event) { e.Source = "" }, "the source attribute is required"},
+		{"no type", func(e *Event) { e.Type = "" }, "the type attribute is required"},
+		{"extension name", func(e *Event) { e.Extensions = map[string]string{"trace-id": "x"} }, "invalid extension name 'trace-id', expected lowercase letters and digits"},
+		{"extension redefining an attribute", func(e *Event) { e.Extensions = map[string]string{"subject": "x"} }, "extension 'subject' redefines an attribute of the spec"},
+	}
+
+	for _, test := range tests {
+		event := valid
+		test.modify(&event)
+		err := event.Validate()
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("%s: got error %v, expected %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	event := Event{SpecVersion: SpecVersion, ID: "1", Source: "/orders", Type: "com.example.order.created"}
+	tests := []struct {
+		name   string
+		modify func(*Event)
+		// data is the JSON of the data member of the document, data_base64 when the data is base64 encoded
+		data string
+	}{
+		{"no data", func(e *Event) {}, ""},
+		{"json", func(e *Event) { e.DataContentType, e.Data = "application/json", []byte(`{"a":1}`) }, `"data":{"a":1}`},
+		{"json suffix", func(e *Event) { e.DataContentType, e.Data = "application/vnd.order+json", []byte(`"text"`) }, `"data":"text"`},
+		{"json by default", func(e *Event) { e.Data = []byte(`[1,2]`) }, `"data":[1,2]`},
+		{"text", func(e *Event) { e.DataContentType, e.Data = "text/plain; charset=utf-8", []byte("héllo") }, `"data":"héllo"`},
+		{"xml", func(e *Event) { e.DataContentType, e.Data = "application/xml", []byte("<a/>") }, `"data":"\u003ca/\u003e"`},
+		{"binary", func(e *Event) { e.DataContentType, e.Data = "application/octet-stream", []byte{0, 0xff} }, `"data_base64":"AP8="`},
+		{"invalid json", func(e *Event) { e.DataContentType, e.Data = "application/json", []byte("{") }, `"data_base64":"ew=="`},
+		{"attributes", func(e *Event) {
+			e.Subject, e.DataSchema = "order-1", "https://example.com/order.json"
+			e.Time = time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+			e.Extensions = map[string]string{"partitionkey": "a"}
+		}, ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			original := event
+			test.modify(&original)
+			document, err := json.Marshal(original)
+			if err != nil {
+				t.Fatalf("could not encode the event: %s", err)
+			}
+			if test.data != "" && !strings.Contains(string(document), test.data) {
+				t.Errorf("encoded %s, expected %s", document, test.data)
+			}
+			var decoded Event
+			if err := json.Unmarshal(document, &decoded); err != nil {
+				t.Fatalf("could not decode %s: %s", document, err)
+			}
+			if !reflect.DeepEqual(decoded, original) {
+				t.Errorf("decoded %+v, expected %+v", decoded, original)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		event    Event
+		err      string
+	}{
+		{
+			name:     "extension types",
+			document: `{"specversion":"1.0","id":"1","source":"s","type":"t","count":42,"sampled":true}`,
+			event:    Event{SpecVersion: "1.0", ID: "1", Source: "s", Type: "t", Extensions: map[string]string{"count": "42", "sampled": "true"}},
+		},
+		{
+			name:     "json string data",
+			document: `{"id":"1","datacontenttype":"application/json","data":"x"}`,
+			event:    Event{ID: "1", DataContentType: "application/json", Data: []byte(`"x"`)},
+		},
+		{name: "invalid time", document: `{"time":"yesterday"}`, err: "invalid time attribute: "},
+		{name: "invalid base64", document: `{"data_base64":"%"}`, err: "invalid data_base64: "},
+		{name: "not an object", document: `[]`, err: "invalid JSON event format: "},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var event Event
+			err := event.UnmarshalJSON([]byte(test.document))
+			if test.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+					t.Fatalf("got error %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not decode the event: %s", err)
+			}
+			if !reflect.DeepEqual(event, test.event) {
+				t.Errorf("decoded %+v, expected %+v", event, test.event)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MarshalJSON encodes the event in the JSON event format. JSON data, per the data content type, is embedded as JSON
+// in data, text data as a string in data, any other data base64 encoded in data_base64.
+func (e Event) MarshalJSON() ([]byte, error) {
+	document := map[string]interface{}{}
+	for name, value := range attributesOf(e) {
+		document[name] = value
+	}
+	if e.DataContentType != "" {
+		document["datacontenttype"] = e.DataContentType
+	}
+	switch {
+	case e.Data == nil:
+	case isJSON(e.DataContentType) && json.Valid(e.Data):
+		document["data"] = json.RawMessage(e.Data)
+	case isText(e.DataContentType) && utf8.Valid(e.Data):
+		document["data"] = string(e.Data)
+	default:
+		document["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+	}
+	return json.Marshal(document)
+}
+
+// UnmarshalJSON decodes an event in the JSON event format, the extension attributes that are not strings are kept in
+// their JSON encoding, e.g. 42 or true
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("invalid JSON event format: %w", err)
+	}
+	*e = Event{}
+	for name, raw := range document {
+		var text string
+		if name != "data" {
+			if err := json.Unmarshal(raw, &text); err != nil {
+				// an extension of another type, e.g. an integer or a boolean
+				text = string(bytes.TrimSpace(raw))
+			}
+		}
+		switch name {
+		case "specversion":
+			e.SpecVersion = text
+		case "id":
+			e.ID = text
+		case "source":
+			e.Source = text
+		case "type":
+			e.Type = text
+		case "subject":
+			e.Subject = text
+		case "datacontenttype":
+			e.DataContentType = text
+		case "dataschema":
+			e.DataSchema = text
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, text)
+			if err != nil {
+				return fmt.Errorf("invalid time attribute: %w", err)
+			}
+			e.Time = t
+		case "data":
+			// a JSON string is the text of the data, unless the data is JSON
+			var text string
+			if json.Unmarshal(raw, &text) == nil && !isJSON(contentTypeOf(document)) {
+				e.Data = []byte(text)
+			} else {
+				e.Data = bytes.TrimSpace(raw)
+			}
+		case "data_base64":
+			decoded, err := base64.StdEncoding.DecodeString(text)
+			if err != nil {
+				return fmt.Errorf("invalid data_base64: %w", err)
+			}
+			e.Data = decoded
+		default:
+			if e.Extensions == nil {
+				e.Extensions = map[string]string{}
+			}
+			e.Extensions[name] = text
+		}
+	}
+	return nil
+}
+
+func contentTypeOf(document map[string]json.RawMessage) string {
+	var contentType string
+	if raw, ok := document["datacontenttype"]; ok {
+		_ = json.Unmarshal(raw, &contentType)
+	}
+	return contentType
+}
+
+// isJSON reports whether the content type is JSON: application/json or a +json suffix, the default of the spec when
+// there is no content type
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isText reports whether the content type is text, the data is then embedded as a string
+func isText(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
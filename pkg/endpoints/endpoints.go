@@ -0,0 +1,133 @@
+// Package endpoints manages the queues used by a sample run: the queues are provisioned at startup with a name
+// tagged with the run they belong to and deprovisioned on exit, so demos can be run repeatedly against a shared
+// broker without leaving orphaned endpoints behind.
+//
+// Tagged queue names have the form <prefix>/<tag>/<name>, e.g. samples/laptop-4242/orders. Queues left over by a
+// run that did not exit cleanly can be found (and removed) on the broker by their prefix.
+package endpoints
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// DefaultPrefix is the first level of the tagged queue names
+const DefaultPrefix = "samples"
+
+// TagEnv is the environment variable overriding the default tag of a run
+const TagEnv = "SOLACE_SAMPLES_TAG"
+
+// ProvisionOption customizes the endpoint provisioner of a single queue, e.g. to set its quota
+type ProvisionOption func(provisioner solace.EndpointProvisioner) solace.EndpointProvisioner
+
+// Manager provisions the queues of a sample run and deprovisions them again on exit
+type Manager struct {
+	messagingService solace.MessagingService
+	prefix           string
+	tag              string
+
+	mu          sync.Mutex
+	provisioned []string
+}
+
+// NewManager creates a manager tagging the queues with the given tag. An empty tag defaults to the SOLACE_SAMPLES_TAG
+// environment variable, or <hostname>-<pid> when it is not set, which keeps concurrent runs apart.
+func NewManager(messagingService solace.MessagingService, tag string) *Manager {
+	if tag == "" {
+		tag = DefaultTag()
+	}
+	return &Manager{messagingService: messagingService, prefix: DefaultPrefix, tag: tag}
+}
+
+// DefaultTag returns the tag used when none is given to NewManager
+func DefaultTag() string {
+	if tag, ok := os.LookupEnv(TagEnv); ok && tag != "" {
+		return tag
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	// topic and queue names must not contain wildcards
+	hostname = strings.NewReplacer("*", "_", ">", "_", "/", "_").Replace(hostname)
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// Tag returns the tag of the queues provisioned by the manager
+func (m *Manager) Tag() string {
+	return m.tag
+}
+
+// QueueName returns the tagged name of the queue
+func (m *Manager) QueueName(name string) string {
+	return m.prefix + "/" + m.tag + "/" + name
+}
+
+// ProvisionQueue provisions a durable exclusive queue under its tagged name and returns the resource to bind to.
+// The queue is recorded for DeprovisionAll, also when it already existed (e.g. left over by a crashed run).
+func (m *Manager) ProvisionQueue(name string, options ...ProvisionOption) (*resource.Queue, error) {
+	queueName, err := m.provision(name, true, options)
+	if err != nil {
+		return nil, err
+	}
+	return resource.QueueDurableExclusive(queueName), nil
+}
+
+// ProvisionNonExclusiveQueue provisions a durable non-exclusive queue under its tagged name, messages are load
+// balanced between the receivers bound to it
+func (m *Manager) ProvisionNonExclusiveQueue(name string, options ...ProvisionOption) (*resource.Queue, error) {
+	queueName, err := m.provision(name, false, options)
+	if err != nil {
+		return nil, err
+	}
+	return resource.QueueDurableNonExclusive(queueName), nil
+}
+
+func (m *Manager) provision(name string, exclusive bool, options []ProvisionOption) (string, error) {
+	queueName := m.QueueName(name)
+	provisioner := m.messagingService.EndpointProvisioner().
+		WithDurability(true).
+		WithExclusiveAccess(exclusive)
+	for _, option := range options {
+		provisioner = option(provisioner)
+	}
+
+	if err := provisioner.Provision(queueName, true).GetError(); err != nil {
+		return "", fmt.Errorf("could not provision queue '%s': %w", queueName, err)
+	}
+
+	m.mu.Lock()
+	m.provisioned = append(m.provisioned, queueName)
+	m.mu.Unlock()
+	return queueName, nil
+}
+
+// Provisioned returns the tagged names of the queues provisioned so far
+func (m *Manager) Provisioned() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.provisioned...)
+}
+
+// DeprovisionAll deprovisions the queues in the reverse order of their provisioning, together with the messages
+// spooled on them. Receivers bound to the queues should be terminated first. All queues are attempted, the first
+// error is returned.
+func (m *Manager) DeprovisionAll() error {
+	m.mu.Lock()
+	provisioned := m.provisioned
+	m.provisioned = nil
+	m.mu.Unlock()
+
+	var firstErr error
+	for i := len(provisioned) - 1; i >= 0; i-- {
+		if err := m.messagingService.EndpointProvisioner().Deprovision(provisioned[i], true); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("could not deprovision queue '%s': %w", provisioned[i], err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,149 @@
+package faultproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// faultsJSON is the JSON form of the faults, with the durations as strings, e.g. "250ms"
+type faultsJSON struct {
+	Latency      *string  `json:"latency,omitempty"`
+	Jitter       *string  `json:"jitter,omitempty"`
+	Bandwidth    *int64   `json:"bandwidth,omitempty"`
+	TruncateRate *float64 `json:"truncate_rate,omitempty"`
+	ResetRate    *float64 `json:"reset_rate,omitempty"`
+}
+
+// MarshalJSON encodes the faults with the durations as strings, e.g. {"latency": "250ms", "jitter": "0s", ...}
+func (f Faults) MarshalJSON() ([]byte, error) {
+	latency, jitter := f.Latency.String(), f.Jitter.String()
+	return json.Marshal(faultsJSON{
+		Latency:      &latency,
+		Jitter:       &jitter,
+		Bandwidth:    &f.Bandwidth,
+		TruncateRate: &f.TruncateRate,
+		ResetRate:    &f.ResetRate,
+	})
+}
+
+// UnmarshalJSON decodes the faults set in the JSON object and leaves the others as they are, which updates faults
+// partially, and checks them
+func (f *Faults) UnmarshalJSON(data []byte) error {
+	var decoded faultsJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	updated := *f
+	for _, d := range []struct {
+		text  *string
+		value *time.Duration
+	}{{decoded.Latency, &updated.Latency}, {decoded.Jitter, &updated.Jitter}} {
+		if d.text == nil {
+			continue
+		}
+		duration, err := time.ParseDuration(*d.text)
+		if err != nil {
+			return err
+		}
+		*d.value = duration
+	}
+	if decoded.Bandwidth != nil {
+		updated.Bandwidth = *decoded.Bandwidth
+	}
+	if decoded.TruncateRate != nil {
+		updated.TruncateRate = *decoded.TruncateRate
+	}
+	if decoded.ResetRate != nil {
+		updated.ResetRate = *decoded.ResetRate
+	}
+	if err := updated.check(); err != nil {
+		return err
+	}
+	*f = updated
+	return nil
+}
+
+// check checks that the faults are within their bounds
+func (f Faults) check() error {
+	switch {
+	case f.Latency < 0 || f.Jitter < 0:
+		return fmt.Errorf("negative latency or jitter")
+	case f.Bandwidth < 0:
+		return fmt.Errorf("negative bandwidth")
+	case f.TruncateRate < 0 || f.TruncateRate > 1 || f.ResetRate < 0 || f.ResetRate > 1:
+		return fmt.Errorf("the truncate and reset rates must be between 0 and 1")
+	}
+	return nil
+}
+
+// Handler serves the admin API of the proxy:
+//
+//	GET    /faults  returns the faults
+//	PUT    /faults  sets the faults of the JSON body, the ones left out are cleared
+//	PATCH  /faults  sets the faults of the JSON body, the ones left out are left as they are
+//	DELETE /faults  clears the faults
+//	POST   /reset   resets the open connections
+//	GET    /stats   returns the counters, see Stats
+//
+// e.g.
+//
+//	curl -X PATCH localhost:6062/faults -d '{"latency": "200ms", "jitter": "50ms", "bandwidth": 65536}'
+//	curl -X PATCH localhost:6062/faults -d '{"truncate_rate": 0.01}'
+//	curl -X POST localhost:6062/reset
+func (p *Proxy) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/faults", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPatch:
+			var faults Faults
+			if r.Method == http.MethodPatch {
+				faults = p.Faults()
+			}
+			if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			p.SetFaults(faults)
+		case http.MethodDelete:
+			p.SetFaults(Faults{})
+		default:
+			w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, p.Faults())
+	})
+	mux.HandleFunc("/reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, map[string]int{"reset": p.Reset()})
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, p.Stats())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// ServeAdmin serves Handler at the address in the background. It has no authentication, bind it to a loopback
+// address. The listen error, e.g. an address already in use, is returned right away.
+func (p *Proxy) ServeAdmin(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: p.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return nil
+}
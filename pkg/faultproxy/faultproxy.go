@@ -0,0 +1,255 @@
+// Package faultproxy is a TCP proxy the samples can connect to the broker through, injecting network faults on demand
+// to see how the API and the sample code cope with them: latency with jitter, a bandwidth cap, truncated packets and
+// connection resets. The faults are changed while the proxy runs, from Go or over its local admin API (see Handler):
+//
+//	proxy, err := faultproxy.Listen("localhost:0", "broker:55555")
+//	proxy.SetFaults(faultproxy.Faults{Latency: 50 * time.Millisecond, Bandwidth: 64 * 1024})
+//	err = proxy.ServeAdmin("localhost:6062")
+//	// connect the messaging service to tcp://<proxy.Addr()>
+//
+// The samples built on internal/sampleconfig connect through a proxy when run with -fault-proxy <admin address>.
+package faultproxy
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Faults are the faults injected in the forwarded traffic, the zero value forwards it untouched
+type Faults struct {
+	// Latency delays every chunk of data forwarded, in either direction
+	Latency time.Duration
+	// Jitter adds a random delay, up to Jitter, to the latency of every chunk
+	Jitter time.Duration
+	// Bandwidth caps the throughput of every connection in each direction, in bytes per second; 0 for no cap
+	Bandwidth int64
+	// TruncateRate is the probability, from 0 to 1, that a chunk of data is truncated: the end of the chunk is
+	// dropped, which corrupts the stream as a faulty middlebox would
+	TruncateRate float64
+	// ResetRate is the probability, from 0 to 1, that the connection is reset with a TCP RST when a chunk of data is
+	// forwarded
+	ResetRate float64
+}
+
+// Stats are the counters of the proxy
+type Stats struct {
+	// Connections is the number of open connections
+	Connections int   `json:"connections"`
+	Accepted    int64 `json:"accepted"`
+	// Bytes is the number of bytes forwarded, in both directions
+	Bytes     int64 `json:"bytes"`
+	Truncated int64 `json:"truncated"`
+	Resets    int64 `json:"resets"`
+}
+
+// Proxy forwards the connections accepted on a local address to the target, the broker
+type Proxy struct {
+	target   string
+	listener net.Listener
+
+	faultsMu sync.RWMutex
+	faults   Faults
+
+	accepted, bytes, truncated, resets atomic.Int64
+
+	mu    sync.Mutex
+	links map[*link]struct{}
+	wg    sync.WaitGroup
+}
+
+// link is a proxied connection, the client side and the target side
+type link struct {
+	client, target net.Conn
+	once           sync.Once
+}
+
+// close closes both sides, with a TCP RST when reset is set
+func (l *link) close(reset bool) {
+	l.once.Do(func() {
+		for _, conn := range []net.Conn{l.client, l.target} {
+			if tcp, ok := conn.(*net.TCPConn); ok && reset {
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+		}
+	})
+}
+
+// chunk is a chunk of data read from one side, to be written to the other one when due
+type chunk struct {
+	data []byte
+	due  time.Time
+}
+
+// Listen starts a proxy on the address, e.g. localhost:0, forwarding to the target address, e.g. broker:55555
+func Listen(address, target string) (*Proxy, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	p := &Proxy{target: target, listener: listener, links: map[*link]struct{}{}}
+	p.wg.Add(1)
+	go p.accept()
+	return p, nil
+}
+
+// Addr returns the address the proxy listens on
+func (p *Proxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Target returns the address the proxy forwards to
+func (p *Proxy) Target() string {
+	return p.target
+}
+
+// Faults returns the faults currently injected
+func (p *Proxy) Faults() Faults {
+	p.faultsMu.RLock()
+	defer p.faultsMu.RUnlock()
+	return p.faults
+}
+
+// SetFaults changes the faults injected, in the open connections as well
+func (p *Proxy) SetFaults(faults Faults) {
+	p.faultsMu.Lock()
+	defer p.faultsMu.Unlock()
+	p.faults = faults
+}
+
+// Stats returns the counters of the proxy
+func (p *Proxy) Stats() Stats {
+	p.mu.Lock()
+	connections := len(p.links)
+	p.mu.Unlock()
+	return Stats{
+		Connections: connections,
+		Accepted:    p.accepted.Load(),
+		Bytes:       p.bytes.Load(),
+		Truncated:   p.truncated.Load(),
+		Resets:      p.resets.Load(),
+	}
+}
+
+func (p *Proxy) accept() {
+	defer p.wg.Done()
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		target, err := net.DialTimeout("tcp", p.target, 10*time.Second)
+		if err != nil {
+			client.Close()
+			continue
+		}
+		p.accepted.Add(1)
+		l := &link{client: client, target: target}
+		p.mu.Lock()
+		p.links[l] = struct{}{}
+		p.mu.Unlock()
+		p.wg.Add(2)
+		go p.forward(l, client, target)
+		go p.forward(l, target, client)
+	}
+}
+
+// forward copies from src to dst, injecting the faults, until either side is closed, then closes both. The chunks
+// read are queued with their due time so that the latency does not cap the throughput.
+func (p *Proxy) forward(l *link, dst, src net.Conn) {
+	defer p.wg.Done()
+	chunks := make(chan chunk, 64)
+	go func() {
+		defer close(chunks)
+		for {
+			buffer := make([]byte, 16*1024)
+			n, err := src.Read(buffer)
+			if n > 0 {
+				faults := p.Faults()
+				delay := faults.Latency
+				if faults.Jitter > 0 {
+					delay += time.Duration(rand.Int63n(int64(faults.Jitter)))
+				}
+				chunks <- chunk{data: buffer[:n], due: time.Now().Add(delay)}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		l.close(false)
+		p.mu.Lock()
+		delete(p.links, l)
+		p.mu.Unlock()
+		// let the reader end on the closed connection
+		for range chunks {
+		}
+	}()
+
+	var next time.Time
+	for c := range chunks {
+		time.Sleep(time.Until(c.due))
+		faults := p.Faults()
+		if faults.ResetRate > 0 && rand.Float64() < faults.ResetRate {
+			p.resets.Add(1)
+			l.close(true)
+			return
+		}
+		data := c.data
+		if faults.TruncateRate > 0 && rand.Float64() < faults.TruncateRate {
+			p.truncated.Add(1)
+			data = data[:rand.Intn(len(data))]
+		}
+		for len(data) > 0 {
+			piece := data
+			if faults.Bandwidth > 0 {
+				// pieces of at most a tenth of a second of traffic, for a smooth throughput
+				if size := max(faults.Bandwidth/10, 1); int64(len(piece)) > size {
+					piece = piece[:size]
+				}
+				if now := time.Now(); next.Before(now) {
+					next = now
+				}
+				next = next.Add(time.Duration(int64(len(piece)) * int64(time.Second) / faults.Bandwidth))
+				time.Sleep(time.Until(next))
+			}
+			if _, err := dst.Write(piece); err != nil {
+				return
+			}
+			p.bytes.Add(int64(len(piece)))
+			data = data[len(piece):]
+		}
+	}
+}
+
+// Reset resets all of the open connections with a TCP RST, as a network failure would, and returns how many were
+// reset
+func (p *Proxy) Reset() int {
+	p.mu.Lock()
+	links := make([]*link, 0, len(p.links))
+	for l := range p.links {
+		links = append(links, l)
+	}
+	p.mu.Unlock()
+	for _, l := range links {
+		l.close(true)
+	}
+	p.resets.Add(int64(len(links)))
+	return len(links)
+}
+
+// Close stops the proxy and closes the open connections
+func (p *Proxy) Close() error {
+	err := p.listener.Close()
+	p.mu.Lock()
+	for l := range p.links {
+		l.close(false)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return err
+}
@@ -0,0 +1,265 @@
+// Package health serves the liveness (/healthz) and readiness (/readyz) endpoints of the consumer samples, for the
+// probes of a Kubernetes deployment:
+//
+//   - ready: every messaging service is connected and every receiver is running. A consumer reconnecting to the
+//     broker is not ready, but alive: the API is reconnecting it.
+//   - alive: no messaging service gave up reconnecting (service interruption), no receiver was terminated, and, when
+//     WithMaxIdle is set, a message was handled recently. A consumer failing these checks needs a restart.
+//
+// A consumer shutting down calls Drain: it is not ready from then on, and its receivers terminating do not fail the
+// liveness check (see pkg/lifecycle).
+//
+// Both endpoints answer 200 or 503 with the state of every check in a JSON body:
+//
+//	checker := health.New()
+//	checker.AddService("broker", messagingService)
+//	checker.AddReceiver("orders", persistentReceiver)
+//	checker.ListenAndServe(":8080")
+//	persistentReceiver.ReceiveAsync(checker.Handler(MessageHandler))
+//
+// with probes such as:
+//
+//	livenessProbe:
+//	  httpGet: {path: /healthz, port: 8080}
+//	readinessProbe:
+//	  httpGet: {path: /readyz, port: 8080}
+package health
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// Option configures a checker
+type Option func(*Checker)
+
+// WithMaxIdle makes the liveness check fail when no message was handled for longer than maxIdle, e.g. to restart a
+// consumer whose handler is stuck. Only use it with queues that always have traffic. 0, the default, disables it.
+func WithMaxIdle(maxIdle time.Duration) Option {
+	return func(c *Checker) {
+		c.maxIdle = maxIdle
+	}
+}
+
+// Checker holds the services and receivers checked by the endpoints, it is safe for concurrent use
+type Checker struct {
+	maxIdle time.Duration
+	started time.Time
+	// unix nanoseconds of the last message handled, 0 before the first one
+	lastActivity int64
+	draining     atomic.Bool
+
+	mu        sync.Mutex
+	services  []*service
+	receivers []receiver
+}
+
+type service struct {
+	name             string
+	messagingService solace.MessagingService
+	interrupted      atomic.Bool
+}
+
+type receiver struct {
+	name     string
+	receiver solace.LifecycleControl
+}
+
+// Check is the state of a check in the responses of the endpoints
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Status is the body of the responses of the endpoints
+type Status struct {
+	OK bool `json:"ok"`
+	// LastMessage is the time the last message was handled, absent before the first one
+	LastMessage *time.Time `json:"last_message,omitempty"`
+	Checks      []Check    `json:"checks"`
+}
+
+// New creates a checker without services nor receivers, it is ready and alive until some are added
+func New(options ...Option) *Checker {
+	c := &Checker{started: time.Now()}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// AddService checks the messaging service: connected for readiness, not interrupted for liveness.
+// Add the service before connecting it so an interruption is never missed.
+func (c *Checker) AddService(name string, messagingService solace.MessagingService) {
+	s := &service{name: name, messagingService: messagingService}
+	messagingService.AddServiceInterruptionListener(func(solace.ServiceEvent) {
+		s.interrupted.Store(true)
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.services = append(c.services, s)
+}
+
+// AddReceiver checks the receiver: running for readiness, not terminated for liveness
+func (c *Checker) AddReceiver(name string, r solace.LifecycleControl) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.receivers = append(c.receivers, receiver{name: name, receiver: r})
+}
+
+// RemoveReceiver stops checking the receiver, e.g. before terminating it on purpose
+func (c *Checker) RemoveReceiver(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, r := range c.receivers {
+		if r.name == name {
+			c.receivers = append(c.receivers[:i], c.receivers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Drain marks the consumer shutting down: the readiness check fails from then on, for the endpoints of a pod to stop
+// listing it, and the receivers terminated on purpose no longer fail the liveness check
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether Drain was called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Activity records that a message was handled now
+func (c *Checker) Activity() {
+	atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+}
+
+// Handler wraps a message handler to record the activity
+func (c *Checker) Handler(handler solace.MessageHandler) solace.MessageHandler {
+	return func(inbound message.InboundMessage) {
+		handler(inbound)
+		c.Activity()
+	}
+}
+
+// Ready returns the readiness status
+func (c *Checker) Ready() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.status()
+	if c.draining.Load() {
+		status.add(Check{Name: "not draining", OK: false, Detail: "shutting down"})
+	}
+	for _, s := range c.services {
+		connected := s.messagingService.IsConnected()
+		check := Check{Name: "service " + s.name + " connected", OK: connected}
+		if !connected {
+			check.Detail = "not connected, reconnecting or not connected yet"
+		}
+		status.add(check)
+	}
+	for _, r := range c.receivers {
+		running := r.receiver.IsRunning()
+		check := Check{Name: "receiver " + r.name + " running", OK: running}
+		if !running {
+			check.Detail = "not started, terminating or terminated"
+		}
+		status.add(check)
+	}
+	return status
+}
+
+// Alive returns the liveness status
+func (c *Checker) Alive() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.status()
+	for _, s := range c.services {
+		check := Check{Name: "service " + s.name + " not interrupted", OK: !s.interrupted.Load()}
+		if !check.OK {
+			check.Detail = "gave up reconnecting"
+		}
+		status.add(check)
+	}
+	draining := c.draining.Load()
+	for _, r := range c.receivers {
+		terminated := r.receiver.IsTerminated()
+		check := Check{Name: "receiver " + r.name + " not terminated", OK: !terminated || draining}
+		if terminated {
+			check.Detail = "terminated"
+			if draining {
+				check.Detail = "terminated while draining"
+			}
+		}
+		status.add(check)
+	}
+	if c.maxIdle > 0 {
+		// the idle time counts from the start until the first message
+		last := c.started
+		if status.LastMessage != nil {
+			last = *status.LastMessage
+		}
+		idle := time.Since(last)
+		check := Check{Name: "recent message activity", OK: idle <= c.maxIdle}
+		if !check.OK {
+			check.Detail = "no message handled for " + idle.Round(time.Second).String()
+		}
+		status.add(check)
+	}
+	return status
+}
+
+func (c *Checker) status() Status {
+	status := Status{OK: true, Checks: []Check{}}
+	if last := atomic.LoadInt64(&c.lastActivity); last != 0 {
+		t := time.Unix(0, last)
+		status.LastMessage = &t
+	}
+	return status
+}
+
+func (s *Status) add(check Check) {
+	s.Checks = append(s.Checks, check)
+	s.OK = s.OK && check.OK
+}
+
+// HTTPHandler returns the handler serving /healthz and /readyz
+func (c *Checker) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, c.Alive())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, c.Ready())
+	})
+	return mux
+}
+
+func writeStatus(w http.ResponseWriter, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	if !status.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// ListenAndServe serves the endpoints at the address in the background. The listen error, e.g. an address already in
+// use, is returned right away; close the returned server to stop serving.
+func (c *Checker) ListenAndServe(address string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: c.HTTPHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return server, nil
+}
@@ -0,0 +1,218 @@
+// Package lifecycle shuts the samples down gracefully on SIGTERM, the signal Kubernetes, docker stop and systemd send
+// before killing a process, as well as on an interrupt (CTRL+C). A consumer registers its receivers, publishers and
+// messaging services with a Drainer, wraps its message handler, then drains on the signal:
+//
+//  1. not ready: the readiness check of the health.Checker fails, for the endpoints of the pod to stop listing it
+//  2. the persistent receivers are paused, no new message is delivered to the handlers
+//  3. the handlers in flight are waited for, to settle the messages they hold
+//  4. the receivers and publishers are terminated, then the messaging services disconnected
+//
+// all within the grace period, 25 seconds by default, below the 30 seconds of terminationGracePeriodSeconds of a pod:
+//
+//	drainer := lifecycle.New(lifecycle.WithChecker(checker))
+//	drainer.AddReceiver("orders", persistentReceiver)
+//	drainer.AddService(messagingService)
+//	persistentReceiver.ReceiveAsync(drainer.Handler(MessageHandler))
+//	lifecycle.WaitForSignal()
+//	report := drainer.Shutdown()
+//
+// The samples without anything to drain only catch the signals, with signal.Notify(c, lifecycle.ShutdownSignals...).
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/health"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// DefaultGracePeriod is the time a drain takes at most, leaving 5 seconds of the default termination grace period of
+// a pod to exit
+const DefaultGracePeriod = 25 * time.Second
+
+// ShutdownSignals are the signals the samples shut down on: an interrupt and SIGTERM
+var ShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// WaitForSignal blocks until the process receives one of the ShutdownSignals and returns it
+func WaitForSignal() os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, ShutdownSignals...)
+	defer signal.Stop(c)
+	return <-c
+}
+
+// Option configures a drainer
+type Option func(*Drainer)
+
+// WithGracePeriod sets the time a drain takes at most, DefaultGracePeriod by default. Set it a few seconds below the
+// terminationGracePeriodSeconds of the pod.
+func WithGracePeriod(gracePeriod time.Duration) Option {
+	return func(d *Drainer) {
+		d.gracePeriod = gracePeriod
+	}
+}
+
+// WithChecker has the drain fail the readiness check of the checker first
+func WithChecker(checker *health.Checker) Option {
+	return func(d *Drainer) {
+		d.checker = checker
+	}
+}
+
+// Drainer holds what a drain stops, in the order they are stopped, it is safe for concurrent use
+type Drainer struct {
+	gracePeriod time.Duration
+	checker     *health.Checker
+	// handlers running
+	inFlight atomic.Int64
+
+	mu         sync.Mutex
+	receivers  []receiver
+	publishers []solace.LifecycleControl
+	services   []solace.MessagingService
+}
+
+type receiver struct {
+	name     string
+	receiver solace.LifecycleControl
+}
+
+// pausable is a receiver that can stop the delivery of messages without terminating, the persistent receivers
+type pausable interface {
+	Pause() error
+}
+
+// Report is the outcome of a drain
+type Report struct {
+	// Duration is the time the drain took
+	Duration time.Duration
+	// Abandoned is the number of handlers still running at the end of the grace period, their messages are redelivered
+	Abandoned int64
+	// Err joins the errors pausing, terminating and disconnecting, nil when there was none
+	Err error
+}
+
+func (r Report) String() string {
+	text := fmt.Sprintf("drained in %s", r.Duration.Round(time.Millisecond))
+	if r.Abandoned > 0 {
+		text += fmt.Sprintf(", %d handlers abandoned at the end of the grace period", r.Abandoned)
+	}
+	if r.Err != nil {
+		text += ", " + r.Err.Error()
+	}
+	return text
+}
+
+// New creates a drainer with nothing to drain
+func New(options ...Option) *Drainer {
+	d := &Drainer{gracePeriod: DefaultGracePeriod}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// AddReceiver drains the receiver: paused when it is a persistent receiver, then terminated once the handlers are done
+func (d *Drainer) AddReceiver(name string, r solace.LifecycleControl) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.receivers = append(d.receivers, receiver{name: name, receiver: r})
+}
+
+// AddPublisher terminates the publisher after the receivers, the messages the handlers published are sent meanwhile
+func (d *Drainer) AddPublisher(publisher solace.LifecycleControl) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.publishers = append(d.publishers, publisher)
+}
+
+// AddService disconnects the messaging service last
+func (d *Drainer) AddService(messagingService solace.MessagingService) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.services = append(d.services, messagingService)
+}
+
+// Handler wraps a message handler for the drain to wait for the messages it is handling
+func (d *Drainer) Handler(handler solace.MessageHandler) solace.MessageHandler {
+	return func(inbound message.InboundMessage) {
+		defer d.Begin()()
+		handler(inbound)
+	}
+}
+
+// Begin counts a message settled after its handler returned, e.g. acknowledged by a goroutine, for the drain to wait
+// for it; call the returned function once the message is settled
+func (d *Drainer) Begin() (done func()) {
+	d.inFlight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.inFlight.Add(-1)
+		})
+	}
+}
+
+// InFlight returns the number of handlers running
+func (d *Drainer) InFlight() int64 {
+	return d.inFlight.Load()
+}
+
+// Shutdown drains within the grace period: fails the readiness check, pauses the receivers, waits for the handlers
+// in flight, terminates the receivers and the publishers then disconnects the services
+func (d *Drainer) Shutdown() Report {
+	started := time.Now()
+	deadline := started.Add(d.gracePeriod)
+	d.mu.Lock()
+	receivers := append([]receiver(nil), d.receivers...)
+	publishers := append([]solace.LifecycleControl(nil), d.publishers...)
+	services := append([]solace.MessagingService(nil), d.services...)
+	d.mu.Unlock()
+
+	if d.checker != nil {
+		d.checker.Drain()
+	}
+	var errs []error
+	for _, r := range receivers {
+		if p, ok := r.receiver.(pausable); ok {
+			if err := p.Pause(); err != nil {
+				errs = append(errs, fmt.Errorf("could not pause receiver %s: %w", r.name, err))
+			}
+		}
+	}
+	for d.inFlight.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	report := Report{Abandoned: d.inFlight.Load()}
+
+	// the receivers and the publishers share what is left of the grace period
+	remaining := func() time.Duration {
+		return max(time.Until(deadline), 0)
+	}
+	for _, r := range receivers {
+		if err := r.receiver.Terminate(remaining()); err != nil {
+			errs = append(errs, fmt.Errorf("could not terminate receiver %s: %w", r.name, err))
+		}
+	}
+	for _, publisher := range publishers {
+		if err := publisher.Terminate(remaining()); err != nil {
+			errs = append(errs, fmt.Errorf("could not terminate publisher: %w", err))
+		}
+	}
+	for _, messagingService := range services {
+		if err := messagingService.Disconnect(); err != nil {
+			errs = append(errs, fmt.Errorf("could not disconnect: %w", err))
+		}
+	}
+	report.Duration = time.Since(started)
+	report.Err = errors.Join(errs...)
+	return report
+}
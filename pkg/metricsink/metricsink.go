@@ -0,0 +1,92 @@
+// Package metricsink selects where the samples send their metrics, from the settings, the environment variables or the
+// -config file (see internal/sampleconfig):
+//
+//	SOLACE_METRICS_SINK   prometheus (default), statsd or dogstatsd
+//	SOLACE_METRICS_ADDR   prometheus: address /metrics is served on, e.g. :2112, the metrics are not served when unset
+//	SOLACE_STATSD_ADDR    statsd and dogstatsd: address of the agent, localhost:8125 by default
+//	SOLACE_STATSD_PREFIX  statsd and dogstatsd: prefix of the metric names, solace by default
+//	SOLACE_STATSD_TAGS    dogstatsd: comma separated tags added to every metric, e.g. env:prod,team:payments
+//
+// Both sinks count the same metrics (see pkg/promexporter and pkg/statsd), the samples use them through Sink:
+//
+//	sink, err := metricsink.FromEnv()
+//	defer sink.Close()
+//	sink.AddService("receiver", messagingService)
+//	persistentReceiver.ReceiveAsync(sink.Handler("orders", handler))
+package metricsink
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"SolaceSamples.com/PubSub+Go/internal/sampleconfig"
+	"SolaceSamples.com/PubSub+Go/pkg/promexporter"
+	"SolaceSamples.com/PubSub+Go/pkg/statsd"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// Sink counts the metrics of a sample
+type Sink interface {
+	// AddService counts the API metrics and the reconnections of the messaging service under the service name
+	AddService(name string, messagingService solace.MessagingService)
+	// RemoveService stops counting the API metrics of the service
+	RemoveService(name string)
+	// Handler wraps the message handler of the named receiver to count the messages and time the handler
+	Handler(receiver string, handler solace.MessageHandler) solace.MessageHandler
+	// Ack acknowledges the message and counts it as an ACCEPTED settlement of the named receiver
+	Ack(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage) error
+	// Settle settles the message with the outcome and counts the settlement of the named receiver
+	Settle(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage, outcome config.MessageSettlementOutcome) error
+	// Settled counts a settlement made by the sample itself
+	Settled(receiver string, outcome config.MessageSettlementOutcome, err error)
+	// Close stops serving or sends the last metrics
+	Close() error
+}
+
+// Prometheus is the Prometheus sink, its exporter holds the registry for the metrics of the sample itself
+type Prometheus struct {
+	*promexporter.Exporter
+	server *http.Server
+}
+
+// Close stops serving the metrics
+func (p *Prometheus) Close() error {
+	if p.server == nil {
+		return nil
+	}
+	return p.server.Close()
+}
+
+// FromEnv returns the sink selected by SOLACE_METRICS_SINK, serving or sending the metrics right away
+func FromEnv() (Sink, error) {
+	switch kind := sampleconfig.Setting("SOLACE_METRICS_SINK", "prometheus"); kind {
+	case "prometheus":
+		sink := &Prometheus{Exporter: promexporter.New()}
+		if address := sampleconfig.Setting("SOLACE_METRICS_ADDR", ""); address != "" {
+			server, err := sink.ListenAndServe(address)
+			if err != nil {
+				return nil, err
+			}
+			sink.server = server
+		}
+		return sink, nil
+	case "statsd", "dogstatsd":
+		options := []statsd.Option{statsd.WithPrefix(sampleconfig.Setting("SOLACE_STATSD_PREFIX", "solace"))}
+		if kind == "dogstatsd" {
+			options = append(options, statsd.WithDogStatsD())
+			if tags := sampleconfig.Setting("SOLACE_STATSD_TAGS", ""); tags != "" {
+				options = append(options, statsd.WithTags(strings.Split(tags, ",")...))
+			}
+		}
+		emitter, err := statsd.New(sampleconfig.Setting("SOLACE_STATSD_ADDR", "localhost:8125"), options...)
+		if err != nil {
+			return nil, err
+		}
+		return emitter, nil
+	default:
+		return nil, fmt.Errorf("SOLACE_METRICS_SINK: unknown sink '%s', expected prometheus, statsd or dogstatsd", kind)
+	}
+}
@@ -0,0 +1,216 @@
+// Package metricsnap takes snapshots of the API metrics of a messaging service (messagingService.Metrics()) and diffs
+// them, for tools reporting the counters per interval or per run rather than since the service was created:
+//
+//	tracker := metricsnap.NewTracker(messagingService.Metrics())
+//	...
+//	diff := tracker.Interval() // the change since the previous interval
+//	diff.Render(os.Stdout, true)
+//
+// The API counters only go down when they are reset with Reset: a diff across a reset counts from zero, the value
+// after the reset, and flags the metric as reset.
+package metricsnap
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace/metrics"
+)
+
+// Metric names an API metric
+type Metric struct {
+	Metric metrics.Metric
+	Name   string
+}
+
+// All are the API metrics, in the order of the snapshots and of the rendering
+var All = []Metric{
+	{metrics.DirectMessagesSent, "direct messages sent"},
+	{metrics.DirectBytesSent, "direct bytes sent"},
+	{metrics.DirectMessagesReceived, "direct messages received"},
+	{metrics.DirectBytesReceived, "direct bytes received"},
+	{metrics.PersistentMessagesSent, "persistent messages sent"},
+	{metrics.PersistentBytesSent, "persistent bytes sent"},
+	{metrics.PublishedMessagesAcknowledged, "persistent messages acknowledged"},
+	{metrics.PersistentMessagesReceived, "persistent messages received"},
+	{metrics.PersistentBytesReceived, "persistent bytes received"},
+	{metrics.PersistentAcknowledgeSent, "persistent acknowledgements sent"},
+	{metrics.PersistentMessagesAccepted, "persistent messages accepted"},
+	{metrics.PersistentMessagesFailed, "persistent messages failed"},
+	{metrics.PersistentMessagesRejected, "persistent messages rejected"},
+	{metrics.PersistentMessagesRedelivered, "persistent messages redelivered"},
+	{metrics.PersistentDuplicateMessagesDiscarded, "persistent duplicates discarded"},
+	{metrics.PersistentNoMatchingFlowMessagesDiscarded, "persistent no matching flow discarded"},
+	{metrics.PersistentOutOfOrderMessagesDiscarded, "persistent out of order discarded"},
+	{metrics.ControlMessagesSent, "control messages sent"},
+	{metrics.ControlBytesSent, "control bytes sent"},
+	{metrics.ControlMessagesReceived, "control messages received"},
+	{metrics.ControlBytesReceived, "control bytes received"},
+	{metrics.TotalMessagesSent, "total messages sent"},
+	{metrics.TotalBytesSent, "total bytes sent"},
+	{metrics.TotalMessagesReceived, "total messages received"},
+	{metrics.TotalBytesReceived, "total bytes received"},
+	{metrics.CompressedBytesReceived, "compressed bytes received"},
+	{metrics.PublisherWouldBlock, "publisher would block"},
+	{metrics.PublishMessagesDiscarded, "publish messages discarded"},
+	{metrics.BrokerDiscardNotificationsReceived, "broker discard notifications"},
+	{metrics.ReceivedMessagesBackpressureDiscarded, "backpressure discards"},
+	{metrics.ReceivedMessagesTerminationDiscarded, "termination discards"},
+	{metrics.TooBigMessagesDiscarded, "too big messages discarded"},
+	{metrics.UnknownParameterMessagesDiscarded, "unknown parameter messages discarded"},
+	{metrics.ConnectionAttempts, "connection attempts"},
+}
+
+// Snapshot holds the values of the API metrics at a point in time, in the order of All
+type Snapshot struct {
+	Time   time.Time
+	Values []uint64
+}
+
+// Take reads the API metrics
+func Take(apiMetrics metrics.APIMetrics) Snapshot {
+	snapshot := Snapshot{Time: time.Now(), Values: make([]uint64, len(All))}
+	for i, metric := range All {
+		snapshot.Values[i] = apiMetrics.GetValue(metric.Metric)
+	}
+	return snapshot
+}
+
+// Get returns the value of the metric
+func (s Snapshot) Get(metric metrics.Metric) uint64 {
+	for i, m := range All {
+		if m.Metric == metric {
+			return s.Values[i]
+		}
+	}
+	return 0
+}
+
+// Delta is the change of a metric between two snapshots
+type Delta struct {
+	Metric
+	// Total is the value at the end of the diff
+	Total uint64
+	// Delta is the change over the diff, from zero when the metric was reset
+	Delta uint64
+	// Rate is the change per second
+	Rate float64
+	// Reset is set when the metric went down, i.e. it was reset during the diff
+	Reset bool
+}
+
+// Diff is the change of the API metrics between two snapshots
+type Diff struct {
+	From, To time.Time
+	Deltas   []Delta
+}
+
+// Diff returns the change of the metrics from the previous snapshot to this one
+func (s Snapshot) Diff(previous Snapshot) Diff {
+	diff := Diff{From: previous.Time, To: s.Time, Deltas: make([]Delta, len(All))}
+	elapsed := s.Time.Sub(previous.Time).Seconds()
+	for i, metric := range All {
+		delta := Delta{Metric: metric, Total: s.Values[i]}
+		if s.Values[i] < previous.Values[i] {
+			delta.Delta, delta.Reset = s.Values[i], true
+		} else {
+			delta.Delta = s.Values[i] - previous.Values[i]
+		}
+		if elapsed > 0 {
+			delta.Rate = float64(delta.Delta) / elapsed
+		}
+		diff.Deltas[i] = delta
+	}
+	return diff
+}
+
+// Elapsed returns the time between the snapshots
+func (d Diff) Elapsed() time.Duration {
+	return d.To.Sub(d.From)
+}
+
+// Get returns the change of the metric
+func (d Diff) Get(metric metrics.Metric) Delta {
+	for _, delta := range d.Deltas {
+		if delta.Metric.Metric == metric {
+			return delta
+		}
+	}
+	return Delta{}
+}
+
+// Changed returns the metrics that changed, in the order of All
+func (d Diff) Changed() []Delta {
+	var changed []Delta
+	for _, delta := range d.Deltas {
+		if delta.Delta > 0 || delta.Reset {
+			changed = append(changed, delta)
+		}
+	}
+	return changed
+}
+
+// Render writes the diff as a table of the metrics with their total, change and rate, only the ones that changed
+// when changedOnly is set. The metrics that were reset are marked with a *.
+func (d Diff) Render(w io.Writer, changedOnly bool) error {
+	deltas := d.Deltas
+	if changedOnly {
+		deltas = d.Changed()
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "metric\ttotal\tdelta\tper second\t\n")
+	for _, delta := range deltas {
+		reset := ""
+		if delta.Reset {
+			reset = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%+d%s\t%.1f\t\n", delta.Name, delta.Total, delta.Delta, reset, delta.Rate)
+	}
+	return tw.Flush()
+}
+
+// Tracker keeps the snapshot of the previous interval, it is safe for concurrent use
+type Tracker struct {
+	apiMetrics metrics.APIMetrics
+
+	mu       sync.Mutex
+	start    Snapshot
+	previous Snapshot
+}
+
+// NewTracker starts the first interval, and the run, now
+func NewTracker(apiMetrics metrics.APIMetrics) *Tracker {
+	snapshot := Take(apiMetrics)
+	return &Tracker{apiMetrics: apiMetrics, start: snapshot, previous: snapshot}
+}
+
+// Interval returns the change since the previous interval and starts a new one
+func (t *Tracker) Interval() Diff {
+	current := Take(t.apiMetrics)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	diff := current.Diff(t.previous)
+	t.previous = current
+	return diff
+}
+
+// Run returns the change since the tracker was created, or reset
+func (t *Tracker) Run() Diff {
+	current := Take(t.apiMetrics)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return current.Diff(t.start)
+}
+
+// Reset resets the API metrics of the service and starts a new run and interval. Any other tracker of the service
+// sees the reset in its next diff.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apiMetrics.Reset()
+	t.start = Take(t.apiMetrics)
+	t.previous = t.start
+}
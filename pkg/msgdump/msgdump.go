@@ -0,0 +1,215 @@
+// Package msgdump renders inbound messages for debugging: headers, destination, user properties,
+// delivery details and a preview of the payload, either as aligned text or as JSON.
+package msgdump
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// PayloadPreviewSize is the maximum number of payload bytes rendered in a dump
+const PayloadPreviewSize = 256
+
+// Dump holds the details of an inbound message that are rendered by Text and JSON.
+// Headers that are not set on the message are left empty and omitted from the output.
+type Dump struct {
+	Destination            string                 `json:"destination"`
+	ApplicationMessageID   string                 `json:"applicationMessageId,omitempty"`
+	ApplicationMessageType string                 `json:"applicationMessageType,omitempty"`
+	CorrelationID          string                 `json:"correlationId,omitempty"`
+	SenderID               string                 `json:"senderId,omitempty"`
+	SenderTimestamp        string                 `json:"senderTimestamp,omitempty"`
+	ReceiveTimestamp       string                 `json:"receiveTimestamp,omitempty"`
+	Expiration             string                 `json:"expiration,omitempty"`
+	SequenceNumber         *int64                 `json:"sequenceNumber,omitempty"`
+	Priority               *int                   `json:"priority,omitempty"`
+	ClassOfService         int                    `json:"classOfService"`
+	HTTPContentType        string                 `json:"httpContentType,omitempty"`
+	HTTPContentEncoding    string                 `json:"httpContentEncoding,omitempty"`
+	UserProperties         map[string]interface{} `json:"userProperties,omitempty"`
+	Redelivered            bool                   `json:"redelivered"`
+	ReplicationGroupMsgID  string                 `json:"replicationGroupMessageId,omitempty"`
+	BrokerDiscard          bool                   `json:"brokerDiscard"`
+	InternalDiscard        bool                   `json:"internalDiscard"`
+	PayloadSize            int                    `json:"payloadSize"`
+	PayloadEncoding        string                 `json:"payloadEncoding"`
+	Payload                string                 `json:"payload,omitempty"`
+	PayloadTruncated       bool                   `json:"payloadTruncated,omitempty"`
+}
+
+// Of collects the details of the given inbound message
+func Of(msg message.InboundMessage) Dump {
+	d := Dump{
+		Destination:    msg.GetDestinationName(),
+		ClassOfService: msg.GetClassOfService(),
+		Redelivered:    msg.IsRedelivered(),
+	}
+
+	d.ApplicationMessageID, _ = msg.GetApplicationMessageID()
+	d.ApplicationMessageType, _ = msg.GetApplicationMessageType()
+	d.CorrelationID, _ = msg.GetCorrelationID()
+	d.SenderID, _ = msg.GetSenderID()
+	d.HTTPContentType, _ = msg.GetHTTPContentType()
+	d.HTTPContentEncoding, _ = msg.GetHTTPContentEncoding()
+
+	if t, ok := msg.GetSenderTimestamp(); ok {
+		d.SenderTimestamp = t.Format(time.RFC3339Nano)
+	}
+	if t, ok := msg.GetTimeStamp(); ok {
+		d.ReceiveTimestamp = t.Format(time.RFC3339Nano)
+	}
+	if t := msg.GetExpiration(); !t.IsZero() && t.Unix() != 0 {
+		d.Expiration = t.Format(time.RFC3339Nano)
+	}
+	if seq, ok := msg.GetSequenceNumber(); ok {
+		d.SequenceNumber = &seq
+	}
+	if priority, ok := msg.GetPriority(); ok {
+		d.Priority = &priority
+	}
+	if id, ok := msg.GetReplicationGroupMessageID(); ok {
+		d.ReplicationGroupMsgID = id.String()
+	}
+	if discard := msg.GetMessageDiscardNotification(); discard != nil {
+		d.BrokerDiscard = discard.HasBrokerDiscardIndication()
+		d.InternalDiscard = discard.HasInternalDiscardIndication()
+	}
+
+	if properties := msg.GetProperties(); len(properties) > 0 {
+		d.UserProperties = make(map[string]interface{}, len(properties))
+		for key, value := range properties {
+			// keep the value as is when it can be rendered as JSON, otherwise fall back to its string form
+			if _, err := json.Marshal(value); err != nil {
+				d.UserProperties[key] = fmt.Sprint(value)
+			} else {
+				d.UserProperties[key] = value
+			}
+		}
+	}
+
+	if payload, ok := msg.GetPayloadAsString(); ok {
+		d.setPayload([]byte(payload))
+	} else if payload, ok := msg.GetPayloadAsBytes(); ok {
+		d.setPayload(payload)
+	} else if payload, ok := msg.GetPayloadAsMap(); ok {
+		d.setPayload([]byte(fmt.Sprint(payload)))
+		d.PayloadEncoding = "sdt-map"
+	} else if payload, ok := msg.GetPayloadAsStream(); ok {
+		d.setPayload([]byte(fmt.Sprint(payload)))
+		d.PayloadEncoding = "sdt-stream"
+	} else {
+		d.PayloadEncoding = "none"
+	}
+	return d
+}
+
+// setPayload renders up to PayloadPreviewSize bytes of the payload, as text when it is valid UTF-8
+// and as hex otherwise
+func (d *Dump) setPayload(payload []byte) {
+	d.PayloadSize = len(payload)
+	preview := payload
+	if len(preview) > PayloadPreviewSize {
+		preview = preview[:PayloadPreviewSize]
+		d.PayloadTruncated = true
+	}
+	// a text payload cut in the middle of a rune is still text, the cut is moved back to the start of the rune
+	text := preview
+	if cut := len(preview); cut < len(payload) {
+		for cut > 0 && cut > len(preview)-utf8.UTFMax && !utf8.RuneStart(payload[cut]) {
+			cut--
+		}
+		text = payload[:cut]
+	}
+	if utf8.Valid(text) {
+		d.PayloadEncoding = "utf-8"
+		d.Payload = string(text)
+	} else {
+		d.PayloadEncoding = "hex"
+		d.Payload = hex.EncodeToString(preview)
+	}
+}
+
+// WriteText writes the dump as aligned "label: value" lines
+func (d Dump) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	line := func(label string, value interface{}) {
+		fmt.Fprintf(tw, "%s:\t%v\n", label, value)
+	}
+	optional := func(label string, value string) {
+		if value != "" {
+			line(label, value)
+		}
+	}
+
+	line("Destination", d.Destination)
+	optional("Application Message ID", d.ApplicationMessageID)
+	optional("Application Message Type", d.ApplicationMessageType)
+	optional("Correlation ID", d.CorrelationID)
+	optional("Sender ID", d.SenderID)
+	optional("Sender Timestamp", d.SenderTimestamp)
+	optional("Receive Timestamp", d.ReceiveTimestamp)
+	optional("Expiration", d.Expiration)
+	if d.SequenceNumber != nil {
+		line("Sequence Number", *d.SequenceNumber)
+	}
+	if d.Priority != nil {
+		line("Priority", *d.Priority)
+	}
+	line("Class Of Service", d.ClassOfService)
+	optional("HTTP Content Type", d.HTTPContentType)
+	optional("HTTP Content Encoding", d.HTTPContentEncoding)
+	line("Redelivered", d.Redelivered)
+	optional("Replication Group Message ID", d.ReplicationGroupMsgID)
+	line("Discard Indication", fmt.Sprintf("broker=%t internal=%t", d.BrokerDiscard, d.InternalDiscard))
+
+	keys := make([]string, 0, len(d.UserProperties))
+	for key := range d.UserProperties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		line("User Property "+key, d.UserProperties[key])
+	}
+
+	size := fmt.Sprintf("%d bytes (%s)", d.PayloadSize, d.PayloadEncoding)
+	if d.PayloadTruncated {
+		size += fmt.Sprintf(", first %d shown", PayloadPreviewSize)
+	}
+	line("Payload Size", size)
+	if d.Payload != "" {
+		// keep multi-line payloads aligned under the value column
+		line("Payload", strings.ReplaceAll(d.Payload, "\n", "\n\t"))
+	}
+	return tw.Flush()
+}
+
+// Text returns the dump rendered as aligned text
+func (d Dump) Text() string {
+	var sb strings.Builder
+	d.WriteText(&sb)
+	return sb.String()
+}
+
+// JSON returns the dump rendered as indented JSON
+func (d Dump) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// Text renders the given inbound message as aligned text
+func Text(msg message.InboundMessage) string {
+	return Of(msg).Text()
+}
+
+// JSON renders the given inbound message as indented JSON
+func JSON(msg message.InboundMessage) ([]byte, error) {
+	return Of(msg).JSON()
+}
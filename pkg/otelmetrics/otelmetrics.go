@@ -0,0 +1,161 @@
+// Package otelmetrics translates the metrics of the samples into OpenTelemetry instruments exported over OTLP, next
+// to the spans of the tracing samples (patterns/otel-tracing), so one backend holds both:
+//
+//   - the API metrics of the messaging services (messagingService.Metrics()), observed at every collection as the
+//     solace.api.<metric> counters, with the name given to the messaging service as solace.service attribute
+//   - solace.service.connected, 1 while the messaging service is connected
+//   - the counters of the samples themselves, see Counters
+//
+// Usage:
+//
+//	meterProvider, err := otelmetrics.NewMeterProvider(ctx, "my-sample", 10*time.Second)
+//	defer meterProvider.Shutdown(ctx)
+//	meter := meterProvider.Meter("my-sample")
+//	otelmetrics.ObserveService(meter, "publisher", messagingService)
+//	counters, err := otelmetrics.NewCounters(meter)
+//	counters.Published.Add(ctx, 1)
+//
+// The endpoint is set with the standard OTEL_EXPORTER_OTLP_ENDPOINT variable (http://localhost:4318 by default).
+package otelmetrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/metrics"
+)
+
+// ServiceAttribute names the messaging service the API metrics belong to
+const ServiceAttribute = attribute.Key("solace.service")
+
+// apiMetrics names the API metrics, solace.api.<name>, with their unit
+var apiMetrics = []struct {
+	metric metrics.Metric
+	name   string
+	unit   string
+}{
+	{metrics.DirectBytesReceived, "direct.bytes.received", "By"},
+	{metrics.DirectMessagesReceived, "direct.messages.received", "{message}"},
+	{metrics.BrokerDiscardNotificationsReceived, "broker.discard_notifications.received", "{message}"},
+	{metrics.UnknownParameterMessagesDiscarded, "unknown_parameter.messages.discarded", "{message}"},
+	{metrics.TooBigMessagesDiscarded, "too_big.messages.discarded", "{message}"},
+	{metrics.PersistentAcknowledgeSent, "persistent.acknowledgements.sent", "{acknowledgement}"},
+	{metrics.PersistentDuplicateMessagesDiscarded, "persistent.duplicate.messages.discarded", "{message}"},
+	{metrics.PersistentNoMatchingFlowMessagesDiscarded, "persistent.no_matching_flow.messages.discarded", "{message}"},
+	{metrics.PersistentOutOfOrderMessagesDiscarded, "persistent.out_of_order.messages.discarded", "{message}"},
+	{metrics.PersistentBytesReceived, "persistent.bytes.received", "By"},
+	{metrics.PersistentMessagesReceived, "persistent.messages.received", "{message}"},
+	{metrics.ControlMessagesReceived, "control.messages.received", "{message}"},
+	{metrics.ControlBytesReceived, "control.bytes.received", "By"},
+	{metrics.TotalBytesReceived, "total.bytes.received", "By"},
+	{metrics.TotalMessagesReceived, "total.messages.received", "{message}"},
+	{metrics.CompressedBytesReceived, "compressed.bytes.received", "By"},
+	{metrics.PersistentBytesSent, "persistent.bytes.sent", "By"},
+	{metrics.PersistentMessagesSent, "persistent.messages.sent", "{message}"},
+	{metrics.DirectBytesSent, "direct.bytes.sent", "By"},
+	{metrics.DirectMessagesSent, "direct.messages.sent", "{message}"},
+	{metrics.ControlMessagesSent, "control.messages.sent", "{message}"},
+	{metrics.ControlBytesSent, "control.bytes.sent", "By"},
+	{metrics.TotalBytesSent, "total.bytes.sent", "By"},
+	{metrics.TotalMessagesSent, "total.messages.sent", "{message}"},
+	{metrics.ConnectionAttempts, "connection.attempts", "{attempt}"},
+	{metrics.PublishedMessagesAcknowledged, "published.messages.acknowledged", "{message}"},
+	{metrics.PublishMessagesDiscarded, "publish.messages.discarded", "{message}"},
+	{metrics.PublisherWouldBlock, "publisher.would_block", "{publish}"},
+	{metrics.ReceivedMessagesTerminationDiscarded, "received.messages.termination_discarded", "{message}"},
+	{metrics.ReceivedMessagesBackpressureDiscarded, "received.messages.backpressure_discarded", "{message}"},
+	{metrics.PersistentMessagesRedelivered, "persistent.messages.redelivered", "{message}"},
+	{metrics.PersistentMessagesAccepted, "persistent.messages.accepted", "{message}"},
+	{metrics.PersistentMessagesFailed, "persistent.messages.failed", "{message}"},
+	{metrics.PersistentMessagesRejected, "persistent.messages.rejected", "{message}"},
+}
+
+// NewMeterProvider creates a meter provider exporting over OTLP/HTTP every interval, shut it down before exiting to
+// export the last values
+func NewMeterProvider(ctx context.Context, serviceName string, interval time.Duration) (*sdkmetric.MeterProvider, error) {
+	exporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(sdkresource.NewSchemaless(semconv.ServiceName(serviceName))),
+	), nil
+}
+
+// ObserveService observes the API metrics of the messaging service under the name, unregister the returned
+// registration to stop observing it, e.g. once it is disconnected for good
+func ObserveService(meter metric.Meter, name string, messagingService solace.MessagingService) (metric.Registration, error) {
+	counters := make([]metric.Int64ObservableCounter, len(apiMetrics))
+	instruments := make([]metric.Observable, 0, len(apiMetrics)+1)
+	for i, m := range apiMetrics {
+		counter, err := meter.Int64ObservableCounter("solace.api."+m.name, metric.WithUnit(m.unit))
+		if err != nil {
+			return nil, err
+		}
+		counters[i] = counter
+		instruments = append(instruments, counter)
+	}
+	connected, err := meter.Int64ObservableGauge("solace.service.connected",
+		metric.WithDescription("1 while the messaging service is connected"))
+	if err != nil {
+		return nil, err
+	}
+	instruments = append(instruments, connected)
+
+	attributes := metric.WithAttributes(ServiceAttribute.String(name))
+	return meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		serviceMetrics := messagingService.Metrics()
+		for i, m := range apiMetrics {
+			observer.ObserveInt64(counters[i], int64(serviceMetrics.GetValue(m.metric)), attributes)
+		}
+		var isConnected int64
+		if messagingService.IsConnected() {
+			isConnected = 1
+		}
+		observer.ObserveInt64(connected, isConnected, attributes)
+		return nil
+	}, instruments...)
+}
+
+// Counters are the instruments the samples record their own activity with
+type Counters struct {
+	// Published counts the messages published
+	Published metric.Int64Counter
+	// PublishFailures counts the messages that could not be published
+	PublishFailures metric.Int64Counter
+	// Received counts the messages received
+	Received metric.Int64Counter
+	// ProcessingDuration records the time spent processing a received message
+	ProcessingDuration metric.Float64Histogram
+}
+
+// NewCounters creates the counters of the samples
+func NewCounters(meter metric.Meter) (*Counters, error) {
+	var c Counters
+	var err error
+	if c.Published, err = meter.Int64Counter("solace.sample.messages.published", metric.WithUnit("{message}"),
+		metric.WithDescription("Messages published by the sample")); err != nil {
+		return nil, err
+	}
+	if c.PublishFailures, err = meter.Int64Counter("solace.sample.messages.publish_failures", metric.WithUnit("{message}"),
+		metric.WithDescription("Messages the sample could not publish")); err != nil {
+		return nil, err
+	}
+	if c.Received, err = meter.Int64Counter("solace.sample.messages.received", metric.WithUnit("{message}"),
+		metric.WithDescription("Messages received by the sample")); err != nil {
+		return nil, err
+	}
+	if c.ProcessingDuration, err = meter.Float64Histogram("solace.sample.processing.duration", metric.WithUnit("s"),
+		metric.WithDescription("Time spent processing a received message")); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
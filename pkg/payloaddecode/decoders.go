@@ -0,0 +1,129 @@
+package payloaddecode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxInflated caps the size of a decompressed payload, against compression bombs
+const maxInflated = 64 << 20
+
+// Gzip decompresses gzip payloads, detected from the gzip content encoding or the gzip magic number
+type Gzip struct{}
+
+func (Gzip) Name() string { return "gzip" }
+
+func (Gzip) Accepts(hints Hints, payload []byte) bool {
+	return strings.EqualFold(hints.ContentEncoding, "gzip") || bytes.HasPrefix(payload, []byte{0x1f, 0x8b})
+}
+
+func (Gzip) Decode(hints Hints, payload []byte) (string, []byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return "", nil, err
+	}
+	defer reader.Close()
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxInflated+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(inflated) > maxInflated {
+		return "", nil, fmt.Errorf("the payload inflates to more than %d bytes", maxInflated)
+	}
+	return fmt.Sprintf("%d bytes inflated to %d bytes", len(payload), len(inflated)), inflated, nil
+}
+
+// JSON pretty prints JSON payloads, detected from a JSON content type, e.g. application/json or
+// application/cloudevents+json, or a payload holding a JSON object or array
+type JSON struct{}
+
+func (JSON) Name() string { return "json" }
+
+func (JSON) Accepts(hints Hints, payload []byte) bool {
+	if strings.HasSuffix(hints.ContentType, "json") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(payload)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed)
+}
+
+func (JSON) Decode(hints Hints, payload []byte) (string, []byte, error) {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(payload), "", "  "); err != nil {
+		return "", nil, err
+	}
+	return indented.String(), nil, nil
+}
+
+// UTF8 renders text payloads, detected from a text content type or a payload of valid UTF-8 made of printable
+// characters and white space
+type UTF8 struct {
+	// MaxBytes limits the rendering, 0 for no limit
+	MaxBytes int
+}
+
+func (UTF8) Name() string { return "utf8" }
+
+func (UTF8) Accepts(hints Hints, payload []byte) bool {
+	if strings.HasPrefix(hints.ContentType, "text/") {
+		return true
+	}
+	if len(payload) == 0 || !utf8.Valid(payload) {
+		return false
+	}
+	for _, r := range string(payload) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (u UTF8) Decode(hints Hints, payload []byte) (string, []byte, error) {
+	text, truncated := limit(payload, u.MaxBytes)
+	if truncated != "" {
+		// the limit may cut the last character, it is left out with the bytes after it
+		cut := len(text)
+		for cut > 0 && cut > len(text)-utf8.UTFMax && !utf8.RuneStart(payload[cut]) {
+			cut--
+		}
+		text, truncated = payload[:cut], more(len(payload)-cut)
+	}
+	// the invalid sequences of a payload decoded on demand are replaced
+	return strings.ToValidUTF8(string(text), "\uFFFD") + truncated, nil, nil
+}
+
+// Hex renders any payload as a hex dump, with offsets and the printable characters, as hexdump -C does
+type Hex struct {
+	// MaxBytes limits the rendering, 0 for no limit
+	MaxBytes int
+}
+
+func (Hex) Name() string { return "hex" }
+
+func (Hex) Accepts(Hints, []byte) bool { return true }
+
+func (h Hex) Decode(hints Hints, payload []byte) (string, []byte, error) {
+	data, truncated := limit(payload, h.MaxBytes)
+	return strings.TrimSuffix(hex.Dump(data), "\n") + truncated, nil, nil
+}
+
+// limit returns the first maxBytes of the payload, with a note of the bytes left out
+func limit(payload []byte, maxBytes int) ([]byte, string) {
+	if maxBytes <= 0 || len(payload) <= maxBytes {
+		return payload, ""
+	}
+	return payload[:maxBytes], more(len(payload) - maxBytes)
+}
+
+// more notes the bytes left out of a rendering
+func more(n int) string {
+	return fmt.Sprintf("\n... %d more bytes", n)
+}
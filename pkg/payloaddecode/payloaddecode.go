@@ -0,0 +1,171 @@
+// Package payloaddecode renders message payloads through pluggable decoders, to debug "garbage payload" reports: a
+// hex dump, UTF-8 text, pretty printed JSON, gzip decompression and protobuf messages decoded with a descriptor set
+// (see NewProtobuf). Unless decoders are named, the decoder is detected from the content type and encoding of the
+// message, then by sniffing the payload, and the payloads unwrapped by a decoder, e.g. gzip, are decoded in turn:
+//
+//	decoders := payloaddecode.Default(4096)
+//	decoders.Register(protobufDecoder)
+//	layers, err := decoders.Decode(payloaddecode.HintsOf(msg), payload)
+//	layers, err = decoders.Decode(payloaddecode.HintsOf(msg), payload, "gzip", "json")
+//
+// A decoder is added by implementing Decoder and registering it, it is then detected before the built-in ones.
+package payloaddecode
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// maxDepth is the number of payloads unwrapped in a row before the decoding stops, e.g. gzip in gzip
+const maxDepth = 4
+
+// Hints describe the payload, from the headers and the user properties of the message
+type Hints struct {
+	// ContentType is the media type of the payload, e.g. application/json, without its parameters
+	ContentType string
+	// ContentEncoding is the encoding of the payload, e.g. gzip
+	ContentEncoding string
+	// MessageType is the type of the payload within its content type, e.g. the full name of a protobuf message
+	MessageType string
+}
+
+// HintsOf returns the hints of the message: the HTTP content type and encoding, or the content-type and
+// content-encoding user properties, and the message type from the messageType (or proto) parameter of the content
+// type, or the application message type
+func HintsOf(msg message.InboundMessage) Hints {
+	var hints Hints
+	contentType, ok := msg.GetHTTPContentType()
+	if !ok || contentType == "" {
+		contentType = property(msg, "content-type", "Content-Type")
+	}
+	hints.ContentEncoding, ok = msg.GetHTTPContentEncoding()
+	if !ok || hints.ContentEncoding == "" {
+		hints.ContentEncoding = property(msg, "content-encoding", "Content-Encoding")
+	}
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+		hints.ContentType = mediaType
+		hints.MessageType = params["messagetype"]
+		if hints.MessageType == "" {
+			hints.MessageType = params["proto"]
+		}
+	}
+	if hints.MessageType == "" {
+		hints.MessageType, _ = msg.GetApplicationMessageType()
+	}
+	return hints
+}
+
+// property returns the first of the user properties set, as a string
+func property(msg message.InboundMessage, names ...string) string {
+	for _, name := range names {
+		if value, ok := msg.GetProperty(name); ok && value != nil {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// Decoder renders payloads of a kind
+type Decoder interface {
+	// Name names the decoder, e.g. json
+	Name() string
+	// Accepts reports whether the payload is of the kind of the decoder, for the detection
+	Accepts(hints Hints, payload []byte) bool
+	// Decode renders the payload. A decoder unwrapping the payload, e.g. gzip, returns the unwrapped payload as
+	// inner, which is decoded next; the others return nil.
+	Decode(hints Hints, payload []byte) (text string, inner []byte, err error)
+}
+
+// Layer is the rendering of a payload by a decoder
+type Layer struct {
+	Decoder string
+	Text    string
+}
+
+// Registry holds the decoders, in the order of the detection
+type Registry struct {
+	decoders []Decoder
+}
+
+// Default returns the registry of the built-in decoders: gzip, json, utf8 and hex, the last accepting any payload.
+// The text and hex renderings are limited to maxBytes, 0 for no limit.
+func Default(maxBytes int) *Registry {
+	return &Registry{decoders: []Decoder{Gzip{}, JSON{}, UTF8{MaxBytes: maxBytes}, Hex{MaxBytes: maxBytes}}}
+}
+
+// Register adds the decoder, detected before the decoders already registered. A decoder with the same name is
+// replaced.
+func (r *Registry) Register(decoder Decoder) {
+	decoders := []Decoder{decoder}
+	for _, d := range r.decoders {
+		if d.Name() != decoder.Name() {
+			decoders = append(decoders, d)
+		}
+	}
+	r.decoders = decoders
+}
+
+// Names returns the names of the decoders, in the order of the detection
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.decoders))
+	for i, d := range r.decoders {
+		names[i] = d.Name()
+	}
+	return names
+}
+
+// Lookup returns the decoder with the name
+func (r *Registry) Lookup(name string) (Decoder, bool) {
+	for _, d := range r.decoders {
+		if d.Name() == name {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// Detect returns the first decoder accepting the payload
+func (r *Registry) Detect(hints Hints, payload []byte) (Decoder, bool) {
+	for _, d := range r.decoders {
+		if d.Accepts(hints, payload) {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// Decode renders the payload through the named decoders, in order, each one decoding the payload unwrapped by the
+// previous one. Without names, or once the named decoders unwrapped a payload left to decode, the decoders are
+// detected. The layers rendered so far are returned with the error of a decoder.
+func (r *Registry) Decode(hints Hints, payload []byte, names ...string) ([]Layer, error) {
+	var layers []Layer
+	for depth := 0; payload != nil; depth++ {
+		var decoder Decoder
+		if depth < len(names) {
+			var ok bool
+			if decoder, ok = r.Lookup(names[depth]); !ok {
+				return layers, fmt.Errorf("unknown decoder %s, expected one of %s", names[depth], strings.Join(r.Names(), ", "))
+			}
+		} else {
+			if depth >= maxDepth+len(names) {
+				break
+			}
+			var ok bool
+			if decoder, ok = r.Detect(hints, payload); !ok {
+				return layers, fmt.Errorf("no decoder accepts the payload")
+			}
+		}
+		text, inner, err := decoder.Decode(hints, payload)
+		if err != nil {
+			return layers, fmt.Errorf("%s: %w", decoder.Name(), err)
+		}
+		layers = append(layers, Layer{Decoder: decoder.Name(), Text: text})
+		// the encoding applied to the outer payload only
+		hints.ContentEncoding = ""
+		payload = inner
+	}
+	return layers, nil
+}
@@ -0,0 +1,90 @@
+package payloaddecode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+// gzipped compresses the payload
+func gzipped(t *testing.T, payload string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(payload)); err != nil {
+		t.Fatalf("could not compress the payload: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not compress the payload: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		hints   Hints
+		payload []byte
+		// maxBytes limits the text and hex renderings
+		maxBytes int
+		names    []string
+		// decoders are the decoders of the layers rendered, in order
+		decoders []string
+		// text is the rendering of the last layer
+		text string
+		err  string
+	}{
+		{name: "json object", payload: []byte(` {"a":1} `), decoders: []string{"json"}, text: "{\n  \"a\": 1\n}"},
+		{name: "json content type", hints: Hints{ContentType: "application/cloudevents+json"}, payload: []byte(`"a"`), decoders: []string{"json"}, text: `"a"`},
+		{name: "json scalar is text", payload: []byte(`42`), decoders: []string{"utf8"}, text: "42"},
+		{name: "text", payload: []byte("héllo\nworld"), decoders: []string{"utf8"}, text: "héllo\nworld"},
+		{name: "text cut at a rune", payload: []byte("aaé"), maxBytes: 3, decoders: []string{"utf8"}, text: "aa\n... 2 more bytes"},
+		{name: "text cut", payload: []byte("aaé"), maxBytes: 2, decoders: []string{"utf8"}, text: "aa\n... 2 more bytes"},
+		{name: "hex cut", payload: []byte{0x00, 0x01, 'a'}, maxBytes: 1, decoders: []string{"hex"}, text: "00000000  00                                                |.|\n... 2 more bytes"},
+		{name: "binary", payload: []byte{0x00, 0x01, 'a'}, decoders: []string{"hex"}, text: "00000000  00 01 61                                          |..a|"},
+		{name: "gzip json", payload: gzipped(t, `[1]`), decoders: []string{"gzip", "json"}, text: "[\n  1\n]"},
+		{name: "gzip content encoding", hints: Hints{ContentEncoding: "GZIP"}, payload: gzipped(t, "plain"), decoders: []string{"gzip", "utf8"}, text: "plain"},
+		{name: "named decoder", payload: []byte(`{}`), names: []string{"hex"}, decoders: []string{"hex"}, text: "00000000  7b 7d                                             |{}|"},
+		{name: "named then detected", payload: gzipped(t, `{}`), names: []string{"gzip"}, decoders: []string{"gzip", "json"}, text: "{}"},
+		{name: "unknown decoder", payload: []byte("a"), names: []string{"avro"}, err: "unknown decoder avro, expected one of gzip, json, utf8, hex"},
+		{name: "decoder error", payload: []byte("not gzip"), names: []string{"gzip"}, err: "gzip: "},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			layers, err := Default(test.maxBytes).Decode(test.hints, test.payload, test.names...)
+			if test.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+					t.Fatalf("got error %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not decode the payload: %s", err)
+			}
+			var decoders []string
+			for _, layer := range layers {
+				decoders = append(decoders, layer.Decoder)
+			}
+			if strings.Join(decoders, ",") != strings.Join(test.decoders, ",") {
+				t.Fatalf("decoded by %v, expected %v", decoders, test.decoders)
+			}
+			if text := layers[len(layers)-1].Text; text != test.text {
+				t.Errorf("rendered %q, expected %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	registry := Default(0)
+	registry.Register(Hex{MaxBytes: 1})
+	if names := strings.Join(registry.Names(), ","); names != "hex,gzip,json,utf8" {
+		t.Fatalf("registered %s, expected the hex decoder replaced and detected first", names)
+	}
+	if decoder, _ := registry.Detect(Hints{}, []byte(`{}`)); decoder.Name() != "hex" {
+		t.Errorf("detected %s, expected hex", decoder.Name())
+	}
+}
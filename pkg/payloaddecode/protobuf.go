@@ -0,0 +1,100 @@
+package payloaddecode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Protobuf decodes protobuf payloads with the message descriptors of a descriptor set, as written by
+// protoc --descriptor_set_out=set.pb --include_imports or buf build -o set.pb, and renders them as JSON. The
+// message type is the one of the hints, see HintsOf, or the default one. The payloads are detected from a protobuf
+// content type, e.g. application/x-protobuf, or from a message type of the hints known to the descriptor set.
+type Protobuf struct {
+	files       *protoregistry.Files
+	defaultType protoreflect.MessageDescriptor
+}
+
+// NewProtobuf reads the descriptor set. The default message type, e.g. shop.Order, decodes the payloads without a
+// message type in their hints, none when empty.
+func NewProtobuf(descriptorSetPath, defaultType string) (*Protobuf, error) {
+	data, err := os.ReadFile(descriptorSetPath)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%s is not a descriptor set: %w", descriptorSetPath, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s, were the imports included? %w", descriptorSetPath, err)
+	}
+	p := &Protobuf{files: files}
+	if defaultType != "" {
+		if p.defaultType, err = p.lookup(defaultType); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// lookup returns the descriptor of the message type, a full name with or without a leading dot
+func (p *Protobuf) lookup(name string) (protoreflect.MessageDescriptor, error) {
+	descriptor, err := p.files.FindDescriptorByName(protoreflect.FullName(strings.TrimPrefix(name, ".")))
+	if err != nil {
+		return nil, fmt.Errorf("message type %s: %w", name, err)
+	}
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", name)
+	}
+	return message, nil
+}
+
+// messageType returns the descriptor of the message type of the hints, or the default one
+func (p *Protobuf) messageType(hints Hints) (protoreflect.MessageDescriptor, error) {
+	if hints.MessageType != "" {
+		if descriptor, err := p.lookup(hints.MessageType); err == nil || p.defaultType == nil {
+			return descriptor, err
+		}
+	}
+	if p.defaultType == nil {
+		return nil, fmt.Errorf("no message type in the message and no default one")
+	}
+	return p.defaultType, nil
+}
+
+func (p *Protobuf) Name() string { return "protobuf" }
+
+func (p *Protobuf) Accepts(hints Hints, payload []byte) bool {
+	if strings.Contains(hints.ContentType, "protobuf") {
+		return true
+	}
+	if hints.MessageType == "" {
+		return false
+	}
+	_, err := p.lookup(hints.MessageType)
+	return err == nil
+}
+
+func (p *Protobuf) Decode(hints Hints, payload []byte) (string, []byte, error) {
+	descriptor, err := p.messageType(hints)
+	if err != nil {
+		return "", nil, err
+	}
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return "", nil, fmt.Errorf("not a %s: %w", descriptor.FullName(), err)
+	}
+	text := protojson.MarshalOptions{Multiline: true, Indent: "  ", EmitUnpopulated: true}.Format(message)
+	return string(descriptor.FullName()) + " " + text, nil, nil
+}
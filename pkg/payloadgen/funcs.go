@@ -0,0 +1,147 @@
+package payloadgen
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	firstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Ken", "Barbara", "Dennis", "Frances", "John", "Radia", "Niklaus"}
+	lastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Thompson", "Liskov", "Ritchie", "Allen", "Backus", "Perlman", "Wirth"}
+	words      = []string{"order", "payment", "invoice", "shipment", "customer", "account", "refund", "basket", "item", "status", "priority", "region"}
+	cities     = []string{"Ottawa", "London", "Paris", "Tokyo", "Sydney", "Singapore", "Toronto", "Berlin", "Madrid", "Chicago"}
+	countries  = []string{"CA", "GB", "FR", "JP", "AU", "SG", "US", "DE", "ES", "BR"}
+	currencies = []string{"CAD", "USD", "EUR", "GBP", "JPY", "AUD", "SGD"}
+)
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Funcs are the faker functions of the templates, drawing from the random source of the generator:
+//
+//	uuid                   random UUID (version 4)
+//	now, unix, unixMilli   current time, RFC 3339 with nanoseconds, in seconds or milliseconds since the epoch
+//	ago max                time up to the duration before now, e.g. {{ago "24h"}}
+//	int min max            integer between min and max, included
+//	float min max          number between min and max
+//	amount min max         amount between min and max, with 2 decimals
+//	normal mean stddev     number around the mean, with the standard deviation
+//	exp mean               number with an exponential distribution of the mean
+//	bool                   true or false
+//	pick a b ...           one of the arguments
+//	weighted a 3 b 1 ...   one of the values, each picked in proportion to the weight after it
+//	firstName, lastName, name, email, city, country, currency, word
+//	words n                n words separated by spaces
+//	text n, hex n          n random alphanumeric or hexadecimal characters
+//
+// They are bound to the generator, and only called by Next under its lock.
+func (g *Generator) Funcs() template.FuncMap {
+	r := g.random
+	return template.FuncMap{
+		"uuid": func() string {
+			b := make([]byte, 16)
+			r.Read(b)
+			b[6] = b[6]&0x0f | 0x40
+			b[8] = b[8]&0x3f | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		},
+		"now":       func() string { return time.Now().UTC().Format(time.RFC3339Nano) },
+		"unix":      func() int64 { return time.Now().Unix() },
+		"unixMilli": func() int64 { return time.Now().UnixMilli() },
+		"ago": func(max string) (string, error) {
+			d, err := time.ParseDuration(max)
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("ago: invalid duration %q", max)
+			}
+			return time.Now().Add(-time.Duration(r.Int63n(int64(d)))).UTC().Format(time.RFC3339Nano), nil
+		},
+		"int": func(min, max int) int {
+			if max <= min {
+				return min
+			}
+			return min + r.Intn(max-min+1)
+		},
+		"float":  func(min, max float64) float64 { return min + r.Float64()*(max-min) },
+		"amount": func(min, max float64) string { return fmt.Sprintf("%.2f", min+r.Float64()*(max-min)) },
+		"normal": func(mean, stddev float64) float64 { return r.NormFloat64()*stddev + mean },
+		"exp":    func(mean float64) float64 { return r.ExpFloat64() * mean },
+		"bool":   func() bool { return r.Intn(2) == 1 },
+		"pick": func(values ...interface{}) interface{} {
+			if len(values) == 0 {
+				return ""
+			}
+			return values[r.Intn(len(values))]
+		},
+		"weighted": func(pairs ...interface{}) (interface{}, error) {
+			if len(pairs) == 0 || len(pairs)%2 != 0 {
+				return nil, fmt.Errorf("weighted: expected value weight pairs")
+			}
+			total := 0.0
+			for i := 1; i < len(pairs); i += 2 {
+				weight, ok := toFloat(pairs[i])
+				if !ok || weight < 0 {
+					return nil, fmt.Errorf("weighted: invalid weight %v", pairs[i])
+				}
+				total += weight
+			}
+			pick := r.Float64() * total
+			for i := 1; i < len(pairs); i += 2 {
+				weight, _ := toFloat(pairs[i])
+				if pick < weight {
+					return pairs[i-1], nil
+				}
+				pick -= weight
+			}
+			return pairs[len(pairs)-2], nil
+		},
+		"firstName": func() string { return firstNames[r.Intn(len(firstNames))] },
+		"lastName":  func() string { return lastNames[r.Intn(len(lastNames))] },
+		"name": func() string {
+			return firstNames[r.Intn(len(firstNames))] + " " + lastNames[r.Intn(len(lastNames))]
+		},
+		"email": func() string {
+			return strings.ToLower(firstNames[r.Intn(len(firstNames))]+"."+lastNames[r.Intn(len(lastNames))]) + "@example.com"
+		},
+		"city":     func() string { return cities[r.Intn(len(cities))] },
+		"country":  func() string { return countries[r.Intn(len(countries))] },
+		"currency": func() string { return currencies[r.Intn(len(currencies))] },
+		"word":     func() string { return words[r.Intn(len(words))] },
+		"words": func(n int) string {
+			picked := make([]string, n)
+			for i := range picked {
+				picked[i] = words[r.Intn(len(words))]
+			}
+			return strings.Join(picked, " ")
+		},
+		"text": g.text,
+		"hex": func(n int) string {
+			b := make([]byte, (n+1)/2)
+			r.Read(b)
+			return fmt.Sprintf("%x", b)[:n]
+		},
+	}
+}
+
+// text returns n random alphanumeric characters
+func (g *Generator) text(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphanumeric[g.random.Intn(len(alphanumeric))]
+	}
+	return string(b)
+}
+
+// toFloat converts the numbers of the templates, ints or floats
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, !math.IsNaN(n)
+	}
+	return 0, false
+}
@@ -0,0 +1,196 @@
+// Package payloadgen generates message payloads from Go templates (text/template), to publish realistic traffic
+// instead of fixed or empty payloads. The templates call faker functions for the values, see Funcs, and the payloads
+// are padded to a size drawn from a distribution, see Distribution:
+//
+//	generator, err := payloadgen.New(`{"orderId":"{{uuid}}","amount":{{amount 5 500}},"currency":"{{currency}}","at":"{{now}}"}`,
+//		payloadgen.Options{Size: payloadgen.Uniform(200, 2000)})
+//	payload, err := generator.Next()
+//
+// The values are drawn from a random source seeded with Options.Seed, so that a run can be reproduced. The template
+// data are the sequence number of the payload, .Seq, starting at 1, and its target size, .Size.
+package payloadgen
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Distribution draws numbers, the sizes of the payloads
+type Distribution interface {
+	// Sample draws a number with the random source
+	Sample(r *rand.Rand) int
+}
+
+type fixed int
+
+func (f fixed) Sample(*rand.Rand) int { return int(f) }
+
+// Fixed always draws n
+func Fixed(n int) Distribution { return fixed(n) }
+
+type uniform struct{ min, max int }
+
+func (u uniform) Sample(r *rand.Rand) int { return u.min + r.Intn(u.max-u.min+1) }
+
+// Uniform draws numbers evenly between min and max, included
+func Uniform(min, max int) Distribution {
+	if max < min {
+		min, max = max, min
+	}
+	return uniform{min, max}
+}
+
+type normal struct{ mean, stddev float64 }
+
+func (n normal) Sample(r *rand.Rand) int {
+	return int(math.Max(0, math.Round(r.NormFloat64()*n.stddev+n.mean)))
+}
+
+// Normal draws numbers around the mean, with the standard deviation, never below 0
+func Normal(mean, stddev float64) Distribution { return normal{mean, stddev} }
+
+type exponential struct{ mean float64 }
+
+func (e exponential) Sample(r *rand.Rand) int { return int(math.Round(r.ExpFloat64() * e.mean)) }
+
+// Exponential draws mostly small numbers and a few large ones, with the mean, e.g. the sizes of documents
+func Exponential(mean float64) Distribution { return exponential{mean} }
+
+// ParseDistribution parses the distribution of a command line flag:
+//
+//	1024                 Fixed(1024)
+//	uniform:100-1000     Uniform(100, 1000)
+//	normal:512,64        Normal(512, 64)
+//	exp:256              Exponential(256)
+func ParseDistribution(spec string) (Distribution, error) {
+	kind, args, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		n, err := strconv.Atoi(kind)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid size %q, expected a number of bytes or a distribution", spec)
+		}
+		return Fixed(n), nil
+	}
+	invalid := fmt.Errorf("invalid %s distribution %q", kind, spec)
+	switch kind {
+	case "uniform":
+		low, high, ok := strings.Cut(args, "-")
+		min, err1 := strconv.Atoi(low)
+		max, err2 := strconv.Atoi(high)
+		if !ok || err1 != nil || err2 != nil || min < 0 || max < 0 {
+			return nil, invalid
+		}
+		return Uniform(min, max), nil
+	case "normal":
+		m, s, ok := strings.Cut(args, ",")
+		mean, err1 := strconv.ParseFloat(m, 64)
+		stddev, err2 := strconv.ParseFloat(s, 64)
+		if !ok || err1 != nil || err2 != nil || mean < 0 || stddev < 0 {
+			return nil, invalid
+		}
+		return Normal(mean, stddev), nil
+	case "exp":
+		mean, err := strconv.ParseFloat(args, 64)
+		if err != nil || mean < 0 {
+			return nil, invalid
+		}
+		return Exponential(mean), nil
+	}
+	return nil, fmt.Errorf("unknown distribution %q, expected uniform, normal or exp", kind)
+}
+
+// Options of a generator
+type Options struct {
+	// Size is the distribution of the payload sizes, the rendered templates shorter than the size drawn are padded
+	// with spaces (valid after JSON or XML documents), the longer ones are left as they are. Without a template the
+	// payloads are random text of the size drawn. Nil does not pad.
+	Size Distribution
+	// Seed seeds the random source of the values and sizes, 0 seeds it with the current time
+	Seed int64
+}
+
+// Data is the data of the templates
+type Data struct {
+	// Seq is the sequence number of the payload, starting at 1
+	Seq int64
+	// Size is the size drawn for the payload, 0 when there is no size distribution
+	Size int
+}
+
+// Generator generates the payloads, safe for concurrent use
+type Generator struct {
+	mu       sync.Mutex
+	template *template.Template
+	random   *rand.Rand
+	size     Distribution
+	seq      int64
+	buffer   bytes.Buffer
+}
+
+// New parses the template, empty for random text payloads of the size drawn
+func New(text string, options Options) (*Generator, error) {
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g := &Generator{random: rand.New(rand.NewSource(seed)), size: options.Size}
+	if text != "" {
+		t, err := template.New("payload").Funcs(g.Funcs()).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		g.template = t
+	}
+	return g, nil
+}
+
+// Load returns the template of a command line flag, read from the file when it starts with @, e.g. @order.json.tmpl
+func Load(arg string) (string, error) {
+	if !strings.HasPrefix(arg, "@") {
+		return arg, nil
+	}
+	text, err := os.ReadFile(arg[1:])
+	return string(text), err
+}
+
+// Next generates the next payload
+func (g *Generator) Next() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.seq++
+	data := Data{Seq: g.seq}
+	if g.size != nil {
+		data.Size = g.size.Sample(g.random)
+	}
+	g.buffer.Reset()
+	if g.template == nil {
+		g.buffer.WriteString(g.text(data.Size))
+	} else if err := g.template.Execute(&g.buffer, data); err != nil {
+		return nil, err
+	}
+	for g.buffer.Len() < data.Size {
+		g.buffer.WriteByte(' ')
+	}
+	return append([]byte(nil), g.buffer.Bytes()...), nil
+}
+
+// Pool generates n payloads, e.g. ahead of a benchmark measuring the API rather than the generation
+func (g *Generator) Pool(n int) ([][]byte, error) {
+	pool := make([][]byte, n)
+	for i := range pool {
+		payload, err := g.Next()
+		if err != nil {
+			return nil, err
+		}
+		pool[i] = payload
+	}
+	return pool, nil
+}
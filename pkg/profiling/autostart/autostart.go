@@ -0,0 +1,69 @@
+// Package autostart starts a profiling harness configured from the environment when it is imported, so any sample
+// can be profiled without changing its code, see cmd/profile:
+//
+//	SOLACE_PPROF_ADDR           address of the pprof and expvar endpoints, localhost:6060 by default
+//	SOLACE_PPROF_HEAP_DIR       directory of the heap profiles, none are written when empty
+//	SOLACE_PPROF_HEAP_INTERVAL  period of the heap profiles, 1m by default
+//	SOLACE_PPROF_KEEP_HEAPS     number of heap profiles kept, all by default
+//	SOLACE_PPROF_GAUGE_INTERVAL period the gauges are sampled and reported on standard error, 10s by default
+//
+// Invalid settings are reported on standard error and replaced by the defaults, the sample runs without profiling
+// when the harness can not start.
+package autostart
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/profiling"
+)
+
+// Harness is the harness started by the import, nil when it could not start
+var Harness *profiling.Harness
+
+func init() {
+	options := profiling.Options{
+		Address:       "localhost:6060",
+		HeapDir:       os.Getenv("SOLACE_PPROF_HEAP_DIR"),
+		HeapInterval:  duration("SOLACE_PPROF_HEAP_INTERVAL"),
+		GaugeInterval: duration("SOLACE_PPROF_GAUGE_INTERVAL"),
+		Report:        os.Stderr,
+	}
+	if address, ok := os.LookupEnv("SOLACE_PPROF_ADDR"); ok {
+		options.Address = address
+	}
+	if keep := os.Getenv("SOLACE_PPROF_KEEP_HEAPS"); keep != "" {
+		n, err := strconv.Atoi(keep)
+		if err != nil || n < 0 {
+			fmt.Fprintf(os.Stderr, "SOLACE_PPROF_KEEP_HEAPS: invalid count '%s'\n", keep)
+		} else {
+			options.KeepHeaps = n
+		}
+	}
+
+	harness, err := profiling.Start(options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "profiling:", err)
+		return
+	}
+	Harness = harness
+	if options.Address != "" {
+		fmt.Fprintf(os.Stderr, "profiling: pprof on http://%s/debug/pprof/, gauges on http://%s/debug/vars\n", options.Address, options.Address)
+	}
+}
+
+// duration parses the duration in the variable, 0 (the default) when it is not set or invalid
+func duration(key string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		fmt.Fprintf(os.Stderr, "%s: invalid duration '%s'\n", key, value)
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,252 @@
+// Package profiling instruments a long running sample to investigate suspected leaks in its handler code under load:
+//
+//   - the net/http/pprof endpoints, on /debug/pprof/
+//   - goroutine and heap gauges, sampled every interval and published with expvar on /debug/vars, under profiling
+//   - heap profiles written to a directory every snapshot interval, to compare with go tool pprof -base
+//
+// Samples start it first thing in main, or get it without any change through cmd/profile and the autostart package:
+//
+//	harness, err := profiling.Start(profiling.Options{Address: "localhost:6060", HeapDir: "heap"})
+//	defer harness.Stop()
+//
+// then, while the sample runs:
+//
+//	go tool pprof -http : http://localhost:6060/debug/pprof/heap
+//	curl -s 'localhost:6060/debug/pprof/goroutine?debug=1' | head
+//	go tool pprof -base heap/heap-0001.pb.gz heap/heap-0010.pb.gz
+package profiling
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	rpprof "runtime/pprof"
+	"sync"
+	"time"
+)
+
+// Options configures a harness, the zero value samples the gauges every 10 seconds without serving nor writing anything
+type Options struct {
+	// Address the pprof and expvar endpoints are served at, e.g. localhost:6060, nothing is served when empty.
+	// The endpoints have no authentication, bind them to a loopback address.
+	Address string
+	// HeapDir is the directory the heap profiles are written to, created when missing, none are written when empty
+	HeapDir string
+	// HeapInterval is the period of the heap profiles, 1 minute by default
+	HeapInterval time.Duration
+	// KeepHeaps is the number of heap profiles kept in HeapDir: the first one, the base of the comparisons, and the
+	// latest ones. 0 keeps all of them.
+	KeepHeaps int
+	// GaugeInterval is the period the gauges are sampled, 10 seconds by default
+	GaugeInterval time.Duration
+	// Report, when set, receives a line with the gauges at every sample
+	Report io.Writer
+}
+
+// Gauges are the values sampled by the harness. HeapLive is the heap marked live by the last garbage collection,
+// without the garbage HeapAlloc includes, the steadier of the two to follow a leak.
+type Gauges struct {
+	Time          time.Time `json:"time"`
+	Goroutines    int       `json:"goroutines"`
+	MaxGoroutines int       `json:"max_goroutines"`
+	HeapAlloc     uint64    `json:"heap_alloc_bytes"`
+	HeapLive      uint64    `json:"heap_live_bytes"`
+	HeapObjects   uint64    `json:"heap_objects"`
+	NumGC         uint32    `json:"num_gc"`
+	HeapSnapshots int       `json:"heap_snapshots"`
+}
+
+// Harness samples the gauges and writes the heap profiles until it is stopped
+type Harness struct {
+	options Options
+	server  *http.Server
+	stop    chan struct{}
+	done    sync.WaitGroup
+
+	mu        sync.Mutex
+	gauges    Gauges
+	snapshots []string
+}
+
+// the expvar names are global, the gauges of the last started harness are published
+var (
+	publishOnce sync.Once
+	published   struct {
+		sync.Mutex
+		harness *Harness
+	}
+)
+
+// Start starts sampling the gauges, serving the endpoints and writing the heap profiles as configured. The listen error,
+// e.g. an address already in use, is returned right away.
+func Start(options Options) (*Harness, error) {
+	if options.HeapInterval <= 0 {
+		options.HeapInterval = time.Minute
+	}
+	if options.GaugeInterval <= 0 {
+		options.GaugeInterval = 10 * time.Second
+	}
+	if options.KeepHeaps == 1 {
+		options.KeepHeaps = 2
+	}
+	if options.HeapDir != "" {
+		if err := os.MkdirAll(options.HeapDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	h := &Harness{options: options, stop: make(chan struct{})}
+	h.sample()
+
+	if options.Address != "" {
+		listener, err := net.Listen("tcp", options.Address)
+		if err != nil {
+			return nil, err
+		}
+		h.server = &http.Server{Handler: Handler(), ReadHeaderTimeout: 10 * time.Second}
+		go h.server.Serve(listener)
+	}
+
+	publishOnce.Do(func() {
+		expvar.Publish("profiling", expvar.Func(func() interface{} {
+			published.Lock()
+			defer published.Unlock()
+			if published.harness == nil {
+				return nil
+			}
+			// sampled at every read, for the values of the time of the read
+			return published.harness.sample()
+		}))
+	})
+	published.Lock()
+	published.harness = h
+	published.Unlock()
+
+	h.done.Add(1)
+	go h.run()
+	return h, nil
+}
+
+// Handler returns the handler serving the pprof endpoints on /debug/pprof/ and expvar on /debug/vars
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+func (h *Harness) run() {
+	defer h.done.Done()
+	gaugeTicker := time.NewTicker(h.options.GaugeInterval)
+	defer gaugeTicker.Stop()
+	var heapTick <-chan time.Time
+	if h.options.HeapDir != "" {
+		heapTicker := time.NewTicker(h.options.HeapInterval)
+		defer heapTicker.Stop()
+		heapTick = heapTicker.C
+	}
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-gaugeTicker.C:
+			g := h.sample()
+			if h.options.Report != nil {
+				fmt.Fprintf(h.options.Report, "goroutines=%d (max %d) heap=%d bytes in %d objects gc=%d\n",
+					g.Goroutines, g.MaxGoroutines, g.HeapAlloc, g.HeapObjects, g.NumGC)
+			}
+		case <-heapTick:
+			if _, err := h.Snapshot(); err != nil && h.options.Report != nil {
+				fmt.Fprintln(h.options.Report, "heap snapshot failed:", err)
+			}
+		}
+	}
+}
+
+// sample reads the gauges from the runtime
+func (h *Harness) sample() Gauges {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	goroutines := runtime.NumGoroutine()
+	live := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(live)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gauges.Time = time.Now()
+	h.gauges.Goroutines = goroutines
+	if goroutines > h.gauges.MaxGoroutines {
+		h.gauges.MaxGoroutines = goroutines
+	}
+	h.gauges.HeapAlloc = stats.HeapAlloc
+	if live[0].Value.Kind() == metrics.KindUint64 {
+		h.gauges.HeapLive = live[0].Value.Uint64()
+	}
+	h.gauges.HeapObjects = stats.HeapObjects
+	h.gauges.NumGC = stats.NumGC
+	return h.gauges
+}
+
+// Gauges returns the last sampled gauges
+func (h *Harness) Gauges() Gauges {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.gauges
+}
+
+// Snapshot writes a heap profile to the heap directory after a garbage collection, so it only holds live objects,
+// and returns its path
+func (h *Harness) Snapshot() (string, error) {
+	if h.options.HeapDir == "" {
+		return "", fmt.Errorf("no heap directory configured")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	path := filepath.Join(h.options.HeapDir, fmt.Sprintf("heap-%04d.pb.gz", h.gauges.HeapSnapshots+1))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	runtime.GC()
+	if err := rpprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	h.gauges.HeapSnapshots++
+	h.snapshots = append(h.snapshots, path)
+	if h.options.KeepHeaps > 0 && len(h.snapshots) > h.options.KeepHeaps {
+		os.Remove(h.snapshots[1])
+		h.snapshots = append(h.snapshots[:1], h.snapshots[2:]...)
+	}
+	return path, nil
+}
+
+// Stop stops the harness, writing a last heap profile when a heap directory is configured
+func (h *Harness) Stop() error {
+	close(h.stop)
+	h.done.Wait()
+	var err error
+	if h.options.HeapDir != "" {
+		_, err = h.Snapshot()
+	}
+	if h.server != nil {
+		h.server.Close()
+	}
+	return err
+}
@@ -0,0 +1,268 @@
+// Package promexporter exposes the metrics of the samples in the Prometheus text format on a /metrics endpoint:
+//
+//   - the API metrics of the messaging services (messagingService.Metrics()), read at every scrape, as
+//     solace_api_<metric>_total{service="..."} counters, and whether each service is connected
+//   - the reconnection attempts, reconnections and interruptions of the services
+//   - for the receivers whose message handler is wrapped by Handler: the messages received, the messages being
+//     handled and the time spent in the handler. The API does not expose the fill level of the receiver buffers,
+//     a growing number of messages being handled and the solace_api_received_messages_backpressure_discarded_total
+//     counter are the signs of a receiver falling behind.
+//   - the settlement outcomes of the persistent messages acknowledged or settled with Ack and Settle
+//
+// Usage:
+//
+//	exporter := promexporter.New()
+//	exporter.AddService("receiver", messagingService)
+//	exporter.ListenAndServe(":2112")
+//	persistentReceiver.ReceiveAsync(exporter.Handler("orders", func(message message.InboundMessage) {
+//		...
+//		exporter.Ack("orders", persistentReceiver, message)
+//	}))
+package promexporter
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/metrics"
+)
+
+// Namespace prefixes the names of the metrics
+const Namespace = "solace"
+
+// apiMetrics names the API metrics, solace_api_<name>_total
+var apiMetrics = []struct {
+	metric metrics.Metric
+	name   string
+	help   string
+}{
+	{metrics.DirectBytesReceived, "direct_bytes_received", "Bytes of the direct messages received."},
+	{metrics.DirectMessagesReceived, "direct_messages_received", "Direct messages received."},
+	{metrics.BrokerDiscardNotificationsReceived, "broker_discard_notifications_received", "Messages received with the discard indication set by the broker."},
+	{metrics.UnknownParameterMessagesDiscarded, "unknown_parameter_messages_discarded", "Messages discarded for an unknown parameter."},
+	{metrics.TooBigMessagesDiscarded, "too_big_messages_discarded", "Messages discarded for exceeding the maximum size."},
+	{metrics.PersistentAcknowledgeSent, "persistent_acknowledge_sent", "Acknowledgements sent for persistent messages."},
+	{metrics.PersistentDuplicateMessagesDiscarded, "persistent_duplicate_messages_discarded", "Duplicate persistent messages discarded."},
+	{metrics.PersistentNoMatchingFlowMessagesDiscarded, "persistent_no_matching_flow_messages_discarded", "Persistent messages discarded for lack of a matching flow."},
+	{metrics.PersistentOutOfOrderMessagesDiscarded, "persistent_out_of_order_messages_discarded", "Persistent messages discarded for being out of order."},
+	{metrics.PersistentBytesReceived, "persistent_bytes_received", "Bytes of the persistent messages received."},
+	{metrics.PersistentMessagesReceived, "persistent_messages_received", "Persistent messages received."},
+	{metrics.ControlMessagesReceived, "control_messages_received", "Control messages received."},
+	{metrics.ControlBytesReceived, "control_bytes_received", "Bytes of the control messages received."},
+	{metrics.TotalBytesReceived, "total_bytes_received", "Bytes received."},
+	{metrics.TotalMessagesReceived, "total_messages_received", "Messages received."},
+	{metrics.CompressedBytesReceived, "compressed_bytes_received", "Bytes received over a compressed connection, before decompression."},
+	{metrics.PersistentBytesSent, "persistent_bytes_sent", "Bytes of the persistent messages sent."},
+	{metrics.PersistentMessagesSent, "persistent_messages_sent", "Persistent messages sent."},
+	{metrics.DirectBytesSent, "direct_bytes_sent", "Bytes of the direct messages sent."},
+	{metrics.DirectMessagesSent, "direct_messages_sent", "Direct messages sent."},
+	{metrics.ControlMessagesSent, "control_messages_sent", "Control messages sent."},
+	{metrics.ControlBytesSent, "control_bytes_sent", "Bytes of the control messages sent."},
+	{metrics.TotalBytesSent, "total_bytes_sent", "Bytes sent."},
+	{metrics.TotalMessagesSent, "total_messages_sent", "Messages sent."},
+	{metrics.ConnectionAttempts, "connection_attempts", "Connection attempts."},
+	{metrics.PublishedMessagesAcknowledged, "published_messages_acknowledged", "Published persistent messages acknowledged by the broker."},
+	{metrics.PublishMessagesDiscarded, "publish_messages_discarded", "Messages discarded by the publishers."},
+	{metrics.PublisherWouldBlock, "publisher_would_block", "Publishes that would have blocked, i.e. back pressure."},
+	{metrics.ReceivedMessagesTerminationDiscarded, "received_messages_termination_discarded", "Received messages discarded when terminating a receiver."},
+	{metrics.ReceivedMessagesBackpressureDiscarded, "received_messages_backpressure_discarded", "Received messages discarded because the receiver buffer was full."},
+	{metrics.PersistentMessagesRedelivered, "persistent_messages_redelivered", "Persistent messages redelivered after a settlement as failed."},
+	{metrics.PersistentMessagesAccepted, "persistent_messages_accepted", "Persistent messages settled as accepted."},
+	{metrics.PersistentMessagesFailed, "persistent_messages_failed", "Persistent messages settled as failed."},
+	{metrics.PersistentMessagesRejected, "persistent_messages_rejected", "Persistent messages settled as rejected."},
+}
+
+// Exporter holds the metrics of the samples, it is safe for concurrent use
+type Exporter struct {
+	registry *prometheus.Registry
+	services *serviceCollector
+
+	serviceEvents  *prometheus.CounterVec
+	received       *prometheus.CounterVec
+	inFlight       *prometheus.GaugeVec
+	handlerSeconds *prometheus.HistogramVec
+	settlements    *prometheus.CounterVec
+}
+
+// New creates an exporter with its own registry, holding the Go runtime and process metrics as well
+func New() *Exporter {
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+		services: &serviceCollector{services: map[string]solace.MessagingService{}},
+		serviceEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "service_events_total",
+			Help: "Reconnection attempts (reconnecting), reconnections (reconnected) and interruptions (interrupted) of the messaging services.",
+		}, []string{"service", "event"}),
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "receiver_messages_received_total",
+			Help: "Messages handed to the message handler of the receiver.",
+		}, []string{"receiver"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace, Name: "receiver_messages_in_handler",
+			Help: "Messages being handled by the message handler of the receiver.",
+		}, []string{"receiver"}),
+		handlerSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace, Name: "receiver_handler_seconds",
+			Help:    "Time spent in the message handler of the receiver.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"receiver"}),
+		settlements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "receiver_settlements_total",
+			Help: "Persistent messages settled by the receiver, by outcome, and whether the settlement succeeded.",
+		}, []string{"receiver", "outcome", "result"}),
+	}
+	e.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		e.services, e.serviceEvents, e.received, e.inFlight, e.handlerSeconds, e.settlements,
+	)
+	return e
+}
+
+// Registry returns the registry of the exporter, to register the metrics of the sample itself
+func (e *Exporter) Registry() *prometheus.Registry {
+	return e.registry
+}
+
+// AddService exports the API metrics and the reconnections of the messaging service under the service name.
+// Add the service before connecting it so the reconnections are counted from the start.
+func (e *Exporter) AddService(name string, messagingService solace.MessagingService) {
+	e.services.add(name, messagingService)
+	for _, event := range []string{"reconnecting", "reconnected", "interrupted"} {
+		e.serviceEvents.WithLabelValues(name, event)
+	}
+	messagingService.AddReconnectionAttemptListener(func(solace.ServiceEvent) {
+		e.serviceEvents.WithLabelValues(name, "reconnecting").Inc()
+	})
+	messagingService.AddReconnectionListener(func(solace.ServiceEvent) {
+		e.serviceEvents.WithLabelValues(name, "reconnected").Inc()
+	})
+	messagingService.AddServiceInterruptionListener(func(solace.ServiceEvent) {
+		e.serviceEvents.WithLabelValues(name, "interrupted").Inc()
+	})
+}
+
+// RemoveService stops exporting the API metrics of the service, e.g. once it is disconnected for good
+func (e *Exporter) RemoveService(name string) {
+	e.services.remove(name)
+}
+
+// Handler wraps the message handler of the named receiver to count the messages and time the handler
+func (e *Exporter) Handler(receiver string, handler solace.MessageHandler) solace.MessageHandler {
+	received := e.received.WithLabelValues(receiver)
+	inFlight := e.inFlight.WithLabelValues(receiver)
+	handlerSeconds := e.handlerSeconds.WithLabelValues(receiver)
+	return func(inbound message.InboundMessage) {
+		received.Inc()
+		inFlight.Inc()
+		start := time.Now()
+		defer func() {
+			handlerSeconds.Observe(time.Since(start).Seconds())
+			inFlight.Dec()
+		}()
+		handler(inbound)
+	}
+}
+
+// Ack acknowledges the message and counts it as an ACCEPTED settlement of the named receiver
+func (e *Exporter) Ack(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage) error {
+	err := persistentReceiver.Ack(inbound)
+	e.Settled(receiver, config.PersistentReceiverAcceptedOutcome, err)
+	return err
+}
+
+// Settle settles the message with the outcome and counts the settlement of the named receiver
+func (e *Exporter) Settle(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage, outcome config.MessageSettlementOutcome) error {
+	err := persistentReceiver.Settle(inbound, outcome)
+	e.Settled(receiver, outcome, err)
+	return err
+}
+
+// Settled counts a settlement made by the sample itself, err is the error returned by the settlement
+func (e *Exporter) Settled(receiver string, outcome config.MessageSettlementOutcome, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	e.settlements.WithLabelValues(receiver, string(outcome), result).Inc()
+}
+
+// HTTPHandler returns the handler serving the metrics in the Prometheus text format
+func (e *Exporter) HTTPHandler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{Registry: e.registry})
+}
+
+// ListenAndServe serves the metrics on /metrics at the address in the background. The listen error, e.g. an address
+// already in use, is returned right away; close the returned server to stop serving.
+func (e *Exporter) ListenAndServe(address string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e.HTTPHandler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return server, nil
+}
+
+// serviceCollector reads the API metrics of the services at every scrape
+type serviceCollector struct {
+	mu       sync.Mutex
+	services map[string]solace.MessagingService
+}
+
+var (
+	apiDescs      = make([]*prometheus.Desc, len(apiMetrics))
+	connectedDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "service", "connected"),
+		"Whether the messaging service is connected.", []string{"service"}, nil)
+)
+
+func init() {
+	for i, m := range apiMetrics {
+		apiDescs[i] = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "api", m.name+"_total"), m.help, []string{"service"}, nil)
+	}
+}
+
+func (c *serviceCollector) add(name string, messagingService solace.MessagingService) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.services[name] = messagingService
+}
+
+func (c *serviceCollector) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.services, name)
+}
+
+func (c *serviceCollector) Describe(descs chan<- *prometheus.Desc) {
+	for _, desc := range apiDescs {
+		descs <- desc
+	}
+	descs <- connectedDesc
+}
+
+func (c *serviceCollector) Collect(values chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, messagingService := range c.services {
+		serviceMetrics := messagingService.Metrics()
+		for i, m := range apiMetrics {
+			values <- prometheus.MustNewConstMetric(apiDescs[i], prometheus.CounterValue, float64(serviceMetrics.GetValue(m.metric)), name)
+		}
+		connected := 0.0
+		if messagingService.IsConnected() {
+			connected = 1
+		}
+		values <- prometheus.MustNewConstMetric(connectedDesc, prometheus.GaugeValue, connected, name)
+	}
+}
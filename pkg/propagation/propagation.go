@@ -0,0 +1,172 @@
+// Package propagation carries the context of a flow of messages across the applications it goes through, in the user
+// properties of the messages: a correlation ID shared by all the messages of a business transaction, and the
+// OpenTelemetry trace context and baggage. The keys are stable, so that applications written with other APIs or in
+// other languages can read and write them:
+//
+//	correlation-id  the correlation ID, see KeyCorrelationID
+//	traceparent     the W3C trace context of the span of the sender
+//	tracestate      the vendor specific part of the W3C trace context
+//	baggage         the W3C baggage, key=value pairs forwarded along the flow
+//
+// The trace context keys are the W3C ones, set by the TraceContext and Baggage propagators of OpenTelemetry, so the
+// properties can also be read by any W3C aware consumer. The Solace trace context of solace.dev/go/messaging-trace,
+// set in the message headers rather than in the user properties, is separate.
+//
+// A publisher injects the context into the properties of the messages it builds, a receiver extracts it from the
+// messages it receives, and a processor or a replier does both to pass it on:
+//
+//	ctx := propagation.WithCorrelationID(context.Background(), propagation.NewCorrelationID())
+//	outbound, err := messageBuilder.FromConfigurationProvider(propagation.Properties(ctx)).BuildWithStringPayload(body)
+//	...
+//	ctx := propagation.Extract(context.Background(), inbound)
+//	id, _ := propagation.CorrelationID(ctx)
+//
+// The correlation ID is unrelated to the correlation ID header of the messages (config.MessagePropertyCorrelationID),
+// used by the request-reply publishers to match the replies with their request.
+package propagation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/baggage"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// The user properties set by Inject
+const (
+	KeyCorrelationID = "correlation-id"
+	KeyTraceParent   = "traceparent"
+	KeyTraceState    = "tracestate"
+	KeyBaggage       = "baggage"
+)
+
+// Propagator injects and extracts the trace context and the baggage, whatever propagator is registered globally with
+// otel.SetTextMapPropagator, so the key scheme does not depend on the setup of the application
+var Propagator otelpropagation.TextMapPropagator = otelpropagation.NewCompositeTextMapPropagator(
+	otelpropagation.TraceContext{}, otelpropagation.Baggage{})
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying the correlation ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by the context, false when there is none
+func CorrelationID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// NewCorrelationID returns a random correlation ID, 32 hexadecimal characters
+func NewCorrelationID() string {
+	var id [16]byte
+	// crypto/rand does not fail on the supported platforms
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// EnsureCorrelationID returns the context with a new correlation ID when it does not carry one yet, e.g. at the start
+// of a flow
+func EnsureCorrelationID(ctx context.Context) context.Context {
+	if _, ok := CorrelationID(ctx); ok {
+		return ctx
+	}
+	return WithCorrelationID(ctx, NewCorrelationID())
+}
+
+// WithBaggage returns a context whose baggage holds the member, in addition to the members it already holds. The key
+// and the value must be valid W3C baggage, the value is percent-encoded as needed.
+func WithBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return ctx, err
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, err
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
+// PropertiesCarrier adapts the properties of an outbound message to the OpenTelemetry propagators
+type PropertiesCarrier config.MessagePropertyMap
+
+// Get returns the property as a string, empty when it is not set
+func (c PropertiesCarrier) Get(key string) string {
+	value, _ := c[config.MessageProperty(key)].(string)
+	return value
+}
+
+// Set sets the property
+func (c PropertiesCarrier) Set(key, value string) {
+	c[config.MessageProperty(key)] = value
+}
+
+// Keys returns the names of the properties
+func (c PropertiesCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, string(key))
+	}
+	return keys
+}
+
+// InboundCarrier adapts the user properties of a received message to the OpenTelemetry propagators
+type InboundCarrier struct {
+	Message message.InboundMessage
+}
+
+// Get returns the user property as a string, empty when it is not set
+func (c InboundCarrier) Get(key string) string {
+	if value, ok := c.Message.GetProperty(key); ok {
+		if s, ok := value.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Set does nothing, the properties of a received message can not be changed
+func (c InboundCarrier) Set(key, value string) {}
+
+// Keys returns the names of the user properties
+func (c InboundCarrier) Keys() []string {
+	properties := c.Message.GetProperties()
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Inject sets the correlation ID, the trace context and the baggage carried by the context in the properties, the
+// ones the context does not carry are left as they are
+func Inject(ctx context.Context, properties config.MessagePropertyMap) {
+	if id, ok := CorrelationID(ctx); ok {
+		properties[KeyCorrelationID] = id
+	}
+	Propagator.Inject(ctx, PropertiesCarrier(properties))
+}
+
+// Properties returns new properties holding the context, to build an outbound message with
+// FromConfigurationProvider or to pass to a publish call
+func Properties(ctx context.Context) config.MessagePropertyMap {
+	properties := config.MessagePropertyMap{}
+	Inject(ctx, properties)
+	return properties
+}
+
+// Extract returns the context with the correlation ID, the trace context and the baggage of the received message. The
+// trace context is the remote parent of the spans started with the returned context.
+func Extract(ctx context.Context, inbound message.InboundMessage) context.Context {
+	carrier := InboundCarrier{Message: inbound}
+	if id := carrier.Get(KeyCorrelationID); id != "" {
+		ctx = WithCorrelationID(ctx, id)
+	}
+	return Propagator.Extract(ctx, carrier)
+}
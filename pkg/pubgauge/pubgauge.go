@@ -0,0 +1,363 @@
+// Package pubgauge shows the back pressure building up in the publishers before their publish calls start failing or
+// blocking. The API does not expose the fill level of the publish buffers, the wrappers of this package track it from
+// what goes through them:
+//
+//   - whether the publisher is ready to publish, IsReady, and how long it was not ready since it started
+//   - the publish calls rejected with a PublisherOverflowError (back pressure reject), and the readiness events
+//     telling the publisher is ready again
+//   - the publish calls in progress, above 0 for long when the publish calls block (back pressure wait)
+//   - for the persistent publishers, the messages published and not acknowledged yet, i.e. waiting in the publish
+//     buffer or for the acknowledgement of the broker, and their ratio to the capacity of the buffer
+//
+// Wrap the publishers with the capacity given to OnBackPressureReject or OnBackPressureWait, and publish through the
+// wrappers:
+//
+//	gauge := pubgauge.New()
+//	exporter.Registry().MustRegister(gauge)
+//	publisher := gauge.Persistent("orders", persistentPublisher, 1000)
+//	publisher.SetPublisherReadinessListener(func() { ... })
+//	publisher.Publish(message, topic, nil, nil)
+//
+// The gauge is a Prometheus collector, the metrics are read at every scrape: solace_publisher_ready,
+// solace_publisher_buffer_capacity, solace_publisher_publish_calls_in_progress, solace_publisher_unacknowledged,
+// solace_publisher_buffer_utilization, solace_publisher_overflows_total, solace_publisher_readiness_events_total and
+// solace_publisher_not_ready_seconds_total, by publisher. Stats returns the same values for the samples to print.
+package pubgauge
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// Namespace prefixes the names of the metrics, as in pkg/promexporter
+const Namespace = "solace"
+
+// Stats are the values of the gauge for a publisher
+type Stats struct {
+	Publisher string
+	// Persistent is false for a direct publisher, whose messages are not acknowledged
+	Persistent bool
+	Ready      bool
+	// Capacity is the capacity of the publish buffer given when wrapping the publisher
+	Capacity int
+	// InProgress is the number of publish calls in progress
+	InProgress int64
+	// Unacknowledged is the number of persistent messages published and not acknowledged yet
+	Unacknowledged int64
+	// Utilization is Unacknowledged divided by Capacity, it may go above 1 as the messages sent to the broker and not
+	// acknowledged yet are no longer in the buffer
+	Utilization float64
+	// Overflows is the number of publish calls rejected with a PublisherOverflowError
+	Overflows uint64
+	// ReadinessEvents is the number of times the publisher told it was ready again after an overflow
+	ReadinessEvents uint64
+	// NotReady is the time spent not ready, from an overflow to the next readiness event
+	NotReady time.Duration
+}
+
+// readyPublisher is a direct or a persistent publisher, with its readiness calls
+type readyPublisher interface {
+	solace.MessagePublisher
+	solace.MessagePublisherHealthCheck
+}
+
+// state is the tracking of a publisher
+type state struct {
+	name       string
+	publisher  readyPublisher
+	persistent bool
+	capacity   int
+
+	inProgress     int64
+	unacknowledged int64
+
+	mu              sync.Mutex
+	overflows       uint64
+	readinessEvents uint64
+	notReadySince   time.Time
+	notReady        time.Duration
+	listener        solace.PublisherReadinessListener
+	receiptListener solace.MessagePublishReceiptListener
+}
+
+// Gauge tracks the publishers wrapped by it, it is safe for concurrent use
+type Gauge struct {
+	mu         sync.Mutex
+	publishers map[string]*state
+}
+
+// New returns a gauge tracking no publisher
+func New() *Gauge {
+	return &Gauge{publishers: map[string]*state{}}
+}
+
+func (g *Gauge) add(name string, publisher readyPublisher, persistent bool, capacity int) *state {
+	s := &state{name: name, publisher: publisher, persistent: persistent, capacity: capacity}
+	publisher.SetPublisherReadinessListener(s.ready)
+	g.mu.Lock()
+	g.publishers[name] = s
+	g.mu.Unlock()
+	return s
+}
+
+// Remove stops tracking the named publisher, e.g. once it is terminated
+func (g *Gauge) Remove(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.publishers, name)
+}
+
+// Stats returns the values of the gauge for every publisher, by name
+func (g *Gauge) Stats() []Stats {
+	g.mu.Lock()
+	publishers := make([]*state, 0, len(g.publishers))
+	for _, s := range g.publishers {
+		publishers = append(publishers, s)
+	}
+	g.mu.Unlock()
+	stats := make([]Stats, len(publishers))
+	for i, s := range publishers {
+		stats[i] = s.stats()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Publisher < stats[j].Publisher })
+	return stats
+}
+
+func (s *state) stats() Stats {
+	stats := Stats{
+		Publisher:      s.name,
+		Persistent:     s.persistent,
+		Ready:          s.publisher.IsReady(),
+		Capacity:       s.capacity,
+		InProgress:     atomic.LoadInt64(&s.inProgress),
+		Unacknowledged: atomic.LoadInt64(&s.unacknowledged),
+	}
+	if s.capacity > 0 {
+		stats.Utilization = float64(stats.Unacknowledged) / float64(s.capacity)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.Overflows = s.overflows
+	stats.ReadinessEvents = s.readinessEvents
+	stats.NotReady = s.notReady
+	if !s.notReadySince.IsZero() {
+		stats.NotReady += time.Since(s.notReadySince)
+	}
+	return stats
+}
+
+// publish tracks a publish call, counting it as unacknowledged when it succeeds and awaits a receipt
+func (s *state) publish(awaitsReceipt bool, publish func() error) error {
+	atomic.AddInt64(&s.inProgress, 1)
+	if awaitsReceipt {
+		// counted before publishing, the receipt may come before the publish call returns
+		atomic.AddInt64(&s.unacknowledged, 1)
+	}
+	err := publish()
+	atomic.AddInt64(&s.inProgress, -1)
+	if err != nil {
+		if awaitsReceipt {
+			atomic.AddInt64(&s.unacknowledged, -1)
+		}
+		var overflow *solace.PublisherOverflowError
+		if errors.As(err, &overflow) {
+			s.mu.Lock()
+			s.overflows++
+			if s.notReadySince.IsZero() {
+				s.notReadySince = time.Now()
+			}
+			s.mu.Unlock()
+		}
+	}
+	return err
+}
+
+// ready is the readiness listener of the publisher, it calls the listener set on the wrapper
+func (s *state) ready() {
+	s.mu.Lock()
+	s.readinessEvents++
+	if !s.notReadySince.IsZero() {
+		s.notReady += time.Since(s.notReadySince)
+		s.notReadySince = time.Time{}
+	}
+	listener := s.listener
+	s.mu.Unlock()
+	if listener != nil {
+		listener()
+	}
+}
+
+// receipt is the publish receipt listener of a persistent publisher, it calls the listener set on the wrapper
+func (s *state) receipt(receipt solace.PublishReceipt) {
+	atomic.AddInt64(&s.unacknowledged, -1)
+	s.mu.Lock()
+	listener := s.receiptListener
+	s.mu.Unlock()
+	if listener != nil {
+		listener(receipt)
+	}
+}
+
+// DirectPublisher is a direct publisher tracked by a gauge
+type DirectPublisher struct {
+	solace.DirectMessagePublisher
+	state *state
+}
+
+// Direct tracks the direct publisher under the name, capacity is its publish buffer capacity
+func (g *Gauge) Direct(name string, publisher solace.DirectMessagePublisher, capacity int) *DirectPublisher {
+	return &DirectPublisher{DirectMessagePublisher: publisher, state: g.add(name, publisher, false, capacity)}
+}
+
+// SetPublisherReadinessListener sets the listener called when the publisher is ready again after an overflow
+func (p *DirectPublisher) SetPublisherReadinessListener(listener solace.PublisherReadinessListener) {
+	p.state.mu.Lock()
+	defer p.state.mu.Unlock()
+	p.state.listener = listener
+}
+
+// PublishBytes publishes the bytes
+func (p *DirectPublisher) PublishBytes(message []byte, destination *resource.Topic) error {
+	return p.state.publish(false, func() error {
+		return p.DirectMessagePublisher.PublishBytes(message, destination)
+	})
+}
+
+// PublishString publishes the string
+func (p *DirectPublisher) PublishString(message string, destination *resource.Topic) error {
+	return p.state.publish(false, func() error {
+		return p.DirectMessagePublisher.PublishString(message, destination)
+	})
+}
+
+// Publish publishes the message
+func (p *DirectPublisher) Publish(message message.OutboundMessage, destination *resource.Topic) error {
+	return p.state.publish(false, func() error {
+		return p.DirectMessagePublisher.Publish(message, destination)
+	})
+}
+
+// PublishWithProperties publishes the message with the properties
+func (p *DirectPublisher) PublishWithProperties(message message.OutboundMessage, destination *resource.Topic,
+	properties config.MessagePropertiesConfigurationProvider) error {
+	return p.state.publish(false, func() error {
+		return p.DirectMessagePublisher.PublishWithProperties(message, destination, properties)
+	})
+}
+
+// PersistentPublisher is a persistent publisher tracked by a gauge
+type PersistentPublisher struct {
+	solace.PersistentMessagePublisher
+	state *state
+}
+
+// Persistent tracks the persistent publisher under the name, capacity is its publish buffer capacity. The wrapper
+// sets the publish receipt listener of the publisher to count the acknowledgements, set yours on the wrapper.
+func (g *Gauge) Persistent(name string, publisher solace.PersistentMessagePublisher, capacity int) *PersistentPublisher {
+	s := g.add(name, publisher, true, capacity)
+	publisher.SetMessagePublishReceiptListener(s.receipt)
+	return &PersistentPublisher{PersistentMessagePublisher: publisher, state: s}
+}
+
+// SetPublisherReadinessListener sets the listener called when the publisher is ready again after an overflow
+func (p *PersistentPublisher) SetPublisherReadinessListener(listener solace.PublisherReadinessListener) {
+	p.state.mu.Lock()
+	defer p.state.mu.Unlock()
+	p.state.listener = listener
+}
+
+// SetMessagePublishReceiptListener sets the listener called with the publish receipts
+func (p *PersistentPublisher) SetMessagePublishReceiptListener(listener solace.MessagePublishReceiptListener) {
+	p.state.mu.Lock()
+	defer p.state.mu.Unlock()
+	p.state.receiptListener = listener
+}
+
+// PublishBytes publishes the bytes
+func (p *PersistentPublisher) PublishBytes(message []byte, destination *resource.Topic) error {
+	return p.state.publish(true, func() error {
+		return p.PersistentMessagePublisher.PublishBytes(message, destination)
+	})
+}
+
+// PublishString publishes the string
+func (p *PersistentPublisher) PublishString(message string, destination *resource.Topic) error {
+	return p.state.publish(true, func() error {
+		return p.PersistentMessagePublisher.PublishString(message, destination)
+	})
+}
+
+// Publish publishes the message, the context is passed on to its publish receipt
+func (p *PersistentPublisher) Publish(message message.OutboundMessage, destination *resource.Topic,
+	properties config.MessagePropertiesConfigurationProvider, context interface{}) error {
+	return p.state.publish(true, func() error {
+		return p.PersistentMessagePublisher.Publish(message, destination, properties, context)
+	})
+}
+
+// PublishAwaitAcknowledgement publishes the message and waits for its acknowledgement, the message is unacknowledged
+// until the call returns as it gets no publish receipt
+func (p *PersistentPublisher) PublishAwaitAcknowledgement(message message.OutboundMessage, destination *resource.Topic,
+	timeout time.Duration, properties config.MessagePropertiesConfigurationProvider) error {
+	atomic.AddInt64(&p.state.unacknowledged, 1)
+	defer atomic.AddInt64(&p.state.unacknowledged, -1)
+	return p.state.publish(false, func() error {
+		return p.PersistentMessagePublisher.PublishAwaitAcknowledgement(message, destination, timeout, properties)
+	})
+}
+
+var (
+	readyDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "ready"),
+		"Whether the publisher is ready to publish.", []string{"publisher"}, nil)
+	capacityDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "buffer_capacity"),
+		"Capacity of the publish buffer of the publisher.", []string{"publisher"}, nil)
+	inProgressDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "publish_calls_in_progress"),
+		"Publish calls in progress, blocked when the buffer is full with back pressure wait.", []string{"publisher"}, nil)
+	unacknowledgedDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "unacknowledged"),
+		"Persistent messages published and not acknowledged by the broker yet.", []string{"publisher"}, nil)
+	utilizationDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "buffer_utilization"),
+		"Persistent messages not acknowledged yet divided by the capacity of the publish buffer.", []string{"publisher"}, nil)
+	overflowsDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "overflows_total"),
+		"Publish calls rejected because the publish buffer was full.", []string{"publisher"}, nil)
+	readinessEventsDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "readiness_events_total"),
+		"Times the publisher was ready again after an overflow.", []string{"publisher"}, nil)
+	notReadyDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "publisher", "not_ready_seconds_total"),
+		"Time spent not ready, from an overflow to the next readiness event.", []string{"publisher"}, nil)
+)
+
+// Describe implements prometheus.Collector
+func (g *Gauge) Describe(descs chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{readyDesc, capacityDesc, inProgressDesc, unacknowledgedDesc, utilizationDesc,
+		overflowsDesc, readinessEventsDesc, notReadyDesc} {
+		descs <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (g *Gauge) Collect(values chan<- prometheus.Metric) {
+	for _, stats := range g.Stats() {
+		ready := 0.0
+		if stats.Ready {
+			ready = 1
+		}
+		values <- prometheus.MustNewConstMetric(readyDesc, prometheus.GaugeValue, ready, stats.Publisher)
+		values <- prometheus.MustNewConstMetric(capacityDesc, prometheus.GaugeValue, float64(stats.Capacity), stats.Publisher)
+		values <- prometheus.MustNewConstMetric(inProgressDesc, prometheus.GaugeValue, float64(stats.InProgress), stats.Publisher)
+		if stats.Persistent {
+			values <- prometheus.MustNewConstMetric(unacknowledgedDesc, prometheus.GaugeValue, float64(stats.Unacknowledged), stats.Publisher)
+			values <- prometheus.MustNewConstMetric(utilizationDesc, prometheus.GaugeValue, stats.Utilization, stats.Publisher)
+		}
+		values <- prometheus.MustNewConstMetric(overflowsDesc, prometheus.CounterValue, float64(stats.Overflows), stats.Publisher)
+		values <- prometheus.MustNewConstMetric(readinessEventsDesc, prometheus.CounterValue, float64(stats.ReadinessEvents), stats.Publisher)
+		values <- prometheus.MustNewConstMetric(notReadyDesc, prometheus.CounterValue, stats.NotReady.Seconds(), stats.Publisher)
+	}
+}
@@ -0,0 +1,338 @@
+// Package queuelag watches the backlog of queues through the SEMP v2 monitor API of the broker: it polls the number
+// of messages spooled on every queue, measures the rate they are processed at, and computes the lag, the time the
+// consumers need to drain the backlog at that rate. The lag is exposed as Prometheus metrics, and a callback is
+// called when a queue crosses the thresholds, and again when it recovers:
+//
+//	watcher := queuelag.New(queuelag.SEMPConfig{URL: "http://localhost:8080", VPN: "default", Username: "admin", Password: "admin"},
+//		queuelag.Thresholds{MaxLag: time.Minute}, func(event queuelag.Event) { ... })
+//	watcher.AddQueue("orders")
+//	exporter.Registry().MustRegister(watcher)
+//	go watcher.Run(ctx, 5*time.Second)
+//	persistentReceiver.ReceiveAsync(watcher.Handler("orders", MessageHandler))
+//
+// The processing rate is counted by the watcher when the message handler of the queue is wrapped by Handler (or
+// Processed is called), it is otherwise the output rate of the queue reported by the broker.
+package queuelag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// SEMPConfig locates the SEMP v2 API of the broker
+type SEMPConfig struct {
+	// URL of the broker management service, e.g. http://localhost:8080
+	URL string
+	// VPN is the message VPN of the queues
+	VPN string
+	// Username and Password of a management user allowed to read the queues of the VPN
+	Username string
+	Password string
+	// Client sends the requests, an http.Client with a 10 seconds timeout when nil
+	Client *http.Client
+}
+
+// Thresholds are crossed when any of their non zero limits is exceeded
+type Thresholds struct {
+	// MaxSpooled is the number of messages spooled on the queue
+	MaxSpooled int64
+	// MaxLag is the time needed to drain the queue at the processing rate, a queue not processed at all while messages
+	// are spooled exceeds any lag
+	MaxLag time.Duration
+}
+
+// Sample is the state of a queue at a poll
+type Sample struct {
+	Queue string
+	Time  time.Time
+	// Spooled is the number of messages spooled on the queue
+	Spooled int64
+	// Consumers is the number of consumers bound to the queue
+	Consumers int64
+	// InputRate and OutputRate are the rates reported by the broker, in messages per second
+	InputRate  float64
+	OutputRate float64
+	// ProcessingRate is the rate the lag is computed with, in messages per second: counted by the watcher since the
+	// previous poll when the handler reports the processed messages, the output rate otherwise
+	ProcessingRate float64
+	// Lag is the time needed to drain the queue at the processing rate, only valid when Stalled is false
+	Lag time.Duration
+	// Stalled is true when messages are spooled but none are processed
+	Stalled bool
+}
+
+// Event is passed to the callback when a queue crosses the thresholds, Exceeded, and when it is back within them
+type Event struct {
+	Sample
+	Exceeded bool
+}
+
+// Watcher polls the queues and keeps their last sample, it is safe for concurrent use
+type Watcher struct {
+	semp       SEMPConfig
+	thresholds Thresholds
+	callback   func(Event)
+
+	mu     sync.Mutex
+	queues map[string]*queue
+
+	spooledDesc, lagDesc, rateDesc, exceededDesc, errorsDesc *prometheus.Desc
+}
+
+type queue struct {
+	// processed by the handler, reset at every poll
+	processed int64
+	// reported is true once the handler reported a processed message
+	reported atomic.Bool
+	last     Sample
+	polled   bool
+	exceeded bool
+	errors   uint64
+}
+
+// New returns a watcher polling the SEMP API, callback (which may be nil) is called from the polling goroutine
+func New(semp SEMPConfig, thresholds Thresholds, callback func(Event)) *Watcher {
+	if semp.Client == nil {
+		semp.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	labels := []string{"queue"}
+	return &Watcher{
+		semp:       semp,
+		thresholds: thresholds,
+		callback:   callback,
+		queues:     map[string]*queue{},
+		spooledDesc: prometheus.NewDesc("solace_queue_spooled_messages",
+			"Messages spooled on the queue.", labels, nil),
+		lagDesc: prometheus.NewDesc("solace_queue_lag_seconds",
+			"Time needed to drain the queue at the processing rate, +Inf when the queue is not processed.", labels, nil),
+		rateDesc: prometheus.NewDesc("solace_queue_processing_rate",
+			"Messages processed per second.", labels, nil),
+		exceededDesc: prometheus.NewDesc("solace_queue_lag_threshold_exceeded",
+			"Whether the queue exceeds the thresholds, 1, or not, 0.", labels, nil),
+		errorsDesc: prometheus.NewDesc("solace_queue_poll_errors_total",
+			"Failed polls of the queue.", labels, nil),
+	}
+}
+
+// AddQueue starts watching the queue
+func (w *Watcher) AddQueue(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.queues[name]; !ok {
+		w.queues[name] = &queue{}
+	}
+}
+
+// Processed counts messages of the queue as processed
+func (w *Watcher) Processed(name string, n int64) {
+	w.mu.Lock()
+	q, ok := w.queues[name]
+	w.mu.Unlock()
+	if ok {
+		atomic.AddInt64(&q.processed, n)
+		q.reported.Store(true)
+	}
+}
+
+// Handler wraps the message handler of the queue to count the processed messages
+func (w *Watcher) Handler(name string, handler solace.MessageHandler) solace.MessageHandler {
+	return func(inbound message.InboundMessage) {
+		handler(inbound)
+		w.Processed(name, 1)
+	}
+}
+
+// Run polls the queues every interval until the context is done
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll polls every queue once, calls the callback for the queues crossing the thresholds, and returns the first error
+func (w *Watcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	names := make([]string, 0, len(w.queues))
+	for name := range w.queues {
+		names = append(names, name)
+	}
+	w.mu.Unlock()
+
+	var firstErr error
+	for _, name := range names {
+		if err := w.pollQueue(ctx, name); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (w *Watcher) pollQueue(ctx context.Context, name string) error {
+	w.mu.Lock()
+	q := w.queues[name]
+	w.mu.Unlock()
+
+	state, err := w.fetch(ctx, name)
+	now := time.Now()
+	if err != nil {
+		w.mu.Lock()
+		q.errors++
+		w.mu.Unlock()
+		return err
+	}
+
+	sample := Sample{
+		Queue:          name,
+		Time:           now,
+		Spooled:        state.SpooledMsgCount,
+		Consumers:      state.BindCount,
+		InputRate:      float64(state.RxMsgRate),
+		OutputRate:     float64(state.TxMsgRate),
+		ProcessingRate: float64(state.TxMsgRate),
+	}
+	processed := atomic.SwapInt64(&q.processed, 0)
+
+	w.mu.Lock()
+	if q.reported.Load() && q.polled {
+		if elapsed := now.Sub(q.last.Time).Seconds(); elapsed > 0 {
+			sample.ProcessingRate = float64(processed) / elapsed
+		}
+	}
+	if sample.Spooled > 0 {
+		if sample.ProcessingRate > 0 {
+			sample.Lag = time.Duration(float64(sample.Spooled) / sample.ProcessingRate * float64(time.Second))
+		} else {
+			sample.Stalled = true
+		}
+	}
+	exceeded := w.exceeds(sample)
+	crossed := exceeded != q.exceeded
+	q.last, q.polled, q.exceeded = sample, true, exceeded
+	w.mu.Unlock()
+
+	if crossed && w.callback != nil {
+		w.callback(Event{Sample: sample, Exceeded: exceeded})
+	}
+	return nil
+}
+
+func (w *Watcher) exceeds(sample Sample) bool {
+	if w.thresholds.MaxSpooled > 0 && sample.Spooled > w.thresholds.MaxSpooled {
+		return true
+	}
+	if w.thresholds.MaxLag > 0 && (sample.Stalled || sample.Lag > w.thresholds.MaxLag) {
+		return true
+	}
+	return false
+}
+
+// Sample returns the last sample of the queue, false before the first successful poll
+func (w *Watcher) Sample(name string) (Sample, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	q, ok := w.queues[name]
+	if !ok || !q.polled {
+		return Sample{}, false
+	}
+	return q.last, true
+}
+
+// queueState holds the fields of the SEMP v2 monitor queue object read by the watcher
+type queueState struct {
+	SpooledMsgCount int64 `json:"spooledMsgCount"`
+	BindCount       int64 `json:"bindCount"`
+	RxMsgRate       int64 `json:"rxMsgRate"`
+	TxMsgRate       int64 `json:"txMsgRate"`
+}
+
+// sempResponse is the envelope of the SEMP v2 responses
+type sempResponse struct {
+	Data queueState `json:"data"`
+	Meta struct {
+		ResponseCode int `json:"responseCode"`
+		Error        *struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+	} `json:"meta"`
+}
+
+func (w *Watcher) fetch(ctx context.Context, name string) (queueState, error) {
+	endpoint := fmt.Sprintf("%s/SEMP/v2/monitor/msgVpns/%s/queues/%s?select=spooledMsgCount,bindCount,rxMsgRate,txMsgRate",
+		w.semp.URL, url.PathEscape(w.semp.VPN), url.PathEscape(name))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return queueState{}, err
+	}
+	request.SetBasicAuth(w.semp.Username, w.semp.Password)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := w.semp.Client.Do(request)
+	if err != nil {
+		return queueState{}, err
+	}
+	defer response.Body.Close()
+
+	var body sempResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return queueState{}, fmt.Errorf("queue '%s': %s, invalid SEMP response: %w", name, response.Status, err)
+	}
+	if body.Meta.Error != nil {
+		return queueState{}, fmt.Errorf("queue '%s': %s: %s", name, body.Meta.Error.Status, body.Meta.Error.Description)
+	}
+	if response.StatusCode != http.StatusOK {
+		return queueState{}, fmt.Errorf("queue '%s': %s", name, response.Status)
+	}
+	return body.Data, nil
+}
+
+// Describe implements prometheus.Collector
+func (w *Watcher) Describe(descs chan<- *prometheus.Desc) {
+	descs <- w.spooledDesc
+	descs <- w.lagDesc
+	descs <- w.rateDesc
+	descs <- w.exceededDesc
+	descs <- w.errorsDesc
+}
+
+// Collect implements prometheus.Collector, with the last sample of every queue
+func (w *Watcher) Collect(values chan<- prometheus.Metric) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for name, q := range w.queues {
+		values <- prometheus.MustNewConstMetric(w.errorsDesc, prometheus.CounterValue, float64(q.errors), name)
+		if !q.polled {
+			continue
+		}
+		lag := q.last.Lag.Seconds()
+		if q.last.Stalled {
+			lag = math.Inf(1)
+		}
+		exceeded := 0.0
+		if q.exceeded {
+			exceeded = 1
+		}
+		values <- prometheus.MustNewConstMetric(w.spooledDesc, prometheus.GaugeValue, float64(q.last.Spooled), name)
+		values <- prometheus.MustNewConstMetric(w.lagDesc, prometheus.GaugeValue, lag, name)
+		values <- prometheus.MustNewConstMetric(w.rateDesc, prometheus.GaugeValue, q.last.ProcessingRate, name)
+		values <- prometheus.MustNewConstMetric(w.exceededDesc, prometheus.GaugeValue, exceeded, name)
+	}
+}
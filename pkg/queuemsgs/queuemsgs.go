@@ -0,0 +1,162 @@
+// Package queuemsgs lists the messages spooled on a queue through the SEMP v2 monitor API of the broker, and deletes
+// them through the SEMP v2 action API. Nothing binds to the queue: the messages listed stay on the queue, they are
+// neither delivered nor flagged redelivered, whatever the consumers bound to it.
+//
+//	pager := queuemsgs.NewPager(queuelag.SEMPConfig{URL: "http://localhost:8080", VPN: "default", Username: "admin", Password: "admin"},
+//		"#DEAD_MSG_QUEUE", 20)
+//	for !pager.Exhausted() {
+//		messages, err := pager.Next(ctx)
+//		...
+//	}
+//	err := queuemsgs.Delete(ctx, semp, "#DEAD_MSG_QUEUE", messages[0].MsgID)
+//
+// SEMP only exposes the metadata of the messages, not their payload, topic nor user properties. The management user
+// needs read access to the VPN to list them, and read-write access to delete them.
+package queuemsgs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+)
+
+// Message is the metadata of a message spooled on a queue
+type Message struct {
+	// MsgID identifies the message on the queue, in spool order
+	MsgID int64 `json:"msgId"`
+	// ReplicationGroupMsgID is the replication group message ID of the message, the one of the inbound message
+	ReplicationGroupMsgID string `json:"replicationGroupMsgId,omitempty"`
+	PublisherID           int64  `json:"publisherId"`
+	// SpooledTime and ExpiryTime are Unix times in seconds, ExpiryTime is 0 when the message does not expire
+	SpooledTime     int64 `json:"spooledTime"`
+	ExpiryTime      int64 `json:"expiryTime,omitempty"`
+	ContentSize     int64 `json:"contentSize"`
+	AttachmentSize  int64 `json:"attachmentSize"`
+	Priority        int   `json:"priority"`
+	RedeliveryCount int64 `json:"redeliveryCount"`
+	DMQEligible     bool  `json:"dmqEligible"`
+	// Undelivered is true until the message was delivered to a consumer once
+	Undelivered bool `json:"undelivered"`
+}
+
+// Spooled returns the time the message was spooled
+func (m Message) Spooled() time.Time {
+	return time.Unix(m.SpooledTime, 0)
+}
+
+// Size returns the size of the message, its binary attachment and its XML content
+func (m Message) Size() int64 {
+	return m.AttachmentSize + m.ContentSize
+}
+
+// sempResponse is a page of a SEMP v2 response
+type sempResponse struct {
+	Data json.RawMessage `json:"data"`
+	Meta struct {
+		Error *struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+		Paging *struct {
+			NextPageURI string `json:"nextPageUri"`
+		} `json:"paging"`
+	} `json:"meta"`
+}
+
+// Pager lists the messages of a queue page by page, oldest first. A pager is not safe for concurrent use.
+type Pager struct {
+	semp queuelag.SEMPConfig
+	// next is the URI of the next page, empty once the last page was listed
+	next string
+}
+
+// NewPager creates a pager of the messages of the queue, count messages per page
+func NewPager(semp queuelag.SEMPConfig, queue string, count int) *Pager {
+	return &Pager{semp: semp, next: fmt.Sprintf("%s/SEMP/v2/monitor/msgVpns/%s/queues/%s/msgs?count=%d",
+		semp.URL, url.PathEscape(semp.VPN), url.PathEscape(queue), count)}
+}
+
+// Exhausted reports whether the last page was listed
+func (p *Pager) Exhausted() bool {
+	return p.next == ""
+}
+
+// Next lists the next page of messages, empty once the pager is exhausted
+func (p *Pager) Next(ctx context.Context) ([]Message, error) {
+	if p.next == "" {
+		return nil, nil
+	}
+	var page sempResponse
+	if err := send(ctx, p.semp, http.MethodGet, p.next, nil, &page); err != nil {
+		return nil, err
+	}
+	var messages []Message
+	if len(page.Data) > 0 {
+		if err := json.Unmarshal(page.Data, &messages); err != nil {
+			return nil, fmt.Errorf("invalid SEMP data: %w", err)
+		}
+	}
+	p.next = ""
+	if page.Meta.Paging != nil {
+		p.next = page.Meta.Paging.NextPageURI
+	}
+	return messages, nil
+}
+
+// List returns the oldest messages of the queue, up to count
+func List(ctx context.Context, semp queuelag.SEMPConfig, queue string, count int) ([]Message, error) {
+	return NewPager(semp, queue, count).Next(ctx)
+}
+
+// Delete deletes the message from the queue, for good: it is not moved to the dead message queue
+func Delete(ctx context.Context, semp queuelag.SEMPConfig, queue string, msgID int64) error {
+	endpoint := fmt.Sprintf("%s/SEMP/v2/action/msgVpns/%s/queues/%s/msgs/%d/delete",
+		semp.URL, url.PathEscape(semp.VPN), url.PathEscape(queue), msgID)
+	var response sempResponse
+	return send(ctx, semp, http.MethodPut, endpoint, struct{}{}, &response)
+}
+
+// send sends the request with the JSON of the body, when not nil, and decodes the response
+func send(ctx context.Context, semp queuelag.SEMPConfig, method, endpoint string, body interface{}, response *sempResponse) error {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(semp.Username, semp.Password)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	client := semp.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	httpResponse, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if err := json.NewDecoder(httpResponse.Body).Decode(response); err != nil {
+		return fmt.Errorf("%s, invalid SEMP response: %w", httpResponse.Status, err)
+	}
+	if response.Meta.Error != nil {
+		return fmt.Errorf("%s: %s", response.Meta.Error.Status, response.Meta.Error.Description)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", httpResponse.Status)
+	}
+	return nil
+}
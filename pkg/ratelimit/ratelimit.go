@@ -0,0 +1,233 @@
+// Package ratelimit wraps the publishers with a token bucket, so an application publishes at a steady rate however
+// fast its producer loop runs. The publish calls of a wrapped publisher wait for a token before publishing: the bucket
+// refills at the rate, in messages per second, and holds up to the burst, the messages published back to back after
+// an idle period before the rate applies again.
+//
+//	limiter := ratelimit.NewLimiter(100, 10)
+//	publisher := ratelimit.Direct(directPublisher, limiter)
+//	publisher.PublishString("hello", topic) // waits for a token
+//
+// A limiter may be shared by several publishers, to cap their combined rate. The wrappers are publishers themselves,
+// terminating one releases the publish calls waiting for a token with ErrTerminated.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// ErrTerminated is returned by the publish calls waiting for a token when the publisher is terminated
+var ErrTerminated = errors.New("ratelimit: publisher terminated while waiting for a token")
+
+// Limiter is a token bucket, safe for concurrent use
+type Limiter struct {
+	limiter *rate.Limiter
+}
+
+// NewLimiter returns a full bucket refilling at perSecond messages per second and holding up to burst messages.
+// A rate of 0 or less does not limit, a burst below 1 is 1.
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{limiter: rate.NewLimiter(limit(perSecond), burst)}
+}
+
+func limit(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(perSecond)
+}
+
+// Wait blocks until a token is available or the context is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.limiter.Wait(ctx)
+}
+
+// Allow takes a token if one is available, without waiting
+func (l *Limiter) Allow() bool {
+	return l.limiter.Allow()
+}
+
+// SetRate changes the rate, in messages per second, the waiting publish calls included. A rate of 0 or less does not
+// limit.
+func (l *Limiter) SetRate(perSecond float64) {
+	l.limiter.SetLimit(limit(perSecond))
+}
+
+// SetBurst changes the burst, a burst below 1 is 1
+func (l *Limiter) SetBurst(burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.limiter.SetBurst(burst)
+}
+
+// Rate returns the rate, in messages per second, 0 when it does not limit
+func (l *Limiter) Rate() float64 {
+	if l.limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return float64(l.limiter.Limit())
+}
+
+// Burst returns the burst
+func (l *Limiter) Burst() int {
+	return l.limiter.Burst()
+}
+
+// gate holds the waiting of the wrappers, released when the publisher is terminated
+type gate struct {
+	limiter *Limiter
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newGate(limiter *Limiter) gate {
+	ctx, cancel := context.WithCancel(context.Background())
+	return gate{limiter: limiter, ctx: ctx, cancel: cancel}
+}
+
+func (g gate) wait() error {
+	if err := g.limiter.Wait(g.ctx); err != nil {
+		if g.ctx.Err() != nil {
+			return ErrTerminated
+		}
+		return err
+	}
+	return nil
+}
+
+// DirectPublisher is a direct publisher whose publish calls wait for a token of the limiter
+type DirectPublisher struct {
+	solace.DirectMessagePublisher
+	gate gate
+}
+
+// Direct wraps a direct publisher with the limiter
+func Direct(publisher solace.DirectMessagePublisher, limiter *Limiter) *DirectPublisher {
+	return &DirectPublisher{DirectMessagePublisher: publisher, gate: newGate(limiter)}
+}
+
+// PublishBytes waits for a token and publishes the bytes
+func (p *DirectPublisher) PublishBytes(message []byte, destination *resource.Topic) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.DirectMessagePublisher.PublishBytes(message, destination)
+}
+
+// PublishString waits for a token and publishes the string
+func (p *DirectPublisher) PublishString(message string, destination *resource.Topic) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.DirectMessagePublisher.PublishString(message, destination)
+}
+
+// Publish waits for a token and publishes the message
+func (p *DirectPublisher) Publish(message message.OutboundMessage, destination *resource.Topic) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.DirectMessagePublisher.Publish(message, destination)
+}
+
+// PublishWithProperties waits for a token and publishes the message with the properties
+func (p *DirectPublisher) PublishWithProperties(message message.OutboundMessage, destination *resource.Topic,
+	properties config.MessagePropertiesConfigurationProvider) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.DirectMessagePublisher.PublishWithProperties(message, destination, properties)
+}
+
+// Terminate releases the waiting publish calls and terminates the publisher
+func (p *DirectPublisher) Terminate(gracePeriod time.Duration) error {
+	p.gate.cancel()
+	return p.DirectMessagePublisher.Terminate(gracePeriod)
+}
+
+// TerminateAsync releases the waiting publish calls and terminates the publisher asynchronously
+func (p *DirectPublisher) TerminateAsync(gracePeriod time.Duration) <-chan error {
+	p.gate.cancel()
+	return p.DirectMessagePublisher.TerminateAsync(gracePeriod)
+}
+
+// TerminateAsyncCallback releases the waiting publish calls and terminates the publisher asynchronously
+func (p *DirectPublisher) TerminateAsyncCallback(gracePeriod time.Duration, callback func(error)) {
+	p.gate.cancel()
+	p.DirectMessagePublisher.TerminateAsyncCallback(gracePeriod, callback)
+}
+
+// PersistentPublisher is a persistent publisher whose publish calls wait for a token of the limiter
+type PersistentPublisher struct {
+	solace.PersistentMessagePublisher
+	gate gate
+}
+
+// Persistent wraps a persistent publisher with the limiter
+func Persistent(publisher solace.PersistentMessagePublisher, limiter *Limiter) *PersistentPublisher {
+	return &PersistentPublisher{PersistentMessagePublisher: publisher, gate: newGate(limiter)}
+}
+
+// PublishBytes waits for a token and publishes the bytes
+func (p *PersistentPublisher) PublishBytes(message []byte, destination *resource.Topic) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.PersistentMessagePublisher.PublishBytes(message, destination)
+}
+
+// PublishString waits for a token and publishes the string
+func (p *PersistentPublisher) PublishString(message string, destination *resource.Topic) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.PersistentMessagePublisher.PublishString(message, destination)
+}
+
+// Publish waits for a token and publishes the message, the context is passed on to its publish receipt
+func (p *PersistentPublisher) Publish(message message.OutboundMessage, destination *resource.Topic,
+	properties config.MessagePropertiesConfigurationProvider, context interface{}) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.PersistentMessagePublisher.Publish(message, destination, properties, context)
+}
+
+// PublishAwaitAcknowledgement waits for a token, publishes the message and waits for its acknowledgement. The
+// timeout applies to the acknowledgement only, not to the wait for the token.
+func (p *PersistentPublisher) PublishAwaitAcknowledgement(message message.OutboundMessage, destination *resource.Topic,
+	timeout time.Duration, properties config.MessagePropertiesConfigurationProvider) error {
+	if err := p.gate.wait(); err != nil {
+		return err
+	}
+	return p.PersistentMessagePublisher.PublishAwaitAcknowledgement(message, destination, timeout, properties)
+}
+
+// Terminate releases the waiting publish calls and terminates the publisher
+func (p *PersistentPublisher) Terminate(gracePeriod time.Duration) error {
+	p.gate.cancel()
+	return p.PersistentMessagePublisher.Terminate(gracePeriod)
+}
+
+// TerminateAsync releases the waiting publish calls and terminates the publisher asynchronously
+func (p *PersistentPublisher) TerminateAsync(gracePeriod time.Duration) <-chan error {
+	p.gate.cancel()
+	return p.PersistentMessagePublisher.TerminateAsync(gracePeriod)
+}
+
+// TerminateAsyncCallback releases the waiting publish calls and terminates the publisher asynchronously
+func (p *PersistentPublisher) TerminateAsyncCallback(gracePeriod time.Duration, callback func(error)) {
+	p.gate.cancel()
+	p.PersistentMessagePublisher.TerminateAsyncCallback(gracePeriod, callback)
+}
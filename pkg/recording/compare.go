@@ -0,0 +1,124 @@
+package recording
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of differences between a recording and the messages received
+const (
+	// Missing records were not received
+	Missing = "missing"
+	// Unexpected messages were received without a known sequence number
+	Unexpected = "unexpected"
+	// Duplicate records were received more than once
+	Duplicate = "duplicate"
+	// OutOfOrder records were received after a record that follows them in the recording
+	OutOfOrder = "out-of-order"
+	// Changed records were received with another topic, payload, header or user property
+	Changed = "changed"
+)
+
+// Difference is a difference between a record and what was received
+type Difference struct {
+	Kind string `json:"kind"`
+	// Seq is the sequence number of the record, 0 for the unexpected messages
+	Seq int64 `json:"seq"`
+	// Detail describes the difference, e.g. the fields that changed
+	Detail string `json:"detail,omitempty"`
+}
+
+func (d Difference) String() string {
+	if d.Detail == "" {
+		return fmt.Sprintf("%s #%d", d.Kind, d.Seq)
+	}
+	return fmt.Sprintf("%s #%d: %s", d.Kind, d.Seq, d.Detail)
+}
+
+// Compare compares the records received, in the order they were received, with the recording, matching them by the
+// sequence number of their SeqProperty user property. The differences are sorted by sequence number.
+func Compare(recorded, received []Record) []Difference {
+	expected := make(map[int64]Record, len(recorded))
+	for _, r := range recorded {
+		expected[r.Seq] = r
+	}
+	var differences []Difference
+	seen := map[int64]bool{}
+	var last int64
+	for _, r := range received {
+		seq, err := strconv.ParseInt(r.Properties[SeqProperty], 10, 64)
+		original, ok := expected[seq]
+		if err != nil || !ok {
+			differences = append(differences, Difference{Kind: Unexpected, Detail: fmt.Sprintf("on %s, %d bytes", r.Topic, len(r.Payload))})
+			continue
+		}
+		if seen[seq] {
+			differences = append(differences, Difference{Kind: Duplicate, Seq: seq})
+			continue
+		}
+		seen[seq] = true
+		if seq < last {
+			differences = append(differences, Difference{Kind: OutOfOrder, Seq: seq, Detail: fmt.Sprintf("received after #%d", last)})
+		} else {
+			last = seq
+		}
+		if changes := changes(original, r); len(changes) > 0 {
+			differences = append(differences, Difference{Kind: Changed, Seq: seq, Detail: strings.Join(changes, ", ")})
+		}
+	}
+	for _, r := range recorded {
+		if !seen[r.Seq] {
+			differences = append(differences, Difference{Kind: Missing, Seq: r.Seq})
+		}
+	}
+	sort.SliceStable(differences, func(i, j int) bool { return differences[i].Seq < differences[j].Seq })
+	return differences
+}
+
+// changes lists the fields of the record that changed once received
+func changes(recorded, received Record) []string {
+	var changed []string
+	field := func(name, before, after string) {
+		if before != after {
+			changed = append(changed, fmt.Sprintf("%s %q -> %q", name, before, after))
+		}
+	}
+	field("topic", recorded.Topic, received.Topic)
+	field("applicationMessageId", recorded.ApplicationMessageID, received.ApplicationMessageID)
+	field("applicationMessageType", recorded.ApplicationMessageType, received.ApplicationMessageType)
+	field("correlationId", recorded.CorrelationID, received.CorrelationID)
+	field("httpContentType", recorded.HTTPContentType, received.HTTPContentType)
+	field("httpContentEncoding", recorded.HTTPContentEncoding, received.HTTPContentEncoding)
+	if !bytes.Equal(recorded.Payload, received.Payload) {
+		changed = append(changed, fmt.Sprintf("payload %d -> %d bytes", len(recorded.Payload), len(received.Payload)))
+	}
+	names := map[string]bool{}
+	for name := range recorded.Properties {
+		names[name] = true
+	}
+	for name := range received.Properties {
+		names[name] = true
+	}
+	delete(names, SeqProperty)
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		before, inRecorded := recorded.Properties[name]
+		after, inReceived := received.Properties[name]
+		switch {
+		case !inReceived:
+			changed = append(changed, fmt.Sprintf("property %s removed", name))
+		case !inRecorded:
+			changed = append(changed, fmt.Sprintf("property %s added", name))
+		default:
+			field("property "+name, before, after)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,91 @@
+package recording
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+// encodeProperty returns the text and the type of a user property value. The values of other types than the
+// scalars and byte slices, e.g. maps and streams, are recorded as their text, and replayed as strings.
+func encodeProperty(value interface{}) (text, kind string) {
+	switch v := value.(type) {
+	case string:
+		return v, "string"
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), "bytes"
+	case bool:
+		return strconv.FormatBool(v), "bool"
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint:
+		return fmt.Sprint(v), fmt.Sprintf("%T", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), "float32"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), "float64"
+	case nil:
+		return "", "nil"
+	}
+	return fmt.Sprint(value), "string"
+}
+
+// decodeProperty returns the value of a user property from its text and type, a string when the type is empty
+func decodeProperty(text, kind string) (interface{}, error) {
+	switch kind {
+	case "", "string":
+		return text, nil
+	case "bytes":
+		return base64.StdEncoding.DecodeString(text)
+	case "bool":
+		return strconv.ParseBool(text)
+	case "nil":
+		return nil, nil
+	case "float32":
+		f, err := strconv.ParseFloat(text, 32)
+		return float32(f), err
+	case "float64":
+		return strconv.ParseFloat(text, 64)
+	case "int8", "int16", "int32", "int64", "int":
+		// parsed with the size of the type, a value out of its range is an error rather than truncated
+		n, err := strconv.ParseInt(text, 10, bitSize(kind))
+		switch kind {
+		case "int8":
+			return int8(n), err
+		case "int16":
+			return int16(n), err
+		case "int32":
+			return int32(n), err
+		case "int":
+			return int(n), err
+		}
+		return n, err
+	case "uint8", "uint16", "uint32", "uint64", "uint":
+		n, err := strconv.ParseUint(text, 10, bitSize(kind))
+		switch kind {
+		case "uint8":
+			return uint8(n), err
+		case "uint16":
+			return uint16(n), err
+		case "uint32":
+			return uint32(n), err
+		case "uint":
+			return uint(n), err
+		}
+		return n, err
+	}
+	return nil, fmt.Errorf("unknown property type %q", kind)
+}
+
+// bitSize returns the size of an integer type, e.g. 8 for int8
+func bitSize(kind string) int {
+	switch kind {
+	case "int8", "uint8":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32":
+		return 32
+	case "int", "uint":
+		return strconv.IntSize
+	}
+	return 64
+}
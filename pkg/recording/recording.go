@@ -0,0 +1,302 @@
+// Package recording records a stream of messages to a newline delimited JSON file, one Record per line, replays the
+// recording and compares what a consumer receives against it, e.g. to validate a broker configuration change or
+// upgrade with the traffic recorded before it:
+//
+//	writer, err := recording.Create("baseline.ndjson")
+//	err = writer.Write(inbound) // for every message received
+//
+//	records, err := recording.ReadFile("baseline.ndjson")
+//	outbound, err := records[0].Message(messagingService.MessageBuilder())
+//
+// The messages built from the records carry the sequence number of their record in the recording-seq user property,
+// so the messages received can be matched with the recording by Compare whatever order they arrive in.
+//
+// A recording is also a portable fixture of live traffic (see cmd/record and cmd/replay): the records hold the
+// payload and all the metadata of the messages, and the types of their user properties, so the messages replayed have
+// the original properties.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// SeqProperty is the user property holding the sequence number of the record a replayed message was built from
+const SeqProperty = "recording-seq"
+
+// Record is a recorded message. The user properties are recorded as text, their types other than string in
+// PropertyTypes, so they are replayed with their original type.
+type Record struct {
+	// Seq is the sequence number of the message in the recording, starting at 1
+	Seq                    int64  `json:"seq"`
+	Topic                  string `json:"topic"`
+	ApplicationMessageID   string `json:"applicationMessageId,omitempty"`
+	ApplicationMessageType string `json:"applicationMessageType,omitempty"`
+	CorrelationID          string `json:"correlationId,omitempty"`
+	HTTPContentType        string `json:"httpContentType,omitempty"`
+	HTTPContentEncoding    string `json:"httpContentEncoding,omitempty"`
+	Priority               *int   `json:"priority,omitempty"`
+	SenderID               string `json:"senderId,omitempty"`
+	ClassOfService         int    `json:"classOfService,omitempty"`
+	SequenceNumber         *int64 `json:"sequenceNumber,omitempty"`
+	// SenderTimestamp, Expiration and ReplicationGroupMessageID are informative, the broker and the API set them
+	// again on the messages replayed
+	SenderTimestamp           *time.Time        `json:"senderTimestamp,omitempty"`
+	Expiration                *time.Time        `json:"expiration,omitempty"`
+	ReplicationGroupMessageID string            `json:"replicationGroupMessageId,omitempty"`
+	Properties                map[string]string `json:"properties,omitempty"`
+	// PropertyTypes are the types of the user properties that are not strings, e.g. int64 or bool, see Property
+	PropertyTypes map[string]string `json:"propertyTypes,omitempty"`
+	// Payload is the binary payload, base64 encoded in JSON
+	Payload []byte `json:"payload"`
+	// Received is the time the message was recorded
+	Received time.Time `json:"received"`
+}
+
+// Of records the message with the sequence number
+func Of(msg message.InboundMessage, seq int64) Record {
+	r := Record{Seq: seq, Topic: msg.GetDestinationName(), Received: time.Now()}
+	r.ApplicationMessageID, _ = msg.GetApplicationMessageID()
+	r.ApplicationMessageType, _ = msg.GetApplicationMessageType()
+	r.CorrelationID, _ = msg.GetCorrelationID()
+	r.HTTPContentType, _ = msg.GetHTTPContentType()
+	r.HTTPContentEncoding, _ = msg.GetHTTPContentEncoding()
+	if priority, ok := msg.GetPriority(); ok {
+		r.Priority = &priority
+	}
+	r.SenderID, _ = msg.GetSenderID()
+	r.ClassOfService = msg.GetClassOfService()
+	if sequenceNumber, ok := msg.GetSequenceNumber(); ok {
+		r.SequenceNumber = &sequenceNumber
+	}
+	if timestamp, ok := msg.GetSenderTimestamp(); ok {
+		r.SenderTimestamp = &timestamp
+	}
+	if expiration := msg.GetExpiration(); !expiration.IsZero() {
+		r.Expiration = &expiration
+	}
+	if id, ok := msg.GetReplicationGroupMessageID(); ok {
+		r.ReplicationGroupMessageID = id.String()
+	}
+	if properties := msg.GetProperties(); len(properties) > 0 {
+		r.Properties = make(map[string]string, len(properties))
+		for name, value := range properties {
+			text, kind := encodeProperty(value)
+			r.Properties[name] = text
+			if kind != "string" {
+				if r.PropertyTypes == nil {
+					r.PropertyTypes = map[string]string{}
+				}
+				r.PropertyTypes[name] = kind
+			}
+		}
+	}
+	r.Payload, _ = msg.GetPayloadAsBytes()
+	return r
+}
+
+// Property returns the value of the user property with its original type
+func (r Record) Property(name string) (interface{}, error) {
+	return decodeProperty(r.Properties[name], r.PropertyTypes[name])
+}
+
+// Message builds the message to replay the record, with its sequence number in the SeqProperty user property
+func (r Record) Message(builder solace.OutboundMessageBuilder) (message.OutboundMessage, error) {
+	return r.build(builder, true)
+}
+
+// Restore builds the message as it was recorded, without the SeqProperty user property
+func (r Record) Restore(builder solace.OutboundMessageBuilder) (message.OutboundMessage, error) {
+	return r.build(builder, false)
+}
+
+func (r Record) build(builder solace.OutboundMessageBuilder, withSeq bool) (message.OutboundMessage, error) {
+	properties := config.MessagePropertyMap{}
+	for name := range r.Properties {
+		value, err := r.Property(name)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", name, err)
+		}
+		properties[config.MessageProperty(name)] = value
+	}
+	if withSeq {
+		properties[SeqProperty] = strconv.FormatInt(r.Seq, 10)
+	}
+	builder = builder.FromConfigurationProvider(properties)
+	if r.ApplicationMessageID != "" {
+		builder = builder.WithApplicationMessageID(r.ApplicationMessageID)
+	}
+	if r.ApplicationMessageType != "" {
+		builder = builder.WithApplicationMessageType(r.ApplicationMessageType)
+	}
+	if r.CorrelationID != "" {
+		builder = builder.WithCorrelationID(r.CorrelationID)
+	}
+	if r.HTTPContentType != "" || r.HTTPContentEncoding != "" {
+		builder = builder.WithHTTPContentHeader(r.HTTPContentType, r.HTTPContentEncoding)
+	}
+	if r.Priority != nil {
+		builder = builder.WithPriority(*r.Priority)
+	}
+	if r.SenderID != "" {
+		builder = builder.WithSenderID(r.SenderID)
+	}
+	if r.ClassOfService != 0 {
+		builder = builder.WithProperty(config.MessagePropertyClassOfService, r.ClassOfService)
+	}
+	if r.SequenceNumber != nil && *r.SequenceNumber >= 0 {
+		builder = builder.WithSequenceNumber(uint64(*r.SequenceNumber))
+	}
+	return builder.BuildWithByteArrayPayload(r.Payload)
+}
+
+// Writer appends the records to a file, it is not safe for concurrent use
+type Writer struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+	closer  io.Closer
+	seq     int64
+}
+
+// NewWriter writes the records to w
+func NewWriter(w io.Writer) *Writer {
+	buffer := bufio.NewWriter(w)
+	writer := &Writer{buffer: buffer, encoder: json.NewEncoder(buffer)}
+	if closer, ok := w.(io.Closer); ok {
+		writer.closer = closer
+	}
+	return writer
+}
+
+// Create creates, or truncates, the file of the recording
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(file), nil
+}
+
+// Append opens the file of a recording to append records to it, with the sequence numbers following the ones of the
+// file, and returns the record with the highest sequence number of the file, the zero Record if it is empty or does
+// not exist
+func Append(path string) (*Writer, Record, error) {
+	var last Record
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, last, err
+	}
+	decoder := json.NewDecoder(file)
+	for {
+		var record Record
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, last, fmt.Errorf("%s: %w", path, err)
+		}
+		if record.Seq >= last.Seq {
+			last = record
+		}
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, last, err
+	}
+	writer := NewWriter(file)
+	writer.seq = last.Seq
+	return writer, last, nil
+}
+
+// Write records the message with the next sequence number
+func (w *Writer) Write(msg message.InboundMessage) error {
+	w.seq++
+	return w.WriteRecord(Of(msg, w.seq))
+}
+
+// WriteRecord appends the record as it is
+func (w *Writer) WriteRecord(r Record) error {
+	return w.encoder.Encode(r)
+}
+
+// Count returns the number of messages written, with the ones already in the file with Append
+func (w *Writer) Count() int64 {
+	return w.seq
+}
+
+// Flush writes the records buffered to the file
+func (w *Writer) Flush() error {
+	return w.buffer.Flush()
+}
+
+// Close flushes the records and closes the file
+func (w *Writer) Close() error {
+	err := w.buffer.Flush()
+	if w.closer != nil {
+		if closeErr := w.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Reader reads the records of a recording one by one, in the order of the file, e.g. when they do not all fit in
+// memory
+type Reader struct {
+	decoder *json.Decoder
+	read    int
+}
+
+// NewReader reads the records from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{decoder: json.NewDecoder(r)}
+}
+
+// Next returns the next record, io.EOF after the last one
+func (r *Reader) Next() (Record, error) {
+	var record Record
+	if err := r.decoder.Decode(&record); err == io.EOF {
+		return record, err
+	} else if err != nil {
+		return record, fmt.Errorf("record %d: %w", r.read+1, err)
+	}
+	r.read++
+	return record, nil
+}
+
+// Read reads the records of a recording, sorted by sequence number
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+	reader := NewReader(r)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+	return records, nil
+}
+
+// ReadFile reads the records of the recording file
+func ReadFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
@@ -0,0 +1,208 @@
+package recording
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProperty(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		// text and kind are the recording of the value
+		text string
+		kind string
+		// replayed is the value replayed, the value itself when nil
+		replayed interface{}
+	}{
+		{name: "string", value: "a b", text: "a b", kind: "string"},
+		{name: "bytes", value: []byte{0, 0xff}, text: "AP8=", kind: "bytes"},
+		{name: "bool", value: true, text: "true", kind: "bool"},
+		{name: "int8", value: int8(-8), text: "-8", kind: "int8"},
+		{name: "int64", value: int64(-1 << 40), text: "-1099511627776", kind: "int64"},
+		{name: "uint16", value: uint16(65535), text: "65535", kind: "uint16"},
+		{name: "uint64", value: uint64(1 << 63), text: "9223372036854775808", kind: "uint64"},
+		{name: "float32", value: float32(0.1), text: "0.1", kind: "float32"},
+		{name: "float64", value: 0.1, text: "0.1", kind: "float64"},
+		{name: "nil", value: nil, text: "", kind: "nil"},
+		{name: "map as text", value: map[string]interface{}{"a": 1}, text: "map[a:1]", kind: "string", replayed: "map[a:1]"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			text, kind := encodeProperty(test.value)
+			if text != test.text || kind != test.kind {
+				t.Fatalf("recorded %q of type %s, expected %q of type %s", text, kind, test.text, test.kind)
+			}
+			record := Record{Properties: map[string]string{"p": text}, PropertyTypes: map[string]string{"p": kind}}
+			value, err := record.Property("p")
+			if err != nil {
+				t.Fatalf("could not replay the property: %s", err)
+			}
+			expected := test.replayed
+			if expected == nil {
+				expected = test.value
+			}
+			if !reflect.DeepEqual(value, expected) {
+				t.Errorf("replayed %#v, expected %#v", value, expected)
+			}
+		})
+	}
+}
+
+func TestPropertyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		kind string
+	}{
+		{"unknown type", "1", "decimal"},
+		{"not a bool", "yes", "bool"},
+		{"int8 overflow", "128", "int8"},
+		{"negative uint", "-1", "uint"},
+		{"not base64", "%", "bytes"},
+	}
+
+	for _, test := range tests {
+		if _, err := decodeProperty(test.text, test.kind); err == nil {
+			t.Errorf("%s: replayed %q of type %s without error", test.name, test.text, test.kind)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	priority := 3
+	records := []Record{
+		{Seq: 2, Topic: "a/b", Payload: []byte("second"), Priority: &priority},
+		{Seq: 1, Topic: "a/b", Payload: []byte{0, 1, 2}, Properties: map[string]string{"n": "1"}, PropertyTypes: map[string]string{"n": "int32"}},
+	}
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	for _, record := range records {
+		if err := writer.WriteRecord(record); err != nil {
+			t.Fatalf("could not write the record: %s", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("could not flush the records: %s", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(records) {
+		t.Fatalf("wrote %d lines, expected one per record", lines)
+	}
+
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("could not read the records: %s", err)
+	}
+	// sorted by sequence number
+	if expected := []Record{records[1], records[0]}; !reflect.DeepEqual(read, expected) {
+		t.Errorf("read %+v, expected %+v", read, expected)
+	}
+
+	if _, err := Read(strings.NewReader("{\"seq\":1}\n{")); err == nil || !strings.HasPrefix(err.Error(), "record 2: ") {
+		t.Errorf("got error %v reading a truncated recording, expected one on record 2", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// received builds a record received with the sequence number property
+	received := func(seq string, topic string, payload string) Record {
+		return Record{Topic: topic, Payload: []byte(payload), Properties: map[string]string{SeqProperty: seq}}
+	}
+	recorded := []Record{
+		{Seq: 1, Topic: "t", Payload: []byte("one")},
+		{Seq: 2, Topic: "t", Payload: []byte("two")},
+		{Seq: 3, Topic: "t", Payload: []byte("three"), Properties: map[string]string{"k": "v"}},
+	}
+	tests := []struct {
+		name        string
+		received    []Record
+		differences []string
+	}{
+		{
+			name:     "identical",
+			received: []Record{received("1", "t", "one"), received("2", "t", "two"), {Topic: "t", Payload: []byte("three"), Properties: map[string]string{SeqProperty: "3", "k": "v"}}},
+		},
+		{
+			name:        "missing",
+			received:    []Record{received("1", "t", "one")},
+			differences: []string{"missing #2", "missing #3"},
+		},
+		{
+			name:        "unexpected, duplicate and out of order",
+			received:    []Record{received("2", "t", "two"), received("x", "u", "?"), received("1", "t", "one"), received("2", "t", "two")},
+			differences: []string{"unexpected #0: on u, 1 bytes", "out-of-order #1: received after #2", "duplicate #2", "missing #3"},
+		},
+		{
+			name:        "changed",
+			received:    []Record{received("1", "other", "one"), received("2", "t", "2"), received("3", "t", "three")},
+			differences: []string{`changed #1: topic "t" -> "other"`, "changed #2: payload 3 -> 1 bytes", "changed #3: property k removed"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var differences []string
+			for _, d := range Compare(recorded, test.received) {
+				differences = append(differences, d.String())
+			}
+			if !reflect.DeepEqual(differences, test.differences) {
+				t.Errorf("got differences %q, expected %q", differences, test.differences)
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.ndjson")
+
+	writer, last, err := Append(path)
+	if err != nil {
+		t.Fatalf("could not create the recording: %s", err)
+	}
+	if last.Seq != 0 {
+		t.Errorf("last record #%d of a new recording, expected none", last.Seq)
+	}
+	for _, seq := range []int64{1, 3, 2} {
+		if err := writer.WriteRecord(Record{Seq: seq, Topic: "t"}); err != nil {
+			t.Fatalf("could not write the record: %s", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("could not close the recording: %s", err)
+	}
+
+	writer, last, err = Append(path)
+	if err != nil {
+		t.Fatalf("could not reopen the recording: %s", err)
+	}
+	if last.Seq != 3 || writer.Count() != 3 {
+		t.Errorf("last record #%d and count %d, expected the highest sequence number 3", last.Seq, writer.Count())
+	}
+	if err := writer.WriteRecord(Record{Seq: 4, Topic: "t"}); err != nil {
+		t.Fatalf("could not write the record: %s", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("could not close the recording: %s", err)
+	}
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read the recording: %s", err)
+	}
+	if len(records) != 4 || records[3].Seq != 4 {
+		t.Errorf("read %+v, expected the records appended after the ones of the file", records)
+	}
+
+	if err := os.WriteFile(path, []byte("{\"seq\":1}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Append(path); err == nil {
+		t.Errorf("appended to a corrupt recording without error")
+	}
+}
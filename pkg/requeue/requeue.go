@@ -0,0 +1,64 @@
+// Package requeue copies the messages of a dead message queue to republish them, to their original topic or to another
+// one, with their user properties rewritten. The copy keeps the payload, the user properties, the application message
+// ID and the correlation ID of the message, and records the queue it was requeued from in the requeued-from user
+// property.
+//
+//	outbound, topic, err := requeue.Copy(messagingService.MessageBuilder(), inbound, "#DEAD_MSG_QUEUE", requeue.Rewrite{})
+//	if err == nil && topic != "" {
+//		err = publisher.PublishAwaitAcknowledgement(outbound, resource.TopicOf(topic), 5*time.Second, nil)
+//	}
+//
+// Settle the message on the dead message queue only once the copy was acknowledged, so it is never lost.
+package requeue
+
+import (
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// RequeuedFrom is the user property holding the dead message queue a message was requeued from
+const RequeuedFrom = "requeued-from"
+
+// Rewrite is applied to the messages before they are republished
+type Rewrite struct {
+	// Topic replaces the original topic of the messages, when not empty
+	Topic string
+	// Set are the user properties set on the messages
+	Set map[string]string
+	// Unset are the user properties removed from the messages
+	Unset []string
+}
+
+// Copy builds the copy of the message to republish and returns the topic to republish it to, empty when the message
+// has no topic, e.g. it was published to a queue, and the rewrite does not set one
+func Copy(builder solace.OutboundMessageBuilder, inbound message.InboundMessage, queue string, rewrite Rewrite) (message.OutboundMessage, string, error) {
+	topic := rewrite.Topic
+	if topic == "" {
+		topic = inbound.GetDestinationName()
+	}
+	if topic == "" {
+		return nil, "", nil
+	}
+	properties := config.MessagePropertyMap{}
+	for name, value := range inbound.GetProperties() {
+		properties[config.MessageProperty(name)] = value
+	}
+	for _, name := range rewrite.Unset {
+		delete(properties, config.MessageProperty(name))
+	}
+	for name, value := range rewrite.Set {
+		properties[config.MessageProperty(name)] = value
+	}
+	properties[RequeuedFrom] = queue
+	builder = builder.FromConfigurationProvider(properties)
+	if id, ok := inbound.GetApplicationMessageID(); ok {
+		builder = builder.WithApplicationMessageID(id)
+	}
+	if correlationID, ok := inbound.GetCorrelationID(); ok {
+		builder = builder.WithCorrelationID(correlationID)
+	}
+	payload, _ := inbound.GetPayloadAsBytes()
+	outbound, err := builder.BuildWithByteArrayPayload(payload)
+	return outbound, topic, err
+}
@@ -0,0 +1,121 @@
+// Package scenario runs scripted demo scenarios: timed steps that publish bursts, start, pause and resume receivers,
+// kill connections and assert queue depths, narrating every step and its outcome, for workshops and reproducible bug
+// reports. A scenario is defined in Go or in YAML (see Load):
+//
+//	s := &scenario.Scenario{Name: "slow consumer", Steps: []scenario.Timed{
+//		{Step: scenario.Receive{Name: "orders", Queue: "orders-queue"}},
+//		{Step: scenario.Pause{Receiver: "orders"}},
+//		{Step: scenario.Publish{Topic: "solace/samples/orders", Count: 100, Persistent: true}},
+//		{At: 5 * time.Second, Step: scenario.AssertQueueDepth{Queue: "orders-queue", Min: 100}},
+//		{Step: scenario.Resume{Receiver: "orders"}},
+//		{Step: scenario.AssertReceived{Receiver: "orders", Min: 100, Within: 10 * time.Second}},
+//	}}
+//	err := s.Run(ctx, scenario.NewEnv(messagingService, sempConfig, os.Stdout))
+//
+// The steps run one after the other, a step with At waits until that long after the start of the scenario. The
+// scenario stops at the first step failing, e.g. an assertion, and returns its error.
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/queuelag"
+	"solace.dev/go/messaging/pkg/solace"
+)
+
+// Step is a step of a scenario
+type Step interface {
+	// Describe narrates what the step is about to do
+	Describe() string
+	// Run runs the step and returns the narration of its outcome
+	Run(ctx context.Context, env *Env) (string, error)
+}
+
+// Timed is a step with its time in the scenario
+type Timed struct {
+	// At is the time the step starts at, from the start of the scenario; 0 runs the step right after the previous one
+	At   time.Duration
+	Step Step
+}
+
+// Scenario is a sequence of timed steps
+type Scenario struct {
+	Name string
+	// Description is narrated before the first step
+	Description string
+	Steps       []Timed
+}
+
+// Env is the environment the steps run in: the messaging service, the receivers started by the steps and the SEMP
+// API of the broker
+type Env struct {
+	Service solace.MessagingService
+	SEMP    queuelag.SEMPConfig
+	// Log is where the scenario is narrated
+	Log io.Writer
+
+	receivers map[string]*receiver
+	watcher   *queuelag.Watcher
+	started   time.Time
+}
+
+// NewEnv returns the environment of the scenarios run on the connected service
+func NewEnv(service solace.MessagingService, semp queuelag.SEMPConfig, log io.Writer) *Env {
+	return &Env{
+		Service:   service,
+		SEMP:      semp,
+		Log:       log,
+		receivers: map[string]*receiver{},
+		watcher:   queuelag.New(semp, queuelag.Thresholds{}, nil),
+	}
+}
+
+// Close terminates the receivers started by the steps
+func (e *Env) Close() {
+	for _, r := range e.receivers {
+		r.terminate()
+	}
+	e.receivers = map[string]*receiver{}
+}
+
+// narrate writes a line of the narration, stamped with the time elapsed since the start of the scenario
+func (e *Env) narrate(format string, args ...interface{}) {
+	elapsed := time.Since(e.started)
+	fmt.Fprintf(e.Log, "[%02d:%04.1f] %s\n", int(elapsed.Minutes()), elapsed.Seconds()-60*float64(int(elapsed.Minutes())),
+		fmt.Sprintf(format, args...))
+}
+
+// Run runs the steps of the scenario, narrating them, until one fails or the context is done
+func (s *Scenario) Run(ctx context.Context, env *Env) error {
+	env.started = time.Now()
+	env.narrate("scenario %s, %d step(s)", s.Name, len(s.Steps))
+	if s.Description != "" {
+		env.narrate("%s", s.Description)
+	}
+	for n, timed := range s.Steps {
+		if wait := time.Until(env.started.Add(timed.At)); timed.At > 0 && wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		env.narrate("step %d/%d: %s", n+1, len(s.Steps), timed.Step.Describe())
+		outcome, err := timed.Step.Run(ctx, env)
+		if err != nil {
+			env.narrate("  FAILED: %s", err)
+			return fmt.Errorf("step %d (%s): %w", n+1, timed.Step.Describe(), err)
+		}
+		if outcome != "" {
+			env.narrate("  ok: %s", outcome)
+		}
+	}
+	env.narrate("scenario %s completed", s.Name)
+	return nil
+}
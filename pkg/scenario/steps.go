@@ -0,0 +1,416 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/payloadgen"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/message"
+	"solace.dev/go/messaging/pkg/solace/resource"
+)
+
+// pollInterval is the interval the assertions with a Within delay are checked at
+const pollInterval = 500 * time.Millisecond
+
+// Say narrates a line, e.g. to explain what the next steps show
+type Say struct {
+	Text string `yaml:"text"`
+}
+
+func (s Say) Describe() string { return s.Text }
+
+func (s Say) Run(context.Context, *Env) (string, error) { return "", nil }
+
+// Wait waits for the duration
+type Wait struct {
+	Duration time.Duration `yaml:"duration"`
+}
+
+func (w Wait) Describe() string { return fmt.Sprintf("waiting %s", w.Duration) }
+
+func (w Wait) Run(ctx context.Context, _ *Env) (string, error) {
+	select {
+	case <-time.After(w.Duration):
+		return "", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Publish publishes a burst of messages to the topic
+type Publish struct {
+	Topic string `yaml:"topic"`
+	// Count is the number of messages, 1 when 0
+	Count int `yaml:"count"`
+	// Rate is the rate in messages per second, 0 for as fast as possible
+	Rate int `yaml:"rate"`
+	// Persistent publishes persistent messages, each one waiting for its acknowledgement, instead of direct messages
+	Persistent bool `yaml:"persistent"`
+	// Template of the payloads, see pkg/payloadgen, the sequence number of the message in the burst by default
+	Template string `yaml:"template"`
+}
+
+func (p Publish) Describe() string {
+	kind := "direct"
+	if p.Persistent {
+		kind = "persistent"
+	}
+	rate := "as fast as possible"
+	if p.Rate > 0 {
+		rate = fmt.Sprintf("at %d/s", p.Rate)
+	}
+	return fmt.Sprintf("publishing %d %s message(s) to %s %s", max(p.Count, 1), kind, p.Topic, rate)
+}
+
+func (p Publish) Run(ctx context.Context, env *Env) (string, error) {
+	template := p.Template
+	if template == "" {
+		template = "{{.Seq}}"
+	}
+	generator, err := payloadgen.New(template, payloadgen.Options{})
+	if err != nil {
+		return "", err
+	}
+	var publish func(msg message.OutboundMessage) error
+	var publisher solace.LifecycleControl
+	topic := resource.TopicOf(p.Topic)
+	if p.Persistent {
+		persistentPublisher, err := env.Service.CreatePersistentMessagePublisherBuilder().Build()
+		if err == nil {
+			err = persistentPublisher.Start()
+		}
+		if err != nil {
+			return "", err
+		}
+		publish = func(msg message.OutboundMessage) error {
+			return persistentPublisher.PublishAwaitAcknowledgement(msg, topic, 10*time.Second, nil)
+		}
+		publisher = persistentPublisher
+	} else {
+		directPublisher, err := env.Service.CreateDirectMessagePublisherBuilder().OnBackPressureWait(1000).Build()
+		if err == nil {
+			err = directPublisher.Start()
+		}
+		if err != nil {
+			return "", err
+		}
+		publish = func(msg message.OutboundMessage) error { return directPublisher.Publish(msg, topic) }
+		publisher = directPublisher
+	}
+	defer publisher.Terminate(5 * time.Second)
+
+	var interval time.Duration
+	if p.Rate > 0 {
+		interval = time.Second / time.Duration(p.Rate)
+	}
+	start := time.Now()
+	count := max(p.Count, 1)
+	for n := 0; n < count; n++ {
+		if wait := time.Until(start.Add(time.Duration(n) * interval)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		payload, err := generator.Next()
+		if err != nil {
+			return "", err
+		}
+		msg, err := env.Service.MessageBuilder().BuildWithByteArrayPayload(payload)
+		if err == nil {
+			err = publish(msg)
+		}
+		if err != nil {
+			return "", fmt.Errorf("message %d: %w", n+1, err)
+		}
+	}
+	return fmt.Sprintf("published %d message(s) in %s", count, time.Since(start).Round(time.Millisecond)), nil
+}
+
+// receiver is a receiver started by a Receive step, it counts the messages received
+type receiver struct {
+	lifecycle solace.LifecycleControl
+	// persistent is the queue receiver, nil for a receiver of topic subscriptions
+	persistent solace.PersistentMessageReceiver
+	received   atomic.Int64
+}
+
+func (r *receiver) terminate() {
+	if r.lifecycle.IsRunning() {
+		r.lifecycle.Terminate(1 * time.Second)
+	}
+}
+
+// Receive starts a receiver, named for the other steps, of the queue or of the topic subscriptions. The queue
+// messages are acknowledged once received.
+type Receive struct {
+	Name          string   `yaml:"name"`
+	Queue         string   `yaml:"queue"`
+	Subscriptions []string `yaml:"subscriptions"`
+}
+
+func (r Receive) Describe() string {
+	if r.Queue != "" {
+		return fmt.Sprintf("starting receiver %s on queue %s", r.Name, r.Queue)
+	}
+	return fmt.Sprintf("starting receiver %s on %s", r.Name, strings.Join(r.Subscriptions, ", "))
+}
+
+func (r Receive) Run(_ context.Context, env *Env) (string, error) {
+	if _, ok := env.receivers[r.Name]; ok {
+		return "", fmt.Errorf("receiver %s already started", r.Name)
+	}
+	started := &receiver{}
+	count := func(message.InboundMessage) { started.received.Add(1) }
+	if r.Queue != "" {
+		persistentReceiver, err := env.Service.CreatePersistentMessageReceiverBuilder().WithMessageAutoAcknowledgement().
+			Build(resource.QueueDurableExclusive(r.Queue))
+		if err == nil {
+			err = persistentReceiver.Start()
+		}
+		if err == nil {
+			err = persistentReceiver.ReceiveAsync(count)
+		}
+		if err != nil {
+			return "", err
+		}
+		started.lifecycle, started.persistent = persistentReceiver, persistentReceiver
+	} else {
+		subscriptions := make([]resource.Subscription, len(r.Subscriptions))
+		for i, subscription := range r.Subscriptions {
+			subscriptions[i] = resource.TopicSubscriptionOf(subscription)
+		}
+		directReceiver, err := env.Service.CreateDirectMessageReceiverBuilder().WithSubscriptions(subscriptions...).Build()
+		if err == nil {
+			err = directReceiver.Start()
+		}
+		if err == nil {
+			err = directReceiver.ReceiveAsync(count)
+		}
+		if err != nil {
+			return "", err
+		}
+		started.lifecycle = directReceiver
+	}
+	env.receivers[r.Name] = started
+	return "receiving", nil
+}
+
+// queueReceiver returns the queue receiver started under the name
+func (e *Env) queueReceiver(name string) (solace.PersistentMessageReceiver, error) {
+	r, ok := e.receivers[name]
+	if !ok {
+		return nil, fmt.Errorf("no receiver %s", name)
+	}
+	if r.persistent == nil {
+		return nil, fmt.Errorf("receiver %s does not receive from a queue, only queue receivers can be paused", name)
+	}
+	return r.persistent, nil
+}
+
+// Pause pauses the delivery of the messages to a queue receiver, they stay on the queue until it is resumed
+type Pause struct {
+	Receiver string `yaml:"receiver"`
+}
+
+func (p Pause) Describe() string { return fmt.Sprintf("pausing receiver %s", p.Receiver) }
+
+func (p Pause) Run(_ context.Context, env *Env) (string, error) {
+	r, err := env.queueReceiver(p.Receiver)
+	if err != nil {
+		return "", err
+	}
+	return "paused", r.Pause()
+}
+
+// Resume resumes the delivery of the messages to a paused queue receiver
+type Resume struct {
+	Receiver string `yaml:"receiver"`
+}
+
+func (r Resume) Describe() string { return fmt.Sprintf("resuming receiver %s", r.Receiver) }
+
+func (r Resume) Run(_ context.Context, env *Env) (string, error) {
+	receiver, err := env.queueReceiver(r.Receiver)
+	if err != nil {
+		return "", err
+	}
+	return "resumed", receiver.Resume()
+}
+
+// KillConnection has the broker disconnect a client through the SEMP action API, as a network failure would, and
+// waits for the API to reconnect
+type KillConnection struct {
+	// Client is the name of the client to disconnect, the client of the service of the scenario when empty
+	Client string `yaml:"client"`
+	// Reconnect is how long to wait for the service of the scenario to reconnect, 0 does not wait
+	Reconnect time.Duration `yaml:"reconnect"`
+}
+
+func (k KillConnection) Describe() string {
+	if k.Client == "" {
+		return "killing the connection of the scenario"
+	}
+	return fmt.Sprintf("killing the connection of client %s", k.Client)
+}
+
+func (k KillConnection) Run(ctx context.Context, env *Env) (string, error) {
+	client := k.Client
+	if client == "" {
+		client = env.Service.GetApplicationID()
+	}
+	reconnected := make(chan struct{}, 1)
+	if k.Reconnect > 0 {
+		id := env.Service.AddReconnectionListener(func(solace.ServiceEvent) {
+			select {
+			case reconnected <- struct{}{}:
+			default:
+			}
+		})
+		defer env.Service.RemoveReconnectionListener(id)
+	}
+
+	endpoint := fmt.Sprintf("%s/SEMP/v2/action/msgVpns/%s/clients/%s/disconnect",
+		env.SEMP.URL, url.PathEscape(env.SEMP.VPN), url.PathEscape(client))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, strings.NewReader("{}"))
+	if err != nil {
+		return "", err
+	}
+	request.SetBasicAuth(env.SEMP.Username, env.SEMP.Password)
+	request.Header.Set("Content-Type", "application/json")
+	httpClient := env.SEMP.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not disconnect client %s: %s", client, response.Status)
+	}
+	if k.Reconnect <= 0 {
+		return fmt.Sprintf("client %s disconnected", client), nil
+	}
+
+	start := time.Now()
+	select {
+	case <-reconnected:
+		return fmt.Sprintf("client %s disconnected, reconnected after %s", client, time.Since(start).Round(time.Millisecond)), nil
+	case <-time.After(k.Reconnect):
+		return "", fmt.Errorf("client %s disconnected, not reconnected after %s", client, k.Reconnect)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// bounds checks a value against a minimum and an optional maximum
+type bounds struct {
+	min int64
+	max *int64
+}
+
+func (b bounds) String() string {
+	switch {
+	case b.max == nil:
+		return fmt.Sprintf("at least %d", b.min)
+	case b.min == *b.max:
+		return fmt.Sprintf("exactly %d", b.min)
+	case b.min == 0:
+		return fmt.Sprintf("at most %d", *b.max)
+	}
+	return fmt.Sprintf("between %d and %d", b.min, *b.max)
+}
+
+func (b bounds) contains(value int64) bool {
+	return value >= b.min && (b.max == nil || value <= *b.max)
+}
+
+// eventually checks the value until it is within the bounds or the delay elapsed
+func eventually(ctx context.Context, b bounds, within time.Duration, value func() (int64, error)) (int64, error) {
+	deadline := time.Now().Add(within)
+	for {
+		current, err := value()
+		if err != nil || b.contains(current) || !time.Now().Before(deadline) {
+			return current, err
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return current, ctx.Err()
+		}
+	}
+}
+
+// AssertQueueDepth asserts the number of messages spooled on a queue, read through the SEMP monitor API
+type AssertQueueDepth struct {
+	Queue string `yaml:"queue"`
+	Min   int64  `yaml:"min"`
+	// Max is the maximum number of messages, no maximum when nil
+	Max *int64 `yaml:"max"`
+	// Within is how long the assertion is retried until it holds, 0 checks once
+	Within time.Duration `yaml:"within"`
+}
+
+func (a AssertQueueDepth) bounds() bounds { return bounds{a.Min, a.Max} }
+
+func (a AssertQueueDepth) Describe() string {
+	return fmt.Sprintf("asserting %s message(s) spooled on queue %s", a.bounds(), a.Queue)
+}
+
+func (a AssertQueueDepth) Run(ctx context.Context, env *Env) (string, error) {
+	env.watcher.AddQueue(a.Queue)
+	spooled, err := eventually(ctx, a.bounds(), a.Within, func() (int64, error) {
+		if err := env.watcher.Poll(ctx); err != nil {
+			return 0, err
+		}
+		sample, _ := env.watcher.Sample(a.Queue)
+		return sample.Spooled, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !a.bounds().contains(spooled) {
+		return "", fmt.Errorf("%d message(s) spooled on queue %s, expected %s", spooled, a.Queue, a.bounds())
+	}
+	return fmt.Sprintf("%d message(s) spooled", spooled), nil
+}
+
+// AssertReceived asserts the number of messages a receiver received since it started
+type AssertReceived struct {
+	Receiver string `yaml:"receiver"`
+	Min      int64  `yaml:"min"`
+	// Max is the maximum number of messages, no maximum when nil
+	Max *int64 `yaml:"max"`
+	// Within is how long the assertion is retried until it holds, 0 checks once
+	Within time.Duration `yaml:"within"`
+}
+
+func (a AssertReceived) bounds() bounds { return bounds{a.Min, a.Max} }
+
+func (a AssertReceived) Describe() string {
+	return fmt.Sprintf("asserting receiver %s received %s message(s)", a.Receiver, a.bounds())
+}
+
+func (a AssertReceived) Run(ctx context.Context, env *Env) (string, error) {
+	r, ok := env.receivers[a.Receiver]
+	if !ok {
+		return "", fmt.Errorf("no receiver %s", a.Receiver)
+	}
+	received, err := eventually(ctx, a.bounds(), a.Within, func() (int64, error) { return r.received.Load(), nil })
+	if err != nil {
+		return "", err
+	}
+	if !a.bounds().contains(received) {
+		return "", fmt.Errorf("receiver %s received %d message(s), expected %s", a.Receiver, received, a.bounds())
+	}
+	return fmt.Sprintf("%d message(s) received", received), nil
+}
@@ -0,0 +1,115 @@
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// file is the YAML form of a scenario
+type file struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Steps       []stepSpec `yaml:"steps"`
+}
+
+// stepSpec is the YAML form of a timed step, exactly one of the steps is set
+type stepSpec struct {
+	At               time.Duration     `yaml:"at"`
+	Say              *string           `yaml:"say"`
+	Wait             *time.Duration    `yaml:"wait"`
+	Publish          *Publish          `yaml:"publish"`
+	Receive          *Receive          `yaml:"receive"`
+	Pause            *string           `yaml:"pause"`
+	Resume           *string           `yaml:"resume"`
+	KillConnection   *KillConnection   `yaml:"kill-connection"`
+	AssertQueueDepth *AssertQueueDepth `yaml:"assert-queue-depth"`
+	AssertReceived   *AssertReceived   `yaml:"assert-received"`
+}
+
+func (s stepSpec) step() (Step, error) {
+	var steps []Step
+	if s.Say != nil {
+		steps = append(steps, Say{Text: *s.Say})
+	}
+	if s.Wait != nil {
+		steps = append(steps, Wait{Duration: *s.Wait})
+	}
+	if s.Publish != nil {
+		if s.Publish.Topic == "" {
+			return nil, fmt.Errorf("publish needs a topic")
+		}
+		steps = append(steps, *s.Publish)
+	}
+	if s.Receive != nil {
+		if s.Receive.Name == "" || (s.Receive.Queue == "") == (len(s.Receive.Subscriptions) == 0) {
+			return nil, fmt.Errorf("receive needs a name and either a queue or subscriptions")
+		}
+		steps = append(steps, *s.Receive)
+	}
+	if s.Pause != nil {
+		steps = append(steps, Pause{Receiver: *s.Pause})
+	}
+	if s.Resume != nil {
+		steps = append(steps, Resume{Receiver: *s.Resume})
+	}
+	if s.KillConnection != nil {
+		steps = append(steps, *s.KillConnection)
+	}
+	if s.AssertQueueDepth != nil {
+		if s.AssertQueueDepth.Queue == "" {
+			return nil, fmt.Errorf("assert-queue-depth needs a queue")
+		}
+		steps = append(steps, *s.AssertQueueDepth)
+	}
+	if s.AssertReceived != nil {
+		steps = append(steps, *s.AssertReceived)
+	}
+	if len(steps) != 1 {
+		return nil, fmt.Errorf("expected one of say, wait, publish, receive, pause, resume, kill-connection, " +
+			"assert-queue-depth or assert-received")
+	}
+	return steps[0], nil
+}
+
+// Load reads a YAML scenario, e.g.
+//
+//	name: slow consumer
+//	steps:
+//	  - receive: {name: orders, queue: orders-queue}
+//	  - pause: orders
+//	  - publish: {topic: solace/samples/orders, count: 100, persistent: true}
+//	  - at: 5s
+//	    assert-queue-depth: {queue: orders-queue, min: 100}
+//	  - resume: orders
+//	  - assert-queue-depth: {queue: orders-queue, max: 0, within: 10s}
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	if len(f.Steps) == 0 {
+		return nil, fmt.Errorf("%s has no steps", path)
+	}
+	s := &Scenario{Name: f.Name, Description: f.Description}
+	if s.Name == "" {
+		s.Name = path
+	}
+	for n, spec := range f.Steps {
+		step, err := spec.step()
+		if err != nil {
+			return nil, fmt.Errorf("%s: step %d: %w", path, n+1, err)
+		}
+		s.Steps = append(s.Steps, Timed{At: spec.At, Step: step})
+	}
+	return s, nil
+}
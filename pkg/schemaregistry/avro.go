@@ -0,0 +1,131 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// magicByte starts the payloads of the wire format, its version
+const magicByte = 0
+
+// headerLength is the length of the magic byte and the schema ID
+const headerLength = 5
+
+// ErrNotWireFormat is returned by Deserialize for a payload not starting with the magic byte and a schema ID
+var ErrNotWireFormat = errors.New("not in the schema registry wire format")
+
+// SchemaIDOf returns the ID of the schema of a payload in the wire format
+func SchemaIDOf(payload []byte) (int, bool) {
+	if len(payload) < headerLength || payload[0] != magicByte {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint32(payload[1:headerLength])), true
+}
+
+// Serializer encodes values with one schema, registered under a subject
+type Serializer struct {
+	id    int
+	codec *goavro.Codec
+}
+
+// NewSerializer parses the Avro schema, a JSON document, and registers it under the subject
+func NewSerializer(ctx context.Context, client *Client, subject, schema string) (*Serializer, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	// the schema is registered as written, the canonical form drops the logical types and the defaults the readers
+	// need; it only finds a schema already registered by the client whatever its formatting
+	id, err := client.register(ctx, subject, schema, codec.CanonicalSchema())
+	if err != nil {
+		return nil, err
+	}
+	return &Serializer{id: id, codec: codec}, nil
+}
+
+// SchemaID returns the ID of the schema, as registered
+func (s *Serializer) SchemaID() int {
+	return s.id
+}
+
+// Serialize encodes the value in the wire format. The value is in the native form of goavro: a
+// map[string]interface{} for a record, a map[string]interface{}{"type": value} for a non-null union.
+func (s *Serializer) Serialize(value interface{}) ([]byte, error) {
+	header := make([]byte, headerLength, 256)
+	binary.BigEndian.PutUint32(header[1:], uint32(s.id))
+	return s.codec.BinaryFromNative(header, value)
+}
+
+// Deserializer decodes payloads in the wire format with the schema of their ID, each schema is fetched from the
+// registry once. It is safe for concurrent use.
+type Deserializer struct {
+	client *Client
+
+	mu     sync.Mutex
+	codecs map[int]*goavro.Codec
+}
+
+// NewDeserializer creates a deserializer fetching the schemas from the registry
+func NewDeserializer(client *Client) *Deserializer {
+	return &Deserializer{client: client, codecs: map[int]*goavro.Codec{}}
+}
+
+// Deserialize decodes the payload and returns the value, in the native form of goavro, and the ID of its schema
+func (d *Deserializer) Deserialize(ctx context.Context, payload []byte) (interface{}, int, error) {
+	id, ok := SchemaIDOf(payload)
+	if !ok {
+		return nil, 0, ErrNotWireFormat
+	}
+	codec, err := d.codec(ctx, id)
+	if err != nil {
+		return nil, id, err
+	}
+	value, rest, err := codec.NativeFromBinary(payload[headerLength:])
+	if err != nil {
+		return nil, id, fmt.Errorf("invalid payload for schema %d: %w", id, err)
+	}
+	if len(rest) > 0 {
+		return nil, id, fmt.Errorf("invalid payload for schema %d: %d bytes left after the value", id, len(rest))
+	}
+	return value, id, nil
+}
+
+// JSON decodes the payload as Deserialize does, and returns the value in the JSON encoding of Avro
+func (d *Deserializer) JSON(ctx context.Context, payload []byte) ([]byte, int, error) {
+	value, id, err := d.Deserialize(ctx, payload)
+	if err != nil {
+		return nil, id, err
+	}
+	codec, err := d.codec(ctx, id)
+	if err != nil {
+		return nil, id, err
+	}
+	text, err := codec.TextualFromNative(nil, value)
+	return text, id, err
+}
+
+func (d *Deserializer) codec(ctx context.Context, id int) (*goavro.Codec, error) {
+	d.mu.Lock()
+	codec, ok := d.codecs[id]
+	d.mu.Unlock()
+	if ok {
+		return codec, nil
+	}
+	schema, err := d.client.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	codec, err = goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema %d: %w", id, err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.codecs[id] = codec
+	return codec, nil
+}
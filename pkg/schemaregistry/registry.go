@@ -0,0 +1,156 @@
+// Package schemaregistry serializes payloads as Avro with their schemas registered in a Confluent Schema Registry, in
+// the wire format of the Confluent serializers: a 0 magic byte, the ID of the schema in 4 big-endian bytes, then the
+// Avro binary encoding of the value. The messages published by a Solace application are read by the Kafka
+// deserializers and the Kafka Connect converters as they are, through a connector or a bridge, and the other way
+// round.
+//
+//	client := schemaregistry.New("http://localhost:8081")
+//	serializer, err := schemaregistry.NewSerializer(ctx, client, schemaregistry.ValueSubject(topic), schema)
+//	payload, err := serializer.Serialize(map[string]interface{}{"orderId": "order-1", "amount": 42.5})
+//
+//	deserializer := schemaregistry.NewDeserializer(client)
+//	value, schemaID, err := deserializer.Deserialize(ctx, payload)
+//
+// The schemas are fetched once per ID and cached, the registry is not queried for every message.
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the media type of the registry REST API
+const ContentType = "application/vnd.schemaregistry.v1+json"
+
+// ValueSubject returns the subject of the values published on a topic, per the default TopicNameStrategy of the
+// Confluent serializers: the topic with a -value suffix
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+// Client is a client of the REST API of the registry, it caches the schemas and is safe for concurrent use
+type Client struct {
+	// URL is the base URL of the registry, e.g. http://localhost:8081
+	URL string
+	// Username and Password authenticate with HTTP basic authentication when the username is set, e.g. with the API
+	// key and secret of Confluent Cloud
+	Username string
+	Password string
+	Client   *http.Client
+
+	mu      sync.Mutex
+	schemas map[int]string
+	ids     map[string]int
+}
+
+// New creates a client of the registry at the URL
+func New(registryURL string) *Client {
+	return &Client{
+		URL:     strings.TrimSuffix(registryURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		schemas: map[int]string{},
+		ids:     map[string]int{},
+	}
+}
+
+// registryError is the body of the error responses
+type registryError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// Register registers the schema under the subject, as a new version unless the subject already has it, and returns its
+// ID. A schema incompatible with the previous versions of the subject is refused, per the compatibility level of the
+// subject.
+func (c *Client) Register(ctx context.Context, subject, schema string) (int, error) {
+	return c.register(ctx, subject, schema, schema)
+}
+
+// register registers the schema as Register does, the IDs are cached by subject and cacheKey, a form of the schema
+// that is the same whatever its formatting, e.g. its canonical form
+func (c *Client) register(ctx context.Context, subject, schema, cacheKey string) (int, error) {
+	key := subject + "\x00" + cacheKey
+	c.mu.Lock()
+	id, ok := c.ids[key]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, err
+	}
+	var registered struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", body, &registered); err != nil {
+		return 0, fmt.Errorf("could not register the schema of subject %s: %w", subject, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[key] = registered.ID
+	c.schemas[registered.ID] = schema
+	return registered.ID, nil
+}
+
+// Schema returns the schema of the ID
+func (c *Client) Schema(ctx context.Context, id int) (string, error) {
+	c.mu.Lock()
+	schema, ok := c.schemas[id]
+	c.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	var fetched struct {
+		Schema string `json:"schema"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &fetched); err != nil {
+		return "", fmt.Errorf("could not fetch schema %d: %w", id, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemas[id] = fetched.Schema
+	return fetched.Schema, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, method, c.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", ContentType)
+	if body != nil {
+		request.Header.Set("Content-Type", ContentType)
+	}
+	if c.Username != "" {
+		request.SetBasicAuth(c.Username, c.Password)
+	}
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		var failure registryError
+		if json.NewDecoder(response.Body).Decode(&failure) == nil && failure.Message != "" {
+			return fmt.Errorf("%s: %s (error code %d)", response.Status, failure.Message, failure.ErrorCode)
+		}
+		return fmt.Errorf("%s", response.Status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s, invalid registry response: %w", response.Status, err)
+	}
+	return nil
+}
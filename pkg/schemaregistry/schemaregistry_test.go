@@ -0,0 +1,159 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry serves the register and fetch endpoints of the REST API, with one ID per schema as written
+type fakeRegistry struct {
+	mu sync.Mutex
+	// registered are the schemas registered, in order, their ID is their index plus one
+	registered []string
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/subjects/"):
+		var body struct {
+			Schema string `json:"schema"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		f.registered = append(f.registered, body.Schema)
+		json.NewEncoder(w).Encode(map[string]int{"id": len(f.registered)})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/schemas/ids/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/schemas/ids/"))
+		if id < 1 || id > len(f.registered) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(registryError{ErrorCode: 40403, Message: "Schema not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": f.registered[id-1]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeRegistry) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.registered)
+}
+
+const orderSchema = `{"type": "record", "name": "Order", "fields": [
+	{"name": "orderId", "type": "string"},
+	{"name": "amount", "type": "double", "default": 0}
+]}`
+
+func TestWireFormat(t *testing.T) {
+	registry := &fakeRegistry{}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	ctx := context.Background()
+
+	serializer, err := NewSerializer(ctx, New(server.URL), "orders-value", orderSchema)
+	if err != nil {
+		t.Fatalf("could not create the serializer: %s", err)
+	}
+	payload, err := serializer.Serialize(map[string]interface{}{"orderId": "a", "amount": 2.0})
+	if err != nil {
+		t.Fatalf("could not serialize the value: %s", err)
+	}
+	// the magic byte, the ID 1 in 4 big-endian bytes, then the string a and the double 2 of the record
+	expected := []byte{0, 0, 0, 0, 1, 2, 'a', 0, 0, 0, 0, 0, 0, 0, 0x40}
+	if !bytes.Equal(payload, expected) {
+		t.Fatalf("serialized % x, expected % x", payload, expected)
+	}
+
+	// a deserializer of its own, with nothing cached, fetches the schema by ID
+	deserializer := NewDeserializer(New(server.URL))
+	tests := []struct {
+		name    string
+		payload []byte
+		id      int
+		json    string
+		err     error
+		errText string
+	}{
+		{name: "value", payload: payload, id: 1, json: `{"orderId":"a","amount":2}`},
+		{name: "empty", payload: nil, err: ErrNotWireFormat},
+		{name: "short header", payload: payload[:4], err: ErrNotWireFormat},
+		{name: "magic byte", payload: append([]byte{1}, payload[1:]...), err: ErrNotWireFormat},
+		{name: "bytes left", payload: append(append([]byte{}, payload...), 0), id: 1, errText: "invalid payload for schema 1: 1 bytes left after the value"},
+		{name: "truncated value", payload: payload[:len(payload)-1], id: 1, errText: "invalid payload for schema 1: "},
+		{name: "unknown schema", payload: []byte{0, 0, 0, 0, 9, 0}, id: 9, errText: "could not fetch schema 9: 404 Not Found: Schema not found (error code 40403)"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			text, id, err := deserializer.JSON(ctx, test.payload)
+			if id != test.id {
+				t.Errorf("schema ID %d, expected %d", id, test.id)
+			}
+			switch {
+			case test.err != nil:
+				if !errors.Is(err, test.err) {
+					t.Errorf("got error %v, expected %v", err, test.err)
+				}
+			case test.errText != "":
+				if err == nil || !strings.HasPrefix(err.Error(), test.errText) {
+					t.Errorf("got error %v, expected %q", err, test.errText)
+				}
+			case err != nil:
+				t.Errorf("could not deserialize the payload: %s", err)
+			case string(text) != test.json:
+				t.Errorf("deserialized %s, expected %s", text, test.json)
+			}
+		})
+	}
+}
+
+func TestRegisterCache(t *testing.T) {
+	// reformatted is the order schema written another way, with the same canonical form
+	reformatted := `{"name":"Order","type":"record","fields":[{"name":"orderId","type":"string"},{"name":"amount","type":"double","default":0}]}`
+	tests := []struct {
+		name    string
+		subject string
+		schema  string
+		// registered is whether the registry is called, rather than the ID found in the cache
+		registered bool
+	}{
+		{"first", "orders-value", orderSchema, true},
+		{"same schema", "orders-value", orderSchema, false},
+		{"same canonical form", "orders-value", reformatted, false},
+		{"other subject", "refunds-value", orderSchema, true},
+		{"other schema", "orders-value", `{"type": "string"}`, true},
+	}
+
+	registry := &fakeRegistry{}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	client := New(server.URL)
+	for _, test := range tests {
+		before := registry.count()
+		serializer, err := NewSerializer(context.Background(), client, test.subject, test.schema)
+		if err != nil {
+			t.Fatalf("%s: could not create the serializer: %s", test.name, err)
+		}
+		if registered := registry.count() > before; registered != test.registered {
+			t.Errorf("%s: registered %t, expected %t", test.name, registered, test.registered)
+		}
+		if test.registered && registry.registered[serializer.SchemaID()-1] != test.schema {
+			t.Errorf("%s: registered %s, expected the schema as written", test.name, registry.registered[serializer.SchemaID()-1])
+		}
+	}
+}
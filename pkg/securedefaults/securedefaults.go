@@ -0,0 +1,168 @@
+// Package securedefaults builds messaging services that only connect over TLS with the broker certificate fully
+// validated. It wraps the messaging service builder: the strict settings are applied first, the configuration of
+// the sample is applied over them, and Build refuses to create the service when the result weakens them, instead
+// of connecting insecurely because of a forgotten development setting.
+//
+// Refused settings:
+//   - a plaintext host (tcp:// or ws:// instead of tcps:// or wss://)
+//   - certificate validation disabled, expired certificates accepted or the server name not validated
+//   - a downgrade to plaintext after the login (TransportLayerSecurityPropertyProtocolDowngradeTo)
+//   - SSLv3, TLSv1 or TLSv1.1 not excluded
+//   - no trust store
+package securedefaults
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// ErrInsecure is wrapped by the errors returned for the refused settings
+var ErrInsecure = errors.New("insecure configuration refused")
+
+// WeakProtocols are the protocols excluded from the TLS handshake
+var WeakProtocols = []config.TransportSecurityProtocol{
+	config.TransportSecurityProtocolSSLv3,
+	config.TransportSecurityProtocolTLSv1,
+	config.TransportSecurityProtocolTLSv1_1,
+}
+
+// Strategy returns the strict transport security strategy: full certificate validation against the trust store and
+// the weak protocols excluded
+func Strategy(trustStorePath string) config.TransportSecurityStrategy {
+	return config.NewTransportSecurityStrategy().
+		WithCertificateValidation(false, true, trustStorePath, "").
+		WithExcludedProtocols(WeakProtocols...)
+}
+
+// Builder builds a messaging service with the strict settings, its methods mirror the messaging service builder
+type Builder struct {
+	properties config.ServicePropertyMap
+	// builder options without a property equivalent, applied as is
+	options []func(solace.MessagingServiceBuilder) solace.MessagingServiceBuilder
+}
+
+// NewMessagingServiceBuilder returns a builder starting from the strict settings, with the trust store at
+// trustStorePath
+func NewMessagingServiceBuilder(trustStorePath string) *Builder {
+	b := &Builder{properties: config.ServicePropertyMap{}}
+	return b.WithTransportSecurityStrategy(Strategy(trustStorePath))
+}
+
+// FromConfigurationProvider applies the properties over the current ones
+func (b *Builder) FromConfigurationProvider(provider config.ServicePropertiesConfigurationProvider) *Builder {
+	for property, value := range provider.GetConfiguration() {
+		b.properties[property] = value
+	}
+	return b
+}
+
+// WithTransportSecurityStrategy applies the strategy over the current properties, it is checked like them
+func (b *Builder) WithTransportSecurityStrategy(strategy config.TransportSecurityStrategy) *Builder {
+	return b.FromConfigurationProvider(config.ServicePropertyMap(strategy.ToProperties()))
+}
+
+// WithAuthenticationStrategy sets the authentication of the service
+func (b *Builder) WithAuthenticationStrategy(strategy config.AuthenticationStrategy) *Builder {
+	b.options = append(b.options, func(builder solace.MessagingServiceBuilder) solace.MessagingServiceBuilder {
+		return builder.WithAuthenticationStrategy(strategy)
+	})
+	return b
+}
+
+// WithReconnectionRetryStrategy sets the reconnection retries of the service
+func (b *Builder) WithReconnectionRetryStrategy(strategy config.RetryStrategy) *Builder {
+	b.options = append(b.options, func(builder solace.MessagingServiceBuilder) solace.MessagingServiceBuilder {
+		return builder.WithReconnectionRetryStrategy(strategy)
+	})
+	return b
+}
+
+// Build checks the properties and builds the messaging service, an error wrapping ErrInsecure lists every refused
+// setting
+func (b *Builder) Build() (solace.MessagingService, error) {
+	if err := Check(b.properties); err != nil {
+		return nil, err
+	}
+	builder := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(b.properties)
+	for _, option := range b.options {
+		builder = option(builder)
+	}
+	return builder.Build()
+}
+
+// Check returns an error wrapping ErrInsecure when the properties weaken the strict settings
+func Check(properties config.ServicePropertyMap) error {
+	var problems []string
+
+	hosts, _ := properties[config.TransportLayerPropertyHost].(string)
+	if strings.TrimSpace(hosts) == "" {
+		problems = append(problems, "no host")
+	}
+	for _, host := range strings.Split(hosts, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" && !strings.HasPrefix(host, "tcps://") && !strings.HasPrefix(host, "wss://") {
+			problems = append(problems, fmt.Sprintf("plaintext host %s, use tcps:// or wss://", host))
+		}
+	}
+
+	for _, validation := range []struct {
+		property    config.ServiceProperty
+		description string
+	}{
+		{config.TransportLayerSecurityPropertyCertValidated, "certificate validation disabled"},
+		{config.TransportLayerSecurityPropertyCertRejectExpired, "expired certificates accepted"},
+		{config.TransportLayerSecurityPropertyCertValidateServername, "server name validation disabled"},
+	} {
+		// unset means the API default, which validates
+		if value, ok := properties[validation.property]; ok && !isTrue(value) {
+			problems = append(problems, fmt.Sprintf("%s (%s=%v)", validation.description, validation.property, value))
+		}
+	}
+
+	if value, ok := properties[config.TransportLayerSecurityPropertyProtocolDowngradeTo]; ok && fmt.Sprint(value) != "" {
+		problems = append(problems, fmt.Sprintf("downgrade to %v after login", value))
+	}
+
+	excluded := strings.ToUpper(fmt.Sprint(properties[config.TransportLayerSecurityPropertyExcludedProtocols]))
+	for _, protocol := range WeakProtocols {
+		if !containsProtocol(excluded, string(protocol)) {
+			problems = append(problems, fmt.Sprintf("%s not excluded", protocol))
+		}
+	}
+
+	if path, _ := properties[config.TransportLayerSecurityPropertyTrustStorePath].(string); path == "" {
+		problems = append(problems, "no trust store")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInsecure, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// isTrue accepts booleans and their string form, as read from environment variables or configuration files
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(v)
+		return err == nil && b
+	}
+	return false
+}
+
+func containsProtocol(list, protocol string) bool {
+	for _, excluded := range strings.Split(list, ",") {
+		if strings.TrimSpace(excluded) == strings.ToUpper(protocol) {
+			return true
+		}
+	}
+	return false
+}
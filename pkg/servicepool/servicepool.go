@@ -0,0 +1,435 @@
+// Package servicepool owns a set of connected messaging services and hands out publishers and receivers on them,
+// spreading the load of an application over several connections. A health check replaces the services that can
+// not recover on their own: the ones interrupted for good (reconnection attempts exhausted) and the ones found
+// disconnected while not reconnecting. The receivers added to the pool are created again on the replacement.
+//
+// Publishers are cached per service and should be asked for on every use (or batch) rather than kept: a publisher
+// of a replaced service is terminated.
+package servicepool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"solace.dev/go/messaging"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+)
+
+// DefaultHealthCheckInterval is how often the services are checked, unless WithHealthCheckInterval is given
+const DefaultHealthCheckInterval = 5 * time.Second
+
+// ErrNoHealthyService is returned when every service of the pool is down
+var ErrNoHealthyService = errors.New("no healthy messaging service in the pool")
+
+// ReceiverFactory creates and starts a receiver on the given service. It is called again with the new service when
+// the service the receiver was on is replaced.
+type ReceiverFactory func(messagingService solace.MessagingService) (solace.LifecycleControl, error)
+
+// Option customizes a pool
+type Option func(pool *Pool)
+
+// WithHealthCheckInterval sets how often the services are checked
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(pool *Pool) { pool.healthCheckInterval = interval }
+}
+
+// WithApplicationID names the services <applicationID>-<index> on the broker, the API generates the names otherwise
+func WithApplicationID(applicationID string) Option {
+	return func(pool *Pool) { pool.applicationID = applicationID }
+}
+
+// WithReconnectionRetryStrategy sets how long each service tries to reconnect on its own before it is replaced
+func WithReconnectionRetryStrategy(retryStrategy config.RetryStrategy) Option {
+	return func(pool *Pool) { pool.retryStrategy = &retryStrategy }
+}
+
+// MemberStatus describes a service of the pool
+type MemberStatus struct {
+	Index         int
+	ApplicationID string
+	Connected     bool
+	Reconnecting  bool
+	Healthy       bool
+	Replacements  int
+	Receivers     []string
+}
+
+type receiver struct {
+	name    string
+	factory ReceiverFactory
+	member  int
+}
+
+type member struct {
+	index int
+	// incremented for every new service, the listeners of a replaced service are ignored
+	generation int32
+	// set from the listeners of the service
+	reconnecting int32
+	interrupted  int32
+	healthy      int32
+
+	mu                  sync.Mutex
+	service             solace.MessagingService
+	directPublisher     solace.DirectMessagePublisher
+	persistentPublisher solace.PersistentMessagePublisher
+	receivers           map[string]solace.LifecycleControl
+	replacements        int
+}
+
+// Pool owns the messaging services, it is safe for concurrent use
+type Pool struct {
+	properties          config.ServicePropertyMap
+	healthCheckInterval time.Duration
+	applicationID       string
+	retryStrategy       *config.RetryStrategy
+
+	members []*member
+	next    uint32
+
+	mu        sync.Mutex
+	receivers []*receiver
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// New connects size messaging services with the given properties and starts the health check. It fails if any of
+// them can not connect.
+func New(properties config.ServicePropertyMap, size int, options ...Option) (*Pool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("pool size must be at least 1, got %d", size)
+	}
+	p := &Pool{properties: properties, healthCheckInterval: DefaultHealthCheckInterval, done: make(chan struct{})}
+	for _, option := range options {
+		option(p)
+	}
+
+	for index := 0; index < size; index++ {
+		m := &member{index: index, receivers: make(map[string]solace.LifecycleControl)}
+		if err := p.connect(m); err != nil {
+			p.Close(0)
+			return nil, fmt.Errorf("could not connect service %d of the pool: %w", index, err)
+		}
+		p.members = append(p.members, m)
+	}
+
+	p.wg.Add(1)
+	go p.healthCheck()
+	return p, nil
+}
+
+// connect builds and connects a new service for the member, the caller holds the member lock or owns the member
+func (p *Pool) connect(m *member) error {
+	builder := messaging.NewMessagingServiceBuilder().FromConfigurationProvider(p.properties)
+	if p.retryStrategy != nil {
+		builder = builder.WithReconnectionRetryStrategy(*p.retryStrategy)
+	}
+	var service solace.MessagingService
+	var err error
+	if p.applicationID != "" {
+		service, err = builder.BuildWithApplicationID(fmt.Sprintf("%s-%d", p.applicationID, m.index))
+	} else {
+		service, err = builder.Build()
+	}
+	if err != nil {
+		return err
+	}
+
+	generation := atomic.AddInt32(&m.generation, 1)
+	current := func() bool { return atomic.LoadInt32(&m.generation) == generation }
+	atomic.StoreInt32(&m.reconnecting, 0)
+	atomic.StoreInt32(&m.interrupted, 0)
+	service.AddReconnectionAttemptListener(func(event solace.ServiceEvent) {
+		if current() {
+			atomic.StoreInt32(&m.reconnecting, 1)
+		}
+	})
+	service.AddReconnectionListener(func(event solace.ServiceEvent) {
+		if current() {
+			atomic.StoreInt32(&m.reconnecting, 0)
+		}
+	})
+	service.AddServiceInterruptionListener(func(event solace.ServiceEvent) {
+		if !current() {
+			return
+		}
+		atomic.StoreInt32(&m.reconnecting, 0)
+		atomic.StoreInt32(&m.interrupted, 1)
+		atomic.StoreInt32(&m.healthy, 0)
+	})
+
+	if err := service.Connect(); err != nil {
+		return err
+	}
+	m.service = service
+	atomic.StoreInt32(&m.healthy, 1)
+	return nil
+}
+
+// pick returns the next healthy member, round robin
+func (p *Pool) pick() (*member, error) {
+	start := atomic.AddUint32(&p.next, 1)
+	for i := 0; i < len(p.members); i++ {
+		m := p.members[(int(start)+i)%len(p.members)]
+		if atomic.LoadInt32(&m.healthy) == 1 {
+			return m, nil
+		}
+	}
+	return nil, ErrNoHealthyService
+}
+
+// lockHealthy picks the next healthy member and locks it, the member may have failed between the two
+func (p *Pool) lockHealthy() (*member, error) {
+	m, err := p.pick()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	if m.service == nil || atomic.LoadInt32(&m.healthy) == 0 {
+		m.mu.Unlock()
+		return nil, ErrNoHealthyService
+	}
+	return m, nil
+}
+
+// Service returns a healthy messaging service, e.g. to build a message or a publisher with specific settings.
+// Publishers and receivers built from it are not managed by the pool.
+func (p *Pool) Service() (solace.MessagingService, error) {
+	m, err := p.lockHealthy()
+	if err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+	return m.service, nil
+}
+
+// DirectPublisher returns the started direct publisher of a healthy service
+func (p *Pool) DirectPublisher() (solace.DirectMessagePublisher, error) {
+	m, err := p.lockHealthy()
+	if err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+	if m.directPublisher == nil {
+		publisher, err := m.service.CreateDirectMessagePublisherBuilder().Build()
+		if err != nil {
+			return nil, err
+		}
+		if err := publisher.Start(); err != nil {
+			return nil, err
+		}
+		m.directPublisher = publisher
+	}
+	return m.directPublisher, nil
+}
+
+// PersistentPublisher returns the started persistent publisher of a healthy service. The publisher is shared: use
+// PublishAwaitAcknowledgement, or a publish context with a receipt listener set once per publisher.
+func (p *Pool) PersistentPublisher() (solace.PersistentMessagePublisher, error) {
+	m, err := p.lockHealthy()
+	if err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+	if m.persistentPublisher == nil {
+		publisher, err := m.service.CreatePersistentMessagePublisherBuilder().Build()
+		if err != nil {
+			return nil, err
+		}
+		if err := publisher.Start(); err != nil {
+			return nil, err
+		}
+		m.persistentPublisher = publisher
+	}
+	return m.persistentPublisher, nil
+}
+
+// AddReceiver creates a receiver with the factory on the healthy service with the fewest receivers, and again on
+// its replacement if that service is replaced. The name identifies the receiver in RemoveReceiver and Status.
+func (p *Pool) AddReceiver(name string, factory ReceiverFactory) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.receivers {
+		if r.name == name {
+			return fmt.Errorf("receiver %s already added", name)
+		}
+	}
+
+	var target *member
+	for _, m := range p.members {
+		if atomic.LoadInt32(&m.healthy) == 0 {
+			continue
+		}
+		m.mu.Lock()
+		fewer := target == nil || len(m.receivers) < len(target.receivers)
+		m.mu.Unlock()
+		if fewer {
+			target = m
+		}
+	}
+	if target == nil {
+		return ErrNoHealthyService
+	}
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if target.service == nil {
+		return ErrNoHealthyService
+	}
+	started, err := factory(target.service)
+	if err != nil {
+		return err
+	}
+	target.receivers[name] = started
+	p.receivers = append(p.receivers, &receiver{name: name, factory: factory, member: target.index})
+	return nil
+}
+
+// RemoveReceiver terminates the receiver and stops re-creating it
+func (p *Pool) RemoveReceiver(name string, gracePeriod time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range p.receivers {
+		if r.name != name {
+			continue
+		}
+		p.receivers = append(p.receivers[:i], p.receivers[i+1:]...)
+		m := p.members[r.member]
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		started, ok := m.receivers[name]
+		delete(m.receivers, name)
+		if ok {
+			return started.Terminate(gracePeriod)
+		}
+		return nil
+	}
+	return fmt.Errorf("no receiver %s", name)
+}
+
+func (p *Pool) healthCheck() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		for _, m := range p.members {
+			if p.needsReplacement(m) {
+				if err := p.replace(m); err != nil {
+					fmt.Printf("Could not replace service %d of the pool: %s\n", m.index, err)
+				}
+			}
+		}
+	}
+}
+
+func (p *Pool) needsReplacement(m *member) bool {
+	if atomic.LoadInt32(&m.interrupted) == 1 {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the API is reconnecting on its own, give it time
+	return m.service == nil || (!m.service.IsConnected() && atomic.LoadInt32(&m.reconnecting) == 0)
+}
+
+// replace tears down the service of the member and everything created from it, connects a new one and re-creates
+// the receivers of the member on it
+func (p *Pool) replace(m *member) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	atomic.StoreInt32(&m.healthy, 0)
+	m.teardown(0)
+	if err := p.connect(m); err != nil {
+		return err
+	}
+	m.replacements++
+
+	for _, r := range p.receivers {
+		if r.member != m.index {
+			continue
+		}
+		started, err := r.factory(m.service)
+		if err != nil {
+			// tried again on the next replacement, after the next failure
+			fmt.Printf("Could not re-create receiver %s on service %d of the pool: %s\n", r.name, m.index, err)
+			continue
+		}
+		m.receivers[r.name] = started
+	}
+	return nil
+}
+
+// teardown terminates the publishers and receivers and disconnects, the caller holds the member lock
+func (m *member) teardown(gracePeriod time.Duration) {
+	for name, started := range m.receivers {
+		started.Terminate(gracePeriod)
+		delete(m.receivers, name)
+	}
+	if m.directPublisher != nil {
+		m.directPublisher.Terminate(gracePeriod)
+		m.directPublisher = nil
+	}
+	if m.persistentPublisher != nil {
+		m.persistentPublisher.Terminate(gracePeriod)
+		m.persistentPublisher = nil
+	}
+	if m.service != nil {
+		m.service.Disconnect()
+		m.service = nil
+	}
+}
+
+// Status returns the state of every service of the pool
+func (p *Pool) Status() []MemberStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]MemberStatus, 0, len(p.members))
+	for _, m := range p.members {
+		m.mu.Lock()
+		status := MemberStatus{
+			Index:        m.index,
+			Reconnecting: atomic.LoadInt32(&m.reconnecting) == 1,
+			Healthy:      atomic.LoadInt32(&m.healthy) == 1,
+			Replacements: m.replacements,
+		}
+		if m.service != nil {
+			status.ApplicationID = m.service.GetApplicationID()
+			status.Connected = m.service.IsConnected()
+		}
+		for name := range m.receivers {
+			status.Receivers = append(status.Receivers, name)
+		}
+		m.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Close stops the health check, terminates the receivers and publishers, giving them the grace period to finish,
+// and disconnects every service
+func (p *Pool) Close(gracePeriod time.Duration) {
+	p.closeOnce.Do(func() { close(p.done) })
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		m.mu.Lock()
+		atomic.StoreInt32(&m.healthy, 0)
+		m.teardown(gracePeriod)
+		m.mu.Unlock()
+	}
+}
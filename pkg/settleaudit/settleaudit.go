@@ -0,0 +1,170 @@
+// Package settleaudit records the settlements of persistent messages to an audit log, one JSON object per line (NDJSON)
+// in a file rotated by size, so operators can reconstruct why messages were failed or rejected after the fact: the
+// message, the outcome, the time the handler took, the reason given by the handler and the error of the settlement.
+//
+//	audit, err := settleaudit.Open(settleaudit.Options{Path: "settlements.ndjson"})
+//	defer audit.Close()
+//	persistentReceiver.ReceiveAsync(audit.Handler("orders", persistentReceiver, func(inbound message.InboundMessage) (config.MessageSettlementOutcome, error) {
+//		if err := process(inbound); err != nil {
+//			return config.PersistentReceiverRejectedOutcome, err
+//		}
+//		return config.PersistentReceiverAcceptedOutcome, nil
+//	}))
+//
+// A nil *Log settles the messages without recording them, so the audit can be optional. Writing a record never fails
+// the settlement, write errors are logged through log/slog.
+package settleaudit
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// Record is a line of the audit log
+type Record struct {
+	Time     time.Time `json:"time"`
+	Receiver string    `json:"receiver"`
+	// MessageID is the application message ID, when the publisher set one
+	MessageID string `json:"message_id,omitempty"`
+	// ReplicationGroupMessageID identifies the message on the broker
+	ReplicationGroupMessageID string        `json:"replication_group_message_id,omitempty"`
+	Destination               string        `json:"destination"`
+	Redelivered               bool          `json:"redelivered"`
+	Outcome                   string        `json:"outcome"`
+	HandlerDuration           time.Duration `json:"handler_duration_ns"`
+	// Reason is the reason given by the handler for the outcome, usually the error that failed or rejected the message
+	Reason string `json:"reason,omitempty"`
+	// Error is the error returned by the settlement itself
+	Error string `json:"error,omitempty"`
+}
+
+// Options configure the audit file and its rotation
+type Options struct {
+	// Path of the audit file, the rotated files are kept next to it with a timestamp in their name
+	Path string
+	// MaxSizeMB is the size the file is rotated at, 100 MB by default
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept, 10 by default
+	MaxBackups int
+	// MaxAgeDays removes the rotated files older than the number of days, 0 keeps them regardless of their age
+	MaxAgeDays int
+	// Compress compresses the rotated files with gzip
+	Compress bool
+}
+
+// Log writes the records, it is safe for concurrent use
+type Log struct {
+	mu      sync.Mutex
+	out     io.Writer
+	encoder *json.Encoder
+}
+
+// Open opens the audit file of the options, appending to it when it exists
+func Open(options Options) (*Log, error) {
+	if options.MaxSizeMB <= 0 {
+		options.MaxSizeMB = 100
+	}
+	if options.MaxBackups <= 0 {
+		options.MaxBackups = 10
+	}
+	rotating := &lumberjack.Logger{
+		Filename:   options.Path,
+		MaxSize:    options.MaxSizeMB,
+		MaxBackups: options.MaxBackups,
+		MaxAge:     options.MaxAgeDays,
+		Compress:   options.Compress,
+		LocalTime:  true,
+	}
+	// open now rather than on the first record, to report an unwritable path early
+	if _, err := rotating.Write(nil); err != nil {
+		return nil, err
+	}
+	return New(rotating), nil
+}
+
+// New returns a log writing the records to the writer, without rotation
+func New(out io.Writer) *Log {
+	return &Log{out: out, encoder: json.NewEncoder(out)}
+}
+
+// Record writes a record
+func (l *Log) Record(record Record) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.encoder.Encode(record); err != nil {
+		slog.Warn("settlement audit record not written", "error", err, "message_id", record.MessageID)
+	}
+}
+
+// Settle settles the message with the outcome and records it. The handler duration is the time since started,
+// reason is the reason of the outcome, nil for an ACCEPTED one. It returns the error of the settlement.
+func (l *Log) Settle(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage,
+	outcome config.MessageSettlementOutcome, started time.Time, reason error) error {
+	// measured before settling, the time the handler took to decide
+	handlerDuration := time.Since(started)
+	err := persistentReceiver.Settle(inbound, outcome)
+	if l == nil {
+		return err
+	}
+	record := Record{
+		Time:            time.Now(),
+		Receiver:        receiver,
+		Destination:     inbound.GetDestinationName(),
+		Redelivered:     inbound.IsRedelivered(),
+		Outcome:         string(outcome),
+		HandlerDuration: handlerDuration,
+	}
+	if id, ok := inbound.GetApplicationMessageID(); ok {
+		record.MessageID = id
+	}
+	if id, ok := inbound.GetReplicationGroupMessageID(); ok && id != nil {
+		record.ReplicationGroupMessageID = id.String()
+	}
+	if reason != nil {
+		record.Reason = reason.Error()
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	l.Record(record)
+	return err
+}
+
+// SettlingHandler handles a message and returns the outcome to settle it with, and the reason of the outcome
+type SettlingHandler func(inbound message.InboundMessage) (config.MessageSettlementOutcome, error)
+
+// Handler returns a message handler running the settling handler, then settling the message with the outcome it
+// returns and recording the settlement. The receiver must be built with support for the outcomes the handler returns.
+func (l *Log) Handler(receiver string, persistentReceiver solace.PersistentMessageReceiver, handler SettlingHandler) solace.MessageHandler {
+	return func(inbound message.InboundMessage) {
+		started := time.Now()
+		outcome, reason := handler(inbound)
+		if err := l.Settle(receiver, persistentReceiver, inbound, outcome, started, reason); err != nil {
+			slog.Warn("settlement failed", "receiver", receiver, "outcome", outcome, "error", err)
+		}
+	}
+}
+
+// Close closes the audit file, a log writing to a writer that is not a closer has nothing to close
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if closer, ok := l.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+// Package statsd sends the metrics of the samples over UDP in the StatsD format, or in the DogStatsD one with tags,
+// for stacks built on Datadog or another StatsD agent rather than Prometheus. It emits the same metrics as
+// pkg/promexporter, under the same names with dots:
+//
+//   - the API metrics of the messaging services, as solace.api.<metric> counters incremented by their change every
+//     flush interval (see pkg/metricsnap), and the solace.service.connected gauge
+//   - the reconnection attempts, reconnections and interruptions of the services, solace.service.events
+//   - for the receivers whose message handler is wrapped by Handler: solace.receiver.received,
+//     solace.receiver.in_handler and the solace.receiver.handler timing
+//   - the settlement outcomes of the persistent messages, solace.receiver.settlements
+//
+// With DogStatsD the service, receiver, event, outcome and result are tags. Plain StatsD has no tags, their values
+// are put in the name instead, after the prefix: solace.<service>.api.<metric>, solace.<receiver>.receiver.received.
+//
+//	emitter, err := statsd.New("localhost:8125", statsd.WithDogStatsD())
+//	defer emitter.Close()
+//	emitter.AddService("receiver", messagingService)
+//	persistentReceiver.ReceiveAsync(emitter.Handler("orders", handler))
+//
+// The metrics are buffered and sent every flush interval, or as soon as a datagram is full.
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/metricsnap"
+	"solace.dev/go/messaging/pkg/solace"
+	"solace.dev/go/messaging/pkg/solace/config"
+	"solace.dev/go/messaging/pkg/solace/message"
+)
+
+// DefaultFlushInterval is how often the metrics are sent, unless WithFlushInterval is given
+const DefaultFlushInterval = 10 * time.Second
+
+// maxDatagram keeps the datagrams below the usual MTU, so they are not fragmented
+const maxDatagram = 1432
+
+// Option customizes an emitter
+type Option func(e *Emitter)
+
+// WithPrefix sets the prefix of the metric names, solace by default
+func WithPrefix(prefix string) Option {
+	return func(e *Emitter) { e.prefix = prefix }
+}
+
+// WithDogStatsD sends the metrics in the DogStatsD format, with tags
+func WithDogStatsD() Option {
+	return func(e *Emitter) { e.dogStatsD = true }
+}
+
+// WithTags adds constant tags to every metric, e.g. env:prod, DogStatsD only
+func WithTags(tags ...string) Option {
+	return func(e *Emitter) { e.tags = append(e.tags, tags...) }
+}
+
+// WithFlushInterval sets how often the metrics are sent
+func WithFlushInterval(interval time.Duration) Option {
+	return func(e *Emitter) { e.flushInterval = interval }
+}
+
+// service is a messaging service whose API metrics are emitted
+type service struct {
+	messagingService solace.MessagingService
+	tracker          *metricsnap.Tracker
+}
+
+// Emitter sends the metrics to a StatsD agent, it is safe for concurrent use
+type Emitter struct {
+	conn          net.Conn
+	prefix        string
+	dogStatsD     bool
+	tags          []string
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	buffer   []byte
+	services map[string]*service
+	inFlight map[string]*int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New returns an emitter sending to the agent at the address, e.g. localhost:8125
+func New(address string, options ...Option) (*Emitter, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	e := &Emitter{
+		conn:          conn,
+		prefix:        "solace",
+		flushInterval: DefaultFlushInterval,
+		services:      map[string]*service{},
+		inFlight:      map[string]*int64{},
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, option := range options {
+		option(e)
+	}
+	go e.run()
+	return e, nil
+}
+
+func (e *Emitter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.collect()
+			e.Flush()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// collect emits the change of the API metrics of the services since the previous flush
+func (e *Emitter) collect() {
+	e.mu.Lock()
+	services := make(map[string]*service, len(e.services))
+	for name, s := range e.services {
+		services[name] = s
+	}
+	e.mu.Unlock()
+	for name, s := range services {
+		for _, delta := range s.tracker.Interval().Changed() {
+			e.Count("api."+strings.ReplaceAll(delta.Name, " ", "_"), int64(delta.Delta), "service", name)
+		}
+		connected := 0.0
+		if s.messagingService.IsConnected() {
+			connected = 1
+		}
+		e.Gauge("service.connected", connected, "service", name)
+	}
+}
+
+// AddService emits the API metrics and the reconnections of the messaging service under the service name. Add the
+// service before connecting it so the reconnections are counted from the start.
+func (e *Emitter) AddService(name string, messagingService solace.MessagingService) {
+	e.mu.Lock()
+	e.services[name] = &service{messagingService: messagingService, tracker: metricsnap.NewTracker(messagingService.Metrics())}
+	e.mu.Unlock()
+	messagingService.AddReconnectionAttemptListener(func(solace.ServiceEvent) {
+		e.Count("service.events", 1, "service", name, "event", "reconnecting")
+	})
+	messagingService.AddReconnectionListener(func(solace.ServiceEvent) {
+		e.Count("service.events", 1, "service", name, "event", "reconnected")
+	})
+	messagingService.AddServiceInterruptionListener(func(solace.ServiceEvent) {
+		e.Count("service.events", 1, "service", name, "event", "interrupted")
+	})
+}
+
+// RemoveService stops emitting the API metrics of the service, e.g. once it is disconnected for good
+func (e *Emitter) RemoveService(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.services, name)
+}
+
+// Handler wraps the message handler of the named receiver to count the messages and time the handler
+func (e *Emitter) Handler(receiver string, handler solace.MessageHandler) solace.MessageHandler {
+	e.mu.Lock()
+	inFlight, ok := e.inFlight[receiver]
+	if !ok {
+		inFlight = new(int64)
+		e.inFlight[receiver] = inFlight
+	}
+	e.mu.Unlock()
+	return func(inbound message.InboundMessage) {
+		e.Count("receiver.received", 1, "receiver", receiver)
+		e.Gauge("receiver.in_handler", float64(atomic.AddInt64(inFlight, 1)), "receiver", receiver)
+		start := time.Now()
+		defer func() {
+			e.Timing("receiver.handler", time.Since(start), "receiver", receiver)
+			atomic.AddInt64(inFlight, -1)
+		}()
+		handler(inbound)
+	}
+}
+
+// Ack acknowledges the message and counts it as an ACCEPTED settlement of the named receiver
+func (e *Emitter) Ack(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage) error {
+	err := persistentReceiver.Ack(inbound)
+	e.Settled(receiver, config.PersistentReceiverAcceptedOutcome, err)
+	return err
+}
+
+// Settle settles the message with the outcome and counts the settlement of the named receiver
+func (e *Emitter) Settle(receiver string, persistentReceiver solace.PersistentMessageReceiver, inbound message.InboundMessage, outcome config.MessageSettlementOutcome) error {
+	err := persistentReceiver.Settle(inbound, outcome)
+	e.Settled(receiver, outcome, err)
+	return err
+}
+
+// Settled counts a settlement made by the sample itself, err is the error returned by the settlement
+func (e *Emitter) Settled(receiver string, outcome config.MessageSettlementOutcome, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	e.Count("receiver.settlements", 1, "receiver", receiver, "outcome", string(outcome), "result", result)
+}
+
+// Count increments a counter, tags are name and value pairs
+func (e *Emitter) Count(name string, value int64, tags ...string) {
+	e.write(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Gauge sets a gauge, tags are name and value pairs
+func (e *Emitter) Gauge(name string, value float64, tags ...string) {
+	e.write(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Timing records a duration in milliseconds, tags are name and value pairs
+func (e *Emitter) Timing(name string, d time.Duration, tags ...string) {
+	e.write(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64), "ms", tags)
+}
+
+// line formats a metric, with the tags as DogStatsD tags or in the name
+func (e *Emitter) line(name, value, kind string, tags []string) string {
+	var b strings.Builder
+	if e.dogStatsD {
+		fmt.Fprintf(&b, "%s.%s:%s|%s", e.prefix, name, value, kind)
+		separator := "|#"
+		for _, tag := range e.tags {
+			b.WriteString(separator + tag)
+			separator = ","
+		}
+		for i := 0; i+1 < len(tags); i += 2 {
+			b.WriteString(separator + tags[i] + ":" + sanitize(tags[i+1]))
+			separator = ","
+		}
+		return b.String()
+	}
+	b.WriteString(e.prefix)
+	for i := 1; i < len(tags); i += 2 {
+		b.WriteString("." + sanitize(tags[i]))
+	}
+	fmt.Fprintf(&b, ".%s:%s|%s", name, value, kind)
+	return b.String()
+}
+
+// sanitizer replaces the characters with a meaning in the formats, and the dots separating the parts of the names
+var sanitizer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", ".", "_", "\n", "_")
+
+func sanitize(value string) string {
+	return sanitizer.Replace(value)
+}
+
+func (e *Emitter) write(name, value, kind string, tags []string) {
+	line := e.line(name, value, kind, tags)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.buffer) > 0 && len(e.buffer)+1+len(line) > maxDatagram {
+		e.flushLocked()
+	}
+	if len(e.buffer) > 0 {
+		e.buffer = append(e.buffer, '\n')
+	}
+	e.buffer = append(e.buffer, line...)
+}
+
+// Flush sends the buffered metrics
+func (e *Emitter) Flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.flushLocked()
+}
+
+func (e *Emitter) flushLocked() {
+	if len(e.buffer) == 0 {
+		return
+	}
+	// UDP, a missing agent is not an error worth reporting
+	e.conn.Write(e.buffer)
+	e.buffer = e.buffer[:0]
+}
+
+// Close emits the last change of the API metrics, sends the buffered metrics and closes the connection
+func (e *Emitter) Close() error {
+	close(e.stop)
+	<-e.done
+	e.collect()
+	e.Flush()
+	return e.conn.Close()
+}
@@ -0,0 +1,194 @@
+// Package waitfor blocks until a broker is ready to take connections, e.g. a broker started by docker compose or by
+// a test along with the sample: the SMF port of one of its hosts accepts TCP connections, the SEMP v2 API answers and
+// the message VPN is up. The checks are retried with an exponential backoff until they pass or the timeout elapses:
+//
+//	err := waitfor.Broker(ctx, waitfor.Options{
+//		Host:    "tcp://localhost:55555",
+//		SEMPURL: "http://localhost:8080", Username: "admin", Password: "admin", VPN: "default",
+//		Timeout: 2 * time.Minute,
+//	})
+//
+// The samples wait for the broker before connecting when run with -wait-for-broker (see internal/sampleconfig).
+package waitfor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Defaults of the options
+const (
+	DefaultTimeout        = 2 * time.Minute
+	DefaultInitialBackoff = 250 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+)
+
+// defaultPorts are the ports of the transport protocols when the host omits it
+var defaultPorts = map[string]string{"tcp": "55555", "tcps": "55443", "ws": "80", "wss": "443"}
+
+// Options of the wait
+type Options struct {
+	// Host is the broker URI or the comma separated host list of the service, e.g.
+	// tcp://localhost:55555,tcp://localhost:55554; the SMF check passes when any of the hosts accepts connections
+	Host string
+	// SEMPURL locates the SEMP v2 API, e.g. http://localhost:8080; the SEMP and VPN checks are skipped when empty
+	SEMPURL string
+	// Username and Password of a management user allowed to read the VPN
+	Username string
+	Password string
+	// VPN is the message VPN that must be up, the VPN check is skipped when empty
+	VPN string
+	// Timeout of the whole wait, DefaultTimeout when 0
+	Timeout time.Duration
+	// InitialBackoff is the delay before the first retry, doubled at every retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// HTTPClient sends the SEMP requests, an http.Client with a 5 seconds timeout when nil
+	HTTPClient *http.Client
+	// Progress is called with every failed check before it is retried, e.g. to log the wait, nil for none
+	Progress func(err error)
+}
+
+// Broker waits until the SMF port accepts connections, SEMP answers and the VPN is up, or the timeout elapses
+func Broker(ctx context.Context, options Options) error {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.InitialBackoff <= 0 {
+		options.InitialBackoff = DefaultInitialBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	// the checks pass one after the other, a check that passed is not run again
+	checks := []func(context.Context) error{
+		func(ctx context.Context) error { return SMF(ctx, options.Host) },
+	}
+	if options.SEMPURL != "" {
+		checks = append(checks, func(ctx context.Context) error {
+			return VPN(ctx, options.HTTPClient, options.SEMPURL, options.Username, options.Password, options.VPN)
+		})
+	}
+	backoff := options.InitialBackoff
+	for _, check := range checks {
+		for {
+			err := check(ctx)
+			if err == nil {
+				break
+			}
+			if options.Progress != nil {
+				options.Progress(err)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("the broker is not ready after %s: %w", options.Timeout, err)
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > options.MaxBackoff {
+				backoff = options.MaxBackoff
+			}
+		}
+	}
+	return nil
+}
+
+// SMF checks that one of the hosts of the host list accepts TCP connections
+func SMF(ctx context.Context, hostList string) error {
+	var dialer net.Dialer
+	var firstErr error
+	for _, host := range strings.Split(hostList, ",") {
+		address, err := address(strings.TrimSpace(host))
+		if err == nil {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, "tcp", address); err == nil {
+				conn.Close()
+				return nil
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// address returns the host:port address of a host of the host list, e.g. tcps://broker or localhost:55554
+func address(host string) (string, error) {
+	scheme := "tcp"
+	if i := strings.Index(host, "://"); i >= 0 {
+		scheme, host = strings.ToLower(host[:i]), host[i+3:]
+	}
+	port, ok := defaultPorts[scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown protocol %s in host %s", scheme, host)
+	}
+	// a WebSocket URI may have a path
+	host, _, _ = strings.Cut(host, "/")
+	if host == "" {
+		return "", fmt.Errorf("empty host")
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host, nil
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port), nil
+}
+
+// vpnResponse is the part of the SEMP v2 monitor response of a message VPN read by VPN
+type vpnResponse struct {
+	Data struct {
+		State string `json:"state"`
+	} `json:"data"`
+	Meta struct {
+		Error *struct {
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error"`
+	} `json:"meta"`
+}
+
+// VPN checks that SEMP answers and, when vpn is not empty, that the message VPN is up
+func VPN(ctx context.Context, client *http.Client, sempURL, username, password, vpn string) error {
+	endpoint := strings.TrimSuffix(sempURL, "/") + "/SEMP/v2/monitor/about/api"
+	if vpn != "" {
+		endpoint = strings.TrimSuffix(sempURL, "/") + "/SEMP/v2/monitor/msgVpns/" + url.PathEscape(vpn) + "?select=state"
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	request.SetBasicAuth(username, password)
+	request.Header.Set("Accept", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("SEMP is not reachable: %w", err)
+	}
+	defer response.Body.Close()
+	var body vpnResponse
+	if vpn != "" {
+		json.NewDecoder(response.Body).Decode(&body)
+	}
+	switch {
+	case body.Meta.Error != nil:
+		return fmt.Errorf("VPN %s: %s: %s", vpn, body.Meta.Error.Status, body.Meta.Error.Description)
+	case response.StatusCode != http.StatusOK:
+		return fmt.Errorf("SEMP is not ready: %s", response.Status)
+	case vpn == "":
+		return nil
+	}
+	if body.Data.State != "up" {
+		return fmt.Errorf("VPN %s is %s", vpn, body.Data.State)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package zaplog builds zap loggers for the high throughput samples, where a log line per message would cost more
+// than the messaging itself. Below the error level the records are sampled: within every tick, the first records with
+// a given message are written and then only one in every so many. Errors and above are never sampled, every one of
+// them is written.
+//
+//	logger := zaplog.New(zapcore.InfoLevel, zaplog.DefaultSampling)
+//	defer logger.Sync()
+//	zaplog.RouteAPILogs(logger)
+//	logger.Info("message received", zap.String("topic", topic))
+//
+// The sampling counts records by level and message, so per-message records should keep a constant message and put
+// what varies in fields.
+package zaplog
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"SolaceSamples.com/PubSub+Go/pkg/apilog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"solace.dev/go/messaging/pkg/solace/logging"
+)
+
+// Sampling configures the sampling of the records below the error level
+type Sampling struct {
+	// Tick is the period the records are counted over
+	Tick time.Duration
+	// First records with the same level and message are written within each tick
+	First int
+	// Thereafter one in every Thereafter records is written, 0 drops all of them
+	Thereafter int
+}
+
+// DefaultSampling writes the first 100 records with the same message every second, then one in 1000
+var DefaultSampling = &Sampling{Tick: time.Second, First: 100, Thereafter: 1000}
+
+// New returns a JSON logger writing to standard error the records at the level and above, sampled below the error
+// level unless sampling is nil
+func New(level zapcore.LevelEnabler, sampling *Sampling) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(NewCore(encoder, zapcore.Lock(os.Stderr), level, sampling), zap.AddCaller())
+}
+
+// NewCore returns a core writing the records at the level and above to the sink, sampled below the error level
+// unless sampling is nil
+func NewCore(encoder zapcore.Encoder, sink zapcore.WriteSyncer, level zapcore.LevelEnabler, sampling *Sampling) zapcore.Core {
+	if sampling == nil {
+		return zapcore.NewCore(encoder, sink, level)
+	}
+	belowError := zapcore.NewCore(encoder, sink, zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return level.Enabled(l) && l < zapcore.ErrorLevel
+	}))
+	errors := zapcore.NewCore(encoder.Clone(), sink, zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return level.Enabled(l) && l >= zapcore.ErrorLevel
+	}))
+	return zapcore.NewTee(
+		zapcore.NewSamplerWithOptions(belowError, sampling.Tick, sampling.First, sampling.Thereafter),
+		errors,
+	)
+}
+
+// Writer is an io.Writer turning the API log lines written to it into zap records, see apilog.ParseLine
+type Writer struct {
+	logger *zap.Logger
+}
+
+// NewWriter returns a writer logging the API log lines with the logger
+func NewWriter(logger *zap.Logger) *Writer {
+	return &Writer{logger: logger.WithOptions(zap.WithCaller(false)).With(zap.String("component", "solace-api"))}
+}
+
+// Write logs every line of p as a record
+func (w *Writer) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if text := strings.TrimSpace(string(line)); text != "" {
+			level, subsystem, message := apilog.ParseLine(text)
+			w.logger.Log(zapLevel(level), message, zap.String(apilog.SubsystemKey, subsystem))
+		}
+	}
+	return len(p), nil
+}
+
+// RouteAPILogs routes the API logs to the logger, the API logs at the level of the logger and above
+func RouteAPILogs(logger *zap.Logger) {
+	logging.SetLogOutput(NewWriter(logger))
+	logging.SetLogLevel(apilog.APILevel(slogLevel(logger.Level())))
+}
+
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func slogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	case level == zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		// DPanic and above, only the critical API logs
+		return slog.LevelError + 4
+	}
+}